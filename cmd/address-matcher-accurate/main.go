@@ -1,15 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 
-	_ "github.com/lib/pq"
-
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/matcher"
 	"github.com/ehdc-llpg/internal/normalize"
 )
@@ -21,7 +21,7 @@ func main() {
 		command    = flag.String("cmd", "", "Command: match-single, test-accuracy")
 		address    = flag.String("address", "", "Single address to match")
 		debug      = flag.Bool("debug", false, "Enable debug output")
-		configFile = flag.String("config", ".env", "Path to configuration file")
+		configFile = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 	)
 	flag.Parse()
 
@@ -33,14 +33,15 @@ func main() {
 	fmt.Printf("EHDC Address Matcher %s (ACCURACY-FOCUSED)\n", version)
 	fmt.Printf("Maximum accuracy with component-level matching\n\n")
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	// Connect to database
-	db, err := connectDB()
+	db, err := database.Open(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -81,20 +82,6 @@ func printUsage() {
 	fmt.Println("    ./address-matcher-accurate -cmd=test-accuracy")
 }
 
-func connectDB() (*sql.DB, error) {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	return sql.Open("postgres", connStr)
-}
-
 func matchSingleAddress(localDebug bool, engine *matcher.AccurateEngine, address string) error {
 	fmt.Printf("Testing address (ACCURATE): %s\n", address)
 	fmt.Println("🔍 Using multiple matching strategies:")
@@ -129,7 +116,7 @@ func matchSingleAddress(localDebug bool, engine *matcher.AccurateEngine, address
 	}
 	
 	// Process with accuracy-focused matching
-	result, err := engine.ProcessDocument(localDebug, input)
+	result, err := engine.ProcessDocument(context.Background(), localDebug, input)
 	if err != nil {
 		return fmt.Errorf("accurate matching failed: %w", err)
 	}
@@ -196,7 +183,7 @@ func testAccuracyImprovements(localDebug bool, engine *matcher.AccurateEngine, d
 			AddressCanonical: canonical,
 		}
 		
-		result, err := engine.ProcessDocument(false, input)
+		result, err := engine.ProcessDocument(context.Background(), false, input)
 		if err != nil {
 			fmt.Printf("   ❌ Error: %v\n", err)
 			continue
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 
-	_ "github.com/lib/pq"
-
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/interrupt"
 	"github.com/ehdc-llpg/internal/matcher"
 	"github.com/ehdc-llpg/internal/normalize"
 )
@@ -23,7 +25,7 @@ func main() {
 		address    = flag.String("address", "", "Single address to match")
 		batchSize  = flag.Int("batch-size", 1000, "Batch size for processing (hybrid default)")
 		debug      = flag.Bool("debug", false, "Enable debug output")
-		configFile = flag.String("config", ".env", "Path to configuration file")
+		configFile = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 	)
 	flag.Parse()
 
@@ -35,14 +37,15 @@ func main() {
 	fmt.Printf("EHDC Address Matcher %s (HYBRID)\n", version)
 	fmt.Printf("Fast DB pre-filtering + Advanced Go algorithms + Intelligent decisions\n\n")
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	// Connect to database
-	db, err := connectDB()
+	db, err := database.Open(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -52,22 +55,26 @@ func main() {
 	hybridEngine := matcher.NewHybridEngine(db, nil, nil)
 	hybridBatchProcessor := matcher.NewHybridBatchProcessor(hybridEngine, db)
 
+	// Ctrl-C stops batch runs between documents with partial statistics
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+
 	// Execute command
 	switch *command {
 	case "match-all":
-		err = matchAllDocuments(*debug, hybridBatchProcessor, *batchSize)
+		err = matchAllDocuments(ctx, *debug, hybridBatchProcessor, *batchSize)
 	case "match-type":
 		if *docType == "" {
 			fmt.Println("Error: -type parameter required for match-type command")
 			os.Exit(1)
 		}
-		err = matchDocumentsByType(*debug, hybridBatchProcessor, *docType, *batchSize)
+		err = matchDocumentsByType(ctx, *debug, hybridBatchProcessor, *docType, *batchSize)
 	case "match-single":
 		if *address == "" {
 			fmt.Println("Error: -address parameter required for match-single command")
 			os.Exit(1)
 		}
-		err = matchSingleAddress(*debug, hybridEngine, *address)
+		err = matchSingleAddress(ctx, *debug, hybridEngine, *address)
 	case "stats":
 		err = showStatistics(*debug, db)
 	default:
@@ -83,6 +90,13 @@ func main() {
 	fmt.Println("\nHybrid command completed successfully!")
 }
 
+// printResumeHint tells the user how to continue a run that stopped early
+func printResumeHint(runID int64) {
+	if runID != 0 {
+		fmt.Printf("\nRun %d stopped early; continue it with: ./bin/matcher resume %d\n", runID, runID)
+	}
+}
+
 func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  Match all unmatched documents (hybrid):")
@@ -102,49 +116,29 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -debug          Enable detailed debug output (shows all 3 stages)")
-	fmt.Println("  -config         Path to configuration file (default: .env)")
+	fmt.Println("  -config         Path to configuration file (default: .env in this or a parent directory)")
 	fmt.Println("  -batch-size     Batch size for processing (default: 1000)")
 }
 
-func connectDB() (*sql.DB, error) {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, err
-	}
-
-	// Test connection
-	err = db.Ping()
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
-}
-
-func matchAllDocuments(localDebug bool, bp *matcher.HybridBatchProcessor, batchSize int) error {
+func matchAllDocuments(ctx context.Context, localDebug bool, bp *matcher.HybridBatchProcessor, batchSize int) error {
 	fmt.Println("Starting HYBRID batch processing of all unmatched documents...")
 	fmt.Println("🔄 Stage 1: Fast DB pre-filtering")
 	fmt.Println("🧠 Stage 2: Advanced Go analysis (tokens, spatial, semantic)")
 	fmt.Println("🎯 Stage 3: Intelligent decision making")
 	fmt.Println()
 	
-	stats, err := bp.ProcessAllDocuments(localDebug, batchSize)
-	if err != nil {
+	stats, err := bp.ProcessAllDocuments(ctx, localDebug, batchSize)
+	if stats == nil {
+		return err
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		printResumeHint(stats.RunID)
 		return err
 	}
 	
 	// Print comprehensive summary
 	fmt.Printf("\n📊 HYBRID Batch Processing Summary:\n")
+	fmt.Printf("  Run:                 %d (leg %d)\n", stats.RunID, stats.Legs)
 	fmt.Printf("  Total Documents:        %d\n", stats.TotalDocuments)
 	fmt.Printf("  Successfully Processed: %d\n", stats.ProcessedCount)
 	fmt.Printf("  ✅ Auto-Accepted:       %d (%.1f%%)\n", 
@@ -172,20 +166,28 @@ func matchAllDocuments(localDebug bool, bp *matcher.HybridBatchProcessor, batchS
 	fmt.Printf("  🧠 Semantic Enhanced:   %d\n", stats.SemanticMatchCount)
 	fmt.Printf("  ⬆️  Avg Hybrid Boost:   %.4f\n", stats.HybridBoostAverage)
 	
-	return nil
+	if err != nil {
+		printResumeHint(stats.RunID)
+	}
+	return err // context.Canceled after an interrupt, once partial statistics are shown
 }
 
-func matchDocumentsByType(localDebug bool, bp *matcher.HybridBatchProcessor, docType string, batchSize int) error {
+func matchDocumentsByType(ctx context.Context, localDebug bool, bp *matcher.HybridBatchProcessor, docType string, batchSize int) error {
 	fmt.Printf("Processing documents of type: %s (HYBRID)\n", docType)
 	fmt.Println("🔄 Fast DB filtering → 🧠 Advanced Go analysis → 🎯 Intelligent decisions\n")
 	
-	stats, err := bp.ProcessDocumentsByType(localDebug, docType, batchSize)
-	if err != nil {
+	stats, err := bp.ProcessDocumentsByType(ctx, localDebug, docType, batchSize)
+	if stats == nil {
+		return err
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		printResumeHint(stats.RunID)
 		return err
 	}
 	
 	// Print comprehensive summary
 	fmt.Printf("\n📊 HYBRID Processing Summary for %s:\n", docType)
+	fmt.Printf("  Run:                 %d (leg %d)\n", stats.RunID, stats.Legs)
 	fmt.Printf("  Total Documents:        %d\n", stats.TotalDocuments)
 	fmt.Printf("  Successfully Processed: %d\n", stats.ProcessedCount)
 	fmt.Printf("  ✅ Auto-Accepted:       %d (%.1f%%)\n", 
@@ -209,10 +211,13 @@ func matchDocumentsByType(localDebug bool, bp *matcher.HybridBatchProcessor, doc
 		stats.TokenMatchCount, stats.SpatialMatchCount, stats.SemanticMatchCount)
 	fmt.Printf("  Average Hybrid Boost: %.4f\n", stats.HybridBoostAverage)
 	
-	return nil
+	if err != nil {
+		printResumeHint(stats.RunID)
+	}
+	return err // context.Canceled after an interrupt, once partial statistics are shown
 }
 
-func matchSingleAddress(localDebug bool, engine *matcher.HybridEngine, address string) error {
+func matchSingleAddress(ctx context.Context, localDebug bool, engine *matcher.HybridEngine, address string) error {
 	fmt.Printf("Testing single address (HYBRID): %s\n", address)
 	fmt.Println("🔄 Stage 1: Fast DB pre-filtering")
 	fmt.Println("🧠 Stage 2: Advanced Go analysis")  
@@ -235,7 +240,7 @@ func matchSingleAddress(localDebug bool, engine *matcher.HybridEngine, address s
 	}
 	
 	// Process the address with hybrid analysis
-	result, err := engine.ProcessDocument(localDebug, input)
+	result, err := engine.ProcessDocument(ctx, localDebug, input)
 	if err != nil {
 		return fmt.Errorf("hybrid matching failed: %w", err)
 	}
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 
-	_ "github.com/lib/pq"
-
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/interrupt"
 	"github.com/ehdc-llpg/internal/matcher"
 	"github.com/ehdc-llpg/internal/normalize"
 )
@@ -23,7 +25,7 @@ func main() {
 		address    = flag.String("address", "", "Single address to match")
 		batchSize  = flag.Int("batch-size", 2000, "Batch size for processing (optimized default)")
 		debug      = flag.Bool("debug", false, "Enable debug output")
-		configFile = flag.String("config", ".env", "Path to configuration file")
+		configFile = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 	)
 	flag.Parse()
 
@@ -35,14 +37,15 @@ func main() {
 	fmt.Printf("EHDC Address Matcher %s (Optimized)\n", version)
 	fmt.Printf("Using database functions and materialized views for high performance\n\n")
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	// Connect to database
-	db, err := connectDB()
+	db, err := database.Open(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -52,22 +55,26 @@ func main() {
 	optimizedEngine := matcher.NewOptimizedEngine(db)
 	optimizedBatchProcessor := matcher.NewOptimizedBatchProcessor(optimizedEngine, db)
 
+	// Ctrl-C stops batch runs between documents with partial statistics
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+
 	// Execute command
 	switch *command {
 	case "match-all":
-		err = matchAllDocuments(*debug, optimizedBatchProcessor, *batchSize)
+		err = matchAllDocuments(ctx, *debug, optimizedBatchProcessor, *batchSize)
 	case "match-type":
 		if *docType == "" {
 			fmt.Println("Error: -type parameter required for match-type command")
 			os.Exit(1)
 		}
-		err = matchDocumentsByType(*debug, optimizedBatchProcessor, *docType, *batchSize)
+		err = matchDocumentsByType(ctx, *debug, optimizedBatchProcessor, *docType, *batchSize)
 	case "match-single":
 		if *address == "" {
 			fmt.Println("Error: -address parameter required for match-single command")
 			os.Exit(1)
 		}
-		err = matchSingleAddress(*debug, optimizedEngine, *address)
+		err = matchSingleAddress(ctx, *debug, optimizedEngine, *address)
 	case "stats":
 		err = showStatistics(*debug, db)
 	default:
@@ -83,6 +90,13 @@ func main() {
 	fmt.Println("\nOptimized command completed successfully!")
 }
 
+// printResumeHint tells the user how to continue a run that stopped early
+func printResumeHint(runID int64) {
+	if runID != 0 {
+		fmt.Printf("\nRun %d stopped early; continue it with: ./bin/matcher resume %d\n", runID, runID)
+	}
+}
+
 func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  Match all unmatched documents (optimized):")
@@ -102,45 +116,25 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -debug          Enable detailed debug output")
-	fmt.Println("  -config         Path to configuration file (default: .env)")
+	fmt.Println("  -config         Path to configuration file (default: .env in this or a parent directory)")
 	fmt.Println("  -batch-size     Batch size for processing (default: 2000)")
 }
 
-func connectDB() (*sql.DB, error) {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, err
-	}
-
-	// Test connection
-	err = db.Ping()
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
-}
-
-func matchAllDocuments(localDebug bool, bp *matcher.OptimizedBatchProcessor, batchSize int) error {
+func matchAllDocuments(ctx context.Context, localDebug bool, bp *matcher.OptimizedBatchProcessor, batchSize int) error {
 	fmt.Println("Starting optimized batch processing of all unmatched documents...")
 	
-	stats, err := bp.ProcessAllDocuments(localDebug, batchSize)
-	if err != nil {
+	stats, err := bp.ProcessAllDocuments(ctx, localDebug, batchSize)
+	if stats == nil {
+		return err
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		printResumeHint(stats.RunID)
 		return err
 	}
 	
 	// Print summary
 	fmt.Printf("\n📊 Optimized Batch Processing Summary:\n")
+	fmt.Printf("  Run:                 %d (leg %d)\n", stats.RunID, stats.Legs)
 	fmt.Printf("  Total Documents:     %d\n", stats.TotalDocuments)
 	fmt.Printf("  Successfully Processed: %d\n", stats.ProcessedCount)
 	fmt.Printf("  ✅ Auto-Accepted:    %d (%.1f%%)\n", 
@@ -156,19 +150,27 @@ func matchAllDocuments(localDebug bool, bp *matcher.OptimizedBatchProcessor, bat
 	fmt.Printf("  🚀 Processing Rate:  %.1f docs/sec\n", 
 		float64(stats.ProcessedCount)/stats.ProcessingTime.Seconds())
 	
-	return nil
+	if err != nil {
+		printResumeHint(stats.RunID)
+	}
+	return err // context.Canceled after an interrupt, once partial statistics are shown
 }
 
-func matchDocumentsByType(localDebug bool, bp *matcher.OptimizedBatchProcessor, docType string, batchSize int) error {
+func matchDocumentsByType(ctx context.Context, localDebug bool, bp *matcher.OptimizedBatchProcessor, docType string, batchSize int) error {
 	fmt.Printf("Processing documents of type: %s (optimized)\n", docType)
 	
-	stats, err := bp.ProcessDocumentsByType(localDebug, docType, batchSize)
-	if err != nil {
+	stats, err := bp.ProcessDocumentsByType(ctx, localDebug, docType, batchSize)
+	if stats == nil {
+		return err
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		printResumeHint(stats.RunID)
 		return err
 	}
 	
 	// Print summary
 	fmt.Printf("\n📊 Optimized Processing Summary for %s:\n", docType)
+	fmt.Printf("  Run:                 %d (leg %d)\n", stats.RunID, stats.Legs)
 	fmt.Printf("  Total Documents:     %d\n", stats.TotalDocuments)
 	fmt.Printf("  Successfully Processed: %d\n", stats.ProcessedCount)
 	fmt.Printf("  ✅ Auto-Accepted:    %d (%.1f%%)\n", 
@@ -183,10 +185,13 @@ func matchDocumentsByType(localDebug bool, bp *matcher.OptimizedBatchProcessor,
 	fmt.Printf("  🚀 Processing Rate:  %.1f docs/sec\n", 
 		float64(stats.ProcessedCount)/stats.ProcessingTime.Seconds())
 	
-	return nil
+	if err != nil {
+		printResumeHint(stats.RunID)
+	}
+	return err // context.Canceled after an interrupt, once partial statistics are shown
 }
 
-func matchSingleAddress(localDebug bool, engine *matcher.OptimizedEngine, address string) error {
+func matchSingleAddress(ctx context.Context, localDebug bool, engine *matcher.OptimizedEngine, address string) error {
 	fmt.Printf("Testing single address (optimized): %s\n", address)
 	
 	// Create canonical address
@@ -205,7 +210,7 @@ func matchSingleAddress(localDebug bool, engine *matcher.OptimizedEngine, addres
 	}
 	
 	// Process the address
-	result, err := engine.ProcessDocument(localDebug, input)
+	result, err := engine.ProcessDocument(ctx, localDebug, input)
 	if err != nil {
 		return fmt.Errorf("optimized matching failed: %w", err)
 	}
@@ -7,8 +7,8 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
 )
 
 const version = "1.0.0-bulk-historic-uprns"
@@ -18,11 +18,12 @@ func main() {
 	fmt.Println("Creating historic address records for all missing UPRNs in bulk")
 	fmt.Println()
 
-	// Load configuration
-	err := config.LoadConfig(".env")
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	// Connect to database
 	db := connectDB()
@@ -246,25 +247,11 @@ func createHistoricBatch(tx *sql.Tx, batch []MissingUPRN) (int, error) {
 	return len(batch), nil
 }
 
+// connectDB opens the database from the shared configuration
 func connectDB() *sql.DB {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := database.Open(config.Current().Database)
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Cannot connect to database: %v", err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
-
 	return db
-}
\ No newline at end of file
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"log"
 	"time"
 
-	_ "github.com/lib/pq"
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/interrupt"
 	"github.com/ehdc-llpg/internal/matcher"
 )
 
@@ -19,7 +21,7 @@ func main() {
 		limit      = flag.Int("limit", 0, "Number of documents to process (0 = all)")
 		batchSize  = flag.Int("batch-size", 1000, "Batch size for processing")
 		debug      = flag.Bool("debug", false, "Enable debug output")
-		configFile = flag.String("config", ".env", "Path to configuration file")
+		configFile = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 		workers    = flag.Int("workers", 4, "Number of parallel workers")
 		reprocess  = flag.Bool("reprocess", false, "Reprocess existing matches")
 	)
@@ -30,11 +32,12 @@ func main() {
 	fmt.Println("🔧 CRITICAL FIXES: Strict house number validation, proper business matching")
 	fmt.Println()
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	// Connect to database
 	db := connectDB()
@@ -79,12 +82,19 @@ func main() {
 	// Create FIXED component engine
 	engine := matcher.NewFixedComponentEngine(db)
 
+	// Ctrl-C stops between documents and still reports what was processed
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+
 	// Process documents
 	startTime := time.Now()
-	processed, matched, errors := processDocuments(db, engine, totalDocs, *batchSize, *debug, *reprocess)
+	processed, matched, errors := processDocuments(ctx, db, engine, totalDocs, *batchSize, *debug, *reprocess)
 	duration := time.Since(startTime)
 
 	// Report results
+	if ctx.Err() != nil {
+		fmt.Printf("\n⚠️  Interrupted: partial results below\n")
+	}
 	fmt.Printf("\n✅ FIXED COMPONENT MATCHING COMPLETE\n")
 	fmt.Printf("=====================================\n")
 	fmt.Printf("📊 Documents processed: %d\n", processed)
@@ -98,30 +108,16 @@ func main() {
 	generateComparisonStats(db)
 }
 
+// connectDB opens the database from the shared configuration
 func connectDB() *sql.DB {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := database.Open(config.Current().Database)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
-
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Cannot connect to database: %v", err)
-	}
-
 	return db
 }
 
-func processDocuments(db *sql.DB, engine *matcher.FixedComponentEngine, totalDocs, batchSize int, debug, reprocess bool) (int, int, int) {
+func processDocuments(ctx context.Context, db *sql.DB, engine *matcher.FixedComponentEngine, totalDocs, batchSize int, debug, reprocess bool) (int, int, int) {
 	var processed, matched, errors int
 
 	query := `
@@ -140,7 +136,7 @@ func processDocuments(db *sql.DB, engine *matcher.FixedComponentEngine, totalDoc
 		query += fmt.Sprintf(" LIMIT %d", totalDocs)
 	}
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		log.Fatalf("Failed to query documents: %v", err)
 	}
@@ -150,6 +146,10 @@ func processDocuments(db *sql.DB, engine *matcher.FixedComponentEngine, totalDoc
 	batchStart := time.Now()
 
 	for rows.Next() {
+		if ctx.Err() != nil {
+			break // the current document has been saved or skipped whole
+		}
+
 		var docID int64
 		var address string
 		var rawUPRN sql.NullString
@@ -170,7 +170,7 @@ func processDocuments(db *sql.DB, engine *matcher.FixedComponentEngine, totalDoc
 			input.RawUPRN = &rawUPRN.String
 		}
 
-		result, err := engine.ProcessDocument(debug, input)
+		result, err := engine.ProcessDocument(ctx, debug, input)
 		if err != nil {
 			if debug {
 				fmt.Printf("❌ Error processing doc %d: %v\n", docID, err)
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"log"
 	"time"
 
-	_ "github.com/lib/pq"
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/interrupt"
 	"github.com/ehdc-llpg/internal/matcher"
 )
 
@@ -19,7 +21,7 @@ func main() {
 		limit      = flag.Int("limit", 0, "Number of documents to process (0 = all)")
 		batchSize  = flag.Int("batch-size", 1000, "Batch size for processing")
 		debug      = flag.Bool("debug", false, "Enable debug output")
-		configFile = flag.String("config", ".env", "Path to configuration file")
+		configFile = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 		workers    = flag.Int("workers", 4, "Number of parallel workers")
 	)
 	flag.Parse()
@@ -28,11 +30,12 @@ func main() {
 	fmt.Println("Processing addresses with component-based matching for maximum accuracy")
 	fmt.Println()
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	// Connect to database
 	db := connectDB()
@@ -57,12 +60,19 @@ func main() {
 	// Create component engine
 	engine := matcher.NewComponentEngine(db)
 
+	// Ctrl-C stops between documents and still reports what was processed
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+
 	// Process documents
 	startTime := time.Now()
-	processed, matched, errors := processDocuments(db, engine, totalDocs, *batchSize, *debug)
+	processed, matched, errors := processDocuments(ctx, db, engine, totalDocs, *batchSize, *debug)
 	duration := time.Since(startTime)
 
 	// Report results
+	if ctx.Err() != nil {
+		fmt.Printf("\n⚠️  Interrupted: partial results below\n")
+	}
 	fmt.Printf("\n✅ COMPONENT MATCHING COMPLETE\n")
 	fmt.Printf("===============================\n")
 	fmt.Printf("📊 Documents processed: %d\n", processed)
@@ -76,30 +86,16 @@ func main() {
 	generateSummaryStats(db)
 }
 
+// connectDB opens the database from the shared configuration
 func connectDB() *sql.DB {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := database.Open(config.Current().Database)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
-
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Cannot connect to database: %v", err)
-	}
-
 	return db
 }
 
-func processDocuments(db *sql.DB, engine *matcher.ComponentEngine, totalDocs, batchSize int, debug bool) (int, int, int) {
+func processDocuments(ctx context.Context, db *sql.DB, engine *matcher.ComponentEngine, totalDocs, batchSize int, debug bool) (int, int, int) {
 	var processed, matched, errors int
 
 	query := `
@@ -114,7 +110,7 @@ func processDocuments(db *sql.DB, engine *matcher.ComponentEngine, totalDocs, ba
 		query += fmt.Sprintf(" LIMIT %d", totalDocs)
 	}
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		log.Fatalf("Failed to query documents: %v", err)
 	}
@@ -124,6 +120,10 @@ func processDocuments(db *sql.DB, engine *matcher.ComponentEngine, totalDocs, ba
 	batchStart := time.Now()
 
 	for rows.Next() {
+		if ctx.Err() != nil {
+			break // the current document has been saved or skipped whole
+		}
+
 		var docID int64
 		var address string
 		
@@ -138,7 +138,7 @@ func processDocuments(db *sql.DB, engine *matcher.ComponentEngine, totalDocs, ba
 			RawAddress: address,
 		}
 
-		result, err := engine.ProcessDocument(false, input) // Set debug=false for performance
+		result, err := engine.ProcessDocument(ctx, false, input) // Set debug=false for performance
 		if err != nil {
 			if debug {
 				fmt.Printf("❌ Error processing doc %d: %v\n", docID, err)
@@ -149,7 +149,7 @@ func processDocuments(db *sql.DB, engine *matcher.ComponentEngine, totalDocs, ba
 
 		// Save result if we found a match
 		if result.BestCandidate != nil {
-			err = engine.SaveMatchResult(false, result)
+			err = engine.SaveMatchResult(ctx, false, result)
 			if err != nil {
 				if debug {
 					fmt.Printf("❌ Error saving result for doc %d: %v\n", docID, err)
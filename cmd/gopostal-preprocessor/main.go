@@ -8,8 +8,8 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
 )
 
 const version = "1.0.0"
@@ -37,7 +37,7 @@ func main() {
 		command    = flag.String("cmd", "", "Command: preprocess-llpg, preprocess-source, test-parse, stats")
 		address    = flag.String("address", "", "Single address to test parsing")
 		limit      = flag.Int("limit", 100, "Number of records to process (0 = all)")
-		configFile = flag.String("config", ".env", "Path to configuration file")
+		configFile = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 	)
 	flag.Parse()
 
@@ -50,14 +50,15 @@ func main() {
 	fmt.Println("Pre-processes addresses with gopostal for optimal matching")
 	fmt.Println()
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	// Connect to database
-	db, err := connectDB()
+	db, err := database.Open(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -101,20 +102,6 @@ func printUsage() {
 	fmt.Println("    ./gopostal-preprocessor -cmd=stats")
 }
 
-func connectDB() (*sql.DB, error) {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	return sql.Open("postgres", connStr)
-}
-
 // preprocessLLPG processes LLPG addresses with gopostal
 func preprocessLLPG(db *sql.DB, limit int) error {
 	fmt.Println("📍 Pre-processing LLPG addresses with gopostal...")
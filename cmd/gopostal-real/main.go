@@ -8,9 +8,9 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
 	postal "github.com/openvenues/gopostal/parser"
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
 )
 
 const version = "1.0.0-real-gopostal"
@@ -20,7 +20,7 @@ func main() {
 		command    = flag.String("cmd", "", "Command: test-parse, preprocess-llpg, preprocess-source, preprocess-all, stats")
 		address    = flag.String("address", "", "Single address to test parsing")
 		limit      = flag.Int("limit", 1000, "Number of records to process (0 = all)")
-		configFile = flag.String("config", ".env", "Path to configuration file")
+		configFile = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 	)
 	flag.Parse()
 
@@ -33,11 +33,12 @@ func main() {
 	fmt.Println("Using libpostal for maximum UK address parsing accuracy")
 	fmt.Println()
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
 
 	switch *command {
 	case "test-parse":
@@ -90,20 +91,11 @@ func printUsage() {
 	fmt.Println("    ./gopostal-real -cmd=stats")
 }
 
+// connectDB opens the database from the shared configuration
 func connectDB() *sql.DB {
-	host := config.GetEnv("DB_HOST", "localhost")
-	port := config.GetEnv("DB_PORT", "15435")
-	user := config.GetEnv("DB_USER", "postgres")
-	password := config.GetEnv("DB_PASSWORD", "kljh234hjkl2h")
-	dbname := config.GetEnv("DB_NAME", "ehdc_llpg")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := database.Open(config.Current().Database)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	return db
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ehdc-llpg/internal/audit"
+	"github.com/ehdc-llpg/internal/config"
+)
+
+// chainAudit hashes the changes recorded since the last flush into the audit chain
+func chainAudit(ctx context.Context, localDebug bool, db *sql.DB) error {
+	n, err := audit.ChainPending(ctx, localDebug, db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Chained %d entries\n", n)
+	return nil
+}
+
+// verifyAudit walks the audit chain and compares the chained tables with it, checking the
+// signed checkpoints in the database and the checkpoint directory. Checkpoints must be
+// signed by publicKey, or failing that by AUDIT_SIGNING_KEY's public key.
+func verifyAudit(ctx context.Context, localDebug bool, db *sql.DB, cfg *config.Config, publicKey string) error {
+	trusted, err := trustedAuditKey(cfg, publicKey)
+	if err != nil {
+		return err
+	}
+	exported, err := audit.ReadCheckpoints(cfg.Audit.CheckpointDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Verifying the audit chain...")
+	report, err := audit.Verify(ctx, localDebug, db, exported, trusted)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== AUDIT CHAIN (%.1fs) ===\n", report.Duration.Seconds())
+	fmt.Printf("Entries: %d (%d chained now)", report.Entries, report.Chained)
+	if report.Entries > 0 {
+		fmt.Printf(", head %d at %s (%.16s)", report.Head.Seq,
+			report.Head.RecordedAt.Format("2006-01-02 15:04:05"), report.Head.Hash)
+	}
+	fmt.Printf("\nCheckpoints: %d (%d exported to %s)\n", report.Checkpoints, len(exported), cfg.Audit.CheckpointDir)
+	if trusted == nil && report.Checkpoints > 0 {
+		fmt.Println("Warning: no trusted key; checkpoints were only checked against the key they carry (-public-key)")
+	}
+
+	if report.Intact() {
+		fmt.Println("✓ Audit chain is intact and every chained row matches it")
+		return nil
+	}
+
+	for _, kind := range audit.ChainProblemKinds {
+		if n := report.Counts[kind]; n > 0 {
+			fmt.Printf("  %-14s %d\n", kind, n)
+		}
+	}
+
+	const limit = 20
+	fmt.Printf("\n%-14s %-8s %-22s %-12s %s\n", "PROBLEM", "ENTRY", "TABLE", "ROW", "DETAIL")
+	for i, p := range report.Problems {
+		if i == limit {
+			fmt.Printf("... %d more\n", len(report.Problems)-limit)
+			break
+		}
+		seq := ""
+		if p.Seq > 0 {
+			seq = fmt.Sprintf("%d", p.Seq)
+		}
+		fmt.Printf("%-14s %-8s %-22s %-12s %s\n", p.Kind, seq, p.Table, p.RowKey, p.Detail)
+	}
+	return fmt.Errorf("audit chain verification failed")
+}
+
+// exportAuditCheckpoint signs the chain head with AUDIT_SIGNING_KEY and exports it to the
+// checkpoint directory; run it daily, and copy the files somewhere the database cannot reach
+func exportAuditCheckpoint(ctx context.Context, localDebug bool, db *sql.DB, cfg *config.Config) error {
+	if cfg.Audit.SigningKey == "" {
+		return fmt.Errorf("AUDIT_SIGNING_KEY is not set; create one with -cmd=audit-keygen")
+	}
+	key, err := audit.ParseSigningKey(cfg.Audit.SigningKey)
+	if err != nil {
+		return err
+	}
+
+	c, err := audit.CreateCheckpoint(ctx, localDebug, db, key, time.Now())
+	if err != nil {
+		return err
+	}
+	path, err := audit.WriteCheckpoint(cfg.Audit.CheckpointDir, c)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Checkpoint %s: entry %d, hash %s\n", c.Date, c.Seq, c.Hash)
+	fmt.Printf("Written to %s\n", path)
+	return nil
+}
+
+// generateAuditKey prints a new checkpoint signing key
+func generateAuditKey() error {
+	seed, public, err := audit.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Add the signing key to the configuration and keep it out of the database host's backups;")
+	fmt.Println("give the public key to whoever verifies checkpoints (-public-key).")
+	fmt.Println()
+	fmt.Printf("AUDIT_SIGNING_KEY=%s\n", seed)
+	fmt.Printf("Public key: %s\n", public)
+	return nil
+}
+
+// trustedAuditKey is the key checkpoints must be signed with; nil when none is configured
+func trustedAuditKey(cfg *config.Config, publicKey string) (ed25519.PublicKey, error) {
+	if publicKey != "" {
+		b, err := base64.StdEncoding.DecodeString(publicKey)
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("-public-key must be a base64 Ed25519 public key")
+		}
+		return ed25519.PublicKey(b), nil
+	}
+	if cfg.Audit.SigningKey == "" {
+		return nil, nil
+	}
+	key, err := audit.ParseSigningKey(cfg.Audit.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return key.Public().(ed25519.PublicKey), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// commandRuns are the commands that write matches without a run of their own. main gives
+// each one a run, so the audit chain attributes its changes and rollback-run can undo them.
+// Commands that only change source or dimension tables (standardize-addresses,
+// clean-source-data and the loaders) are not chained and cannot be rolled back; take a
+// snapshot first.
+var commandRuns = map[string]string{
+	"conservative-match":        "Conservative validation matching",
+	"conservative-only":         "Conservative validation matching",
+	"apply-corrections":         "Group consensus corrections",
+	"fuzzy-match-groups":        "Fuzzy matching of unmatched groups",
+	"fuzzy-match-individual":    "Fuzzy matching of unmatched documents",
+	"rebuild-fact-intelligent":  "Layer 1 matching and fact table rebuild",
+	"comprehensive-match":       "Comprehensive multi-layer matching",
+	"end-to-end-with-snapshots": "End-to-end matching with layer snapshots",
+	"link-planning-refs":        "Planning reference linking",
+}
+
+// startCommandRun creates the run for a command listed in commandRuns, returning 0 for
+// other commands. Runs the command creates itself are joined later and take precedence
+// in the audit chain until they complete.
+func startCommandRun(ctx context.Context, db *sql.DB, command, label string) (int64, error) {
+	notes, ok := commandRuns[command]
+	if !ok {
+		return 0, nil
+	}
+	if label == "" {
+		label = command
+	}
+	run, err := store.NewPostgres(db).CreateRun(ctx, label, "matcher-v2/"+command, notes)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("Run %d: %s (undo with: matcher rollback-run %d)\n", run.RunID, label, run.RunID)
+	return run.RunID, nil
+}
+
+// finishCommandRun records how the command's run ended; the command's counts are in its
+// own output, so the run's totals are left at zero
+func finishCommandRun(localDebug bool, db *sql.DB, runID int64, err error) {
+	if runID == 0 {
+		return
+	}
+	status := store.RunCompleted
+	switch {
+	case errors.Is(err, context.Canceled):
+		status = store.RunInterrupted
+	case err != nil:
+		status = store.RunFailed
+	}
+	if ferr := store.NewPostgres(db).CompleteRun(context.Background(), runID, status, store.RunTotals{}); ferr != nil {
+		debug.DebugOutput(localDebug, "Warning: failed to record run %d as %s: %v", runID, status, ferr)
+	}
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime"
 	"sync"
+	"time"
+
+	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/facts"
+	"github.com/ehdc-llpg/internal/interrupt"
+	"github.com/ehdc-llpg/internal/store"
 )
 
 // UniqueAddress represents a unique address for fuzzy matching with deduplication
@@ -23,31 +33,32 @@ type FuzzyMatch struct {
 	SimilarityScore float64
 }
 
-// parallelFuzzyMatchIndividualDocuments performs enhanced parallel fuzzy matching with address deduplication
-func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
-	fmt.Println("Enhanced parallel fuzzy matching with address deduplication...")
-	fmt.Println("=========================================================")
-	
-	// Auto-detect optimal worker count
-	numWorkers := getOptimalWorkerCount()
-	
-	fmt.Printf("Using %d parallel workers (detected %d CPU cores)\n", numWorkers, runtime.NumCPU())
-	
-	// Step 1: Ensure required extensions are enabled
-	_, err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm")
-	if err != nil {
-		return fmt.Errorf("failed to enable pg_trgm extension: %v", err)
-	}
-	
-	_, err = db.Exec("CREATE EXTENSION IF NOT EXISTS fuzzystrmatch")
-	if err != nil {
-		return fmt.Errorf("failed to enable fuzzystrmatch extension: %v", err)
-	}
-	
-	// Step 2: Get unique addresses that need fuzzy matching (address deduplication)
-	fmt.Println("Finding unique addresses that need fuzzy matching...")
-	
-	uniqueAddressesSQL := `
+// layer3Processor names layer3-enhanced runs in match_run_checkpoint
+const layer3Processor = "layer3-enhanced"
+
+// layer3Config is the configuration a layer3-enhanced run was started with
+type layer3Config struct {
+	BatchSize int `json:"batch_size"` // addresses per worker batch
+	Workers   int `json:"workers"`
+}
+
+// layer3Stats are the totals of a layer3-enhanced run over every leg
+type layer3Stats struct {
+	Legs               int           `json:"legs"`
+	AddressesProcessed int           `json:"addresses_processed"`
+	Matches            int           `json:"matches"`
+	ProcessingTime     time.Duration `json:"processing_time"`
+}
+
+// layer3Batch is what a worker reports for one batch: the addresses it tried and matched
+type layer3Batch struct {
+	attempted []string
+	matches   int
+}
+
+// uniqueAddressesSQL selects each distinct unmatched raw address once with its document
+// count, highest impact first
+const uniqueAddressesSQL = `
 	WITH unmatched_addresses AS (
 		SELECT DISTINCT
 			oa.raw_address,
@@ -70,14 +81,95 @@ func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
 		sample_document_id
 	FROM unmatched_addresses
 	ORDER BY document_count DESC, raw_address  -- Process high-impact addresses first
-	`
+`
+
+// parallelFuzzyMatchIndividualDocuments performs enhanced parallel fuzzy matching with address
+// deduplication as a new checkpointed run
+func parallelFuzzyMatchIndividualDocuments(ctx context.Context, localDebug bool, db *sql.DB) error {
+	stores := store.NewPostgresStores(db)
+	cfg := layer3Config{BatchSize: 25, Workers: getOptimalWorkerCount()}
+
+	run, err := stores.Matches.CreateRun(ctx, layer3Processor, version, "Enhanced parallel fuzzy matching")
+	if err != nil {
+		return err
+	}
+	config, _ := json.Marshal(cfg)
+	if err := stores.Checkpoints.CreateCheckpoint(ctx, run.RunID, layer3Processor, config); err != nil {
+		return err
+	}
+
+	return runLayer3Enhanced(ctx, localDebug, db, stores, run.RunID, cfg, &layer3Stats{Legs: 1}, nil)
+}
+
+// resumeLayer3Enhanced continues a stopped layer3-enhanced run, skipping every address an
+// earlier leg tried
+func resumeLayer3Enhanced(ctx context.Context, localDebug bool, db *sql.DB, runID int64) error {
+	stores := store.NewPostgresStores(db)
+
+	cp, err := stores.Checkpoints.ReopenRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+	var cfg layer3Config
+	stats := &layer3Stats{}
+	if err := json.Unmarshal(cp.Config, &cfg); err != nil {
+		return fmt.Errorf("failed to decode config of run %d: %w", runID, err)
+	}
+	if err := json.Unmarshal(cp.Stats, stats); err != nil {
+		return fmt.Errorf("failed to decode statistics of run %d: %w", runID, err)
+	}
+	stats.Legs++
+
+	processed, err := stores.Checkpoints.ProcessedItems(ctx, runID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Resuming run %d: %d addresses already tried, %d matched\n",
+		runID, len(processed), stats.Matches)
+
+	return runLayer3Enhanced(ctx, localDebug, db, stores, runID, cfg, stats, processed)
+}
+
+// runLayer3Enhanced runs a leg and records how the run ended
+func runLayer3Enhanced(ctx context.Context, localDebug bool, db *sql.DB, stores store.Stores, runID int64, cfg layer3Config, stats *layer3Stats, processed map[string]bool) error {
+	err := matchLayer3(ctx, localDebug, db, stores, runID, cfg, stats, processed)
+	return finishLayer3Run(stores, runID, stats, err)
+}
+
+// matchLayer3 matches the unmatched addresses not in processed with cfg.Workers workers.
+// Each finished batch is added to the run's processed set with the running totals, so a
+// resumed run repeats no finished batch.
+func matchLayer3(ctx context.Context, localDebug bool, db *sql.DB, stores store.Stores, runID int64, cfg layer3Config, stats *layer3Stats, processed map[string]bool) error {
+	startTime := time.Now()
+	priorTime := stats.ProcessingTime
+
+	fmt.Println("Enhanced parallel fuzzy matching with address deduplication...")
+	fmt.Println("=========================================================")
+	
+	fmt.Printf("Run %d (leg %d) using %d parallel workers (detected %d CPU cores)\n",
+		runID, stats.Legs, cfg.Workers, runtime.NumCPU())
 	
-	rows, err := db.Query(uniqueAddressesSQL)
+	// Step 1: Ensure required extensions are enabled
+	_, err := db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS pg_trgm")
+	if err != nil {
+		return fmt.Errorf("failed to enable pg_trgm extension: %v", err)
+	}
+	
+	_, err = db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS fuzzystrmatch")
+	if err != nil {
+		return fmt.Errorf("failed to enable fuzzystrmatch extension: %v", err)
+	}
+	
+	// Step 2: Get unique addresses that need fuzzy matching (address deduplication)
+	fmt.Println("Finding unique addresses that need fuzzy matching...")
+	
+	rows, err := db.QueryContext(ctx, uniqueAddressesSQL)
 	if err != nil {
 		return fmt.Errorf("failed to find unique addresses: %v", err)
 	}
 	defer rows.Close()
 	
+	// Addresses an earlier leg already tried stay unmatched but are not tried again
 	var uniqueAddresses []UniqueAddress
 	for rows.Next() {
 		var addr UniqueAddress
@@ -85,7 +177,9 @@ func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
 		if err != nil {
 			return fmt.Errorf("failed to scan unique address: %v", err)
 		}
-		uniqueAddresses = append(uniqueAddresses, addr)
+		if !processed[addr.RawAddress] {
+			uniqueAddresses = append(uniqueAddresses, addr)
+		}
 	}
 	
 	totalDocumentsAffected := 0
@@ -102,7 +196,7 @@ func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
 	}
 	
 	// Step 3: Process unique addresses in parallel with workers
-	batchSize := 25  // Addresses per batch
+	batchSize, numWorkers := cfg.BatchSize, cfg.Workers
 	numBatches := (len(uniqueAddresses) + batchSize - 1) / batchSize
 	
 	fmt.Printf("Processing %d batches of %d addresses each with %d workers\n", 
@@ -110,9 +204,10 @@ func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
 	
 	// Channel for work distribution
 	addressBatches := make(chan []UniqueAddress, numBatches)
-	results := make(chan int, numBatches)  // Count of successful matches per batch
+	results := make(chan layer3Batch, numBatches)
 	
-	// Worker pool
+	// Worker pool. Each address is one atomic UPDATE, so on cancellation workers stop
+	// taking addresses and the statement in flight either commits or rolls back.
 	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
@@ -120,19 +215,16 @@ func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
 			defer wg.Done()
 			
 			// Each worker gets its own database connection
-			workerDB, err := connectDB()
+			workerDB, err := database.Open(config.Current().Database)
 			if err != nil {
 				fmt.Printf("Worker %d failed to connect to database: %v\n", workerID, err)
 				return
 			}
 			defer workerDB.Close()
 			
-			successCount := 0
 			for batch := range addressBatches {
-				batchSuccess := processFuzzyMatchBatch(workerID, batch, workerDB, localDebug)
-				successCount += batchSuccess
+				results <- processFuzzyMatchBatch(ctx, workerID, batch, workerDB, localDebug)
 			}
-			results <- successCount
 		}(i)
 	}
 	
@@ -143,8 +235,12 @@ func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
 			if end > len(uniqueAddresses) {
 				end = len(uniqueAddresses)
 			}
-			batch := uniqueAddresses[i:end]
-			addressBatches <- batch
+			select {
+			case addressBatches <- uniqueAddresses[i:end]:
+			case <-ctx.Done():
+				close(addressBatches)
+				return
+			}
 		}
 		close(addressBatches)
 	}()
@@ -155,34 +251,98 @@ func parallelFuzzyMatchIndividualDocuments(localDebug bool, db *sql.DB) error {
 		close(results)
 	}()
 	
-	// Collect results
-	totalMatches := 0
+	// Collect results, checkpointing each batch. The writes use their own context so the
+	// batches finished after an interrupt are still recorded.
+	var checkpointErr error
 	processedBatches := 0
-	for successCount := range results {
-		totalMatches += successCount
+	for batch := range results {
+		stats.AddressesProcessed += len(batch.attempted)
+		stats.Matches += batch.matches
+		stats.ProcessingTime = priorTime + time.Since(startTime)
 		processedBatches++
+
+		if checkpointErr == nil {
+			checkpointErr = saveLayer3Checkpoint(stores, runID, batch.attempted, stats)
+		}
+
 		if processedBatches%5 == 0 || localDebug {
 			fmt.Printf("Processed %d/%d batches, %d successful fuzzy matches so far\n", 
-				processedBatches, numBatches, totalMatches)
+				processedBatches, numBatches, stats.Matches)
 		}
 	}
 	
+	if err := ctx.Err(); err != nil {
+		fmt.Printf("\nEnhanced parallel fuzzy matching interrupted: %d successful fuzzy matches before stopping\n", stats.Matches)
+		fmt.Printf("Continue with: ./matcher-v2 -cmd=resume -run-id=%d\n", runID)
+		return err
+	}
+	if checkpointErr != nil {
+		return fmt.Errorf("failed to checkpoint run %d: %w", runID, checkpointErr)
+	}
+
 	fmt.Printf("\n✓ Enhanced parallel fuzzy matching completed!\n")
-	fmt.Printf("  Processed %d unique addresses\n", len(uniqueAddresses))
-	fmt.Printf("  Found %d successful fuzzy matches\n", totalMatches)
+	fmt.Printf("  Run %d after %d legs\n", runID, stats.Legs)
+	fmt.Printf("  Processed %d unique addresses\n", stats.AddressesProcessed)
+	fmt.Printf("  Found %d successful fuzzy matches\n", stats.Matches)
 	fmt.Printf("  Used %d parallel workers for optimal performance\n", numWorkers)
 	
 	return nil
 }
 
-// processFuzzyMatchBatch processes a batch of unique addresses for fuzzy matching
-func processFuzzyMatchBatch(workerID int, batch []UniqueAddress, db *sql.DB, localDebug bool) int {
-	successCount := 0
+// saveLayer3Checkpoint adds a finished batch to the run's processed set with the totals
+func saveLayer3Checkpoint(stores store.Stores, runID int64, attempted []string, stats *layer3Stats) error {
+	ctx, cancel := interrupt.Detached()
+	defer cancel()
+
+	if err := stores.Checkpoints.MarkProcessed(ctx, runID, attempted); err != nil {
+		return err
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return stores.Checkpoints.SaveCheckpoint(ctx, runID, 0, data)
+}
+
+// finishLayer3Run records how the run ended and returns runErr
+func finishLayer3Run(stores store.Stores, runID int64, stats *layer3Stats, runErr error) error {
+	ctx, cancel := interrupt.Detached()
+	defer cancel()
+
+	status := store.RunCompleted
+	switch {
+	case errors.Is(runErr, context.Canceled):
+		status = store.RunInterrupted
+	case runErr != nil:
+		status = store.RunFailed
+	}
+
+	err := stores.Matches.CompleteRun(ctx, runID, status, store.RunTotals{
+		Processed: stats.AddressesProcessed,
+		Accepted:  stats.Matches,
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to record run %d as %s: %v\n", runID, status, err)
+	}
+	return runErr
+}
+
+// processFuzzyMatchBatch processes a batch of unique addresses for fuzzy matching. An
+// address cut short by cancellation is not reported, so a resumed run tries it again.
+func processFuzzyMatchBatch(ctx context.Context, workerID int, batch []UniqueAddress, db *sql.DB, localDebug bool) layer3Batch {
+	var result layer3Batch
 	
 	for _, addr := range batch {
-		matched := processIndividualFuzzyMatch(addr, db, localDebug)
+		if ctx.Err() != nil {
+			break
+		}
+		matched := processIndividualFuzzyMatch(ctx, addr, db, localDebug)
+		if ctx.Err() != nil {
+			break
+		}
+		result.attempted = append(result.attempted, addr.RawAddress)
 		if matched {
-			successCount++
+			result.matches++
 		}
 		
 		// Progress indicator for debugging
@@ -192,11 +352,14 @@ func processFuzzyMatchBatch(workerID int, batch []UniqueAddress, db *sql.DB, loc
 		}
 	}
 	
-	return successCount
+	return result
 }
 
 // processIndividualFuzzyMatch processes a single unique address and updates all related documents
-func processIndividualFuzzyMatch(addr UniqueAddress, db *sql.DB, localDebug bool) bool {
+func processIndividualFuzzyMatch(ctx context.Context, addr UniqueAddress, db *sql.DB, localDebug bool) bool {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	// Perform fuzzy matching using trigram similarity
 	fuzzyMatchSQL := `
 	SELECT 
@@ -211,7 +374,7 @@ func processIndividualFuzzyMatch(addr UniqueAddress, db *sql.DB, localDebug bool
 	LIMIT 3
 	`
 	
-	rows, err := db.Query(fuzzyMatchSQL, addr.RawAddress)
+	rows, err := db.QueryContext(ctx, fuzzyMatchSQL, addr.RawAddress)
 	if err != nil {
 		if localDebug {
 			fmt.Printf("Error querying fuzzy matches for '%s': %v\n", addr.RawAddress, err)
@@ -243,23 +406,13 @@ func processIndividualFuzzyMatch(addr UniqueAddress, db *sql.DB, localDebug bool
 		return false
 	}
 	
-	// Update ALL documents with this raw address (key deduplication feature)
-	updateSQL := `
-	UPDATE fact_documents_lean 
-	SET matched_address_id = $1,
-		matched_location_id = $2,
-		match_method_id = 36,  -- "Enhanced Parallel Fuzzy Match"
-		match_confidence_score = $3,
-		match_decision_id = 2,  -- "Accepted"
-		updated_at = CURRENT_TIMESTAMP
-	FROM dim_original_address oa
-	WHERE fact_documents_lean.original_address_id = oa.original_address_id
-	  AND oa.raw_address = $4
-	  AND fact_documents_lean.matched_address_id IS NULL  -- Only update unmatched
-	`
-	
-	result, err := db.Exec(updateSQL, bestMatch.AddressID, bestMatch.LocationID, 
-		bestMatch.SimilarityScore, addr.RawAddress)
+	// Match ALL unmatched documents with this raw address (key deduplication feature)
+	rowsUpdated, err := facts.SaveMatch(ctx, db, facts.LayerMatch{
+		AddressID:  bestMatch.AddressID,
+		MethodID:   36, // "Enhanced Parallel Fuzzy Match"
+		Confidence: bestMatch.SimilarityScore,
+		MatchedBy:  "system_layer3",
+	}, facts.ByRawAddress, addr.RawAddress)
 	if err != nil {
 		if localDebug {
 			fmt.Printf("Error updating documents for '%s': %v\n", addr.RawAddress, err)
@@ -267,7 +420,6 @@ func processIndividualFuzzyMatch(addr UniqueAddress, db *sql.DB, localDebug bool
 		return false
 	}
 	
-	rowsUpdated, _ := result.RowsAffected()
 	
 	if localDebug && rowsUpdated > 0 {
 		fmt.Printf("✓ Matched '%s' → '%s' (%.3f similarity, %d documents updated)\n",
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ehdc-llpg/internal/facts"
+)
+
+// factApplyBatch is how many change log entries fact-apply commits at a time
+const factApplyBatch = 1000
+
+// applyFactChanges rebuilds the fact rows of documents logged in fact_change_log. With
+// watch it repeats at that interval until interrupted, running a consistency check with
+// repair every checkEvery.
+func applyFactChanges(ctx context.Context, localDebug bool, db *sql.DB, watch, checkEvery time.Duration) error {
+	lastCheck := time.Now()
+	for {
+		stats, err := facts.Apply(ctx, localDebug, db, factApplyBatch)
+		if stats != nil && (stats.Changes > 0 || watch <= 0) {
+			fmt.Printf("[%s] Applied %d changes: %d documents, %d fact rows updated, %d removed\n",
+				time.Now().Format("15:04:05"), stats.Changes, stats.Documents, stats.Updated, stats.Removed)
+		}
+		if err != nil {
+			return err
+		}
+		if watch <= 0 {
+			return nil
+		}
+
+		if checkEvery > 0 && time.Since(lastCheck) >= checkEvery {
+			lastCheck = time.Now()
+			report, err := facts.Check(ctx, localDebug, db, true)
+			if err != nil {
+				return err
+			}
+			printFactCheck(report)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watch):
+		}
+	}
+}
+
+// checkFactTable compares the fact table with a full rebuild, optionally repairing it
+func checkFactTable(ctx context.Context, localDebug bool, db *sql.DB, repair bool) error {
+	fmt.Println("Comparing fact_documents_lean with a full rebuild...")
+	report, err := facts.Check(ctx, localDebug, db, repair)
+	if err != nil {
+		return err
+	}
+	printFactCheck(report)
+
+	if !report.Consistent() && report.Repaired == nil {
+		fmt.Println("\nRun with -repair to rebuild the differing rows, or -cmd=rebuild-fact to rebuild them all")
+	}
+	return nil
+}
+
+// printFactCheck prints a consistency check with a sample of the differing documents
+func printFactCheck(r *facts.CheckReport) {
+	fmt.Printf("\n=== FACT TABLE CHECK %d (%.1fs) ===\n", r.CheckID, r.Duration.Seconds())
+	fmt.Printf("Rows: %d in the table, %d from a rebuild; %d documents with pending changes not compared\n",
+		r.Actual, r.Expected, r.Pending)
+
+	if r.Consistent() {
+		fmt.Println("✓ Fact table matches a full rebuild")
+		return
+	}
+
+	for _, kind := range facts.DriftKinds {
+		fmt.Printf("  %-11s %d\n", kind, r.Counts[kind])
+	}
+
+	const limit = 10
+	fmt.Printf("\n%-12s %-11s %-22s %s\n", "DOCUMENT", "DRIFT", "ADDRESS (REBUILD)", "ADDRESS (TABLE)")
+	for i, d := range r.Drifts {
+		if i == limit {
+			fmt.Printf("... %d more (a sample is kept in fact_consistency_check)\n", len(r.Drifts)-limit)
+			break
+		}
+		fmt.Printf("%-12d %-11s %-22s %s\n", d.DocumentID, d.Kind, describeFactRow(d.Expected), describeFactRow(d.Actual))
+	}
+
+	if r.Repaired != nil {
+		fmt.Printf("\nRepaired %d documents: %d fact rows updated, %d removed\n",
+			r.Repaired.Documents, r.Repaired.Updated, r.Repaired.Removed)
+	}
+}
+
+// describeFactRow summarises the match side of a compared row
+func describeFactRow(r facts.Row) string {
+	switch {
+	case !r.Present:
+		return "(no row)"
+	case r.AddressID == 0:
+		return "unmatched"
+	case r.Confidence == nil:
+		return fmt.Sprintf("%d", r.AddressID)
+	}
+	return fmt.Sprintf("%d @ %.4f", r.AddressID, *r.Confidence)
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -20,15 +22,26 @@ import (
 
 	"github.com/ehdc-llpg/internal/audit"
 	"github.com/ehdc-llpg/internal/config"
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
 	"github.com/ehdc-llpg/internal/embeddings"
 	"github.com/ehdc-llpg/internal/etl"
+	"github.com/ehdc-llpg/internal/facts"
+	"github.com/ehdc-llpg/internal/interrupt"
 	"github.com/ehdc-llpg/internal/llpg"
+	"github.com/ehdc-llpg/internal/planning"
 	"github.com/ehdc-llpg/internal/match"
+	"github.com/ehdc-llpg/internal/matcher"
+	"github.com/ehdc-llpg/internal/migrate"
 	"github.com/ehdc-llpg/internal/phonetics"
+	"github.com/ehdc-llpg/internal/profile"
+	"github.com/ehdc-llpg/internal/review"
+	"github.com/ehdc-llpg/internal/snapshot"
+	"github.com/ehdc-llpg/internal/store"
 	"github.com/ehdc-llpg/internal/symspell"
 	"github.com/ehdc-llpg/internal/validation"
 	"github.com/ehdc-llpg/internal/vector"
+	"github.com/ehdc-llpg/migrations"
 )
 
 const version = "2.0.0-proper-algorithm"
@@ -50,17 +63,32 @@ func getOptimalWorkerCount() int {
 
 func main() {
 	var (
-		command     = flag.String("cmd", "", "Command to run: setup-db, load-llpg, load-os-uprn, load-sources, validate-uprns, expand-llpg-ranges, setup-vector, match-batch, match-single, conservative-match, apply-corrections, fuzzy-match-groups, fuzzy-match-individual, layer3-parallel-groups, layer3-parallel-docs, layer3-parallel-combined, layer3-enhanced, setup-spatial-tables, build-spatial-parallel, build-road-postcode-parallel, build-road-parallel, standardize-addresses, comprehensive-match, llm-fix-addresses, rebuild-fact, validate-integrity, stats")
+		command     = flag.String("cmd", "", "Command to run: config-show, setup-db, migrate-status, migrate-up, migrate-down, migrate-baseline, load-llpg, load-os-uprn, load-sources, validate-uprns, expand-llpg-ranges, find-llpg-range, link-planning-refs, validate-coordinates, profile, setup-vector, match-batch, match-single, conservative-match, apply-corrections, fuzzy-match-groups, fuzzy-match-individual, layer3-parallel-groups, layer3-parallel-docs, layer3-parallel-combined, layer3-enhanced, resume, queue-enqueue, queue-work, queue-status, fact-apply, fact-check, audit-chain, verify-audit, audit-checkpoint, audit-keygen, setup-spatial-tables, build-spatial-parallel, build-road-postcode-parallel, build-road-parallel, standardize-addresses, comprehensive-match, llm-fix-addresses, rebuild-fact, validate-integrity, stats")
 		llpgFile    = flag.String("llpg", "", "Path to LLPG CSV file")
 		osUprnFile  = flag.String("os-uprn", "", "Path to OS Open UPRN CSV file")
 		sourceFiles = flag.String("sources", "", "Comma-separated paths to source CSV files (type:path,type:path)")
-		_  = flag.String("source-type", "", "Source type for single file load (decision, land_charge, enforcement, agreement)") // Currently unused
+		sourceType  = flag.String("source-type", "", "Source type whose mapping identifies columns for profile (any type with a mapping in internal/etl/mappings)")
+		csvFile     = flag.String("csv", "", "Path to a CSV file to profile")
+		outputFile  = flag.String("output", "", "Path for the profile JSON report (default <csv>.profile.json)")
 		address     = flag.String("address", "", "Single address to match")
 		runLabel    = flag.String("run-label", "", "Label for matching run")
 		debug       = flag.Bool("debug", false, "Enable debug output")
-		configFile  = flag.String("config", ".env", "Path to configuration file")
+		configFile  = flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
 		batchSize   = flag.Int("batch-size", 50000, "Batch size for OS UPRN loading")
+		resume      = flag.Bool("resume", false, "Resume an interrupted load-llpg, load-os-uprn or load-sources load from its last committed chunk")
+		target      = flag.String("target", "", "Migration version to migrate up/down/baseline to, e.g. 044_mapped_source_types")
+		dryRun      = flag.Bool("dry-run", false, "Print the migration plan without applying it")
+		runID       = flag.Int64("run-id", 0, "Run to resume, work on or report (see match_run)")
+		queueKind   = flag.String("queue-kind", "documents", "What queue-enqueue queues: documents or addresses")
+		chunkSize   = flag.Int("chunk-size", 200, "Items per work queue chunk")
+		workers     = flag.Int("workers", 0, "Worker goroutines for queue-work (default one per core, less one)")
+		watch       = flag.Duration("watch", 0, "Repeat queue-status until the queue drains, or fact-apply until interrupted, at this interval")
+		checkEvery  = flag.Duration("check-interval", 24*time.Hour, "How often fact-apply -watch runs a consistency check (0 to never)")
+		repair      = flag.Bool("repair", false, "Rebuild the fact rows fact-check finds out of date")
+		publicKey   = flag.String("public-key", "", "Base64 Ed25519 key verify-audit requires checkpoints to be signed with (default AUDIT_SIGNING_KEY's)")
 	)
+	var overrides config.Overrides
+	flag.Var(&overrides, "set", "Override a configuration key, e.g. -set DB_HOST=localhost (repeatable)")
 	flag.Parse()
 
 	if *command == "" {
@@ -71,21 +99,36 @@ func main() {
 	fmt.Printf("EHDC LLPG Address Matcher %s\n", version)
 	fmt.Printf("Implementing ADDRESS_MATCHING_ALGORITHM.md specification\n\n")
 
-	// Load configuration
-	err := config.LoadConfig(*configFile)
+	// Load configuration: flags > environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile, Flags: overrides})
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.Use(cfg)
+
+	if *command == "config-show" {
+		showConfig(cfg)
+		return
+	}
+	if *command == "audit-keygen" {
+		if err := generateAuditKey(); err != nil {
+			log.Fatalf("Command failed: %v", err)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
 	// Connect to database
-	db, err := connectDB()
+	db, err := database.Open(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
 	// Initialize SymSpell spelling correction if enabled
-	if symspell.LoadConfigFromEnv().Enabled {
+	if symspell.LoadConfig().Enabled {
 		fmt.Println("Initializing SymSpell spelling correction...")
 		startTime := time.Now()
 		if err := symspell.InitGlobalCorrector(db); err != nil {
@@ -97,26 +140,51 @@ func main() {
 		}
 	}
 
+	// Ctrl-C or SIGTERM cancels ctx: loads and matching stop after their current unit
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+
+	commandRunID, err := startCommandRun(ctx, db, *command, *runLabel)
+	if err != nil {
+		log.Fatalf("Failed to start run: %v", err)
+	}
+
 	// Execute command
 	switch *command {
 	case "setup-db":
 		err = setupDatabase(*debug, db)
+	case "migrate-status":
+		err = migrationStatus(*debug, db)
+	case "migrate-up":
+		err = runMigrations(*debug, db, "up", *target, *dryRun)
+	case "migrate-down":
+		err = runMigrations(*debug, db, "down", *target, *dryRun)
+	case "migrate-baseline":
+		err = runMigrations(*debug, db, "baseline", *target, *dryRun)
 	case "load-llpg":
-		err = loadLLPG(*debug, db, *llpgFile)
+		err = loadLLPG(ctx, *debug, db, *llpgFile, *resume)
 	case "load-os-uprn":
-		err = loadOSUPRN(*debug, db, *osUprnFile, *batchSize)
+		err = loadOSUPRN(ctx, *debug, db, *osUprnFile, *batchSize, *resume)
 	case "load-sources":
-		err = loadSourceDocuments(*debug, db, *sourceFiles)
+		err = loadSourceDocuments(ctx, *debug, db, *sourceFiles, *resume)
 	case "validate-uprns":
-		err = validateUPRNs(*debug, db)
+		err = validateUPRNs(ctx, *debug, db)
 	case "expand-llpg-ranges":
 		err = expandLLPGRanges(*debug, db)
+	case "find-llpg-range":
+		err = findLLPGRange(*debug, db, *address)
+	case "link-planning-refs":
+		err = linkPlanningReferences(ctx, *debug, db, cfg)
+	case "validate-coordinates":
+		err = validateSourceCoordinates(ctx, *debug, db)
+	case "profile":
+		err = profileCSV(*debug, db, *csvFile, *sourceType, *outputFile)
 	case "setup-vector":
 		err = setupVectorDB(*debug, db)
 	case "match-batch":
-		err = runBatchMatching(*debug, db, *runLabel)
+		err = runBatchMatching(ctx, *debug, db, *runLabel)
 	case "match-single":
-		err = runSingleMatch(*debug, db, *address)
+		err = runSingleMatch(ctx, *debug, db, *address)
 	case "conservative-match":
 		err = runConservativeMatching(*debug, db, *runLabel)
 	case "apply-corrections":
@@ -128,9 +196,9 @@ func main() {
 	case "standardize-addresses":
 		err = standardizeSourceAddresses(*debug, db)
 	case "comprehensive-match":
-		err = runComprehensiveMatching(*debug, db)
+		err = runComprehensiveMatching(ctx, *debug, db, commandRunID)
 	case "end-to-end-with-snapshots":
-		err = runEndToEndWithSnapshots(*debug, db)
+		err = runEndToEndWithSnapshots(ctx, *debug, db, commandRunID)
 	case "conservative-only":
 		err = runConservativeMatching(*debug, db, "conservative-test")
 	case "clean-source-data":
@@ -138,9 +206,9 @@ func main() {
 	case "llm-fix-addresses":
 		err = llmFixLowConfidenceAddresses(*debug, db)
 	case "rebuild-fact":
-		err = rebuildFactTable(*debug, db)
+		err = rebuildFactTable(ctx, *debug, db)
 	case "rebuild-fact-intelligent":
-		err = rebuildFactTableIntelligent(*debug, db)
+		err = rebuildFactTableIntelligent(ctx, *debug, db, commandRunID)
 	// case "rebuild-fact-simple":
 	//	err = rebuildFactTableSimple(*debug, db)
 	// case "layer2-only":
@@ -156,7 +224,25 @@ func main() {
 	// case "layer3-parallel-combined":
 	//	err = runParallelLayer3Combined(*debug, db)
 	case "layer3-enhanced":
-		err = parallelFuzzyMatchIndividualDocuments(*debug, db)
+		err = parallelFuzzyMatchIndividualDocuments(ctx, *debug, db)
+	case "resume":
+		err = resumeRun(ctx, *debug, db, *runID)
+	case "queue-enqueue":
+		err = enqueueRun(ctx, *debug, db, *queueKind, *runLabel, *chunkSize)
+	case "queue-work":
+		err = runQueueWorkers(ctx, *debug, db, *runID, *workers)
+	case "queue-status":
+		err = queueStatus(ctx, *debug, db, *runID, *watch)
+	case "fact-apply":
+		err = applyFactChanges(ctx, *debug, db, *watch, *checkEvery)
+	case "fact-check":
+		err = checkFactTable(ctx, *debug, db, *repair)
+	case "audit-chain":
+		err = chainAudit(ctx, *debug, db)
+	case "verify-audit":
+		err = verifyAudit(ctx, *debug, db, cfg, *publicKey)
+	case "audit-checkpoint":
+		err = exportAuditCheckpoint(ctx, *debug, db, cfg)
 	case "setup-spatial-tables":
 		err = setupSpatialTables(*debug, db)
 	case "build-spatial-parallel":
@@ -168,13 +254,20 @@ func main() {
 	case "validate-integrity":
 		err = validateDataIntegrity(*debug, db)
 	case "stats":
-		err = showStatistics(*debug, db)
+		err = showStatistics(ctx, *debug, db)
 	default:
 		fmt.Printf("Unknown command: %s\n", *command)
 		printUsage()
 		os.Exit(1)
 	}
+	finishCommandRun(*debug, db, commandRunID, err)
 
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Command interrupted: work committed before the signal is kept")
+		stop()
+		db.Close()
+		os.Exit(130)
+	}
 	if err != nil {
 		log.Fatalf("Command failed: %v", err)
 	}
@@ -182,22 +275,74 @@ func main() {
 	fmt.Println("Command completed successfully!")
 }
 
+// resumeRun continues a checkpointed run with whatever started it
+func resumeRun(ctx context.Context, localDebug bool, db *sql.DB, runID int64) error {
+	if runID == 0 {
+		return fmt.Errorf("-run-id is required")
+	}
+
+	cp, err := store.NewPostgres(db).Checkpoint(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if cp != nil && cp.Processor == layer3Processor {
+		return resumeLayer3Enhanced(ctx, localDebug, db, runID)
+	}
+
+	stats, err := matcher.ResumeRun(ctx, localDebug, db, runID)
+	if stats == nil {
+		return err
+	}
+	fmt.Printf("Run %d after %d legs: %d processed, %d auto-accepted, %d need review, %d no match, %d errors (%v)\n",
+		stats.RunID, stats.Legs, stats.ProcessedCount, stats.AutoAcceptCount, stats.NeedsReviewCount,
+		stats.NoMatchCount, stats.ErrorCount, stats.ProcessingTime)
+	return err
+}
+
 func printUsage() {
 	fmt.Println("Usage:")
+	fmt.Println("  Show the resolved configuration (flags > env > file > defaults, secrets redacted):")
+	fmt.Println("    ./matcher-v2 -cmd=config-show -set DB_HOST=localhost")
+	fmt.Println()
 	fmt.Println("  Setup database schema:")
 	fmt.Println("    ./matcher-v2 -cmd=setup-db")
 	fmt.Println()
+	fmt.Println("  Schema migrations (applied in order, recorded in schema_migrations):")
+	fmt.Println("    ./matcher-v2 -cmd=migrate-status")
+	fmt.Println("    ./matcher-v2 -cmd=migrate-up -dry-run")
+	fmt.Println("    ./matcher-v2 -cmd=migrate-down -target=046_source_coordinate_checks")
+	fmt.Println("    ./matcher-v2 -cmd=migrate-baseline -target=044_mapped_source_types   (existing databases)")
+	fmt.Println()
 	fmt.Println("  Load EHDC LLPG data (71K records):")
 	fmt.Println("    ./matcher-v2 -cmd=load-llpg -llpg=llpg_docs/ehdc_llpg_20250710.csv")
+	fmt.Println("    ./matcher-v2 -cmd=load-llpg -llpg=llpg_docs/ehdc_llpg_20250710.csv -resume")
 	fmt.Println()
 	fmt.Println("  Load OS Open UPRN data (41M records):")
 	fmt.Println("    ./matcher-v2 -cmd=load-os-uprn -os-uprn=llpg_docs/osopenuprn_202507.csv -batch-size=100000")
+	fmt.Println("    ./matcher-v2 -cmd=load-os-uprn -os-uprn=llpg_docs/osopenuprn_202507.csv -resume")
 	fmt.Println()
 	fmt.Println("  Validate legacy UPRNs:")
 	fmt.Println("    ./matcher-v2 -cmd=validate-uprns")
 	fmt.Println()
 	fmt.Println("  Load source documents:")
 	fmt.Println("    ./matcher-v2 -cmd=load-sources -sources=decision:/path/to/decisions.csv,land_charge:/path/to/charges.csv")
+	fmt.Println("    Source types are defined by mapping files (internal/etl/mappings or SOURCE_MAPPINGS_DIR):")
+	fmt.Printf("    %s\n", strings.Join(etl.ListSourceMappings(), ", "))
+	fmt.Println()
+	fmt.Println("  Expand LLPG range addresses (numbers, flats, units, odd/even):")
+	fmt.Println("    ./matcher-v2 -cmd=expand-llpg-ranges")
+	fmt.Println()
+	fmt.Println("  Find the LLPG range containing a single-number address:")
+	fmt.Println("    ./matcher-v2 -cmd=find-llpg-range -address=\"Unit 4 Mill Lane Industrial Estate, Alton\"")
+	fmt.Println()
+	fmt.Println("  Locate microfiche records via decision notices with the same planning reference:")
+	fmt.Println("    ./matcher-v2 -cmd=link-planning-refs")
+	fmt.Println()
+	fmt.Println("  Check and repair source coordinates (swapped, truncated, lat/lng, placeholders):")
+	fmt.Println("    ./matcher-v2 -cmd=validate-coordinates")
+	fmt.Println()
+	fmt.Println("  Profile a source CSV before loading (console and JSON report):")
+	fmt.Println("    ./matcher-v2 -cmd=profile -csv=source_docs/decision_notices.csv -source-type=decision")
 	fmt.Println()
 	fmt.Println("  Setup vector database:")
 	fmt.Println("    ./matcher-v2 -cmd=setup-vector")
@@ -229,6 +374,14 @@ func printUsage() {
 	fmt.Println("  Run enhanced Layer 3 with address deduplication and auto-scaling:")
 	fmt.Println("    ./matcher-v2 -cmd=layer3-enhanced")
 	fmt.Println()
+	fmt.Println("  Resume an interrupted or failed run (layer3-enhanced or a batch processor run):")
+	fmt.Println("    ./matcher-v2 -cmd=resume -run-id=42")
+	fmt.Println()
+	fmt.Println("  Spread a re-match across servers with the work queue:")
+	fmt.Println("    ./matcher-v2 -cmd=queue-enqueue -queue-kind=documents")
+	fmt.Println("    ./matcher-v2 -cmd=queue-work -run-id=42 -workers=8      # on each server")
+	fmt.Println("    ./matcher-v2 -cmd=queue-status -run-id=42 -watch=30s    # per-worker throughput")
+	fmt.Println()
 	fmt.Println("  Setup spatial tables for Layer 4 preprocessing:")
 	fmt.Println("    ./matcher-v2 -cmd=setup-spatial-tables")
 	fmt.Println()
@@ -253,6 +406,17 @@ func printUsage() {
 	fmt.Println("  Rebuild fact table with corrections:")
 	fmt.Println("    ./matcher-v2 -cmd=rebuild-fact")
 	fmt.Println()
+	fmt.Println("  Keep the fact table current between rebuilds:")
+	fmt.Println("    ./matcher-v2 -cmd=fact-apply                        # apply logged changes once")
+	fmt.Println("    ./matcher-v2 -cmd=fact-apply -watch=1m              # keep applying; daily consistency check")
+	fmt.Println("    ./matcher-v2 -cmd=fact-check -repair                # compare with a full rebuild")
+	fmt.Println()
+	fmt.Println("  Verify and checkpoint the tamper-evident audit chain:")
+	fmt.Println("    ./matcher-v2 -cmd=audit-keygen                      # create AUDIT_SIGNING_KEY")
+	fmt.Println("    ./matcher-v2 -cmd=audit-chain                       # chain recorded changes (run every few minutes)")
+	fmt.Println("    ./matcher-v2 -cmd=audit-checkpoint                  # sign today's chain head (run daily)")
+	fmt.Println("    ./matcher-v2 -cmd=verify-audit                      # detect gaps, edits and bad checkpoints")
+	fmt.Println()
 	fmt.Println("  Validate data integrity:")
 	fmt.Println("    ./matcher-v2 -cmd=validate-integrity")
 	fmt.Println()
@@ -263,35 +427,17 @@ func printUsage() {
 	fmt.Println("  -debug          Enable detailed debug output")
 	fmt.Println("  -config         Path to configuration file (default: .env)")
 	fmt.Println("  -batch-size     Batch size for large data loads (default: 50000)")
+	fmt.Println("  -target         Migration version for migrate-up/down/baseline")
+	fmt.Println("  -dry-run        Print the migration plan without applying it")
 }
 
-func connectDB() (*sql.DB, error) {
-	host := config.GetEnv("DB_HOST", "")
-	port := config.GetEnv("DB_PORT", "")
-	user := config.GetEnv("DB_USER", "")
-	password := config.GetEnv("DB_PASSWORD", "")
-	dbname := config.GetEnv("DB_NAME", "")
-	sslmode := config.GetEnv("DB_SSLMODE", "disable")
-
-	if host == "" || port == "" || user == "" || password == "" || dbname == "" {
-		return nil, fmt.Errorf("missing required database environment variables: DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME")
-	}
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, err
-	}
-
-	// Test connection
-	err = db.Ping()
-	if err != nil {
-		return nil, err
+// showConfig prints the resolved configuration with secrets redacted, then any validation errors
+func showConfig(cfg *config.Config) {
+	cfg.Write(os.Stdout)
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("\n%v\n", err)
+		os.Exit(1)
 	}
-
-	return db, nil
 }
 
 func setupDatabase(localDebug bool, db *sql.DB) error {
@@ -300,22 +446,15 @@ func setupDatabase(localDebug bool, db *sql.DB) error {
 
 	fmt.Println("Setting up database schema...")
 
-	// Read and execute schema SQL (using no-PostGIS version for now)
-	schemaSQL, err := os.ReadFile("migrations/001_no_postgis.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read schema file: %w", err)
-	}
-
-	_, err = db.Exec(string(schemaSQL))
-	if err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+	if err := runMigrations(localDebug, db, "up", "", false); err != nil {
+		return err
 	}
 
 	fmt.Println("Database schema created successfully!")
 	return nil
 }
 
-func loadLLPG(localDebug bool, db *sql.DB, csvPath string) error {
+func loadLLPG(ctx context.Context, localDebug bool, db *sql.DB, csvPath string, resume bool) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -324,12 +463,15 @@ func loadLLPG(localDebug bool, db *sql.DB, csvPath string) error {
 	}
 
 	fmt.Printf("Loading LLPG from: %s\n", csvPath)
+	if resume {
+		fmt.Printf("Resuming from the last committed chunk\n")
+	}
 
 	pipeline := etl.NewPipeline(db)
-	return pipeline.LoadLLPG(localDebug, csvPath)
+	return pipeline.LoadLLPG(ctx, localDebug, csvPath, resume)
 }
 
-func loadOSUPRN(localDebug bool, db *sql.DB, csvPath string, batchSize int) error {
+func loadOSUPRN(ctx context.Context, localDebug bool, db *sql.DB, csvPath string, batchSize int, resume bool) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -340,21 +482,35 @@ func loadOSUPRN(localDebug bool, db *sql.DB, csvPath string, batchSize int) erro
 	fmt.Printf("Loading OS Open UPRN data from: %s\n", csvPath)
 	fmt.Printf("Batch size: %d records\n", batchSize)
 	fmt.Printf("⚠️  This will process 41+ million records and may take 1-2 hours\n")
+	if resume {
+		fmt.Printf("Resuming from the last committed batch\n")
+	}
+	fmt.Printf("Unparseable rows are written to %s.rejects.csv\n", csvPath)
 
 	osLoader := etl.NewOSDataLoader(db)
-	return osLoader.LoadOSOpenUPRN(localDebug, csvPath, batchSize)
+	return osLoader.LoadOSOpenUPRN(ctx, localDebug, csvPath, batchSize, resume)
 }
 
-func validateUPRNs(localDebug bool, db *sql.DB) error {
+func validateUPRNs(ctx context.Context, localDebug bool, db *sql.DB) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
 	fmt.Println("Validating legacy UPRNs against EHDC LLPG and OS Open UPRN datasets...")
 
 	osLoader := etl.NewOSDataLoader(db)
-	
+
+	// Resolve scientific-notation UPRNs left by imports that ran before OS data was loaded
+	repairs, err := etl.NewUPRNRepairer(db).ResolveScientificUPRNs(ctx, localDebug, "")
+	if err != nil {
+		return fmt.Errorf("failed to resolve scientific-notation UPRNs: %w", err)
+	}
+	if repairs.Pending > 0 {
+		fmt.Printf("Resolved scientific-notation UPRNs: %d repaired, %d rejected (%d ambiguous)\n",
+			repairs.Repaired, repairs.Rejected, repairs.Ambiguous)
+	}
+
 	// Generate validation report
-	report, err := osLoader.ValidateLegacyUPRNs(localDebug)
+	report, err := osLoader.ValidateLegacyUPRNs(ctx, localDebug)
 	if err != nil {
 		return fmt.Errorf("failed to validate UPRNs: %w", err)
 	}
@@ -371,10 +527,14 @@ func validateUPRNs(localDebug bool, db *sql.DB) error {
 	fmt.Printf("  ❌ Invalid UPRNs:          %d (%.1f%%)\n", 
 		report.Invalid,
 		float64(report.Invalid)/float64(report.TotalWithUPRN)*100)
+	fmt.Printf("\n  Excel artefacts:\n")
+	fmt.Printf("    Scientific notation left: %d\n", report.ScientificNotation)
+	fmt.Printf("    Repaired on import:       %d\n", report.ExcelRepaired)
+	fmt.Printf("    Rejected on import:       %d\n", report.ExcelRejected)
 
 	// Enrich EHDC addresses with OS coordinates where missing
 	fmt.Println("\nEnriching EHDC addresses with OS coordinate data...")
-	err = osLoader.EnrichCoordinates(localDebug)
+	err = osLoader.EnrichCoordinates(ctx, localDebug)
 	if err != nil {
 		return fmt.Errorf("failed to enrich coordinates: %w", err)
 	}
@@ -382,7 +542,7 @@ func validateUPRNs(localDebug bool, db *sql.DB) error {
 	return nil
 }
 
-func loadSourceDocuments(localDebug bool, db *sql.DB, sourceFiles string) error {
+func loadSourceDocuments(ctx context.Context, localDebug bool, db *sql.DB, sourceFiles string, resume bool) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -408,9 +568,11 @@ func loadSourceDocuments(localDebug bool, db *sql.DB, sourceFiles string) error
 			filePath := strings.TrimSpace(parts[1])
 			
 			fmt.Printf("Loading %s documents from: %s\n", sourceType, filePath)
-			err := pipeline.LoadSourceDocuments(localDebug, sourceType, filePath)
+			batch, err := pipeline.LoadSourceDocuments(ctx, localDebug, sourceType, filePath, resume)
 			if err != nil {
 				fmt.Printf("Error loading %s: %v\n", sourceType, err)
+			} else {
+				fmt.Printf("  %s\n", batch.Summary())
 			}
 		}
 	} else {
@@ -421,14 +583,22 @@ func loadSourceDocuments(localDebug bool, db *sql.DB, sourceFiles string) error
 			"land_charge": "source_docs/land_charges_cards.csv",
 			"enforcement": "source_docs/enforcement_notices.csv",
 			"agreement":   "source_docs/agreements.csv",
+
+			"street_name_numbering": "source_docs/street_name_and_numbering.csv",
+			"microfiche_post_1974":  "source_docs/microfiche_post_1974.csv",
+			"microfiche_pre_1974":   "source_docs/microfiche_pre_1974.csv",
+			"enlargement_map":       "source_docs/enlargement_maps.csv",
+			"enl_folder":            "source_docs/enl_folders.csv",
 		}
 		
 		for sourceType, filePath := range sourceFiles {
 			if _, err := os.Stat(filePath); err == nil {
 				fmt.Printf("Loading %s documents from: %s\n", sourceType, filePath)
-				err := pipeline.LoadSourceDocuments(localDebug, sourceType, filePath)
+				batch, err := pipeline.LoadSourceDocuments(ctx, localDebug, sourceType, filePath, resume)
 				if err != nil {
 					fmt.Printf("Error loading %s: %v\n", sourceType, err)
+				} else {
+					fmt.Printf("  %s\n", batch.Summary())
 				}
 			} else {
 				fmt.Printf("Skipping %s (file not found): %s\n", sourceType, filePath)
@@ -447,9 +617,9 @@ func setupVectorDB(localDebug bool, db *sql.DB) error {
 
 	// Configure Qdrant connection
 	qdrantConfig := vector.QdrantConfig{
-		Host:    config.GetEnv("QDRANT_HOST", "localhost"),
-		Port:    config.GetEnvInt("QDRANT_PORT", 6333),
-		APIKey:  config.GetEnv("QDRANT_API_KEY", ""),
+		Host:    config.Current().Qdrant.Host,
+		Port:    config.Current().Qdrant.Port,
+		APIKey:  config.Current().Qdrant.APIKey,
 		Timeout: 30 * time.Second,
 	}
 
@@ -468,7 +638,7 @@ func setupVectorDB(localDebug bool, db *sql.DB) error {
 	return nil
 }
 
-func runBatchMatching(localDebug bool, db *sql.DB, runLabel string) error {
+func runBatchMatching(ctx context.Context, localDebug bool, db *sql.DB, runLabel string) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -495,14 +665,14 @@ func runBatchMatching(localDebug bool, db *sql.DB, runLabel string) error {
 	}
 
 	// Process in batches
-	batchSize := config.GetEnvInt("MATCH_BATCH_SIZE", 100)
-	results, err := engine.BatchProcess(localDebug, inputs, batchSize)
+	batchSize := config.Current().Matching.BatchSize
+	results, err := engine.BatchProcess(ctx, localDebug, inputs, batchSize)
 	if err != nil {
 		return fmt.Errorf("batch processing failed: %w", err)
 	}
 
 	// Save results
-	err = engine.SaveResults(localDebug, results, runLabel)
+	err = engine.SaveResults(ctx, localDebug, results, runLabel)
 	if err != nil {
 		return fmt.Errorf("failed to save results: %w", err)
 	}
@@ -519,7 +689,7 @@ func runBatchMatching(localDebug bool, db *sql.DB, runLabel string) error {
 	return nil
 }
 
-func runSingleMatch(localDebug bool, db *sql.DB, address string) error {
+func runSingleMatch(ctx context.Context, localDebug bool, db *sql.DB, address string) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -540,7 +710,7 @@ func runSingleMatch(localDebug bool, db *sql.DB, address string) error {
 	}
 
 	// Process single address
-	result, err := engine.SuggestUPRN(localDebug, input)
+	result, err := engine.SuggestUPRN(ctx, localDebug, input)
 	if err != nil {
 		return fmt.Errorf("matching failed: %w", err)
 	}
@@ -548,6 +718,7 @@ func runSingleMatch(localDebug bool, db *sql.DB, address string) error {
 	// Show results
 	fmt.Printf("\nMatching Results:\n")
 	fmt.Printf("  Decision: %s\n", result.Decision)
+	fmt.Printf("  Tier: %s, Margin: %.4f (%s)\n", result.Tier, result.Margin, result.Reason)
 	fmt.Printf("  Processing Time: %v\n", result.ProcessingTime)
 	fmt.Printf("  Candidates Found: %d\n", len(result.Candidates))
 
@@ -563,6 +734,11 @@ func runSingleMatch(localDebug bool, db *sql.DB, address string) error {
 		fmt.Printf("  [%d] UPRN: %s, Score: %.4f, Address: %s\n", 
 			i+1, candidate.UPRN, candidate.Score, candidate.LocAddress)
 		fmt.Printf("      Methods: %v\n", candidate.Methods)
+		fmt.Printf("      Filters: %v\n", candidate.Filters)
+		if r := candidate.Rejection; r != nil {
+			fmt.Printf("      Suppressed: rejected by %s on %s (%s, rejection %d)\n",
+				r.RejectedBy, r.RejectedAt.Format("2006-01-02"), r.Scope, r.RejectionID)
+		}
 	}
 
 	// Show explanation for top candidate if available
@@ -570,6 +746,9 @@ func runSingleMatch(localDebug bool, db *sql.DB, address string) error {
 		explanation := engine.GetExplanation(result)
 		fmt.Printf("\nDetailed Explanation:\n")
 		for key, value := range explanation {
+			if key == "candidates" { // shown above; stored in full by SaveResults
+				continue
+			}
 			fmt.Printf("  %s: %v\n", key, value)
 		}
 	}
@@ -577,7 +756,7 @@ func runSingleMatch(localDebug bool, db *sql.DB, address string) error {
 	return nil
 }
 
-func showStatistics(localDebug bool, db *sql.DB) error {
+func showStatistics(ctx context.Context, localDebug bool, db *sql.DB) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -592,7 +771,7 @@ func showStatistics(localDebug bool, db *sql.DB) error {
 		return fmt.Errorf("no matching runs found: %w", err)
 	}
 
-	stats, err := tracker.GetMatchingStatistics(localDebug, latestRunID)
+	stats, err := tracker.GetMatchingStatistics(ctx, localDebug, latestRunID)
 	if err != nil {
 		return fmt.Errorf("failed to get statistics: %w", err)
 	}
@@ -662,10 +841,11 @@ func getUnmatchedDocuments(db *sql.DB, localDebug bool) ([]match.Input, error) {
 	debug.DebugOutput(localDebug, "Querying unmatched source documents")
 
 	rows, err := db.Query(`
-		SELECT s.src_id, s.raw_address, s.source_type, s.uprn_raw, s.easting_raw, s.northing_raw, s.doc_date
+		SELECT s.src_id, s.raw_address, s.source_type, s.uprn_raw, s.easting_repaired::text, s.northing_repaired::text, s.doc_date
 		FROM src_document s
 		LEFT JOIN match_accepted m ON m.src_id = s.src_id
 		WHERE m.src_id IS NULL
+		  AND s.removed_at IS NULL
 		  AND s.raw_address IS NOT NULL
 		  AND s.raw_address != ''
 		ORDER BY s.src_id
@@ -696,7 +876,7 @@ func getUnmatchedDocuments(db *sql.DB, localDebug bool) ([]match.Input, error) {
 			SourceType:   sourceType,
 		}
 
-		// Parse coordinates if available
+		// Parse coordinates if available (repaired values only - see validate-coordinates)
 		if eastingRaw.Valid && northingRaw.Valid {
 			if easting, err := parseFloat(eastingRaw.String); err == nil {
 				input.Easting = &easting
@@ -850,6 +1030,7 @@ WITH safe_group_candidates AS (
     FROM src_document s
     LEFT JOIN address_match am ON s.document_id = am.document_id
     WHERE s.planning_app_base IS NOT NULL
+      AND s.removed_at IS NULL
     GROUP BY s.planning_app_base
 ),
 safe_groups AS (
@@ -930,6 +1111,7 @@ WITH safe_group_candidates AS (
     FROM src_document s
     LEFT JOIN address_match am ON s.document_id = am.document_id
     WHERE s.planning_app_base IS NOT NULL
+      AND s.removed_at IS NULL
     GROUP BY s.planning_app_base
 ),
 safe_groups AS (
@@ -969,7 +1151,7 @@ safe_corrections AS (
     JOIN src_document s ON s.planning_app_base = sg.planning_app_base
     LEFT JOIN address_match am ON s.document_id = am.document_id
     LEFT JOIN dim_address da_current ON am.address_id = da_current.address_id
-    WHERE (
+    WHERE s.removed_at IS NULL AND (
         -- Unmatched or low confidence
         am.address_id IS NULL 
         OR am.confidence_score < 0.5
@@ -980,6 +1162,11 @@ safe_corrections AS (
         ))
     )
     AND is_real_address(s.raw_address)  -- Only apply to real addresses
+    -- Never the consensus UPRN a reviewer rejected for the document or its canonical address
+    AND match_rejection_for(s.document_id, (
+        SELECT da2.uprn FROM dim_address da2
+        WHERE da2.address_id = sg.group_best_address_id
+    )) IS NULL
 )
 INSERT INTO address_match_corrected (
     document_id, 
@@ -1031,8 +1218,9 @@ FROM address_match_corrected`).Scan(&totalCorrections, &totalGroups)
 	return nil
 }
 
-// rebuildFactTable rebuilds the fact table incorporating corrections
-func rebuildFactTable(localDebug bool, db *sql.DB) error {
+// rebuildFactTable rebuilds the fact table incorporating corrections and accepted matches.
+// Between rebuilds the fact-apply command keeps it current from the change log.
+func rebuildFactTable(ctx context.Context, localDebug bool, db *sql.DB) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -1046,183 +1234,15 @@ func rebuildFactTable(localDebug bool, db *sql.DB) error {
 	}
 	fmt.Printf("Current fact table has %d records\n", originalCount)
 
-	// Step 2: Truncate and rebuild
-	fmt.Println("Truncating fact table...")
-	_, err = db.Exec("TRUNCATE TABLE fact_documents_lean")
-	if err != nil {
-		return fmt.Errorf("failed to truncate fact table: %v", err)
-	}
-
-	// Step 3: Rebuild with corrections
+	// Step 2: Truncate and rebuild in one transaction, with corrections and accepted matches applied
 	fmt.Println("Rebuilding fact table with corrections applied...")
-	rebuildSQL := `
-INSERT INTO fact_documents_lean (
-    document_id,
-    doc_type_id,
-    document_status_id,
-    original_address_id,
-    matched_address_id,
-    matched_location_id,
-    match_method_id,
-    match_decision_id,
-    property_type_id,
-    application_status_id,
-    development_type_id,
-    application_date_id,
-    decision_date_id,
-    import_date_id,
-    match_confidence_score,
-    address_quality_score,
-    data_completeness_score,
-    processing_time_ms,
-    import_batch_id,
-    planning_reference,
-    is_auto_processed,
-    has_validation_issues,
-    additional_measures,
-    created_at,
-    processing_version
-)
-SELECT 
-    sd.document_id,
-    
-    -- Document type (required)
-    COALESCE(sd.doc_type_id, 1) as doc_type_id,
-    1 as document_status_id,
-    
-    -- Original address (required)
-    oa.original_address_id,
-    
-    -- Use corrected match if available, otherwise original
-    COALESCE(amc.corrected_address_id, am.address_id) as matched_address_id,
-    COALESCE(amc.corrected_location_id, am.location_id) as matched_location_id,
-    
-    -- Match method
-    COALESCE(
-        amc.corrected_method_id, 
-        am.match_method_id,
-        (SELECT method_id FROM dim_match_method WHERE method_code = 'no_match' LIMIT 1),
-        1
-    ) as match_method_id,
-    
-    -- Match decision based on effective confidence
-    CASE 
-        WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) >= 0.85 THEN 
-            (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'AUTO_ACCEPT' LIMIT 1)
-        WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) >= 0.50 THEN 
-            (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'NEEDS_REVIEW' LIMIT 1)
-        WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) >= 0.20 THEN 
-            (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'LOW_CONFIDENCE' LIMIT 1)
-        ELSE 
-            (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'NO_MATCH' LIMIT 1)
-    END as match_decision_id,
-    
-    -- Optional dimensions
-    NULL as property_type_id,
-    NULL as application_status_id,
-    NULL as development_type_id,
-    
-    -- Date dimensions
-    CASE 
-        WHEN sd.document_date IS NOT NULL THEN TO_CHAR(sd.document_date, 'YYYYMMDD')::INTEGER
-        ELSE NULL
-    END as application_date_id,
-    NULL as decision_date_id,
-    TO_CHAR(CURRENT_DATE, 'YYYYMMDD')::INTEGER as import_date_id,
-    
-    -- Measures (use corrected values if available)
-    COALESCE(amc.corrected_confidence_score, am.confidence_score) as match_confidence_score,
-    
-    -- Address quality score
-    CASE 
-        WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) IS NOT NULL 
-            THEN COALESCE(amc.corrected_confidence_score, am.confidence_score)
-        WHEN sd.gopostal_processed = TRUE THEN 0.3
-        ELSE 0.1
-    END as address_quality_score,
-    
-    -- Data completeness score
-    (
-        CASE WHEN sd.raw_address IS NOT NULL AND sd.raw_address != '' THEN 0.2 ELSE 0 END +
-        CASE WHEN sd.gopostal_postcode IS NOT NULL AND sd.gopostal_postcode != '' THEN 0.2 ELSE 0 END +
-        CASE WHEN sd.external_reference IS NOT NULL AND sd.external_reference != '' THEN 0.2 ELSE 0 END +
-        CASE WHEN sd.document_date IS NOT NULL THEN 0.2 ELSE 0 END +
-        CASE WHEN sd.job_number IS NOT NULL AND sd.job_number != '' THEN 0.2 ELSE 0 END
-    ) as data_completeness_score,
-    
-    -- Processing time
-    CASE 
-        WHEN COALESCE(amc.corrected_address_id, am.address_id) IS NOT NULL THEN 
-            CASE 
-                WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) >= 0.95 THEN 100
-                WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) >= 0.85 THEN 150
-                WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) >= 0.70 THEN 200
-                ELSE 300
-            END
-        ELSE 50
-    END as processing_time_ms,
-    
-    -- Technical fields
-    1 as import_batch_id,
-    sd.external_reference as planning_reference,
-    
-    -- Boolean flags
-    CASE 
-        WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) >= 0.85 THEN TRUE
-        ELSE FALSE
-    END as is_auto_processed,
-    
-    CASE 
-        WHEN sd.raw_address IS NULL OR sd.raw_address = '' THEN TRUE
-        WHEN sd.gopostal_processed = FALSE THEN TRUE
-        WHEN COALESCE(amc.corrected_address_id, am.address_id) IS NULL AND sd.gopostal_processed = TRUE THEN TRUE
-        WHEN COALESCE(amc.corrected_confidence_score, am.confidence_score) IS NOT NULL 
-             AND COALESCE(amc.corrected_confidence_score, am.confidence_score) < 0.7 THEN TRUE
-        ELSE FALSE
-    END as has_validation_issues,
-    
-    -- Additional measures with correction info
-    jsonb_build_object(
-        'original_source', 'src_document',
-        'has_uprn', CASE WHEN sd.raw_uprn IS NOT NULL THEN TRUE ELSE FALSE END,
-        'has_coordinates', CASE WHEN COALESCE(amc.corrected_location_id, am.location_id) IS NOT NULL THEN TRUE ELSE FALSE END,
-        'gopostal_processed', sd.gopostal_processed,
-        'job_number', sd.job_number,
-        'filepath', sd.filepath,
-        'has_correction', CASE WHEN amc.document_id IS NOT NULL THEN TRUE ELSE FALSE END,
-        'correction_reason', amc.correction_reason
-    ) as additional_measures,
-    
-    -- Audit fields
-    NOW() as created_at,
-    '1.1-with-corrections' as processing_version
-
-FROM src_document sd
-
--- Join to get original address dimension ID (REQUIRED)
-INNER JOIN dim_original_address oa ON oa.address_hash = MD5(LOWER(TRIM(sd.raw_address)))
-
--- Optional join to existing match data
-LEFT JOIN address_match am ON sd.document_id = am.document_id
-
--- Optional join to corrections (takes precedence)
-LEFT JOIN address_match_corrected amc ON sd.document_id = amc.document_id
-
--- Only include records with valid addresses
-WHERE sd.raw_address IS NOT NULL 
-  AND sd.raw_address != ''
-
-ORDER BY sd.document_id;`
-
-	result, err := db.Exec(rebuildSQL)
+	rowsInserted, err := facts.Rebuild(ctx, localDebug, db)
 	if err != nil {
-		return fmt.Errorf("failed to rebuild fact table: %v", err)
+		return err
 	}
-
-	rowsInserted, _ := result.RowsAffected()
 	fmt.Printf("Rebuilt fact table with %d records\n", rowsInserted)
 
-	// Step 4: Update statistics and show results
+	// Step 3: Update statistics and show results
 	fmt.Println("Updating table statistics...")
 	_, err = db.Exec("ANALYZE fact_documents_lean")
 	if err != nil {
@@ -1230,21 +1250,23 @@ ORDER BY sd.document_id;`
 	}
 
 	// Show rebuild results
-	var totalRecords, withMatch, withCorrections int
+	var totalRecords, withMatch, withCorrections, withAccepted int
 	var matchRate float64
 	err = db.QueryRow(`
 SELECT 
     COUNT(*) as total_records,
     COUNT(CASE WHEN matched_address_id IS NOT NULL THEN 1 END) as with_address_match,
     COUNT(CASE WHEN additional_measures->>'has_correction' = 'true' THEN 1 END) as with_corrections,
+    COUNT(CASE WHEN additional_measures->>'accepted' = 'true' THEN 1 END) as with_accepted,
     ROUND(100.0 * COUNT(CASE WHEN matched_address_id IS NOT NULL THEN 1 END) / COUNT(*), 2) as match_rate_pct
-FROM fact_documents_lean`).Scan(&totalRecords, &withMatch, &withCorrections, &matchRate)
+FROM fact_documents_lean`).Scan(&totalRecords, &withMatch, &withCorrections, &withAccepted, &matchRate)
 
 	if err == nil {
 		fmt.Printf("Fact table rebuild complete:\n")
 		fmt.Printf("  Total records: %d\n", totalRecords)
 		fmt.Printf("  With matches: %d (%.1f%%)\n", withMatch, matchRate)
 		fmt.Printf("  With corrections: %d\n", withCorrections)
+		fmt.Printf("  With accepted matches: %d\n", withAccepted)
 	}
 
 	return nil
@@ -1402,6 +1424,7 @@ WITH low_confidence_groups AS (
     FROM src_document s
     LEFT JOIN address_match am ON s.document_id = am.document_id
     WHERE s.planning_app_base IS NOT NULL
+      AND s.removed_at IS NULL
     GROUP BY s.planning_app_base
     HAVING COUNT(*) BETWEEN 2 AND 30  -- Allow larger groups
       AND COUNT(am.document_id) FILTER (WHERE am.confidence_score > 0.5) = 0  -- No good matches
@@ -1551,6 +1574,7 @@ SELECT
     
 FROM src_document s
 WHERE s.planning_app_base = $6
+  AND s.removed_at IS NULL
   AND is_real_address(s.raw_address)
   AND NOT EXISTS (
       SELECT 1 FROM address_match_corrected amc 
@@ -1613,6 +1637,7 @@ FROM src_document s
 LEFT JOIN address_match am ON s.document_id = am.document_id
 LEFT JOIN address_match_corrected amc ON s.document_id = amc.document_id
 WHERE s.raw_address IS NOT NULL 
+  AND s.removed_at IS NULL
   AND s.raw_address != ''
   AND amc.document_id IS NULL  -- Not already corrected
   AND (
@@ -2020,6 +2045,7 @@ FROM src_document s
 LEFT JOIN address_match am ON s.document_id = am.document_id
 LEFT JOIN address_match_corrected amc ON s.document_id = amc.document_id
 WHERE s.planning_app_base IS NOT NULL
+  AND s.removed_at IS NULL
   AND amc.document_id IS NULL  -- Not already corrected
 GROUP BY s.planning_app_base
 HAVING COUNT(*) BETWEEN 2 AND 8  -- Reasonable group size
@@ -2062,6 +2088,7 @@ FROM src_document s
 LEFT JOIN address_match am ON s.document_id = am.document_id
 LEFT JOIN address_match_corrected amc ON s.document_id = amc.document_id
 WHERE s.planning_app_base = $1
+  AND s.removed_at IS NULL
   AND amc.document_id IS NULL
   AND (am.confidence_score IS NULL OR am.confidence_score = 0)
   AND s.raw_address IS NOT NULL 
@@ -2290,6 +2317,7 @@ FROM src_document s
 LEFT JOIN address_match am ON s.document_id = am.document_id
 LEFT JOIN address_match_corrected amc ON s.document_id = amc.document_id
 WHERE s.raw_address IS NOT NULL 
+  AND s.removed_at IS NULL
   AND s.raw_address != ''
   AND LENGTH(s.raw_address) > 15  -- Meaningful addresses only
   AND amc.document_id IS NULL  -- Not already corrected
@@ -2554,7 +2582,7 @@ ON src_document USING gin(standardized_address gin_trgm_ops)`)
 }
 
 // runComprehensiveMatching runs the complete multi-layered matching strategy
-func runComprehensiveMatching(localDebug bool, db *sql.DB) error {
+func runComprehensiveMatching(ctx context.Context, localDebug bool, db *sql.DB, runID int64) error {
 	fmt.Println("Running comprehensive multi-layered matching strategy...")
 	fmt.Println("=======================================================")
 
@@ -2567,7 +2595,7 @@ func runComprehensiveMatching(localDebug bool, db *sql.DB) error {
 
 	// Layer 1: Intelligent Fact Table Population (REDESIGNED)
 	fmt.Println("\n--- LAYER 1: Intelligent Fact Table Population ---")
-	err = rebuildFactTableIntelligent(localDebug, db)
+	err = rebuildFactTableIntelligent(ctx, localDebug, db, runID)
 	if err != nil {
 		return fmt.Errorf("layer 1 failed: %v", err)
 	}
@@ -2581,9 +2609,9 @@ func runComprehensiveMatching(localDebug bool, db *sql.DB) error {
 	
 	// Layer 3: Enhanced parallel individual document fuzzy matching with address deduplication
 	fmt.Println("\n--- LAYER 3: Enhanced Parallel Fuzzy Matching ---")
-	err = parallelFuzzyMatchIndividualDocuments(localDebug, db)
+	err = parallelFuzzyMatchIndividualDocuments(ctx, localDebug, db)
 	if err != nil {
-		return fmt.Errorf("layer 3 failed: %v", err)
+		return fmt.Errorf("layer 3 failed: %w", err)
 	}
 
 	// Layer 4: Group consensus corrections
@@ -2595,7 +2623,7 @@ func runComprehensiveMatching(localDebug bool, db *sql.DB) error {
 
 	// Summary statistics
 	fmt.Println("\n--- FINAL STATISTICS ---")
-	err = showStatistics(localDebug, db)
+	err = showStatistics(ctx, localDebug, db)
 	if err != nil {
 		fmt.Printf("Warning: failed to show final statistics: %v\n", err)
 	}
@@ -2617,7 +2645,8 @@ func runConservativeMatching(localDebug bool, db *sql.DB, runLabel string) error
 
 	// Process ALL unmatched records for production run
 	query := `
-		SELECT f.fact_id, f.document_id, o.raw_address, dt.type_name as source_type, s.raw_uprn
+		SELECT f.fact_id, f.document_id, o.raw_address, dt.type_name as source_type, s.raw_uprn,
+			COALESCE(s.address_canonical, '') as address_canonical
 		FROM fact_documents_lean f
 		JOIN dim_original_address o ON f.original_address_id = o.original_address_id
 		JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
@@ -2643,18 +2672,25 @@ func runConservativeMatching(localDebug bool, db *sql.DB, runLabel string) error
 	// Process unmatched documents
 	var processedCount, acceptedCount, rejectedCount, reviewCount int
 	var sourceUPRNCount int // Track documents matched using source UPRNs
+	var suppressedCount int // Candidates ruled out by reviewers' earlier rejections
 	
 	for rows.Next() {
 		var factID, docID int64
-		var sourceAddress, sourceType string
+		var sourceAddress, sourceType, canonical string
 		var sourceUPRN sql.NullString
 		
-		err := rows.Scan(&factID, &docID, &sourceAddress, &sourceType, &sourceUPRN)
+		err := rows.Scan(&factID, &docID, &sourceAddress, &sourceType, &sourceUPRN, &canonical)
 		if err != nil {
 			return fmt.Errorf("failed to scan document row: %v", err)
 		}
 
 		processedCount++
+
+		// UPRNs a reviewer has rejected for this document or its canonical address
+		rejections, err := match.LoadRejections(context.Background(), db, docID, canonical)
+		if err != nil {
+			return err
+		}
 		
 		if localDebug && processedCount <= 10 {
 			fmt.Printf("\n--- Processing Document %d ---\n", docID)
@@ -2664,8 +2700,13 @@ func runConservativeMatching(localDebug bool, db *sql.DB, runLabel string) error
 			}
 		}
 
-		// Check if document already has a source UPRN
-		if sourceUPRN.Valid && sourceUPRN.String != "" {
+		// Check if document already has a source UPRN a reviewer has not rejected
+		if r, rejected := rejections[sourceUPRN.String]; rejected && sourceUPRN.Valid {
+			suppressedCount++
+			if localDebug && processedCount <= 10 {
+				fmt.Printf("Source UPRN %s suppressed by prior rejection %d (%s)\n", sourceUPRN.String, r.RejectionID, r.Scope)
+			}
+		} else if sourceUPRN.Valid && sourceUPRN.String != "" {
 			// Find the address record for this source UPRN
 			var targetAddressID int64
 			err := db.QueryRow(`
@@ -2683,15 +2724,13 @@ func runConservativeMatching(localDebug bool, db *sql.DB, runLabel string) error
 					fmt.Printf("USING SOURCE UPRN: %s (Address ID: %d)\n", sourceUPRN.String, targetAddressID)
 				}
 
-				// Update fact table with source UPRN match
-				_, err = db.Exec(`
-					UPDATE fact_documents_lean 
-					SET matched_address_id = $1,
-						match_method_id = 25, -- source_uprn method
-						match_confidence_score = 1.0,
-						updated_at = NOW()
-					WHERE fact_id = $2
-				`, targetAddressID, factID)
+				// Record the source UPRN match; the fact row is rebuilt from it
+				_, err = facts.SaveMatch(context.Background(), db, facts.LayerMatch{
+					AddressID:  int(targetAddressID),
+					MethodID:   25, // source_uprn method
+					Confidence: 1.0,
+					MatchedBy:  "system_conservative",
+				}, facts.ByDocument, docID)
 				
 				if err != nil {
 					fmt.Printf("Warning: failed to update document %d with source UPRN: %v\n", docID, err)
@@ -2705,8 +2744,9 @@ func runConservativeMatching(localDebug bool, db *sql.DB, runLabel string) error
 		}
 
 		// PRODUCTION OPTIMIZATION: Use targeted database queries for matching
-		ctx := &runConservativeMatchingContext{}
+		ctx := &runConservativeMatchingContext{rejections: rejections}
 		bestMatch, bestDecision, err := ctx.findBestMatchOptimized(db, sourceAddress, validator, localDebug && processedCount <= 10)
+		suppressedCount += ctx.suppressed
 		if err != nil {
 			if localDebug && processedCount <= 10 {
 				fmt.Printf("Error finding match: %v\n", err)
@@ -2731,15 +2771,13 @@ func runConservativeMatching(localDebug bool, db *sql.DB, runLabel string) error
 				}
 			}
 
-			// Update the dimensional fact table with the conservative match
-			_, err = db.Exec(`
-				UPDATE fact_documents_lean 
-				SET matched_address_id = $1,
-				    match_method_id = 26, -- conservative method
-				    match_confidence_score = $2,
-				    updated_at = NOW()
-				WHERE fact_id = $3
-			`, bestMatch.AddressID, bestDecision.Confidence, factID)
+			// Record the conservative match; the fact row is rebuilt from it
+			_, err = facts.SaveMatch(context.Background(), db, facts.LayerMatch{
+				AddressID:  int(bestMatch.AddressID),
+				MethodID:   26, // conservative method
+				Confidence: bestDecision.Confidence,
+				MatchedBy:  "system_conservative",
+			}, facts.ByDocument, docID)
 			
 			if err != nil {
 				fmt.Printf("Warning: failed to update document %d: %v\n", docID, err)
@@ -2780,6 +2818,7 @@ func runConservativeMatching(localDebug bool, db *sql.DB, runLabel string) error
 		float64(reviewCount)*100.0/float64(processedCount))
 	fmt.Printf("Rejected: %d (%.1f%%)\n", rejectedCount, 
 		float64(rejectedCount)*100.0/float64(processedCount))
+	fmt.Printf("Candidates suppressed by prior rejections: %d\n", suppressedCount)
 	fmt.Println("=====================================")
 
 	fmt.Printf("\nMatch Source Tracking:\n")
@@ -2938,6 +2977,9 @@ func (v *runConservativeMatchingContext) tryCanonicalAddressMatch(db *sql.DB, so
 		if err != nil {
 			continue
 		}
+		if v.suppress(candidate.UPRN, debug) {
+			continue
+		}
 		
 		candidates = append(candidates, candidate)
 		
@@ -3113,6 +3155,9 @@ func (v *runConservativeMatchingContext) evaluateCandidates(rows *sql.Rows, vali
 		}
 		
 		candidateCount++
+		if v.suppress(candidate.UPRN, debug) {
+			continue
+		}
 		
 		// Apply conservative validation
 		decision := validator.MakeMatchDecision(sourceAddress, candidate.Address)
@@ -3154,6 +3199,9 @@ func (v *runConservativeMatchingContext) evaluateCandidatesWithSimilarity(rows *
 		}
 		
 		candidateCount++
+		if v.suppress(candidate.UPRN, debug) {
+			continue
+		}
 		
 		// Apply conservative validation
 		decision := validator.MakeMatchDecision(sourceAddress, candidate.Address)
@@ -3179,7 +3227,23 @@ func (v *runConservativeMatchingContext) evaluateCandidatesWithSimilarity(rows *
 }
 
 // Context struct to hold method receivers (Go doesn't allow methods on function types)
-type runConservativeMatchingContext struct{}
+type runConservativeMatchingContext struct {
+	rejections match.Rejections // UPRNs reviewers have rejected for the document
+	suppressed int              // candidates skipped because of them
+}
+
+// suppress reports whether a reviewer has rejected the candidate UPRN for the document
+func (v *runConservativeMatchingContext) suppress(uprn string, debug bool) bool {
+	r, rejected := v.rejections[uprn]
+	if !rejected {
+		return false
+	}
+	v.suppressed++
+	if debug {
+		fmt.Printf("  Candidate UPRN %s suppressed by prior rejection %d (%s)\n", uprn, r.RejectionID, r.Scope)
+	}
+	return true
+}
 
 func (v *runConservativeMatchingContext) ParseAddress(address string) validation.AddressComponents {
 	parser := validation.NewAddressParser()
@@ -3208,7 +3272,10 @@ func expandLLPGRanges(localDebug bool, db *sql.DB) error {
 	fmt.Println("Processing range expansions...")
 	fmt.Println("  - Numeric ranges (e.g., 10-11 → 10, 11)")
 	fmt.Println("  - Unit ranges (e.g., Unit 3-4 → Unit 3, Unit 4)")
+	fmt.Println("  - Flat and unit letter ranges (e.g., Flats 1-12, Units A-F)")
 	fmt.Println("  - Alpha ranges (e.g., 9A-9C → 9A, 9B, 9C)")
+	fmt.Println("  - Odd/even ranges (e.g., 2-10 (Even) → 2, 4, 6, 8, 10)")
+	fmt.Println("  - Unit lists (e.g., Units 3 & 5 → Unit 3, Unit 5)")
 	
 	startTime := time.Now()
 	expandedCount, err := expander.ExpandAllRanges()
@@ -3256,10 +3323,219 @@ func expandLLPGRanges(localDebug bool, db *sql.DB) error {
 			}
 		}
 	}
-	
+
+	return nil
+}
+
+// findLLPGRange reports the LLPG range addresses a single-number source address falls inside
+func findLLPGRange(localDebug bool, db *sql.DB, address string) error {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	if address == "" {
+		return fmt.Errorf("address is required for range lookup")
+	}
+
+	fmt.Printf("Finding LLPG ranges containing: %s\n", address)
+
+	expander := llpg.NewRangeExpander(db)
+	matches, err := expander.FindContainingRanges(address, 0.5)
+	if err != nil {
+		return fmt.Errorf("range lookup failed: %v", err)
+	}
+
+	if len(matches) == 0 {
+		fmt.Println("No containing LLPG range found")
+		return nil
+	}
+
+	for i, m := range matches {
+		fmt.Printf("  [%d] UPRN: %s, Range: %s, Similarity: %.3f\n", i+1, m.UPRN, m.RangeText, m.Similarity)
+		fmt.Printf("      Address: %s\n", m.FullAddress)
+	}
+
+	return nil
+}
+
+// linkPlanningReferences inherits UPRNs for reference-only documents from decision notices.
+// Its accepts follow the review queue's sign-off rules.
+func linkPlanningReferences(ctx context.Context, localDebug bool, db *sql.DB, cfg *config.Config) error {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	fmt.Println("Linking microfiche records via planning references...")
+
+	reviews := review.NewService(store.NewPostgres(db), review.Policy{
+		Lease:        cfg.Web.ReviewLease,
+		SignoffBelow: cfg.Web.SignoffBelow,
+	})
+	linker := planning.NewLinker(db, reviews)
+	result, err := linker.Link(ctx, localDebug)
+	if err != nil {
+		return fmt.Errorf("planning reference linking failed: %v", err)
+	}
+
+	fmt.Printf("Microfiche records considered: %d\n", result.Candidates)
+	fmt.Printf("  Linked (%s): %d\n", planning.MethodInheritedViaPlanningRef, result.Linked)
+	fmt.Printf("  Left for review (below sign-off or queued): %d\n", result.Proposed)
+	fmt.Printf("  Unchanged since last run: %d\n", result.Unchanged)
+	fmt.Printf("  Unlinked (no longer hold): %d\n", result.Unlinked)
+	fmt.Printf("  Ambiguous (decisions disagree): %d\n", result.Ambiguous)
+	fmt.Printf("  Inherited UPRN rejected by a reviewer: %d\n", result.Rejected)
+	fmt.Printf("  No matched decision notice: %d\n", result.NoDecision)
+
+	return nil
+}
+
+// validateSourceCoordinates classifies every source coordinate and stores repaired values
+func validateSourceCoordinates(ctx context.Context, localDebug bool, db *sql.DB) error {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	fmt.Println("Validating source coordinates...")
+
+	validator := etl.NewCoordinateValidator(db)
+	summary, err := validator.Validate(ctx, localDebug, "")
+	if err != nil {
+		return fmt.Errorf("coordinate validation failed: %v", err)
+	}
+
+	fmt.Printf("Checked %d documents:\n", summary.Checked)
+	for _, status := range summary.SortedStatuses() {
+		fmt.Printf("  %-16s %d\n", status, summary.ByStatus[status])
+	}
+
+	return nil
+}
+
+// profileCSV reports per-column fill, patterns, types and validity for a CSV before it is loaded
+func profileCSV(localDebug bool, db *sql.DB, csvPath, sourceType, outputPath string) error {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	if csvPath == "" {
+		return fmt.Errorf("CSV file path is required (-csv)")
+	}
+	if outputPath == "" {
+		outputPath = csvPath + ".profile.json"
+	}
+
+	report, err := profile.NewProfiler(db).ProfileCSV(localDebug, csvPath, sourceType)
+	if err != nil {
+		return fmt.Errorf("profiling failed: %v", err)
+	}
+
+	report.Print(os.Stdout)
+
+	if err := report.WriteJSON(outputPath); err != nil {
+		return err
+	}
+	fmt.Printf("\nJSON report written to %s\n", outputPath)
+
+	return nil
+}
+
+// migrationStatus lists every migration and whether it has been applied
+func migrationStatus(localDebug bool, db *sql.DB) error {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	migrator, err := migrate.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read migration status: %v", err)
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.ReplacedBy != "":
+			state = "replaced by " + s.ReplacedBy
+		case s.Missing:
+			state = "applied, file missing"
+		case s.Drift:
+			state = "applied, CHANGED since"
+		case s.Baseline:
+			state = "baseline"
+		case s.Applied:
+			state = "applied"
+		}
+		if !s.Applied {
+			pending++
+		}
+
+		appliedAt := ""
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04")
+		}
+		down := ""
+		if !s.Missing && !s.HasDown() {
+			down = " (no down)"
+		}
+		fmt.Printf("  %-45s %-22s %s%s\n", s.Version, state, appliedAt, down)
+	}
+	fmt.Printf("\n%d migrations, %d pending\n", len(statuses), pending)
+
+	printSharedMigrationNumbers(migrator)
+	return nil
+}
+
+// runMigrations applies (up), rolls back (down) or records without running (baseline)
+// migrations up to target, printing the plan first
+func runMigrations(localDebug bool, db *sql.DB, direction, target string, dryRun bool) error {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	migrator, err := migrate.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	printSharedMigrationNumbers(migrator)
+
+	var plan []migrate.Migration
+	switch direction {
+	case "up":
+		plan, err = migrator.Up(localDebug, target, dryRun)
+	case "down":
+		plan, err = migrator.Down(localDebug, target, dryRun)
+	case "baseline":
+		plan, err = migrator.Baseline(localDebug, target, dryRun)
+	default:
+		return fmt.Errorf("unknown migration direction: %s", direction)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate %s failed: %v", direction, err)
+	}
+
+	if len(plan) == 0 {
+		fmt.Println("Nothing to do, schema is up to date")
+		return nil
+	}
+
+	verb := map[string]string{"up": "Applied", "down": "Rolled back", "baseline": "Baselined"}[direction]
+	if dryRun {
+		verb = "Would " + map[string]string{"up": "apply", "down": "roll back", "baseline": "baseline"}[direction]
+	}
+	fmt.Printf("%s %d migrations:\n", verb, len(plan))
+	for _, m := range plan {
+		fmt.Printf("  %s\n", m.Version)
+	}
+
 	return nil
 }
 
+// printSharedMigrationNumbers warns about migration files that share a numeric prefix
+func printSharedMigrationNumbers(migrator *migrate.Migrator) {
+	for _, number := range migrator.SharedNumbers() {
+		fmt.Printf("Note: several migrations are numbered %s; they are applied in file name order\n", number)
+	}
+}
+
 // cleanSourceAddressData fixes spelling errors and formatting issues in source addresses
 func cleanSourceAddressData(localDebug bool, db *sql.DB) error {
 	fmt.Println("Cleaning source address data...")
@@ -3360,8 +3636,10 @@ func cleanSourceAddressData(localDebug bool, db *sql.DB) error {
 	fmt.Println("\n✓ Address data cleaning completed")
 	return nil
 }
-// runEndToEndWithSnapshots runs the complete multi-layered matching with snapshots after each layer
-func runEndToEndWithSnapshots(localDebug bool, db *sql.DB) error {
+// runEndToEndWithSnapshots runs the complete multi-layered matching with snapshots after each
+// layer, each recording runID as the run that produced it
+func runEndToEndWithSnapshots(ctx context.Context, localDebug bool, db *sql.DB, runID int64) error {
+	startTime := time.Now()
 	fmt.Println("Running end-to-end matching with layer snapshots...")
 	fmt.Println("============================================================")
 	
@@ -3374,7 +3652,7 @@ func runEndToEndWithSnapshots(localDebug bool, db *sql.DB) error {
 	
 	// Layer 1: Intelligent Fact Table Population (includes Layer 1 snapshot)
 	fmt.Println("\n--- LAYER 1: Intelligent Fact Table Population ---")
-	err = rebuildFactTableIntelligent(localDebug, db)
+	err = rebuildFactTableIntelligent(ctx, localDebug, db, runID)
 	if err != nil {
 		return fmt.Errorf("layer 1 failed: %v", err)
 	}
@@ -3388,21 +3666,21 @@ func runEndToEndWithSnapshots(localDebug bool, db *sql.DB) error {
 	
 	// Create Layer 2 snapshot
 	fmt.Println("\n=== CREATING LAYER 2 SNAPSHOT ===")
-	err = createLayerSnapshot(localDebug, db, "layer_2")
+	err = createLayerSnapshot(ctx, localDebug, db, runID, "layer_2")
 	if err != nil {
 		return fmt.Errorf("failed to create Layer 2 snapshot: %v", err)
 	}
 	
 	// Layer 3: Enhanced parallel individual document fuzzy matching 
 	fmt.Println("\n--- LAYER 3: Enhanced Parallel Fuzzy Matching ---")
-	err = parallelFuzzyMatchIndividualDocuments(localDebug, db)
+	err = parallelFuzzyMatchIndividualDocuments(ctx, localDebug, db)
 	if err != nil {
-		return fmt.Errorf("layer 3 failed: %v", err)
+		return fmt.Errorf("layer 3 failed: %w", err)
 	}
 	
 	// Create Layer 3 snapshot
 	fmt.Println("\n=== CREATING LAYER 3 SNAPSHOT ===")
-	err = createLayerSnapshot(localDebug, db, "layer_3")
+	err = createLayerSnapshot(ctx, localDebug, db, runID, "layer_3")
 	if err != nil {
 		return fmt.Errorf("failed to create Layer 3 snapshot: %v", err)
 	}
@@ -3416,41 +3694,44 @@ func runEndToEndWithSnapshots(localDebug bool, db *sql.DB) error {
 	
 	// Create Layer 4 snapshot
 	fmt.Println("\n=== CREATING LAYER 4 SNAPSHOT ===")
-	err = createLayerSnapshot(localDebug, db, "layer_4")
+	err = createLayerSnapshot(ctx, localDebug, db, runID, "layer_4")
 	if err != nil {
 		return fmt.Errorf("failed to create Layer 4 snapshot: %v", err)
 	}
 	
 	// Summary statistics
 	fmt.Println("\n--- FINAL STATISTICS ---")
-	err = showStatistics(localDebug, db)
+	err = showStatistics(ctx, localDebug, db)
 	if err != nil {
 		fmt.Printf("Warning: failed to show final statistics: %v\n", err)
 	}
 	
 	// Show snapshot summary
 	fmt.Println("\n--- LAYER SNAPSHOT SUMMARY ---")
-	snapshots := []string{"layer_1", "layer_2", "layer_3", "layer_4"}
-	for _, snapshot := range snapshots {
-		var count int
-		var matched int
-		tableName := fmt.Sprintf("snapshot_fact_documents_lean_%s", snapshot)
-		
-		countSQL := fmt.Sprintf("SELECT COUNT(*), COUNT(*) FILTER (WHERE matched_address_id IS NOT NULL) FROM %s", tableName)
-		err := db.QueryRow(countSQL).Scan(&count, &matched)
-		if err == nil {
-			matchRate := float64(matched) / float64(count) * 100
-			fmt.Printf("  %s: %d total, %d matched (%.1f%%)\n", snapshot, count, matched, matchRate)
+	snapshots, err := snapshot.List(ctx, db)
+	if err != nil {
+		fmt.Printf("Warning: failed to list snapshots: %v\n", err)
+	}
+	var taken []snapshot.Snapshot
+	for _, s := range snapshots {
+		if !s.CreatedAt.Before(startTime) {
+			taken = append([]snapshot.Snapshot{s}, taken...) // oldest first
+		}
+	}
+	for _, s := range taken {
+		matchRate := 0.0
+		if s.Rows > 0 {
+			matchRate = float64(s.Matched) / float64(s.Rows) * 100
 		}
+		fmt.Printf("  %s: %d total, %d matched (%.1f%%)\n", s.Name, s.Rows, s.Matched, matchRate)
 	}
 	
 	fmt.Println("\n============================================================")
 	fmt.Println("End-to-end matching with snapshots completed!")
-	fmt.Println("Use the snapshot tables and cross-layer views to analyze results:")
-	for _, snapshot := range snapshots {
-		fmt.Printf("  snapshot_fact_documents_lean_%s\n", snapshot)
+	if len(taken) > 1 {
+		fmt.Println("Compare layers with:")
+		fmt.Printf("  ./bin/matcher snapshot diff %s %s\n", taken[0].Name, taken[len(taken)-1].Name)
 	}
-	fmt.Println("  vw_fact_documents_cross_layer (unified analysis view)")
 	
 	return nil
 }
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/ehdc-llpg/internal/facts"
 )
 
 // optimizeLayer2Performance creates optimized structures for faster Layer 2 matching
@@ -187,18 +190,13 @@ func runOptimizedLayer2(localDebug bool, db *sql.DB) error {
 		}
 		
 		if matchedAddressID > 0 {
-			// Update ALL documents with this address
-			updateQuery := `
-			UPDATE fact_documents_lean 
-			SET matched_address_id = $1, 
-			    match_method_id = 4,  -- "Optimized Conservative Match"
-			    match_confidence_score = $2,
-			    match_decision_id = 2  -- "Accepted"
-			WHERE original_address_id = $3 
-			  AND matched_address_id IS NULL
-			`
-			
-			result, err := db.Exec(updateQuery, matchedAddressID, confidence, addr.originalAddressID)
+			// Match ALL unmatched documents with this address
+			rowsAffected, err := facts.SaveMatch(context.Background(), db, facts.LayerMatch{
+				AddressID:  matchedAddressID,
+				MethodID:   4, // "Optimized Conservative Match"
+				Confidence: confidence,
+				MatchedBy:  "system_layer2",
+			}, facts.ByOriginalAddress, addr.originalAddressID)
 			if err != nil {
 				if localDebug {
 					fmt.Printf("Error updating documents for address '%s': %v\n", addr.rawAddress, err)
@@ -206,7 +204,6 @@ func runOptimizedLayer2(localDebug bool, db *sql.DB) error {
 				continue
 			}
 			
-			rowsAffected, _ := result.RowsAffected()
 			totalMatches++
 			totalDocuments += int(rowsAffected)
 			
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ehdc-llpg/internal/facts"
 )
 
 // GroupBatch represents a batch of groups to process in parallel
@@ -81,11 +84,12 @@ func runParallelLayer3Groups(localDebug bool, db *sql.DB) error {
 			s.planning_app_base,
 			COUNT(*) as total_docs,
 			COUNT(f.matched_address_id) as matched_docs,
-			(SELECT raw_address FROM src_document s2 WHERE s2.planning_app_base = s.planning_app_base LIMIT 1) as best_address_in_group,
+			(SELECT raw_address FROM src_document s2 WHERE s2.planning_app_base = s.planning_app_base AND s2.removed_at IS NULL LIMIT 1) as best_address_in_group,
 			COALESCE(AVG(f.match_confidence_score), 0) as avg_confidence
 		FROM src_document s
 		LEFT JOIN fact_documents_lean f ON s.document_id = f.document_id
 		WHERE s.planning_app_base IS NOT NULL 
+		  AND s.removed_at IS NULL
 		  AND s.planning_app_base != ''
 		  AND s.raw_address IS NOT NULL
 		GROUP BY s.planning_app_base
@@ -211,6 +215,7 @@ func runParallelLayer3Documents(localDebug bool, db *sql.DB) error {
 	FROM src_document s
 	LEFT JOIN fact_documents_lean f ON s.document_id = f.document_id
 	WHERE s.raw_address IS NOT NULL 
+	  AND s.removed_at IS NULL
 	  AND s.raw_address != ''
 	  AND LENGTH(s.raw_address) > 10  -- Reasonable length addresses
 	  AND s.raw_address !~ '^(F|PRD|N/A|ALR|AUD|UNKNOWN)'  -- Exclude codes
@@ -389,21 +394,13 @@ func processGroupBatchWorker(workerID int, db *sql.DB, batchChan <-chan GroupBat
 			}
 			
 			if bestMatch != nil {
-				// Apply the fuzzy match to all documents in this group
-				updateQuery := `
-				UPDATE fact_documents_lean 
-				SET matched_address_id = $1, 
-				    match_method_id = 30,  -- "Group Fuzzy Match"
-				    match_confidence_score = $2,
-				    match_decision_id = 2  -- "Accepted"
-				WHERE document_id IN (
-				    SELECT document_id FROM src_document 
-				    WHERE planning_app_base = $3
-				)
-				AND matched_address_id IS NULL  -- Only update unmatched documents
-				`
-				
-				updateResult, err := db.Exec(updateQuery, bestMatch.AddressID, bestMatch.SimilarityScore, group.PlanningAppBase)
+				// Apply the fuzzy match to the unmatched documents in this group
+				rowsAffected, err := facts.SaveMatch(context.Background(), db, facts.LayerMatch{
+					AddressID:  bestMatch.AddressID,
+					MethodID:   30, // "Group Fuzzy Match"
+					Confidence: bestMatch.SimilarityScore,
+					MatchedBy:  "system_layer3",
+				}, facts.ByPlanningAppBase, group.PlanningAppBase)
 				if err != nil {
 					if debug {
 						fmt.Printf("Worker %d: Error updating group %s: %v\n", workerID, group.PlanningAppBase, err)
@@ -411,7 +408,6 @@ func processGroupBatchWorker(workerID int, db *sql.DB, batchChan <-chan GroupBat
 					continue
 				}
 				
-				rowsAffected, _ := updateResult.RowsAffected()
 				result.MatchCount++
 				result.DocumentCount += int(rowsAffected)
 				
@@ -463,18 +459,13 @@ func processDocumentBatchWorker(workerID int, db *sql.DB, batchChan <-chan Docum
 			}
 			
 			if bestMatch != nil {
-				// Apply the fuzzy match to this individual document
-				updateQuery := `
-				UPDATE fact_documents_lean 
-				SET matched_address_id = $1, 
-				    match_method_id = 31,  -- "Individual Fuzzy Match"
-				    match_confidence_score = $2,
-				    match_decision_id = 2  -- "Accepted"
-				WHERE document_id = $3
-				AND matched_address_id IS NULL  -- Only update unmatched documents
-				`
-				
-				updateResult, err := db.Exec(updateQuery, bestMatch.AddressID, bestMatch.SimilarityScore, doc.DocumentID)
+				// Apply the fuzzy match to this individual document if still unmatched
+				rowsAffected, err := facts.SaveMatch(context.Background(), db, facts.LayerMatch{
+					AddressID:  bestMatch.AddressID,
+					MethodID:   31, // "Individual Fuzzy Match"
+					Confidence: bestMatch.SimilarityScore,
+					MatchedBy:  "system_layer3",
+				}, facts.ByDocument, doc.DocumentID)
 				if err != nil {
 					if debug {
 						fmt.Printf("Worker %d: Error updating document %d: %v\n", workerID, doc.DocumentID, err)
@@ -482,7 +473,6 @@ func processDocumentBatchWorker(workerID int, db *sql.DB, batchChan <-chan Docum
 					continue
 				}
 				
-				rowsAffected, _ := updateResult.RowsAffected()
 				if rowsAffected > 0 {
 					result.MatchCount++
 					result.DocumentCount += int(rowsAffected)
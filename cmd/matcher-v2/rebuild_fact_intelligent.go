@@ -1,77 +1,57 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/ehdc-llpg/internal/config"
+	"github.com/ehdc-llpg/internal/facts"
+	"github.com/ehdc-llpg/internal/snapshot"
 )
 
-// createLayerSnapshot creates a complete snapshot of the fact table after each layer
-func createLayerSnapshot(localDebug bool, db *sql.DB, layerName string) error {
-	snapshotTable := fmt.Sprintf("snapshot_fact_documents_lean_%s", layerName)
-	
-	fmt.Printf("Creating snapshot: %s\n", snapshotTable)
-	
-	// Drop existing snapshot table if it exists
-	dropSQL := fmt.Sprintf("DROP TABLE IF EXISTS %s", snapshotTable)
-	_, err := db.Exec(dropSQL)
-	if err != nil {
-		return fmt.Errorf("failed to drop existing snapshot table %s: %v", snapshotTable, err)
-	}
-	
-	// Create new snapshot table with complete copy
-	createSQL := fmt.Sprintf(`
-		CREATE TABLE %s AS 
-		SELECT * FROM fact_documents_lean
-	`, snapshotTable)
-	
-	_, err = db.Exec(createSQL)
-	if err != nil {
-		return fmt.Errorf("failed to create snapshot table %s: %v", snapshotTable, err)
-	}
-	
-	// Get row count
-	var count int
-	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s", snapshotTable)
-	err = db.QueryRow(countSQL).Scan(&count)
+// createLayerSnapshot records the fact table after a layer as a catalogued snapshot named
+// after the layer and the time, so earlier runs' layers are kept for comparison. runID is
+// the run that produced the layer, or 0 when there is none.
+func createLayerSnapshot(ctx context.Context, localDebug bool, db *sql.DB, runID int64, layerName string) error {
+	snap, err := snapshot.Create(ctx, localDebug, db, snapshot.Options{
+		Name:       snapshot.DefaultName(layerName, time.Now()),
+		RunID:      runID,
+		ConfigHash: config.Current().Hash(),
+		Notes:      "Fact table after " + layerName,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to count snapshot rows: %v", err)
+		return err
 	}
-	
-	fmt.Printf("✓ Created snapshot %s with %d records\n", snapshotTable, count)
+
+	fmt.Printf("✓ Created snapshot %s with %d records (%d matched)\n", snap.Name, snap.Rows, snap.Matched)
 	return nil
 }
 
-// rebuildFactTableIntelligent implements the intelligent fact table population strategy
-// Phase 1: Insert records with UPRN matches
-// Phase 2: Insert records with canonical address matches  
-// Phase 3: Insert remaining unmatched records
-func rebuildFactTableIntelligent(localDebug bool, db *sql.DB) error {
+// rebuildFactTableIntelligent implements the intelligent fact table population strategy.
+// Layer 1 matches are recorded in address_match, so the fact table is built by the same
+// row definition as facts.Apply and later layers' matches are kept:
+// Phase 1: Match documents by their source UPRN
+// Phase 2: Match documents by exact canonical address, then expanded canonical address
+// Phase 3: Rebuild the fact table, leaving the rest unmatched for later layers
+func rebuildFactTableIntelligent(ctx context.Context, localDebug bool, db *sql.DB, runID int64) error {
 	fmt.Println("Rebuilding fact table with intelligent UPRN-first approach...")
 	fmt.Println("===========================================================")
 	
-	// First, clear the fact table
-	fmt.Println("Truncating fact table...")
-	_, err := db.Exec("TRUNCATE TABLE fact_documents_lean")
-	if err != nil {
-		return fmt.Errorf("failed to truncate fact table: %v", err)
-	}
-	
-	// Ensure dim_original_address is up to date
+	// Ensure dim_original_address is up to date, hashed as rowsQuery joins it
 	fmt.Println("Updating dim_original_address...")
 	updateDimOriginalQuery := `
 	INSERT INTO dim_original_address (raw_address, address_hash)
-	SELECT DISTINCT 
+	SELECT DISTINCT ON (MD5(LOWER(TRIM(raw_address))))
 	    raw_address,
-	    MD5(UPPER(TRIM(raw_address))) as address_hash
+	    MD5(LOWER(TRIM(raw_address))) as address_hash
 	FROM src_document s
 	WHERE raw_address IS NOT NULL 
 	  AND raw_address != ''
-	  AND NOT EXISTS (
-	    SELECT 1 FROM dim_original_address oa 
-	    WHERE oa.raw_address = s.raw_address
-	  )
+	ON CONFLICT (address_hash) DO NOTHING
 	`
-	result, err := db.Exec(updateDimOriginalQuery)
+	result, err := db.ExecContext(ctx, updateDimOriginalQuery)
 	if err != nil {
 		return fmt.Errorf("failed to update dim_original_address: %v", err)
 	}
@@ -80,269 +60,112 @@ func rebuildFactTableIntelligent(localDebug bool, db *sql.DB) error {
 		fmt.Printf("  ✓ Added %d new original addresses\n", newOriginalAddresses)
 	}
 	
-	// PHASE 1: Insert records with UPRN matches
-	fmt.Println("\nPhase 1: Inserting records with UPRN matches...")
+	// PHASE 1: Match documents by source UPRN
+	fmt.Println("\nPhase 1: Matching documents by source UPRN...")
 	uprnQuery := `
-	INSERT INTO fact_documents_lean (
-		document_id, doc_type_id, document_status_id, original_address_id,
-		matched_address_id, match_method_id, match_confidence_score, 
-		property_type_id, application_status_id, development_type_id,
-		application_date_id, decision_date_id, import_date_id, 
-		match_decision_id, matched_location_id, planning_reference
-	)
 	SELECT DISTINCT ON (s.document_id)
-		s.document_id,
-		COALESCE(s.doc_type_id, 1) as doc_type_id,
-		1 as document_status_id,  -- Active
-		oa.original_address_id,
-		da.address_id as matched_address_id,  -- UPRN MATCH!
+		s.document_id, da.address_id, da.location_id,
 		1 as match_method_id,  -- "Exact UPRN Match"
-		1.0 as match_confidence_score,
-		NULL as property_type_id,  -- Unknown
-		NULL as application_status_id,
-		NULL as development_type_id,
-		CASE 
-			WHEN s.document_date IS NOT NULL THEN TO_CHAR(s.document_date, 'YYYYMMDD')::INTEGER
-			ELSE NULL
-		END as application_date_id,
-		NULL::INTEGER as decision_date_id,
-		TO_CHAR(CURRENT_DATE, 'YYYYMMDD')::INTEGER as import_date_id,
-		2 as match_decision_id,  -- "Accepted"
-		da.location_id as matched_location_id,
-		CASE 
-		WHEN s.planning_app_sequence IS NOT NULL AND s.planning_app_sequence != '' 
-		THEN s.planning_app_base || '/' || s.planning_app_sequence
-		ELSE s.planning_app_base
-	END as planning_reference
+		1.0 as confidence_score
 	FROM src_document s
-	INNER JOIN dim_original_address oa ON oa.raw_address = s.raw_address
 	INNER JOIN dim_address da ON da.uprn = s.raw_uprn  -- UPRN MATCH
 	WHERE s.raw_uprn IS NOT NULL 
 	  AND s.raw_uprn != ''
-	  AND da.uprn IS NOT NULL
+	  AND NOT EXISTS (SELECT 1 FROM address_match am WHERE am.document_id = s.document_id)
+	  AND s.removed_at IS NULL
 	ORDER BY s.document_id, da.address_id
 	`
-	
-	result, err = db.Exec(uprnQuery)
+	uprnMatches, err := saveLayer1Matches(ctx, db, uprnQuery)
 	if err != nil {
-		return fmt.Errorf("failed to insert UPRN matches: %v", err)
+		return fmt.Errorf("failed to record UPRN matches: %v", err)
 	}
-	uprnMatches, _ := result.RowsAffected()
-	fmt.Printf("  ✓ Inserted %d records with UPRN matches\n", uprnMatches)
+	fmt.Printf("  ✓ Recorded %d UPRN matches\n", uprnMatches)
 	
-	// PHASE 2: Insert records with exact canonical address matches
-	fmt.Println("\nPhase 2: Inserting records with exact canonical address matches...")
+	// PHASE 2: Match documents by exact canonical address
+	fmt.Println("\nPhase 2: Matching documents by exact canonical address...")
 	
 	// First ensure we have an index on canonical addresses for speed
 	fmt.Println("  Creating index on canonical addresses...")
-	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_dim_address_canonical ON dim_address(address_canonical)")
+	_, err = db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_dim_address_canonical ON dim_address(address_canonical)")
 	if err != nil {
 		fmt.Printf("  Warning: failed to create canonical index: %v\n", err)
 	}
 	
 	canonicalQuery := `
-	WITH unmatched_canonical AS (
-		-- Get unique canonical addresses from unmatched source documents
-		SELECT DISTINCT 
-			UPPER(REGEXP_REPLACE(COALESCE(s.standardized_address, s.raw_address), '[^\w\s]', '', 'g')) as canonical_address
-		FROM src_document s
-		WHERE NOT EXISTS (SELECT 1 FROM fact_documents_lean f WHERE f.document_id = s.document_id)
-		  AND s.raw_uprn IS NULL OR s.raw_uprn = ''  -- No UPRN in source
-	)
-	INSERT INTO fact_documents_lean (
-		document_id, doc_type_id, document_status_id, original_address_id,
-		matched_address_id, match_method_id, match_confidence_score,
-		property_type_id, application_status_id, development_type_id,
-		application_date_id, decision_date_id, import_date_id,
-		match_decision_id, matched_location_id, planning_reference
-	)
 	SELECT DISTINCT ON (s.document_id)
-		s.document_id,
-		COALESCE(s.doc_type_id, 1) as doc_type_id,
-		1 as document_status_id,
-		oa.original_address_id,
-		da.address_id as matched_address_id,  -- EXACT CANONICAL MATCH!
+		s.document_id, da.address_id, da.location_id,
 		2 as match_method_id,  -- "Exact Canonical Match"
-		1.0 as match_confidence_score,  -- Exact match
-		NULL::INTEGER as property_type_id,
-		NULL::INTEGER as application_status_id,
-		NULL::INTEGER as development_type_id,
-		CASE 
-			WHEN s.document_date IS NOT NULL THEN TO_CHAR(s.document_date, 'YYYYMMDD')::INTEGER
-			ELSE NULL
-		END as application_date_id,
-		NULL::INTEGER as decision_date_id,
-		TO_CHAR(CURRENT_DATE, 'YYYYMMDD')::INTEGER as import_date_id,
-		2 as match_decision_id,  -- "Accepted"
-		da.location_id as matched_location_id,
-		CASE 
-		WHEN s.planning_app_sequence IS NOT NULL AND s.planning_app_sequence != '' 
-		THEN s.planning_app_base || '/' || s.planning_app_sequence
-		ELSE s.planning_app_base
-	END as planning_reference
+		1.0 as confidence_score
 	FROM src_document s
-	INNER JOIN dim_original_address oa ON oa.raw_address = s.raw_address
 	INNER JOIN dim_address da ON 
 		da.address_canonical = UPPER(REGEXP_REPLACE(COALESCE(s.standardized_address, s.raw_address), '[^\w\s]', '', 'g'))
-	WHERE NOT EXISTS (SELECT 1 FROM fact_documents_lean f WHERE f.document_id = s.document_id)  -- Not already inserted
-	  AND (s.raw_uprn IS NULL OR s.raw_uprn = '')  -- No UPRN in source
+	WHERE (s.raw_uprn IS NULL OR s.raw_uprn = '')  -- No UPRN in source
+	  AND NOT EXISTS (SELECT 1 FROM address_match am WHERE am.document_id = s.document_id)
+	  AND s.removed_at IS NULL
 	ORDER BY s.document_id, da.address_id
 	`
-	
-	result, err = db.Exec(canonicalQuery)
+	canonicalMatches, err := saveLayer1Matches(ctx, db, canonicalQuery)
 	if err != nil {
-		return fmt.Errorf("failed to insert canonical matches: %v", err)
+		return fmt.Errorf("failed to record canonical matches: %v", err)
 	}
-	canonicalMatches, _ := result.RowsAffected()
-	fmt.Printf("  ✓ Inserted %d records with canonical address matches\n", canonicalMatches)
+	fmt.Printf("  ✓ Recorded %d canonical address matches\n", canonicalMatches)
 	
 	// PHASE 2b: Also check exact canonical matches in dim_address_expanded
 	fmt.Println("\nPhase 2b: Checking expanded addresses for exact canonical matches...")
 	
 	// Create index on expanded canonical addresses for speed
-	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_dim_address_expanded_canonical ON dim_address_expanded(address_canonical)")
+	_, err = db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_dim_address_expanded_canonical ON dim_address_expanded(address_canonical)")
 	if err != nil {
 		fmt.Printf("  Warning: failed to create expanded canonical index: %v\n", err)
 	}
 	
 	expandedCanonicalQuery := `
-	INSERT INTO fact_documents_lean (
-		document_id, doc_type_id, document_status_id, original_address_id,
-		matched_address_id, match_method_id, match_confidence_score,
-		property_type_id, application_status_id, development_type_id,
-		application_date_id, decision_date_id, import_date_id,
-		match_decision_id, matched_location_id, planning_reference
-	)
 	SELECT DISTINCT ON (s.document_id)
-		s.document_id,
-		COALESCE(s.doc_type_id, 1) as doc_type_id,
-		1 as document_status_id,
-		oa.original_address_id,
-		dae.original_address_id as matched_address_id,  -- EXPANDED CANONICAL MATCH!
+		s.document_id, da.address_id, da.location_id,
 		3 as match_method_id,  -- "Expanded Canonical Match"
-		1.0 as match_confidence_score,  -- Exact match
-		NULL::INTEGER as property_type_id,
-		NULL::INTEGER as application_status_id,
-		NULL::INTEGER as development_type_id,
-		CASE 
-			WHEN s.document_date IS NOT NULL THEN TO_CHAR(s.document_date, 'YYYYMMDD')::INTEGER
-			ELSE NULL
-		END as application_date_id,
-		NULL::INTEGER as decision_date_id,
-		TO_CHAR(CURRENT_DATE, 'YYYYMMDD')::INTEGER as import_date_id,
-		2 as match_decision_id,  -- "Accepted"
-		da.location_id as matched_location_id,
-		CASE 
-		WHEN s.planning_app_sequence IS NOT NULL AND s.planning_app_sequence != '' 
-		THEN s.planning_app_base || '/' || s.planning_app_sequence
-		ELSE s.planning_app_base
-	END as planning_reference
+		1.0 as confidence_score
 	FROM src_document s
-	INNER JOIN dim_original_address oa ON oa.raw_address = s.raw_address
 	INNER JOIN dim_address_expanded dae ON 
 		dae.address_canonical = UPPER(REGEXP_REPLACE(COALESCE(s.standardized_address, s.raw_address), '[^\w\s]', '', 'g'))
 	INNER JOIN dim_address da ON da.address_id = dae.original_address_id
-	WHERE NOT EXISTS (SELECT 1 FROM fact_documents_lean f WHERE f.document_id = s.document_id)  -- Not already inserted
-	  AND (s.raw_uprn IS NULL OR s.raw_uprn = '')  -- No UPRN in source
+	WHERE (s.raw_uprn IS NULL OR s.raw_uprn = '')  -- No UPRN in source
+	  AND NOT EXISTS (SELECT 1 FROM address_match am WHERE am.document_id = s.document_id)
+	  AND s.removed_at IS NULL
 	ORDER BY s.document_id, dae.original_address_id
 	`
-	
-	result, err = db.Exec(expandedCanonicalQuery)
+	expandedCanonicalMatches, err := saveLayer1Matches(ctx, db, expandedCanonicalQuery)
 	if err != nil {
 		// Ignore errors for expanded addresses as table might not exist
 		if localDebug {
 			fmt.Printf("  Note: Expanded canonical matching skipped: %v\n", err)
 		}
 	} else {
-		expandedCanonicalMatches, _ := result.RowsAffected()
-		fmt.Printf("  ✓ Inserted %d records with expanded canonical matches\n", expandedCanonicalMatches)
+		fmt.Printf("  ✓ Recorded %d expanded canonical matches\n", expandedCanonicalMatches)
 		canonicalMatches += expandedCanonicalMatches
 	}
 	
-	// PHASE 3: Insert remaining unmatched records
-	fmt.Println("\nPhase 3: Inserting remaining unmatched records...")
-	
-	// Check how many records are already in fact table
-	var currentFactCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM fact_documents_lean").Scan(&currentFactCount)
-	if err == nil {
-		fmt.Printf("  Current fact table has %d records before Phase 3\n", currentFactCount)
-	}
-	
-	// Check how many source documents should be unmatched
-	var sourceCount, remainingCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM src_document").Scan(&sourceCount)
-	if err == nil {
-		remainingCount = sourceCount - currentFactCount
-		fmt.Printf("  Source documents: %d, Already processed: %d, Remaining: %d\n", 
-			sourceCount, currentFactCount, remainingCount)
+	// PHASE 3: Rebuild every fact row from the recorded matches
+	fmt.Println("\nPhase 3: Rebuilding fact table...")
+	totalRecords, err := facts.Rebuild(ctx, localDebug, db)
+	if err != nil {
+		return err
 	}
 	
-	unmatchedQuery := `
-	INSERT INTO fact_documents_lean (
-		document_id, doc_type_id, document_status_id, original_address_id,
-		matched_address_id, match_method_id, match_confidence_score,
-		property_type_id, application_status_id, development_type_id,
-		application_date_id, decision_date_id, import_date_id,
-		match_decision_id, matched_location_id, planning_reference
-	)
-	SELECT DISTINCT ON (s.document_id)
-		s.document_id,
-		COALESCE(s.doc_type_id, 1) as doc_type_id,
-		1 as document_status_id,
-		oa.original_address_id,
-		NULL::INTEGER as matched_address_id,  -- NO MATCH YET
-		NULL::INTEGER as match_method_id,
-		NULL::NUMERIC as match_confidence_score,
-		NULL::INTEGER as property_type_id,
-		NULL::INTEGER as application_status_id,
-		NULL::INTEGER as development_type_id,
-		CASE 
-			WHEN s.document_date IS NOT NULL THEN TO_CHAR(s.document_date, 'YYYYMMDD')::INTEGER
-			ELSE NULL
-		END as application_date_id,
-		NULL::INTEGER as decision_date_id,
-		TO_CHAR(CURRENT_DATE, 'YYYYMMDD')::INTEGER as import_date_id,
-		1 as match_decision_id,  -- "Pending"
-		NULL::INTEGER as matched_location_id,
-		CASE 
-		WHEN s.planning_app_sequence IS NOT NULL AND s.planning_app_sequence != '' 
-		THEN s.planning_app_base || '/' || s.planning_app_sequence
-		ELSE s.planning_app_base
-	END as planning_reference
-	FROM src_document s
-	INNER JOIN dim_original_address oa ON oa.raw_address = s.raw_address
-	WHERE NOT EXISTS (SELECT 1 FROM fact_documents_lean f WHERE f.document_id = s.document_id)  -- Not already inserted
-	ORDER BY s.document_id, oa.original_address_id
-	`
-	
-	result, err = db.Exec(unmatchedQuery)
+	var matchedRecords int64
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fact_documents_lean WHERE matched_address_id IS NOT NULL").Scan(&matchedRecords)
 	if err != nil {
-		return fmt.Errorf("failed to insert unmatched records: %v", err)
+		return fmt.Errorf("failed to count matched records: %v", err)
 	}
-	unmatchedRecords, _ := result.RowsAffected()
-	fmt.Printf("  ✓ Inserted %d unmatched records\n", unmatchedRecords)
+	unmatchedRecords := totalRecords - matchedRecords
 	
 	// Summary
-	totalRecords := uprnMatches + canonicalMatches + unmatchedRecords
 	fmt.Printf("\n=== INTELLIGENT FACT TABLE SUMMARY ===\n")
 	fmt.Printf("Total records: %d\n", totalRecords)
-	fmt.Printf("  UPRN matches: %d (%.1f%%)\n", uprnMatches, float64(uprnMatches)/float64(totalRecords)*100)
-	fmt.Printf("  Canonical matches: %d (%.1f%%)\n", canonicalMatches, float64(canonicalMatches)/float64(totalRecords)*100)
+	fmt.Printf("  New UPRN matches: %d\n", uprnMatches)
+	fmt.Printf("  New canonical matches: %d\n", canonicalMatches)
+	fmt.Printf("  Matched, including earlier layers and reviews: %d (%.1f%%)\n", matchedRecords, float64(matchedRecords)/float64(totalRecords)*100)
 	fmt.Printf("  Unmatched (for further processing): %d (%.1f%%)\n", unmatchedRecords, float64(unmatchedRecords)/float64(totalRecords)*100)
 	
-	// Verify all source records are accounted for
-	var totalSourceCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM src_document").Scan(&totalSourceCount)
-	if err == nil {
-		if int64(totalSourceCount) == totalRecords {
-			fmt.Printf("\n✓ All %d source records accounted for\n", totalSourceCount)
-		} else {
-			fmt.Printf("\n⚠ Warning: %d source records but %d in fact table (difference: %d)\n", 
-				totalSourceCount, totalRecords, int64(totalSourceCount)-totalRecords)
-		}
-	}
-	
 	// Show sample matches from each phase
 	if localDebug {
 		fmt.Println("\n=== SAMPLE MATCHES ===")
@@ -394,10 +217,29 @@ func rebuildFactTableIntelligent(localDebug bool, db *sql.DB) error {
 	
 	// Create Layer 1 snapshot after intelligent fact table population
 	fmt.Println("\n=== CREATING LAYER 1 SNAPSHOT ===")
-	err = createLayerSnapshot(localDebug, db, "layer_1")
+	err = createLayerSnapshot(ctx, localDebug, db, runID, "layer_1")
 	if err != nil {
 		return fmt.Errorf("failed to create Layer 1 snapshot: %v", err)
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+// saveLayer1Matches records the matches a query selects in address_match as system_layer1.
+// The query returns document_id, address_id, location_id, match_method_id and
+// confidence_score; the rejection trigger drops rejected pairs.
+func saveLayer1Matches(ctx context.Context, db *sql.DB, query string) (int64, error) {
+	result, err := db.ExecContext(ctx, `
+	INSERT INTO address_match (
+		document_id, address_id, location_id, match_method_id,
+		confidence_score, match_status, matched_by, matched_at
+	)
+	SELECT document_id, address_id, location_id, match_method_id,
+		confidence_score, 'auto', 'system_layer1', now()
+	FROM (`+query+`) layer1
+	ON CONFLICT (document_id) DO NOTHING
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
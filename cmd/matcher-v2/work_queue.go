@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ehdc-llpg/internal/matcher"
+	"github.com/ehdc-llpg/internal/queue"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// enqueueRun creates a queued run of unmatched documents or deduplicated addresses for
+// queue-work processes to share
+func enqueueRun(ctx context.Context, localDebug bool, db *sql.DB, kind, label string, chunkSize int) error {
+	var items []string
+	var err error
+	switch kind {
+	case store.WorkDocuments:
+		items, err = matcher.UnmatchedDocumentIDs(ctx, db, "")
+	case store.WorkAddresses:
+		items, err = unmatchedAddresses(ctx, db)
+	default:
+		return fmt.Errorf("unknown queue kind %q (use %s or %s)", kind, store.WorkDocuments, store.WorkAddresses)
+	}
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		fmt.Println("Nothing to enqueue: no unmatched", kind)
+		return nil
+	}
+
+	if label == "" {
+		label = "queue-" + kind
+	}
+	if chunkSize <= 0 {
+		chunkSize = queue.DefaultChunkSize
+	}
+
+	stores := store.NewPostgresStores(db)
+	run, err := stores.Matches.CreateRun(ctx, label, version,
+		fmt.Sprintf("Work queue of %d %s in chunks of %d", len(items), kind, chunkSize))
+	if err != nil {
+		return err
+	}
+	chunks, err := stores.Queue.Enqueue(ctx, run.RunID, kind, items, chunkSize)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Run %d: enqueued %d %s in %d chunks\n", run.RunID, len(items), kind, chunks)
+	fmt.Println("Start workers on each server with:")
+	fmt.Printf("  ./matcher-v2 -cmd=queue-work -run-id=%d\n", run.RunID)
+	fmt.Println("and follow them with:")
+	fmt.Printf("  ./matcher-v2 -cmd=queue-status -run-id=%d -watch=30s\n", run.RunID)
+	return nil
+}
+
+// unmatchedAddresses lists the addresses layer3-enhanced would match, as queue items
+func unmatchedAddresses(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, uniqueAddressesSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unique addresses: %w", err)
+	}
+	defer rows.Close()
+
+	var items []string
+	for rows.Next() {
+		var addr UniqueAddress
+		if err := rows.Scan(&addr.RawAddress, &addr.DocumentCount, &addr.SampleDocumentID); err != nil {
+			return nil, fmt.Errorf("failed to scan unique address: %w", err)
+		}
+		items = append(items, addr.RawAddress)
+	}
+	return items, rows.Err()
+}
+
+// runQueueWorkers works through a queued run with workers goroutines until the queue is
+// drained. Run it on as many servers as needed; the queue keeps them apart.
+func runQueueWorkers(ctx context.Context, localDebug bool, db *sql.DB, runID int64, workers int) error {
+	if runID == 0 {
+		return fmt.Errorf("-run-id is required")
+	}
+	if workers <= 0 {
+		workers = getOptimalWorkerCount()
+	}
+
+	stores := store.NewPostgresStores(db)
+	documents := matcher.DocumentHandler(db)
+
+	// Attribute this process's writes to the run, for rollback-run
+	if err := stores.Matches.JoinRun(ctx, runID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Working on queued run %d with %d workers...\n", runID, workers)
+	startTime := time.Now()
+
+	stats := make([]*queue.WorkerStats, workers)
+	errs := make([]error, workers)
+	ids := make([]string, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		ids[i] = queue.WorkerID(i)
+		handle := func(workerID int) queue.Handler {
+			return func(ctx context.Context, chunk *store.WorkChunk) (store.ChunkResult, error) {
+				switch chunk.Kind {
+				case store.WorkDocuments:
+					return documents(ctx, chunk)
+				case store.WorkAddresses:
+					return matchAddressChunk(ctx, localDebug, db, workerID, chunk)
+				}
+				return store.ChunkResult{}, fmt.Errorf("unknown chunk kind %q", chunk.Kind)
+			}
+		}(i)
+
+		wg.Add(1)
+		go func(i int, handle queue.Handler) {
+			defer wg.Done()
+			stats[i], errs[i] = queue.NewWorker(stores, runID, ids[i], handle, queue.DefaultOptions()).Run(ctx, localDebug)
+		}(i, handle)
+	}
+	wg.Wait()
+
+	fmt.Printf("\n=== WORKERS (%s) ===\n", time.Since(startTime).Round(time.Second))
+	var firstErr error
+	for i, s := range stats {
+		fmt.Printf("%-32s %4d chunks %7d items %6d matched %5d errors %3d released %3d lost\n",
+			ids[i], s.Chunks, s.Processed, s.Matched, s.Errors, s.Released, s.Lost)
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+
+	if errors.Is(firstErr, context.Canceled) {
+		fmt.Printf("Interrupted: claimed chunks were returned to the queue. Restart with -cmd=queue-work -run-id=%d\n", runID)
+	}
+	return firstErr
+}
+
+// matchAddressChunk fuzzy matches a chunk of deduplicated addresses as layer3-enhanced does
+func matchAddressChunk(ctx context.Context, localDebug bool, db *sql.DB, workerID int, chunk *store.WorkChunk) (store.ChunkResult, error) {
+	batch := make([]UniqueAddress, len(chunk.Items))
+	for i, item := range chunk.Items {
+		batch[i] = UniqueAddress{RawAddress: item}
+	}
+
+	result := processFuzzyMatchBatch(ctx, workerID, batch, db, localDebug)
+	if err := ctx.Err(); err != nil {
+		return store.ChunkResult{}, err
+	}
+	return store.ChunkResult{Processed: len(result.attempted), Matched: result.matches}, nil
+}
+
+// queueStatus re-queues abandoned chunks and reports a queued run's progress and per-worker
+// throughput, repeating every watch interval until the queue drains when watch is set
+func queueStatus(ctx context.Context, localDebug bool, db *sql.DB, runID int64, watch time.Duration) error {
+	if runID == 0 {
+		return fmt.Errorf("-run-id is required")
+	}
+	stores := store.NewPostgresStores(db)
+
+	for {
+		report, err := queue.Coordinate(ctx, stores, runID, queue.DefaultOptions())
+		if err != nil {
+			return err
+		}
+		printQueueReport(report)
+
+		if watch <= 0 || report.Finished {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watch):
+		}
+	}
+}
+
+// printQueueReport prints the coordinator's view of a queued run
+func printQueueReport(r *queue.Report) {
+	p := r.Progress
+	fmt.Printf("\n=== QUEUED RUN %d (%s) ===\n", r.RunID, time.Now().Format("15:04:05"))
+	fmt.Printf("Chunks: %d pending, %d claimed, %d done, %d failed\n", p.Pending, p.Claimed, p.Done, p.Failed)
+	if p.Items > 0 {
+		fmt.Printf("Items: %d of %d processed (%.1f%%), %d matched, %d errors\n",
+			p.Processed, p.Items, float64(p.Processed)/float64(p.Items)*100, p.Matched, p.Errors)
+	}
+	if r.Requeued > 0 {
+		fmt.Printf("Re-queued %d abandoned chunks\n", r.Requeued)
+	}
+
+	if len(r.Workers) > 0 {
+		fmt.Printf("\n%-32s %7s %8s %8s %7s %9s %8s  %s\n",
+			"Worker", "Chunks", "Items", "Matched", "Errors", "Items/s", "Holding", "Last seen")
+		for _, w := range r.Workers {
+			lastSeen := "-"
+			if w.LastSeen != nil {
+				lastSeen = time.Since(*w.LastSeen).Round(time.Second).String() + " ago"
+			}
+			fmt.Printf("%-32s %7d %8d %8d %7d %9.1f %8d  %s\n",
+				w.WorkerID, w.Chunks, w.Processed, w.Matched, w.Errors, w.ItemsPerSecond(), w.Holding, lastSeen)
+		}
+	}
+
+	if r.Finished {
+		if p.Failed > 0 {
+			fmt.Printf("\nQueue drained with %d failed chunks; run %d recorded as failed\n", p.Failed, r.RunID)
+		} else {
+			fmt.Printf("\nQueue drained; run %d completed\n", r.RunID)
+		}
+	}
+}
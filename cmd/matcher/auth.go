@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ehdc-llpg/internal/auth"
+	"github.com/ehdc-llpg/internal/snapshot"
+)
+
+// createAuthCmd creates the auth subcommand
+func createAuthCmd() *cobra.Command {
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage web interface users and API keys",
+		Long: `Add users of the web interface, set their roles and passwords, and issue and revoke
+API keys. Roles, least privileged first: viewer (read only), reviewer (accept, reject and
+work the review queue), senior_reviewer (also sign off low-confidence accepts and move
+coordinates) and admin (everything).`,
+	}
+
+	userCmd := &cobra.Command{Use: "user", Short: "Manage users"}
+	userCmd.AddCommand(createAuthUserAddCmd())
+	userCmd.AddCommand(createAuthUserListCmd())
+	userCmd.AddCommand(createAuthUserSetRoleCmd())
+	userCmd.AddCommand(createAuthUserPasswdCmd())
+	userCmd.AddCommand(createAuthUserDisableCmd(true))
+	userCmd.AddCommand(createAuthUserDisableCmd(false))
+	userCmd.AddCommand(createAuthUserSignOutCmd())
+
+	keyCmd := &cobra.Command{Use: "key", Short: "Manage API keys"}
+	keyCmd.AddCommand(createAuthKeyCreateCmd())
+	keyCmd.AddCommand(createAuthKeyListCmd())
+	keyCmd.AddCommand(createAuthKeyRevokeCmd())
+
+	authCmd.AddCommand(userCmd)
+	authCmd.AddCommand(keyCmd)
+	return authCmd
+}
+
+func authStore() *auth.Store {
+	return auth.NewStore(dbConn.DB, cfg.Web.SessionKey)
+}
+
+// operator names who ran the command, for created_by
+func operator() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "matcher"
+}
+
+// readPassword takes a password from the first line of stdin, or generates one and
+// reports it
+func readPassword(fromStdin bool) (string, error) {
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	password, err := auth.GeneratePassword()
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Password: %s\n(shown once; give it to the user over a trusted channel)\n", password)
+	return password, nil
+}
+
+func createAuthUserAddCmd() *cobra.Command {
+	var role, name string
+	var passwordStdin, generate bool
+
+	cmd := &cobra.Command{
+		Use:   "add [username]",
+		Short: "Add a user; without a password option the user can only use API keys",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			r, err := auth.ParseRole(role)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			password := ""
+			if passwordStdin || generate {
+				if password, err = readPassword(passwordStdin); err != nil {
+					log.Fatalf("%v", err)
+				}
+			}
+
+			u, err := authStore().CreateUser(cmd.Context(), args[0], name, r, password, operator())
+			if err != nil {
+				log.Fatalf("Failed to add user: %v", err)
+			}
+			fmt.Printf("Added %s (%s)\n", u.Username, u.Role)
+			if !u.HasPassword {
+				fmt.Printf("No password: issue a key with matcher auth key create %s\n", u.Username)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&role, "role", string(auth.RoleViewer), "viewer, reviewer, senior_reviewer or admin")
+	cmd.Flags().StringVar(&name, "name", "", "Display name")
+	cmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "Read the password from the first line of stdin")
+	cmd.Flags().BoolVar(&generate, "generate-password", false, "Generate a password and print it")
+	return cmd
+}
+
+func createAuthUserListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List users",
+		Run: func(cmd *cobra.Command, args []string) {
+			users, err := authStore().Users(cmd.Context())
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if len(users) == 0 {
+				fmt.Println("No users")
+				return
+			}
+
+			fmt.Printf("%-20s %-16s %-8s %4s %-16s %-10s %s\n", "USERNAME", "ROLE", "PASSWORD", "KEYS", "LAST SIGN-IN", "STATUS", "NAME")
+			for _, u := range users {
+				password, lastLogin, status := "no", "-", "active"
+				if u.HasPassword {
+					password = "yes"
+				}
+				if u.LastLoginAt != nil {
+					lastLogin = u.LastLoginAt.Local().Format("2006-01-02 15:04")
+				}
+				if u.DisabledAt != nil {
+					status = "disabled"
+				}
+				fmt.Printf("%-20s %-16s %-8s %4d %-16s %-10s %s\n", u.Username, u.Role, password, u.Keys, lastLogin, status, u.DisplayName)
+			}
+		},
+	}
+}
+
+func createAuthUserSetRoleCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-role [username] [role]",
+		Short: "Change a user's role; it applies to their next request",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			r, err := auth.ParseRole(args[1])
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if err := authStore().SetRole(cmd.Context(), args[0], r); err != nil {
+				log.Fatalf("Failed to set role: %v", err)
+			}
+			fmt.Printf("%s is now %s\n", args[0], r)
+		},
+	}
+}
+
+func createAuthUserPasswdCmd() *cobra.Command {
+	var passwordStdin bool
+
+	cmd := &cobra.Command{
+		Use:   "passwd [username]",
+		Short: "Set a new password (generated unless --password-stdin) and end the user's sessions",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			password, err := readPassword(passwordStdin)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if err := authStore().SetPassword(cmd.Context(), args[0], password); err != nil {
+				log.Fatalf("Failed to set password: %v", err)
+			}
+			fmt.Printf("Password changed for %s\n", args[0])
+		},
+	}
+
+	cmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "Read the password from the first line of stdin")
+	return cmd
+}
+
+func createAuthUserDisableCmd(disable bool) *cobra.Command {
+	use, short, done := "enable [username]", "Let a disabled user sign in and use their keys again", "enabled"
+	if disable {
+		use, short, done = "disable [username]", "Stop a user signing in or using their keys, and end their sessions", "disabled"
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := authStore().SetDisabled(cmd.Context(), args[0], disable); err != nil {
+				log.Fatalf("Failed: %v", err)
+			}
+			fmt.Printf("%s %s\n", args[0], done)
+		},
+	}
+}
+
+func createAuthUserSignOutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sign-out [username]",
+		Short: "End every session of a user",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			n, err := authStore().RevokeSessions(cmd.Context(), args[0])
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			fmt.Printf("Ended %d sessions of %s\n", n, args[0])
+		},
+	}
+}
+
+func createAuthKeyCreateCmd() *cobra.Command {
+	var label, expires string
+
+	cmd := &cobra.Command{
+		Use:   "create [username]",
+		Short: "Issue an API key acting as the user; it is printed once",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var expiresAt time.Time
+			if expires != "" && expires != "never" {
+				age, err := snapshot.ParseAge(expires)
+				if err != nil {
+					log.Fatalf("Invalid --expires: %v", err)
+				}
+				expiresAt = time.Now().Add(age)
+			}
+
+			key, k, err := authStore().CreateAPIKey(cmd.Context(), args[0], label, expiresAt, operator())
+			if err != nil {
+				log.Fatalf("Failed to create key: %v", err)
+			}
+			fmt.Printf("Key %d for %s", k.KeyID, k.Username)
+			if k.ExpiresAt != nil {
+				fmt.Printf(", expires %s", k.ExpiresAt.Local().Format("2006-01-02 15:04"))
+			}
+			fmt.Printf("\n\n%s\n\nSend it as the X-API-Key header. It is not stored and cannot be shown again.\n", key)
+		},
+	}
+
+	cmd.Flags().StringVar(&label, "label", "", "What the key is for")
+	cmd.Flags().StringVar(&expires, "expires", "90d", "Lifetime, e.g. 30d or 12h, or never")
+	return cmd
+}
+
+func createAuthKeyListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [username]",
+		Short: "List API keys, of one user or everyone",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			username := ""
+			if len(args) == 1 {
+				username = args[0]
+			}
+			keys, err := authStore().APIKeys(cmd.Context(), username)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if len(keys) == 0 {
+				fmt.Println("No API keys")
+				return
+			}
+
+			const stamp = "2006-01-02 15:04"
+			fmt.Printf("%-6s %-20s %-14s %-16s %-16s %-16s %s\n", "ID", "USER", "KEY", "CREATED", "EXPIRES", "LAST USED", "LABEL")
+			for _, k := range keys {
+				expires, used := "never", "-"
+				if k.ExpiresAt != nil {
+					expires = k.ExpiresAt.Local().Format(stamp)
+				}
+				if k.RevokedAt != nil {
+					expires = "revoked"
+				}
+				if k.LastUsedAt != nil {
+					used = k.LastUsedAt.Local().Format(stamp)
+				}
+				fmt.Printf("%-6d %-20s %-14s %-16s %-16s %-16s %s\n", k.KeyID, k.Username, "ehdc_"+k.Prefix+"_…",
+					k.CreatedAt.Local().Format(stamp), expires, used, k.Label)
+			}
+		},
+	}
+}
+
+func createAuthKeyRevokeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke [key_id]",
+		Short: "Stop an API key working",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			keyID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid key ID %q", args[0])
+			}
+			if err := authStore().RevokeAPIKey(cmd.Context(), keyID); err != nil {
+				log.Fatalf("%v", err)
+			}
+			fmt.Printf("Revoked key %d\n", keyID)
+		},
+	}
+}
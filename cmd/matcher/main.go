@@ -1,53 +1,129 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/ehdc-llpg/internal/config"
 	"github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/engine"
+	"github.com/ehdc-llpg/internal/etl"
+	"github.com/ehdc-llpg/internal/interrupt"
+	"github.com/ehdc-llpg/internal/matcher"
 	import_pkg "github.com/ehdc-llpg/internal/import"
+	"github.com/ehdc-llpg/internal/migrate"
+	"github.com/ehdc-llpg/internal/store"
+	"github.com/ehdc-llpg/migrations"
 )
 
 var (
-	// Global database connection
+	// Global configuration and database connection
+	cfg    *config.Config
 	dbConn *db.Connection
+
+	configFile string
+	overrides  config.Overrides
 )
 
 func main() {
-	var err error
-	
-	// Initialize database connection
-	dbConn, err = db.NewConnection()
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer dbConn.Close()
-
 	// Create root command
 	rootCmd := &cobra.Command{
 		Use:   "matcher",
 		Short: "EHDC LLPG Address Matching System",
 		Long:  `A sophisticated address matching system for East Hampshire District Council LLPG data`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadConfig(); err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			// Initialize database connection
+			var err error
+			dbConn, err = db.NewConnection()
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			return nil
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if dbConn != nil {
+				dbConn.Close()
+			}
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to configuration file (default .env in this or a parent directory)")
+	rootCmd.PersistentFlags().Var(&overrides, "set", "Override a configuration key, e.g. --set DB_HOST=localhost (repeatable)")
 
 	// Add subcommands
 	rootCmd.AddCommand(createImportCmd())
 	rootCmd.AddCommand(createMatchCmd())
 	rootCmd.AddCommand(createPingCmd())
+	rootCmd.AddCommand(createResumeCmd())
+	rootCmd.AddCommand(createRollbackRunCmd())
+	rootCmd.AddCommand(createAuthCmd())
+	rootCmd.AddCommand(createSnapshotCmd())
 	rootCmd.AddCommand(createDBCmd())
+	rootCmd.AddCommand(createConfigCmd())
 
-	// Execute root command
-	if err := rootCmd.Execute(); err != nil {
+	// Execute root command; the first Ctrl-C cancels cmd.Context() so a run can stop
+	// between documents and record itself as interrupted
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
+// loadConfig resolves the configuration (flags > env > file > defaults) and installs it
+func loadConfig() error {
+	var err error
+	cfg, err = config.Load(config.Options{File: configFile, Flags: overrides})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	config.Use(cfg)
+	return nil
+}
+
+// createConfigCmd creates the config subcommand, which needs no database
+func createConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect configuration",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfig()
+		},
+	}
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Show every configuration key with its value and source (secrets redacted)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Write(os.Stdout)
+			if err := cfg.Validate(); err != nil {
+				fmt.Println()
+				return err
+			}
+			return nil
+		},
+	})
+
+	return configCmd
+}
+
 // createPingCmd creates a command to test database connectivity
 func createPingCmd() *cobra.Command {
 	return &cobra.Command{
@@ -75,6 +151,47 @@ func createPingCmd() *cobra.Command {
 	}
 }
 
+// createResumeCmd creates the command that continues a stopped batch run
+func createResumeCmd() *cobra.Command {
+	var localDebug bool
+
+	cmd := &cobra.Command{
+		Use:   "resume [run_id]",
+		Short: "Resume an interrupted or failed batch run from its checkpoint",
+		Long: `Continue a checkpointed batch run (address-matcher, -optimized or -hybrid) where it stopped,
+with the processor, batch size and document type it was started with. Documents already
+attempted are skipped and the statistics combine every leg of the run.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid run ID %q", args[0])
+			}
+
+			stats, err := matcher.ResumeRun(cmd.Context(), localDebug, dbConn.DB, runID)
+			if stats == nil || (err != nil && !errors.Is(err, context.Canceled)) {
+				log.Fatalf("Resume failed: %v", err)
+			}
+
+			fmt.Printf("\nRun %d after %d legs:\n", stats.RunID, stats.Legs)
+			fmt.Printf("  Total documents:  %d\n", stats.TotalDocuments)
+			fmt.Printf("  Processed:        %d\n", stats.ProcessedCount)
+			fmt.Printf("  Auto-accepted:    %d\n", stats.AutoAcceptCount)
+			fmt.Printf("  Needs review:     %d\n", stats.NeedsReviewCount)
+			fmt.Printf("  No match:         %d\n", stats.NoMatchCount)
+			fmt.Printf("  Errors:           %d\n", stats.ErrorCount)
+			fmt.Printf("  Average score:    %.4f\n", stats.AverageScore)
+			fmt.Printf("  Processing time:  %v\n", stats.ProcessingTime)
+			if err != nil {
+				fmt.Printf("\nInterrupted again; continue with: matcher resume %d\n", stats.RunID)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&localDebug, "debug", false, "Enable debug output")
+	return cmd
+}
+
 // createImportCmd creates the import subcommand
 func createImportCmd() *cobra.Command {
 	importCmd := &cobra.Command{
@@ -88,6 +205,7 @@ func createImportCmd() *cobra.Command {
 	importCmd.AddCommand(createImportLandChargeCmd())
 	importCmd.AddCommand(createImportEnforcementCmd())
 	importCmd.AddCommand(createImportAgreementCmd())
+	importCmd.AddCommand(createImportSourceCmd())
 
 	return importCmd
 }
@@ -101,7 +219,7 @@ func createImportDecisionCmd() *cobra.Command {
 			filename := args[0]
 			importer := import_pkg.NewCSVImporter(dbConn.DB)
 			
-			if err := importer.ImportDecisionNotices(filename); err != nil {
+			if err := importer.ImportDecisionNotices(cmd.Context(), filename); err != nil {
 				log.Fatalf("Failed to import decision notices: %v", err)
 			}
 		},
@@ -117,7 +235,7 @@ func createImportLandChargeCmd() *cobra.Command {
 			filename := args[0]
 			importer := import_pkg.NewCSVImporter(dbConn.DB)
 			
-			if err := importer.ImportLandCharges(filename); err != nil {
+			if err := importer.ImportLandCharges(cmd.Context(), filename); err != nil {
 				log.Fatalf("Failed to import land charges: %v", err)
 			}
 		},
@@ -133,7 +251,7 @@ func createImportEnforcementCmd() *cobra.Command {
 			filename := args[0]
 			importer := import_pkg.NewCSVImporter(dbConn.DB)
 			
-			if err := importer.ImportEnforcementNotices(filename); err != nil {
+			if err := importer.ImportEnforcementNotices(cmd.Context(), filename); err != nil {
 				log.Fatalf("Failed to import enforcement notices: %v", err)
 			}
 		},
@@ -149,12 +267,31 @@ func createImportAgreementCmd() *cobra.Command {
 			filename := args[0]
 			importer := import_pkg.NewCSVImporter(dbConn.DB)
 			
-			if err := importer.ImportAgreements(filename); err != nil {
+			if err := importer.ImportAgreements(cmd.Context(), filename); err != nil {
 				log.Fatalf("Failed to import agreements: %v", err)
 			}
 		},
 	}
 }
+func createImportSourceCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "source [source-type] [filename]",
+		Short: "Import any source CSV using its mapping file",
+		Long: `Import a source CSV using the mapping file for its source type.
+Mappings are read from internal/etl/mappings or SOURCE_MAPPINGS_DIR.
+Available source types: ` + strings.Join(etl.ListSourceMappings(), ", "),
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			sourceType, filename := args[0], args[1]
+			importer := import_pkg.NewCSVImporter(dbConn.DB)
+
+			if err := importer.ImportMapped(cmd.Context(), filename, sourceType); err != nil {
+				log.Fatalf("Failed to import %s: %v", sourceType, err)
+			}
+		},
+	}
+}
+
 // createMatchCmd creates the match subcommand
 func createMatchCmd() *cobra.Command {
 	matchCmd := &cobra.Command{
@@ -180,6 +317,25 @@ func createMatchCmd() *cobra.Command {
 	return matchCmd
 }
 
+// completeRun records how a matching run ended: interrupted runs (runErr is
+// context.Canceled) and failed runs still record the totals they reached
+func completeRun(matchEngine *engine.MatchEngine, runID int64, runErr error, totalProcessed, autoAccepted, needsReview, rejected int) {
+	status := store.RunCompleted
+	switch {
+	case errors.Is(runErr, context.Canceled):
+		status = store.RunInterrupted
+	case runErr != nil:
+		status = store.RunFailed
+	}
+
+	// The command's context is already cancelled after an interrupt
+	ctx, cancel := interrupt.Detached()
+	defer cancel()
+	if err := matchEngine.CompleteMatchRun(ctx, runID, status, totalProcessed, autoAccepted, needsReview, rejected); err != nil {
+		log.Printf("Failed to complete match run: %v", err)
+	}
+}
+
 func createMatchDeterministicCmd() *cobra.Command {
 	var runLabel string
 	var batchSize int
@@ -197,25 +353,22 @@ func createMatchDeterministicCmd() *cobra.Command {
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
 			
 			// Create matching run
-			run, err := matchEngine.CreateMatchRun(runLabel, "v1.0", "Deterministic matching: legacy UPRN validation + exact canonical matches")
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v1.0", "Deterministic matching: legacy UPRN validation + exact canonical matches")
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
 			}
 
 			// Run deterministic matching
 			deterministicMatcher := engine.NewDeterministicMatcher(dbConn.DB)
-			totalProcessed, totalAccepted, err := deterministicMatcher.RunDeterministicMatching(run.RunID, batchSize)
-			if err != nil {
-				log.Fatalf("Deterministic matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, err := deterministicMatcher.RunDeterministicMatching(cmd.Context(), run.RunID, batchSize)
 
 			// Complete the run
 			needsReview := 0 // TODO: Count from match_result table
 			rejected := 0    // TODO: Count from match_result table
 			
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, needsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, needsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Deterministic matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Deterministic Matching Results ===\n")
@@ -251,7 +404,7 @@ func createMatchFuzzyCmd() *cobra.Command {
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
 			
 			// Create matching run
-			run, err := matchEngine.CreateMatchRun(runLabel, "v1.0", fmt.Sprintf("Fuzzy matching: pg_trgm similarity >= %.2f with phonetic and structural filtering", minSimilarity))
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v1.0", fmt.Sprintf("Fuzzy matching: pg_trgm similarity >= %.2f with phonetic and structural filtering", minSimilarity))
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
 			}
@@ -262,17 +415,14 @@ func createMatchFuzzyCmd() *cobra.Command {
 
 			// Run fuzzy matching
 			fuzzyMatcher := engine.NewFuzzyMatcher(dbConn.DB)
-			totalProcessed, totalAccepted, totalNeedsReview, err := fuzzyMatcher.RunFuzzyMatching(run.RunID, batchSize, tiers)
-			if err != nil {
-				log.Fatalf("Fuzzy matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, totalNeedsReview, err := fuzzyMatcher.RunFuzzyMatching(cmd.Context(), run.RunID, batchSize, tiers)
 
 			// Complete the run
 			rejected := totalProcessed - totalAccepted - totalNeedsReview
 			
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, totalNeedsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, totalNeedsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Fuzzy matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Fuzzy Matching Results ===\n")
@@ -313,7 +463,7 @@ func createMatchOptimizedCmd() *cobra.Command {
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
 			
 			// Create matching run
-			run, err := matchEngine.CreateMatchRun(runLabel, "v2.0", 
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v2.0", 
 				fmt.Sprintf("Optimized fuzzy matching: %d workers, similarity >= %.2f", workers, minSimilarity))
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
@@ -325,17 +475,14 @@ func createMatchOptimizedCmd() *cobra.Command {
 
 			// Run optimized fuzzy matching
 			optimizedMatcher := engine.NewOptimizedFuzzyMatcher(dbConn.DB, workers)
-			totalProcessed, totalAccepted, totalNeedsReview, err := optimizedMatcher.RunOptimizedFuzzyMatching(run.RunID, batchSize, tiers)
-			if err != nil {
-				log.Fatalf("Optimized fuzzy matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, totalNeedsReview, err := optimizedMatcher.RunOptimizedFuzzyMatching(cmd.Context(), run.RunID, batchSize, tiers)
 
 			// Complete the run
 			rejected := totalProcessed - totalAccepted - totalNeedsReview
 			
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, totalNeedsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, totalNeedsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Optimized fuzzy matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Optimized Fuzzy Matching Results ===\n")
@@ -379,7 +526,7 @@ func createTuneThresholdsCmd() *cobra.Command {
 			}
 
 			// Run threshold tuning
-			results, err := tuner.TestThresholds(sampleSize)
+			results, err := tuner.TestThresholds(cmd.Context(), sampleSize)
 			if err != nil {
 				log.Fatalf("Threshold tuning failed: %v", err)
 			}
@@ -425,7 +572,7 @@ func createMatchPostcodeCmd() *cobra.Command {
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
 			
 			// Create matching run
-			run, err := matchEngine.CreateMatchRun(runLabel, "v1.0", 
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v1.0", 
 				"Postcode-centric matching with component analysis")
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
@@ -433,17 +580,14 @@ func createMatchPostcodeCmd() *cobra.Command {
 
 			// Run postcode matching
 			postcodeMatcher := engine.NewPostcodeMatcher(dbConn.DB)
-			totalProcessed, totalAccepted, totalNeedsReview, err := postcodeMatcher.RunPostcodeMatching(run.RunID, batchSize)
-			if err != nil {
-				log.Fatalf("Postcode matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, totalNeedsReview, err := postcodeMatcher.RunPostcodeMatching(cmd.Context(), run.RunID, batchSize)
 
 			// Complete the run
 			rejected := totalProcessed - totalAccepted - totalNeedsReview
 			
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, totalNeedsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, totalNeedsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Postcode matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Postcode Matching Results ===\n")
@@ -493,7 +637,7 @@ func createAnalyzeCmd() *cobra.Command {
 
 			// Analyze postcode quality
 			postcodeMatcher := engine.NewPostcodeMatcher(dbConn.DB)
-			if err := postcodeMatcher.AnalyzePostcodeQuality(); err != nil {
+			if err := postcodeMatcher.AnalyzePostcodeQuality(cmd.Context()); err != nil {
 				log.Printf("Failed to analyze postcode quality: %v", err)
 			}
 
@@ -588,22 +732,19 @@ func createMatchSpatialCmd() *cobra.Command {
 			}
 
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
-			run, err := matchEngine.CreateMatchRun(runLabel, "v1.0", 
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v1.0", 
 				fmt.Sprintf("Spatial proximity matching (max distance: %.0fm)", maxDistance))
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
 			}
 
 			spatialMatcher := engine.NewSpatialMatcher(dbConn.DB)
-			totalProcessed, totalAccepted, totalNeedsReview, err := spatialMatcher.RunSpatialMatching(run.RunID, batchSize, maxDistance)
-			if err != nil {
-				log.Fatalf("Spatial matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, totalNeedsReview, err := spatialMatcher.RunSpatialMatching(cmd.Context(), run.RunID, batchSize, maxDistance)
 
 			rejected := totalProcessed - totalAccepted - totalNeedsReview
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, totalNeedsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, totalNeedsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Spatial matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Spatial Matching Results ===\n")
@@ -636,22 +777,19 @@ func createMatchHierarchicalCmd() *cobra.Command {
 			}
 
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
-			run, err := matchEngine.CreateMatchRun(runLabel, "v1.0", 
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v1.0", 
 				"Hierarchical component matching with multi-level fallbacks")
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
 			}
 
 			hierarchicalMatcher := engine.NewHierarchicalMatcher(dbConn.DB)
-			totalProcessed, totalAccepted, totalNeedsReview, err := hierarchicalMatcher.RunHierarchicalMatching(run.RunID, batchSize)
-			if err != nil {
-				log.Fatalf("Hierarchical matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, totalNeedsReview, err := hierarchicalMatcher.RunHierarchicalMatching(cmd.Context(), run.RunID, batchSize)
 
 			rejected := totalProcessed - totalAccepted - totalNeedsReview
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, totalNeedsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, totalNeedsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Hierarchical matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Hierarchical Matching Results ===\n")
@@ -683,22 +821,19 @@ func createMatchRuleCmd() *cobra.Command {
 			}
 
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
-			run, err := matchEngine.CreateMatchRun(runLabel, "v1.0", 
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v1.0", 
 				"Rule-based pattern matching with known address transformations")
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
 			}
 
 			ruleMatcher := engine.NewRuleMatcher(dbConn.DB)
-			totalProcessed, totalAccepted, totalNeedsReview, err := ruleMatcher.RunRuleMatching(run.RunID, batchSize)
-			if err != nil {
-				log.Fatalf("Rule-based matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, totalNeedsReview, err := ruleMatcher.RunRuleMatching(cmd.Context(), run.RunID, batchSize)
 
 			rejected := totalProcessed - totalAccepted - totalNeedsReview
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, totalNeedsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, totalNeedsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Rule-based matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Rule-Based Matching Results ===\n")
@@ -732,22 +867,19 @@ func createMatchVectorCmd() *cobra.Command {
 			}
 
 			matchEngine := engine.NewMatchEngine(dbConn.DB)
-			run, err := matchEngine.CreateMatchRun(runLabel, "v1.0", 
+			run, err := matchEngine.CreateMatchRun(cmd.Context(), runLabel, "v1.0", 
 				fmt.Sprintf("Vector/semantic matching (min similarity: %.2f)", minSimilarity))
 			if err != nil {
 				log.Fatalf("Failed to create match run: %v", err)
 			}
 
 			vectorMatcher := engine.NewVectorMatcher(dbConn.DB, embeddingAPI)
-			totalProcessed, totalAccepted, totalNeedsReview, err := vectorMatcher.RunVectorMatching(run.RunID, batchSize, minSimilarity)
-			if err != nil {
-				log.Fatalf("Vector matching failed: %v", err)
-			}
+			totalProcessed, totalAccepted, totalNeedsReview, err := vectorMatcher.RunVectorMatching(cmd.Context(), run.RunID, batchSize, minSimilarity)
 
 			rejected := totalProcessed - totalAccepted - totalNeedsReview
-			err = matchEngine.CompleteMatchRun(run.RunID, totalProcessed, totalAccepted, totalNeedsReview, rejected)
-			if err != nil {
-				log.Printf("Failed to complete match run: %v", err)
+			completeRun(matchEngine, run.RunID, err, totalProcessed, totalAccepted, totalNeedsReview, rejected)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Vector matching failed: %v", err)
 			}
 
 			fmt.Printf("\n=== Vector Matching Results ===\n")
@@ -775,7 +907,8 @@ func createReviewCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "review",
 		Short: "Interactive manual review interface",
-		Long:  `Start interactive session to manually review candidate matches`,
+		Long: `Start interactive session to manually review candidate matches. For several
+reviewers at once use the web review queue, which leases each record to one reviewer.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			reviewInterface := engine.NewReviewInterface(dbConn.DB)
 			
@@ -786,7 +919,7 @@ func createReviewCmd() *cobra.Command {
 				return
 			}
 
-			if err := reviewInterface.RunInteractiveReview(batchSize, reviewer); err != nil {
+			if err := reviewInterface.RunInteractiveReview(cmd.Context(), batchSize, reviewer); err != nil && !errors.Is(err, context.Canceled) {
 				log.Fatalf("Review session failed: %v", err)
 			}
 		},
@@ -864,6 +997,7 @@ func createDBCmd() *cobra.Command {
 		Long:  `Database utilities for schema updates and maintenance`,
 	}
 	
+	dbCmd.AddCommand(createMigrateCmd())
 	dbCmd.AddCommand(createApplyViewsCmd())
 	dbCmd.AddCommand(createTestViewsCmd())
 	dbCmd.AddCommand(createInspectViewsCmd())
@@ -871,17 +1005,108 @@ func createDBCmd() *cobra.Command {
 	return dbCmd
 }
 
+func createMigrateCmd() *cobra.Command {
+	var target string
+	var dryRun bool
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Versioned schema migrations",
+		Long:  `Apply, roll back and inspect the migrations embedded from migrations/, recorded in schema_migrations`,
+	}
+	migrateCmd.PersistentFlags().StringVar(&target, "to", "", "Migration version to migrate to, e.g. 044_mapped_source_types")
+	migrateCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the plan without applying it")
+
+	migrateCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "List migrations and whether they have been applied",
+		Run: func(cmd *cobra.Command, args []string) {
+			statuses, err := newMigrator().Status()
+			if err != nil {
+				log.Fatalf("Failed to read migration status: %v", err)
+			}
+			for _, s := range statuses {
+				state := "pending"
+				switch {
+				case s.ReplacedBy != "":
+					state = "replaced by " + s.ReplacedBy
+				case s.Missing:
+					state = "applied, file missing"
+				case s.Drift:
+					state = "applied, CHANGED since"
+				case s.Baseline:
+					state = "baseline"
+				case s.Applied:
+					state = "applied"
+				}
+				fmt.Printf("  %-45s %s\n", s.Version, state)
+			}
+		},
+	})
+
+	directions := []struct {
+		use, short, verb, dryVerb string
+		run              func(m *migrate.Migrator) ([]migrate.Migration, error)
+	}{
+		{"up", "Apply pending migrations (up to --to)", "Applied", "Would apply", func(m *migrate.Migrator) ([]migrate.Migration, error) { return m.Up(false, target, dryRun) }},
+		{"down", "Roll back the latest migration (or back to --to)", "Rolled back", "Would roll back", func(m *migrate.Migrator) ([]migrate.Migration, error) { return m.Down(false, target, dryRun) }},
+		{"baseline", "Record migrations up to --to as applied without running them", "Baselined", "Would baseline", func(m *migrate.Migrator) ([]migrate.Migration, error) { return m.Baseline(false, target, dryRun) }},
+	}
+	for _, d := range directions {
+		d := d
+		migrateCmd.AddCommand(&cobra.Command{
+			Use:   d.use,
+			Short: d.short,
+			Run: func(cmd *cobra.Command, args []string) {
+				plan, err := d.run(newMigrator())
+				if err != nil {
+					log.Fatalf("migrate %s failed: %v", d.use, err)
+				}
+				verb := d.verb
+				if dryRun {
+					verb = d.dryVerb
+				}
+				printMigrationPlan(verb, plan)
+			},
+		})
+	}
+
+	return migrateCmd
+}
+
+func newMigrator() *migrate.Migrator {
+	migrator, err := migrate.NewMigrator(dbConn.DB, migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	return migrator
+}
+
+func printMigrationPlan(verb string, plan []migrate.Migration) {
+	if len(plan) == 0 {
+		fmt.Println("Nothing to do, schema is up to date")
+		return
+	}
+	fmt.Printf("%s %d migrations:\n", verb, len(plan))
+	for _, m := range plan {
+		fmt.Printf("  %s\n", m.Version)
+	}
+}
+
 func createApplyViewsCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "apply-views",
-		Short: "Apply enhanced SQL views to database",
-		Long:  `Create enhanced views for source documents with calculated quality metrics and matching results`,
+		Short: "Apply pending migrations (including views) and test the views",
+		Long:  `Apply pending schema migrations, which create the source document and map views, then test the views`,
 		Run: func(cmd *cobra.Command, args []string) {
-			dbUtil := engine.NewDBUtil(dbConn.DB)
-			
-			if err := dbUtil.ExecuteSQLFiles("sql/09_create_enhanced_views.sql", "sql/10_create_map_views_fixed.sql"); err != nil {
-				log.Fatalf("Failed to apply views: %v", err)
+			migrator := newMigrator()
+			plan, err := migrator.Up(false, "", false)
+			if err != nil {
+				log.Fatalf("Failed to apply migrations: %v", err)
 			}
+			printMigrationPlan("Applied", plan)
+
+			dbUtil := engine.NewDBUtil(dbConn.DB)
 			
 			fmt.Println("\n=== Testing Views ===")
 			if err := dbUtil.TestViews(); err != nil {
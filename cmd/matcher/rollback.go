@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/user"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ehdc-llpg/internal/audit"
+)
+
+// createRollbackRunCmd creates the command that undoes a match run
+func createRollbackRunCmd() *cobra.Command {
+	var preview, localDebug bool
+	var reason, requestedBy string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "rollback-run [run_id]",
+		Short: "Restore every row a match run changed to its state before the run",
+		Long: `Undo a match run using the audit chain: rows the run added to match_result, match_accepted,
+match_audit, address_match and address_match_corrected are removed, and rows it changed
+or deleted are put back. Rows a reviewer or a later run has changed since are left alone.
+The fact rows of every document the run touched are then rebuilt from the reverted rows.
+
+The rollback is itself a run, recorded in match_run_rollback with who asked for it and why;
+rolling it back undoes it. Only runs made after migration 056 can be rolled back.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid run ID %q", args[0])
+			}
+			ctx := cmd.Context()
+
+			if preview {
+				plan, err := audit.PlanRollback(ctx, localDebug, dbConn.DB, runID)
+				if err != nil {
+					log.Fatalf("Preview failed: %v", err)
+				}
+				printRollbackPlan(plan, limit)
+				fmt.Printf("\nNothing changed. Roll back with: matcher rollback-run %d --reason \"...\"\n", runID)
+				return
+			}
+
+			if reason == "" {
+				log.Fatalf("--reason is required (or use --preview)")
+			}
+			if requestedBy == "" {
+				if u, err := user.Current(); err == nil {
+					requestedBy = u.Username
+				}
+			}
+
+			result, err := audit.RollbackRun(ctx, localDebug, dbConn.DB, runID, requestedBy, reason)
+			if result == nil {
+				log.Fatalf("Rollback failed: %v", err)
+			}
+			printRollbackPlan(result.Plan, -1)
+			fmt.Printf("\nRollback run %d: %d rows restored, %d removed\n", result.RollbackRunID, result.Restored, result.Removed)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					log.Fatalf("Interrupted; repeat the command to finish the rollback")
+				}
+				log.Fatalf("Rollback failed: %v (repeat the command to finish it)", err)
+			}
+			fmt.Printf("Fact rows: %d updated, %d removed\n", result.Facts.Updated, result.Facts.Removed)
+		},
+	}
+
+	cmd.Flags().BoolVar(&preview, "preview", false, "List what would revert without changing anything")
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the run is being rolled back (recorded)")
+	cmd.Flags().StringVar(&requestedBy, "by", "", "Who is rolling the run back (default the current user)")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Rows listed by --preview (0 for all)")
+	cmd.Flags().BoolVar(&localDebug, "debug", false, "Enable debug output")
+	return cmd
+}
+
+// printRollbackPlan summarises a rollback plan and lists up to limit of the rows that would
+// change or be kept: 0 lists them all, a negative limit none
+func printRollbackPlan(plan *audit.RollbackPlan, limit int) {
+	fmt.Printf("Run %d (%s, %s) changed %d rows of %d documents\n",
+		plan.RunID, plan.Label, plan.Status, len(plan.Items), plan.Documents)
+	for _, action := range audit.RollbackActions {
+		fmt.Printf("  %-10s %d\n", action, plan.Counts[action])
+	}
+	if plan.Counts[audit.RollbackKeep] > 0 {
+		fmt.Println("Kept rows were changed after the run by a reviewer or a later run and are left as they are.")
+	}
+
+	if limit < 0 {
+		return
+	}
+	fmt.Printf("\n%-24s %-12s %-10s %-10s %s\n", "TABLE", "ROW", "DOCUMENT", "ACTION", "DETAIL")
+	shown := 0
+	for _, item := range plan.Items {
+		if item.Action == audit.RollbackUnchanged {
+			continue
+		}
+		if limit > 0 && shown == limit {
+			fmt.Printf("... %d more (--limit=0 lists all)\n", len(plan.Items)-plan.Counts[audit.RollbackUnchanged]-shown)
+			break
+		}
+		detail := ""
+		if item.Action == audit.RollbackKeep {
+			detail = fmt.Sprintf("changed by %s at %s", item.KeptBy, item.KeptAt.Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("%-24s %-12s %-10d %-10s %s\n", item.Table, item.RowKey, item.DocumentID, item.Action, detail)
+		shown++
+	}
+}
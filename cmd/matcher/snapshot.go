@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ehdc-llpg/internal/snapshot"
+)
+
+// createSnapshotCmd creates the snapshot subcommand
+func createSnapshotCmd() *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Named snapshots of the fact table",
+		Long: `Create, list, compare, restore and prune catalogued snapshots of fact_documents_lean.
+Each snapshot records when it was taken, the run that produced it and a hash of the
+matching configuration.`,
+	}
+
+	snapshotCmd.AddCommand(createSnapshotCreateCmd())
+	snapshotCmd.AddCommand(createSnapshotListCmd())
+	snapshotCmd.AddCommand(createSnapshotDiffCmd())
+	snapshotCmd.AddCommand(createSnapshotRestoreCmd())
+	snapshotCmd.AddCommand(createSnapshotPruneCmd())
+
+	return snapshotCmd
+}
+
+func createSnapshotCreateCmd() *cobra.Command {
+	var opts snapshot.Options
+	var localDebug bool
+
+	cmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Snapshot fact_documents_lean (name defaults to snapshot-<timestamp>)",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				opts.Name = args[0]
+			}
+			opts.ConfigHash = cfg.Hash()
+
+			snap, err := snapshot.Create(cmd.Context(), localDebug, dbConn.DB, opts)
+			if err != nil {
+				log.Fatalf("Snapshot failed: %v", err)
+			}
+			fmt.Printf("Created snapshot %s (%s): %d rows, %d matched\n", snap.Name, snap.TableName, snap.Rows, snap.Matched)
+		},
+	}
+
+	cmd.Flags().Int64Var(&opts.RunID, "run-id", 0, "Matching run whose output this is")
+	cmd.Flags().StringVar(&opts.Notes, "notes", "", "Free-text notes")
+	cmd.Flags().BoolVar(&opts.Keep, "keep", false, "Exempt the snapshot from pruning")
+	cmd.Flags().BoolVar(&localDebug, "debug", false, "Enable debug output")
+	return cmd
+}
+
+func createSnapshotListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List snapshots, newest first",
+		Run: func(cmd *cobra.Command, args []string) {
+			snapshots, err := snapshot.List(cmd.Context(), dbConn.DB)
+			if err != nil {
+				log.Fatalf("Failed to list snapshots: %v", err)
+			}
+			if len(snapshots) == 0 {
+				fmt.Println("No snapshots")
+				return
+			}
+
+			fmt.Printf("%-5s %-32s %-17s %8s %9s %7s %-12s %s\n", "ID", "NAME", "CREATED", "ROWS", "MATCHED", "RUN", "CONFIG", "NOTES")
+			for _, s := range snapshots {
+				run := "-"
+				if s.RunID != nil {
+					run = strconv.FormatInt(*s.RunID, 10)
+				}
+				notes := s.Notes
+				if s.Keep {
+					notes = "[keep] " + notes
+				}
+				fmt.Printf("%-5d %-32s %-17s %8d %9d %7s %-12s %s\n", s.ID, s.Name,
+					s.CreatedAt.Local().Format("2006-01-02 15:04"), s.Rows, s.Matched, run, shortHash(s.ConfigHash), notes)
+			}
+		},
+	}
+}
+
+func createSnapshotDiffCmd() *cobra.Command {
+	var limit int
+	var csvPath string
+
+	cmd := &cobra.Command{
+		Use:   "diff [from] [to]",
+		Short: "Compare per-document UPRN, method and confidence between two snapshots",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			report, err := snapshot.Diff(cmd.Context(), dbConn.DB, args[0], args[1])
+			if err != nil {
+				log.Fatalf("Diff failed: %v", err)
+			}
+
+			fmt.Printf("%s (%s, %d matched) -> %s (%s, %d matched)\n",
+				report.From.Name, report.From.CreatedAt.Local().Format("2006-01-02 15:04"), report.From.Matched,
+				report.To.Name, report.To.CreatedAt.Local().Format("2006-01-02 15:04"), report.To.Matched)
+			if report.From.ConfigHash != report.To.ConfigHash {
+				fmt.Printf("Configuration differs: %s -> %s\n", shortHash(report.From.ConfigHash), shortHash(report.To.ConfigHash))
+			}
+
+			fmt.Println()
+			for _, kind := range snapshot.ChangeKinds {
+				fmt.Printf("  %-11s %d\n", kind, report.Counts[kind])
+			}
+			fmt.Printf("  %-11s %d\n", snapshot.Unchanged, report.Counts[snapshot.Unchanged])
+
+			if csvPath != "" {
+				if err := writeDiffCSV(csvPath, report); err != nil {
+					log.Fatalf("Failed to write %s: %v", csvPath, err)
+				}
+				fmt.Printf("\nWrote %d changes to %s\n", len(report.Changes), csvPath)
+			}
+
+			if limit > 0 && len(report.Changes) > 0 {
+				fmt.Printf("\n%-12s %-11s %-14s %-14s %-20s %-20s %s\n", "DOCUMENT", "CHANGE", "FROM UPRN", "TO UPRN", "FROM METHOD", "TO METHOD", "CONFIDENCE")
+				for i, c := range report.Changes {
+					if i == limit {
+						fmt.Printf("... %d more (use --csv for all)\n", len(report.Changes)-limit)
+						break
+					}
+					fmt.Printf("%-12d %-11s %-14s %-14s %-20s %-20s %s -> %s\n", c.DocumentID, c.Kind,
+						orDash(c.From.UPRN), orDash(c.To.UPRN), orDash(c.From.Method), orDash(c.To.Method),
+						formatConfidence(c.From.Confidence), formatConfidence(c.To.Confidence))
+				}
+			}
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 50, "Changes to print (0 for none)")
+	cmd.Flags().StringVar(&csvPath, "csv", "", "Write every change to this CSV file")
+	return cmd
+}
+
+func createSnapshotRestoreCmd() *cobra.Command {
+	var noBackup, yes, localDebug bool
+
+	cmd := &cobra.Command{
+		Use:   "restore [name]",
+		Short: "Replace fact_documents_lean with a snapshot in one transaction",
+		Long: `Replace the contents of fact_documents_lean with a snapshot. The current contents are
+snapshotted first as pre-restore-<timestamp> unless --no-backup is given.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			snap, err := snapshot.Get(cmd.Context(), dbConn.DB, args[0])
+			if err != nil {
+				log.Fatalf("Restore failed: %v", err)
+			}
+			if !yes {
+				fmt.Printf("This replaces fact_documents_lean with %s (%d rows, taken %s).\nRe-run with --yes to restore.\n",
+					snap.Name, snap.Rows, snap.CreatedAt.Local().Format("2006-01-02 15:04"))
+				return
+			}
+
+			var backup *snapshot.Options
+			if !noBackup {
+				backup = &snapshot.Options{
+					Name:       snapshot.DefaultName("pre-restore", time.Now()),
+					ConfigHash: cfg.Hash(),
+					Notes:      "Fact table before restoring " + snap.Name,
+				}
+			}
+
+			saved, restored, err := snapshot.Restore(cmd.Context(), localDebug, dbConn.DB, snap.Name, backup)
+			if saved != nil {
+				fmt.Printf("Saved the previous fact table as %s\n", saved.Name)
+			}
+			if err != nil {
+				log.Fatalf("Restore failed, fact_documents_lean is unchanged: %v", err)
+			}
+			fmt.Printf("Restored %d rows from %s\n", restored, snap.Name)
+		},
+	}
+
+	cmd.Flags().BoolVar(&noBackup, "no-backup", false, "Do not snapshot the current fact table first")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm the restore")
+	cmd.Flags().BoolVar(&localDebug, "debug", false, "Enable debug output")
+	return cmd
+}
+
+func createSnapshotPruneCmd() *cobra.Command {
+	var olderThan string
+	var keepLast int
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Drop snapshots beyond the retention limits",
+		Long: `Drop snapshots older than --older-than that are not among the --keep-last newest.
+Snapshots created with --keep are never pruned.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var r snapshot.Retention
+			r.KeepLast = keepLast
+			if olderThan != "" {
+				age, err := snapshot.ParseAge(olderThan)
+				if err != nil {
+					log.Fatalf("%v", err)
+				}
+				r.MaxAge = age
+			}
+
+			pruned, err := snapshot.Prune(cmd.Context(), dbConn.DB, r, dryRun)
+			if err != nil {
+				log.Fatalf("Prune failed: %v", err)
+			}
+
+			verb := "Pruned"
+			if dryRun {
+				verb = "Would prune"
+			}
+			fmt.Printf("%s %d snapshots\n", verb, len(pruned))
+			for _, s := range pruned {
+				fmt.Printf("  %-32s %s\n", s.Name, s.CreatedAt.Local().Format("2006-01-02 15:04"))
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "Maximum age, e.g. 30d or 72h")
+	cmd.Flags().IntVar(&keepLast, "keep-last", 0, "Always keep this many of the newest snapshots")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List what would be pruned without dropping anything")
+	return cmd
+}
+
+// writeDiffCSV writes every change in the report
+func writeDiffCSV(path string, report *snapshot.DiffReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"document_id", "change", "from_uprn", "to_uprn", "from_method", "to_method", "from_confidence", "to_confidence"})
+	for _, c := range report.Changes {
+		w.Write([]string{strconv.FormatInt(c.DocumentID, 10), c.Kind, c.From.UPRN, c.To.UPRN,
+			c.From.Method, c.To.Method, formatConfidence(c.From.Confidence), formatConfidence(c.To.Confidence)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func formatConfidence(c *float64) string {
+	if c == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*c, 'f', 4, 64)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func shortHash(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
@@ -1,31 +1,22 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
 	"strings"
 
-	_ "github.com/lib/pq"
-
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/symspell"
 )
 
 func main() {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		getEnv("PGHOST", "localhost"),
-		getEnv("PGPORT", "15435"),
-		getEnv("PGUSER", "postgres"),
-		getEnv("PGPASSWORD", "kljh234hjkl2h"),
-		getEnv("PGDATABASE", "ehdc_llpg"),
-	)
-
-	db, err := sql.Open("postgres", dsn)
+	conn, err := database.NewConnection()
 	if err != nil {
 		fmt.Printf("Failed to connect: %v\n", err)
 		os.Exit(1)
 	}
-	defer db.Close()
+	defer conn.Close()
+	db := conn.DB
 
 	fmt.Println("Building SymSpell dictionary...")
 	config := &symspell.Config{
@@ -178,10 +169,3 @@ func isSkipWord(s string) bool {
 	}
 	return skip[s]
 }
-
-func getEnv(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return defaultVal
-}
@@ -2,37 +2,23 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
 	"time"
 
-	_ "github.com/lib/pq"
-
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/symspell"
 )
 
 func main() {
-	// Connect to database
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		getEnv("PGHOST", "localhost"),
-		getEnv("PGPORT", "15435"),
-		getEnv("PGUSER", "postgres"),
-		getEnv("PGPASSWORD", "kljh234hjkl2h"),
-		getEnv("PGDATABASE", "ehdc_llpg"),
-	)
-
-	db, err := sql.Open("postgres", dsn)
+	// Connect to database from the shared configuration
+	conn, err := database.NewConnection()
 	if err != nil {
 		fmt.Printf("Failed to connect: %v\n", err)
 		os.Exit(1)
 	}
-	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		fmt.Printf("Failed to ping database: %v\n", err)
-		os.Exit(1)
-	}
+	defer conn.Close()
+	db := conn.DB
 
 	fmt.Println("Connected to database")
 	fmt.Println()
@@ -230,10 +216,3 @@ func isStreetSuffix(s string) bool {
 	}
 	return suffixes[s]
 }
-
-func getEnv(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return defaultVal
-}
@@ -1,88 +1,62 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"strconv"
 
 	"github.com/ehdc-llpg/internal/config"
-	"github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/web"
 )
 
 func main() {
-	// Load environment configuration
-	config.LoadEnv()
+	var overrides config.Overrides
+	configFile := flag.String("config", "", "Path to configuration file (default .env in this or a parent directory)")
+	flag.Var(&overrides, "set", "Override a configuration key, e.g. -set WEB_PORT=8080 (repeatable)")
+	flag.Parse()
 
 	fmt.Println("=== EHDC LLPG Web Interface ===")
 
-	// Get configuration from environment
-	portStr := config.GetEnv("WEB_PORT", "8443")
-	port, err := strconv.Atoi(portStr)
+	// Load configuration: flags > environment > file > defaults
+	cfg, err := config.Load(config.Options{File: *configFile, Flags: overrides})
 	if err != nil {
-		log.Fatalf("Invalid port number: %s", portStr)
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
-	
-	host := config.GetEnv("WEB_HOST", "localhost")
-	dbName := config.GetEnv("DB_NAME", "ehdc_llpg")
-
-	fmt.Printf("Server: http://%s:%d\n", host, port)
-	fmt.Printf("Database: %s\n", dbName)
-
-	// Initialize database connection
-	dbConn, err := db.NewConnection()
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("%v", err)
 	}
-	defer dbConn.Close()
+	config.Use(cfg)
 
-	// Test database connectivity
-	var dbVersion string
-	err = dbConn.DB.QueryRow("SELECT version()").Scan(&dbVersion)
-	if err != nil {
-		log.Fatalf("Database connection test failed: %v", err)
-	}
-	fmt.Printf("Database connected successfully\n")
+	fmt.Printf("Server: http://%s:%d\n", cfg.Web.Host, cfg.Web.Port)
+	fmt.Printf("Database: %s\n", cfg.Database)
 
-	// Create web server configuration using the existing structure
-	webConfig := &web.Config{
-		Server: web.ServerConfig{
-			Port: port,
-			Host: host,
-		},
-		Database: web.DatabaseConfig{
-			URL:            fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-				config.GetEnv("DB_USER", ""),
-				config.GetEnv("DB_PASSWORD", ""),
-				config.GetEnv("DB_HOST", ""),
-				config.GetEnv("DB_PORT", ""),
-				dbName),
-			MaxConnections: config.GetEnvInt("DB_MAX_CONNECTIONS", 10),
-		},
-		Auth: web.AuthConfig{
-			Enabled:    false,
-			SessionKey: config.GetEnv("SESSION_KEY", "changeme"),
-		},
-		Features: web.FeatureConfig{
-			ExportEnabled:         config.GetEnvBool("ENABLE_EXPORT", true),
-			ManualOverrideEnabled: config.GetEnvBool("ENABLE_MANUAL_OVERRIDE", true),
-		},
-	}
+	// Create web server configuration from the shared configuration
+	webConfig := web.NewConfig(cfg)
 
-	// Create web server (it already has the DB connection from internal/web/server.go)
+	// Create web server (connects to the database)
 	server, err := web.NewServer(webConfig)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
+	fmt.Printf("Database connected successfully\n")
 
-	fmt.Printf("\nStarting web server on http://%s:%d\n", host, port)
+	fmt.Printf("\nStarting web server on http://%s:%d\n", cfg.Web.Host, cfg.Web.Port)
 	fmt.Println("\nFeatures enabled:")
-	fmt.Printf("  • Export: %v\n", webConfig.Features.ExportEnabled)
+	fmt.Printf("  • Export: %v", webConfig.Features.ExportEnabled)
+	if webConfig.Features.ExportEnabled {
+		fmt.Printf(" (to %s, kept %s)", webConfig.Export.Dir, webConfig.Export.TTL)
+	}
+	fmt.Println()
 	fmt.Printf("  • Manual Override: %v\n", webConfig.Features.ManualOverrideEnabled) 
+	if webConfig.Auth.Enabled {
+		fmt.Printf("  • Authentication: sign-in or API key (sessions last %s)\n", webConfig.Auth.SessionTTL)
+	} else {
+		fmt.Println("  • Authentication: OFF (WEB_AUTH=false) - every request acts as web_user")
+	}
 	fmt.Println()
 
 	// Start server
 	if err := server.Start(); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,356 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+)
+
+// GenesisHash is the previous hash of the first chain entry
+var GenesisHash = strings.Repeat("0", 64)
+
+// ChainedTable is a table whose changes are appended to audit_chain, keyed by one column
+type ChainedTable struct {
+	Name string
+	Key  string
+}
+
+// ChainedTables are the tables migrations 055 to 057 and 066 attach to the chain. match_audit
+// is only attached if it existed at the time; verify-audit reports it otherwise.
+var ChainedTables = []ChainedTable{
+	{Name: "match_result", Key: "match_id"},
+	{Name: "match_accepted", Key: "src_id"},
+	{Name: "match_override", Key: "override_id"},
+	{Name: "audit_match_decisions", Key: "decision_id"},
+	{Name: "match_audit", Key: "audit_id"},
+	{Name: "address_match", Key: "match_id"},
+	{Name: "address_match_corrected", Key: "document_id"},
+	{Name: "match_rejection", Key: "rejection_id"},
+}
+
+// ChainEntry is one audit_chain row
+type ChainEntry struct {
+	Seq        int64
+	Table      string
+	Operation  string // INSERT, UPDATE, DELETE or BASELINE
+	RowKey     string
+	RowData    string // the row as JSONB text; empty for deletes
+	Actor      string
+	RecordedAt time.Time
+	PrevHash   string
+	Hash       string
+	RunID      int64 // match run whose process made the change; 0 for none
+}
+
+// ComputeHash returns the entry's hash; it must agree with audit_chain_hash in migration 056.
+// The run is only part of the content when set, as entries chained before 056 have none.
+func (e ChainEntry) ComputeHash() string {
+	fields := []string{
+		strconv.FormatInt(e.Seq, 10), e.Table, e.Operation, e.RowKey, e.RowData,
+		e.Actor, strconv.FormatInt(e.RecordedAt.UnixMicro(), 10), e.PrevHash,
+	}
+	if e.RunID != 0 {
+		fields = append(fields, strconv.FormatInt(e.RunID, 10))
+	}
+	content := strings.Join(fields, "\n")
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain problem kinds, in report order
+const (
+	ChainGap         = "gap"           // entries are missing from the sequence
+	ChainBrokenLink  = "broken_link"   // prev_hash is not the previous entry's hash
+	ChainAltered     = "altered"       // the entry's content no longer matches its hash
+	RowAltered       = "row_altered"   // the row differs from its latest chained state
+	RowDeleted       = "row_deleted"   // the row was removed without a chained delete
+	RowUnchained     = "row_unchained" // the row has no chain entry
+	TableUnattached  = "unattached"    // a chain trigger of the table is missing or disabled
+	CheckpointFailed = "checkpoint"    // a signed checkpoint does not verify against the chain
+)
+
+// ChainProblemKinds lists the problem kinds in report order
+var ChainProblemKinds = []string{
+	ChainGap, ChainBrokenLink, ChainAltered, RowAltered, RowDeleted, RowUnchained, TableUnattached, CheckpointFailed,
+}
+
+// ChainProblem is one way the audit trail fails verification
+type ChainProblem struct {
+	Kind   string
+	Seq    int64 // chain position, 0 for row and table problems
+	Table  string
+	RowKey string
+	Detail string
+}
+
+// VerifyReport is the outcome of verifying the audit chain
+type VerifyReport struct {
+	Entries     int64
+	Chained     int64      // pending entries chained before verifying
+	Head        ChainEntry // zero when the chain is empty
+	Checkpoints int        // signed checkpoints checked
+	Counts      map[string]int
+	Problems    []ChainProblem // the first problemSample of each kind
+	Duration    time.Duration
+}
+
+// Intact reports whether verification found no problems
+func (r *VerifyReport) Intact() bool {
+	return len(r.Problems) == 0
+}
+
+// problemSample is how many problems of each kind a report keeps
+const problemSample = 100
+
+func (r *VerifyReport) add(p ChainProblem) {
+	r.Counts[p.Kind]++
+	if r.Counts[p.Kind] <= problemSample {
+		r.Problems = append(r.Problems, p)
+	}
+}
+
+// chainWalker checks entries in sequence order against their predecessors
+type chainWalker struct {
+	prev *ChainEntry
+}
+
+// step checks e follows the previous entry
+func (w *chainWalker) step(e ChainEntry) []ChainProblem {
+	var problems []ChainProblem
+	wantSeq, wantPrev := int64(1), GenesisHash
+	if w.prev != nil {
+		wantSeq, wantPrev = w.prev.Seq+1, w.prev.Hash
+	}
+
+	if e.Seq != wantSeq {
+		problems = append(problems, ChainProblem{Kind: ChainGap, Seq: e.Seq, Table: e.Table, RowKey: e.RowKey,
+			Detail: fmt.Sprintf("entries %d to %d are missing", wantSeq, e.Seq-1)})
+	}
+	if e.PrevHash != wantPrev {
+		problems = append(problems, ChainProblem{Kind: ChainBrokenLink, Seq: e.Seq, Table: e.Table, RowKey: e.RowKey,
+			Detail: "previous hash does not match the preceding entry"})
+	}
+	if e.ComputeHash() != e.Hash {
+		problems = append(problems, ChainProblem{Kind: ChainAltered, Seq: e.Seq, Table: e.Table, RowKey: e.RowKey,
+			Detail: fmt.Sprintf("%s entry no longer matches its hash", strings.ToLower(e.Operation))})
+	}
+
+	w.prev = &e
+	return problems
+}
+
+// Verify walks the whole audit chain, checking every link and hash, then checks each
+// chained table's rows against their latest chain entry and that its trigger is enabled.
+// Checkpoints, from audit_checkpoint and any exported files, must be signed by trusted
+// (or, when trusted is nil, by the key they carry) and name a hash still in the chain.
+func Verify(ctx context.Context, localDebug bool, db *sql.DB, exported []Checkpoint, trusted ed25519.PublicKey) (*VerifyReport, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	start := time.Now()
+	report := &VerifyReport{Counts: make(map[string]int)}
+
+	chained, err := ChainPending(ctx, localDebug, db)
+	if err != nil {
+		return nil, err
+	}
+	report.Chained = chained
+
+	stored, err := StoredCheckpoints(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	bySeq := make(map[int64][]Checkpoint)
+	for _, c := range append(stored, exported...) {
+		report.Checkpoints++
+		if err := c.Verify(trusted); err != nil {
+			report.add(ChainProblem{Kind: CheckpointFailed, Seq: c.Seq, Detail: fmt.Sprintf("%s: %v", c.Date, err)})
+			continue
+		}
+		bySeq[c.Seq] = append(bySeq[c.Seq], c)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT seq, table_name, operation, COALESCE(row_key, ''), COALESCE(row_data::TEXT, ''),
+			actor, recorded_at, prev_hash, hash, COALESCE(run_id, 0)
+		FROM audit_chain
+		ORDER BY seq
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	defer rows.Close()
+
+	var walker chainWalker
+	for rows.Next() {
+		var e ChainEntry
+		if err := rows.Scan(&e.Seq, &e.Table, &e.Operation, &e.RowKey, &e.RowData,
+			&e.Actor, &e.RecordedAt, &e.PrevHash, &e.Hash, &e.RunID); err != nil {
+			return nil, fmt.Errorf("failed to scan audit chain entry: %w", err)
+		}
+		for _, p := range walker.step(e) {
+			report.add(p)
+		}
+		for _, c := range bySeq[e.Seq] {
+			if c.Hash != e.Hash {
+				report.add(ChainProblem{Kind: CheckpointFailed, Seq: e.Seq,
+					Detail: fmt.Sprintf("%s: checkpoint hash %.12s differs from the chain's %.12s", c.Date, c.Hash, e.Hash)})
+			}
+		}
+		delete(bySeq, e.Seq)
+		report.Entries++
+		report.Head = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	for seq, cs := range bySeq {
+		for _, c := range cs {
+			report.add(ChainProblem{Kind: CheckpointFailed, Seq: seq,
+				Detail: fmt.Sprintf("%s: checkpoint entry %d is missing from the chain", c.Date, seq)})
+		}
+	}
+	debug.DebugOutput(localDebug, "Walked %d chain entries, %d checkpoints", report.Entries, report.Checkpoints)
+
+	for _, t := range ChainedTables {
+		if err := verifyTable(ctx, localDebug, db, t, report); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// verifyTable checks the table's triggers (one per operation, see migration 065) and compares
+// each row with its latest chain entry, on the columns that entry recorded so columns added
+// later do not count as edits. Rows changed since the chain was flushed are left for next time.
+func verifyTable(ctx context.Context, localDebug bool, db *sql.DB, t ChainedTable, report *VerifyReport) error {
+	triggers := []string{
+		"trg_audit_chain_" + t.Name + "_insert",
+		"trg_audit_chain_" + t.Name + "_update",
+		"trg_audit_chain_" + t.Name + "_delete",
+	}
+	var exists bool
+	var enabled int
+	err := db.QueryRowContext(ctx, `
+		SELECT to_regclass($1) IS NOT NULL,
+			(SELECT count(*) FROM pg_trigger
+			 WHERE tgrelid = to_regclass($1) AND tgname = ANY($2) AND tgenabled <> 'D')
+	`, t.Name, pq.Array(triggers)).Scan(&exists, &enabled)
+	if err != nil {
+		return fmt.Errorf("failed to check %s triggers: %w", t.Name, err)
+	}
+	if !exists {
+		debug.DebugOutput(localDebug, "%s does not exist; skipped", t.Name)
+		return nil
+	}
+	if enabled < len(triggers) {
+		report.add(ChainProblem{Kind: TableUnattached, Table: t.Name,
+			Detail: fmt.Sprintf("changes are not being chained; run SELECT audit_chain_attach('%s', '%s')", t.Name, t.Key)})
+	}
+
+	query := fmt.Sprintf(`
+		WITH latest AS (
+			SELECT DISTINCT ON (row_key) row_key, operation, row_data
+			FROM audit_chain
+			WHERE table_name = $1 AND row_key IS NOT NULL
+			ORDER BY row_key, seq DESC
+		), cur AS (
+			SELECT to_jsonb(t) ->> $2 AS row_key, to_jsonb(t) AS row_data
+			FROM %s t
+		)
+		SELECT COALESCE(l.row_key, c.row_key),
+			CASE WHEN l.row_key IS NULL OR l.operation = 'DELETE' THEN $3
+				WHEN c.row_key IS NULL THEN $4
+				ELSE $5 END
+		FROM latest l
+		FULL OUTER JOIN cur c ON c.row_key = l.row_key
+		WHERE ((l.row_key IS NULL AND c.row_key IS NOT NULL)
+			OR (c.row_key IS NULL AND l.operation <> 'DELETE')
+			OR (c.row_key IS NOT NULL AND l.operation = 'DELETE')
+			OR (c.row_key IS NOT NULL AND l.operation <> 'DELETE' AND l.row_data IS DISTINCT FROM
+				(SELECT jsonb_object_agg(k, c.row_data -> k) FROM jsonb_object_keys(l.row_data) k)))
+			AND NOT EXISTS (SELECT 1 FROM audit_chain_pending p
+				WHERE p.table_name = $1 AND p.row_key = COALESCE(l.row_key, c.row_key))
+		ORDER BY 1
+	`, pq.QuoteIdentifier(t.Name))
+
+	// Rows are recorded as UTC JSON (see audit_chain_append_rows), so compare them the same way
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin %s comparison: %w", t.Name, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "SET LOCAL TIME ZONE 'UTC'"); err != nil {
+		return fmt.Errorf("failed to set comparison time zone: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, t.Name, t.Key, RowUnchained, RowDeleted, RowAltered)
+	if err != nil {
+		return fmt.Errorf("failed to compare %s with the audit chain: %w", t.Name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := ChainProblem{Table: t.Name}
+		if err := rows.Scan(&p.RowKey, &p.Kind); err != nil {
+			return fmt.Errorf("failed to scan %s comparison: %w", t.Name, err)
+		}
+		report.add(p)
+	}
+	return rows.Err()
+}
+
+// chainBatch is how many pending entries one audit_chain_flush call chains
+const chainBatch = 10000
+
+// ChainPending moves the changes waiting in audit_chain_pending into the chain (migration 065),
+// a batch per transaction so the chainer's lock is never held for long. Only chainers wait for
+// each other; writers to the chained tables do not. Returns the number of entries chained.
+func ChainPending(ctx context.Context, localDebug bool, db *sql.DB) (int64, error) {
+	var total int64
+	for {
+		var n int64
+		if err := db.QueryRowContext(ctx, "SELECT audit_chain_flush($1)", chainBatch).Scan(&n); err != nil {
+			return total, fmt.Errorf("failed to chain pending audit entries: %w", err)
+		}
+		total += n
+		if n < chainBatch {
+			debug.DebugOutput(localDebug, "Chained %d pending entries", total)
+			return total, nil
+		}
+	}
+}
+
+// ChainHead reads the latest chain entry and the number of entries; a zero entry when empty
+func ChainHead(ctx context.Context, db *sql.DB) (ChainEntry, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var e ChainEntry
+	err := db.QueryRowContext(ctx, `
+		SELECT seq, table_name, operation, COALESCE(row_key, ''), actor, recorded_at, prev_hash, hash
+		FROM audit_chain
+		ORDER BY seq DESC
+		LIMIT 1
+	`).Scan(&e.Seq, &e.Table, &e.Operation, &e.RowKey, &e.Actor, &e.RecordedAt, &e.PrevHash, &e.Hash)
+	if err == sql.ErrNoRows {
+		return ChainEntry{}, nil
+	}
+	if err != nil {
+		return ChainEntry{}, fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	return e, nil
+}
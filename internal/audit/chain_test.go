@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+)
+
+// testChain builds n linked entries
+func testChain(n int) []ChainEntry {
+	var entries []ChainEntry
+	prev := GenesisHash
+	at := time.Date(2026, 10, 18, 9, 0, 0, 123456000, time.UTC)
+	for i := 1; i <= n; i++ {
+		e := ChainEntry{Seq: int64(i), Table: "match_accepted", Operation: "INSERT",
+			RowKey: "7", RowData: `{"uprn": "100062000001", "src_id": 7}`, Actor: "matcher",
+			RecordedAt: at.Add(time.Duration(i) * time.Second), PrevHash: prev}
+		e.Hash = e.ComputeHash()
+		prev = e.Hash
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func walk(entries []ChainEntry) map[string]int {
+	var w chainWalker
+	kinds := make(map[string]int)
+	for _, e := range entries {
+		for _, p := range w.step(e) {
+			kinds[p.Kind]++
+		}
+	}
+	return kinds
+}
+
+func TestChainWalker(t *testing.T) {
+	if kinds := walk(testChain(5)); len(kinds) != 0 {
+		t.Fatalf("intact chain reported %v", kinds)
+	}
+
+	entries := testChain(5)
+	entries[2].RowData = `{"uprn": "100062000009", "src_id": 7}`
+	if kinds := walk(entries); kinds[ChainAltered] != 1 || len(kinds) != 1 {
+		t.Errorf("edited entry reported %v, want one altered", kinds)
+	}
+
+	entries = testChain(5)
+	entries = append(entries[:2], entries[3:]...)
+	if kinds := walk(entries); kinds[ChainGap] != 1 || kinds[ChainBrokenLink] != 1 {
+		t.Errorf("removed entry reported %v, want a gap and a broken link", kinds)
+	}
+
+	// Rehashing an edited entry still breaks the next link
+	entries = testChain(5)
+	entries[2].RowData = `{}`
+	entries[2].Hash = entries[2].ComputeHash()
+	if kinds := walk(entries); kinds[ChainBrokenLink] != 1 || kinds[ChainAltered] != 0 {
+		t.Errorf("rehashed entry reported %v, want one broken link", kinds)
+	}
+
+	entries = testChain(3)[1:]
+	if kinds := walk(entries); kinds[ChainGap] != 1 {
+		t.Errorf("chain missing its first entry reported %v, want a gap", kinds)
+	}
+}
+
+func TestCheckpointSignature(t *testing.T) {
+	seed, public, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseSigningKey(seed)
+	if err != nil {
+		t.Fatalf("ParseSigningKey: %v", err)
+	}
+	if _, err := ParseSigningKey("not a key"); err == nil {
+		t.Error("ParseSigningKey accepted a bad seed")
+	}
+
+	head := testChain(3)[2]
+	c := Checkpoint{Date: "2026-10-18", Seq: head.Seq, Hash: head.Hash, CreatedAt: time.Now()}
+	c.Sign(key)
+	if c.PublicKey != public {
+		t.Errorf("checkpoint key %s, want %s", c.PublicKey, public)
+	}
+	trusted := key.Public().(ed25519.PublicKey)
+	if err := c.Verify(trusted); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	dir := t.TempDir()
+	if _, err := WriteCheckpoint(dir, &c); err != nil {
+		t.Fatalf("WriteCheckpoint: %v", err)
+	}
+	read, err := ReadCheckpoints(dir)
+	if err != nil || len(read) != 1 {
+		t.Fatalf("ReadCheckpoints = %d, %v; want 1", len(read), err)
+	}
+	if err := read[0].Verify(trusted); err != nil {
+		t.Errorf("exported checkpoint did not verify: %v", err)
+	}
+
+	forged := c
+	forged.Seq = 2
+	if err := forged.Verify(nil); err == nil {
+		t.Error("a checkpoint with a changed seq verified")
+	}
+
+	other, _, _ := GenerateSigningKey()
+	otherKey, _ := ParseSigningKey(other)
+	resigned := c
+	resigned.Sign(otherKey)
+	if err := resigned.Verify(nil); err != nil {
+		t.Errorf("self-signed checkpoint did not verify: %v", err)
+	}
+	if err := resigned.Verify(trusted); err == nil {
+		t.Error("a checkpoint signed by another key verified against the trusted key")
+	}
+}
+
+func TestRollbackAction(t *testing.T) {
+	none := rollbackState{}
+	deleted := rollbackState{Operation: "DELETE"}
+	old := rollbackState{Operation: "BASELINE", Data: `{"uprn": "100062000001"}`}
+	updated := rollbackState{Operation: "UPDATE", Data: `{"uprn": "100062000002"}`}
+	same := rollbackState{Operation: "INSERT", Data: old.Data}
+
+	cases := []struct {
+		name            string
+		before, current rollbackState
+		changedSince    bool
+		want            string
+	}{
+		{"added by the run", none, updated, false, RollbackRemove},
+		{"re-added after a delete", deleted, updated, false, RollbackRemove},
+		{"updated by the run", old, updated, false, RollbackRestore},
+		{"deleted by the run", old, deleted, false, RollbackRestore},
+		{"added then removed", none, deleted, false, RollbackUnchanged},
+		{"already restored", old, same, false, RollbackUnchanged},
+		{"reviewed since", old, updated, true, RollbackKeep},
+	}
+	for _, c := range cases {
+		if got := rollbackAction(c.before, c.current, c.changedSince); got != c.want {
+			t.Errorf("%s: %s, want %s", c.name, got, c.want)
+		}
+	}
+}
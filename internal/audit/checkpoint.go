@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+)
+
+// Checkpoint is a signed statement of the audit chain's head on a date. Kept outside the
+// database, it lets an auditor show the chain up to Seq has not been rewritten since.
+type Checkpoint struct {
+	Date      string    `json:"date"` // YYYY-MM-DD
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	PublicKey string    `json:"public_key"` // base64 Ed25519 public key
+	Signature string    `json:"signature"`  // base64 Ed25519 signature of message()
+}
+
+// message is the signed content
+func (c Checkpoint) message() []byte {
+	return []byte(fmt.Sprintf("ehdc-llpg audit checkpoint\n%s\n%d\n%s\n%s",
+		c.Date, c.Seq, c.Hash, c.CreatedAt.UTC().Format(time.RFC3339Nano)))
+}
+
+// Sign signs the checkpoint with key, recording its public key
+func (c *Checkpoint) Sign(key ed25519.PrivateKey) {
+	c.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, c.message()))
+}
+
+// Verify checks the signature. With a trusted key the checkpoint must have been signed by it;
+// with none, the checkpoint's own key is used, which only shows the file is self-consistent.
+func (c Checkpoint) Verify(trusted ed25519.PublicKey) error {
+	public, err := base64.StdEncoding.DecodeString(c.PublicKey)
+	if err != nil || len(public) != ed25519.PublicKeySize {
+		return errors.New("checkpoint public key is not a base64 Ed25519 key")
+	}
+	if trusted != nil && !trusted.Equal(ed25519.PublicKey(public)) {
+		return errors.New("checkpoint was signed with a different key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil || !ed25519.Verify(public, c.message(), signature) {
+		return errors.New("checkpoint signature is invalid")
+	}
+	return nil
+}
+
+// ParseSigningKey decodes a base64 Ed25519 seed, as held in AUDIT_SIGNING_KEY
+func ParseSigningKey(seed string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(b) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key must be a base64 %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(b), nil
+}
+
+// GenerateSigningKey returns a new base64 seed and its base64 public key
+func GenerateSigningKey() (seed, public string, err error) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key.Seed()), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// CreateCheckpoint chains pending entries, then signs the chain head and records it in
+// audit_checkpoint
+func CreateCheckpoint(ctx context.Context, localDebug bool, db *sql.DB, key ed25519.PrivateKey, now time.Time) (*Checkpoint, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	if _, err := ChainPending(ctx, localDebug, db); err != nil {
+		return nil, err
+	}
+	head, err := ChainHead(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if head.Seq == 0 {
+		return nil, errors.New("the audit chain is empty; nothing to checkpoint")
+	}
+
+	now = now.UTC().Truncate(time.Microsecond) // what timestamptz keeps
+	c := &Checkpoint{Date: now.Format("2006-01-02"), Seq: head.Seq, Hash: head.Hash, CreatedAt: now}
+	c.Sign(key)
+	debug.DebugOutput(localDebug, "Checkpoint %s at entry %d: %s", c.Date, c.Seq, c.Hash)
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO audit_checkpoint (checkpoint_date, seq, hash, public_key, signature, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, c.Date, c.Seq, c.Hash, c.PublicKey, c.Signature, c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record audit checkpoint: %w", err)
+	}
+	return c, nil
+}
+
+// StoredCheckpoints reads the checkpoints recorded in audit_checkpoint, oldest first
+func StoredCheckpoints(ctx context.Context, db *sql.DB) ([]Checkpoint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT to_char(checkpoint_date, 'YYYY-MM-DD'), seq, hash, created_at, public_key, signature
+		FROM audit_checkpoint
+		ORDER BY checkpoint_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []Checkpoint
+	for rows.Next() {
+		var c Checkpoint
+		if err := rows.Scan(&c.Date, &c.Seq, &c.Hash, &c.CreatedAt, &c.PublicKey, &c.Signature); err != nil {
+			return nil, fmt.Errorf("failed to scan audit checkpoint: %w", err)
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	return checkpoints, rows.Err()
+}
+
+// checkpointPattern matches exported checkpoint files
+const checkpointPattern = "audit-checkpoint-*.json"
+
+// WriteCheckpoint exports the checkpoint to dir, returning the file written
+func WriteCheckpoint(dir string, c *Checkpoint) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("audit-checkpoint-%s-%d.json", c.Date, c.Seq))
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return path, nil
+}
+
+// ReadCheckpoints reads every checkpoint exported to dir, oldest first; none if dir is missing
+func ReadCheckpoints(dir string) ([]Checkpoint, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, checkpointPattern))
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []Checkpoint
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+		var c Checkpoint
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint %s: %w", filepath.Base(path), err)
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Seq < checkpoints[j].Seq })
+	return checkpoints, nil
+}
@@ -0,0 +1,402 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/facts"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// What a rollback does with each row the run changed
+const (
+	RollbackRestore   = "restore"   // put the row back as it was before the run
+	RollbackRemove    = "remove"    // delete the row the run added
+	RollbackKeep      = "keep"      // changed again after the run by a reviewer or later run; left alone
+	RollbackUnchanged = "unchanged" // already as it was before the run
+)
+
+// RollbackActions lists the actions in report order
+var RollbackActions = []string{RollbackRestore, RollbackRemove, RollbackKeep, RollbackUnchanged}
+
+// rollbackTables are the tables a run writes, children before parents: removals run in
+// this order and restores in reverse, so foreign keys hold throughout
+var rollbackTables = []ChainedTable{
+	{Name: "match_audit", Key: "audit_id"},
+	{Name: "match_accepted", Key: "src_id"},
+	{Name: "address_match_corrected", Key: "document_id"},
+	{Name: "address_match", Key: "match_id"},
+	{Name: "match_result", Key: "match_id"},
+}
+
+// rollbackBatch is how many rows a rollback reverts per transaction
+const rollbackBatch = 500
+
+// RollbackItem is one row the run changed
+type RollbackItem struct {
+	Table      string
+	RowKey     string
+	DocumentID int64 // the row's document, 0 when it has none
+	Action     string
+	Before     string // the row before the run as JSON; empty when it did not exist
+	Current    string // the row now; empty when it does not exist
+	KeptBy     string // for RollbackKeep, who changed it after the run
+	KeptAt     time.Time
+}
+
+// RollbackPlan is what rolling a run back would do
+type RollbackPlan struct {
+	RunID     int64
+	Label     string
+	Status    string
+	Items     []RollbackItem // ordered by table then row
+	Counts    map[string]int
+	Documents int // distinct documents touched by the run
+}
+
+// rollbackState is one side of a row's history: the chain entry's operation and row
+type rollbackState struct {
+	Operation string // empty when the row has no entry
+	Data      string
+}
+
+func (s rollbackState) exists() bool {
+	return s.Operation != "" && s.Operation != "DELETE"
+}
+
+// rollbackAction decides what to do with a row given its state before the run, its
+// current state and whether anyone other than the run (or its rollbacks) changed it since
+func rollbackAction(before, current rollbackState, changedSince bool) string {
+	switch {
+	case changedSince:
+		return RollbackKeep
+	case !before.exists() && !current.exists():
+		return RollbackUnchanged
+	case before.exists() && current.exists() && before.Data == current.Data:
+		return RollbackUnchanged
+	case !before.exists():
+		return RollbackRemove
+	}
+	return RollbackRestore
+}
+
+// ErrNothingToRollBack is returned for runs with no attributed changes
+var ErrNothingToRollBack = errors.New("the run made no recorded changes; runs before migration 056 were not tracked")
+
+// PlanRollback works out, from the audit chain, how to restore every row the run changed
+// to its state before the run. Rows a reviewer or later run has changed since are kept.
+func PlanRollback(ctx context.Context, localDebug bool, db *sql.DB, runID int64) (*RollbackPlan, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	plan := &RollbackPlan{RunID: runID, Counts: make(map[string]int)}
+
+	// The run's last changes may still be waiting to be chained
+	if _, err := ChainPending(ctx, localDebug, db); err != nil {
+		return nil, err
+	}
+
+	// A run is only still running while one of its processes is connected; one left
+	// running by a crashed process can be rolled back
+	var active bool
+	var rolledBackBy sql.NullInt64
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(r.run_label, ''), r.status,
+			EXISTS (SELECT 1 FROM match_run_client c
+				JOIN pg_stat_activity a ON a.application_name = c.client
+				WHERE c.run_id = r.run_id AND c.ended_at IS NULL),
+			(SELECT rollback_run_id FROM match_run_rollback
+			 WHERE run_id = r.run_id AND completed_at IS NOT NULL
+			 ORDER BY completed_at DESC LIMIT 1)
+		FROM match_run r
+		WHERE r.run_id = $1
+	`, runID).Scan(&plan.Label, &plan.Status, &active, &rolledBackBy)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("run %d not found", runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run %d: %w", runID, err)
+	}
+	if plan.Status == store.RunRunning && active {
+		return nil, fmt.Errorf("run %d is still running; stop it before rolling it back", runID)
+	}
+	if rolledBackBy.Valid {
+		return nil, fmt.Errorf("run %d was already rolled back by run %d (roll that back to undo it)", runID, rolledBackBy.Int64)
+	}
+
+	tables := make([]string, len(rollbackTables))
+	for i, t := range rollbackTables {
+		tables[i] = t.Name
+	}
+
+	// Entries by the rollbacks of this run do not count as later changes, so an
+	// interrupted rollback can be repeated
+	rows, err := db.QueryContext(ctx, `
+		WITH rollbacks AS (
+			SELECT rollback_run_id FROM match_run_rollback WHERE run_id = $1
+		), touched AS (
+			SELECT table_name, row_key, MIN(seq) AS first_seq, MAX(seq) AS last_seq
+			FROM audit_chain
+			WHERE run_id = $1 AND table_name = ANY($2)
+			GROUP BY table_name, row_key
+		)
+		SELECT t.table_name, t.row_key,
+			COALESCE(b.operation, ''), COALESCE(b.row_data::TEXT, ''),
+			c.operation, COALESCE(c.row_data::TEXT, ''),
+			COALESCE(c.row_data, b.row_data) ->> 'document_id', COALESCE(c.row_data, b.row_data) ->> 'src_id',
+			l.seq IS NOT NULL,
+			COALESCE('run ' || l.run_id, l.row_data ->> 'accepted_by', l.row_data ->> 'matched_by', l.actor, ''),
+			l.recorded_at
+		FROM touched t
+		LEFT JOIN LATERAL (
+			SELECT operation, row_data FROM audit_chain
+			WHERE table_name = t.table_name AND row_key = t.row_key AND seq < t.first_seq
+			ORDER BY seq DESC LIMIT 1
+		) b ON true
+		JOIN LATERAL (
+			SELECT operation, row_data FROM audit_chain
+			WHERE table_name = t.table_name AND row_key = t.row_key
+			ORDER BY seq DESC LIMIT 1
+		) c ON true
+		LEFT JOIN LATERAL (
+			SELECT seq, run_id, row_data, actor, recorded_at FROM audit_chain
+			WHERE table_name = t.table_name AND row_key = t.row_key AND seq > t.last_seq
+				AND (run_id IS NULL OR run_id NOT IN (SELECT rollback_run_id FROM rollbacks))
+			ORDER BY seq LIMIT 1
+		) l ON true
+		ORDER BY t.table_name, t.first_seq
+	`, runID, pq.Array(tables))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run %d from the audit chain: %w", runID, err)
+	}
+	defer rows.Close()
+
+	documents := make(map[int64]bool)
+	for rows.Next() {
+		var item RollbackItem
+		var before, current rollbackState
+		var documentID, srcID sql.NullInt64
+		var changedSince bool
+		var keptAt sql.NullTime
+		if err := rows.Scan(&item.Table, &item.RowKey, &before.Operation, &before.Data,
+			&current.Operation, &current.Data, &documentID, &srcID,
+			&changedSince, &item.KeptBy, &keptAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rollback row: %w", err)
+		}
+
+		item.Action = rollbackAction(before, current, changedSince)
+		if before.exists() {
+			item.Before = before.Data
+		}
+		if current.exists() {
+			item.Current = current.Data
+		}
+		if item.Action != RollbackKeep {
+			item.KeptBy = ""
+		}
+		item.KeptAt = keptAt.Time
+		item.DocumentID = documentID.Int64
+		if !documentID.Valid {
+			item.DocumentID = srcID.Int64
+		}
+		if item.DocumentID != 0 {
+			documents[item.DocumentID] = true
+		}
+
+		plan.Items = append(plan.Items, item)
+		plan.Counts[item.Action]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run %d from the audit chain: %w", runID, err)
+	}
+	if len(plan.Items) == 0 {
+		return nil, ErrNothingToRollBack
+	}
+
+	plan.Documents = len(documents)
+	debug.DebugOutput(localDebug, "Run %d changed %d rows of %d documents", runID, len(plan.Items), plan.Documents)
+	return plan, nil
+}
+
+// RollbackResult is the outcome of a rollback
+type RollbackResult struct {
+	Plan          *RollbackPlan
+	RollbackRunID int64 // the rollback's own run; rolling it back undoes the rollback
+	Restored      int
+	Removed       int
+	Facts         facts.ApplyStats // fact rows of the run's documents rebuilt afterwards
+}
+
+// RollbackRun reverts the rows in the run's rollback plan. The rollback is itself a match
+// run, so its changes are chained and attributed to it, and it is recorded in
+// match_run_rollback with who asked for it and why. An interrupted rollback can be repeated.
+// The fact rows of every document in the plan are then rebuilt from the reverted rows, so
+// fact_documents_lean also loses matches a run wrote to it directly.
+func RollbackRun(ctx context.Context, localDebug bool, db *sql.DB, runID int64, requestedBy, reason string) (*RollbackResult, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	plan, err := PlanRollback(ctx, localDebug, db, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := store.NewPostgres(db)
+	run, err := matches.CreateRun(ctx, fmt.Sprintf("rollback-%d", runID), "rollback",
+		fmt.Sprintf("Rollback of run %d requested by %s: %s", runID, requestedBy, reason))
+	if err != nil {
+		return nil, err
+	}
+	result := &RollbackResult{Plan: plan, RollbackRunID: run.RunID}
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO match_run_rollback (rollback_run_id, run_id, requested_by, reason, kept)
+		VALUES ($1, $2, $3, $4, $5)
+	`, run.RunID, runID, requestedBy, reason, plan.Counts[RollbackKeep]); err != nil {
+		return nil, fmt.Errorf("failed to record rollback: %w", err)
+	}
+
+	err = revert(ctx, localDebug, db, plan, result)
+	if err == nil {
+		err = refreshFacts(ctx, db, plan, result)
+	}
+
+	status := store.RunCompleted
+	switch {
+	case errors.Is(err, context.Canceled):
+		status = store.RunInterrupted
+	case err != nil:
+		status = store.RunFailed
+	}
+	// Record the outcome even when interrupted
+	finishCtx := context.Background()
+	if _, ferr := db.ExecContext(finishCtx, `
+		UPDATE match_run_rollback
+		SET restored = $1, removed = $2, completed_at = CASE WHEN $3 THEN now() END
+		WHERE rollback_run_id = $4
+	`, result.Restored, result.Removed, err == nil, run.RunID); ferr != nil {
+		debug.DebugOutput(localDebug, "Warning: failed to record rollback %d: %v", run.RunID, ferr)
+	}
+	if ferr := matches.CompleteRun(finishCtx, run.RunID, status, store.RunTotals{
+		Processed: result.Restored + result.Removed,
+	}); ferr != nil {
+		debug.DebugOutput(localDebug, "Warning: failed to record run %d as %s: %v", run.RunID, status, ferr)
+	}
+	return result, err
+}
+
+// refreshFacts rebuilds the fact rows of the plan's documents, a batch per transaction
+func refreshFacts(ctx context.Context, db *sql.DB, plan *RollbackPlan, result *RollbackResult) error {
+	seen := make(map[int64]bool)
+	var documentIDs []int64
+	for _, item := range plan.Items {
+		if item.DocumentID != 0 && !seen[item.DocumentID] {
+			seen[item.DocumentID] = true
+			documentIDs = append(documentIDs, item.DocumentID)
+		}
+	}
+
+	for start := 0; start < len(documentIDs); start += rollbackBatch {
+		end := start + rollbackBatch
+		if end > len(documentIDs) {
+			end = len(documentIDs)
+		}
+		stats, err := facts.Refresh(ctx, db, documentIDs[start:end]...)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild fact rows: %w", err)
+		}
+		result.Facts.Changes += stats.Changes
+		result.Facts.Documents += stats.Documents
+		result.Facts.Updated += stats.Updated
+		result.Facts.Removed += stats.Removed
+	}
+	return nil
+}
+
+// revert removes rows in table order, then restores rows in reverse table order
+func revert(ctx context.Context, localDebug bool, db *sql.DB, plan *RollbackPlan, result *RollbackResult) error {
+	byTable := make(map[string][]RollbackItem)
+	for _, item := range plan.Items {
+		if item.Action == RollbackRemove || item.Action == RollbackRestore {
+			byTable[item.Table] = append(byTable[item.Table], item)
+		}
+	}
+
+	for _, t := range rollbackTables {
+		if err := revertTable(ctx, localDebug, db, t, byTable[t.Name], RollbackRemove, result); err != nil {
+			return err
+		}
+	}
+	for i := len(rollbackTables) - 1; i >= 0; i-- {
+		t := rollbackTables[i]
+		if err := revertTable(ctx, localDebug, db, t, byTable[t.Name], RollbackRestore, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revertTable applies one action to the table's items, a batch per transaction
+func revertTable(ctx context.Context, localDebug bool, db *sql.DB, t ChainedTable, items []RollbackItem,
+	action string, result *RollbackResult) error {
+	var batch []RollbackItem
+	for _, item := range items {
+		if item.Action == action {
+			batch = append(batch, item)
+		}
+	}
+
+	// The key is cast to the column's type through the table's row type
+	remove := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE %[2]s = (jsonb_populate_record(NULL::%[1]s, jsonb_build_object(%[3]s, $1::TEXT))).%[2]s
+	`, pq.QuoteIdentifier(t.Name), pq.QuoteIdentifier(t.Key), pq.QuoteLiteral(t.Key))
+	restore := fmt.Sprintf(`
+		INSERT INTO %[1]s SELECT * FROM jsonb_populate_record(NULL::%[1]s, $1::JSONB)
+	`, pq.QuoteIdentifier(t.Name))
+
+	for start := 0; start < len(batch); start += rollbackBatch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := start + rollbackBatch
+		if end > len(batch) {
+			end = len(batch)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		for _, item := range batch[start:end] {
+			if _, err := tx.ExecContext(ctx, remove, item.RowKey); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to remove %s %s: %w", t.Name, item.RowKey, err)
+			}
+			if action == RollbackRestore {
+				if _, err := tx.ExecContext(ctx, restore, item.Before); err != nil {
+					tx.Rollback()
+					return fmt.Errorf("failed to restore %s %s: %w", t.Name, item.RowKey, err)
+				}
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit rollback batch: %w", err)
+		}
+
+		n := end - start
+		if action == RollbackRestore {
+			result.Restored += n
+		} else {
+			result.Removed += n
+		}
+		debug.DebugOutput(localDebug, "%s: %s %d rows", t.Name, action, n)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ehdc-llpg/internal/match"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+func TestRecordDecision(t *testing.T) {
+	m := store.NewMemory()
+	tracker := NewTrackerWithStore(m)
+
+	ctx := context.Background()
+	run, _ := m.CreateRun(ctx, "audit-test", "test", "")
+	err := tracker.RecordDecision(ctx, false, AuditDecision{
+		SrcID:     7,
+		UPRN:      "100062000001",
+		Decision:  "accepted",
+		Method:    "fuzzy",
+		Score:     0.93,
+		DecidedBy: "system",
+		DecidedAt: time.Now(),
+		RunID:     run.RunID,
+		Candidates: []match.Candidate{
+			{UPRN: "100062000001", Score: 0.93, Methods: []string{"fuzzy"}},
+			{UPRN: "100062000002", Score: 0.71},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RecordDecision: %v", err)
+	}
+
+	history, err := tracker.GetDecisionHistory(ctx, false, 7)
+	if err != nil {
+		t.Fatalf("GetDecisionHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history has %d entries, want decision plus one alternative", len(history))
+	}
+	if alt := history[1]; alt.CandidateUPRN != "100062000002" || alt.Method != "unknown" || alt.TieRank != 2 {
+		t.Errorf("alternative = %+v", alt)
+	}
+
+	if a, _ := m.Accepted(ctx, 7); a == nil || a.UPRN != "100062000001" {
+		t.Errorf("accepted = %+v, want 100062000001", a)
+	}
+
+	explained, err := m.LatestExplanation(ctx, 7)
+	if err != nil || explained == nil {
+		t.Fatalf("LatestExplanation = %v, %v; want the decision's explanation", explained, err)
+	}
+	if explained.RunID != run.RunID || explained.AcceptedUPRN != "100062000001" || explained.Margin == nil {
+		t.Errorf("explanation = %+v", explained)
+	}
+	candidates, _ := explained.Candidates.([]match.CandidateExplanation)
+	if len(candidates) != 2 || !candidates[0].Selected || candidates[1].Selected {
+		t.Errorf("explained candidates = %+v, want the accepted one selected", candidates)
+	}
+
+	stats, err := tracker.GetMatchingStatistics(ctx, false, run.RunID)
+	if err != nil {
+		t.Fatalf("GetMatchingStatistics: %v", err)
+	}
+	if stats.TotalProcessed != 1 || stats.DecisionBreakdown["accepted"].Count != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
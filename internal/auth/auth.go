@@ -0,0 +1,106 @@
+// Package auth authenticates web API callers and says what they may do. Users sign in with
+// a password for a browser session or call the API with a key; only hashes of passwords,
+// keys and session tokens are stored. Each user has one role, and roles are ordered:
+// a senior reviewer may do everything a reviewer may, and an admin everything.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Role is what a user may do in the web interface
+type Role string
+
+// Roles, least privileged first
+const (
+	RoleViewer         Role = "viewer"          // read records, matches, explanations and history
+	RoleReviewer       Role = "reviewer"        // accept and reject matches, work the review queue
+	RoleSeniorReviewer Role = "senior_reviewer" // sign off low-confidence accepts, override coordinates
+	RoleAdmin          Role = "admin"           // everything, including user administration
+)
+
+// Roles lists the roles in order of privilege
+var Roles = []Role{RoleViewer, RoleReviewer, RoleSeniorReviewer, RoleAdmin}
+
+// ParseRole checks a role name
+func ParseRole(name string) (Role, error) {
+	for _, r := range Roles {
+		if string(r) == name {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q (want viewer, reviewer, senior_reviewer or admin)", name)
+}
+
+// rank orders roles; unknown roles rank below viewer
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// AtLeast reports whether r carries every permission of min
+func (r Role) AtLeast(min Role) bool {
+	return r.rank() >= 0 && r.rank() >= min.rank()
+}
+
+// How a request was authenticated
+const (
+	MethodAPIKey    = "api_key"
+	MethodSession   = "session"
+	MethodAnonymous = "anonymous" // authentication is disabled (WEB_AUTH=false)
+)
+
+// SessionCookie carries a signed-in browser's session token
+const SessionCookie = "ehdc_session"
+
+// AnonymousUser is who requests act as when authentication is disabled
+const AnonymousUser = "web_user"
+
+var (
+	// ErrInvalidCredentials is returned for an unknown, wrong, expired, revoked or
+	// disabled credential; callers should not say which
+	ErrInvalidCredentials = errors.New("invalid or expired credentials")
+
+	// ErrUserNotFound is returned when managing a user that does not exist
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	UserID      int64  `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Role        Role   `json:"role"`
+	Method      string `json:"method"`
+	KeyID       int64  `json:"key_id,omitempty"` // for MethodAPIKey
+}
+
+// Anonymous is the identity of every request when authentication is disabled. It may do
+// everything, as before authentication existed.
+func Anonymous() *Identity {
+	return &Identity{Username: AnonymousUser, Role: RoleAdmin, Method: MethodAnonymous}
+}
+
+// Can reports whether the identity holds at least the role; a nil identity holds none
+func (id *Identity) Can(min Role) bool {
+	return id != nil && id.Role.AtLeast(min)
+}
+
+type identityKey struct{}
+
+// WithIdentity records the request's caller in its context
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the request's caller, nil when it has not been authenticated
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestRolesAreOrdered(t *testing.T) {
+	tests := []struct {
+		role, min Role
+		want      bool
+	}{
+		{RoleAdmin, RoleSeniorReviewer, true},
+		{RoleSeniorReviewer, RoleReviewer, true},
+		{RoleReviewer, RoleReviewer, true},
+		{RoleReviewer, RoleSeniorReviewer, false},
+		{RoleViewer, RoleReviewer, false},
+		{Role("owner"), RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.AtLeast(tt.min); got != tt.want {
+			t.Errorf("%s.AtLeast(%s) = %v, want %v", tt.role, tt.min, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRole("senior_reviewer"); err != nil {
+		t.Errorf("ParseRole(senior_reviewer): %v", err)
+	}
+	if _, err := ParseRole("root"); err == nil {
+		t.Error("ParseRole(root) accepted an unknown role")
+	}
+
+	var none *Identity
+	if none.Can(RoleViewer) {
+		t.Error("a nil identity can view")
+	}
+	ctx := WithIdentity(context.Background(), &Identity{Username: "alice", Role: RoleReviewer})
+	if id := FromContext(ctx); !id.Can(RoleReviewer) || id.Can(RoleAdmin) {
+		t.Errorf("identity from context = %+v", id)
+	}
+}
+
+func TestPasswordHash(t *testing.T) {
+	// A low work factor keeps the test quick; CheckPassword reads it from the hash
+	encoded := encodePassword("correct horse battery", []byte("0123456789abcdef"), 10)
+	if !CheckPassword(encoded, "correct horse battery") {
+		t.Error("CheckPassword rejected the right password")
+	}
+	if CheckPassword(encoded, "correct horse battery!") {
+		t.Error("CheckPassword accepted a wrong password")
+	}
+	if CheckPassword("plain-text", "plain-text") {
+		t.Error("CheckPassword accepted a value that is not a hash")
+	}
+	if _, err := HashPassword("short"); err == nil {
+		t.Error("HashPassword accepted a password under the minimum length")
+	}
+}
+
+func TestPBKDF2Vector(t *testing.T) {
+	// RFC 7914 section 11, PBKDF2-HMAC-SHA256 with one iteration
+	got := pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64)
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("pbkdf2SHA256 = %s, want %s", hex.EncodeToString(got), want)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "ehdc_"+prefix+"_") {
+		t.Errorf("key %q does not carry prefix %q", key, prefix)
+	}
+	if got, ok := parseAPIKey(key); !ok || got != prefix {
+		t.Errorf("parseAPIKey(%q) = %q, %v; want %q", key, got, ok, prefix)
+	}
+	for _, bad := range []string{"", "ehdc_1234", "other_12345678_secret", "ehdc_123_secret", "ehdc_12345678_"} {
+		if _, ok := parseAPIKey(bad); ok {
+			t.Errorf("parseAPIKey(%q) accepted a malformed key", bad)
+		}
+	}
+	if hashAPIKey(key) == hashAPIKey(key+"x") {
+		t.Error("different keys hash alike")
+	}
+}
+
+func TestSessionHashDependsOnKey(t *testing.T) {
+	if hashSession([]byte("key one"), "token") == hashSession([]byte("key two"), "token") {
+		t.Error("session hash does not depend on the session key")
+	}
+}
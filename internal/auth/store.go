@@ -0,0 +1,413 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
+)
+
+// User is a web interface account (app_user)
+type User struct {
+	UserID      int64
+	Username    string
+	DisplayName string
+	Role        Role
+	HasPassword bool // false for accounts that only use API keys
+	CreatedBy   string
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+	DisabledAt  *time.Time
+	Keys        int // live API keys
+}
+
+// APIKey describes an API key (app_api_key); the key itself is only shown when created
+type APIKey struct {
+	KeyID      int64
+	Username   string
+	Prefix     string
+	Label      string
+	CreatedBy  string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// Store keeps users, API keys and sessions in PostgreSQL (migration 059)
+type Store struct {
+	db         *sql.DB
+	sessionKey []byte
+}
+
+// NewStore creates an auth store; sessionKey (SESSION_KEY) keys the session token hashes
+func NewStore(db *sql.DB, sessionKey string) *Store {
+	return &Store{db: db, sessionKey: []byte(sessionKey)}
+}
+
+// CreateUser adds a user. An empty password creates an account that can only use API keys.
+func (s *Store) CreateUser(ctx context.Context, username, displayName string, role Role, password, createdBy string) (*User, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if username == AnonymousUser {
+		return nil, fmt.Errorf("%q is reserved for unauthenticated use", AnonymousUser)
+	}
+	var hash sql.NullString
+	if password != "" {
+		h, err := HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		hash = sql.NullString{String: h, Valid: true}
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	u := &User{Username: username, DisplayName: displayName, Role: role, HasPassword: hash.Valid, CreatedBy: createdBy}
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO app_user (username, display_name, role, password_hash, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		RETURNING user_id, created_at
+	`, username, displayName, string(role), hash, createdBy).Scan(&u.UserID, &u.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user %s: %w", username, err)
+	}
+	return u, nil
+}
+
+// Users lists every user with how many live API keys each has
+func (s *Store) Users(ctx context.Context) ([]User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.user_id, u.username, COALESCE(u.display_name, ''), u.role, u.password_hash IS NOT NULL,
+			u.created_by, u.created_at, u.last_login_at, u.disabled_at,
+			(SELECT count(*) FROM app_api_key k
+			 WHERE k.user_id = u.user_id AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > now()))
+		FROM app_user u
+		ORDER BY u.username
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		var role string
+		var lastLogin, disabled sql.NullTime
+		if err := rows.Scan(&u.UserID, &u.Username, &u.DisplayName, &role, &u.HasPassword,
+			&u.CreatedBy, &u.CreatedAt, &lastLogin, &disabled, &u.Keys); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		u.Role = Role(role)
+		u.LastLoginAt = nullTime(lastLogin)
+		u.DisabledAt = nullTime(disabled)
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// SetRole changes a user's role
+func (s *Store) SetRole(ctx context.Context, username string, role Role) error {
+	return s.updateUser(ctx, username, "role = $2", string(role))
+}
+
+// SetPassword replaces a user's password and signs them out everywhere
+func (s *Store) SetPassword(ctx context.Context, username, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.updateUser(ctx, username, "password_hash = $2", hash); err != nil {
+		return err
+	}
+	_, err = s.RevokeSessions(ctx, username)
+	return err
+}
+
+// SetDisabled disables or re-enables a user. A disabled user's sessions are revoked and
+// their API keys stop working until they are enabled again.
+func (s *Store) SetDisabled(ctx context.Context, username string, disabled bool) error {
+	if !disabled {
+		return s.updateUser(ctx, username, "disabled_at = NULL")
+	}
+	if err := s.updateUser(ctx, username, "disabled_at = COALESCE(disabled_at, now())"); err != nil {
+		return err
+	}
+	_, err := s.RevokeSessions(ctx, username)
+	return err
+}
+
+// updateUser applies a SET clause to the named user; the username is $1
+func (s *Store) updateUser(ctx context.Context, username, set string, args ...interface{}) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE app_user SET "+set+" WHERE username = $1",
+		append([]interface{}{strings.ToLower(username)}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update user %s: %w", username, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// CreateAPIKey issues a key for the user and returns it; it cannot be shown again. A zero
+// expiry never expires.
+func (s *Store) CreateAPIKey(ctx context.Context, username, label string, expires time.Time, createdBy string) (string, *APIKey, error) {
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	var expiresAt sql.NullTime
+	if !expires.IsZero() {
+		expiresAt = sql.NullTime{Time: expires, Valid: true}
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	k := &APIKey{Username: strings.ToLower(username), Prefix: prefix, Label: label, CreatedBy: createdBy, ExpiresAt: nullTime(expiresAt)}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO app_api_key (user_id, prefix, secret_hash, label, created_by, expires_at)
+		SELECT user_id, $2, $3, NULLIF($4, ''), $5, $6 FROM app_user WHERE username = $1
+		RETURNING key_id, created_at
+	`, k.Username, prefix, hashAPIKey(key), label, createdBy, expiresAt).Scan(&k.KeyID, &k.CreatedAt)
+	if err == sql.ErrNoRows {
+		return "", nil, ErrUserNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create API key for %s: %w", username, err)
+	}
+	return key, k, nil
+}
+
+// APIKeys lists the API keys of one user, or of everyone when username is empty
+func (s *Store) APIKeys(ctx context.Context, username string) ([]APIKey, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT k.key_id, u.username, k.prefix, COALESCE(k.label, ''), k.created_by, k.created_at,
+			k.expires_at, k.last_used_at, k.revoked_at
+		FROM app_api_key k
+		JOIN app_user u ON u.user_id = k.user_id
+		WHERE $1 = '' OR u.username = $1
+		ORDER BY u.username, k.key_id
+	`, strings.ToLower(username))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var k APIKey
+		var expires, used, revoked sql.NullTime
+		if err := rows.Scan(&k.KeyID, &k.Username, &k.Prefix, &k.Label, &k.CreatedBy, &k.CreatedAt,
+			&expires, &used, &revoked); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		k.ExpiresAt, k.LastUsedAt, k.RevokedAt = nullTime(expires), nullTime(used), nullTime(revoked)
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey stops a key working; revoking a revoked key is not an error
+func (s *Store) RevokeAPIKey(ctx context.Context, keyID int64) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE app_api_key SET revoked_at = COALESCE(revoked_at, now()) WHERE key_id = $1
+	`, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key %d: %w", keyID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("API key %d not found", keyID)
+	}
+	return nil
+}
+
+// AuthenticateKey identifies the caller presenting an API key
+func (s *Store) AuthenticateKey(ctx context.Context, key string) (*Identity, error) {
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	id := &Identity{Method: MethodAPIKey}
+	var hash, role string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT k.key_id, k.secret_hash, u.user_id, u.username, COALESCE(u.display_name, ''), u.role
+		FROM app_api_key k
+		JOIN app_user u ON u.user_id = k.user_id
+		WHERE k.prefix = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > now())
+		  AND u.disabled_at IS NULL
+	`, prefix).Scan(&id.KeyID, &hash, &id.UserID, &id.Username, &id.DisplayName, &role)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashAPIKey(key))) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	id.Role = Role(role)
+
+	// Last use is informational; a failure to record it does not fail the request
+	s.db.ExecContext(ctx, `
+		UPDATE app_api_key SET last_used_at = now()
+		WHERE key_id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+	`, id.KeyID)
+	return id, nil
+}
+
+// dummyPassword is checked for unknown users so a failed sign-in takes as long either way
+var dummyPassword = encodePassword("not a password", []byte("0123456789abcdef"), passwordIterations)
+
+// Login checks a username and password and opens a session lasting ttl, returning its
+// token. client describes where the sign-in came from.
+func (s *Store) Login(ctx context.Context, username, password, client string, ttl time.Duration) (string, *Identity, time.Time, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	id := &Identity{Method: MethodSession}
+	var hash sql.NullString
+	var role string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, username, COALESCE(display_name, ''), role, password_hash
+		FROM app_user
+		WHERE username = $1 AND disabled_at IS NULL
+	`, strings.ToLower(strings.TrimSpace(username))).Scan(&id.UserID, &id.Username, &id.DisplayName, &role, &hash)
+	if err != nil && err != sql.ErrNoRows {
+		return "", nil, time.Time{}, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if err == sql.ErrNoRows || !hash.Valid {
+		CheckPassword(dummyPassword, password)
+		return "", nil, time.Time{}, ErrInvalidCredentials
+	}
+	if !CheckPassword(hash.String, password) {
+		return "", nil, time.Time{}, ErrInvalidCredentials
+	}
+	id.Role = Role(role)
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	expires := time.Now().Add(ttl)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO app_session (token_hash, user_id, client, expires_at) VALUES ($1, $2, NULLIF($3, ''), $4)
+	`, hashSession(s.sessionKey, token), id.UserID, client, expires); err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to open session: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE app_user SET last_login_at = now() WHERE user_id = $1`, id.UserID); err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to record sign-in: %w", err)
+	}
+	// Sessions are kept a week past expiry for reference, then dropped
+	if _, err := tx.ExecContext(ctx, `DELETE FROM app_session WHERE expires_at < now() - interval '7 days'`); err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to purge old sessions: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to commit session: %w", err)
+	}
+	return token, id, expires, nil
+}
+
+// AuthenticateSession identifies the caller presenting a session token
+func (s *Store) AuthenticateSession(ctx context.Context, token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	id := &Identity{Method: MethodSession}
+	var role string
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE app_session s SET last_seen_at = now()
+		FROM app_user u
+		WHERE s.token_hash = $1 AND u.user_id = s.user_id
+		  AND s.revoked_at IS NULL AND s.expires_at > now() AND u.disabled_at IS NULL
+		RETURNING u.user_id, u.username, COALESCE(u.display_name, ''), u.role
+	`, hashSession(s.sessionKey, token)).Scan(&id.UserID, &id.Username, &id.DisplayName, &role)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+	id.Role = Role(role)
+	return id, nil
+}
+
+// Logout ends the session with the token
+func (s *Store) Logout(ctx context.Context, token string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE app_session SET revoked_at = now() WHERE token_hash = $1 AND revoked_at IS NULL
+	`, hashSession(s.sessionKey, token))
+	if err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	return nil
+}
+
+// RevokeSessions signs the user out everywhere and returns how many sessions it ended
+func (s *Store) RevokeSessions(ctx context.Context, username string) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE app_session SET revoked_at = now()
+		WHERE revoked_at IS NULL AND expires_at > now()
+		  AND user_id = (SELECT user_id FROM app_user WHERE username = $1)
+	`, strings.ToLower(username))
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions of %s: %w", username, err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// IsNotFound reports whether err is ErrUserNotFound
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrUserNotFound)
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,618 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is the typed configuration shared by the CLIs, the web server and library packages.
+// Values are resolved per key in the order flags > environment > file > defaults.
+type Config struct {
+	Database Database
+	Web      Web
+	SymSpell SymSpell
+	Matching Matching
+	Qdrant   Qdrant
+	Audit    Audit
+	Paths    Paths
+
+	// File is the configuration file that was read, empty when none was found
+	File string
+
+	sources map[string]Source
+}
+
+// Database holds the PostgreSQL connection settings
+type Database struct {
+	Host           string
+	Port           int
+	User           string
+	Password       string
+	Name           string
+	SSLMode        string
+	MaxConnections int
+
+	// QueryTimeout bounds each query made with a context (db.WithTimeout); 0 disables it
+	QueryTimeout time.Duration
+	// StatementTimeout is sent as PostgreSQL statement_timeout for every statement; 0 disables it
+	StatementTimeout time.Duration
+
+	// Application is sent as application_name; db.Open makes one unique to the process
+	Application string
+}
+
+// Web holds the web server settings
+type Web struct {
+	Host                  string
+	Port                  int
+	SessionKey            string
+	ExportEnabled         bool
+	ManualOverrideEnabled bool
+
+	// ExportDir holds finished exports until they expire after ExportTTL
+	ExportDir     string
+	ExportTTL     time.Duration
+	ExportWorkers int
+
+	// Auth requires a signed-in session or an API key for the web API
+	Auth bool
+	// SessionTTL is how long a browser session lasts after sign-in
+	SessionTTL time.Duration
+
+	// ReviewLease is how long a claimed review item stays with its reviewer without renewal
+	ReviewLease time.Duration
+	// SignoffBelow is the confidence under which an accept needs a second reviewer's sign-off
+	SignoffBelow float64
+}
+
+// SymSpell holds the spelling correction settings
+type SymSpell struct {
+	Enabled         bool
+	MaxEditDistance int
+	PrefixLength    int
+	MinTermLength   int
+}
+
+// Matching holds batch matching settings
+type Matching struct {
+	BatchSize int
+}
+
+// Qdrant holds the vector database connection settings
+type Qdrant struct {
+	Host   string
+	Port   int
+	APIKey string
+}
+
+// Audit holds the audit chain checkpoint settings
+type Audit struct {
+	// SigningKey is the base64 Ed25519 seed that signs audit checkpoints
+	SigningKey    string
+	CheckpointDir string
+}
+
+// Paths holds optional local data locations
+type Paths struct {
+	SourceMappingsDir string
+	OSTN15GridFile    string
+}
+
+// Source records where a value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Key documents one configuration setting
+type Key struct {
+	Name        string
+	Aliases     []string // older environment names still accepted, e.g. PGHOST
+	Default     string
+	Description string
+	Secret      bool
+	Output      bool // changes matching output; included in Hash
+	field       func(c *Config) interface{} // *string, *int, *float64, *bool or *time.Duration
+}
+
+// Keys lists every configuration key in display order
+var Keys = []Key{
+	{Name: "DB_HOST", Aliases: []string{"PGHOST"}, Description: "PostgreSQL host",
+		field: func(c *Config) interface{} { return &c.Database.Host }},
+	{Name: "DB_PORT", Aliases: []string{"PGPORT"}, Default: "5432", Description: "PostgreSQL port",
+		field: func(c *Config) interface{} { return &c.Database.Port }},
+	{Name: "DB_USER", Aliases: []string{"PGUSER"}, Description: "PostgreSQL user",
+		field: func(c *Config) interface{} { return &c.Database.User }},
+	{Name: "DB_PASSWORD", Aliases: []string{"PGPASSWORD"}, Description: "PostgreSQL password", Secret: true,
+		field: func(c *Config) interface{} { return &c.Database.Password }},
+	{Name: "DB_NAME", Aliases: []string{"PGDATABASE"}, Description: "PostgreSQL database name",
+		field: func(c *Config) interface{} { return &c.Database.Name }},
+	{Name: "DB_SSLMODE", Default: "disable", Description: "PostgreSQL sslmode",
+		field: func(c *Config) interface{} { return &c.Database.SSLMode }},
+	{Name: "DB_MAX_CONNECTIONS", Default: "20", Description: "Maximum open database connections",
+		field: func(c *Config) interface{} { return &c.Database.MaxConnections }},
+	{Name: "DB_QUERY_TIMEOUT", Default: "2m", Description: "Timeout for each matching, loading and web query (0 disables)",
+		field: func(c *Config) interface{} { return &c.Database.QueryTimeout }},
+	{Name: "DB_STATEMENT_TIMEOUT", Default: "0", Description: "PostgreSQL statement_timeout for every statement (0 disables)",
+		field: func(c *Config) interface{} { return &c.Database.StatementTimeout }},
+
+	{Name: "WEB_HOST", Default: "localhost", Description: "Web server listen host",
+		field: func(c *Config) interface{} { return &c.Web.Host }},
+	{Name: "WEB_PORT", Default: "8443", Description: "Web server listen port",
+		field: func(c *Config) interface{} { return &c.Web.Port }},
+	{Name: "SESSION_KEY", Description: "Web session signing key", Secret: true,
+		field: func(c *Config) interface{} { return &c.Web.SessionKey }},
+	{Name: "WEB_AUTH", Default: "true", Description: "Require sign-in or an API key for the web API (false for development only)",
+		field: func(c *Config) interface{} { return &c.Web.Auth }},
+	{Name: "SESSION_TTL", Default: "12h", Description: "How long a web sign-in lasts",
+		field: func(c *Config) interface{} { return &c.Web.SessionTTL }},
+	{Name: "ENABLE_EXPORT", Default: "true", Description: "Allow CSV exports from the web interface",
+		field: func(c *Config) interface{} { return &c.Web.ExportEnabled }},
+	{Name: "EXPORT_DIR", Default: "exports", Description: "Directory the web server writes exports to (only for exports: files left in it are removed at start-up)",
+		field: func(c *Config) interface{} { return &c.Web.ExportDir }},
+	{Name: "EXPORT_TTL", Default: "24h", Description: "How long a finished export can be downloaded before it is deleted",
+		field: func(c *Config) interface{} { return &c.Web.ExportTTL }},
+	{Name: "EXPORT_WORKERS", Default: "2", Description: "Exports the web server runs at once; more wait in a queue",
+		field: func(c *Config) interface{} { return &c.Web.ExportWorkers }},
+	{Name: "ENABLE_MANUAL_OVERRIDE", Default: "true", Description: "Allow manual match overrides from the web interface",
+		field: func(c *Config) interface{} { return &c.Web.ManualOverrideEnabled }},
+	{Name: "REVIEW_LEASE", Default: "15m", Description: "How long a reviewer holds a claimed review item without renewing it",
+		field: func(c *Config) interface{} { return &c.Web.ReviewLease }},
+	{Name: "REVIEW_SIGNOFF_BELOW", Default: "0.80", Description: "Accepts below this confidence need a second reviewer's sign-off (0 disables)",
+		field: func(c *Config) interface{} { return &c.Web.SignoffBelow }},
+
+	{Name: "SYMSPELL_ENABLED", Default: "false", Description: "Enable SymSpell spelling correction", Output: true,
+		field: func(c *Config) interface{} { return &c.SymSpell.Enabled }},
+	{Name: "SYMSPELL_MAX_EDIT_DISTANCE", Default: "2", Description: "SymSpell maximum edit distance (1-3)", Output: true,
+		field: func(c *Config) interface{} { return &c.SymSpell.MaxEditDistance }},
+	{Name: "SYMSPELL_PREFIX_LENGTH", Default: "7", Description: "SymSpell index prefix length", Output: true,
+		field: func(c *Config) interface{} { return &c.SymSpell.PrefixLength }},
+	{Name: "SYMSPELL_MIN_TERM_LENGTH", Default: "3", Description: "Shortest token SymSpell will correct", Output: true,
+		field: func(c *Config) interface{} { return &c.SymSpell.MinTermLength }},
+
+	{Name: "MATCH_BATCH_SIZE", Default: "100", Description: "Documents per batch in match-batch", Output: true,
+		field: func(c *Config) interface{} { return &c.Matching.BatchSize }},
+
+	{Name: "QDRANT_HOST", Default: "localhost", Description: "Qdrant host",
+		field: func(c *Config) interface{} { return &c.Qdrant.Host }},
+	{Name: "QDRANT_PORT", Default: "6333", Description: "Qdrant port",
+		field: func(c *Config) interface{} { return &c.Qdrant.Port }},
+	{Name: "QDRANT_API_KEY", Description: "Qdrant API key", Secret: true,
+		field: func(c *Config) interface{} { return &c.Qdrant.APIKey }},
+
+	{Name: "AUDIT_SIGNING_KEY", Description: "Base64 Ed25519 seed that signs audit checkpoints (matcher-v2 -cmd=audit-keygen)", Secret: true,
+		field: func(c *Config) interface{} { return &c.Audit.SigningKey }},
+	{Name: "AUDIT_CHECKPOINT_DIR", Default: "audit-checkpoints", Description: "Directory signed audit checkpoints are exported to",
+		field: func(c *Config) interface{} { return &c.Audit.CheckpointDir }},
+
+	{Name: "SOURCE_MAPPINGS_DIR", Description: "Directory of source mapping JSON overriding the built-in mappings", Output: true,
+		field: func(c *Config) interface{} { return &c.Paths.SourceMappingsDir }},
+	{Name: "OSTN15_GRID_FILE", Description: "OSTN15 grid file for coordinate transformation", Output: true,
+		field: func(c *Config) interface{} { return &c.Paths.OSTN15GridFile }},
+}
+
+// DefaultFiles are searched, in order, when Options.File is empty
+var DefaultFiles = []string{".env", "../.env", "../../.env"}
+
+// Options controls where Load reads values from
+type Options struct {
+	File    string    // KEY=VALUE file; empty searches DefaultFiles
+	Environ []string  // KEY=VALUE pairs; nil uses os.Environ()
+	Flags   Overrides // values given on the command line
+}
+
+// Overrides holds KEY=VALUE settings from repeated command line flags. It satisfies
+// flag.Value and pflag.Value.
+type Overrides map[string]string
+
+func (o *Overrides) String() string {
+	var pairs []string
+	for k, v := range *o {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (o *Overrides) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", s)
+	}
+	if *o == nil {
+		*o = make(Overrides)
+	}
+	(*o)[strings.TrimSpace(parts[0])] = parts[1]
+	return nil
+}
+
+func (o *Overrides) Type() string {
+	return "KEY=VALUE"
+}
+
+// Defaults returns the configuration with every key at its default
+func Defaults() *Config {
+	c := &Config{sources: make(map[string]Source)}
+	for _, k := range Keys {
+		if err := k.set(c, k.Default); err != nil {
+			panic(fmt.Sprintf("bad default for %s: %v", k.Name, err))
+		}
+		c.sources[k.Name] = SourceDefault
+	}
+	return c
+}
+
+// Load resolves the configuration from defaults, the file, the environment and flags.
+// It reports values that cannot be parsed; call Validate for range and required checks.
+func Load(opts Options) (*Config, error) {
+	c := Defaults()
+
+	fileValues, file, err := readFile(opts.File)
+	if err != nil {
+		return nil, err
+	}
+	c.File = file
+	if err := c.apply(fileValues, SourceFile); err != nil {
+		return nil, err
+	}
+
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	if err := c.apply(parseEnviron(environ), SourceEnv); err != nil {
+		return nil, err
+	}
+
+	for name := range opts.Flags {
+		if lookupKey(name) == nil {
+			return nil, fmt.Errorf("unknown configuration key %s", name)
+		}
+	}
+	if err := c.apply(opts.Flags, SourceFlag); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// apply sets every key present in values, preferring a key's name over its aliases
+func (c *Config) apply(values map[string]string, source Source) error {
+	for _, k := range Keys {
+		value, ok := values[k.Name]
+		if !ok {
+			for _, alias := range k.Aliases {
+				if value, ok = values[alias]; ok {
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := k.set(c, value); err != nil {
+			return fmt.Errorf("%s (from %s): %w", k.Name, source, err)
+		}
+		c.sources[k.Name] = source
+	}
+	return nil
+}
+
+func (k Key) set(c *Config, value string) error {
+	value = strings.TrimSpace(value)
+	switch p := k.field(c).(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*p = f
+	case *bool:
+		switch strings.ToLower(value) {
+		case "true", "1", "yes", "on":
+			*p = true
+		case "false", "0", "no", "off", "":
+			*p = false
+		default:
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+	case *time.Duration:
+		if value == "0" {
+			*p = 0
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*p = d
+	}
+	return nil
+}
+
+func (k Key) get(c *Config) string {
+	switch p := k.field(c).(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *float64:
+		return strconv.FormatFloat(*p, 'f', -1, 64)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *time.Duration:
+		return p.String()
+	}
+	return ""
+}
+
+func lookupKey(name string) *Key {
+	for i := range Keys {
+		if Keys[i].Name == name {
+			return &Keys[i]
+		}
+	}
+	return nil
+}
+
+// readFile parses a KEY=VALUE file, ignoring blank lines and # comments. An explicit
+// path must exist; the default locations are optional.
+func readFile(path string) (map[string]string, string, error) {
+	paths := DefaultFiles
+	if path != "" {
+		paths = []string{path}
+	}
+
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			if path != "" {
+				return nil, "", fmt.Errorf("failed to read config file: %w", err)
+			}
+			continue
+		}
+
+		values := make(map[string]string)
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) != 2 {
+				return nil, "", fmt.Errorf("%s line %d: expected KEY=VALUE", p, i+1)
+			}
+			values[strings.TrimSpace(parts[0])] = unquote(strings.TrimSpace(parts[1]))
+		}
+		return values, p, nil
+	}
+	return nil, "", nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func parseEnviron(environ []string) map[string]string {
+	values := make(map[string]string)
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			values[parts[0]] = parts[1]
+		}
+	}
+	return values
+}
+
+// ValidationError lists every invalid setting
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// Validate checks the whole configuration
+func (c *Config) Validate() error {
+	var problems ValidationError
+	problems = append(problems, c.Database.problems()...)
+	problems = checkPort(problems, "WEB_PORT", c.Web.Port)
+	if c.Web.ExportTTL <= 0 {
+		problems = append(problems, fmt.Sprintf("EXPORT_TTL must be positive (got %s)", c.Web.ExportTTL))
+	}
+	problems = checkPositive(problems, "EXPORT_WORKERS", c.Web.ExportWorkers)
+	if c.Web.SessionTTL <= 0 {
+		problems = append(problems, fmt.Sprintf("SESSION_TTL must be positive (got %s)", c.Web.SessionTTL))
+	}
+	if c.Web.ReviewLease <= 0 {
+		problems = append(problems, fmt.Sprintf("REVIEW_LEASE must be positive (got %s)", c.Web.ReviewLease))
+	}
+	if c.Web.SignoffBelow < 0 || c.Web.SignoffBelow > 1 {
+		problems = append(problems, fmt.Sprintf("REVIEW_SIGNOFF_BELOW must be between 0 and 1 (got %g)", c.Web.SignoffBelow))
+	}
+	if c.SymSpell.MaxEditDistance < 1 || c.SymSpell.MaxEditDistance > 3 {
+		problems = append(problems, fmt.Sprintf("SYMSPELL_MAX_EDIT_DISTANCE must be between 1 and 3 (got %d)", c.SymSpell.MaxEditDistance))
+	}
+	problems = checkPositive(problems, "SYMSPELL_PREFIX_LENGTH", c.SymSpell.PrefixLength)
+	problems = checkPositive(problems, "SYMSPELL_MIN_TERM_LENGTH", c.SymSpell.MinTermLength)
+	problems = checkPositive(problems, "MATCH_BATCH_SIZE", c.Matching.BatchSize)
+	problems = checkPort(problems, "QDRANT_PORT", c.Qdrant.Port)
+	if c.Audit.SigningKey != "" {
+		if seed, err := base64.StdEncoding.DecodeString(c.Audit.SigningKey); err != nil || len(seed) != 32 {
+			problems = append(problems, "AUDIT_SIGNING_KEY must be a base64 32-byte Ed25519 seed")
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// Validate checks only the database settings, for commands that just need a connection
+func (d Database) Validate() error {
+	if problems := d.problems(); len(problems) > 0 {
+		return ValidationError(problems)
+	}
+	return nil
+}
+
+var sslModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true,
+}
+
+func (d Database) problems() []string {
+	var problems []string
+	for _, required := range []struct{ key, value string }{
+		{"DB_HOST", d.Host}, {"DB_USER", d.User}, {"DB_NAME", d.Name},
+	} {
+		if required.value == "" {
+			problems = append(problems, required.key+" is required")
+		}
+	}
+	problems = checkPort(problems, "DB_PORT", d.Port)
+	if !sslModes[d.SSLMode] {
+		problems = append(problems, fmt.Sprintf("DB_SSLMODE %q is not a PostgreSQL sslmode", d.SSLMode))
+	}
+	problems = checkPositive(problems, "DB_MAX_CONNECTIONS", d.MaxConnections)
+	if d.QueryTimeout < 0 || d.StatementTimeout < 0 {
+		problems = append(problems, "DB_QUERY_TIMEOUT and DB_STATEMENT_TIMEOUT cannot be negative")
+	}
+	return problems
+}
+
+func checkPort(problems []string, key string, port int) []string {
+	if port < 1 || port > 65535 {
+		return append(problems, fmt.Sprintf("%s must be between 1 and 65535 (got %d)", key, port))
+	}
+	return problems
+}
+
+func checkPositive(problems []string, key string, n int) []string {
+	if n < 1 {
+		return append(problems, fmt.Sprintf("%s must be positive (got %d)", key, n))
+	}
+	return problems
+}
+
+// DSN returns the lib/pq connection string. lib/pq sends statement_timeout to the server
+// as a run-time parameter.
+func (d Database) DSN() string {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, quoteDSN(d.Password), d.Name, d.SSLMode)
+	if d.StatementTimeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", d.StatementTimeout.Milliseconds())
+	}
+	if d.Application != "" {
+		dsn += " application_name=" + quoteDSN(d.Application)
+	}
+	return dsn
+}
+
+// String describes the connection with the password redacted
+func (d Database) String() string {
+	return fmt.Sprintf("%s@%s:%d/%s", d.User, d.Host, d.Port, d.Name)
+}
+
+// quoteDSN quotes a value containing spaces or quotes, as lib/pq expects
+func quoteDSN(s string) string {
+	if s != "" && !strings.ContainsAny(s, ` '\`) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// Source reports where a key's value came from
+func (c *Config) Source(key string) Source {
+	return c.sources[key]
+}
+
+// Value returns a key's value, with secrets redacted
+func (c *Config) Value(key string) string {
+	k := lookupKey(key)
+	if k == nil {
+		return ""
+	}
+	value := k.get(c)
+	if k.Secret && value != "" {
+		return "********"
+	}
+	return value
+}
+
+// Write prints every key with its redacted value and source
+func (c *Config) Write(w io.Writer) {
+	if c.File != "" {
+		fmt.Fprintf(w, "Config file: %s\n\n", c.File)
+	} else {
+		fmt.Fprintf(w, "Config file: (none)\n\n")
+	}
+	fmt.Fprintf(w, "%-28s %-24s %-8s %s\n", "KEY", "VALUE", "SOURCE", "DESCRIPTION")
+	for _, k := range Keys {
+		fmt.Fprintf(w, "%-28s %-24s %-8s %s\n", k.Name, c.Value(k.Name), c.Source(k.Name), k.Description)
+	}
+}
+
+// Hash fingerprints the settings that change matching output, so results produced under
+// different configurations can be told apart. Connection settings and secrets are excluded.
+func (c *Config) Hash() string {
+	h := sha256.New()
+	for _, k := range Keys {
+		if k.Output {
+			fmt.Fprintf(h, "%s=%s\n", k.Name, k.get(c))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var (
+	currentMu sync.RWMutex
+	current   *Config
+)
+
+// Use installs cfg as the configuration library packages read through Current
+func Use(cfg *Config) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = cfg
+}
+
+// Current returns the configuration installed by Use. Before Use it loads one from the
+// default file and the environment, falling back to defaults if that fails.
+func Current() *Config {
+	currentMu.RLock()
+	cfg := current
+	currentMu.RUnlock()
+	if cfg != nil {
+		return cfg
+	}
+
+	cfg, err := Load(Options{})
+	if err != nil {
+		cfg = Defaults()
+	}
+	Use(cfg)
+	return cfg
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "# comment\nDB_HOST=filehost\nDB_NAME=filedb\nDB_PORT=6543\nWEB_PORT='9000'\nUNRELATED=x\n")
+
+	cfg, err := Load(Options{
+		File:    file,
+		Environ: []string{"DB_HOST=envhost", "PGUSER=aliasuser", "DB_PASSWORD=secret"},
+		Flags:   Overrides{"DB_HOST": "flaghost"},
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	checks := []struct {
+		key, value string
+		source     Source
+	}{
+		{"DB_HOST", "flaghost", SourceFlag},
+		{"DB_USER", "aliasuser", SourceEnv},
+		{"DB_NAME", "filedb", SourceFile},
+		{"DB_PORT", "6543", SourceFile},
+		{"WEB_PORT", "9000", SourceFile},
+		{"DB_SSLMODE", "disable", SourceDefault},
+		{"DB_PASSWORD", "********", SourceEnv},
+	}
+	for _, c := range checks {
+		if got := cfg.Value(c.key); got != c.value {
+			t.Errorf("%s = %q, want %q", c.key, got, c.value)
+		}
+		if got := cfg.Source(c.key); got != c.source {
+			t.Errorf("%s source = %s, want %s", c.key, got, c.source)
+		}
+	}
+	if cfg.Database.Password != "secret" {
+		t.Errorf("password not loaded")
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(Options{File: "/nonexistent/test.env", Environ: []string{}}); err == nil {
+		t.Errorf("expected error for a missing explicit config file")
+	}
+	if _, err := Load(Options{Environ: []string{"DB_PORT=abc"}}); err == nil || !strings.Contains(err.Error(), "DB_PORT") {
+		t.Errorf("expected DB_PORT parse error, got %v", err)
+	}
+	if _, err := Load(Options{Environ: []string{}, Flags: Overrides{"DB_HOTS": "x"}}); err == nil {
+		t.Errorf("expected error for an unknown flag key")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Defaults()
+	cfg.SymSpell.MaxEditDistance = 5
+
+	err := cfg.Validate()
+	problems, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("Validate = %v, want ValidationError", err)
+	}
+	want := []string{"DB_HOST is required", "DB_USER is required", "DB_NAME is required", "SYMSPELL_MAX_EDIT_DISTANCE"}
+	for _, w := range want {
+		if !strings.Contains(problems.Error(), w) {
+			t.Errorf("validation errors %q missing %q", problems.Error(), w)
+		}
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	cfg := Defaults()
+	cfg.Database = Database{Host: "h", Port: 5432, User: "u", Password: "p w'd", Name: "n", SSLMode: "disable"}
+	cfg.Qdrant.APIKey = "qdrant-key"
+
+	var out strings.Builder
+	cfg.Write(&out)
+	for _, secret := range []string{"p w'd", "qdrant-key"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("Write leaked %q", secret)
+		}
+	}
+	if strings.Contains(cfg.Database.String(), "p w") {
+		t.Errorf("Database.String leaked the password")
+	}
+	if dsn := cfg.Database.DSN(); !strings.Contains(dsn, `password='p w\'d'`) {
+		t.Errorf("DSN = %s, want quoted password", dsn)
+	}
+}
+
+func TestHash(t *testing.T) {
+	base := Defaults()
+
+	other := Defaults()
+	other.Database.Password = "changed"
+	other.Web.Port = 9000
+	if other.Hash() != base.Hash() {
+		t.Error("connection settings changed the config hash")
+	}
+
+	other.Matching.BatchSize = 500
+	if other.Hash() == base.Hash() {
+		t.Error("MATCH_BATCH_SIZE did not change the config hash")
+	}
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type actorKey struct{}
+
+// WithActor names the person a request acts for. Transactions begun with BeginTx pass it
+// to the audit chain, which records it alongside the database user.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the person named by WithActor, or ""
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// BeginTx begins a transaction and, when the context names an actor, sets ehdc.actor for
+// it so audit chain entries written in the transaction name them
+func BeginTx(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if actor := Actor(ctx); actor != "" {
+		if _, err := tx.ExecContext(ctx, "SELECT set_config('ehdc.actor', $1, true)", actor); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to set audit actor: %w", err)
+		}
+	}
+	return tx, nil
+}
@@ -1,11 +1,15 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	_ "github.com/lib/pq"
+
+	"github.com/ehdc-llpg/internal/config"
 )
 
 // Connection holds the database connection
@@ -13,46 +17,58 @@ type Connection struct {
 	DB *sql.DB
 }
 
-// NewConnection creates a new database connection
+// NewConnection creates a new database connection from the shared configuration
 func NewConnection() (*Connection, error) {
-	host := getEnvOrDefault("PGHOST", "")
-	port := getEnvOrDefault("PGPORT", "")
-	user := getEnvOrDefault("PGUSER", "")
-	password := getEnvOrDefault("PGPASSWORD", "")
-	dbname := getEnvOrDefault("PGDATABASE", "")
-
-	if host == "" || port == "" || user == "" || password == "" || dbname == "" {
-		return nil, fmt.Errorf("missing required database environment variables: PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE")
+	db, err := Open(config.Current().Database)
+	if err != nil {
+		return nil, err
+	}
+	return &Connection{DB: db}, nil
+}
+
+// Open validates the database settings, connects and applies the pool size
+func Open(cfg config.Database) (*sql.DB, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+	if cfg.Application == "" {
+		cfg.Application = ClientName()
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database %s: %w", cfg, err)
 	}
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(20)
-	db.SetMaxIdleConns(10)
+	db.SetMaxOpenConns(cfg.MaxConnections)
+	db.SetMaxIdleConns(cfg.MaxConnections / 2)
 
-	return &Connection{DB: db}, nil
+	return db, nil
+}
+
+// ClientName names this process for application_name: program/host/pid. The audit chain
+// uses it to attribute a process's writes to the match run it is working on.
+func ClientName() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%s/%d", filepath.Base(os.Args[0]), host, os.Getpid())
+}
+
+// WithTimeout derives a context for one query, bounded by DB_QUERY_TIMEOUT
+func WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := config.Current().Database.QueryTimeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
 }
 
 // Close closes the database connection
 func (c *Connection) Close() error {
 	return c.DB.Close()
 }
-
-// getEnvOrDefault returns environment variable or default value
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
\ No newline at end of file
@@ -3,8 +3,6 @@ package engine
 import (
 	"database/sql"
 	"fmt"
-	"io"
-	"os"
 )
 
 // DBUtil provides database utility functions
@@ -17,45 +15,9 @@ func NewDBUtil(db *sql.DB) *DBUtil {
 	return &DBUtil{db: db}
 }
 
-// ExecuteSQLFiles executes multiple SQL files
-func (util *DBUtil) ExecuteSQLFiles(filenames ...string) error {
-	for _, filename := range filenames {
-		if err := util.ExecuteSQLFile(filename); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// ExecuteSQLFile executes SQL commands from a file
-func (util *DBUtil) ExecuteSQLFile(filename string) error {
-	fmt.Printf("Executing SQL file: %s\n", filename)
-	
-	// Read the SQL file
-	file, err := os.Open(filename)
-	if err != nil {
-		return fmt.Errorf("failed to open SQL file: %w", err)
-	}
-	defer file.Close()
-	
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return fmt.Errorf("failed to read SQL file: %w", err)
-	}
-	
-	// Execute the SQL
-	_, err = util.db.Exec(string(content))
-	if err != nil {
-		return fmt.Errorf("failed to execute SQL: %w", err)
-	}
-	
-	fmt.Printf("✓ Successfully executed SQL file: %s\n", filename)
-	return nil
-}
-
 // TestViews tests that all enhanced views are working correctly
 func (util *DBUtil) TestViews() error {
-	fmt.Println("=== Testing Enhanced Views ===\n")
+	fmt.Print("=== Testing Enhanced Views ===\n\n")
 	
 	views := map[string]string{
 		"v_enhanced_source_documents": "Main enhanced source documents view",
@@ -84,7 +46,7 @@ func (util *DBUtil) TestViews() error {
 
 // ShowViewSamples shows sample data from key views
 func (util *DBUtil) ShowViewSamples() error {
-	fmt.Println("\n=== Sample Data from Enhanced Views ===\n")
+	fmt.Print("\n=== Sample Data from Enhanced Views ===\n\n")
 	
 	// Show main enhanced view sample
 	fmt.Println("Sample from v_enhanced_source_documents:")
@@ -1,9 +1,14 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/match"
 )
 
 // DeterministicMatcher handles Stage 1 deterministic matching
@@ -29,7 +34,7 @@ type AddressCandidate struct {
 }
 
 // RunDeterministicMatching performs Stage 1 matching for all unmatched documents
-func (dm *DeterministicMatcher) RunDeterministicMatching(runID int64, batchSize int) (int, int, error) {
+func (dm *DeterministicMatcher) RunDeterministicMatching(ctx context.Context, runID int64, batchSize int) (int, int, error) {
 	totalProcessed := 0
 	totalAccepted := 0
 	
@@ -37,8 +42,8 @@ func (dm *DeterministicMatcher) RunDeterministicMatching(runID int64, batchSize
 	
 	for {
 		// Get batch of unmatched documents
-		engine := &MatchEngine{db: dm.db}
-		docs, err := engine.GetUnmatchedDocuments(batchSize, "")
+		engine := NewMatchEngine(dm.db)
+		docs, err := engine.GetUnmatchedDocuments(ctx, batchSize, "")
 		if err != nil {
 			return totalProcessed, totalAccepted, fmt.Errorf("failed to get unmatched documents: %w", err)
 		}
@@ -50,12 +55,22 @@ func (dm *DeterministicMatcher) RunDeterministicMatching(runID int64, batchSize
 		batchAccepted := 0
 		
 		for _, doc := range docs {
+			// Stop between documents, so an interrupted run never leaves one half-recorded
+			if err := ctx.Err(); err != nil {
+				return totalProcessed, totalAccepted + batchAccepted, err
+			}
+
 			accepted := false
+
+			rejections, err := engine.Rejections(ctx, doc)
+			if err != nil {
+				return totalProcessed, totalAccepted + batchAccepted, err
+			}
 			
 			// Try legacy UPRN validation first
 			if doc.UPRNRaw != nil && strings.TrimSpace(*doc.UPRNRaw) != "" {
-				if candidate, found := dm.ValidateLegacyUPRN(strings.TrimSpace(*doc.UPRNRaw)); found {
-					err := dm.acceptMatch(engine, runID, doc.SrcID, candidate, "valid_uprn", 1.0, 1.0, "Legacy UPRN validated against LLPG")
+				if candidate, found := dm.ValidateLegacyUPRN(ctx, strings.TrimSpace(*doc.UPRNRaw), doc.DocDate); found && !rejections.Rejected(candidate.UPRN) {
+					err := dm.acceptMatch(ctx, engine, runID, doc.SrcID, candidate, "valid_uprn", 1.0, 1.0, "Legacy UPRN validated against LLPG")
 					if err == nil {
 						accepted = true
 						batchAccepted++
@@ -65,11 +80,11 @@ func (dm *DeterministicMatcher) RunDeterministicMatching(runID int64, batchSize
 			
 			// If not matched by legacy UPRN, try exact canonical match
 			if !accepted && doc.AddrCan != nil && strings.TrimSpace(*doc.AddrCan) != "" {
-				candidates := dm.FindExactCanonicalMatches(strings.TrimSpace(*doc.AddrCan))
+				candidates := withoutRejected(rejections, dm.FindExactCanonicalMatches(ctx, strings.TrimSpace(*doc.AddrCan), doc.DocDate))
 				
 				if len(candidates) == 1 {
 					// Single exact match - auto accept
-					err := dm.acceptMatch(engine, runID, doc.SrcID, candidates[0], "addr_exact", 0.99, 0.99, "Exact canonical address match")
+					err := dm.acceptMatch(ctx, engine, runID, doc.SrcID, candidates[0], "addr_exact", 0.99, 0.99, "Exact canonical address match")
 					if err == nil {
 						accepted = true
 						batchAccepted++
@@ -96,7 +111,7 @@ func (dm *DeterministicMatcher) RunDeterministicMatching(runID int64, batchSize
 							Notes:     fmt.Sprintf("Multiple exact matches found (%d candidates)", len(candidates)),
 						}
 						
-						engine.SaveMatchResult(result)
+						engine.SaveMatchResult(ctx, result)
 					}
 				}
 			}
@@ -115,18 +130,34 @@ func (dm *DeterministicMatcher) RunDeterministicMatching(runID int64, batchSize
 	return totalProcessed, totalAccepted, nil
 }
 
-// ValidateLegacyUPRN checks if a legacy UPRN exists in the LLPG
-func (dm *DeterministicMatcher) ValidateLegacyUPRN(uprn string) (*AddressCandidate, bool) {
+// withoutRejected drops the candidates whose UPRN a reviewer has rejected, keeping the order
+func withoutRejected(rejections match.Rejections, candidates []*AddressCandidate) []*AddressCandidate {
+	var kept []*AddressCandidate
+	for _, c := range candidates {
+		if !rejections.Rejected(c.UPRN) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// ValidateLegacyUPRN checks if a legacy UPRN exists in the LLPG and could have existed on the
+// document date (nil when unknown)
+func (dm *DeterministicMatcher) ValidateLegacyUPRN(ctx context.Context, uprn string, docDate *time.Time) (*AddressCandidate, bool) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var candidate AddressCandidate
 
-	err := dm.db.QueryRow(`
+	err := dm.db.QueryRowContext(ctx, `
 		SELECT a.uprn, a.full_address, a.address_canonical,
 		       COALESCE(l.easting, 0), COALESCE(l.northing, 0),
 		       a.usrn, a.blpu_class, a.status_code
 		FROM dim_address a
 		LEFT JOIN dim_location l ON a.location_id = l.location_id
 		WHERE a.uprn = $1
-	`, uprn).Scan(&candidate.UPRN, &candidate.LocAddress, &candidate.AddrCan,
+		  AND `+match.TemporalPredicate("a", "$2")+`
+	`, uprn, docDate).Scan(&candidate.UPRN, &candidate.LocAddress, &candidate.AddrCan,
 		&candidate.Easting, &candidate.Northing, &candidate.USRN,
 		&candidate.BLPUClass, &candidate.Status)
 
@@ -142,17 +173,22 @@ func (dm *DeterministicMatcher) ValidateLegacyUPRN(uprn string) (*AddressCandida
 	return &candidate, true
 }
 
-// FindExactCanonicalMatches finds addresses with exactly matching canonical form
-func (dm *DeterministicMatcher) FindExactCanonicalMatches(addrCan string) []*AddressCandidate {
-	rows, err := dm.db.Query(`
+// FindExactCanonicalMatches finds addresses with exactly matching canonical form that could
+// have existed on the document date (nil when unknown)
+func (dm *DeterministicMatcher) FindExactCanonicalMatches(ctx context.Context, addrCan string, docDate *time.Time) []*AddressCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := dm.db.QueryContext(ctx, `
 		SELECT a.uprn, a.full_address, a.address_canonical,
 		       COALESCE(l.easting, 0), COALESCE(l.northing, 0),
 		       a.usrn, a.blpu_class, a.status_code
 		FROM dim_address a
 		LEFT JOIN dim_location l ON a.location_id = l.location_id
 		WHERE a.address_canonical = $1
+		  AND `+match.TemporalPredicate("a", "$2")+`
 		ORDER BY a.uprn
-	`, addrCan)
+	`, addrCan, docDate)
 
 	if err != nil {
 		fmt.Printf("Error finding exact canonical matches for '%s': %v\n", addrCan, err)
@@ -178,7 +214,7 @@ func (dm *DeterministicMatcher) FindExactCanonicalMatches(addrCan string) []*Add
 }
 
 // acceptMatch is a helper to accept a match and save the result
-func (dm *DeterministicMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, candidate *AddressCandidate, method string, score, confidence float64, notes string) error {
+func (dm *DeterministicMatcher) acceptMatch(ctx context.Context, engine *MatchEngine, runID, srcID int64, candidate *AddressCandidate, method string, score, confidence float64, notes string) error {
 	// Save match result
 	result := &MatchResult{
 		RunID:         runID,
@@ -203,12 +239,12 @@ func (dm *DeterministicMatcher) acceptMatch(engine *MatchEngine, runID, srcID in
 		Notes:     notes,
 	}
 	
-	if err := engine.SaveMatchResult(result); err != nil {
+	if err := engine.SaveMatchResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to save match result: %w", err)
 	}
 	
 	// Accept the match
-	if err := engine.AcceptMatch(srcID, candidate.UPRN, method, score, confidence, runID, "system"); err != nil {
+	if err := engine.AcceptMatch(ctx, srcID, candidate.UPRN, method, score, confidence, runID, "system"); err != nil {
 		return fmt.Errorf("failed to accept match: %w", err)
 	}
 	
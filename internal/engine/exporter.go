@@ -58,7 +58,7 @@ type EnhancedSourceDocument struct {
 
 // ExportEnhancedCSVs exports all source types as enhanced CSVs with matching results
 func (e *Exporter) ExportEnhancedCSVs(outputDir string) error {
-	fmt.Println("=== Exporting Enhanced Source Document CSVs ===\n")
+	fmt.Print("=== Exporting Enhanced Source Document CSVs ===\n\n")
 	
 	// Ensure output directory exists
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -94,6 +94,7 @@ func (e *Exporter) getSourceTypes() ([]string, error) {
 	rows, err := e.db.Query(`
 		SELECT DISTINCT source_type 
 		FROM src_document 
+		WHERE removed_at IS NULL
 		ORDER BY source_type
 	`)
 	if err != nil {
@@ -176,6 +177,7 @@ func (e *Exporter) getEnhancedDocuments(sourceType string) ([]*EnhancedSourceDoc
 		LEFT JOIN dim_address d ON d.uprn = m.uprn
 		LEFT JOIN dim_location l ON d.location_id = l.location_id
 		WHERE s.source_type = $1
+		  AND s.removed_at IS NULL
 		ORDER BY s.src_id
 	`
 	
@@ -450,6 +452,7 @@ func (e *Exporter) GetExportStats() (map[string]interface{}, error) {
 			COUNT(CASE WHEN m.uprn IS NULL THEN 1 END) as unmatched
 		FROM src_document s
 		LEFT JOIN match_accepted m ON m.src_id = s.src_id
+		WHERE s.removed_at IS NULL
 		GROUP BY source_type
 		ORDER BY source_type
 	`)
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"math"
@@ -8,17 +9,25 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/ehdc-llpg/internal/match"
 	"github.com/ehdc-llpg/internal/normalize"
+	"github.com/ehdc-llpg/internal/store"
 )
 
-// FuzzyMatcher handles Stage 2 fuzzy matching using PostgreSQL pg_trgm
+// FuzzyMatcher handles Stage 2 fuzzy matching using trigram similarity (pg_trgm in Postgres)
 type FuzzyMatcher struct {
-	db *sql.DB
+	addresses store.AddressStore
+	engine    *MatchEngine
 }
 
 // NewFuzzyMatcher creates a new fuzzy matcher
 func NewFuzzyMatcher(db *sql.DB) *FuzzyMatcher {
-	return &FuzzyMatcher{db: db}
+	return NewFuzzyMatcherWithStores(store.NewPostgresStores(db))
+}
+
+// NewFuzzyMatcherWithStores creates a fuzzy matcher over the given stores
+func NewFuzzyMatcherWithStores(stores store.Stores) *FuzzyMatcher {
+	return &FuzzyMatcher{addresses: stores.Addresses, engine: NewMatchEngineWithStores(stores)}
 }
 
 // FuzzyCandidate represents a fuzzy match candidate with features
@@ -59,7 +68,7 @@ func DefaultTiers() *FuzzyMatchingTiers {
 }
 
 // RunFuzzyMatching performs Stage 2 fuzzy matching for unmatched documents
-func (fm *FuzzyMatcher) RunFuzzyMatching(runID int64, batchSize int, tiers *FuzzyMatchingTiers) (int, int, int, error) {
+func (fm *FuzzyMatcher) RunFuzzyMatching(ctx context.Context, runID int64, batchSize int, tiers *FuzzyMatchingTiers) (int, int, int, error) {
 	if tiers == nil {
 		tiers = DefaultTiers()
 	}
@@ -70,10 +79,11 @@ func (fm *FuzzyMatcher) RunFuzzyMatching(runID int64, batchSize int, tiers *Fuzz
 
 	fmt.Println("Starting fuzzy matching (Stage 2) with pg_trgm similarity...")
 
+	engine := fm.engine
+	var lastSrcID int64
 	for {
-		// Get batch of unmatched documents  
-		engine := &MatchEngine{db: fm.db}
-		docs, err := engine.GetUnmatchedDocuments(batchSize, "")
+		// Get batch of unmatched documents
+		docs, err := engine.GetUnmatchedDocumentsAfter(ctx, lastSrcID, batchSize, "")
 		if err != nil {
 			return totalProcessed, totalAccepted, totalNeedsReview, fmt.Errorf("failed to get unmatched documents: %w", err)
 		}
@@ -81,24 +91,36 @@ func (fm *FuzzyMatcher) RunFuzzyMatching(runID int64, batchSize int, tiers *Fuzz
 		if len(docs) == 0 {
 			break
 		}
+		lastSrcID = docs[len(docs)-1].SrcID
 
 		batchAccepted := 0
 		batchReview := 0
 
 		for _, doc := range docs {
+			// Stop between documents, so an interrupted run never leaves one half-recorded
+			if err := ctx.Err(); err != nil {
+				return totalProcessed, totalAccepted + batchAccepted, totalNeedsReview + batchReview, err
+			}
+
 			if doc.AddrCan == nil || strings.TrimSpace(*doc.AddrCan) == "" {
 				totalProcessed++
 				continue // Skip documents without canonical addresses
 			}
 
 			// Find fuzzy candidates
-			candidates, err := fm.FindFuzzyCandidates(doc, tiers.MinThreshold)
+			candidates, err := fm.FindFuzzyCandidates(ctx, doc, tiers.MinThreshold)
 			if err != nil {
 				fmt.Printf("Error finding fuzzy candidates for doc %d: %v\n", doc.SrcID, err)
 				totalProcessed++
 				continue
 			}
 
+			rejections, err := engine.Rejections(ctx, doc)
+			if err != nil {
+				return totalProcessed, totalAccepted + batchAccepted, totalNeedsReview + batchReview, err
+			}
+			candidates = withoutRejectedFuzzy(rejections, candidates)
+
 			if len(candidates) == 0 {
 				totalProcessed++
 				continue // No candidates found
@@ -111,7 +133,7 @@ func (fm *FuzzyMatcher) RunFuzzyMatching(runID int64, batchSize int, tiers *Fuzz
 			case "auto_accepted":
 				// Accept the best candidate
 				best := candidates[0]
-				err := fm.acceptFuzzyMatch(engine, runID, doc.SrcID, best, "fuzzy_auto", best.FinalScore, best.TrgramScore)
+				err := fm.acceptFuzzyMatch(ctx, engine, runID, doc.SrcID, best, "fuzzy_auto", best.FinalScore, best.TrgramScore)
 				if err == nil {
 					batchAccepted++
 				}
@@ -138,7 +160,7 @@ func (fm *FuzzyMatcher) RunFuzzyMatching(runID int64, batchSize int, tiers *Fuzz
 						Notes:         fmt.Sprintf("Fuzzy match requiring review (similarity=%.3f)", candidate.TrgramScore),
 					}
 
-					engine.SaveMatchResult(result)
+					engine.SaveMatchResult(ctx, result)
 				}
 				batchReview++
 
@@ -163,8 +185,20 @@ func (fm *FuzzyMatcher) RunFuzzyMatching(runID int64, batchSize int, tiers *Fuzz
 	return totalProcessed, totalAccepted, totalNeedsReview, nil
 }
 
+// withoutRejectedFuzzy drops the candidates whose UPRN a reviewer has rejected, keeping the
+// order; the result is a new slice, so cached candidates are left alone
+func withoutRejectedFuzzy(rejections match.Rejections, candidates []*FuzzyCandidate) []*FuzzyCandidate {
+	var kept []*FuzzyCandidate
+	for _, c := range candidates {
+		if !rejections.Rejected(c.UPRN) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
 // FindFuzzyCandidates finds fuzzy candidates using pg_trgm similarity
-func (fm *FuzzyMatcher) FindFuzzyCandidates(doc SourceDocument, minSimilarity float64) ([]*FuzzyCandidate, error) {
+func (fm *FuzzyMatcher) FindFuzzyCandidates(ctx context.Context, doc SourceDocument, minSimilarity float64) ([]*FuzzyCandidate, error) {
 	if doc.AddrCan == nil {
 		return nil, nil
 	}
@@ -174,40 +208,28 @@ func (fm *FuzzyMatcher) FindFuzzyCandidates(doc SourceDocument, minSimilarity fl
 		return nil, nil
 	}
 
-	// Query for trigram similarity matches
-	rows, err := fm.db.Query(`
-		SELECT d.uprn, d.full_address, d.address_canonical,
-		       COALESCE(l.easting, 0), COALESCE(l.northing, 0),
-		       d.usrn, d.blpu_class, d.status_code,
-		       similarity($1, d.address_canonical) as trgm_score
-		FROM dim_address d
-		LEFT JOIN dim_location l ON d.location_id = l.location_id
-		WHERE d.address_canonical % $1
-		  AND similarity($1, d.address_canonical) >= $2
-		ORDER BY trgm_score DESC
-		LIMIT 50
-	`, addrCan, minSimilarity)
-
+	similar, err := fm.addresses.SimilarAddresses(ctx, addrCan, minSimilarity, 50)
 	if err != nil {
-		return nil, fmt.Errorf("trigram query failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
 	var candidates []*FuzzyCandidate
 
-	for rows.Next() {
+	for _, a := range similar {
 		candidate := &FuzzyCandidate{
-			AddressCandidate: &AddressCandidate{},
-			Features:         make(map[string]interface{}),
+			AddressCandidate: &AddressCandidate{
+				UPRN:       a.UPRN,
+				LocAddress: a.FullAddress,
+				AddrCan:    a.Canonical,
+				USRN:       a.USRN,
+				BLPUClass:  a.BLPUClass,
+				Status:     a.Status,
+			},
+			TrgramScore: a.Similarity,
+			Features:    make(map[string]interface{}),
 		}
-
-		err := rows.Scan(
-			&candidate.UPRN, &candidate.LocAddress, &candidate.AddrCan,
-			&candidate.Easting, &candidate.Northing, &candidate.USRN,
-			&candidate.BLPUClass, &candidate.Status, &candidate.TrgramScore,
-		)
-		if err != nil {
-			continue
+		if a.Easting != nil && a.Northing != nil {
+			candidate.Easting, candidate.Northing = *a.Easting, *a.Northing
 		}
 
 		// Compute additional features
@@ -252,8 +274,8 @@ func (fm *FuzzyMatcher) computeFeatures(doc SourceDocument, candidate *FuzzyCand
 	candidate.PhoneticHits = normalize.PhoneticTokenOverlap(srcAddr, candidate.AddrCan)
 
 	// Spatial distance if available
-	if doc.EastingRaw != nil && doc.NorthingRaw != nil {
-		candidate.SpatialDistance = distance(*doc.EastingRaw, *doc.NorthingRaw, 
+	if doc.Easting != nil && doc.Northing != nil {
+		candidate.SpatialDistance = distance(*doc.Easting, *doc.Northing, 
 			candidate.Easting, candidate.Northing)
 		candidate.SpatialBoost = math.Exp(-candidate.SpatialDistance / 300.0)
 	} else {
@@ -389,7 +411,7 @@ func (fm *FuzzyMatcher) makeDecision(candidates []*FuzzyCandidate, tiers *FuzzyM
 }
 
 // acceptFuzzyMatch accepts a fuzzy match and records it
-func (fm *FuzzyMatcher) acceptFuzzyMatch(engine *MatchEngine, runID, srcID int64, candidate *FuzzyCandidate, method string, score, confidence float64) error {
+func (fm *FuzzyMatcher) acceptFuzzyMatch(ctx context.Context, engine *MatchEngine, runID, srcID int64, candidate *FuzzyCandidate, method string, score, confidence float64) error {
 	// Save match result
 	result := &MatchResult{
 		RunID:         runID,
@@ -406,12 +428,12 @@ func (fm *FuzzyMatcher) acceptFuzzyMatch(engine *MatchEngine, runID, srcID int64
 		Notes:         fmt.Sprintf("Fuzzy match auto-accepted (trgm=%.3f, final=%.3f)", candidate.TrgramScore, candidate.FinalScore),
 	}
 
-	if err := engine.SaveMatchResult(result); err != nil {
+	if err := engine.SaveMatchResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to save match result: %w", err)
 	}
 
 	// Accept the match
-	if err := engine.AcceptMatch(srcID, candidate.UPRN, method, score, confidence, runID, "system"); err != nil {
+	if err := engine.AcceptMatch(ctx, srcID, candidate.UPRN, method, score, confidence, runID, "system"); err != nil {
 		return fmt.Errorf("failed to accept match: %w", err)
 	}
 
@@ -1,10 +1,13 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
 	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
 )
 
 // OptimizedFuzzyMatcher provides optimized fuzzy matching with parallel processing
@@ -30,7 +33,7 @@ func NewOptimizedFuzzyMatcher(db *sql.DB, workerCount int) *OptimizedFuzzyMatche
 }
 
 // RunOptimizedFuzzyMatching performs optimized fuzzy matching with parallel processing
-func (ofm *OptimizedFuzzyMatcher) RunOptimizedFuzzyMatching(runID int64, batchSize int, tiers *FuzzyMatchingTiers) (int, int, int, error) {
+func (ofm *OptimizedFuzzyMatcher) RunOptimizedFuzzyMatching(ctx context.Context, runID int64, batchSize int, tiers *FuzzyMatchingTiers) (int, int, int, error) {
 	if tiers == nil {
 		tiers = DefaultTiers()
 	}
@@ -52,7 +55,7 @@ func (ofm *OptimizedFuzzyMatcher) RunOptimizedFuzzyMatching(runID int64, batchSi
 	// Start workers
 	for i := 0; i < ofm.workerCount; i++ {
 		wg.Add(1)
-		go ofm.worker(i, runID, tiers, docChan, resultChan, &wg)
+		go ofm.worker(ctx, i, runID, tiers, docChan, resultChan, &wg)
 	}
 
 	// Result collector goroutine
@@ -77,9 +80,9 @@ func (ofm *OptimizedFuzzyMatcher) RunOptimizedFuzzyMatching(runID int64, batchSi
 	}()
 
 	// Feed documents to workers
-	engine := &MatchEngine{db: ofm.db}
+	engine := NewMatchEngine(ofm.db)
 	for {
-		docs, err := engine.GetUnmatchedDocuments(batchSize, "")
+		docs, err := engine.GetUnmatchedDocuments(ctx, batchSize, "")
 		if err != nil {
 			close(docChan)
 			wg.Wait()
@@ -92,8 +95,17 @@ func (ofm *OptimizedFuzzyMatcher) RunOptimizedFuzzyMatching(runID int64, batchSi
 			break
 		}
 
-		for _, doc := range docs {
-			docChan <- &doc
+		for i := range docs {
+			select {
+			case docChan <- &docs[i]:
+			case <-ctx.Done():
+				// Workers finish the document they hold; report what completed
+				close(docChan)
+				wg.Wait()
+				close(resultChan)
+				<-doneChan
+				return totalProcessed, totalAccepted, totalNeedsReview, ctx.Err()
+			}
 		}
 	}
 
@@ -112,14 +124,17 @@ func (ofm *OptimizedFuzzyMatcher) RunOptimizedFuzzyMatching(runID int64, batchSi
 }
 
 // worker processes documents in parallel
-func (ofm *OptimizedFuzzyMatcher) worker(id int, runID int64, tiers *FuzzyMatchingTiers,
+func (ofm *OptimizedFuzzyMatcher) worker(ctx context.Context, id int, runID int64, tiers *FuzzyMatchingTiers,
 	docChan <-chan *SourceDocument, resultChan chan<- *matchResult, wg *sync.WaitGroup) {
 	
 	defer wg.Done()
-	fm := &FuzzyMatcher{db: ofm.db}
-	engine := &MatchEngine{db: ofm.db}
+	fm := NewFuzzyMatcher(ofm.db)
+	engine := NewMatchEngine(ofm.db)
 
 	for doc := range docChan {
+		if ctx.Err() != nil {
+			return // leave queued documents for the next run
+		}
 		result := &matchResult{srcID: doc.SrcID}
 
 		if doc.AddrCan == nil || *doc.AddrCan == "" || *doc.AddrCan == "N A" {
@@ -137,7 +152,7 @@ func (ofm *OptimizedFuzzyMatcher) worker(id int, runID int64, tiers *FuzzyMatchi
 
 		if candidates == nil {
 			// Find fuzzy candidates with optimized query
-			candidates, err = ofm.findOptimizedCandidates(*doc, tiers.MinThreshold)
+			candidates, err = ofm.findOptimizedCandidates(ctx, *doc, tiers.MinThreshold)
 			if err != nil {
 				fmt.Printf("Worker %d: Error finding candidates for doc %d: %v\n", id, doc.SrcID, err)
 				resultChan <- result
@@ -149,6 +164,14 @@ func (ofm *OptimizedFuzzyMatcher) worker(id int, runID int64, tiers *FuzzyMatchi
 			}
 		}
 
+		rejections, err := engine.Rejections(ctx, *doc)
+		if err != nil {
+			fmt.Printf("Worker %d: Error reading rejections for doc %d: %v\n", id, doc.SrcID, err)
+			resultChan <- result
+			continue
+		}
+		candidates = withoutRejectedFuzzy(rejections, candidates)
+
 		if len(candidates) == 0 {
 			resultChan <- result
 			continue
@@ -160,7 +183,7 @@ func (ofm *OptimizedFuzzyMatcher) worker(id int, runID int64, tiers *FuzzyMatchi
 		switch decision {
 		case "auto_accepted":
 			best := candidates[0]
-			err := fm.acceptFuzzyMatch(engine, runID, doc.SrcID, best, "fuzzy_auto_optimized", best.FinalScore, best.TrgramScore)
+			err := fm.acceptFuzzyMatch(ctx, engine, runID, doc.SrcID, best, "fuzzy_auto_optimized", best.FinalScore, best.TrgramScore)
 			if err == nil {
 				result.accepted = true
 			}
@@ -186,7 +209,7 @@ func (ofm *OptimizedFuzzyMatcher) worker(id int, runID int64, tiers *FuzzyMatchi
 					DecidedBy:     "system",
 					Notes:         fmt.Sprintf("Optimized fuzzy match requiring review (similarity=%.3f)", candidate.TrgramScore),
 				}
-				engine.SaveMatchResult(matchResult)
+				engine.SaveMatchResult(ctx, matchResult)
 			}
 			result.needsReview = true
 		}
@@ -196,7 +219,10 @@ func (ofm *OptimizedFuzzyMatcher) worker(id int, runID int64, tiers *FuzzyMatchi
 }
 
 // findOptimizedCandidates uses an optimized query for finding candidates
-func (ofm *OptimizedFuzzyMatcher) findOptimizedCandidates(doc SourceDocument, minSimilarity float64) ([]*FuzzyCandidate, error) {
+func (ofm *OptimizedFuzzyMatcher) findOptimizedCandidates(ctx context.Context, doc SourceDocument, minSimilarity float64) ([]*FuzzyCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if doc.AddrCan == nil || *doc.AddrCan == "" {
 		return nil, nil
 	}
@@ -204,7 +230,7 @@ func (ofm *OptimizedFuzzyMatcher) findOptimizedCandidates(doc SourceDocument, mi
 	addrCan := *doc.AddrCan
 
 	// Use prepared statement for better performance
-	rows, err := ofm.db.Query(`
+	rows, err := ofm.db.QueryContext(ctx, `
 		WITH candidates AS (
 			SELECT d.uprn, d.full_address, d.address_canonical,
 			       COALESCE(l.easting, 0), COALESCE(l.northing, 0),
@@ -226,7 +252,7 @@ func (ofm *OptimizedFuzzyMatcher) findOptimizedCandidates(doc SourceDocument, mi
 	defer rows.Close()
 
 	var candidates []*FuzzyCandidate
-	fm := &FuzzyMatcher{db: ofm.db}
+	fm := NewFuzzyMatcher(ofm.db)
 
 	for rows.Next() {
 		candidate := &FuzzyCandidate{
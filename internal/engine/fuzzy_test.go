@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+func loadFixtureStores(t *testing.T) *store.Memory {
+	t.Helper()
+	m, err := store.LoadFixtures("../store/testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	return m
+}
+
+func TestRunFuzzyMatching(t *testing.T) {
+	m := loadFixtureStores(t)
+	fm := NewFuzzyMatcherWithStores(m.Stores())
+
+	ctx := context.Background()
+	run, err := fm.engine.CreateMatchRun(ctx, "fuzzy-test", "test", "")
+	if err != nil {
+		t.Fatalf("CreateMatchRun: %v", err)
+	}
+
+	processed, accepted, _, err := fm.RunFuzzyMatching(ctx, run.RunID, 2, nil)
+	if err != nil {
+		t.Fatalf("RunFuzzyMatching: %v", err)
+	}
+	if processed != 4 {
+		t.Errorf("processed = %d, want 4 (every fixture document once)", processed)
+	}
+	if accepted == 0 {
+		t.Errorf("accepted = 0, want the exact canonical matches accepted")
+	}
+
+	want := map[int64]string{1: "100062000001", 2: "100062000003"}
+	for srcID, uprn := range want {
+		a, err := m.Accepted(ctx, srcID)
+		if err != nil || a == nil {
+			t.Errorf("src_id %d not accepted (%v)", srcID, err)
+			continue
+		}
+		if a.UPRN != uprn {
+			t.Errorf("src_id %d accepted %s, want %s", srcID, a.UPRN, uprn)
+		}
+	}
+	if a, _ := m.Accepted(ctx, 4); a != nil {
+		t.Errorf("src_id 4 (no address) accepted %s", a.UPRN)
+	}
+}
+
+func TestRunFuzzyMatchingStopsWhenCancelled(t *testing.T) {
+	m := loadFixtureStores(t)
+	fm := NewFuzzyMatcherWithStores(m.Stores())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	processed, accepted, _, err := fm.RunFuzzyMatching(ctx, 1, 2, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if processed != 0 || accepted != 0 {
+		t.Errorf("processed %d, accepted %d after cancel, want 0", processed, accepted)
+	}
+	if len(m.Results()) != 0 {
+		t.Errorf("%d results saved after cancel, want none", len(m.Results()))
+	}
+}
@@ -1,11 +1,13 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/normalize"
 )
 
@@ -39,7 +41,7 @@ type MatchLevel struct {
 }
 
 // RunHierarchicalMatching performs hierarchical component matching
-func (hm *HierarchicalMatcher) RunHierarchicalMatching(runID int64, batchSize int) (int, int, int, error) {
+func (hm *HierarchicalMatcher) RunHierarchicalMatching(ctx context.Context, runID int64, batchSize int) (int, int, int, error) {
 	startTime := time.Now()
 	totalProcessed := 0
 	totalAccepted := 0
@@ -106,11 +108,11 @@ func (hm *HierarchicalMatcher) RunHierarchicalMatching(runID int64, batchSize in
 		},
 	}
 
-	engine := &MatchEngine{db: hm.db}
+	engine := NewMatchEngine(hm.db)
 
 	for {
 		// Get unmatched documents
-		docs, err := hm.getUnmatchedForHierarchical(batchSize)
+		docs, err := hm.getUnmatchedForHierarchical(ctx, batchSize)
 		if err != nil {
 			return totalProcessed, totalAccepted, totalNeedsReview, fmt.Errorf("failed to get documents: %w", err)
 		}
@@ -120,6 +122,11 @@ func (hm *HierarchicalMatcher) RunHierarchicalMatching(runID int64, batchSize in
 		}
 
 		for _, doc := range docs {
+			// Stop between documents, so an interrupted run never leaves one half-recorded
+			if err := ctx.Err(); err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+
 			totalProcessed++
 
 			if doc.AddrCan == nil || *doc.AddrCan == "" || *doc.AddrCan == "N A" {
@@ -135,19 +142,29 @@ func (hm *HierarchicalMatcher) RunHierarchicalMatching(runID int64, batchSize in
 
 			components := normalize.ExtractAddressComponents(sourceAddr + " " + postcode)
 
-			// Try each matching level in hierarchy
+			rejections, err := engine.Rejections(ctx, doc)
+			if err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+
+			// Try each matching level in hierarchy, passing over rejected UPRNs
 			var bestCandidate *HierarchicalCandidate
 			var matchFound bool
 
 			for _, level := range matchLevels {
-				candidates, err := hm.findCandidatesAtLevel(doc, components, level)
+				candidates, err := hm.findCandidatesAtLevel(ctx, doc, components, level)
 				if err != nil {
 					continue
 				}
 
-				if len(candidates) > 0 {
-					bestCandidate = candidates[0]
-					matchFound = true
+				for _, c := range candidates {
+					if !rejections.Rejected(c.UPRN) {
+						bestCandidate = c
+						matchFound = true
+						break
+					}
+				}
+				if matchFound {
 					break // Found match at this level, don't try lower levels
 				}
 			}
@@ -159,13 +176,13 @@ func (hm *HierarchicalMatcher) RunHierarchicalMatching(runID int64, batchSize in
 			// Make decision based on match level and score
 			if bestCandidate.Score >= 0.90 {
 				// High confidence - auto accept
-				err = hm.acceptMatch(engine, runID, doc.SrcID, bestCandidate)
+				err = hm.acceptMatch(ctx, engine, runID, doc.SrcID, bestCandidate)
 				if err == nil {
 					totalAccepted++
 				}
 			} else if bestCandidate.Score >= 0.70 {
 				// Medium confidence - needs review
-				hm.saveForReview(engine, runID, doc.SrcID, bestCandidate, 1)
+				hm.saveForReview(ctx, engine, runID, doc.SrcID, bestCandidate, 1)
 				totalNeedsReview++
 			}
 			// Below 0.70 is rejected
@@ -185,13 +202,18 @@ func (hm *HierarchicalMatcher) RunHierarchicalMatching(runID int64, batchSize in
 }
 
 // getUnmatchedForHierarchical gets unmatched documents suitable for hierarchical matching
-func (hm *HierarchicalMatcher) getUnmatchedForHierarchical(limit int) ([]SourceDocument, error) {
-	rows, err := hm.db.Query(`
+func (hm *HierarchicalMatcher) getUnmatchedForHierarchical(ctx context.Context, limit int) ([]SourceDocument, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := hm.db.QueryContext(ctx, `
 		SELECT s.src_id, s.source_type, s.raw_address, s.addr_can, s.postcode_text,
-			   s.easting_raw, s.northing_raw, s.uprn_raw
+			   s.easting_raw, s.northing_raw, s.uprn_raw,
+			   s.easting_repaired, s.northing_repaired
 		FROM src_document s
 		LEFT JOIN match_accepted m ON m.src_id = s.src_id
 		WHERE m.src_id IS NULL
+		  AND s.removed_at IS NULL
 		  AND s.addr_can IS NOT NULL
 		  AND s.addr_can != 'N A'
 		  AND s.addr_can != ''
@@ -211,6 +233,7 @@ func (hm *HierarchicalMatcher) getUnmatchedForHierarchical(limit int) ([]SourceD
 		err := rows.Scan(
 			&doc.SrcID, &doc.SourceType, &doc.RawAddress, &doc.AddrCan,
 			&doc.PostcodeText, &doc.EastingRaw, &doc.NorthingRaw, &doc.UPRNRaw,
+			&doc.Easting, &doc.Northing,
 		)
 		if err != nil {
 			continue
@@ -222,7 +245,10 @@ func (hm *HierarchicalMatcher) getUnmatchedForHierarchical(limit int) ([]SourceD
 }
 
 // findCandidatesAtLevel finds candidates at a specific hierarchical level
-func (hm *HierarchicalMatcher) findCandidatesAtLevel(doc SourceDocument, components *normalize.AddressComponents, level MatchLevel) ([]*HierarchicalCandidate, error) {
+func (hm *HierarchicalMatcher) findCandidatesAtLevel(ctx context.Context, doc SourceDocument, components *normalize.AddressComponents, level MatchLevel) ([]*HierarchicalCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var candidates []*HierarchicalCandidate
 
 	// Prepare parameters based on match level
@@ -278,7 +304,7 @@ func (hm *HierarchicalMatcher) findCandidatesAtLevel(doc SourceDocument, compone
 	}
 
 	// Execute query
-	rows, err := hm.db.Query(level.Query, params...)
+	rows, err := hm.db.QueryContext(ctx, level.Query, params...)
 	if err != nil {
 		return candidates, fmt.Errorf("hierarchical query failed at level %s: %w", level.Name, err)
 	}
@@ -425,7 +451,7 @@ func (hm *HierarchicalMatcher) componentsToMap(components *normalize.AddressComp
 }
 
 // acceptMatch accepts a hierarchical match
-func (hm *HierarchicalMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, candidate *HierarchicalCandidate) error {
+func (hm *HierarchicalMatcher) acceptMatch(ctx context.Context, engine *MatchEngine, runID, srcID int64, candidate *HierarchicalCandidate) error {
 	result := &MatchResult{
 		RunID:         runID,
 		SrcID:         srcID,
@@ -442,16 +468,16 @@ func (hm *HierarchicalMatcher) acceptMatch(engine *MatchEngine, runID, srcID int
 			candidate.MatchLevel, candidate.Score),
 	}
 
-	if err := engine.SaveMatchResult(result); err != nil {
+	if err := engine.SaveMatchResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to save match result: %w", err)
 	}
 
-	return engine.AcceptMatch(srcID, candidate.UPRN, fmt.Sprintf("hierarchical_%s", candidate.MatchLevel),
+	return engine.AcceptMatch(ctx, srcID, candidate.UPRN, fmt.Sprintf("hierarchical_%s", candidate.MatchLevel),
 		candidate.Score, candidate.Score, runID, "system")
 }
 
 // saveForReview saves a hierarchical candidate for manual review
-func (hm *HierarchicalMatcher) saveForReview(engine *MatchEngine, runID, srcID int64,
+func (hm *HierarchicalMatcher) saveForReview(ctx context.Context, engine *MatchEngine, runID, srcID int64,
 	candidate *HierarchicalCandidate, rank int) error {
 
 	result := &MatchResult{
@@ -470,5 +496,5 @@ func (hm *HierarchicalMatcher) saveForReview(engine *MatchEngine, runID, srcID i
 			candidate.MatchLevel, candidate.Score),
 	}
 
-	return engine.SaveMatchResult(result)
+	return engine.SaveMatchResult(ctx, result)
 }
\ No newline at end of file
@@ -1,26 +1,39 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/ehdc-llpg/internal/normalize"
+	"github.com/ehdc-llpg/internal/store"
 )
 
 // PostcodeMatcher handles postcode-centric matching
 type PostcodeMatcher struct {
-	db *sql.DB
+	addresses store.AddressStore
+	documents store.DocumentStore
+	engine    *MatchEngine
 }
 
 // NewPostcodeMatcher creates a new postcode matcher
 func NewPostcodeMatcher(db *sql.DB) *PostcodeMatcher {
-	return &PostcodeMatcher{db: db}
+	return NewPostcodeMatcherWithStores(store.NewPostgresStores(db))
+}
+
+// NewPostcodeMatcherWithStores creates a postcode matcher over the given stores
+func NewPostcodeMatcherWithStores(stores store.Stores) *PostcodeMatcher {
+	return &PostcodeMatcher{
+		addresses: stores.Addresses,
+		documents: stores.Documents,
+		engine:    NewMatchEngineWithStores(stores),
+	}
 }
 
 // RunPostcodeMatching performs matching based on postcodes
-func (pm *PostcodeMatcher) RunPostcodeMatching(runID int64, batchSize int) (int, int, int, error) {
+func (pm *PostcodeMatcher) RunPostcodeMatching(ctx context.Context, runID int64, batchSize int) (int, int, int, error) {
 	startTime := time.Now()
 	totalProcessed := 0
 	totalAccepted := 0
@@ -28,11 +41,12 @@ func (pm *PostcodeMatcher) RunPostcodeMatching(runID int64, batchSize int) (int,
 
 	fmt.Println("Starting postcode-centric matching...")
 	
-	engine := &MatchEngine{db: pm.db}
+	engine := pm.engine
 
+	var lastSrcID int64
 	for {
 		// Get unmatched documents with postcodes
-		docs, err := pm.getUnmatchedWithPostcodes(batchSize)
+		docs, err := pm.documents.UnmatchedWithPostcode(ctx, lastSrcID, batchSize)
 		if err != nil {
 			return totalProcessed, totalAccepted, totalNeedsReview, fmt.Errorf("failed to get documents: %w", err)
 		}
@@ -40,17 +54,35 @@ func (pm *PostcodeMatcher) RunPostcodeMatching(runID int64, batchSize int) (int,
 		if len(docs) == 0 {
 			break
 		}
+		lastSrcID = docs[len(docs)-1].SrcID
 
 		for _, doc := range docs {
+			// Stop between documents, so an interrupted run never leaves one half-recorded
+			if err := ctx.Err(); err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+
 			totalProcessed++
 
 			// Find candidates in same postcode
-			candidates, err := pm.findPostcodeMatches(doc)
+			candidates, err := pm.findPostcodeMatches(ctx, doc)
 			if err != nil {
 				fmt.Printf("Error finding postcode matches for doc %d: %v\n", doc.SrcID, err)
 				continue
 			}
 
+			rejections, err := engine.Rejections(ctx, doc)
+			if err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+			var allowed []*PostcodeCandidate
+			for _, c := range candidates {
+				if !rejections.Rejected(c.UPRN) {
+					allowed = append(allowed, c)
+				}
+			}
+			candidates = allowed
+
 			if len(candidates) == 0 {
 				continue
 			}
@@ -60,7 +92,7 @@ func (pm *PostcodeMatcher) RunPostcodeMatching(runID int64, batchSize int) (int,
 			
 			if bestCandidate.Score >= 0.85 && len(candidates) == 1 {
 				// High confidence single match
-				err = pm.acceptMatch(engine, runID, doc.SrcID, bestCandidate)
+				err = pm.acceptMatch(ctx, engine, runID, doc.SrcID, bestCandidate)
 				if err == nil {
 					totalAccepted++
 				}
@@ -70,7 +102,7 @@ func (pm *PostcodeMatcher) RunPostcodeMatching(runID int64, batchSize int) (int,
 					if i >= 3 {
 						break
 					}
-					pm.saveForReview(engine, runID, doc.SrcID, candidate, i+1)
+					pm.saveForReview(ctx, engine, runID, doc.SrcID, candidate, i+1)
 				}
 				totalNeedsReview++
 			}
@@ -90,43 +122,6 @@ func (pm *PostcodeMatcher) RunPostcodeMatching(runID int64, batchSize int) (int,
 	return totalProcessed, totalAccepted, totalNeedsReview, nil
 }
 
-// getUnmatchedWithPostcodes gets documents that have postcodes but no matches
-func (pm *PostcodeMatcher) getUnmatchedWithPostcodes(limit int) ([]SourceDocument, error) {
-	rows, err := pm.db.Query(`
-		SELECT s.src_id, s.source_type, s.raw_address, s.addr_can, s.postcode_text,
-			   s.easting_raw, s.northing_raw, s.uprn_raw
-		FROM src_document s
-		LEFT JOIN match_accepted m ON m.src_id = s.src_id
-		WHERE m.src_id IS NULL
-		  AND s.postcode_text IS NOT NULL
-		  AND s.postcode_text != ''
-		  AND s.addr_can IS NOT NULL
-		  AND s.addr_can != 'N A'
-		ORDER BY s.src_id
-		LIMIT $1
-	`, limit)
-
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var docs []SourceDocument
-	for rows.Next() {
-		var doc SourceDocument
-		err := rows.Scan(
-			&doc.SrcID, &doc.SourceType, &doc.RawAddress, &doc.AddrCan,
-			&doc.PostcodeText, &doc.EastingRaw, &doc.NorthingRaw, &doc.UPRNRaw,
-		)
-		if err != nil {
-			continue
-		}
-		docs = append(docs, doc)
-	}
-
-	return docs, nil
-}
-
 // PostcodeCandidate represents a candidate found by postcode matching
 type PostcodeCandidate struct {
 	UPRN           string
@@ -139,8 +134,9 @@ type PostcodeCandidate struct {
 	Features       map[string]interface{}
 }
 
-// findPostcodeMatches finds LLPG addresses in the same postcode
-func (pm *PostcodeMatcher) findPostcodeMatches(doc SourceDocument) ([]*PostcodeCandidate, error) {
+// findPostcodeMatches finds LLPG addresses in the same postcode that could have existed on the
+// document date
+func (pm *PostcodeMatcher) findPostcodeMatches(ctx context.Context, doc SourceDocument) ([]*PostcodeCandidate, error) {
 	if doc.PostcodeText == nil || *doc.PostcodeText == "" {
 		return nil, nil
 	}
@@ -154,30 +150,24 @@ func (pm *PostcodeMatcher) findPostcodeMatches(doc SourceDocument) ([]*PostcodeC
 	// Extract components from source address
 	sourceComponents := normalize.ExtractAddressComponents(sourceAddr + " " + postcode)
 
-	// Query for addresses with same postcode
-	rows, err := pm.db.Query(`
-		SELECT d.uprn, d.full_address, d.address_canonical
-		FROM dim_address d
-		WHERE d.full_address LIKE $1
-		   OR d.full_address LIKE $2
-		ORDER BY d.uprn
-	`, "%"+postcode, "%"+strings.ReplaceAll(postcode, " ", ""))
-
+	// Addresses with the same postcode
+	addresses, err := pm.addresses.AddressesInPostcode(ctx, postcode)
 	if err != nil {
-		return nil, fmt.Errorf("postcode query failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
 	var candidates []*PostcodeCandidate
 	
-	for rows.Next() {
-		candidate := &PostcodeCandidate{
-			Features: make(map[string]interface{}),
+	for _, a := range addresses {
+		if !existedOn(doc, a.StartDate, a.EndDate) {
+			continue
 		}
 
-		err := rows.Scan(&candidate.UPRN, &candidate.Address, &candidate.CanonicalAddr)
-		if err != nil {
-			continue
+		candidate := &PostcodeCandidate{
+			UPRN:          a.UPRN,
+			Address:       a.FullAddress,
+			CanonicalAddr: a.Canonical,
+			Features:      make(map[string]interface{}),
 		}
 
 		// Extract components from candidate
@@ -198,11 +188,7 @@ func (pm *PostcodeMatcher) findPostcodeMatches(doc SourceDocument) ([]*PostcodeC
 		targetWithoutPostcode := strings.ReplaceAll(candidate.CanonicalAddr, postcode, "")
 		
 		// Use trigram similarity if available
-		var trigramScore float64
-		err = pm.db.QueryRow(`
-			SELECT similarity($1, $2)
-		`, sourceWithoutPostcode, targetWithoutPostcode).Scan(&trigramScore)
-		
+		trigramScore, err := pm.addresses.Similarity(ctx, sourceWithoutPostcode, targetWithoutPostcode)
 		if err == nil {
 			candidate.Score = trigramScore*0.6 + candidate.ComponentScore*0.4
 		} else {
@@ -249,7 +235,7 @@ func (pm *PostcodeMatcher) findPostcodeMatches(doc SourceDocument) ([]*PostcodeC
 }
 
 // acceptMatch accepts a postcode-based match
-func (pm *PostcodeMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, candidate *PostcodeCandidate) error {
+func (pm *PostcodeMatcher) acceptMatch(ctx context.Context, engine *MatchEngine, runID, srcID int64, candidate *PostcodeCandidate) error {
 	result := &MatchResult{
 		RunID:         runID,
 		SrcID:         srcID,
@@ -266,16 +252,16 @@ func (pm *PostcodeMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64,
 			candidate.Score, candidate.HouseNumMatch),
 	}
 
-	if err := engine.SaveMatchResult(result); err != nil {
+	if err := engine.SaveMatchResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to save match result: %w", err)
 	}
 
-	return engine.AcceptMatch(srcID, candidate.UPRN, "postcode_match",
+	return engine.AcceptMatch(ctx, srcID, candidate.UPRN, "postcode_match",
 		candidate.Score, candidate.Score, runID, "system")
 }
 
 // saveForReview saves a candidate for manual review
-func (pm *PostcodeMatcher) saveForReview(engine *MatchEngine, runID, srcID int64, 
+func (pm *PostcodeMatcher) saveForReview(ctx context.Context, engine *MatchEngine, runID, srcID int64, 
 	candidate *PostcodeCandidate, rank int) error {
 	
 	result := &MatchResult{
@@ -293,83 +279,36 @@ func (pm *PostcodeMatcher) saveForReview(engine *MatchEngine, runID, srcID int64
 		Notes:         fmt.Sprintf("Postcode match requiring review (score=%.3f)", candidate.Score),
 	}
 
-	return engine.SaveMatchResult(result)
+	return engine.SaveMatchResult(ctx, result)
 }
 
 // AnalyzePostcodeQuality analyzes the quality of postcode data
-func (pm *PostcodeMatcher) AnalyzePostcodeQuality() error {
-	fmt.Println("\n=== Postcode Data Quality Analysis ===\n")
-
-	// Documents with postcodes
-	var withPostcode, withoutPostcode, invalidPostcode int
-	err := pm.db.QueryRow(`
-		SELECT 
-			COUNT(CASE WHEN postcode_text IS NOT NULL AND postcode_text != '' THEN 1 END) as with_postcode,
-			COUNT(CASE WHEN postcode_text IS NULL OR postcode_text = '' THEN 1 END) as without_postcode,
-			COUNT(CASE WHEN postcode_text IS NOT NULL AND postcode_text != '' 
-				AND postcode_text !~ '^[A-Z]{1,2}[0-9]{1,2}[A-Z]?\s*[0-9][A-Z]{2}$' THEN 1 END) as invalid
-		FROM src_document
-	`).Scan(&withPostcode, &withoutPostcode, &invalidPostcode)
+func (pm *PostcodeMatcher) AnalyzePostcodeQuality(ctx context.Context) error {
+	fmt.Print("\n=== Postcode Data Quality Analysis ===\n\n")
 
+	quality, err := pm.documents.PostcodeQuality(ctx)
 	if err != nil {
 		return err
 	}
 
+	withPostcode := quality.WithPostcode
 	fmt.Printf("Documents with postcodes: %d\n", withPostcode)
-	fmt.Printf("Documents without postcodes: %d\n", withoutPostcode)
-	fmt.Printf("Documents with invalid postcodes: %d\n", invalidPostcode)
+	fmt.Printf("Documents without postcodes: %d\n", quality.WithoutPostcode)
+	fmt.Printf("Documents with invalid postcodes: %d\n", quality.Invalid)
 	fmt.Printf("Postcode coverage: %.2f%%\n", 
-		float64(withPostcode)/float64(withPostcode+withoutPostcode)*100)
+		float64(withPostcode)/float64(withPostcode+quality.WithoutPostcode)*100)
 
 	// Postcode distribution
 	fmt.Println("\n=== Top 10 Postcodes in Source Data ===")
-	rows, err := pm.db.Query(`
-		SELECT postcode_text, COUNT(*) as count
-		FROM src_document
-		WHERE postcode_text IS NOT NULL AND postcode_text != ''
-		GROUP BY postcode_text
-		ORDER BY count DESC
-		LIMIT 10
-	`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var postcode string
-		var count int
-		if err := rows.Scan(&postcode, &count); err == nil {
-			fmt.Printf("  %s: %d documents\n", postcode, count)
-		}
+	for _, pc := range quality.TopPostcodes {
+		fmt.Printf("  %s: %d documents\n", pc.Postcode, pc.Count)
 	}
 
 	// Postcode matching potential
 	fmt.Println("\n=== Postcode Matching Potential ===")
-	
-	var matchPotential int
-	err = pm.db.QueryRow(`
-		WITH postcode_pairs AS (
-			SELECT DISTINCT s.src_id, s.postcode_text
-			FROM src_document s
-			LEFT JOIN match_accepted m ON m.src_id = s.src_id
-			WHERE m.src_id IS NULL
-			  AND s.postcode_text IS NOT NULL
-			  AND s.postcode_text != ''
-		)
-		SELECT COUNT(DISTINCT pp.src_id)
-		FROM postcode_pairs pp
-		WHERE EXISTS (
-			SELECT 1 FROM dim_address d
-			WHERE d.full_address LIKE '%' || pp.postcode_text || '%'
-		)
-	`).Scan(&matchPotential)
-
-	if err == nil {
-		fmt.Printf("Documents with matching postcodes in LLPG: %d\n", matchPotential)
-		fmt.Printf("Potential coverage improvement: %.2f%%\n",
-			float64(matchPotential)/float64(withPostcode)*100)
-	}
+	fmt.Printf("Documents with matching postcodes in LLPG: %d\n", quality.MatchPotential)
+	fmt.Printf("Potential coverage improvement: %.2f%%\n",
+		float64(quality.MatchPotential)/float64(withPostcode)*100)
 
 	return nil
-}
\ No newline at end of file
+}
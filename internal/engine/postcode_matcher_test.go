@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+func TestRunPostcodeMatching(t *testing.T) {
+	m := loadFixtureStores(t)
+	pm := NewPostcodeMatcherWithStores(m.Stores())
+
+	ctx := context.Background()
+	processed, _, _, err := pm.RunPostcodeMatching(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("RunPostcodeMatching: %v", err)
+	}
+	if processed != 2 {
+		t.Errorf("processed = %d, want 2 (documents with a postcode)", processed)
+	}
+
+	// Only the two High Street addresses share GU34 1AB; number 12 must win
+	a, _ := m.Accepted(ctx, 1)
+	if a != nil && a.UPRN != "100062000001" {
+		t.Errorf("src_id 1 accepted %s, want 100062000001", a.UPRN)
+	}
+	for _, r := range m.Results() {
+		if r.SrcID == 1 && r.TieRank == 1 && r.CandidateUPRN != "100062000001" {
+			t.Errorf("src_id 1 top candidate = %s, want 100062000001", r.CandidateUPRN)
+		}
+	}
+}
+
+func TestRunPostcodeMatchingSkipsRejected(t *testing.T) {
+	m := loadFixtureStores(t)
+	m.AddRejection(store.Rejection{SrcID: 1, UPRN: "100062000001", Source: "review", RejectedBy: "alice"})
+	pm := NewPostcodeMatcherWithStores(m.Stores())
+
+	ctx := context.Background()
+	if _, _, _, err := pm.RunPostcodeMatching(ctx, 1, 10); err != nil {
+		t.Fatalf("RunPostcodeMatching: %v", err)
+	}
+
+	if a, _ := m.Accepted(ctx, 1); a != nil && a.UPRN == "100062000001" {
+		t.Errorf("src_id 1 accepted the rejected UPRN %s", a.UPRN)
+	}
+	for _, r := range m.Results() {
+		if r.SrcID == 1 && r.CandidateUPRN == "100062000001" {
+			t.Errorf("src_id 1 kept the rejected UPRN as a %s candidate", r.Decision)
+		}
+	}
+}
+
+func TestRunPostcodeMatchingSkipsAddressesGoneByDocDate(t *testing.T) {
+	demolished := time.Date(2001, 3, 1, 0, 0, 0, 0, time.UTC)
+	docDate := time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)
+	addrCan := "12 HIGH STREET ALTON"
+	postcode := "GU34 1AB"
+
+	m := store.NewMemory()
+	m.AddAddress(store.Address{AddressID: 1, UPRN: "100062000001", FullAddress: "12 HIGH STREET, ALTON, GU34 1AB",
+		Canonical: "12 HIGH STREET ALTON", EndDate: &demolished})
+	m.AddAddress(store.Address{AddressID: 2, UPRN: "100062000002", FullAddress: "14 HIGH STREET, ALTON, GU34 1AB",
+		Canonical: "14 HIGH STREET ALTON"})
+	m.AddDocument(store.SourceDocument{SrcID: 1, SourceType: "decision", RawAddress: "12 High St, Alton, GU34 1AB",
+		AddrCan: &addrCan, PostcodeText: &postcode, DocDate: &docDate})
+	pm := NewPostcodeMatcherWithStores(m.Stores())
+
+	ctx := context.Background()
+	if _, _, _, err := pm.RunPostcodeMatching(ctx, 1, 10); err != nil {
+		t.Fatalf("RunPostcodeMatching: %v", err)
+	}
+
+	if a, _ := m.Accepted(ctx, 1); a != nil && a.UPRN == "100062000001" {
+		t.Errorf("src_id 1 accepted %s, which ended before the document date", a.UPRN)
+	}
+	for _, r := range m.Results() {
+		if r.CandidateUPRN == "100062000001" {
+			t.Errorf("src_id 1 kept %s, which ended before the document date, as a %s candidate", r.CandidateUPRN, r.Decision)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
 )
 
 // ReviewInterface provides manual review capabilities
@@ -45,8 +48,8 @@ type ReviewCandidate struct {
 }
 
 // RunInteractiveReview starts an interactive review session
-func (ri *ReviewInterface) RunInteractiveReview(batchSize int, reviewer string) error {
-	fmt.Println("=== EHDC LLPG Interactive Review Interface ===\n")
+func (ri *ReviewInterface) RunInteractiveReview(ctx context.Context, batchSize int, reviewer string) error {
+	fmt.Print("=== EHDC LLPG Interactive Review Interface ===\n\n")
 	
 	if reviewer == "" {
 		reviewer = "system_user"
@@ -56,7 +59,7 @@ func (ri *ReviewInterface) RunInteractiveReview(batchSize int, reviewer string)
 	
 	for {
 		// Get next batch of items needing review
-		items, err := ri.getItemsNeedingReview(batchSize)
+		items, err := ri.getItemsNeedingReview(ctx, batchSize)
 		if err != nil {
 			return fmt.Errorf("failed to get review items: %w", err)
 		}
@@ -69,9 +72,13 @@ func (ri *ReviewInterface) RunInteractiveReview(batchSize int, reviewer string)
 		fmt.Printf("Found %d items requiring review. Starting batch...\n\n", len(items))
 
 		for i, item := range items {
+			if err := ctx.Err(); err != nil {
+				fmt.Printf("\nReview session interrupted. Total reviewed: %d\n", totalReviewed)
+				return err
+			}
 			fmt.Printf("=== Review Item %d of %d ===\n", i+1, len(items))
 			
-			decision, err := ri.reviewItem(item, reviewer)
+			decision, err := ri.reviewItem(ctx, item, reviewer)
 			if err != nil {
 				fmt.Printf("Error reviewing item: %v\n", err)
 				continue
@@ -101,13 +108,17 @@ func (ri *ReviewInterface) RunInteractiveReview(batchSize int, reviewer string)
 }
 
 // getItemsNeedingReview gets items that need manual review
-func (ri *ReviewInterface) getItemsNeedingReview(limit int) ([]*ReviewItem, error) {
+func (ri *ReviewInterface) getItemsNeedingReview(ctx context.Context, limit int) ([]*ReviewItem, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	// Get distinct source documents that have candidates needing review
-	rows, err := ri.db.Query(`
+	rows, err := ri.db.QueryContext(ctx, `
 		SELECT DISTINCT s.src_id, s.source_type, s.raw_address, s.addr_can
 		FROM src_document s
 		JOIN match_result mr ON mr.src_id = s.src_id
 		WHERE mr.decision = 'needs_review'
+		  AND s.removed_at IS NULL
 		  AND mr.src_id NOT IN (
 			  SELECT src_id FROM match_accepted
 		  )
@@ -136,7 +147,7 @@ func (ri *ReviewInterface) getItemsNeedingReview(limit int) ([]*ReviewItem, erro
 		}
 
 		// Get candidates for this item
-		candidates, err := ri.getCandidatesForReview(item.SrcID)
+		candidates, err := ri.getCandidatesForReview(ctx, item.SrcID)
 		if err != nil {
 			continue
 		}
@@ -149,8 +160,11 @@ func (ri *ReviewInterface) getItemsNeedingReview(limit int) ([]*ReviewItem, erro
 }
 
 // getCandidatesForReview gets all candidates for a source document
-func (ri *ReviewInterface) getCandidatesForReview(srcID int64) ([]*ReviewCandidate, error) {
-	rows, err := ri.db.Query(`
+func (ri *ReviewInterface) getCandidatesForReview(ctx context.Context, srcID int64) ([]*ReviewCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := ri.db.QueryContext(ctx, `
 		SELECT mr.candidate_uprn, mr.method, mr.score, mr.confidence,
 			   mr.tie_rank, mr.features, d.full_address
 		FROM match_result mr
@@ -195,7 +209,7 @@ func (ri *ReviewInterface) getCandidatesForReview(srcID int64) ([]*ReviewCandida
 }
 
 // reviewItem presents an item for manual review
-func (ri *ReviewInterface) reviewItem(item *ReviewItem, reviewer string) (string, error) {
+func (ri *ReviewInterface) reviewItem(ctx context.Context, item *ReviewItem, reviewer string) (string, error) {
 	// Display source information
 	fmt.Printf("Source ID: %d\n", item.SrcID)
 	fmt.Printf("Source Type: %s\n", item.SourceType)
@@ -222,7 +236,7 @@ func (ri *ReviewInterface) reviewItem(item *ReviewItem, reviewer string) (string
 	}
 
 	// Get user decision
-	return ri.getUserDecision(item, reviewer)
+	return ri.getUserDecision(ctx, item, reviewer)
 }
 
 // displayKeyFeatures shows important matching features
@@ -259,7 +273,7 @@ func (ri *ReviewInterface) displayKeyFeatures(features map[string]interface{}) {
 }
 
 // getUserDecision prompts user for decision
-func (ri *ReviewInterface) getUserDecision(item *ReviewItem, reviewer string) (string, error) {
+func (ri *ReviewInterface) getUserDecision(ctx context.Context, item *ReviewItem, reviewer string) (string, error) {
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println("Options:")
@@ -281,7 +295,7 @@ func (ri *ReviewInterface) getUserDecision(item *ReviewItem, reviewer string) (s
 
 	switch choice {
 	case "r":
-		return ri.recordDecision(item, "rejected", "", reviewer)
+		return ri.recordDecision(ctx, item, "rejected", "", reviewer)
 	case "s":
 		return "skipped", nil
 	case "q":
@@ -291,16 +305,16 @@ func (ri *ReviewInterface) getUserDecision(item *ReviewItem, reviewer string) (s
 		num, err := strconv.Atoi(choice)
 		if err != nil || num < 1 || num > len(item.Candidates) {
 			fmt.Printf("Invalid choice '%s'. Please try again.\n", choice)
-			return ri.getUserDecision(item, reviewer)
+			return ri.getUserDecision(ctx, item, reviewer)
 		}
 		
 		selectedCandidate := item.Candidates[num-1]
-		return ri.recordDecision(item, "accepted", selectedCandidate.UPRN, reviewer)
+		return ri.recordDecision(ctx, item, "accepted", selectedCandidate.UPRN, reviewer)
 	}
 }
 
 // recordDecision records the user's decision
-func (ri *ReviewInterface) recordDecision(item *ReviewItem, decision, uprn, reviewer string) (string, error) {
+func (ri *ReviewInterface) recordDecision(ctx context.Context, item *ReviewItem, decision, uprn, reviewer string) (string, error) {
 	// Get notes if rejecting
 	var notes string
 	if decision == "rejected" {
@@ -313,14 +327,14 @@ func (ri *ReviewInterface) recordDecision(item *ReviewItem, decision, uprn, revi
 	// Record the decision
 	if decision == "accepted" && uprn != "" {
 		// Accept the match
-		engine := &MatchEngine{db: ri.db}
-		err := engine.AcceptMatch(item.SrcID, uprn, "manual_review", 1.0, 1.0, 0, reviewer)
+		engine := NewMatchEngine(ri.db)
+		err := engine.AcceptReviewed(ctx, item.SrcID, uprn, "manual_review", reviewer)
 		if err != nil {
 			return "", fmt.Errorf("failed to accept match: %w", err)
 		}
 
 		// Update all match_result records for this src_id
-		_, err = ri.db.Exec(`
+		_, err = ri.db.ExecContext(ctx, `
 			UPDATE match_result 
 			SET decided = true, decision = $1, decided_by = $2, reviewed_at = NOW(),
 				notes = $3
@@ -335,8 +349,20 @@ func (ri *ReviewInterface) recordDecision(item *ReviewItem, decision, uprn, revi
 		return "accepted", nil
 
 	} else if decision == "rejected" {
+		// Keep the rejected UPRNs out of later runs for this document and its canonical address
+		_, err := ri.db.ExecContext(ctx, `
+			SELECT record_match_rejection($1, c.candidate_uprn, 'cli_review', $3, $2)
+			FROM (
+				SELECT DISTINCT candidate_uprn FROM match_result
+				WHERE src_id = $1 AND decision = 'needs_review' AND candidate_uprn IS NOT NULL
+			) c
+		`, item.SrcID, reviewer, notes)
+		if err != nil {
+			return "", fmt.Errorf("failed to record rejections: %w", err)
+		}
+
 		// Reject all candidates
-		_, err := ri.db.Exec(`
+		_, err = ri.db.ExecContext(ctx, `
 			UPDATE match_result 
 			SET decided = true, decision = $1, decided_by = $2, reviewed_at = NOW(),
 				notes = $3
@@ -362,7 +388,7 @@ func (ri *ReviewInterface) recordDecision(item *ReviewItem, decision, uprn, revi
 
 // GetReviewStats shows statistics about items needing review
 func (ri *ReviewInterface) GetReviewStats() error {
-	fmt.Println("\n=== Review Queue Statistics ===\n")
+	fmt.Print("\n=== Review Queue Statistics ===\n\n")
 
 	// Total items needing review
 	var totalNeedsReview int
@@ -1,11 +1,15 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"regexp"
 	"strings"
 	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/match"
 )
 
 // RuleMatcher handles rule-based matching for known patterns
@@ -154,7 +158,7 @@ func (rm *RuleMatcher) loadDefaultRules() {
 }
 
 // RunRuleMatching performs rule-based pattern matching
-func (rm *RuleMatcher) RunRuleMatching(runID int64, batchSize int) (int, int, int, error) {
+func (rm *RuleMatcher) RunRuleMatching(ctx context.Context, runID int64, batchSize int) (int, int, int, error) {
 	startTime := time.Now()
 	totalProcessed := 0
 	totalAccepted := 0
@@ -163,11 +167,11 @@ func (rm *RuleMatcher) RunRuleMatching(runID int64, batchSize int) (int, int, in
 	fmt.Println("Starting rule-based pattern matching...")
 	fmt.Printf("Loaded %d active rules\n", len(rm.getActiveRules()))
 
-	engine := &MatchEngine{db: rm.db}
+	engine := NewMatchEngine(rm.db)
 
 	for {
 		// Get unmatched documents
-		docs, err := rm.getUnmatchedForRules(batchSize)
+		docs, err := rm.getUnmatchedForRules(ctx, batchSize)
 		if err != nil {
 			return totalProcessed, totalAccepted, totalNeedsReview, fmt.Errorf("failed to get documents: %w", err)
 		}
@@ -177,6 +181,11 @@ func (rm *RuleMatcher) RunRuleMatching(runID int64, batchSize int) (int, int, in
 		}
 
 		for _, doc := range docs {
+			// Stop between documents, so an interrupted run never leaves one half-recorded
+			if err := ctx.Err(); err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+
 			totalProcessed++
 
 			if doc.AddrCan == nil || *doc.AddrCan == "" || *doc.AddrCan == "N A" {
@@ -185,12 +194,17 @@ func (rm *RuleMatcher) RunRuleMatching(runID int64, batchSize int) (int, int, in
 
 			sourceAddr := strings.ToUpper(*doc.AddrCan)
 
+			rejections, err := engine.Rejections(ctx, doc)
+			if err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+
 			// Try each rule
 			var bestCandidate *RuleCandidate
 			var matchFound bool
 
 			for _, rule := range rm.getActiveRules() {
-				candidate, err := rm.tryRule(doc, sourceAddr, rule)
+				candidate, err := rm.tryRule(ctx, doc, sourceAddr, rule, rejections)
 				if err != nil {
 					continue
 				}
@@ -209,13 +223,13 @@ func (rm *RuleMatcher) RunRuleMatching(runID int64, batchSize int) (int, int, in
 			// Make decision based on rule confidence
 			if bestCandidate.Confidence >= 0.85 {
 				// High confidence rule - auto accept
-				err = rm.acceptMatch(engine, runID, doc.SrcID, bestCandidate)
+				err = rm.acceptMatch(ctx, engine, runID, doc.SrcID, bestCandidate)
 				if err == nil {
 					totalAccepted++
 				}
 			} else if bestCandidate.Confidence >= 0.65 {
 				// Medium confidence rule - needs review
-				rm.saveForReview(engine, runID, doc.SrcID, bestCandidate, 1)
+				rm.saveForReview(ctx, engine, runID, doc.SrcID, bestCandidate, 1)
 				totalNeedsReview++
 			}
 			// Below 0.65 is rejected
@@ -235,13 +249,18 @@ func (rm *RuleMatcher) RunRuleMatching(runID int64, batchSize int) (int, int, in
 }
 
 // getUnmatchedForRules gets unmatched documents suitable for rule-based matching
-func (rm *RuleMatcher) getUnmatchedForRules(limit int) ([]SourceDocument, error) {
-	rows, err := rm.db.Query(`
+func (rm *RuleMatcher) getUnmatchedForRules(ctx context.Context, limit int) ([]SourceDocument, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := rm.db.QueryContext(ctx, `
 		SELECT s.src_id, s.source_type, s.raw_address, s.addr_can, s.postcode_text,
-			   s.easting_raw, s.northing_raw, s.uprn_raw
+			   s.easting_raw, s.northing_raw, s.uprn_raw,
+			   s.easting_repaired, s.northing_repaired, s.doc_date
 		FROM src_document s
 		LEFT JOIN match_accepted m ON m.src_id = s.src_id
 		WHERE m.src_id IS NULL
+		  AND s.removed_at IS NULL
 		  AND s.addr_can IS NOT NULL
 		  AND s.addr_can != 'N A'
 		  AND s.addr_can != ''
@@ -260,6 +279,7 @@ func (rm *RuleMatcher) getUnmatchedForRules(limit int) ([]SourceDocument, error)
 		err := rows.Scan(
 			&doc.SrcID, &doc.SourceType, &doc.RawAddress, &doc.AddrCan,
 			&doc.PostcodeText, &doc.EastingRaw, &doc.NorthingRaw, &doc.UPRNRaw,
+			&doc.Easting, &doc.Northing, &doc.DocDate,
 		)
 		if err != nil {
 			continue
@@ -270,8 +290,12 @@ func (rm *RuleMatcher) getUnmatchedForRules(limit int) ([]SourceDocument, error)
 	return docs, nil
 }
 
-// tryRule attempts to apply a rule to an address and find matches
-func (rm *RuleMatcher) tryRule(doc SourceDocument, sourceAddr string, rule AddressRule) (*RuleCandidate, error) {
+// tryRule attempts to apply a rule to an address and find matches, skipping rejected UPRNs and
+// addresses that could not have existed on the document date
+func (rm *RuleMatcher) tryRule(ctx context.Context, doc SourceDocument, sourceAddr string, rule AddressRule, rejections match.Rejections) (*RuleCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	// Apply the rule transformation
 	transformedAddr, matched := rm.applyRule(sourceAddr, rule)
 	if !matched {
@@ -279,14 +303,15 @@ func (rm *RuleMatcher) tryRule(doc SourceDocument, sourceAddr string, rule Addre
 	}
 
 	// Search for matches using the transformed address
-	rows, err := rm.db.Query(`
+	rows, err := rm.db.QueryContext(ctx, `
 		SELECT d.uprn, d.full_address, d.address_canonical, similarity($1, d.address_canonical) as sim
 		FROM dim_address d
 		WHERE d.address_canonical % $1
 		  AND similarity($1, d.address_canonical) >= 0.70
+		  AND `+match.TemporalPredicate("d", "$2")+`
 		ORDER BY sim DESC
 		LIMIT 5
-	`, transformedAddr)
+	`, transformedAddr, doc.DocDate)
 
 	if err != nil {
 		return nil, fmt.Errorf("rule query failed: %w", err)
@@ -310,7 +335,7 @@ func (rm *RuleMatcher) tryRule(doc SourceDocument, sourceAddr string, rule Addre
 			&candidate.CanonicalAddr,
 			&similarity,
 		)
-		if err != nil {
+		if err != nil || rejections.Rejected(candidate.UPRN) {
 			continue
 		}
 
@@ -393,7 +418,7 @@ func (rm *RuleMatcher) getActiveRules() []AddressRule {
 }
 
 // acceptMatch accepts a rule-based match
-func (rm *RuleMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, candidate *RuleCandidate) error {
+func (rm *RuleMatcher) acceptMatch(ctx context.Context, engine *MatchEngine, runID, srcID int64, candidate *RuleCandidate) error {
 	result := &MatchResult{
 		RunID:         runID,
 		SrcID:         srcID,
@@ -410,16 +435,16 @@ func (rm *RuleMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, cand
 			candidate.RuleName, candidate.Confidence),
 	}
 
-	if err := engine.SaveMatchResult(result); err != nil {
+	if err := engine.SaveMatchResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to save match result: %w", err)
 	}
 
-	return engine.AcceptMatch(srcID, candidate.UPRN, fmt.Sprintf("rule_%s", candidate.RuleName),
+	return engine.AcceptMatch(ctx, srcID, candidate.UPRN, fmt.Sprintf("rule_%s", candidate.RuleName),
 		candidate.Confidence, candidate.Confidence, runID, "system")
 }
 
 // saveForReview saves a rule-based candidate for manual review
-func (rm *RuleMatcher) saveForReview(engine *MatchEngine, runID, srcID int64,
+func (rm *RuleMatcher) saveForReview(ctx context.Context, engine *MatchEngine, runID, srcID int64,
 	candidate *RuleCandidate, rank int) error {
 
 	result := &MatchResult{
@@ -438,7 +463,7 @@ func (rm *RuleMatcher) saveForReview(engine *MatchEngine, runID, srcID int64,
 			candidate.RuleName, candidate.Confidence),
 	}
 
-	return engine.SaveMatchResult(result)
+	return engine.SaveMatchResult(ctx, result)
 }
 
 // AddCustomRule adds a custom rule to the matcher
@@ -454,7 +479,7 @@ func (rm *RuleMatcher) ListRules() []AddressRule {
 
 // AnalyzeRuleEffectiveness analyzes how effective the rules are
 func (rm *RuleMatcher) AnalyzeRuleEffectiveness() error {
-	fmt.Println("\n=== Rule-Based Matching Analysis ===\n")
+	fmt.Print("\n=== Rule-Based Matching Analysis ===\n\n")
 
 	// Count potential matches for each rule
 	fmt.Println("Rule Effectiveness Analysis:")
@@ -470,6 +495,7 @@ func (rm *RuleMatcher) AnalyzeRuleEffectiveness() error {
 			FROM src_document s
 			LEFT JOIN match_accepted m ON m.src_id = s.src_id
 			WHERE m.src_id IS NULL
+			  AND s.removed_at IS NULL
 			  AND s.addr_can IS NOT NULL
 			  AND s.addr_can != 'N A'
 		`).Scan(&count)
@@ -492,6 +518,7 @@ func (rm *RuleMatcher) AnalyzeRuleEffectiveness() error {
 		FROM src_document s
 		LEFT JOIN match_accepted m ON m.src_id = s.src_id
 		WHERE m.src_id IS NULL
+		  AND s.removed_at IS NULL
 		  AND s.addr_can IS NOT NULL
 		  AND s.addr_can != 'N A'
 		  AND (
@@ -1,11 +1,14 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"math"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/match"
 	"github.com/ehdc-llpg/internal/normalize"
 )
 
@@ -34,7 +37,7 @@ type SpatialCandidate struct {
 }
 
 // RunSpatialMatching performs spatial proximity matching
-func (sm *SpatialMatcher) RunSpatialMatching(runID int64, batchSize int, maxDistance float64) (int, int, int, error) {
+func (sm *SpatialMatcher) RunSpatialMatching(ctx context.Context, runID int64, batchSize int, maxDistance float64) (int, int, int, error) {
 	if maxDistance <= 0 {
 		maxDistance = 100.0 // Default 100 meters
 	}
@@ -46,11 +49,11 @@ func (sm *SpatialMatcher) RunSpatialMatching(runID int64, batchSize int, maxDist
 
 	fmt.Printf("Starting spatial proximity matching (max distance: %.0fm)...\n", maxDistance)
 
-	engine := &MatchEngine{db: sm.db}
+	engine := NewMatchEngine(sm.db)
 
 	for {
 		// Get unmatched documents with coordinates
-		docs, err := sm.getUnmatchedWithCoordinates(batchSize)
+		docs, err := sm.getUnmatchedWithCoordinates(ctx, batchSize)
 		if err != nil {
 			return totalProcessed, totalAccepted, totalNeedsReview, fmt.Errorf("failed to get documents: %w", err)
 		}
@@ -60,19 +63,36 @@ func (sm *SpatialMatcher) RunSpatialMatching(runID int64, batchSize int, maxDist
 		}
 
 		for _, doc := range docs {
+			// Stop between documents, so an interrupted run never leaves one half-recorded
+			if err := ctx.Err(); err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+
 			totalProcessed++
 
-			if doc.EastingRaw == nil || doc.NorthingRaw == nil {
+			if doc.Easting == nil || doc.Northing == nil {
 				continue
 			}
 
 			// Find spatial candidates
-			candidates, err := sm.findSpatialCandidates(doc, maxDistance)
+			candidates, err := sm.findSpatialCandidates(ctx, doc, maxDistance)
 			if err != nil {
 				fmt.Printf("Error finding spatial candidates for doc %d: %v\n", doc.SrcID, err)
 				continue
 			}
 
+			rejections, err := engine.Rejections(ctx, doc)
+			if err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+			var allowed []*SpatialCandidate
+			for _, c := range candidates {
+				if !rejections.Rejected(c.UPRN) {
+					allowed = append(allowed, c)
+				}
+			}
+			candidates = allowed
+
 			if len(candidates) == 0 {
 				continue
 			}
@@ -82,13 +102,13 @@ func (sm *SpatialMatcher) RunSpatialMatching(runID int64, batchSize int, maxDist
 
 			if bestCandidate.Distance <= 25.0 && bestCandidate.AddressSimilarity >= 0.80 {
 				// Very close and similar - high confidence
-				err = sm.acceptMatch(engine, runID, doc.SrcID, bestCandidate, "spatial_high")
+				err = sm.acceptMatch(ctx, engine, runID, doc.SrcID, bestCandidate, "spatial_high")
 				if err == nil {
 					totalAccepted++
 				}
 			} else if bestCandidate.Distance <= 50.0 && bestCandidate.AddressSimilarity >= 0.60 {
 				// Close with reasonable similarity - auto accept
-				err = sm.acceptMatch(engine, runID, doc.SrcID, bestCandidate, "spatial_medium")
+				err = sm.acceptMatch(ctx, engine, runID, doc.SrcID, bestCandidate, "spatial_medium")
 				if err == nil {
 					totalAccepted++
 				}
@@ -98,7 +118,7 @@ func (sm *SpatialMatcher) RunSpatialMatching(runID int64, batchSize int, maxDist
 					if i >= 3 {
 						break
 					}
-					sm.saveForReview(engine, runID, doc.SrcID, candidate, i+1)
+					sm.saveForReview(ctx, engine, runID, doc.SrcID, candidate, i+1)
 				}
 				totalNeedsReview++
 			}
@@ -118,17 +138,20 @@ func (sm *SpatialMatcher) RunSpatialMatching(runID int64, batchSize int, maxDist
 }
 
 // getUnmatchedWithCoordinates gets documents with coordinates but no matches
-func (sm *SpatialMatcher) getUnmatchedWithCoordinates(limit int) ([]SourceDocument, error) {
-	rows, err := sm.db.Query(`
+func (sm *SpatialMatcher) getUnmatchedWithCoordinates(ctx context.Context, limit int) ([]SourceDocument, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := sm.db.QueryContext(ctx, `
 		SELECT s.src_id, s.source_type, s.raw_address, s.addr_can, s.postcode_text,
-			   s.easting_raw, s.northing_raw, s.uprn_raw
+			   s.easting_raw, s.northing_raw, s.uprn_raw,
+			   s.easting_repaired, s.northing_repaired, s.doc_date
 		FROM src_document s
 		LEFT JOIN match_accepted m ON m.src_id = s.src_id
 		WHERE m.src_id IS NULL
-		  AND s.easting_raw IS NOT NULL
-		  AND s.northing_raw IS NOT NULL
-		  AND s.easting_raw > 0
-		  AND s.northing_raw > 0
+		  AND s.removed_at IS NULL
+		  AND s.easting_repaired IS NOT NULL
+		  AND s.northing_repaired IS NOT NULL
 		ORDER BY s.src_id
 		LIMIT $1
 	`, limit)
@@ -144,6 +167,7 @@ func (sm *SpatialMatcher) getUnmatchedWithCoordinates(limit int) ([]SourceDocume
 		err := rows.Scan(
 			&doc.SrcID, &doc.SourceType, &doc.RawAddress, &doc.AddrCan,
 			&doc.PostcodeText, &doc.EastingRaw, &doc.NorthingRaw, &doc.UPRNRaw,
+			&doc.Easting, &doc.Northing, &doc.DocDate,
 		)
 		if err != nil {
 			continue
@@ -154,21 +178,25 @@ func (sm *SpatialMatcher) getUnmatchedWithCoordinates(limit int) ([]SourceDocume
 	return docs, nil
 }
 
-// findSpatialCandidates finds LLPG addresses within spatial proximity
-func (sm *SpatialMatcher) findSpatialCandidates(doc SourceDocument, maxDistance float64) ([]*SpatialCandidate, error) {
-	if doc.EastingRaw == nil || doc.NorthingRaw == nil {
+// findSpatialCandidates finds LLPG addresses within spatial proximity that could have existed
+// on the document date
+func (sm *SpatialMatcher) findSpatialCandidates(ctx context.Context, doc SourceDocument, maxDistance float64) ([]*SpatialCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	if doc.Easting == nil || doc.Northing == nil {
 		return nil, nil
 	}
 
-	sourceEasting := *doc.EastingRaw
-	sourceNorthing := *doc.NorthingRaw
+	sourceEasting := *doc.Easting
+	sourceNorthing := *doc.Northing
 	sourceAddr := ""
 	if doc.AddrCan != nil {
 		sourceAddr = *doc.AddrCan
 	}
 
 	// Use PostGIS for efficient spatial query
-	rows, err := sm.db.Query(`
+	rows, err := sm.db.QueryContext(ctx, `
 		SELECT
 			d.uprn,
 			d.full_address,
@@ -191,9 +219,10 @@ func (sm *SpatialMatcher) findSpatialCandidates(doc SourceDocument, maxDistance
 			ST_SetSRID(ST_MakePoint(l.easting, l.northing), 27700),
 			$4
 		)
+		  AND `+match.TemporalPredicate("d", "$5")+`
 		ORDER BY distance_meters ASC, address_similarity DESC
 		LIMIT 10
-	`, sourceEasting, sourceNorthing, sourceAddr, maxDistance)
+	`, sourceEasting, sourceNorthing, sourceAddr, maxDistance, doc.DocDate)
 
 	if err != nil {
 		return nil, fmt.Errorf("spatial query failed: %w", err)
@@ -304,7 +333,7 @@ func (sm *SpatialMatcher) calculateSpatialScore(candidate *SpatialCandidate, sou
 }
 
 // acceptMatch accepts a spatial match
-func (sm *SpatialMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, candidate *SpatialCandidate, confidence string) error {
+func (sm *SpatialMatcher) acceptMatch(ctx context.Context, engine *MatchEngine, runID, srcID int64, candidate *SpatialCandidate, confidence string) error {
 	result := &MatchResult{
 		RunID:         runID,
 		SrcID:         srcID,
@@ -321,16 +350,16 @@ func (sm *SpatialMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, c
 			candidate.Distance, candidate.AddressSimilarity),
 	}
 
-	if err := engine.SaveMatchResult(result); err != nil {
+	if err := engine.SaveMatchResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to save match result: %w", err)
 	}
 
-	return engine.AcceptMatch(srcID, candidate.UPRN, confidence,
+	return engine.AcceptMatch(ctx, srcID, candidate.UPRN, confidence,
 		candidate.FinalScore, candidate.FinalScore, runID, "system")
 }
 
 // saveForReview saves a spatial candidate for manual review
-func (sm *SpatialMatcher) saveForReview(engine *MatchEngine, runID, srcID int64,
+func (sm *SpatialMatcher) saveForReview(ctx context.Context, engine *MatchEngine, runID, srcID int64,
 	candidate *SpatialCandidate, rank int) error {
 
 	result := &MatchResult{
@@ -349,12 +378,12 @@ func (sm *SpatialMatcher) saveForReview(engine *MatchEngine, runID, srcID int64,
 			candidate.Distance, candidate.FinalScore),
 	}
 
-	return engine.SaveMatchResult(result)
+	return engine.SaveMatchResult(ctx, result)
 }
 
 // AnalyzeSpatialQuality analyzes spatial data quality and potential
 func (sm *SpatialMatcher) AnalyzeSpatialQuality() error {
-	fmt.Println("\n=== Spatial Data Quality Analysis ===\n")
+	fmt.Print("\n=== Spatial Data Quality Analysis ===\n\n")
 
 	// Documents with coordinates
 	var withCoords, withoutCoords, validCoords int
@@ -387,21 +416,20 @@ func (sm *SpatialMatcher) AnalyzeSpatialQuality() error {
 		var potential int
 		err = sm.db.QueryRow(`
 			WITH unmatched_with_coords AS (
-				SELECT s.src_id, s.easting_raw, s.northing_raw
+				SELECT s.src_id, s.easting_repaired, s.northing_repaired
 				FROM src_document s
 				LEFT JOIN match_accepted m ON m.src_id = s.src_id
 				WHERE m.src_id IS NULL
-				  AND s.easting_raw IS NOT NULL
-				  AND s.northing_raw IS NOT NULL
-				  AND s.easting_raw > 0
-				  AND s.northing_raw > 0
+				  AND s.removed_at IS NULL
+				  AND s.easting_repaired IS NOT NULL
+				  AND s.northing_repaired IS NOT NULL
 			)
 			SELECT COUNT(DISTINCT uwc.src_id)
 			FROM unmatched_with_coords uwc
 			WHERE EXISTS (
 				SELECT 1 FROM dim_address d
 				WHERE ST_DWithin(
-					ST_SetSRID(ST_MakePoint(uwc.easting_raw, uwc.northing_raw), 27700),
+					ST_SetSRID(ST_MakePoint(uwc.easting_repaired, uwc.northing_repaired), 27700),
 					ST_SetSRID(ST_MakePoint(d.easting, d.northing), 27700),
 					$1
 				)
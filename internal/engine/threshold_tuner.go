@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"math"
@@ -34,7 +35,7 @@ type TuningResult struct {
 }
 
 // TestThresholds tests different similarity thresholds to find optimal settings
-func (tt *ThresholdTuner) TestThresholds(sampleSize int) ([]*TuningResult, error) {
+func (tt *ThresholdTuner) TestThresholds(ctx context.Context, sampleSize int) ([]*TuningResult, error) {
 	// Test different threshold values
 	thresholds := []float64{0.50, 0.55, 0.60, 0.65, 0.70, 0.75, 0.80, 0.85, 0.90}
 	results := make([]*TuningResult, 0, len(thresholds))
@@ -43,7 +44,7 @@ func (tt *ThresholdTuner) TestThresholds(sampleSize int) ([]*TuningResult, error
 	fmt.Printf("Testing %d thresholds with sample size %d\n\n", len(thresholds), sampleSize)
 
 	// Get sample of documents with known good matches (using existing accepted matches as ground truth)
-	knownGood, err := tt.getKnownGoodMatches(sampleSize)
+	knownGood, err := tt.getKnownGoodMatches(ctx, sampleSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get known good matches: %w", err)
 	}
@@ -52,7 +53,7 @@ func (tt *ThresholdTuner) TestThresholds(sampleSize int) ([]*TuningResult, error
 
 	for _, threshold := range thresholds {
 		fmt.Printf("Testing threshold %.2f...\n", threshold)
-		result, err := tt.testThreshold(threshold, knownGood)
+		result, err := tt.testThreshold(ctx, threshold, knownGood)
 		if err != nil {
 			fmt.Printf("  Error: %v\n", err)
 			continue
@@ -80,7 +81,7 @@ func (tt *ThresholdTuner) TestThresholds(sampleSize int) ([]*TuningResult, error
 }
 
 // testThreshold tests a specific threshold value
-func (tt *ThresholdTuner) testThreshold(threshold float64, knownGood map[int64]string) (*TuningResult, error) {
+func (tt *ThresholdTuner) testThreshold(ctx context.Context, threshold float64, knownGood map[int64]string) (*TuningResult, error) {
 	startTime := time.Now()
 	result := &TuningResult{
 		Threshold: threshold,
@@ -101,11 +102,11 @@ func (tt *ThresholdTuner) testThreshold(threshold float64, knownGood map[int64]s
 	for srcID, correctUPRN := range knownGood {
 		// Get the document
 		var doc SourceDocument
-		err := tt.db.QueryRow(`
-			SELECT src_id, addr_can, easting_raw, northing_raw
+		err := tt.db.QueryRowContext(ctx, `
+			SELECT src_id, addr_can, easting_repaired, northing_repaired
 			FROM src_document
 			WHERE src_id = $1
-		`, srcID).Scan(&doc.SrcID, &doc.AddrCan, &doc.EastingRaw, &doc.NorthingRaw)
+		`, srcID).Scan(&doc.SrcID, &doc.AddrCan, &doc.Easting, &doc.Northing)
 		
 		if err != nil {
 			continue
@@ -116,7 +117,7 @@ func (tt *ThresholdTuner) testThreshold(threshold float64, knownGood map[int64]s
 		}
 
 		// Find candidates at this threshold
-		candidates, err := fm.FindFuzzyCandidates(doc, threshold)
+		candidates, err := fm.FindFuzzyCandidates(ctx, doc, threshold)
 		if err != nil {
 			continue
 		}
@@ -182,10 +183,10 @@ func (tt *ThresholdTuner) testThreshold(threshold float64, knownGood map[int64]s
 }
 
 // getKnownGoodMatches gets documents with existing accepted matches for validation
-func (tt *ThresholdTuner) getKnownGoodMatches(limit int) (map[int64]string, error) {
+func (tt *ThresholdTuner) getKnownGoodMatches(ctx context.Context, limit int) (map[int64]string, error) {
 	knownGood := make(map[int64]string)
 
-	rows, err := tt.db.Query(`
+	rows, err := tt.db.QueryContext(ctx, `
 		SELECT s.src_id, m.uprn
 		FROM src_document s
 		JOIN match_accepted m ON m.src_id = s.src_id
@@ -213,7 +214,7 @@ func (tt *ThresholdTuner) getKnownGoodMatches(limit int) (map[int64]string, erro
 
 	// If we don't have enough deterministic matches, add some high-confidence fuzzy matches
 	if len(knownGood) < limit/2 {
-		rows2, err := tt.db.Query(`
+		rows2, err := tt.db.QueryContext(ctx, `
 			SELECT s.src_id, m.uprn
 			FROM src_document s
 			JOIN match_accepted m ON m.src_id = s.src_id
@@ -272,7 +273,7 @@ func (tt *ThresholdTuner) findOptimalThreshold(results []*TuningResult) *TuningR
 
 // AnalyzeCurrentMatches analyzes the quality of existing matches
 func (tt *ThresholdTuner) AnalyzeCurrentMatches() error {
-	fmt.Println("\n=== Current Match Quality Analysis ===\n")
+	fmt.Print("\n=== Current Match Quality Analysis ===\n\n")
 
 	// Analyze by similarity score bands
 	rows, err := tt.db.Query(`
@@ -320,7 +321,7 @@ func (tt *ThresholdTuner) AnalyzeCurrentMatches() error {
 	}
 
 	// Show distribution of similarity scores
-	fmt.Println("\n=== Address Quality Distribution ===\n")
+	fmt.Print("\n=== Address Quality Distribution ===\n\n")
 	
 	err = tt.db.QueryRow(`
 		SELECT 
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
 )
 
 // VectorMatcher handles semantic/vector-based matching using embeddings
@@ -60,7 +63,7 @@ func NewVectorMatcher(db *sql.DB, embeddingAPI string) *VectorMatcher {
 }
 
 // RunVectorMatching performs semantic/vector-based matching
-func (vm *VectorMatcher) RunVectorMatching(runID int64, batchSize int, minSimilarity float64) (int, int, int, error) {
+func (vm *VectorMatcher) RunVectorMatching(ctx context.Context, runID int64, batchSize int, minSimilarity float64) (int, int, int, error) {
 	if minSimilarity <= 0 {
 		minSimilarity = 0.70 // Default semantic similarity threshold
 	}
@@ -78,15 +81,15 @@ func (vm *VectorMatcher) RunVectorMatching(runID int64, batchSize int, minSimila
 	}
 
 	// Pre-populate vector database with LLPG addresses if empty
-	if err := vm.indexLLPGAddresses(); err != nil {
+	if err := vm.indexLLPGAddresses(ctx); err != nil {
 		return 0, 0, 0, fmt.Errorf("failed to index LLPG addresses: %w", err)
 	}
 
-	engine := &MatchEngine{db: vm.db}
+	engine := NewMatchEngine(vm.db)
 
 	for {
 		// Get unmatched documents
-		docs, err := vm.getUnmatchedForVector(batchSize)
+		docs, err := vm.getUnmatchedForVector(ctx, batchSize)
 		if err != nil {
 			return totalProcessed, totalAccepted, totalNeedsReview, fmt.Errorf("failed to get documents: %w", err)
 		}
@@ -96,6 +99,11 @@ func (vm *VectorMatcher) RunVectorMatching(runID int64, batchSize int, minSimila
 		}
 
 		for _, doc := range docs {
+			// Stop between documents, so an interrupted run never leaves one half-recorded
+			if err := ctx.Err(); err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+
 			totalProcessed++
 
 			if doc.AddrCan == nil || *doc.AddrCan == "" || *doc.AddrCan == "N A" {
@@ -103,12 +111,24 @@ func (vm *VectorMatcher) RunVectorMatching(runID int64, batchSize int, minSimila
 			}
 
 			// Find semantic candidates
-			candidates, err := vm.findSemanticCandidates(doc, minSimilarity)
+			candidates, err := vm.findSemanticCandidates(ctx, doc, minSimilarity)
 			if err != nil {
 				fmt.Printf("Error finding semantic candidates for doc %d: %v\n", doc.SrcID, err)
 				continue
 			}
 
+			rejections, err := engine.Rejections(ctx, doc)
+			if err != nil {
+				return totalProcessed, totalAccepted, totalNeedsReview, err
+			}
+			var allowed []*VectorCandidate
+			for _, c := range candidates {
+				if !rejections.Rejected(c.UPRN) {
+					allowed = append(allowed, c)
+				}
+			}
+			candidates = allowed
+
 			if len(candidates) == 0 {
 				continue
 			}
@@ -118,7 +138,7 @@ func (vm *VectorMatcher) RunVectorMatching(runID int64, batchSize int, minSimila
 
 			if bestCandidate.CombinedScore >= 0.85 {
 				// High semantic similarity - auto accept
-				err = vm.acceptMatch(engine, runID, doc.SrcID, bestCandidate)
+				err = vm.acceptMatch(ctx, engine, runID, doc.SrcID, bestCandidate)
 				if err == nil {
 					totalAccepted++
 				}
@@ -128,7 +148,7 @@ func (vm *VectorMatcher) RunVectorMatching(runID int64, batchSize int, minSimila
 					if i >= 3 {
 						break
 					}
-					vm.saveForReview(engine, runID, doc.SrcID, candidate, i+1)
+					vm.saveForReview(ctx, engine, runID, doc.SrcID, candidate, i+1)
 				}
 				totalNeedsReview++
 			}
@@ -148,13 +168,18 @@ func (vm *VectorMatcher) RunVectorMatching(runID int64, batchSize int, minSimila
 }
 
 // getUnmatchedForVector gets unmatched documents suitable for vector matching
-func (vm *VectorMatcher) getUnmatchedForVector(limit int) ([]SourceDocument, error) {
-	rows, err := vm.db.Query(`
+func (vm *VectorMatcher) getUnmatchedForVector(ctx context.Context, limit int) ([]SourceDocument, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := vm.db.QueryContext(ctx, `
 		SELECT s.src_id, s.source_type, s.raw_address, s.addr_can, s.postcode_text,
-			   s.easting_raw, s.northing_raw, s.uprn_raw
+			   s.easting_raw, s.northing_raw, s.uprn_raw,
+			   s.easting_repaired, s.northing_repaired, s.doc_date
 		FROM src_document s
 		LEFT JOIN match_accepted m ON m.src_id = s.src_id
 		WHERE m.src_id IS NULL
+		  AND s.removed_at IS NULL
 		  AND s.addr_can IS NOT NULL
 		  AND s.addr_can != 'N A'
 		  AND s.addr_can != ''
@@ -174,6 +199,7 @@ func (vm *VectorMatcher) getUnmatchedForVector(limit int) ([]SourceDocument, err
 		err := rows.Scan(
 			&doc.SrcID, &doc.SourceType, &doc.RawAddress, &doc.AddrCan,
 			&doc.PostcodeText, &doc.EastingRaw, &doc.NorthingRaw, &doc.UPRNRaw,
+			&doc.Easting, &doc.Northing, &doc.DocDate,
 		)
 		if err != nil {
 			continue
@@ -185,11 +211,11 @@ func (vm *VectorMatcher) getUnmatchedForVector(limit int) ([]SourceDocument, err
 }
 
 // indexLLPGAddresses pre-indexes all LLPG addresses for vector search
-func (vm *VectorMatcher) indexLLPGAddresses() error {
+func (vm *VectorMatcher) indexLLPGAddresses(ctx context.Context) error {
 	fmt.Println("Indexing LLPG addresses for vector search (this may take a few minutes)...")
 
-	rows, err := vm.db.Query(`
-		SELECT uprn, locaddress, addr_can
+	rows, err := vm.db.QueryContext(ctx, `
+		SELECT uprn, locaddress, addr_can, start_date, end_date
 		FROM dim_address
 		WHERE addr_can IS NOT NULL AND addr_can != ''
 		ORDER BY uprn
@@ -202,22 +228,27 @@ func (vm *VectorMatcher) indexLLPGAddresses() error {
 	count := 0
 	batchSize := 50 // Process in batches to avoid overwhelming the embedding API
 	var batch []struct {
-		UPRN    string
-		Address string
-		CanAddr string
+		UPRN      string
+		Address   string
+		CanAddr   string
+		StartDate *time.Time
+		EndDate   *time.Time
 	}
 
 	for rows.Next() {
 		var uprn, address, canAddr string
-		if err := rows.Scan(&uprn, &address, &canAddr); err != nil {
+		var startDate, endDate *time.Time
+		if err := rows.Scan(&uprn, &address, &canAddr, &startDate, &endDate); err != nil {
 			continue
 		}
 
 		batch = append(batch, struct {
-			UPRN    string
-			Address string
-			CanAddr string
-		}{uprn, address, canAddr})
+			UPRN      string
+			Address   string
+			CanAddr   string
+			StartDate *time.Time
+			EndDate   *time.Time
+		}{uprn, address, canAddr, startDate, endDate})
 
 		if len(batch) >= batchSize {
 			if err := vm.processBatch(batch); err != nil {
@@ -245,9 +276,11 @@ func (vm *VectorMatcher) indexLLPGAddresses() error {
 
 // processBatch processes a batch of addresses for vector indexing
 func (vm *VectorMatcher) processBatch(batch []struct {
-	UPRN    string
-	Address string
-	CanAddr string
+	UPRN      string
+	Address   string
+	CanAddr   string
+	StartDate *time.Time
+	EndDate   *time.Time
 }) error {
 	for _, item := range batch {
 		// Get embedding for canonical address
@@ -261,6 +294,8 @@ func (vm *VectorMatcher) processBatch(batch []struct {
 			"uprn":            item.UPRN,
 			"address":         item.Address,
 			"canonical_addr":  item.CanAddr,
+			"start_date":      item.StartDate,
+			"end_date":        item.EndDate,
 		}
 
 		if err := vm.vectorDB.Store(item.UPRN, embedding, metadata); err != nil {
@@ -274,7 +309,10 @@ func (vm *VectorMatcher) processBatch(batch []struct {
 }
 
 // findSemanticCandidates finds candidates using semantic similarity
-func (vm *VectorMatcher) findSemanticCandidates(doc SourceDocument, minSimilarity float64) ([]*VectorCandidate, error) {
+func (vm *VectorMatcher) findSemanticCandidates(ctx context.Context, doc SourceDocument, minSimilarity float64) ([]*VectorCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	sourceAddr := *doc.AddrCan
 
 	// Get embedding for source address
@@ -291,6 +329,13 @@ func (vm *VectorMatcher) findSemanticCandidates(doc SourceDocument, minSimilarit
 
 	var candidates []*VectorCandidate
 	for _, match := range matches {
+		// The index holds every LLPG address; drop those the document predates or outlives
+		startDate, _ := match.Metadata["start_date"].(*time.Time)
+		endDate, _ := match.Metadata["end_date"].(*time.Time)
+		if !existedOn(doc, startDate, endDate) {
+			continue
+		}
+
 		candidate := &VectorCandidate{
 			UPRN:          match.Metadata["uprn"].(string),
 			Address:       match.Metadata["address"].(string),
@@ -300,7 +345,7 @@ func (vm *VectorMatcher) findSemanticCandidates(doc SourceDocument, minSimilarit
 		}
 
 		// Calculate combined score (semantic + text similarity)
-		candidate.CombinedScore = vm.calculateCombinedScore(sourceAddr, candidate)
+		candidate.CombinedScore = vm.calculateCombinedScore(ctx, sourceAddr, candidate)
 
 		// Store features for explainability
 		candidate.Features = map[string]interface{}{
@@ -319,14 +364,17 @@ func (vm *VectorMatcher) findSemanticCandidates(doc SourceDocument, minSimilarit
 }
 
 // calculateCombinedScore combines semantic and text similarity
-func (vm *VectorMatcher) calculateCombinedScore(sourceAddr string, candidate *VectorCandidate) float64 {
+func (vm *VectorMatcher) calculateCombinedScore(ctx context.Context, sourceAddr string, candidate *VectorCandidate) float64 {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	// Weight semantic similarity at 70%, text similarity at 30%
 	semanticWeight := 0.70
 	textWeight := 0.30
 
 	// Calculate text similarity using trigram (if available)
 	var textSim float64
-	err := vm.db.QueryRow(`SELECT similarity($1, $2)`, sourceAddr, candidate.CanonicalAddr).Scan(&textSim)
+	err := vm.db.QueryRowContext(ctx, `SELECT similarity($1, $2)`, sourceAddr, candidate.CanonicalAddr).Scan(&textSim)
 	if err != nil {
 		textSim = 0.5 // Default if similarity function not available
 	}
@@ -418,7 +466,7 @@ func (vm *VectorMatcher) getMockEmbedding(text string) []float32 {
 }
 
 // acceptMatch accepts a vector-based match
-func (vm *VectorMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, candidate *VectorCandidate) error {
+func (vm *VectorMatcher) acceptMatch(ctx context.Context, engine *MatchEngine, runID, srcID int64, candidate *VectorCandidate) error {
 	result := &MatchResult{
 		RunID:         runID,
 		SrcID:         srcID,
@@ -435,16 +483,16 @@ func (vm *VectorMatcher) acceptMatch(engine *MatchEngine, runID, srcID int64, ca
 			candidate.SemanticScore, candidate.CombinedScore),
 	}
 
-	if err := engine.SaveMatchResult(result); err != nil {
+	if err := engine.SaveMatchResult(ctx, result); err != nil {
 		return fmt.Errorf("failed to save match result: %w", err)
 	}
 
-	return engine.AcceptMatch(srcID, candidate.UPRN, "vector_semantic",
+	return engine.AcceptMatch(ctx, srcID, candidate.UPRN, "vector_semantic",
 		candidate.CombinedScore, candidate.SemanticScore, runID, "system")
 }
 
 // saveForReview saves a vector candidate for manual review
-func (vm *VectorMatcher) saveForReview(engine *MatchEngine, runID, srcID int64,
+func (vm *VectorMatcher) saveForReview(ctx context.Context, engine *MatchEngine, runID, srcID int64,
 	candidate *VectorCandidate, rank int) error {
 
 	result := &MatchResult{
@@ -463,5 +511,5 @@ func (vm *VectorMatcher) saveForReview(engine *MatchEngine, runID, srcID int64,
 			candidate.SemanticScore, candidate.CombinedScore),
 	}
 
-	return engine.SaveMatchResult(result)
+	return engine.SaveMatchResult(ctx, result)
 }
\ No newline at end of file
@@ -0,0 +1,170 @@
+package etl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/geo"
+	"github.com/lib/pq"
+)
+
+const coordinateBatchSize = 5000
+
+// CoordinateValidator classifies source easting/northing values and stores a repaired copy.
+// easting_raw/northing_raw are never modified; spatial matching reads easting_repaired/northing_repaired.
+type CoordinateValidator struct {
+	db *sql.DB
+}
+
+// NewCoordinateValidator creates a new coordinate validator
+func NewCoordinateValidator(db *sql.DB) *CoordinateValidator {
+	return &CoordinateValidator{db: db}
+}
+
+// CoordinateSummary counts documents by coordinate status
+type CoordinateSummary struct {
+	Checked  int
+	ByStatus map[string]int
+}
+
+// coordinateUpdate is one row of a batched status update
+type coordinateUpdate struct {
+	srcID      int64
+	status     string
+	easting    sql.NullFloat64
+	northing   sql.NullFloat64
+	confidence sql.NullFloat64
+	reason     sql.NullString
+}
+
+// Validate checks the coordinates of every document, or only those of sourceType when given
+func (cv *CoordinateValidator) Validate(ctx context.Context, localDebug bool, sourceType string) (*CoordinateSummary, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	query := `
+		SELECT src_id, easting_raw::text, northing_raw::text
+		FROM src_document
+	`
+	var args []interface{}
+	if sourceType != "" {
+		query += " WHERE source_type::text = $1"
+		args = append(args, sourceType)
+	}
+
+	rows, err := cv.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query source coordinates: %w", err)
+	}
+
+	summary := &CoordinateSummary{ByStatus: make(map[string]int)}
+	var updates []coordinateUpdate
+
+	for rows.Next() {
+		var srcID int64
+		var eastingRaw, northingRaw sql.NullString
+		if err := rows.Scan(&srcID, &eastingRaw, &northingRaw); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan source coordinates: %w", err)
+		}
+
+		check := geo.CheckCoordinate(parseCoordinate(eastingRaw), parseCoordinate(northingRaw))
+		summary.Checked++
+		summary.ByStatus[check.Status]++
+
+		u := coordinateUpdate{srcID: srcID, status: check.Status}
+		if check.Easting != nil {
+			u.easting = sql.NullFloat64{Float64: *check.Easting, Valid: true}
+			u.northing = sql.NullFloat64{Float64: *check.Northing, Valid: true}
+			u.confidence = sql.NullFloat64{Float64: check.Confidence, Valid: true}
+		}
+		if check.Reason != "" {
+			u.reason = sql.NullString{String: check.Reason, Valid: true}
+		}
+		if check.Status != geo.CoordValid && check.Status != geo.CoordMissing {
+			debug.DebugOutput(localDebug, "src_id %d: %s - %s", srcID, check.Status, check.Reason)
+		}
+		updates = append(updates, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read source coordinates: %w", err)
+	}
+
+	for start := 0; start < len(updates); start += coordinateBatchSize {
+		end := start + coordinateBatchSize
+		if end > len(updates) {
+			end = len(updates)
+		}
+		if err := cv.saveBatch(ctx, updates[start:end]); err != nil {
+			return summary, err
+		}
+		debug.DebugOutput(localDebug, "Saved coordinate checks %d-%d of %d", start+1, end, len(updates))
+	}
+
+	return summary, nil
+}
+
+// saveBatch writes a batch of coordinate checks in one statement
+func (cv *CoordinateValidator) saveBatch(ctx context.Context, batch []coordinateUpdate) error {
+	ids := make([]int64, len(batch))
+	statuses := make([]string, len(batch))
+	eastings := make([]sql.NullFloat64, len(batch))
+	northings := make([]sql.NullFloat64, len(batch))
+	confidences := make([]sql.NullFloat64, len(batch))
+	reasons := make([]sql.NullString, len(batch))
+
+	for i, u := range batch {
+		ids[i], statuses[i] = u.srcID, u.status
+		eastings[i], northings[i] = u.easting, u.northing
+		confidences[i], reasons[i] = u.confidence, u.reason
+	}
+
+	_, err := cv.db.ExecContext(ctx, `
+		UPDATE src_document s
+		SET coord_status = u.status,
+		    easting_repaired = u.easting,
+		    northing_repaired = u.northing,
+		    coord_repair_confidence = u.confidence,
+		    coord_repair_reason = u.reason,
+		    coord_checked_at = now()
+		FROM unnest($1::bigint[], $2::text[], $3::numeric[], $4::numeric[], $5::numeric[], $6::text[])
+		     AS u(src_id, status, easting, northing, confidence, reason)
+		WHERE s.src_id = u.src_id
+	`, pq.Array(ids), pq.Array(statuses), pq.Array(eastings), pq.Array(northings), pq.Array(confidences), pq.Array(reasons))
+	if err != nil {
+		return fmt.Errorf("failed to save coordinate checks: %w", err)
+	}
+	return nil
+}
+
+// SortedStatuses returns the summary's statuses in a stable order for reporting
+func (s *CoordinateSummary) SortedStatuses() []string {
+	statuses := make([]string, 0, len(s.ByStatus))
+	for status := range s.ByStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+// parseCoordinate converts a raw coordinate, treating blanks and text as missing
+func parseCoordinate(raw sql.NullString) *float64 {
+	if !raw.Valid {
+		return nil
+	}
+	s := strings.TrimSpace(raw.String)
+	if s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,464 @@
+package etl
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/lib/pq"
+)
+
+// CopyLoader streams CSV files into PostgreSQL using the COPY protocol
+type CopyLoader struct {
+	db *sql.DB
+}
+
+// NewCopyLoader creates a new streaming COPY loader
+func NewCopyLoader(db *sql.DB) *CopyLoader {
+	return &CopyLoader{db: db}
+}
+
+// CopySpec describes a single streaming load into a target table
+type CopySpec struct {
+	Table       string   // target table
+	Columns     []string // target columns, in the order MapRow returns values
+	ChunkSize   int      // rows per committed chunk (default 50000)
+	RejectsPath string   // rejects file (default <csv>.rejects.csv)
+	Resume      bool     // continue from the last committed byte offset
+	Truncate    bool     // empty Table first, unless the load resumes
+
+	// RowNumberColumn, when set, receives each record's CSV row number (header excluded)
+	RowNumberColumn string
+
+	// MapRow converts a CSV record into column values; an error rejects the row
+	MapRow func(record []string, columnMap map[string]int) ([]interface{}, error)
+}
+
+// CopyResult summarises a streaming load
+type CopyResult struct {
+	RowsRead     int64
+	RowsLoaded   int64
+	RowsRejected int64
+	Chunks       int
+	ResumedFrom  int64 // byte offset the load resumed from, 0 for a fresh load
+	RejectsPath  string
+}
+
+// checkpoint is the last committed position for a file/table pair. The file's size and
+// modification time are kept so a changed file is loaded afresh rather than resumed.
+type checkpoint struct {
+	byteOffset   int64
+	rowNumber    int64
+	rowsLoaded   int64
+	rowsRejected int64
+	completed    bool
+	fileSize     int64
+	fileModified time.Time
+}
+
+const defaultCopyChunkSize = 50000
+
+// Load streams csvPath into the spec's table, committing every ChunkSize rows together with
+// the byte offset reached so a failed load can be resumed without reloading committed rows
+func (cl *CopyLoader) Load(ctx context.Context, localDebug bool, csvPath string, spec CopySpec) (*CopyResult, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	if spec.ChunkSize <= 0 {
+		spec.ChunkSize = defaultCopyChunkSize
+	}
+	if spec.RejectsPath == "" {
+		spec.RejectsPath = csvPath + ".rejects.csv"
+	}
+
+	columns := spec.Columns
+	if spec.RowNumberColumn != "" {
+		columns = append(append([]string{}, spec.Columns...), spec.RowNumberColumn)
+	}
+
+	if err := cl.ensureCheckpointTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create load checkpoint table: %w", err)
+	}
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat CSV: %w", err)
+	}
+	// timestamptz keeps microseconds
+	fileSize, fileModified := info.Size(), info.ModTime().Truncate(time.Microsecond)
+
+	reader := newOffsetCSVReader(file)
+
+	header, _, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columnMap := make(map[string]int)
+	for i, col := range header {
+		columnMap[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))] = i
+	}
+
+	result := &CopyResult{RejectsPath: spec.RejectsPath}
+	var rowNumber int64
+
+	// Pick up where the last committed chunk left off
+	cp, err := cl.loadCheckpoint(ctx, spec.Table, csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read load checkpoint: %w", err)
+	}
+
+	resuming := spec.Resume && cp != nil && !cp.completed && cp.byteOffset > reader.Offset() &&
+		cp.fileSize == fileSize && cp.fileModified.Equal(fileModified)
+	if spec.Resume && cp != nil && !cp.completed && !resuming {
+		debug.DebugOutput(localDebug, "%s changed since its last checkpoint; loading it afresh", csvPath)
+	}
+	if resuming {
+		if _, err := file.Seek(cp.byteOffset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek to offset %d: %w", cp.byteOffset, err)
+		}
+		reader = newOffsetCSVReaderAt(file, cp.byteOffset)
+		rowNumber = cp.rowNumber
+		result.RowsLoaded = cp.rowsLoaded
+		result.RowsRejected = cp.rowsRejected
+		result.ResumedFrom = cp.byteOffset
+		debug.DebugOutput(localDebug, "Resuming %s from byte %d (row %d)", csvPath, cp.byteOffset, cp.rowNumber)
+	} else {
+		if err := cl.clearCheckpoint(ctx, spec.Table, csvPath); err != nil {
+			return nil, fmt.Errorf("failed to reset load checkpoint: %w", err)
+		}
+		if spec.Truncate {
+			if err := cl.truncate(ctx, spec.Table); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rejects, err := openRejectsFile(spec.RejectsPath, resuming)
+	if err != nil {
+		return nil, err
+	}
+	defer rejects.Close()
+	rejectWriter := csv.NewWriter(rejects)
+
+	// Rejects are only written once their chunk commits so a resumed load doesn't repeat them
+	var pendingRejects [][]string
+	reject := func(row int64, reason, raw string) {
+		pendingRejects = append(pendingRejects, []string{strconv.FormatInt(row, 10), reason, strings.TrimRight(raw, "\r\n")})
+	}
+
+	eof := false
+	for !eof {
+		// Cancelling ctx stops between chunks or rolls back the chunk in flight with its
+		// checkpoint, so a resumed load continues from the last committed chunk
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		tx, err := cl.db.BeginTx(ctx, nil)
+		if err != nil {
+			return result, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn(spec.Table, columns...))
+		if err != nil {
+			tx.Rollback()
+			return result, fmt.Errorf("failed to prepare COPY into %s: %w", spec.Table, err)
+		}
+
+		chunkRows := 0
+		for chunkRows < spec.ChunkSize {
+			record, raw, err := reader.Read()
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			rowNumber++
+			result.RowsRead++
+
+			if err != nil {
+				reject(rowNumber, fmt.Sprintf("unparseable CSV: %v", err), raw)
+				continue
+			}
+			if len(record) < len(header) {
+				reject(rowNumber, fmt.Sprintf("expected %d fields, got %d", len(header), len(record)), raw)
+				continue
+			}
+
+			values, err := spec.MapRow(record, columnMap)
+			if err != nil {
+				reject(rowNumber, err.Error(), raw)
+				continue
+			}
+			if spec.RowNumberColumn != "" {
+				values = append(values, rowNumber)
+			}
+
+			if _, err := stmt.Exec(values...); err != nil {
+				stmt.Close()
+				tx.Rollback()
+				return result, fmt.Errorf("COPY failed at row %d: %w", rowNumber, err)
+			}
+			chunkRows++
+		}
+
+		// Flush the COPY buffer - constraint and type errors surface here
+		if _, err := stmt.Exec(); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return result, fmt.Errorf("COPY chunk ending at row %d failed: %w", rowNumber, err)
+		}
+		if err := stmt.Close(); err != nil {
+			tx.Rollback()
+			return result, fmt.Errorf("failed to close COPY statement: %w", err)
+		}
+
+		cp := checkpoint{
+			byteOffset:   reader.Offset(),
+			rowNumber:    rowNumber,
+			rowsLoaded:   result.RowsLoaded + int64(chunkRows),
+			rowsRejected: result.RowsRejected + int64(len(pendingRejects)),
+			completed:    eof,
+			fileSize:     fileSize,
+			fileModified: fileModified,
+		}
+		if err := cl.saveCheckpoint(ctx, tx, spec.Table, csvPath, cp); err != nil {
+			tx.Rollback()
+			return result, fmt.Errorf("failed to save load checkpoint: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return result, fmt.Errorf("failed to commit chunk ending at row %d: %w", rowNumber, err)
+		}
+
+		if err := rejectWriter.WriteAll(pendingRejects); err != nil {
+			return result, fmt.Errorf("failed to write rejects file: %w", err)
+		}
+		result.RowsLoaded += int64(chunkRows)
+		result.RowsRejected += int64(len(pendingRejects))
+		result.Chunks++
+		pendingRejects = pendingRejects[:0]
+		debug.DebugOutput(localDebug, "Committed chunk %d: %d rows loaded, %d rejected (byte %d)",
+			result.Chunks, result.RowsLoaded, result.RowsRejected, cp.byteOffset)
+	}
+
+	debug.DebugOutput(localDebug, "COPY into %s complete: %d loaded, %d rejected", spec.Table, result.RowsLoaded, result.RowsRejected)
+	return result, nil
+}
+
+// ensureCheckpointTable creates the load checkpoint table
+func (cl *CopyLoader) ensureCheckpointTable(ctx context.Context) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := cl.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS etl_load_checkpoint (
+			table_name     text NOT NULL,
+			file_path      text NOT NULL,
+			byte_offset    bigint NOT NULL DEFAULT 0,
+			row_number     bigint NOT NULL DEFAULT 0,
+			rows_loaded    bigint NOT NULL DEFAULT 0,
+			rows_rejected  bigint NOT NULL DEFAULT 0,
+			completed      boolean NOT NULL DEFAULT false,
+			updated_at     timestamptz DEFAULT now(),
+			PRIMARY KEY (table_name, file_path)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = cl.db.ExecContext(ctx, `
+		ALTER TABLE etl_load_checkpoint
+			ADD COLUMN IF NOT EXISTS file_size bigint,
+			ADD COLUMN IF NOT EXISTS file_modified timestamptz
+	`)
+	return err
+}
+
+// truncate empties the target table of a fresh load
+func (cl *CopyLoader) truncate(ctx context.Context, table string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	if _, err := cl.db.ExecContext(ctx, "TRUNCATE TABLE "+pq.QuoteIdentifier(table)); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", table, err)
+	}
+	return nil
+}
+
+func (cl *CopyLoader) loadCheckpoint(ctx context.Context, table, csvPath string) (*checkpoint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var cp checkpoint
+	var fileSize sql.NullInt64
+	var fileModified sql.NullTime
+	err := cl.db.QueryRowContext(ctx, `
+		SELECT byte_offset, row_number, rows_loaded, rows_rejected, completed, file_size, file_modified
+		FROM etl_load_checkpoint
+		WHERE table_name = $1 AND file_path = $2
+	`, table, csvPath).Scan(&cp.byteOffset, &cp.rowNumber, &cp.rowsLoaded, &cp.rowsRejected, &cp.completed,
+		&fileSize, &fileModified)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp.fileSize, cp.fileModified = fileSize.Int64, fileModified.Time
+	return &cp, nil
+}
+
+func (cl *CopyLoader) clearCheckpoint(ctx context.Context, table, csvPath string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := cl.db.ExecContext(ctx, `DELETE FROM etl_load_checkpoint WHERE table_name = $1 AND file_path = $2`, table, csvPath)
+	return err
+}
+
+func (cl *CopyLoader) saveCheckpoint(ctx context.Context, tx *sql.Tx, table, csvPath string, cp checkpoint) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO etl_load_checkpoint (
+			table_name, file_path, byte_offset, row_number, rows_loaded, rows_rejected, completed,
+			file_size, file_modified, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now())
+		ON CONFLICT (table_name, file_path) DO UPDATE SET
+			byte_offset = EXCLUDED.byte_offset,
+			row_number = EXCLUDED.row_number,
+			rows_loaded = EXCLUDED.rows_loaded,
+			rows_rejected = EXCLUDED.rows_rejected,
+			completed = EXCLUDED.completed,
+			file_size = EXCLUDED.file_size,
+			file_modified = EXCLUDED.file_modified,
+			updated_at = now()
+	`, table, csvPath, cp.byteOffset, cp.rowNumber, cp.rowsLoaded, cp.rowsRejected, cp.completed,
+		cp.fileSize, cp.fileModified)
+	return err
+}
+
+// openRejectsFile creates the rejects file, appending when resuming a load
+func openRejectsFile(path string, appendMode bool) (*os.File, error) {
+	if appendMode {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open rejects file: %w", err)
+		}
+		return f, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rejects file: %w", err)
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"row_number", "reason", "raw_record"})
+	w.Flush()
+	return f, nil
+}
+
+// maxCSVRecordBytes caps a record's raw text. A stray unbalanced quote would otherwise
+// pull the rest of the file into one record.
+const maxCSVRecordBytes = 1 << 20
+
+// errRecordTooLong fails a line whose quoted field never closes within maxCSVRecordBytes
+var errRecordTooLong = errors.New("unbalanced quote: record exceeds the size limit")
+
+// offsetCSVReader reads CSV records one at a time while tracking the exact byte offset
+// consumed, so a load can seek back to a record boundary. Quoted fields may span lines.
+type offsetCSVReader struct {
+	br        *bufio.Reader
+	offset    int64
+	maxRecord int
+	pending   []string // lines read ahead past a record that was too long
+}
+
+func newOffsetCSVReader(r io.Reader) *offsetCSVReader {
+	return newOffsetCSVReaderAt(r, 0)
+}
+
+func newOffsetCSVReaderAt(r io.Reader, offset int64) *offsetCSVReader {
+	return &offsetCSVReader{br: bufio.NewReaderSize(r, 1<<20), offset: offset, maxRecord: maxCSVRecordBytes}
+}
+
+// Offset returns the byte offset of the start of the next record
+func (r *offsetCSVReader) Offset() int64 {
+	return r.offset
+}
+
+// readLine returns the next line, replaying any read ahead
+func (r *offsetCSVReader) readLine() (string, error) {
+	if len(r.pending) > 0 {
+		line := r.pending[0]
+		r.pending = r.pending[1:]
+		return line, nil
+	}
+	return r.br.ReadString('\n')
+}
+
+// Read returns the next record and its raw text; a parse error still returns the raw text.
+// A record whose quotes are still open after maxRecord bytes fails with errRecordTooLong
+// for its first line only, and reading continues from the line after it.
+func (r *offsetCSVReader) Read() ([]string, string, error) {
+	var lines []string
+	size, quotes := 0, 0
+
+	for {
+		line, err := r.readLine()
+		if line != "" {
+			lines = append(lines, line)
+		}
+		size += len(line)
+		quotes += strings.Count(line, `"`)
+
+		if err == io.EOF {
+			if size == 0 {
+				return nil, "", io.EOF
+			}
+			break
+		}
+		if err != nil {
+			r.offset += int64(size)
+			return nil, strings.Join(lines, ""), err
+		}
+
+		// An odd number of quotes means a quoted field continues on the next line
+		if quotes%2 == 0 {
+			break
+		}
+		if size > r.maxRecord {
+			r.pending = append(lines[1:], r.pending...)
+			r.offset += int64(len(lines[0]))
+			return nil, lines[0], errRecordTooLong
+		}
+	}
+
+	text := strings.Join(lines, "")
+	r.offset += int64(len(text))
+	if strings.TrimSpace(text) == "" {
+		return r.Read()
+	}
+
+	record, err := csv.NewReader(strings.NewReader(text)).Read()
+	if err != nil {
+		return nil, text, err
+	}
+	return record, text, nil
+}
@@ -0,0 +1,89 @@
+package etl
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readAll reads every record, returning the records (nil for a failed row) and the offset
+// after each
+func readAll(t *testing.T, r *offsetCSVReader) ([][]string, []int64, []error) {
+	t.Helper()
+	var records [][]string
+	var offsets []int64
+	var errs []error
+	for i := 0; i < 100; i++ {
+		record, _, err := r.Read()
+		if err == io.EOF {
+			return records, offsets, errs
+		}
+		records = append(records, record)
+		offsets = append(offsets, r.Offset())
+		errs = append(errs, err)
+	}
+	t.Fatal("reader did not reach EOF")
+	return nil, nil, nil
+}
+
+func TestOffsetCSVReaderResumesAtRecordBoundaries(t *testing.T) {
+	data := "id,address\n1,\"1 High Street\nAlton\"\n\n2,\"Flat 2, Mill Lane\"\n3,Petersfield"
+
+	records, offsets, errs := readAll(t, newOffsetCSVReader(strings.NewReader(data)))
+	want := []string{"id", "1", "2", "3"}
+	if len(records) != len(want) {
+		t.Fatalf("read %d records, want %d: %q", len(records), len(want), records)
+	}
+	for i, record := range records {
+		if errs[i] != nil || record[0] != want[i] {
+			t.Errorf("record %d = %q, %v; want id %s", i, record, errs[i], want[i])
+		}
+	}
+	if records[1][1] != "1 High Street\nAlton" {
+		t.Errorf("multi-line field = %q", records[1][1])
+	}
+	if offsets[len(offsets)-1] != int64(len(data)) {
+		t.Errorf("final offset = %d, want %d", offsets[len(offsets)-1], len(data))
+	}
+
+	// Resuming from any record's offset reads exactly the records after it
+	for i, offset := range offsets {
+		resumed, _, _ := readAll(t, newOffsetCSVReaderAt(strings.NewReader(data[offset:]), offset))
+		if len(resumed) != len(records)-i-1 {
+			t.Errorf("resumed at %d: read %d records, want %d", offset, len(resumed), len(records)-i-1)
+			continue
+		}
+		for j, record := range resumed {
+			if record[0] != want[i+j+1] {
+				t.Errorf("resumed at %d: record %d = %q, want id %s", offset, j, record, want[i+j+1])
+			}
+		}
+	}
+}
+
+func TestOffsetCSVReaderFailsUnbalancedQuote(t *testing.T) {
+	data := "1,\"unterminated\n2,Alton\n3,Liphook\n4,Petersfield\n"
+
+	r := newOffsetCSVReader(strings.NewReader(data))
+	r.maxRecord = 20
+	records, offsets, errs := readAll(t, r)
+
+	if len(records) != 4 {
+		t.Fatalf("read %d rows, want 4: %q", len(records), records)
+	}
+	if !errors.Is(errs[0], errRecordTooLong) {
+		t.Errorf("first row error = %v, want errRecordTooLong", errs[0])
+	}
+	if offsets[0] != int64(len("1,\"unterminated\n")) {
+		t.Errorf("offset after the failed row = %d, want the end of its line", offsets[0])
+	}
+	for i, id := range []string{"2", "3", "4"} {
+		if errs[i+1] != nil || records[i+1][0] != id {
+			t.Errorf("row %d = %q, %v; want id %s", i+1, records[i+1], errs[i+1], id)
+		}
+	}
+	if offsets[3] != int64(len(data)) {
+		t.Errorf("final offset = %d, want %d", offsets[3], len(data))
+	}
+}
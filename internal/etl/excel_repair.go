@@ -0,0 +1,327 @@
+package etl
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column repairs a mapping can request for values that have been through Excel
+const (
+	RepairUPRN   = "uprn"   // scientific notation, ".0" suffixes, lost/extra leading zeros
+	RepairDate   = "date"   // serial numbers, mixed dd/mm and mm/dd
+	RepairNumber = "number" // thousands separators, scientific notation
+)
+
+// Date orders detected for a column
+const (
+	DateOrderDMY   = "dmy"
+	DateOrderMDY   = "mdy"
+	DateOrderMixed = "mixed" // both forms present: day/month-ambiguous values are rejected
+)
+
+// ImportFlagsColumn carries repair flags from staging through to src_document
+const ImportFlagsColumn = "import_flags"
+
+var (
+	scientificPattern  = regexp.MustCompile(`^([0-9])(?:\.([0-9]+))?[eE]\+?([0-9]+)$`)
+	decimalZeroSuffix  = regexp.MustCompile(`^([0-9]+)\.0+$`)
+	numericDatePattern = regexp.MustCompile(`^([0-9]{1,2})[/.\-]([0-9]{1,2})[/.\-]([0-9]{2}|[0-9]{4})$`)
+	digitsPattern      = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Excel's day zero (serial 1 is 1900-01-01 because of the 1900 leap year bug)
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Plausible document dates; serials outside this window are not dates
+var (
+	minRepairDate = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxRepairDate = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// minExcelSerial is the smallest number read as a serial (1927-05-18); smaller numbers are
+// more likely years or references, and a bare four-digit year is not a date
+const minExcelSerial = 10000
+
+var yearPattern = regexp.MustCompile(`^(1[89]|20)[0-9]{2}$`)
+
+// RepairResult is the outcome of repairing one value
+type RepairResult struct {
+	Value    string // repaired value; empty when rejected
+	Repair   string // repair applied, empty when the value was already clean
+	Rejected bool
+	Pending  bool // needs a database lookup (scientific-notation UPRN)
+}
+
+// Flag renders the result as an import flag for column, or "" when nothing happened
+func (r RepairResult) Flag(column string) string {
+	switch {
+	case r.Rejected:
+		return column + ":rejected_" + r.Repair
+	case r.Repair != "":
+		return column + ":" + r.Repair
+	}
+	return ""
+}
+
+// ScientificRange returns the inclusive range of integers that Excel would display as raw,
+// e.g. 1.00062E+11 covers 100061500000..100062499999. exact is true when no digits were lost.
+func ScientificRange(raw string) (lo, hi int64, exact bool, ok bool) {
+	m := scientificPattern.FindStringSubmatch(strings.TrimSpace(raw))
+	if m == nil {
+		return 0, 0, false, false
+	}
+
+	exponent, err := strconv.Atoi(m[3])
+	if err != nil || exponent > 17 {
+		return 0, 0, false, false
+	}
+	mantissa := strings.TrimRight(m[2], "0")
+	if len(mantissa) > exponent {
+		return 0, 0, false, false // not an integer
+	}
+
+	digits := m[1] + mantissa
+	value, err := strconv.ParseInt(digits+strings.Repeat("0", exponent-len(mantissa)), 10, 64)
+	if err != nil {
+		return 0, 0, false, false
+	}
+
+	// Every digit shown is significant, including trailing zeros
+	lost := exponent - len(m[2])
+	if lost <= 0 {
+		return value, value, true, true
+	}
+
+	half := int64(math.Pow10(lost)) / 2
+	return value - half, value + half - 1, false, true
+}
+
+// RepairExcelUPRN cleans a UPRN. Scientific notation that lost digits is marked Pending so
+// the value can be resolved against OS Open UPRN once loaded.
+func RepairExcelUPRN(raw string) RepairResult {
+	s := strings.TrimSpace(raw)
+	if s == "" || digitsPattern.MatchString(s) && s[0] != '0' {
+		return RepairResult{Value: s}
+	}
+
+	if m := decimalZeroSuffix.FindStringSubmatch(s); m != nil {
+		s = strings.TrimLeft(m[1], "0")
+		return RepairResult{Value: s, Repair: "decimal_suffix"}
+	}
+
+	if digitsPattern.MatchString(s) {
+		trimmed := strings.TrimLeft(s, "0")
+		if trimmed == "" {
+			return RepairResult{Repair: "placeholder", Rejected: true}
+		}
+		return RepairResult{Value: trimmed, Repair: "leading_zeros"}
+	}
+
+	if lo, _, exact, ok := ScientificRange(s); ok {
+		if exact {
+			return RepairResult{Value: strconv.FormatInt(lo, 10), Repair: "scientific_exact"}
+		}
+		// Keep the raw text; ResolveScientificUPRNs replaces it with the unique match
+		return RepairResult{Value: s, Repair: "scientific_notation", Pending: true}
+	}
+
+	return RepairResult{Value: s}
+}
+
+// RepairExcelNumber cleans a numeric value, rejecting scientific notation that lost precision
+func RepairExcelNumber(raw string) RepairResult {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return RepairResult{}
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "eE") {
+		return RepairResult{Value: s}
+	}
+
+	if strings.ContainsAny(s, ", ") {
+		cleaned := strings.NewReplacer(",", "", " ", "").Replace(s)
+		if _, err := strconv.ParseFloat(cleaned, 64); err == nil {
+			return RepairResult{Value: cleaned, Repair: "thousands_separator"}
+		}
+	}
+
+	if lo, _, exact, ok := ScientificRange(s); ok {
+		if !exact {
+			return RepairResult{Repair: "scientific_precision_lost", Rejected: true}
+		}
+		return RepairResult{Value: strconv.FormatInt(lo, 10), Repair: "scientific_exact"}
+	}
+
+	return RepairResult{Value: s}
+}
+
+// RepairExcelDate converts a date to ISO form. Excel serials and month-first values are
+// repaired and flagged; values that only a column convention could settle are rejected
+// when the column mixes both conventions.
+func RepairExcelDate(raw string, formats []string, order string) RepairResult {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return RepairResult{}
+	}
+
+	if t, err := time.Parse(stagingDateLayout, s); err == nil {
+		return RepairResult{Value: t.Format(stagingDateLayout)}
+	}
+
+	if yearPattern.MatchString(s) {
+		return RepairResult{Repair: "year_only", Rejected: true}
+	}
+
+	// Excel serial number, optionally with a time fraction
+	if serial, err := strconv.ParseFloat(s, 64); err == nil {
+		t := excelEpoch.AddDate(0, 0, int(math.Floor(serial)))
+		if serial >= minExcelSerial && t.After(minRepairDate) && t.Before(maxRepairDate) {
+			return RepairResult{Value: t.Format(stagingDateLayout), Repair: "excel_serial"}
+		}
+		return RepairResult{Repair: "not_a_date", Rejected: true}
+	}
+
+	if m := numericDatePattern.FindStringSubmatch(s); m != nil {
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[2])
+		year := expandYear(m[3])
+
+		dayFirst, dayFirstOK := makeDate(year, b, a)
+		monthFirst, monthFirstOK := makeDate(year, a, b)
+
+		switch {
+		case dayFirstOK && monthFirstOK && a != b:
+			switch order {
+			case DateOrderMixed:
+				return RepairResult{Repair: "ambiguous_day_month", Rejected: true}
+			case DateOrderMDY:
+				return RepairResult{Value: monthFirst.Format(stagingDateLayout), Repair: "month_first"}
+			}
+			return RepairResult{Value: dayFirst.Format(stagingDateLayout)}
+		case dayFirstOK:
+			return RepairResult{Value: dayFirst.Format(stagingDateLayout)}
+		case monthFirstOK:
+			return RepairResult{Value: monthFirst.Format(stagingDateLayout), Repair: "month_first"}
+		}
+		return RepairResult{Repair: "invalid_date", Rejected: true}
+	}
+
+	for _, format := range formats {
+		if t, err := time.Parse(goDateLayout(format), s); err == nil {
+			return RepairResult{Value: t.Format(stagingDateLayout)}
+		}
+	}
+
+	return RepairResult{Repair: "unrecognised_date", Rejected: true}
+}
+
+// DetectDateOrder classifies a column from its unambiguous values (first part > 12 is
+// day-first, second part > 12 is month-first)
+func DetectDateOrder(values []string) string {
+	dayFirst, monthFirst := 0, 0
+	for _, v := range values {
+		m := numericDatePattern.FindStringSubmatch(strings.TrimSpace(v))
+		if m == nil {
+			continue
+		}
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[2])
+		switch {
+		case a > 12 && b <= 12:
+			dayFirst++
+		case b > 12 && a <= 12:
+			monthFirst++
+		}
+	}
+
+	switch {
+	case dayFirst > 0 && monthFirst > 0:
+		return DateOrderMixed
+	case monthFirst > 0:
+		return DateOrderMDY
+	}
+	return DateOrderDMY
+}
+
+// DetectDateOrders scans a CSV once and sets the date order of each date-repair column
+func (m *SourceMapping) DetectDateOrders(csvPath string) error {
+	var repairCols []int
+	for i, c := range m.Columns {
+		if c.Repair == RepairDate {
+			repairCols = append(repairCols, i)
+		}
+	}
+	if len(repairCols) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columnMap := make(map[string]int)
+	for i, col := range header {
+		columnMap[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))] = i
+	}
+
+	values := make(map[int][]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		for _, i := range repairCols {
+			if idx, ok := columnMap[strings.ToLower(m.Columns[i].CSV)]; ok && idx < len(record) {
+				values[i] = append(values[i], record[idx])
+			}
+		}
+	}
+
+	for _, i := range repairCols {
+		m.Columns[i].dateOrder = DetectDateOrder(values[i])
+	}
+	return nil
+}
+
+// expandYear applies Excel's two-digit year pivot (00-29 -> 20xx, 30-99 -> 19xx)
+func expandYear(y string) int {
+	year, _ := strconv.Atoi(y)
+	if len(y) == 2 {
+		if year < 30 {
+			return 2000 + year
+		}
+		return 1900 + year
+	}
+	return year
+}
+
+func makeDate(year, month, day int) (time.Time, bool) {
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, false // e.g. 31/02
+	}
+	return t, true
+}
@@ -0,0 +1,162 @@
+package etl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScientificRange(t *testing.T) {
+	tests := []struct {
+		raw       string
+		lo, hi    int64
+		exact, ok bool
+	}{
+		{"1.00062E+11", 100061500000, 100062499999, false, true},
+		{"1.710030787E+9", 1710030787, 1710030787, true, true},
+		{"1.7100307E+09", 1710030650, 1710030749, false, true},
+		{"1.5e-3", 0, 0, false, false},
+		{"10062", 0, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		lo, hi, exact, ok := ScientificRange(tt.raw)
+		if lo != tt.lo || hi != tt.hi || exact != tt.exact || ok != tt.ok {
+			t.Errorf("ScientificRange(%q) = (%d, %d, %v, %v), want (%d, %d, %v, %v)",
+				tt.raw, lo, hi, exact, ok, tt.lo, tt.hi, tt.exact, tt.ok)
+		}
+	}
+}
+
+func TestRepairExcelUPRN(t *testing.T) {
+	tests := []struct {
+		raw, value, flag string
+		pending          bool
+	}{
+		{"1710030787", "1710030787", "", false},
+		{"1710030787.0", "1710030787", "uprn:decimal_suffix", false},
+		{"001710030787", "1710030787", "uprn:leading_zeros", false},
+		{"0000", "", "uprn:rejected_placeholder", false},
+		{"1.710030787E+9", "1710030787", "uprn:scientific_exact", false},
+		{"1.00062E+11", "1.00062E+11", "uprn:scientific_notation", true},
+	}
+
+	for _, tt := range tests {
+		r := RepairExcelUPRN(tt.raw)
+		if r.Value != tt.value || r.Flag("uprn") != tt.flag || r.Pending != tt.pending {
+			t.Errorf("RepairExcelUPRN(%q) = %+v, want value %q flag %q", tt.raw, r, tt.value, tt.flag)
+		}
+	}
+}
+
+func TestRepairExcelDate(t *testing.T) {
+	formats := []string{"D/M/YYYY", "D/M/YY"}
+	tests := []struct {
+		raw, order, value, repair string
+		rejected                  bool
+	}{
+		{"9/9/85", DateOrderDMY, "1985-09-09", "", false},
+		{"25/12/1990", DateOrderDMY, "1990-12-25", "", false},
+		{"12/25/1990", DateOrderDMY, "1990-12-25", "month_first", false},
+		{"03/04/1990", DateOrderMDY, "1990-03-04", "month_first", false},
+		{"03/04/1990", DateOrderMixed, "", "ambiguous_day_month", true},
+		{"33970", DateOrderDMY, "1993-01-01", "excel_serial", false},
+		{"1985", DateOrderDMY, "", "year_only", true},
+		{"365", DateOrderDMY, "", "not_a_date", true},
+		{"31/02/1990", DateOrderDMY, "", "invalid_date", true},
+		{"pending", DateOrderDMY, "", "unrecognised_date", true},
+	}
+
+	for _, tt := range tests {
+		r := RepairExcelDate(tt.raw, formats, tt.order)
+		if r.Value != tt.value || r.Repair != tt.repair || r.Rejected != tt.rejected {
+			t.Errorf("RepairExcelDate(%q, %s) = %+v, want %q/%q", tt.raw, tt.order, r, tt.value, tt.repair)
+		}
+	}
+}
+
+func TestDetectDateOrder(t *testing.T) {
+	if got := DetectDateOrder([]string{"1/2/90", "25/12/90"}); got != DateOrderDMY {
+		t.Errorf("day-first column detected as %s", got)
+	}
+	if got := DetectDateOrder([]string{"1/2/90", "12/25/90"}); got != DateOrderMDY {
+		t.Errorf("month-first column detected as %s", got)
+	}
+	if got := DetectDateOrder([]string{"25/12/90", "12/25/90"}); got != DateOrderMixed {
+		t.Errorf("mixed column detected as %s", got)
+	}
+}
+
+func TestRepairExcelNumber(t *testing.T) {
+	if r := RepairExcelNumber("474,793"); r.Value != "474793" || r.Repair != "thousands_separator" {
+		t.Errorf("thousands separator not repaired: %+v", r)
+	}
+	if r := RepairExcelNumber("4.74793E+5"); r.Value != "474793" || r.Repair != "scientific_exact" {
+		t.Errorf("exact scientific not repaired: %+v", r)
+	}
+	if r := RepairExcelNumber("4.75E+5"); !r.Rejected {
+		t.Errorf("lossy scientific not rejected: %+v", r)
+	}
+}
+
+func TestPipelineRepairFloatFlags(t *testing.T) {
+	p := &Pipeline{}
+	var flags []string
+	if v, err := p.repairFloat("easting", "474,793", &flags); err != nil || v != 474793.0 {
+		t.Errorf("repairFloat() = %v, %v", v, err)
+	}
+	if v, err := p.repairFloat("northing", "", &flags); err != nil || v != nil {
+		t.Errorf("empty value = %v, %v, want NULL", v, err)
+	}
+	if _, err := p.repairFloat("northing", "1.23E+5", &flags); err == nil {
+		t.Error("lossy scientific northing loaded instead of rejecting the row")
+	}
+	if strings.Join(flags, ";") != "easting:thousands_separator;northing:rejected_scientific_precision_lost" {
+		t.Errorf("flags = %v", flags)
+	}
+}
+
+func TestStageRecordFlagsRepairs(t *testing.T) {
+	mapping, err := LoadSourceMapping("agreement")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columnMap := map[string]int{
+		"job number": 0, "filepath": 1, "address": 2, "date": 3,
+		"bs7666uprn": 4, "easting": 5, "northing": 6,
+	}
+	record := []string{"JN1", "a.pdf", "12 SUSSEX ROAD, PETERSFIELD", "31412", "001710030787.0", "474793", "122942"}
+
+	staged, err := mapping.StageRecord(record, columnMap)
+	if err != nil {
+		t.Fatalf("StageRecord() error: %v", err)
+	}
+	if staged["date"] != "1985-12-31" || staged["bs7666uprn"] != "1710030787" {
+		t.Errorf("values not repaired: %v", staged)
+	}
+	if staged[ImportFlagsColumn] != "date:excel_serial;bs7666uprn:decimal_suffix" {
+		t.Errorf("import_flags = %q", staged[ImportFlagsColumn])
+	}
+}
+
+func TestScientificOutcome(t *testing.T) {
+	tests := []struct {
+		candidates []string
+		want       string
+	}{
+		{nil, "rejected_scientific_notation"},
+		{[]string{"100062000002"}, "scientific_resolved"},
+		// Several fit, however near or well known one of them is: picking one would be a guess
+		{[]string{"100062000001", "100062000002"}, "rejected_ambiguous"},
+	}
+	for _, tt := range tests {
+		if got := scientificOutcome(tt.candidates); got != tt.want {
+			t.Errorf("scientificOutcome(%v) = %q, want %q", tt.candidates, got, tt.want)
+		}
+	}
+
+	ranges := digitRanges(99999999995, 100000000004)
+	if len(ranges) != 2 || ranges[0][1] != "99999999999" || ranges[1][0] != "100000000000" {
+		t.Errorf("digitRanges() = %v", ranges)
+	}
+}
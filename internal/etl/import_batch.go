@@ -0,0 +1,310 @@
+package etl
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+)
+
+// LoaderVersion is recorded on every import batch. Bump it when staging or transform
+// logic changes so that re-importing an unchanged file is not skipped.
+const LoaderVersion = "etl-2026.10"
+
+// Import batch statuses
+const (
+	BatchRunning   = "running"
+	BatchCompleted = "completed"
+	BatchFailed    = "failed"
+)
+
+// ImportBatch records one load of a source file and what it changed in src_document
+type ImportBatch struct {
+	BatchID       int64
+	SourceType    string
+	FilePath      string
+	FileSHA256    string
+	FileSize      int64
+	LoaderVersion string
+	RowCount      int64 // CSV records read
+	RowsRejected  int64
+	Added         int64
+	Changed       int64
+	Removed       int64
+	Unchanged     int64
+
+	// Skipped is set when the file matches the latest completed batch; nothing was loaded
+	Skipped    bool
+	SkippedFor int64
+}
+
+// Summary describes the batch outcome in one line
+func (b *ImportBatch) Summary() string {
+	if b.Skipped {
+		return fmt.Sprintf("%s unchanged since batch %d (sha256 %s), nothing to do", b.FilePath, b.SkippedFor, b.FileSHA256[:12])
+	}
+	return fmt.Sprintf("batch %d: %d rows read, %d added, %d changed, %d removed, %d unchanged, %d rejected",
+		b.BatchID, b.RowCount, b.Added, b.Changed, b.Removed, b.Unchanged, b.RowsRejected)
+}
+
+// FileSHA256 hashes a file, returning the hex digest and size
+func FileSHA256(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// startBatch hashes csvPath and opens a batch, or returns a skipped batch when the latest
+// completed batch for the source type loaded the same file with the same loader
+func (p *Pipeline) startBatch(ctx context.Context, localDebug bool, sourceType, csvPath string) (*ImportBatch, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	absPath, err := filepath.Abs(csvPath)
+	if err != nil {
+		absPath = csvPath
+	}
+
+	sum, size, err := FileSHA256(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	batch := &ImportBatch{
+		SourceType:    sourceType,
+		FilePath:      absPath,
+		FileSHA256:    sum,
+		FileSize:      size,
+		LoaderVersion: LoaderVersion,
+	}
+
+	var lastID int64
+	var lastSHA, lastVersion string
+	err = p.db.QueryRowContext(ctx, `
+		SELECT batch_id, file_sha256, loader_version
+		FROM import_batch
+		WHERE source_type = $1 AND status = $2
+		ORDER BY batch_id DESC
+		LIMIT 1
+	`, sourceType, BatchCompleted).Scan(&lastID, &lastSHA, &lastVersion)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read previous import batch: %w", err)
+	}
+	if err == nil && lastSHA == sum && lastVersion == LoaderVersion {
+		debug.DebugOutput(localDebug, "%s matches batch %d, skipping", csvPath, lastID)
+		batch.Skipped = true
+		batch.SkippedFor = lastID
+		return batch, nil
+	}
+
+	err = p.db.QueryRowContext(ctx, `
+		INSERT INTO import_batch (source_type, file_path, file_sha256, file_size, loader_version, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING batch_id
+	`, sourceType, absPath, sum, size, LoaderVersion, BatchRunning).Scan(&batch.BatchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import batch: %w", err)
+	}
+
+	debug.DebugOutput(localDebug, "Started import batch %d for %s (sha256 %s)", batch.BatchID, absPath, sum)
+	return batch, nil
+}
+
+// finishBatch records the batch outcome
+func (p *Pipeline) finishBatch(ctx context.Context, batch *ImportBatch, status string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		UPDATE import_batch
+		SET status = $2, row_count = $3, rows_rejected = $4,
+		    rows_added = $5, rows_changed = $6, rows_removed = $7, rows_unchanged = $8,
+		    completed_at = now()
+		WHERE batch_id = $1
+	`, batch.BatchID, status, batch.RowCount, batch.RowsRejected,
+		batch.Added, batch.Changed, batch.Removed, batch.Unchanged)
+	if err != nil {
+		return fmt.Errorf("failed to update import batch %d: %w", batch.BatchID, err)
+	}
+	return nil
+}
+
+// upsertDocuments merges the staged rows into src_document by natural key in one transaction.
+// Rows missing from the file are marked removed rather than deleted so their match decisions survive.
+func (p *Pipeline) upsertDocuments(ctx context.Context, localDebug bool, mapping *SourceMapping, batch *ImportBatch) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin document upsert: %w", err)
+	}
+	defer tx.Rollback()
+
+	sourceType := mapping.SourceType
+	keyExpr := naturalKeySQL(mapping.NaturalKey)
+
+	// Documents loaded before lineage tracking get their key and hash from their current values
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE src_document d
+		SET natural_key = k.natural_key, row_hash = %s
+		FROM (
+			SELECT src_id, key || CASE WHEN n > 1 THEN '#' || n ELSE '' END AS natural_key
+			FROM (
+				SELECT src_id, %s AS key,
+				       row_number() OVER (PARTITION BY %s ORDER BY src_id) AS n
+				FROM src_document
+				WHERE source_type = $1 AND natural_key IS NULL
+			) keyed
+		) k
+		WHERE d.src_id = k.src_id
+	`, rowHashSQL("d"), keyExpr, keyExpr), sourceType)
+	if err != nil {
+		return fmt.Errorf("failed to backfill natural keys: %w", err)
+	}
+
+	// Stage the file's documents with src_document's column types so hashes compare exactly
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TEMP TABLE incoming_document ON COMMIT DROP AS
+		SELECT source_type, %s, %s, %s, natural_key, row_hash
+		FROM src_document
+		WITH NO DATA
+	`, strings.Join(documentFields, ", "), ImportFlagsColumn, SourceRowColumn))
+	if err != nil {
+		return fmt.Errorf("failed to create incoming document table: %w", err)
+	}
+
+	query, args := mapping.DocumentInsertSQL("incoming_document")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to transform %s to documents: %w", sourceType, err)
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE incoming_document i
+		SET natural_key = k.natural_key, row_hash = %s
+		FROM (
+			SELECT source_row, key || CASE WHEN n > 1 THEN '#' || n ELSE '' END AS natural_key
+			FROM (
+				SELECT source_row, %s AS key,
+				       row_number() OVER (PARTITION BY %s ORDER BY source_row) AS n
+				FROM incoming_document
+			) keyed
+		) k
+		WHERE i.source_row = k.source_row
+	`, rowHashSQL("i"), keyExpr, keyExpr))
+	if err != nil {
+		return fmt.Errorf("failed to key incoming documents: %w", err)
+	}
+
+	// Changed (or reappearing) rows take the new values; canonical addresses are
+	// recomputed only where the raw address changed
+	var sets []string
+	for _, f := range documentFields {
+		sets = append(sets, fmt.Sprintf("%s = i.%s", f, f))
+	}
+	changed, err := execCount(ctx, tx, fmt.Sprintf(`
+		UPDATE src_document d
+		SET %s,
+		    addr_can = CASE WHEN d.raw_address IS DISTINCT FROM i.raw_address THEN NULL ELSE d.addr_can END,
+		    postcode_text = CASE WHEN d.raw_address IS DISTINCT FROM i.raw_address THEN NULL ELSE d.postcode_text END,
+		    import_flags = i.import_flags,
+		    row_hash = i.row_hash,
+		    import_batch_id = $2,
+		    source_row = i.source_row,
+		    removed_at = NULL,
+		    removed_batch_id = NULL
+		FROM incoming_document i
+		WHERE d.source_type = $1
+		  AND d.natural_key = i.natural_key
+		  AND (d.row_hash IS DISTINCT FROM i.row_hash OR d.removed_at IS NOT NULL)
+	`, strings.Join(sets, ",\n\t\t    ")), sourceType, batch.BatchID)
+	if err != nil {
+		return fmt.Errorf("failed to update changed documents: %w", err)
+	}
+
+	// Unchanged rows only move to the new batch (their row number may have shifted)
+	unchanged, err := execCount(ctx, tx, `
+		UPDATE src_document d
+		SET import_batch_id = $2, source_row = i.source_row
+		FROM incoming_document i
+		WHERE d.source_type = $1
+		  AND d.natural_key = i.natural_key
+		  AND d.import_batch_id IS DISTINCT FROM $2
+	`, sourceType, batch.BatchID)
+	if err != nil {
+		return fmt.Errorf("failed to update unchanged documents: %w", err)
+	}
+
+	added, err := execCount(ctx, tx, fmt.Sprintf(`
+		INSERT INTO src_document (source_type, %s, %s, %s, natural_key, row_hash, import_batch_id)
+		SELECT i.source_type, %s, i.%s, i.%s, i.natural_key, i.row_hash, $2
+		FROM incoming_document i
+		WHERE NOT EXISTS (
+			SELECT 1 FROM src_document d
+			WHERE d.source_type = $1 AND d.natural_key = i.natural_key
+		)
+	`, strings.Join(documentFields, ", "), ImportFlagsColumn, SourceRowColumn,
+		"i."+strings.Join(documentFields, ", i."), ImportFlagsColumn, SourceRowColumn), sourceType, batch.BatchID)
+	if err != nil {
+		return fmt.Errorf("failed to insert new documents: %w", err)
+	}
+
+	removed, err := execCount(ctx, tx, `
+		UPDATE src_document d
+		SET removed_at = now(), removed_batch_id = $2
+		WHERE d.source_type = $1
+		  AND d.removed_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM incoming_document i WHERE i.natural_key = d.natural_key)
+	`, sourceType, batch.BatchID)
+	if err != nil {
+		return fmt.Errorf("failed to mark removed documents: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit document upsert: %w", err)
+	}
+
+	batch.Added, batch.Changed, batch.Removed, batch.Unchanged = added, changed, removed, unchanged
+	debug.DebugOutput(localDebug, "Upserted %s: %d added, %d changed, %d removed, %d unchanged",
+		sourceType, added, changed, removed, unchanged)
+	return nil
+}
+
+// naturalKeySQL joins the natural key fields into one text key
+func naturalKeySQL(fields []string) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = fmt.Sprintf("coalesce(%s::text, '')", f)
+	}
+	return "concat_ws('|', " + strings.Join(parts, ", ") + ")"
+}
+
+// rowHashSQL hashes a row's document fields; NULL and empty hash differently
+func rowHashSQL(alias string) string {
+	parts := make([]string, len(documentFields))
+	for i, f := range documentFields {
+		parts[i] = fmt.Sprintf("coalesce(%s.%s::text, '\\N')", alias, f)
+	}
+	return "md5(concat_ws(chr(31), " + strings.Join(parts, ", ") + "))"
+}
+
+func execCount(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -1,11 +1,10 @@
 package etl
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"fmt"
-	"io"
-	"os"
 	"strconv"
 	"strings"
 
@@ -22,149 +21,161 @@ func NewOSDataLoader(db *sql.DB) *OSDataLoader {
 	return &OSDataLoader{db: db}
 }
 
-// LoadOSOpenUPRN loads OS Open UPRN data for validation purposes
-func (osl *OSDataLoader) LoadOSOpenUPRN(localDebug bool, csvPath string, batchSize int) error {
+// LoadOSOpenUPRN streams OS Open UPRN data into the unconstrained stg_os_uprn with COPY,
+// then replaces os_uprn_reference from it with INSERT ... ON CONFLICT, so a UPRN repeated in
+// the file cannot fail a chunk. Repeats after the first are appended to the rejects file.
+// With resume, a previously interrupted staging load continues from its last committed chunk.
+func (osl *OSDataLoader) LoadOSOpenUPRN(ctx context.Context, localDebug bool, csvPath string, batchSize int, resume bool) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
 	debug.DebugOutput(localDebug, "Loading OS Open UPRN data from: %s", csvPath)
 
 	// Create OS UPRN reference table if it doesn't exist
-	err := osl.createOSUPRNTable(localDebug)
+	err := osl.createOSUPRNTable(ctx, localDebug)
 	if err != nil {
 		return fmt.Errorf("failed to create OS UPRN table: %w", err)
 	}
 
-	// Clear existing data
-	_, err = osl.db.Exec("TRUNCATE TABLE os_uprn_reference")
+	// Staging is emptied unless continuing an interrupted load
+	loader := NewCopyLoader(osl.db)
+	result, err := loader.Load(ctx, localDebug, csvPath, CopySpec{
+		Table:           "stg_os_uprn",
+		Columns:         []string{"uprn", "x_coordinate", "y_coordinate", "latitude", "longitude"},
+		RowNumberColumn: "row_number",
+		ChunkSize:       batchSize,
+		Resume:          resume,
+		Truncate:        true,
+		MapRow: func(record []string, columnMap map[string]int) ([]interface{}, error) {
+			uprn := osl.getColumnValue(record, columnMap, "uprn")
+			xCoord := osl.getColumnValue(record, columnMap, "x_coordinate")
+			yCoord := osl.getColumnValue(record, columnMap, "y_coordinate")
+
+			// Reject records with missing essential data
+			if uprn == "" || xCoord == "" || yCoord == "" {
+				return nil, fmt.Errorf("missing uprn or coordinates")
+			}
+			if _, err := strconv.ParseInt(uprn, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid uprn %q", uprn)
+			}
+
+			return []interface{}{
+				uprn,
+				osl.parseNullableFloat(xCoord),
+				osl.parseNullableFloat(yCoord),
+				osl.parseNullableFloat(osl.getColumnValue(record, columnMap, "latitude")),
+				osl.parseNullableFloat(osl.getColumnValue(record, columnMap, "longitude")),
+			}, nil
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to truncate OS UPRN table: %w", err)
+		return fmt.Errorf("failed to load OS UPRN data: %w", err)
 	}
 
-	file, err := os.Open(csvPath)
+	loaded, duplicates, err := osl.mergeOSUPRNStaging(ctx, result.RejectsPath)
 	if err != nil {
-		return fmt.Errorf("failed to open OS UPRN CSV: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	
-	// Read header
-	header, err := reader.Read()
+	debug.DebugOutput(localDebug, "OS UPRN loading complete: %d records, %d rejected, %d duplicate UPRNs (see %s)",
+		loaded, result.RowsRejected, duplicates, result.RejectsPath)
+
+	// Create indexes for performance
+	err = osl.createOSUPRNIndexes(ctx, localDebug)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
+		debug.DebugOutput(localDebug, "Warning: failed to create OS UPRN indexes: %v", err)
 	}
 
-	debug.DebugOutput(localDebug, "CSV columns: %v", header)
+	return nil
+}
 
-	// Create column mapping
-	columnMap := make(map[string]int)
-	for i, col := range header {
-		columnMap[strings.ToLower(strings.TrimSpace(col))] = i
+// mergeOSUPRNStaging replaces os_uprn_reference with the staged rows in one transaction. The
+// first row for each UPRN wins; the others are appended to the rejects file with the values
+// they staged.
+func (osl *OSDataLoader) mergeOSUPRNStaging(ctx context.Context, rejectsPath string) (loaded, duplicates int64, err error) {
+	tx, err := osl.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin OS UPRN merge: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Prepare batch insert statement
-	stmt, err := osl.db.Prepare(`
-		INSERT INTO os_uprn_reference (
-			uprn, x_coordinate, y_coordinate, latitude, longitude
-		) VALUES ($1, $2, $3, $4, $5)
+	if _, err := tx.ExecContext(ctx, "TRUNCATE TABLE os_uprn_reference"); err != nil {
+		return 0, 0, fmt.Errorf("failed to truncate os_uprn_reference: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO os_uprn_reference (uprn, x_coordinate, y_coordinate, latitude, longitude, geom27700, geom4326)
+		SELECT uprn, x_coordinate, y_coordinate, latitude, longitude,
+		       ST_SetSRID(ST_MakePoint(x_coordinate::float8, y_coordinate::float8), 27700),
+		       CASE WHEN latitude IS NOT NULL AND longitude IS NOT NULL
+		            THEN ST_SetSRID(ST_MakePoint(longitude::float8, latitude::float8), 4326) END
+		FROM stg_os_uprn
+		ORDER BY row_number
+		ON CONFLICT (uprn) DO NOTHING
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to prepare insert statement: %w", err)
+		return 0, 0, fmt.Errorf("failed to merge OS UPRN staging data: %w", err)
 	}
-	defer stmt.Close()
-
-	// Process records in batches
-	recordCount := 0
-	batchCount := 0
-	tx, err := osl.db.Begin()
+	loaded, _ = res.RowsAffected()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT row_number, uprn, first_row,
+		       concat_ws(',', uprn, x_coordinate, y_coordinate, latitude, longitude)
+		FROM (
+			SELECT *, MIN(row_number) OVER (PARTITION BY uprn) AS first_row
+			FROM stg_os_uprn
+			WHERE uprn IN (SELECT uprn FROM stg_os_uprn GROUP BY uprn HAVING COUNT(*) > 1)
+		) s
+		WHERE row_number > first_row
+		ORDER BY row_number
+	`)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, 0, fmt.Errorf("failed to find duplicate OS UPRNs: %w", err)
 	}
-
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error reading CSV record %d: %v", recordCount, err)
-			continue
-		}
-
-		// Extract values using column mapping
-		uprn := osl.getColumnValue(record, columnMap, "uprn")
-		xCoord := osl.getColumnValue(record, columnMap, "x_coordinate")
-		yCoord := osl.getColumnValue(record, columnMap, "y_coordinate")
-		latitude := osl.getColumnValue(record, columnMap, "latitude")
-		longitude := osl.getColumnValue(record, columnMap, "longitude")
-
-		// Skip records with missing essential data
-		if uprn == "" || xCoord == "" || yCoord == "" {
-			continue
-		}
-
-		// Insert record
-		_, err = tx.Stmt(stmt).Exec(
-			uprn,
-			osl.parseNullableFloat(xCoord),
-			osl.parseNullableFloat(yCoord),
-			osl.parseNullableFloat(latitude),
-			osl.parseNullableFloat(longitude),
-		)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error inserting record %d: %v", recordCount, err)
-			continue
-		}
-
-		recordCount++
-		batchCount++
-
-		// Commit batch and start new transaction
-		if batchCount >= batchSize {
-			err = tx.Commit()
-			if err != nil {
-				return fmt.Errorf("failed to commit batch at record %d: %w", recordCount, err)
-			}
-
-			debug.DebugOutput(localDebug, "Committed batch: %d records processed", recordCount)
-
-			tx, err = osl.db.Begin()
-			if err != nil {
-				return fmt.Errorf("failed to begin new transaction: %w", err)
-			}
-			batchCount = 0
+	var rejects [][]string
+	for rows.Next() {
+		var row, first int64
+		var uprn, values string
+		if err := rows.Scan(&row, &uprn, &first, &values); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan duplicate OS UPRN: %w", err)
 		}
+		rejects = append(rejects, []string{strconv.FormatInt(row, 10),
+			fmt.Sprintf("duplicate uprn %s (first on row %d)", uprn, first), values})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read duplicate OS UPRNs: %w", err)
+	}
 
-		if recordCount%100000 == 0 {
-			debug.DebugOutput(localDebug, "Processed %d OS UPRN records", recordCount)
-		}
+	if _, err := tx.ExecContext(ctx, "TRUNCATE TABLE stg_os_uprn"); err != nil {
+		return 0, 0, fmt.Errorf("failed to empty OS UPRN staging: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit OS UPRN merge: %w", err)
 	}
 
-	// Commit final batch
-	if batchCount > 0 {
-		err = tx.Commit()
+	if len(rejects) > 0 {
+		f, err := openRejectsFile(rejectsPath, true)
 		if err != nil {
-			return fmt.Errorf("failed to commit final batch: %w", err)
+			return loaded, int64(len(rejects)), err
+		}
+		defer f.Close()
+		w := csv.NewWriter(f)
+		if err := w.WriteAll(rejects); err != nil {
+			return loaded, int64(len(rejects)), fmt.Errorf("failed to write rejects file: %w", err)
 		}
 	}
 
-	debug.DebugOutput(localDebug, "OS UPRN loading complete: %d records", recordCount)
-
-	// Create indexes for performance
-	err = osl.createOSUPRNIndexes(localDebug)
-	if err != nil {
-		debug.DebugOutput(localDebug, "Warning: failed to create OS UPRN indexes: %v", err)
-	}
-
-	return nil
+	return loaded, int64(len(rejects)), nil
 }
 
-// createOSUPRNTable creates the OS UPRN reference table
-func (osl *OSDataLoader) createOSUPRNTable(localDebug bool) error {
+// createOSUPRNTable creates the OS UPRN reference table and its staging table. Staging has
+// no key, so COPY never fails on a repeated UPRN.
+func (osl *OSDataLoader) createOSUPRNTable(ctx context.Context, localDebug bool) error {
 	debug.DebugOutput(localDebug, "Creating OS UPRN reference table")
 
-	_, err := osl.db.Exec(`
+	_, err := osl.db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS os_uprn_reference (
 			uprn           text PRIMARY KEY,
 			x_coordinate   numeric,
@@ -180,8 +191,22 @@ func (osl *OSDataLoader) createOSUPRNTable(localDebug bool) error {
 		return err
 	}
 
+	_, err = osl.db.ExecContext(ctx, `
+		CREATE UNLOGGED TABLE IF NOT EXISTS stg_os_uprn (
+			row_number     bigint,
+			uprn           text,
+			x_coordinate   numeric,
+			y_coordinate   numeric,
+			latitude       numeric,
+			longitude      numeric
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
 	// Create geometry columns from coordinates
-	_, err = osl.db.Exec(`
+	_, err = osl.db.ExecContext(ctx, `
 		UPDATE os_uprn_reference 
 		SET geom27700 = ST_SetSRID(ST_MakePoint(x_coordinate::float8, y_coordinate::float8), 27700),
 		    geom4326 = ST_SetSRID(ST_MakePoint(longitude::float8, latitude::float8), 4326)
@@ -192,7 +217,7 @@ func (osl *OSDataLoader) createOSUPRNTable(localDebug bool) error {
 }
 
 // createOSUPRNIndexes creates performance indexes
-func (osl *OSDataLoader) createOSUPRNIndexes(localDebug bool) error {
+func (osl *OSDataLoader) createOSUPRNIndexes(ctx context.Context, localDebug bool) error {
 	debug.DebugOutput(localDebug, "Creating OS UPRN indexes")
 
 	indexes := []string{
@@ -203,7 +228,7 @@ func (osl *OSDataLoader) createOSUPRNIndexes(localDebug bool) error {
 	}
 
 	for _, indexSQL := range indexes {
-		_, err := osl.db.Exec(indexSQL)
+		_, err := osl.db.ExecContext(ctx, indexSQL)
 		if err != nil {
 			debug.DebugOutput(localDebug, "Warning: failed to create index: %v", err)
 		}
@@ -213,7 +238,7 @@ func (osl *OSDataLoader) createOSUPRNIndexes(localDebug bool) error {
 }
 
 // ValidateLegacyUPRNs validates source document UPRNs against OS Open UPRN dataset
-func (osl *OSDataLoader) ValidateLegacyUPRNs(localDebug bool) (*ValidationReport, error) {
+func (osl *OSDataLoader) ValidateLegacyUPRNs(ctx context.Context, localDebug bool) (*ValidationReport, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -223,7 +248,7 @@ func (osl *OSDataLoader) ValidateLegacyUPRNs(localDebug bool) (*ValidationReport
 	var report ValidationReport
 
 	// Total source documents with UPRNs
-	err := osl.db.QueryRow(`
+	err := osl.db.QueryRowContext(ctx, `
 		SELECT COUNT(*) 
 		FROM src_document 
 		WHERE uprn_raw IS NOT NULL AND TRIM(uprn_raw) != ''
@@ -233,7 +258,7 @@ func (osl *OSDataLoader) ValidateLegacyUPRNs(localDebug bool) (*ValidationReport
 	}
 
 	// UPRNs found in EHDC LLPG
-	err = osl.db.QueryRow(`
+	err = osl.db.QueryRowContext(ctx, `
 		SELECT COUNT(*) 
 		FROM src_document s
 		INNER JOIN dim_address d ON d.uprn = TRIM(s.uprn_raw)
@@ -244,7 +269,7 @@ func (osl *OSDataLoader) ValidateLegacyUPRNs(localDebug bool) (*ValidationReport
 	}
 
 	// UPRNs found in OS Open UPRN (but not in EHDC LLPG)
-	err = osl.db.QueryRow(`
+	err = osl.db.QueryRowContext(ctx, `
 		SELECT COUNT(*) 
 		FROM src_document s
 		LEFT JOIN dim_address d ON d.uprn = TRIM(s.uprn_raw)
@@ -260,24 +285,38 @@ func (osl *OSDataLoader) ValidateLegacyUPRNs(localDebug bool) (*ValidationReport
 	// Invalid UPRNs (not found in either dataset)
 	report.Invalid = report.TotalWithUPRN - report.ValidInEHDCLLPG - report.ValidInOSOnly
 
+	// Excel artefacts: scientific notation still unresolved, and repairs made during import
+	err = osl.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE uprn_raw ~* '^\s*[0-9](\.[0-9]+)?e\+?[0-9]+\s*$'),
+			COUNT(*) FILTER (WHERE import_flags ~ 'uprn:(decimal_suffix|leading_zeros|scientific_exact|scientific_resolved)'),
+			COUNT(*) FILTER (WHERE import_flags ~ 'uprn:rejected_')
+		FROM src_document
+	`).Scan(&report.ScientificNotation, &report.ExcelRepaired, &report.ExcelRejected)
+	if err != nil {
+		return nil, err
+	}
+
 	debug.DebugOutput(localDebug, "UPRN Validation Report:")
 	debug.DebugOutput(localDebug, "  Total with UPRN: %d", report.TotalWithUPRN)
 	debug.DebugOutput(localDebug, "  Valid in EHDC LLPG: %d", report.ValidInEHDCLLPG) 
 	debug.DebugOutput(localDebug, "  Valid in OS only: %d", report.ValidInOSOnly)
 	debug.DebugOutput(localDebug, "  Invalid: %d", report.Invalid)
+	debug.DebugOutput(localDebug, "  Scientific notation: %d, Excel repaired: %d, rejected: %d",
+		report.ScientificNotation, report.ExcelRepaired, report.ExcelRejected)
 
 	return &report, nil
 }
 
 // EnrichCoordinates enriches EHDC addresses with coordinates from OS data where missing
-func (osl *OSDataLoader) EnrichCoordinates(localDebug bool) error {
+func (osl *OSDataLoader) EnrichCoordinates(ctx context.Context, localDebug bool) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
 	debug.DebugOutput(localDebug, "Enriching EHDC addresses with OS coordinates")
 
 	// Update dim_address with coordinates from OS data where EHDC data is missing or different
-	result, err := osl.db.Exec(`
+	result, err := osl.db.ExecContext(ctx, `
 		UPDATE dim_address 
 		SET 
 			easting = o.x_coordinate,
@@ -324,4 +363,9 @@ type ValidationReport struct {
 	ValidInEHDCLLPG  int `json:"valid_in_ehdc_llpg"`
 	ValidInOSOnly    int `json:"valid_in_os_only"`
 	Invalid          int `json:"invalid"`
+
+	// Excel artefacts
+	ScientificNotation int `json:"scientific_notation"` // still unresolved
+	ExcelRepaired      int `json:"excel_repaired"`
+	ExcelRejected      int `json:"excel_rejected"`
 }
\ No newline at end of file
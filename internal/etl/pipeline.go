@@ -1,11 +1,9 @@
 package etl
 
 import (
+	"context"
 	"database/sql"
-	"encoding/csv"
 	"fmt"
-	"io"
-	"os"
 	"strconv"
 	"strings"
 
@@ -23,121 +21,80 @@ func NewPipeline(db *sql.DB) *Pipeline {
 	return &Pipeline{db: db}
 }
 
-// LoadLLPG loads LLPG data into staging and dimension tables
-func (p *Pipeline) LoadLLPG(localDebug bool, csvPath string) error {
+// LoadLLPG loads LLPG data into staging and rebuilds the dimension table from it. With
+// resume, an interrupted staging load continues from its last committed chunk.
+func (p *Pipeline) LoadLLPG(ctx context.Context, localDebug bool, csvPath string, resume bool) error {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
 	debug.DebugOutput(localDebug, "Loading LLPG from: %s", csvPath)
 
-	// Clear existing data
-	_, err := p.db.Exec("TRUNCATE TABLE stg_llpg CASCADE")
-	if err != nil {
-		return fmt.Errorf("failed to truncate stg_llpg: %w", err)
-	}
-
-	_, err = p.db.Exec("TRUNCATE TABLE dim_address CASCADE")
-	if err != nil {
-		return fmt.Errorf("failed to truncate dim_address: %w", err)
-	}
-
-	// Load staging data
-	file, err := os.Open(csvPath)
-	if err != nil {
-		return fmt.Errorf("failed to open LLPG CSV: %w", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	
-	// Read header
-	header, err := reader.Read()
+	// Stream staging data with COPY, emptying staging unless resuming
+	loader := NewCopyLoader(p.db)
+	result, err := loader.Load(ctx, localDebug, csvPath, CopySpec{
+		Table:    "stg_llpg",
+		Resume:   resume,
+		Truncate: true,
+		Columns: []string{
+			"ogc_fid", "locaddress", "easting", "northing", "lgcstatusc",
+			"bs7666uprn", "bs7666usrn", "landparcel", "blpuclass", "postal",
+			"logicalstatus", "startdate", "enddate", ImportFlagsColumn,
+		},
+		MapRow: func(record []string, columnMap map[string]int) ([]interface{}, error) {
+			var flags []string
+			easting, err := p.repairFloat("easting", p.getColumnValue(record, columnMap, "easting"), &flags)
+			if err != nil {
+				return nil, err
+			}
+			northing, err := p.repairFloat("northing", p.getColumnValue(record, columnMap, "northing"), &flags)
+			if err != nil {
+				return nil, err
+			}
+
+			return []interface{}{
+				p.parseNullableInt(p.getColumnValue(record, columnMap, "ogc_fid")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "locaddress")),
+				easting,
+				northing,
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "lgcstatusc")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "bs7666uprn")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "bs7666usrn")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "landparcel")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "blpuclass")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "postal")),
+				// Lifecycle columns are optional in older extracts
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "logicalstatus")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "startdate")),
+				p.nullIfEmpty(p.getColumnValue(record, columnMap, "enddate")),
+				p.nullIfEmpty(strings.Join(flags, ";")),
+			}, nil
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
+		return fmt.Errorf("failed to load LLPG staging data: %w", err)
 	}
 
-	debug.DebugOutput(localDebug, "CSV columns: %v", header)
-
-	// Create column mapping
-	columnMap := make(map[string]int)
-	for i, col := range header {
-		columnMap[strings.ToLower(col)] = i
-	}
-
-	// Prepare staging insert
-	stagingStmt, err := p.db.Prepare(`
-		INSERT INTO stg_llpg (
-			ogc_fid, locaddress, easting, northing, lgcstatusc,
-			bs7666uprn, bs7666usrn, landparcel, blpuclass, postal
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare staging statement: %w", err)
-	}
-	defer stagingStmt.Close()
-
-	// Process records
-	recordCount := 0
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error reading CSV record %d: %v", recordCount, err)
-			continue
-		}
-
-		// Extract values using column mapping
-		ogcFid := p.getColumnValue(record, columnMap, "ogc_fid")
-		locAddress := p.getColumnValue(record, columnMap, "locaddress")
-		easting := p.getColumnValue(record, columnMap, "easting")
-		northing := p.getColumnValue(record, columnMap, "northing")
-		lgcStatusC := p.getColumnValue(record, columnMap, "lgcstatusc")
-		bs7666UPRN := p.getColumnValue(record, columnMap, "bs7666uprn")
-		bs7666USRN := p.getColumnValue(record, columnMap, "bs7666usrn")
-		landParcel := p.getColumnValue(record, columnMap, "landparcel")
-		blpuClass := p.getColumnValue(record, columnMap, "blpuclass")
-		postal := p.getColumnValue(record, columnMap, "postal")
-
-		// Insert into staging
-		_, err = stagingStmt.Exec(
-			p.parseNullableInt(ogcFid),
-			p.nullIfEmpty(locAddress),
-			p.parseNullableFloat(easting),
-			p.parseNullableFloat(northing),
-			p.nullIfEmpty(lgcStatusC),
-			p.nullIfEmpty(bs7666UPRN),
-			p.nullIfEmpty(bs7666USRN),
-			p.nullIfEmpty(landParcel),
-			p.nullIfEmpty(blpuClass),
-			p.nullIfEmpty(postal),
-		)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error inserting staging record %d: %v", recordCount, err)
-			continue
-		}
-
-		recordCount++
-		if recordCount%1000 == 0 {
-			debug.DebugOutput(localDebug, "Loaded %d staging records", recordCount)
-		}
-	}
-
-	debug.DebugOutput(localDebug, "Loaded %d total staging records", recordCount)
+	debug.DebugOutput(localDebug, "Loaded %d total staging records, %d rejected (see %s)",
+		result.RowsLoaded, result.RowsRejected, result.RejectsPath)
 
 	// Transform to dimension table
-	return p.transformLLPGToDimension(localDebug)
+	return p.transformLLPGToDimension(ctx, localDebug)
 }
 
-// transformLLPGToDimension transforms staging LLPG data to dim_address
-func (p *Pipeline) transformLLPGToDimension(localDebug bool) error {
+// transformLLPGToDimension replaces dim_address with the staged LLPG data
+func (p *Pipeline) transformLLPGToDimension(ctx context.Context, localDebug bool) error {
 	debug.DebugOutput(localDebug, "Transforming LLPG staging data to dimension table")
 
+	_, err := p.db.ExecContext(ctx, "TRUNCATE TABLE dim_address CASCADE")
+	if err != nil {
+		return fmt.Errorf("failed to truncate dim_address: %w", err)
+	}
+
 	// Transform with canonical address generation (PostGIS temporarily disabled)
-	_, err := p.db.Exec(`
+	_, err = p.db.ExecContext(ctx, `
 		INSERT INTO dim_address (
-			uprn, locaddress, easting, northing, usrn, blpu_class, postal_flag
+			uprn, locaddress, easting, northing, usrn, blpu_class, postal_flag,
+			logical_status, start_date, end_date
 		)
 		SELECT 
 			bs7666uprn,
@@ -146,7 +103,16 @@ func (p *Pipeline) transformLLPGToDimension(localDebug bool) error {
 			northing,
 			bs7666usrn,
 			blpuclass,
-			postal
+			postal,
+			COALESCE(NULLIF(logicalstatus, ''), lgcstatusc),
+			CASE
+				WHEN startdate ~ '^\d{4}-\d{2}-\d{2}' THEN to_date(LEFT(startdate, 10), 'YYYY-MM-DD')
+				WHEN startdate ~ '^\d{1,2}/\d{1,2}/\d{4}$' THEN to_date(startdate, 'DD/MM/YYYY')
+			END,
+			CASE
+				WHEN enddate ~ '^\d{4}-\d{2}-\d{2}' THEN to_date(LEFT(enddate, 10), 'YYYY-MM-DD')
+				WHEN enddate ~ '^\d{1,2}/\d{1,2}/\d{4}$' THEN to_date(enddate, 'DD/MM/YYYY')
+			END
 		FROM stg_llpg
 		WHERE bs7666uprn IS NOT NULL 
 		  AND bs7666uprn != ''
@@ -162,7 +128,7 @@ func (p *Pipeline) transformLLPGToDimension(localDebug bool) error {
 
 	// Get count of transformed records
 	var count int
-	err = p.db.QueryRow("SELECT COUNT(*) FROM dim_address").Scan(&count)
+	err = p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dim_address").Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to count dimension records: %w", err)
 	}
@@ -179,7 +145,7 @@ func (p *Pipeline) transformLLPGToDimension(localDebug bool) error {
 	}
 
 	for _, indexSQL := range indexes {
-		_, err = p.db.Exec(indexSQL)
+		_, err = p.db.ExecContext(ctx, indexSQL)
 		if err != nil {
 			debug.DebugOutput(localDebug, "Warning: failed to create index: %v", err)
 		}
@@ -190,397 +156,127 @@ func (p *Pipeline) transformLLPGToDimension(localDebug bool) error {
 	return nil
 }
 
-// LoadSourceDocuments loads source document CSV files into staging and src_document tables
-func (p *Pipeline) LoadSourceDocuments(localDebug bool, sourceType, csvPath string) error {
+// LoadSourceDocuments loads a source CSV into staging and src_document using the source type's
+// mapping file. Each load is recorded as an import batch; an unchanged file is skipped and a
+// changed one is merged by natural key, so match decisions on existing documents are kept.
+// With resume, an interrupted staging load of the same file continues from its last
+// committed chunk.
+func (p *Pipeline) LoadSourceDocuments(ctx context.Context, localDebug bool, sourceType, csvPath string, resume bool) (*ImportBatch, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
 	debug.DebugOutput(localDebug, "Loading source documents: %s from %s", sourceType, csvPath)
 
-	// Load to appropriate staging table first
-	err := p.loadToStaging(localDebug, sourceType, csvPath)
+	mapping, err := LoadSourceMapping(sourceType)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Transform to src_document
-	return p.transformSourceToDocument(localDebug, sourceType)
-}
-
-// loadToStaging loads CSV to appropriate staging table
-func (p *Pipeline) loadToStaging(localDebug bool, sourceType, csvPath string) error {
-	file, err := os.Open(csvPath)
-	if err != nil {
-		return fmt.Errorf("failed to open CSV: %w", err)
+	batch, err := p.startBatch(ctx, localDebug, mapping.SourceType, csvPath)
+	if err != nil || batch.Skipped {
+		return batch, err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	
-	// Read header
-	header, err := reader.Read()
+	err = p.loadSourceBatch(ctx, localDebug, mapping, csvPath, batch, resume)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
+		if finishErr := p.finishBatch(ctx, batch, BatchFailed); finishErr != nil {
+			debug.DebugOutput(localDebug, "Warning: %v", finishErr)
+		}
+		return batch, err
 	}
 
-	debug.DebugOutput(localDebug, "Source CSV columns: %v", header)
+	return batch, p.finishBatch(ctx, batch, BatchCompleted)
+}
 
-	// Get appropriate staging table and statement
-	stagingTable, stmt, err := p.getStagingInsertStatement(sourceType)
+// loadSourceBatch stages the file then merges it into src_document
+func (p *Pipeline) loadSourceBatch(ctx context.Context, localDebug bool, mapping *SourceMapping, csvPath string, batch *ImportBatch, resume bool) error {
+	// Load to the mapped staging table first
+	err := p.loadToStaging(ctx, localDebug, mapping, csvPath, batch, resume)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-
-	debug.DebugOutput(localDebug, "Loading to staging table: %s", stagingTable)
-
-	// Create column mapping
-	columnMap := make(map[string]int)
-	for i, col := range header {
-		columnMap[strings.ToLower(col)] = i
-	}
 
-	// Process records
-	recordCount := 0
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error reading CSV record %d: %v", recordCount, err)
-			continue
-		}
-
-		// Extract values based on source type
-		err = p.insertStagingRecord(sourceType, stmt, record, columnMap)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error inserting staging record %d: %v", recordCount, err)
-			continue
-		}
-
-		recordCount++
-		if recordCount%1000 == 0 {
-			debug.DebugOutput(localDebug, "Loaded %d staging records", recordCount)
-		}
-	}
-
-	debug.DebugOutput(localDebug, "Loaded %d total records to %s", recordCount, stagingTable)
-	return nil
+	// Transform to src_document
+	return p.transformSourceToDocument(ctx, localDebug, mapping, batch)
 }
 
-// getStagingInsertStatement returns the appropriate staging table and prepared statement
-func (p *Pipeline) getStagingInsertStatement(sourceType string) (string, *sql.Stmt, error) {
-	switch sourceType {
-	case "decision":
-		stmt, err := p.db.Prepare(`
-			INSERT INTO stg_decision_notices (
-				job_number, filepath, planning_application_number, adress,
-				decision_date, decision_type, document_type, bs7666uprn, easting, northing
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		`)
-		return "stg_decision_notices", stmt, err
-
-	case "land_charge":
-		stmt, err := p.db.Prepare(`
-			INSERT INTO stg_land_charges_cards (
-				job_number, filepath, card_code, address, bs7666uprn, easting, northing
-			) VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`)
-		return "stg_land_charges_cards", stmt, err
-
-	case "enforcement":
-		stmt, err := p.db.Prepare(`
-			INSERT INTO stg_enforcement_notices (
-				job_number, filepath, planning_enforcement_reference_number, address,
-				date, document_type, bs7666uprn, easting, northing
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		`)
-		return "stg_enforcement_notices", stmt, err
-
-	case "agreement":
-		stmt, err := p.db.Prepare(`
-			INSERT INTO stg_agreements (
-				job_number, filepath, address, date, bs7666uprn, easting, northing
-			) VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`)
-		return "stg_agreements", stmt, err
-
-	default:
-		return "", nil, fmt.Errorf("unknown source type: %s", sourceType)
-	}
-}
+// loadToStaging replaces the staging table's contents with a source CSV, recording each row
+// number. Staging only ever holds the file being imported, so it is emptied unless resuming.
+func (p *Pipeline) loadToStaging(ctx context.Context, localDebug bool, mapping *SourceMapping, csvPath string, batch *ImportBatch, resume bool) error {
+	debug.DebugOutput(localDebug, "Loading to staging table: %s", mapping.StagingTable)
 
-// insertStagingRecord inserts a record into the appropriate staging table
-func (p *Pipeline) insertStagingRecord(sourceType string, stmt *sql.Stmt, record []string, columnMap map[string]int) error {
-	switch sourceType {
-	case "decision":
-		return p.insertDecisionRecord(stmt, record, columnMap)
-	case "land_charge":
-		return p.insertLandChargeRecord(stmt, record, columnMap)
-	case "enforcement":
-		return p.insertEnforcementRecord(stmt, record, columnMap)
-	case "agreement":
-		return p.insertAgreementRecord(stmt, record, columnMap)
-	default:
-		return fmt.Errorf("unknown source type: %s", sourceType)
+	// Date repairs need to know whether the column mixes dd/mm and mm/dd
+	if err := mapping.DetectDateOrders(csvPath); err != nil {
+		return err
 	}
-}
 
-// insertDecisionRecord inserts a decision notice record
-func (p *Pipeline) insertDecisionRecord(stmt *sql.Stmt, record []string, columnMap map[string]int) error {
-	jobNumber := p.getColumnValue(record, columnMap, "job number")
-	filepath := p.getColumnValue(record, columnMap, "filepath")
-	planningAppNum := p.getColumnValue(record, columnMap, "planning application number")
-	address := p.getColumnValue(record, columnMap, "adress") // Note: typo preserved from spec
-	decisionDate := p.getColumnValue(record, columnMap, "decision date")
-	decisionType := p.getColumnValue(record, columnMap, "decision type")
-	documentType := p.getColumnValue(record, columnMap, "document type")
-	uprn := p.getColumnValue(record, columnMap, "bs7666uprn")
-	easting := p.getColumnValue(record, columnMap, "easting")
-	northing := p.getColumnValue(record, columnMap, "northing")
-
-	_, err := stmt.Exec(
-		p.nullIfEmpty(jobNumber),
-		p.nullIfEmpty(filepath),
-		p.nullIfEmpty(planningAppNum),
-		p.nullIfEmpty(address),
-		p.nullIfEmpty(decisionDate),
-		p.nullIfEmpty(decisionType),
-		p.nullIfEmpty(documentType),
-		p.nullIfEmpty(uprn),
-		p.nullIfEmpty(easting),
-		p.nullIfEmpty(northing),
-	)
-	return err
-}
-
-// insertLandChargeRecord inserts a land charge record
-func (p *Pipeline) insertLandChargeRecord(stmt *sql.Stmt, record []string, columnMap map[string]int) error {
-	jobNumber := p.getColumnValue(record, columnMap, "job number")
-	filepath := p.getColumnValue(record, columnMap, "filepath")
-	cardCode := p.getColumnValue(record, columnMap, "card code")
-	address := p.getColumnValue(record, columnMap, "address")
-	uprn := p.getColumnValue(record, columnMap, "bs7666uprn")
-	easting := p.getColumnValue(record, columnMap, "easting")
-	northing := p.getColumnValue(record, columnMap, "northing")
-
-	_, err := stmt.Exec(
-		p.nullIfEmpty(jobNumber),
-		p.nullIfEmpty(filepath),
-		p.nullIfEmpty(cardCode),
-		p.nullIfEmpty(address),
-		p.nullIfEmpty(uprn),
-		p.nullIfEmpty(easting),
-		p.nullIfEmpty(northing),
-	)
-	return err
-}
-
-// insertEnforcementRecord inserts an enforcement notice record
-func (p *Pipeline) insertEnforcementRecord(stmt *sql.Stmt, record []string, columnMap map[string]int) error {
-	jobNumber := p.getColumnValue(record, columnMap, "job number")
-	filepath := p.getColumnValue(record, columnMap, "filepath")
-	enforcementRef := p.getColumnValue(record, columnMap, "planning enforcement reference number")
-	address := p.getColumnValue(record, columnMap, "address")
-	date := p.getColumnValue(record, columnMap, "date")
-	documentType := p.getColumnValue(record, columnMap, "document type")
-	uprn := p.getColumnValue(record, columnMap, "bs7666uprn")
-	easting := p.getColumnValue(record, columnMap, "easting")
-	northing := p.getColumnValue(record, columnMap, "northing")
-
-	_, err := stmt.Exec(
-		p.nullIfEmpty(jobNumber),
-		p.nullIfEmpty(filepath),
-		p.nullIfEmpty(enforcementRef),
-		p.nullIfEmpty(address),
-		p.nullIfEmpty(date),
-		p.nullIfEmpty(documentType),
-		p.nullIfEmpty(uprn),
-		p.nullIfEmpty(easting),
-		p.nullIfEmpty(northing),
-	)
-	return err
-}
+	loader := NewCopyLoader(p.db)
+	result, err := loader.Load(ctx, localDebug, csvPath, CopySpec{
+		Table:           mapping.StagingTable,
+		Resume:          resume,
+		Truncate:        true,
+		Columns:         mapping.StagingColumns(),
+		MapRow:          mapping.StagingRow,
+		RowNumberColumn: SourceRowColumn,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", mapping.StagingTable, err)
+	}
+	batch.RowCount = result.RowsRead
+	batch.RowsRejected = result.RowsRejected
 
-// insertAgreementRecord inserts an agreement record
-func (p *Pipeline) insertAgreementRecord(stmt *sql.Stmt, record []string, columnMap map[string]int) error {
-	jobNumber := p.getColumnValue(record, columnMap, "job number")
-	filepath := p.getColumnValue(record, columnMap, "filepath")
-	address := p.getColumnValue(record, columnMap, "address")
-	date := p.getColumnValue(record, columnMap, "date")
-	uprn := p.getColumnValue(record, columnMap, "bs7666uprn")
-	easting := p.getColumnValue(record, columnMap, "easting")
-	northing := p.getColumnValue(record, columnMap, "northing")
-
-	_, err := stmt.Exec(
-		p.nullIfEmpty(jobNumber),
-		p.nullIfEmpty(filepath),
-		p.nullIfEmpty(address),
-		p.nullIfEmpty(date),
-		p.nullIfEmpty(uprn),
-		p.nullIfEmpty(easting),
-		p.nullIfEmpty(northing),
-	)
-	return err
+	debug.DebugOutput(localDebug, "Loaded %d total records to %s, %d rejected (see %s)",
+		result.RowsLoaded, mapping.StagingTable, result.RowsRejected, result.RejectsPath)
+	return nil
 }
 
-// transformSourceToDocument transforms staging data to src_document
-func (p *Pipeline) transformSourceToDocument(localDebug bool, sourceType string) error {
+// transformSourceToDocument merges staging data into src_document using the mapping's document section
+func (p *Pipeline) transformSourceToDocument(ctx context.Context, localDebug bool, mapping *SourceMapping, batch *ImportBatch) error {
+	sourceType := mapping.SourceType
 	debug.DebugOutput(localDebug, "Transforming %s staging data to src_document", sourceType)
 
-	var sql string
-	switch sourceType {
-	case "decision":
-		sql = `
-			INSERT INTO src_document (
-				source_type, job_number, filepath, external_ref, doc_type, doc_date,
-				raw_address, addr_can, postcode_text, uprn_raw, easting_raw, northing_raw
-			)
-			SELECT 
-				'decision'::source_type,
-				job_number,
-				filepath,
-				planning_application_number,
-				document_type,
-				CASE 
-					WHEN decision_date ~ '^\d{1,2}/\d{1,2}/\d{4}$' 
-					THEN CASE 
-						WHEN (split_part(decision_date, '/', 2)::int > 12 OR split_part(decision_date, '/', 1)::int > 31) 
-						THEN NULL  -- Invalid date
-						ELSE to_date(decision_date, 'DD/MM/YYYY')
-					END
-					WHEN decision_date ~ '^\d{1,2}/\d{1,2}/\d{2}$' 
-					THEN CASE 
-						WHEN (split_part(decision_date, '/', 2)::int > 12 OR split_part(decision_date, '/', 1)::int > 31) 
-						THEN NULL  -- Invalid date
-						ELSE to_date(decision_date, 'DD/MM/YY')
-					END
-					ELSE NULL
-				END,
-				adress as raw_address,
-				$1 as addr_can,
-				$2 as postcode_text,
-				bs7666uprn,
-				easting,
-				northing
-			FROM stg_decision_notices
-			WHERE adress IS NOT NULL AND adress != ''
-		`
-	case "land_charge":
-		sql = `
-			INSERT INTO src_document (
-				source_type, job_number, filepath, external_ref, doc_type, doc_date,
-				raw_address, addr_can, postcode_text, uprn_raw, easting_raw, northing_raw
-			)
-			SELECT 
-				'land_charge'::source_type,
-				job_number,
-				filepath,
-				card_code,
-				'Land Charge Card',
-				NULL,
-				address as raw_address,
-				$1 as addr_can,
-				$2 as postcode_text,
-				bs7666uprn,
-				easting,
-				northing
-			FROM stg_land_charges_cards
-			WHERE address IS NOT NULL AND address != ''
-		`
-	case "enforcement":
-		sql = `
-			INSERT INTO src_document (
-				source_type, job_number, filepath, external_ref, doc_type, doc_date,
-				raw_address, addr_can, postcode_text, uprn_raw, easting_raw, northing_raw
-			)
-			SELECT 
-				'enforcement'::source_type,
-				job_number,
-				filepath,
-				planning_enforcement_reference_number,
-				document_type,
-				CASE 
-					WHEN date ~ '^\d{1,2}/\d{1,2}/\d{4}$' 
-					THEN to_date(date, 'DD/MM/YYYY')
-					WHEN date ~ '^\d{1,2}/\d{1,2}/\d{2}$' 
-					THEN to_date(date, 'DD/MM/YY')
-					ELSE NULL
-				END,
-				address as raw_address,
-				$1 as addr_can,
-				$2 as postcode_text,
-				bs7666uprn,
-				easting,
-				northing
-			FROM stg_enforcement_notices
-			WHERE address IS NOT NULL AND address != ''
-		`
-	case "agreement":
-		sql = `
-			INSERT INTO src_document (
-				source_type, job_number, filepath, external_ref, doc_type, doc_date,
-				raw_address, addr_can, postcode_text, uprn_raw, easting_raw, northing_raw
-			)
-			SELECT 
-				'agreement'::source_type,
-				job_number,
-				filepath,
-				NULL,
-				'Agreement',
-				CASE 
-					WHEN date ~ '^\d{1,2}/\d{1,2}/\d{4}$' 
-					THEN to_date(date, 'DD/MM/YYYY')
-					WHEN date ~ '^\d{1,2}/\d{1,2}/\d{2}$' 
-					THEN to_date(date, 'DD/MM/YY')
-					ELSE NULL
-				END,
-				address as raw_address,
-				$1 as addr_can,
-				$2 as postcode_text,
-				bs7666uprn,
-				easting,
-				northing
-			FROM stg_agreements
-			WHERE address IS NOT NULL AND address != ''
-		`
-	default:
-		return fmt.Errorf("unknown source type: %s", sourceType)
+	if err := p.upsertDocuments(ctx, localDebug, mapping, batch); err != nil {
+		return err
 	}
 
-	// We need to process row by row to generate canonical addresses
-	// This is a simplified version - in practice, you'd batch this
-	_, err := p.db.Exec(sql, "", "") // Placeholder for addr_can and postcode_text
+	// Update canonical addresses and postcodes
+	err := p.updateCanonicalAddresses(ctx, localDebug, sourceType)
 	if err != nil {
-		return fmt.Errorf("failed to transform %s to src_document: %w", sourceType, err)
+		return err
 	}
 
-	// Update canonical addresses and postcodes
-	err = p.updateCanonicalAddresses(localDebug, sourceType)
-	if err != nil {
+	// Classify and repair source coordinates; spatial matching only uses the repaired values
+	if _, err := NewCoordinateValidator(p.db).Validate(ctx, localDebug, sourceType); err != nil {
 		return err
 	}
 
-	// Get count of transformed records
+	// Resolve scientific-notation UPRNs now that repaired coordinates are available
+	if mapping.HasRepairs() {
+		if _, err := NewUPRNRepairer(p.db).ResolveScientificUPRNs(ctx, localDebug, sourceType); err != nil {
+			return err
+		}
+	}
+
+	// Get count of current records
 	var count int
-	err = p.db.QueryRow("SELECT COUNT(*) FROM src_document WHERE source_type = $1", sourceType).Scan(&count)
+	err = p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM src_document WHERE source_type = $1 AND removed_at IS NULL", sourceType).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to count transformed records: %w", err)
 	}
 
-	debug.DebugOutput(localDebug, "Transformed %d %s records to src_document", count, sourceType)
+	debug.DebugOutput(localDebug, "%d current %s records in src_document", count, sourceType)
 	return nil
 }
 
 // updateCanonicalAddresses updates addr_can and postcode_text fields
-func (p *Pipeline) updateCanonicalAddresses(localDebug bool, sourceType string) error {
+func (p *Pipeline) updateCanonicalAddresses(ctx context.Context, localDebug bool, sourceType string) error {
 	debug.DebugOutput(localDebug, "Updating canonical addresses for %s", sourceType)
 
-	rows, err := p.db.Query(`
+	rows, err := p.db.QueryContext(ctx, `
 		SELECT src_id, raw_address 
 		FROM src_document 
 		WHERE source_type = $1 
+		  AND raw_address IS NOT NULL
 		  AND (addr_can IS NULL OR addr_can = '' OR postcode_text IS NULL OR postcode_text = '')
 	`, sourceType)
 	if err != nil {
@@ -653,12 +349,22 @@ func (p *Pipeline) parseNullableInt(s string) interface{} {
 	return nil
 }
 
-func (p *Pipeline) parseNullableFloat(s string) interface{} {
-	if s == "" {
-		return nil
+// repairFloat parses a number through RepairExcelNumber, recording any repair in flags as
+// column:repair. A value the repair rejects rejects the row rather than loading as NULL.
+func (p *Pipeline) repairFloat(column, s string, flags *[]string) (interface{}, error) {
+	repaired := RepairExcelNumber(s)
+	if flag := repaired.Flag(column); flag != "" {
+		*flags = append(*flags, flag)
 	}
-	if f, err := strconv.ParseFloat(s, 64); err == nil {
-		return f
+	if repaired.Rejected {
+		return nil, fmt.Errorf("%s: rejected %s value %q", column, repaired.Repair, s)
 	}
-	return nil
+	if repaired.Value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(repaired.Value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid number %q", column, s)
+	}
+	return f, nil
 }
\ No newline at end of file
@@ -0,0 +1,473 @@
+package etl
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ehdc-llpg/internal/config"
+)
+
+// Built-in source mappings; SOURCE_MAPPINGS_DIR can supply additional or replacement files
+//
+//go:embed mappings/*.json
+var builtinMappings embed.FS
+
+// Staging column types supported in mapping files
+const (
+	ColumnTypeText  = "text"
+	ColumnTypeInt   = "int"
+	ColumnTypeFloat = "float"
+	ColumnTypeDate  = "date"
+)
+
+// stagingDateLayout is how parsed dates are written to the (text) staging columns
+const stagingDateLayout = "2006-01-02"
+
+// documentFields are the src_document columns a mapping may populate, in insert order
+var documentFields = []string{
+	"job_number", "filepath", "external_ref", "doc_type", "doc_date",
+	"raw_address", "uprn_raw", "easting_raw", "northing_raw",
+}
+
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// SourceMapping declares how one source CSV is staged and transformed into src_document
+type SourceMapping struct {
+	SourceType     string                   `json:"source_type"`
+	Description    string                   `json:"description,omitempty"`
+	StagingTable   string                   `json:"staging_table"`
+	RequireAddress bool                     `json:"require_address"`
+	Columns        []ColumnMapping          `json:"columns"`
+	Document       map[string]DocumentField `json:"document"`
+
+	// NaturalKey lists the document fields identifying a row across re-imports
+	// (default job_number, filepath)
+	NaturalKey []string `json:"natural_key,omitempty"`
+}
+
+// defaultNaturalKey identifies a scanned document by its job and file
+var defaultNaturalKey = []string{"job_number", "filepath"}
+
+// SourceRowColumn holds each staged record's CSV row number
+const SourceRowColumn = "source_row"
+
+// ColumnMapping maps a CSV column to a staging column
+type ColumnMapping struct {
+	CSV         string   `json:"csv"`                    // CSV header, case-insensitive
+	Column      string   `json:"column"`                 // staging column
+	Type        string   `json:"type,omitempty"`         // text (default), int, float, date
+	DateFormats []string `json:"date_formats,omitempty"` // e.g. "DD/MM/YYYY", "D/M/YY"
+	Transforms  []string `json:"transforms,omitempty"`   // applied in order before type conversion
+	Required    bool     `json:"required,omitempty"`     // reject the row if missing or unparseable
+	Repair      string   `json:"repair,omitempty"`       // Excel artefact repair: uprn, date or number
+
+	dateOrder string // detected by DetectDateOrders before a load
+}
+
+// DocumentField sets a src_document column from a staging column or a constant
+type DocumentField struct {
+	Column     string   `json:"column,omitempty"`
+	Value      string   `json:"value,omitempty"`
+	Transforms []string `json:"transforms,omitempty"`
+}
+
+// LoadSourceMapping loads the mapping for a source type, preferring SOURCE_MAPPINGS_DIR over built-ins
+func LoadSourceMapping(sourceType string) (*SourceMapping, error) {
+	if !identifierPattern.MatchString(sourceType) {
+		return nil, fmt.Errorf("invalid source type: %s", sourceType)
+	}
+
+	var data []byte
+	var err error
+
+	if dir := config.Current().Paths.SourceMappingsDir; dir != "" {
+		data, err = os.ReadFile(filepath.Join(dir, sourceType+".json"))
+	}
+	if data == nil {
+		data, err = builtinMappings.ReadFile("mappings/" + sourceType + ".json")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no mapping for source type %s: %w", sourceType, err)
+	}
+
+	var m SourceMapping
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping for %s: %w", sourceType, err)
+	}
+	if m.SourceType == "" {
+		m.SourceType = sourceType
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mapping for %s: %w", sourceType, err)
+	}
+
+	return &m, nil
+}
+
+// ListSourceMappings returns the source types with a mapping available
+func ListSourceMappings() []string {
+	seen := make(map[string]bool)
+
+	if entries, err := builtinMappings.ReadDir("mappings"); err == nil {
+		for _, e := range entries {
+			seen[strings.TrimSuffix(e.Name(), ".json")] = true
+		}
+	}
+	if dir := config.Current().Paths.SourceMappingsDir; dir != "" {
+		if matches, err := filepath.Glob(filepath.Join(dir, "*.json")); err == nil {
+			for _, m := range matches {
+				seen[strings.TrimSuffix(filepath.Base(m), ".json")] = true
+			}
+		}
+	}
+
+	var types []string
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks identifiers, types and transforms so mapping files can't inject SQL
+func (m *SourceMapping) Validate() error {
+	if !identifierPattern.MatchString(m.SourceType) {
+		return fmt.Errorf("invalid source_type %q", m.SourceType)
+	}
+	if !identifierPattern.MatchString(m.StagingTable) {
+		return fmt.Errorf("invalid staging_table %q", m.StagingTable)
+	}
+	if len(m.Columns) == 0 {
+		return fmt.Errorf("no columns declared")
+	}
+
+	staged := make(map[string]bool)
+	for _, c := range m.Columns {
+		if !identifierPattern.MatchString(c.Column) {
+			return fmt.Errorf("invalid staging column %q", c.Column)
+		}
+		switch c.Type {
+		case "", ColumnTypeText, ColumnTypeInt, ColumnTypeFloat:
+		case ColumnTypeDate:
+			if len(c.DateFormats) == 0 {
+				return fmt.Errorf("date column %s has no date_formats", c.Column)
+			}
+		default:
+			return fmt.Errorf("unknown type %q for column %s", c.Type, c.Column)
+		}
+		for _, t := range c.Transforms {
+			if _, ok := transforms[t]; !ok {
+				return fmt.Errorf("unknown transform %q for column %s", t, c.Column)
+			}
+		}
+		switch c.Repair {
+		case "":
+		case RepairUPRN:
+			if c.Type != "" && c.Type != ColumnTypeText {
+				return fmt.Errorf("uprn repair on column %s requires type text", c.Column)
+			}
+		case RepairDate:
+			if c.Type != ColumnTypeDate {
+				return fmt.Errorf("date repair on column %s requires type date", c.Column)
+			}
+		case RepairNumber:
+			if c.Type != ColumnTypeInt && c.Type != ColumnTypeFloat {
+				return fmt.Errorf("number repair on column %s requires type int or float", c.Column)
+			}
+		default:
+			return fmt.Errorf("unknown repair %q for column %s", c.Repair, c.Column)
+		}
+		if c.Column == ImportFlagsColumn || c.Column == SourceRowColumn {
+			return fmt.Errorf("staging column %s is reserved", c.Column)
+		}
+		staged[c.Column] = true
+	}
+
+	allowed := make(map[string]bool)
+	for _, f := range documentFields {
+		allowed[f] = true
+	}
+	for name, f := range m.Document {
+		if !allowed[name] {
+			return fmt.Errorf("unknown document field %q", name)
+		}
+		if f.Column != "" && !staged[f.Column] {
+			return fmt.Errorf("document field %s refers to undeclared column %q", name, f.Column)
+		}
+		for _, t := range f.Transforms {
+			if _, ok := sqlTransforms[t]; !ok {
+				return fmt.Errorf("transform %q is not supported for document field %s", t, name)
+			}
+		}
+	}
+	if m.RequireAddress && m.Document["raw_address"].Column == "" {
+		return fmt.Errorf("require_address set but raw_address has no column")
+	}
+
+	if len(m.NaturalKey) == 0 {
+		m.NaturalKey = defaultNaturalKey
+	}
+	for _, name := range m.NaturalKey {
+		if _, ok := m.Document[name]; !ok {
+			return fmt.Errorf("natural_key field %q is not a mapped document field", name)
+		}
+	}
+
+	return nil
+}
+
+// HasRepairs reports whether any column requests Excel artefact repair
+func (m *SourceMapping) HasRepairs() bool {
+	for _, c := range m.Columns {
+		if c.Repair != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// StagingColumns returns the staging columns in declaration order, followed by
+// import_flags when the mapping repairs values
+func (m *SourceMapping) StagingColumns() []string {
+	cols := make([]string, len(m.Columns))
+	for i, c := range m.Columns {
+		cols[i] = c.Column
+	}
+	if m.HasRepairs() {
+		cols = append(cols, ImportFlagsColumn)
+	}
+	return cols
+}
+
+// StageRecord converts a CSV record into staging values keyed by staging column.
+// Unparseable optional values become empty; unparseable required values reject the row.
+// Excel repairs are recorded in import_flags as column:repair entries.
+func (m *SourceMapping) StageRecord(record []string, columnMap map[string]int) (map[string]string, error) {
+	values := make(map[string]string, len(m.Columns)+1)
+	var flags []string
+
+	for _, c := range m.Columns {
+		raw := ""
+		if idx, ok := columnMap[strings.ToLower(c.CSV)]; ok && idx < len(record) {
+			raw = strings.TrimSpace(record[idx])
+		}
+
+		for _, t := range c.Transforms {
+			raw = transforms[t](raw)
+		}
+
+		if c.Repair != "" {
+			repaired := repairValue(raw, c)
+			if flag := repaired.Flag(c.Column); flag != "" {
+				flags = append(flags, flag)
+			}
+			if repaired.Rejected && c.Required {
+				return nil, fmt.Errorf("%s: rejected %s value %q", c.CSV, repaired.Repair, raw)
+			}
+			raw = repaired.Value
+		}
+
+		value, err := convertValue(raw, c)
+		if err != nil || value == "" {
+			if c.Required {
+				if err == nil {
+					err = fmt.Errorf("missing value")
+				}
+				return nil, fmt.Errorf("%s: %v", c.CSV, err)
+			}
+			value = ""
+		}
+		values[c.Column] = value
+	}
+
+	if len(flags) > 0 {
+		values[ImportFlagsColumn] = strings.Join(flags, ";")
+	}
+
+	return values, nil
+}
+
+// repairValue applies a column's Excel repair
+func repairValue(raw string, c ColumnMapping) RepairResult {
+	switch c.Repair {
+	case RepairUPRN:
+		return RepairExcelUPRN(raw)
+	case RepairDate:
+		return RepairExcelDate(raw, c.DateFormats, c.dateOrder)
+	case RepairNumber:
+		return RepairExcelNumber(raw)
+	}
+	return RepairResult{Value: raw}
+}
+
+// StagingRow returns StageRecord values ordered by StagingColumns, with empty values as NULL
+func (m *SourceMapping) StagingRow(record []string, columnMap map[string]int) ([]interface{}, error) {
+	staged, err := m.StageRecord(record, columnMap)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := m.StagingColumns()
+	row := make([]interface{}, len(cols))
+	for i, col := range cols {
+		if v := staged[col]; v != "" {
+			row[i] = v
+		}
+	}
+	return row, nil
+}
+
+// DocumentValues applies the document section to staged values, keyed by src_document column
+func (m *SourceMapping) DocumentValues(staged map[string]string) map[string]string {
+	doc := make(map[string]string, len(m.Document))
+	for name, f := range m.Document {
+		value := f.Value
+		if f.Column != "" {
+			value = staged[f.Column]
+		}
+		for _, t := range f.Transforms {
+			value = transforms[t](value)
+		}
+		doc[name] = value
+	}
+	if m.HasRepairs() {
+		doc[ImportFlagsColumn] = staged[ImportFlagsColumn]
+	}
+	return doc
+}
+
+// DocumentInsertSQL builds the staging -> table INSERT for this mapping, where table has
+// src_document's columns. Constants are passed as parameters; $1 is always the source type.
+func (m *SourceMapping) DocumentInsertSQL(table string) (string, []interface{}) {
+	cols := []string{"source_type"}
+	exprs := []string{"$1::source_type"}
+	args := []interface{}{m.SourceType}
+
+	for _, name := range documentFields {
+		f, ok := m.Document[name]
+		if !ok {
+			continue
+		}
+
+		var expr string
+		if f.Column != "" {
+			expr = f.Column
+		} else {
+			args = append(args, f.Value)
+			expr = fmt.Sprintf("$%d::text", len(args))
+		}
+		for _, t := range f.Transforms {
+			expr = fmt.Sprintf(sqlTransforms[t], expr)
+		}
+
+		// Staged dates are normalised to ISO; anything else is left NULL
+		if name == "doc_date" {
+			expr = fmt.Sprintf(`CASE WHEN %s ~ '^\d{4}-\d{2}-\d{2}$' THEN (%s)::date END`, expr, expr)
+		}
+
+		cols = append(cols, name)
+		exprs = append(exprs, expr)
+	}
+
+	if m.HasRepairs() {
+		cols = append(cols, ImportFlagsColumn)
+		exprs = append(exprs, ImportFlagsColumn)
+	}
+	cols = append(cols, SourceRowColumn)
+	exprs = append(exprs, SourceRowColumn)
+
+	query := fmt.Sprintf("INSERT INTO %s (%s)\nSELECT %s\nFROM %s",
+		table, strings.Join(cols, ", "), strings.Join(exprs, ", "), m.StagingTable)
+
+	if m.RequireAddress {
+		addr := m.Document["raw_address"].Column
+		query += fmt.Sprintf("\nWHERE %s IS NOT NULL AND %s != ''", addr, addr)
+	}
+
+	return query, args
+}
+
+// convertValue validates and normalises a value for its declared type
+func convertValue(raw string, c ColumnMapping) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	switch c.Type {
+	case ColumnTypeInt:
+		i, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid integer %q", raw)
+		}
+		return strconv.FormatInt(i, 10), nil
+
+	case ColumnTypeFloat:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid number %q", raw)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+
+	case ColumnTypeDate:
+		// Already normalised (e.g. by an Excel date repair)
+		if t, err := time.Parse(stagingDateLayout, raw); err == nil {
+			return t.Format(stagingDateLayout), nil
+		}
+		for _, format := range c.DateFormats {
+			if t, err := time.Parse(goDateLayout(format), raw); err == nil {
+				return t.Format(stagingDateLayout), nil
+			}
+		}
+		return "", fmt.Errorf("date %q does not match %v", raw, c.DateFormats)
+	}
+
+	return raw, nil
+}
+
+// dateTokenReplacer converts mapping date formats (DD/MM/YYYY) to Go layouts
+var dateTokenReplacer = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02", "M", "1", "D", "2")
+
+func goDateLayout(format string) string {
+	return dateTokenReplacer.Replace(format)
+}
+
+// transforms are the value transforms available to staging columns
+var transforms = map[string]func(string) string{
+	"trim":                strings.TrimSpace,
+	"upper":               strings.ToUpper,
+	"lower":               strings.ToLower,
+	"collapse_whitespace": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+	"digits_only": func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s)
+	},
+	"filename": func(s string) string {
+		// Handle both Windows and Unix path separators
+		if i := strings.LastIndexAny(s, `/\`); i >= 0 && i < len(s)-1 {
+			return s[i+1:]
+		}
+		return s
+	},
+}
+
+// sqlTransforms are the SQL equivalents used when transforming staging data to src_document
+var sqlTransforms = map[string]string{
+	"trim":                "TRIM(%s)",
+	"upper":               "UPPER(%s)",
+	"lower":               "LOWER(%s)",
+	"collapse_whitespace": `REGEXP_REPLACE(%s, '\s+', ' ', 'g')`,
+	"digits_only":         `REGEXP_REPLACE(%s, '[^0-9]', '', 'g')`,
+	"filename":            `REGEXP_REPLACE(%s, '^.*[\\/]', '')`,
+}
@@ -0,0 +1,78 @@
+package etl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinMappingsValidate(t *testing.T) {
+	types := ListSourceMappings()
+	if len(types) < 9 {
+		t.Fatalf("expected at least 9 built-in mappings, got %d: %v", len(types), types)
+	}
+
+	for _, sourceType := range types {
+		if _, err := LoadSourceMapping(sourceType); err != nil {
+			t.Errorf("LoadSourceMapping(%q) failed: %v", sourceType, err)
+		}
+	}
+}
+
+func TestStageRecord(t *testing.T) {
+	mapping, err := LoadSourceMapping("agreement")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columnMap := map[string]int{
+		"job number": 0, "filepath": 1, "address": 2, "date": 3,
+		"bs7666uprn": 4, "easting": 5, "northing": 6,
+	}
+	record := []string{
+		"JN24960", `Box 01\Section 52\12 SUSSEX ROAD.pdf`, "12  SUSSEX ROAD,  PETERSFIELD",
+		"9/9/85", "1710030787", "474793.00", "122942.00",
+	}
+
+	staged, err := mapping.StageRecord(record, columnMap)
+	if err != nil {
+		t.Fatalf("StageRecord() error: %v", err)
+	}
+	if staged["date"] != "1985-09-09" {
+		t.Errorf("date = %q, want 1985-09-09", staged["date"])
+	}
+	if staged["address"] != "12 SUSSEX ROAD, PETERSFIELD" {
+		t.Errorf("address = %q, want collapsed whitespace", staged["address"])
+	}
+
+	doc := mapping.DocumentValues(staged)
+	if doc["external_ref"] != "12 SUSSEX ROAD.pdf" || doc["doc_type"] != "Agreement" {
+		t.Errorf("DocumentValues() = %v", doc)
+	}
+
+	// Unparseable optional dates are dropped rather than rejecting the row
+	record[3] = "31/13/1985"
+	staged, err = mapping.StageRecord(record, columnMap)
+	if err != nil || staged["date"] != "" {
+		t.Errorf("invalid date: got (%q, %v), want empty value and no error", staged["date"], err)
+	}
+
+	query, _ := mapping.DocumentInsertSQL("src_document")
+	if !strings.Contains(query, "FROM stg_agreements") || !strings.Contains(query, SourceRowColumn) {
+		t.Errorf("DocumentInsertSQL() = %s", query)
+	}
+}
+
+func TestNaturalKey(t *testing.T) {
+	mapping, err := LoadSourceMapping("decision")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(mapping.NaturalKey, ",") != "job_number,filepath" {
+		t.Errorf("default natural key = %v", mapping.NaturalKey)
+	}
+
+	mapping.NaturalKey = []string{"postcode_text"}
+	if err := mapping.Validate(); err == nil {
+		t.Error("expected an unmapped natural key field to be rejected")
+	}
+}
@@ -0,0 +1,245 @@
+package etl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/geo"
+)
+
+// uprnRepairCandidatesMax caps the OS Open UPRNs counted for one truncated value
+const uprnRepairCandidatesMax = 10000
+
+// UPRNRepairer resolves scientific-notation UPRNs (e.g. 1.00062E+11) that Excel truncated.
+// A value is only repaired when exactly one OS Open UPRN in the district is consistent
+// with it. With several it is flagged uprn:rejected_ambiguous, as picking one would be a
+// guess; with none, uprn:rejected_scientific_notation. Every outcome is logged in
+// import_repair.
+type UPRNRepairer struct {
+	db *sql.DB
+}
+
+// NewUPRNRepairer creates a new UPRN repairer
+func NewUPRNRepairer(db *sql.DB) *UPRNRepairer {
+	return &UPRNRepairer{db: db}
+}
+
+// UPRNRepairSummary counts the outcome of a resolution pass
+type UPRNRepairSummary struct {
+	Pending   int
+	Repaired  int
+	Rejected  int
+	Ambiguous int // rejected because several UPRNs fit; included in Rejected
+}
+
+// pendingUPRN is a document whose UPRN still holds scientific notation
+type pendingUPRN struct {
+	srcID int64
+	raw   string
+}
+
+// ResolveScientificUPRNs resolves pending scientific-notation UPRNs, optionally for one source type
+func (ur *UPRNRepairer) ResolveScientificUPRNs(ctx context.Context, localDebug bool, sourceType string) (*UPRNRepairSummary, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	pending, err := ur.pendingDocuments(ctx, sourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &UPRNRepairSummary{Pending: len(pending)}
+	debug.DebugOutput(localDebug, "Resolving %d scientific-notation UPRNs", len(pending))
+
+	for _, doc := range pending {
+		lo, hi, _, ok := ScientificRange(doc.raw)
+		if !ok {
+			if err := ur.reject(ctx, doc, nil); err != nil {
+				return summary, err
+			}
+			summary.Rejected++
+			continue
+		}
+
+		candidates, err := ur.candidates(ctx, lo, hi)
+		if err != nil {
+			return summary, err
+		}
+
+		if len(candidates) != 1 {
+			debug.DebugOutput(localDebug, "src_id %d: %s has %d candidates, rejected", doc.srcID, doc.raw, len(candidates))
+			if err := ur.reject(ctx, doc, candidates); err != nil {
+				return summary, err
+			}
+			summary.Rejected++
+			if len(candidates) > 1 {
+				summary.Ambiguous++
+			}
+			continue
+		}
+
+		debug.DebugOutput(localDebug, "src_id %d: %s resolved to %s", doc.srcID, doc.raw, candidates[0])
+		if err := ur.repair(ctx, doc, candidates[0]); err != nil {
+			return summary, err
+		}
+		summary.Repaired++
+	}
+
+	debug.DebugOutput(localDebug, "Scientific UPRNs: %d repaired, %d rejected (%d ambiguous)",
+		summary.Repaired, summary.Rejected, summary.Ambiguous)
+	return summary, nil
+}
+
+// pendingDocuments loads documents flagged with an unresolved scientific-notation UPRN
+func (ur *UPRNRepairer) pendingDocuments(ctx context.Context, sourceType string) ([]pendingUPRN, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT src_id, TRIM(uprn_raw)
+		FROM src_document
+		WHERE import_flags LIKE '%:scientific_notation%'
+		  AND uprn_raw ~* '^\s*[0-9](\.[0-9]+)?e\+?[0-9]+\s*$'
+	`
+	var args []interface{}
+	if sourceType != "" {
+		query += " AND source_type::text = $1"
+		args = append(args, sourceType)
+	}
+
+	rows, err := ur.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scientific-notation UPRNs: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []pendingUPRN
+	for rows.Next() {
+		var p pendingUPRN
+		if err := rows.Scan(&p.srcID, &p.raw); err != nil {
+			return nil, fmt.Errorf("failed to scan scientific-notation UPRN: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// candidates returns the OS Open UPRNs inside the district that fall in [lo, hi], at most
+// uprnRepairCandidatesMax+1 of them
+func (ur *UPRNRepairer) candidates(ctx context.Context, lo, hi int64) ([]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var candidates []string
+
+	// UPRNs are text, so compare equal-length digit strings to use the primary key index
+	for _, r := range digitRanges(lo, hi) {
+		if len(candidates) > uprnRepairCandidatesMax {
+			break
+		}
+		rows, err := ur.db.QueryContext(ctx, `
+			SELECT o.uprn
+			FROM os_uprn_reference o
+			WHERE length(o.uprn) = $1
+			  AND o.uprn BETWEEN $2 AND $3
+			  AND o.x_coordinate BETWEEN $4 AND $5
+			  AND o.y_coordinate BETWEEN $6 AND $7
+			LIMIT $8
+		`, len(r[0]), r[0], r[1],
+			geo.DistrictMinEasting, geo.DistrictMaxEasting,
+			geo.DistrictMinNorthing, geo.DistrictMaxNorthing,
+			uprnRepairCandidatesMax+1-len(candidates))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query UPRN candidates: %w", err)
+		}
+
+		for rows.Next() {
+			var uprn string
+			if err := rows.Scan(&uprn); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan UPRN candidate: %w", err)
+			}
+			candidates = append(candidates, uprn)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read UPRN candidates: %w", err)
+		}
+	}
+
+	return candidates, nil
+}
+
+// digitRanges splits [lo, hi] into decimal string ranges of equal length
+func digitRanges(lo, hi int64) [][2]string {
+	var ranges [][2]string
+	for lo <= hi {
+		s := strconv.FormatInt(lo, 10)
+		end, _ := strconv.ParseInt(strings.Repeat("9", len(s)), 10, 64)
+		if end > hi {
+			end = hi
+		}
+		ranges = append(ranges, [2]string{s, strconv.FormatInt(end, 10)})
+		lo = end + 1
+	}
+	return ranges
+}
+
+// repair replaces the scientific value with the one UPRN it can be and logs it
+func (ur *UPRNRepairer) repair(ctx context.Context, doc pendingUPRN, uprn string) error {
+	return ur.record(ctx, doc, sql.NullString{String: uprn, Valid: true}, "repaired", scientificOutcome([]string{uprn}), 1)
+}
+
+// reject clears the unusable UPRN and logs it
+func (ur *UPRNRepairer) reject(ctx context.Context, doc pendingUPRN, candidates []string) error {
+	return ur.record(ctx, doc, sql.NullString{}, "rejected", scientificOutcome(candidates), len(candidates))
+}
+
+// scientificOutcome is the import flag for a truncated UPRN with the given candidates: only
+// a single candidate resolves it
+func scientificOutcome(candidates []string) string {
+	switch len(candidates) {
+	case 0:
+		return "rejected_scientific_notation"
+	case 1:
+		return "scientific_resolved"
+	default:
+		return "rejected_ambiguous"
+	}
+}
+
+func (ur *UPRNRepairer) record(ctx context.Context, doc pendingUPRN, uprn sql.NullString, status, flag string, candidates int) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin UPRN repair: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE src_document
+		SET uprn_raw = $2,
+		    import_flags = replace(import_flags, ':scientific_notation', ':' || $3)
+		WHERE src_id = $1
+	`, doc.srcID, uprn, flag)
+	if err != nil {
+		return fmt.Errorf("failed to update UPRN for src_id %d: %w", doc.srcID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO import_repair (src_id, column_name, original_value, repaired_value, repair_type, status, candidates)
+		VALUES ($1, 'uprn_raw', $2, $3, 'scientific_notation', $4, $5)
+	`, doc.srcID, doc.raw, uprn, status, candidates)
+	if err != nil {
+		return fmt.Errorf("failed to log UPRN repair for src_id %d: %w", doc.srcID, err)
+	}
+
+	return tx.Commit()
+}
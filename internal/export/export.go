@@ -0,0 +1,430 @@
+// Package export runs web exports as background jobs. A job is queued with the query whose
+// rows it exports; a worker counts the rows, streams them to a file in the export
+// directory as CSV or GeoJSON, and records its progress. Finished files can be downloaded
+// until they expire, when the sweeper removes them. Jobs live in the web server's memory:
+// a restart forgets them, and their files are removed when the next server starts.
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatGeoJSON = "geojson"
+)
+
+// Job statuses
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	StatusExpired = "expired"
+)
+
+var (
+	// ErrNotFound is returned for jobs that do not exist or have been forgotten
+	ErrNotFound = errors.New("export not found")
+
+	// ErrQueueFull is returned when too many exports are already waiting
+	ErrQueueFull = errors.New("too many exports waiting; try again shortly")
+
+	// ErrNotReady is returned when downloading an export that has not finished
+	ErrNotReady = errors.New("export has not finished")
+)
+
+// Query is the SQL a job exports. For GeoJSON it selects one column, a GeoJSON Feature, or
+// with Locate the Feature then its easting and northing.
+type Query struct {
+	SQL  string
+	Args []interface{}
+
+	// Locate, when set, gives each GeoJSON Feature the geometry of its easting and northing
+	Locate func(easting, northing float64) interface{}
+}
+
+// Rows is the part of *sql.Rows a job reads
+type Rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// Source runs export queries; DBSource runs them against PostgreSQL
+type Source interface {
+	Count(ctx context.Context, q Query) (int64, error)
+	Rows(ctx context.Context, q Query) (Rows, error)
+}
+
+// DBSource runs export queries on a database, without the per-query timeout: an export
+// streams for as long as it takes
+type DBSource struct {
+	DB *sql.DB
+}
+
+// Count counts the rows the query returns
+func (s DBSource) Count(ctx context.Context, q Query) (int64, error) {
+	var n int64
+	err := s.DB.QueryRowContext(ctx, "SELECT count(*) FROM ("+q.SQL+") AS export_rows", q.Args...).Scan(&n)
+	return n, err
+}
+
+// Rows runs the query
+func (s DBSource) Rows(ctx context.Context, q Query) (Rows, error) {
+	return s.DB.QueryContext(ctx, q.SQL, q.Args...)
+}
+
+// Job is one export and how far it has got
+type Job struct {
+	ID          string     `json:"id"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	RowsTotal   int64      `json:"rows_total"`
+	RowsWritten int64      `json:"rows_written"`
+	Bytes       int64      `json:"bytes"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	query Query
+	path  string
+}
+
+// Progress is the share of rows written, 0 to 1
+func (j Job) Progress() float64 {
+	if j.Status == StatusDone {
+		return 1
+	}
+	if j.RowsTotal <= 0 {
+		return 0
+	}
+	return float64(j.RowsWritten) / float64(j.RowsTotal)
+}
+
+// Filename is the name a download is saved as
+func (j Job) Filename() string {
+	ext := "csv"
+	if j.Format == FormatGeoJSON {
+		ext = "geojson"
+	}
+	return fmt.Sprintf("ehdc-export-%s-%s.%s", j.CreatedAt.Format("20060102-1504"), j.ID[:8], ext)
+}
+
+// Options configure a Manager
+type Options struct {
+	Dir     string        // where files are written
+	TTL     time.Duration // how long a finished file can be downloaded
+	Workers int           // exports run at once
+	Queued  int           // exports that may wait for a worker
+}
+
+// sweepEvery is how often expired files are removed, at most
+const sweepEvery = 10 * time.Minute
+
+// progressEvery is how many rows are written between progress updates
+const progressEvery = 1000
+
+// filePrefix starts every export file, so Start only removes files it wrote
+const filePrefix = "export-"
+
+// Manager queues, runs and expires export jobs
+type Manager struct {
+	source Source
+	opts   Options
+	now    func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+
+	queue  chan *Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a manager; Start runs its workers
+func NewManager(source Source, opts Options) *Manager {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.Queued < 1 {
+		opts.Queued = 100
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		source: source,
+		opts:   opts,
+		now:    time.Now,
+		jobs:   make(map[string]*Job),
+		queue:  make(chan *Job, opts.Queued),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start creates the export directory, removes files left by a previous server, and starts
+// the workers and the sweeper
+func (m *Manager) Start() error {
+	if err := os.MkdirAll(m.opts.Dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create export directory %s: %w", m.opts.Dir, err)
+	}
+	leftover, err := filepath.Glob(filepath.Join(m.opts.Dir, filePrefix+"*"))
+	if err != nil {
+		return fmt.Errorf("failed to list export directory: %w", err)
+	}
+	for _, path := range leftover {
+		os.Remove(path)
+	}
+
+	for i := 0; i < m.opts.Workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	m.wg.Add(1)
+	go m.sweepLoop()
+	return nil
+}
+
+// Stop cancels running exports and waits for the workers to finish
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Submit queues an export of the query's rows for requestedBy
+func (m *Manager) Submit(format string, q Query, requestedBy string) (Job, error) {
+	if format != FormatCSV && format != FormatGeoJSON {
+		return Job{}, fmt.Errorf("unsupported export format %q (want csv or geojson)", format)
+	}
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := &Job{ID: id, Format: format, Status: StatusQueued, RequestedBy: requestedBy, CreatedAt: m.now(), query: q}
+	select {
+	case m.queue <- job:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[id] = job
+	return *job, nil
+}
+
+// Get returns a copy of the job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// List returns the jobs requested by requestedBy, or every job when it is empty, newest
+// first
+func (m *Manager) List(requestedBy string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var jobs []Job
+	for _, job := range m.jobs {
+		if requestedBy == "" || job.RequestedBy == requestedBy {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt.After(jobs[k].CreatedAt) })
+	return jobs
+}
+
+// Open opens a finished export's file for download
+func (m *Manager) Open(id string) (*os.File, Job, error) {
+	job, err := m.Get(id)
+	if err != nil {
+		return nil, job, err
+	}
+	switch job.Status {
+	case StatusDone:
+	case StatusExpired:
+		return nil, job, ErrNotFound
+	default:
+		return nil, job, ErrNotReady
+	}
+	f, err := os.Open(job.path)
+	if err != nil {
+		return nil, job, fmt.Errorf("failed to open export %s: %w", id, err)
+	}
+	return f, job, nil
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case job := <-m.queue:
+			m.run(job)
+		}
+	}
+}
+
+// run exports one job, recording its outcome
+func (m *Manager) run(job *Job) {
+	m.update(job, func(j *Job) {
+		started := m.now()
+		j.Status, j.StartedAt = StatusRunning, &started
+	})
+
+	path, err := m.export(job)
+	if err == nil {
+		// The file is complete once renamed; until then a failed export leaves only .part
+		final := strings.TrimSuffix(path, ".part")
+		if err = os.Rename(path, final); err == nil {
+			path = final
+		}
+	}
+	if err != nil {
+		os.Remove(path)
+		log.Printf("Export %s failed: %v", job.ID, err)
+	}
+
+	m.update(job, func(j *Job) {
+		completed := m.now()
+		j.CompletedAt = &completed
+		if err != nil {
+			j.Status, j.Error = StatusFailed, err.Error()
+			if m.ctx.Err() != nil {
+				j.Error = "the web server stopped during the export"
+			}
+			return
+		}
+		expires := completed.Add(m.opts.TTL)
+		j.Status, j.path, j.ExpiresAt = StatusDone, path, &expires
+	})
+}
+
+// export writes the job's rows to a .part file and returns its path
+func (m *Manager) export(job *Job) (string, error) {
+	path := filepath.Join(m.opts.Dir, filePrefix+job.ID+".part")
+
+	total, err := m.source.Count(m.ctx, job.query)
+	if err != nil {
+		return path, fmt.Errorf("failed to count rows: %w", err)
+	}
+	m.update(job, func(j *Job) { j.RowsTotal = total })
+
+	rows, err := m.source.Rows(m.ctx, job.query)
+	if err != nil {
+		return path, fmt.Errorf("failed to query rows: %w", err)
+	}
+	defer rows.Close()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return path, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer f.Close()
+
+	counter := &countingWriter{w: f}
+	progress := func(written int64) {
+		m.update(job, func(j *Job) { j.RowsWritten, j.Bytes = written, counter.n })
+	}
+	if job.Format == FormatGeoJSON {
+		err = writeGeoJSON(counter, rows, job.query.Locate, progress)
+	} else {
+		err = writeCSV(counter, rows, progress)
+	}
+	if err != nil {
+		return path, err
+	}
+	if err := f.Sync(); err != nil {
+		return path, fmt.Errorf("failed to write export file: %w", err)
+	}
+	m.update(job, func(j *Job) { j.Bytes = counter.n })
+	return path, nil
+}
+
+func (m *Manager) update(job *Job, change func(j *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	change(job)
+}
+
+func (m *Manager) sweepLoop() {
+	defer m.wg.Done()
+	every := sweepEvery
+	if m.opts.TTL > 0 && m.opts.TTL/2 < every {
+		every = m.opts.TTL / 2
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sweep()
+		}
+	}
+}
+
+// Sweep removes the files of expired exports, and forgets exports that expired or failed
+// more than a TTL ago. It returns how many files it removed.
+func (m *Manager) Sweep() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+
+	removed := 0
+	for id, job := range m.jobs {
+		if job.Status == StatusDone && job.ExpiresAt != nil && !now.Before(*job.ExpiresAt) {
+			if err := os.Remove(job.path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove expired export %s: %v", id, err)
+				continue
+			}
+			job.Status, job.path = StatusExpired, ""
+			removed++
+		}
+		finished := job.ExpiresAt
+		if job.Status == StatusFailed {
+			finished = job.CompletedAt
+		}
+		if (job.Status == StatusExpired || job.Status == StatusFailed) && finished != nil &&
+			now.Sub(*finished) >= m.opts.TTL {
+			delete(m.jobs, id)
+		}
+	}
+	return removed
+}
+
+// newID returns an unguessable job ID; knowing it is not enough to download, but it keeps
+// jobs from being enumerated
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate export ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
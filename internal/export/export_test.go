@@ -0,0 +1,256 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRows serves fixed rows
+type fakeRows struct {
+	columns []string
+	rows    [][]interface{}
+	next    int
+	err     error
+}
+
+func (r *fakeRows) Columns() ([]string, error) { return r.columns, nil }
+func (r *fakeRows) Err() error                 { return r.err }
+func (r *fakeRows) Close() error               { return nil }
+
+func (r *fakeRows) Next() bool {
+	if r.next >= len(r.rows) {
+		return false
+	}
+	r.next++
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	for i, v := range r.rows[r.next-1] {
+		switch d := dest[i].(type) {
+		case *interface{}:
+			*d = v
+		case *[]byte:
+			*d = []byte(v.(string))
+		case *float64:
+			*d = v.(float64)
+		}
+	}
+	return nil
+}
+
+type fakeSource struct {
+	rows func() *fakeRows
+}
+
+func (s fakeSource) Count(ctx context.Context, q Query) (int64, error) {
+	return int64(len(s.rows().rows)), nil
+}
+
+func (s fakeSource) Rows(ctx context.Context, q Query) (Rows, error) {
+	return s.rows(), nil
+}
+
+func startManager(t *testing.T, source Source) (*Manager, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	m := NewManager(source, Options{Dir: t.TempDir(), TTL: time.Hour, Workers: 2})
+	m.now = func() time.Time { return now }
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(m.Stop)
+	return m, &now
+}
+
+// wait polls until the job leaves the queue
+func wait(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if job.Status != StatusQueued && job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("export %s did not finish", id)
+	return Job{}
+}
+
+func TestCSVExport(t *testing.T) {
+	decided := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	source := fakeSource{rows: func() *fakeRows {
+		return &fakeRows{
+			columns: []string{"src_id", "address", "score", "doc_date"},
+			rows: [][]interface{}{
+				{int64(1), []byte("1 HIGH STREET, ALTON"), 0.92, decided},
+				{int64(2), nil, nil, nil},
+			},
+		}
+	}}
+	m, _ := startManager(t, source)
+
+	job, err := m.Submit(FormatCSV, Query{SQL: "SELECT ..."}, "alice")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	job = wait(t, m, job.ID)
+	if job.Status != StatusDone || job.RowsWritten != 2 || job.RowsTotal != 2 || job.Progress() != 1 {
+		t.Fatalf("finished job = %+v", job)
+	}
+
+	f, _, err := m.Open(job.ID)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	data, _ := os.ReadFile(f.Name())
+	want := "src_id,address,score,doc_date\n1,\"1 HIGH STREET, ALTON\",0.92,2024-03-01\n2,,,\n"
+	if string(data) != want {
+		t.Errorf("CSV = %q, want %q", data, want)
+	}
+	if job.Bytes != int64(len(want)) {
+		t.Errorf("Bytes = %d, want %d", job.Bytes, len(want))
+	}
+}
+
+func TestGeoJSONExport(t *testing.T) {
+	source := fakeSource{rows: func() *fakeRows {
+		return &fakeRows{
+			columns: []string{"geojson_feature"},
+			rows: [][]interface{}{
+				{`{"type":"Feature","geometry":null,"properties":{"src_id":1}}`},
+				{`{"type":"Feature","geometry":null,"properties":{"src_id":2}}`},
+			},
+		}
+	}}
+	m, _ := startManager(t, source)
+
+	job, _ := m.Submit(FormatGeoJSON, Query{}, "alice")
+	job = wait(t, m, job.ID)
+	f, _, err := m.Open(job.ID)
+	if err != nil {
+		t.Fatalf("Open: %v (job %+v)", err, job)
+	}
+	defer f.Close()
+
+	var collection struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.NewDecoder(f).Decode(&collection); err != nil {
+		t.Fatalf("export is not JSON: %v", err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Errorf("collection = %s with %d features", collection.Type, len(collection.Features))
+	}
+}
+
+func TestGeoJSONExportLocatesFeatures(t *testing.T) {
+	source := fakeSource{rows: func() *fakeRows {
+		return &fakeRows{
+			columns: []string{"geojson_feature", "easting", "northing"},
+			rows: [][]interface{}{
+				{`{"type":"Feature","properties":{"src_id":1}}`, 474793.0, 122942.0},
+			},
+		}
+	}}
+	m, _ := startManager(t, source)
+
+	locate := func(easting, northing float64) interface{} {
+		return map[string]interface{}{"type": "Point", "coordinates": []float64{easting / 1e5, northing / 1e5}}
+	}
+	job, _ := m.Submit(FormatGeoJSON, Query{Locate: locate}, "alice")
+	job = wait(t, m, job.ID)
+	f, _, err := m.Open(job.ID)
+	if err != nil {
+		t.Fatalf("Open: %v (job %+v)", err, job)
+	}
+	defer f.Close()
+
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Type        string     `json:"type"`
+				Coordinates [2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(f).Decode(&collection); err != nil {
+		t.Fatalf("export is not JSON: %v", err)
+	}
+	if len(collection.Features) != 1 {
+		t.Fatalf("exported %d features, want 1", len(collection.Features))
+	}
+	feature := collection.Features[0]
+	if feature.Geometry.Type != "Point" || feature.Geometry.Coordinates != [2]float64{4.74793, 1.22942} {
+		t.Errorf("geometry = %+v, want the located point", feature.Geometry)
+	}
+	if feature.Properties["src_id"] != 1.0 {
+		t.Errorf("properties = %v, want them kept", feature.Properties)
+	}
+}
+
+func TestFailedExportLeavesNoFile(t *testing.T) {
+	source := fakeSource{rows: func() *fakeRows {
+		return &fakeRows{columns: []string{"src_id"}, rows: [][]interface{}{{int64(1)}}, err: errors.New("connection reset")}
+	}}
+	m, _ := startManager(t, source)
+
+	job, _ := m.Submit(FormatCSV, Query{}, "alice")
+	job = wait(t, m, job.ID)
+	if job.Status != StatusFailed || !strings.Contains(job.Error, "connection reset") {
+		t.Errorf("job = %+v, want failed with the query error", job)
+	}
+	if _, _, err := m.Open(job.ID); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Open of a failed export: %v, want ErrNotReady", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(m.opts.Dir, "*")); len(files) != 0 {
+		t.Errorf("failed export left %v", files)
+	}
+}
+
+func TestSweepExpiresFiles(t *testing.T) {
+	source := fakeSource{rows: func() *fakeRows {
+		return &fakeRows{columns: []string{"src_id"}, rows: [][]interface{}{{int64(1)}}}
+	}}
+	m, now := startManager(t, source)
+
+	job, _ := m.Submit(FormatCSV, Query{}, "alice")
+	job = wait(t, m, job.ID)
+	if removed := m.Sweep(); removed != 0 {
+		t.Fatalf("Sweep removed %d files before expiry", removed)
+	}
+
+	m.mu.Lock()
+	*now = now.Add(time.Hour)
+	m.mu.Unlock()
+	if removed := m.Sweep(); removed != 1 {
+		t.Fatalf("Sweep removed %d files at expiry, want 1", removed)
+	}
+	if _, _, err := m.Open(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open of an expired export: %v, want ErrNotFound", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(m.opts.Dir, "*")); len(files) != 0 {
+		t.Errorf("expired export left %v", files)
+	}
+
+	m.mu.Lock()
+	*now = now.Add(time.Hour)
+	m.mu.Unlock()
+	m.Sweep()
+	if _, err := m.Get(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired export still listed a TTL later: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// writeCSV writes a header of the column names and a line per row
+func writeCSV(w io.Writer, rows Rows, progress func(written int64)) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to read columns: %w", err)
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+
+	var written int64
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("failed to read row %d: %w", written+1, err)
+		}
+		for i, v := range values {
+			record[i] = csvValue(v)
+		}
+		if err := out.Write(record); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		written++
+		if written%progressEvery == 0 {
+			out.Flush()
+			progress(written)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	progress(written)
+	return nil
+}
+
+// csvValue formats a scanned value; NULL is an empty field
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeGeoJSON writes a FeatureCollection of the rows, each a GeoJSON Feature, located by
+// locate when it is set
+func writeGeoJSON(w io.Writer, rows Rows, locate func(easting, northing float64) interface{}, progress func(written int64)) error {
+	out := bufio.NewWriter(w)
+	if _, err := io.WriteString(out, `{"type":"FeatureCollection","features":[`); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	var written int64
+	for rows.Next() {
+		var feature []byte
+		var easting, northing float64
+		dest := []interface{}{&feature}
+		if locate != nil {
+			dest = append(dest, &easting, &northing)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to read row %d: %w", written+1, err)
+		}
+		if locate != nil {
+			located, err := withGeometry(feature, locate(easting, northing))
+			if err != nil {
+				return fmt.Errorf("row %d is not a GeoJSON feature: %w", written+1, err)
+			}
+			feature = located
+		} else if !json.Valid(feature) {
+			return fmt.Errorf("row %d is not a GeoJSON feature", written+1)
+		}
+		sep := ",\n"
+		if written == 0 {
+			sep = "\n"
+		}
+		if _, err := io.WriteString(out, sep); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		if _, err := out.Write(feature); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		written++
+		if written%progressEvery == 0 {
+			if err := out.Flush(); err != nil {
+				return fmt.Errorf("failed to write export file: %w", err)
+			}
+			progress(written)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	if _, err := io.WriteString(out, "\n]}\n"); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	progress(written)
+	return nil
+}
+
+// withGeometry sets a feature's geometry
+func withGeometry(feature []byte, geometry interface{}) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(feature, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, errors.New("feature is null")
+	}
+	encoded, err := json.Marshal(geometry)
+	if err != nil {
+		return nil, err
+	}
+	fields["geometry"] = encoded
+	return json.Marshal(fields)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,255 @@
+package facts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+)
+
+// Drift kinds, comparing the fact table with a full rebuild
+const (
+	Missing        = "missing"    // a rebuild would write the row; the table lacks it
+	Extra          = "extra"      // the table has a row a rebuild would not write
+	MatchDrift     = "match"      // address, location, method, decision or confidence differ
+	AttributeDrift = "attributes" // same match; document type, address, date, reference or flags differ
+	Consistent     = "consistent"
+)
+
+// DriftKinds lists the kinds that are reported, in report order
+var DriftKinds = []string{Missing, Extra, MatchDrift, AttributeDrift}
+
+// sampleSize is how many differing document IDs a check records
+const sampleSize = 100
+
+// repairBatch is how many documents a repair rebuilds per transaction
+const repairBatch = 1000
+
+// Row is the compared part of one document's fact row
+type Row struct {
+	Present    bool
+	AddressID  int64 // 0 when unmatched
+	LocationID int64
+	MethodID   int64
+	DecisionID int64
+	Confidence *float64
+	Attributes string // doc type, original address, application date, reference and flags
+}
+
+// Drift is a document whose fact row differs from a rebuild's
+type Drift struct {
+	DocumentID int64
+	Kind       string
+	Expected   Row // what a rebuild would write
+	Actual     Row // what fact_documents_lean holds
+}
+
+// CheckReport is the outcome of a consistency check
+type CheckReport struct {
+	CheckID  int64
+	Expected int            // rows a rebuild would write
+	Actual   int            // rows in fact_documents_lean
+	Pending  int            // documents with unapplied changes, not compared
+	Counts   map[string]int // by kind, excluding Consistent
+	Drifts   []Drift        // ordered by document ID
+	Repaired *ApplyStats    // nil unless repair was requested
+	Duration time.Duration
+}
+
+// Consistent reports whether every compared document matched a rebuild
+func (r *CheckReport) Consistent() bool {
+	return len(r.Drifts) == 0
+}
+
+// confidenceEpsilon is half the resolution of match_confidence_score, DECIMAL(5,4)
+const confidenceEpsilon = 0.00005
+
+// Classify reports how a document's fact row differs from a rebuild's
+func Classify(expected, actual Row) string {
+	switch {
+	case !expected.Present && !actual.Present:
+		return Consistent
+	case !actual.Present:
+		return Missing
+	case !expected.Present:
+		return Extra
+	case expected.AddressID != actual.AddressID, expected.LocationID != actual.LocationID,
+		expected.MethodID != actual.MethodID, expected.DecisionID != actual.DecisionID:
+		return MatchDrift
+	case (expected.Confidence == nil) != (actual.Confidence == nil):
+		return MatchDrift
+	case expected.Confidence != nil && math.Abs(*expected.Confidence-*actual.Confidence) >= confidenceEpsilon:
+		return MatchDrift
+	case expected.Attributes != actual.Attributes:
+		return AttributeDrift
+	}
+	return Consistent
+}
+
+// compareQuery returns the documents whose row in fact_expected and fact_documents_lean
+// differ, skipping documents with unapplied changes
+const compareQuery = `
+	WITH e AS (
+		SELECT document_id, matched_address_id, matched_location_id, match_method_id, match_decision_id,
+			ROUND(match_confidence_score, 4) AS confidence,
+			format('%s|%s|%s|%s|%s|%s', doc_type_id, original_address_id, application_date_id,
+				planning_reference, is_auto_processed, has_validation_issues) AS attributes
+		FROM fact_expected
+	), f AS (
+		SELECT document_id, matched_address_id, matched_location_id, match_method_id, match_decision_id,
+			match_confidence_score AS confidence,
+			format('%s|%s|%s|%s|%s|%s', doc_type_id, original_address_id, application_date_id,
+				planning_reference, is_auto_processed, has_validation_issues) AS attributes
+		FROM fact_documents_lean
+	)
+	SELECT COALESCE(e.document_id, f.document_id),
+		e.document_id IS NOT NULL, COALESCE(e.matched_address_id, 0), COALESCE(e.matched_location_id, 0),
+		COALESCE(e.match_method_id, 0), COALESCE(e.match_decision_id, 0), e.confidence, COALESCE(e.attributes, ''),
+		f.document_id IS NOT NULL, COALESCE(f.matched_address_id, 0), COALESCE(f.matched_location_id, 0),
+		COALESCE(f.match_method_id, 0), COALESCE(f.match_decision_id, 0), f.confidence, COALESCE(f.attributes, '')
+	FROM e
+	FULL OUTER JOIN f ON f.document_id = e.document_id
+	WHERE (e.document_id IS NULL OR f.document_id IS NULL
+		OR (e.matched_address_id, e.matched_location_id, e.match_method_id, e.match_decision_id, e.confidence, e.attributes)
+			IS DISTINCT FROM
+		   (f.matched_address_id, f.matched_location_id, f.match_method_id, f.match_decision_id, f.confidence, f.attributes))
+	  AND NOT EXISTS (
+		SELECT 1 FROM fact_change_log c
+		WHERE c.document_id = COALESCE(e.document_id, f.document_id) AND c.applied_at IS NULL
+	  )
+	ORDER BY 1`
+
+// Check compares fact_documents_lean with the rows a full rebuild would write and records
+// the result in fact_consistency_check. Documents with unapplied changes are expected to
+// differ and are skipped. With repair the differing documents are rebuilt.
+func Check(ctx context.Context, localDebug bool, db *sql.DB, repair bool) (*CheckReport, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	start := time.Now()
+	report := &CheckReport{Counts: make(map[string]int)}
+
+	if err := compare(ctx, localDebug, db, report); err != nil {
+		return nil, err
+	}
+
+	if repair && len(report.Drifts) > 0 {
+		report.Repaired = &ApplyStats{}
+		for i := 0; i < len(report.Drifts); i += repairBatch {
+			end := i + repairBatch
+			if end > len(report.Drifts) {
+				end = len(report.Drifts)
+			}
+			ids := make([]int64, 0, end-i)
+			for _, d := range report.Drifts[i:end] {
+				ids = append(ids, d.DocumentID)
+			}
+			stats, err := refresh(ctx, db, ids)
+			if err != nil {
+				return report, fmt.Errorf("failed to repair drifted fact rows: %w", err)
+			}
+			report.Repaired.add(stats)
+		}
+	}
+	report.Duration = time.Since(start)
+
+	if err := record(ctx, db, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// compare fills report from a rebuild into a temporary table
+func compare(ctx context.Context, localDebug bool, db *sql.DB, report *CheckReport) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	debug.DebugOutput(localDebug, "Building the rows a full rebuild would write")
+	if _, err := tx.ExecContext(ctx, "CREATE TEMP TABLE fact_expected ON COMMIT DROP AS"+rowsQuery); err != nil {
+		return fmt.Errorf("failed to build expected fact rows: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM fact_expected),
+			(SELECT COUNT(*) FROM fact_documents_lean),
+			(SELECT COUNT(DISTINCT document_id) FROM fact_change_log WHERE applied_at IS NULL)
+	`).Scan(&report.Expected, &report.Actual, &report.Pending)
+	if err != nil {
+		return fmt.Errorf("failed to count fact rows: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, compareQuery)
+	if err != nil {
+		return fmt.Errorf("failed to compare fact rows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d Drift
+		var expected, actual sql.NullFloat64
+		err := rows.Scan(&d.DocumentID,
+			&d.Expected.Present, &d.Expected.AddressID, &d.Expected.LocationID, &d.Expected.MethodID,
+			&d.Expected.DecisionID, &expected, &d.Expected.Attributes,
+			&d.Actual.Present, &d.Actual.AddressID, &d.Actual.LocationID, &d.Actual.MethodID,
+			&d.Actual.DecisionID, &actual, &d.Actual.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to scan fact comparison: %w", err)
+		}
+		if expected.Valid {
+			d.Expected.Confidence = &expected.Float64
+		}
+		if actual.Valid {
+			d.Actual.Confidence = &actual.Float64
+		}
+
+		d.Kind = Classify(d.Expected, d.Actual)
+		if d.Kind != Consistent {
+			report.Counts[d.Kind]++
+			report.Drifts = append(report.Drifts, d)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to compare fact rows: %w", err)
+	}
+	return tx.Commit()
+}
+
+// record writes the report to fact_consistency_check, setting its CheckID
+func record(ctx context.Context, db *sql.DB, report *CheckReport) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	sample := make([]int64, 0, sampleSize)
+	for _, d := range report.Drifts {
+		if len(sample) == sampleSize {
+			break
+		}
+		sample = append(sample, d.DocumentID)
+	}
+	var repaired int
+	if report.Repaired != nil {
+		repaired = report.Repaired.Documents
+	}
+
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO fact_consistency_check
+			(expected_rows, actual_rows, missing, extra, drifted, repaired, pending, duration_ms, sample)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING check_id
+	`, report.Expected, report.Actual, report.Counts[Missing], report.Counts[Extra],
+		report.Counts[MatchDrift]+report.Counts[AttributeDrift], repaired, report.Pending,
+		report.Duration.Milliseconds(), pq.Array(sample)).Scan(&report.CheckID)
+	if err != nil {
+		return fmt.Errorf("failed to record consistency check: %w", err)
+	}
+	return nil
+}
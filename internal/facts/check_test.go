@@ -0,0 +1,37 @@
+package facts
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	conf := func(f float64) *float64 { return &f }
+	row := Row{Present: true, AddressID: 10, LocationID: 20, MethodID: 1, DecisionID: 2, Confidence: conf(0.95), Attributes: "1|7|20240101|APP/1|true|false"}
+
+	with := func(change func(*Row)) Row {
+		r := row
+		change(&r)
+		return r
+	}
+
+	tests := []struct {
+		name             string
+		expected, actual Row
+		want             string
+	}{
+		{"same", row, row, Consistent},
+		{"neither", Row{}, Row{}, Consistent},
+		{"missing", row, Row{}, Missing},
+		{"extra", Row{}, row, Extra},
+		{"address", row, with(func(r *Row) { r.AddressID = 11 }), MatchDrift},
+		{"unmatched", row, with(func(r *Row) { r.AddressID, r.LocationID, r.Confidence = 0, 0, nil }), MatchDrift},
+		{"method", row, with(func(r *Row) { r.MethodID = 3 }), MatchDrift},
+		{"decision", row, with(func(r *Row) { r.DecisionID = 3 }), MatchDrift},
+		{"confidence", row, with(func(r *Row) { r.Confidence = conf(0.90) }), MatchDrift},
+		{"rounding", row, with(func(r *Row) { r.Confidence = conf(0.95000001) }), Consistent},
+		{"attributes", row, with(func(r *Row) { r.Attributes = "1|7|20240101|APP/1|true|true" }), AttributeDrift},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.expected, tt.actual); got != tt.want {
+			t.Errorf("%s: Classify = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+// Package facts builds fact_documents_lean. A full rebuild writes every row; between
+// rebuilds, triggers on match_accepted, address_match, address_match_corrected and
+// src_document log the affected documents in fact_change_log and Apply rebuilds just
+// those rows, so reviewer decisions reach the fact table and the CSV export views the
+// same day. Check compares the table with what a full rebuild would write.
+//
+// Both paths use the same row definition, rowsQuery. A reviewer's match_accepted row
+// takes precedence over a correction, and a correction over the matcher's address_match.
+// Matching layers record their matches in address_match through SaveMatch rather than
+// updating fact rows, so no rebuild loses them.
+package facts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+)
+
+// columns are the fact_documents_lean columns rowsQuery writes, in its select order.
+// created_at keeps its default and updated_at is maintained by trigger.
+var columns = []string{
+	"document_id", "doc_type_id", "document_status_id", "original_address_id",
+	"matched_address_id", "matched_location_id", "match_method_id", "match_decision_id",
+	"property_type_id", "application_status_id", "development_type_id",
+	"application_date_id", "decision_date_id", "import_date_id",
+	"match_confidence_score", "address_quality_score", "data_completeness_score", "processing_time_ms",
+	"import_batch_id", "planning_reference", "is_auto_processed", "has_validation_issues",
+	"additional_measures", "processing_version",
+}
+
+// rowsQuery selects the fact row of every live document with an address; documents a
+// later import batch removed lose theirs. Callers append further conditions on sd.
+const rowsQuery = `
+	SELECT
+		sd.document_id,
+		COALESCE(sd.doc_type_id, 1) AS doc_type_id,
+		1 AS document_status_id,
+		oa.original_address_id,
+		m.address_id AS matched_address_id,
+		m.location_id AS matched_location_id,
+		COALESCE(m.method_id, (SELECT method_id FROM dim_match_method WHERE method_code = 'no_match' LIMIT 1), 1) AS match_method_id,
+		CASE
+			WHEN m.confidence >= 0.85 THEN (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'AUTO_ACCEPT' LIMIT 1)
+			WHEN m.confidence >= 0.50 THEN (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'NEEDS_REVIEW' LIMIT 1)
+			WHEN m.confidence >= 0.20 THEN (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'LOW_CONFIDENCE' LIMIT 1)
+			ELSE (SELECT match_decision_id FROM dim_match_decision WHERE decision_code = 'NO_MATCH' LIMIT 1)
+		END AS match_decision_id,
+		NULL::INTEGER AS property_type_id,
+		NULL::INTEGER AS application_status_id,
+		NULL::INTEGER AS development_type_id,
+		CASE WHEN sd.document_date IS NOT NULL THEN TO_CHAR(sd.document_date, 'YYYYMMDD')::INTEGER END AS application_date_id,
+		NULL::INTEGER AS decision_date_id,
+		TO_CHAR(CURRENT_DATE, 'YYYYMMDD')::INTEGER AS import_date_id,
+		m.confidence AS match_confidence_score,
+		CASE
+			WHEN m.confidence IS NOT NULL THEN m.confidence
+			WHEN sd.gopostal_processed = TRUE THEN 0.3
+			ELSE 0.1
+		END AS address_quality_score,
+		(
+			CASE WHEN sd.raw_address IS NOT NULL AND sd.raw_address != '' THEN 0.2 ELSE 0 END +
+			CASE WHEN sd.gopostal_postcode IS NOT NULL AND sd.gopostal_postcode != '' THEN 0.2 ELSE 0 END +
+			CASE WHEN sd.external_reference IS NOT NULL AND sd.external_reference != '' THEN 0.2 ELSE 0 END +
+			CASE WHEN sd.document_date IS NOT NULL THEN 0.2 ELSE 0 END +
+			CASE WHEN sd.job_number IS NOT NULL AND sd.job_number != '' THEN 0.2 ELSE 0 END
+		) AS data_completeness_score,
+		CASE
+			WHEN m.address_id IS NULL THEN 50
+			WHEN m.confidence >= 0.95 THEN 100
+			WHEN m.confidence >= 0.85 THEN 150
+			WHEN m.confidence >= 0.70 THEN 200
+			ELSE 300
+		END AS processing_time_ms,
+		1 AS import_batch_id,
+		sd.external_reference AS planning_reference,
+		COALESCE(m.confidence >= 0.85, FALSE) AS is_auto_processed,
+		CASE
+			WHEN sd.gopostal_processed = FALSE THEN TRUE
+			WHEN m.address_id IS NULL AND sd.gopostal_processed = TRUE THEN TRUE
+			WHEN m.confidence < 0.7 THEN TRUE
+			ELSE FALSE
+		END AS has_validation_issues,
+		jsonb_build_object(
+			'original_source', 'src_document',
+			'has_uprn', sd.raw_uprn IS NOT NULL,
+			'has_coordinates', m.location_id IS NOT NULL,
+			'gopostal_processed', sd.gopostal_processed,
+			'job_number', sd.job_number,
+			'filepath', sd.filepath,
+			'has_correction', amc.document_id IS NOT NULL,
+			'correction_reason', amc.correction_reason,
+			'accepted', m.accepted,
+			'accepted_by', CASE WHEN m.accepted THEN ma.accepted_by END
+		) AS additional_measures,
+		'1.2-with-acceptances' AS processing_version
+	FROM src_document sd
+	INNER JOIN dim_original_address oa ON oa.address_hash = MD5(LOWER(TRIM(sd.raw_address)))
+	LEFT JOIN address_match am ON am.document_id = sd.document_id
+	LEFT JOIN address_match_corrected amc ON amc.document_id = sd.document_id
+	LEFT JOIN match_accepted ma ON ma.src_id = sd.document_id
+	LEFT JOIN LATERAL (
+		SELECT da.address_id, da.location_id
+		FROM dim_address da
+		WHERE da.uprn = ma.uprn
+		ORDER BY da.address_id
+		LIMIT 1
+	) acc ON TRUE
+	CROSS JOIN LATERAL (
+		SELECT
+			acc.address_id IS NOT NULL AS accepted,
+			CASE WHEN acc.address_id IS NOT NULL THEN acc.address_id
+				ELSE COALESCE(amc.corrected_address_id, am.address_id) END AS address_id,
+			CASE WHEN acc.address_id IS NOT NULL THEN acc.location_id
+				ELSE COALESCE(amc.corrected_location_id, am.location_id) END AS location_id,
+			CASE WHEN acc.address_id IS NOT NULL
+				THEN COALESCE((SELECT method_id FROM dim_match_method WHERE method_code = ma.method LIMIT 1),
+					amc.corrected_method_id, am.match_method_id)
+				ELSE COALESCE(amc.corrected_method_id, am.match_method_id) END AS method_id,
+			CASE WHEN acc.address_id IS NOT NULL THEN COALESCE(ma.confidence, ma.score, 1.0)
+				ELSE COALESCE(amc.corrected_confidence_score, am.confidence_score) END AS confidence
+	) m
+	WHERE sd.raw_address IS NOT NULL
+	  AND sd.raw_address != ''
+	  AND sd.removed_at IS NULL`
+
+// insertPrefix inserts rowsQuery's columns into fact_documents_lean
+var insertPrefix = "INSERT INTO fact_documents_lean (" + strings.Join(columns, ", ") + ")"
+
+// upsertSuffix replaces the existing row of a document, keeping its fact_id and created_at
+var upsertSuffix = func() string {
+	set := make([]string, 0, len(columns)-1)
+	for _, c := range columns[1:] {
+		set = append(set, c+" = EXCLUDED."+c)
+	}
+	return " ON CONFLICT (document_id) DO UPDATE SET " + strings.Join(set, ", ")
+}()
+
+// ApplyStats summarises an Apply or Refresh
+type ApplyStats struct {
+	Changes   int   // change log entries applied
+	Documents int   // distinct documents rebuilt
+	Updated   int64 // fact rows inserted or replaced
+	Removed   int64 // fact rows deleted: the document was removed or lost its address
+}
+
+func (s *ApplyStats) add(o ApplyStats) {
+	s.Changes += o.Changes
+	s.Documents += o.Documents
+	s.Updated += o.Updated
+	s.Removed += o.Removed
+}
+
+// Rebuild replaces every fact row in one transaction, so readers wait for it rather than
+// see an empty table, and marks the change log applied up to the rebuild. It returns the
+// rows written.
+func Rebuild(ctx context.Context, localDebug bool, db *sql.DB) (int64, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	through, err := LastChange(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "TRUNCATE TABLE fact_documents_lean"); err != nil {
+		return 0, fmt.Errorf("failed to truncate fact table: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, insertPrefix+rowsQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild fact table: %w", err)
+	}
+	written, _ := result.RowsAffected()
+	debug.DebugOutput(localDebug, "Wrote %d fact rows", written)
+
+	if err := MarkRebuilt(ctx, tx, through); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit fact table rebuild: %w", err)
+	}
+	return written, nil
+}
+
+// Querier is satisfied by *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// LastChange returns the newest change_id, 0 when the log is empty. Record it before a
+// full rebuild starts and pass it to MarkRebuilt when the rebuild is done.
+func LastChange(ctx context.Context, q Querier) (int64, error) {
+	var id int64
+	if err := q.QueryRowContext(ctx, "SELECT COALESCE(MAX(change_id), 0) FROM fact_change_log").Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to read fact change log: %w", err)
+	}
+	return id, nil
+}
+
+// MarkRebuilt marks changes up to through applied; a full rebuild has included them
+func MarkRebuilt(ctx context.Context, q Querier, through int64) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE fact_change_log SET applied_at = now()
+		WHERE applied_at IS NULL AND change_id <= $1
+	`, through)
+	if err != nil {
+		return fmt.Errorf("failed to mark fact changes applied: %w", err)
+	}
+	return nil
+}
+
+// Pending counts unapplied changes and the distinct documents they name
+func Pending(ctx context.Context, db *sql.DB) (changes, documents int, err error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT document_id)
+		FROM fact_change_log
+		WHERE applied_at IS NULL
+	`).Scan(&changes, &documents)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count pending fact changes: %w", err)
+	}
+	return changes, documents, nil
+}
+
+// Apply drains the change log in batches of batchSize entries, rebuilding the fact rows of
+// the documents named. Batches are claimed with SKIP LOCKED, so several appliers can run.
+// Each batch commits on its own; an interrupted Apply keeps the batches it finished.
+func Apply(ctx context.Context, localDebug bool, db *sql.DB, batchSize int) (*ApplyStats, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	stats := &ApplyStats{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		batch, err := applyBatch(ctx, db, batchSize)
+		if err != nil {
+			return stats, err
+		}
+		stats.add(batch)
+		debug.DebugOutput(localDebug, "Applied %d changes to %d documents", batch.Changes, batch.Documents)
+		if batch.Changes < batchSize {
+			return stats, nil
+		}
+	}
+}
+
+// applyBatch claims up to limit pending changes and rebuilds their documents' rows
+func applyBatch(ctx context.Context, db *sql.DB, limit int) (ApplyStats, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ApplyStats{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	changeIDs, documentIDs, err := claimChanges(ctx, tx, `
+		SELECT change_id, document_id
+		FROM fact_change_log
+		WHERE applied_at IS NULL
+		ORDER BY change_id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil || len(changeIDs) == 0 {
+		return ApplyStats{}, err
+	}
+
+	stats, err := refreshDocuments(ctx, tx, documentIDs, changeIDs)
+	if err != nil {
+		return ApplyStats{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ApplyStats{}, fmt.Errorf("failed to commit fact changes: %w", err)
+	}
+	return stats, nil
+}
+
+// Refresh rebuilds the fact rows of the given documents now, marking their pending changes
+// applied. The web UI calls it after a decision so the reviewer sees it in the exports.
+func Refresh(ctx context.Context, db *sql.DB, documentIDs ...int64) (*ApplyStats, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	stats, err := refresh(ctx, db, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// refresh rebuilds the fact rows of documentIDs in one transaction, with any pending
+// changes that name them
+func refresh(ctx context.Context, db *sql.DB, documentIDs []int64) (ApplyStats, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ApplyStats{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stats, err := refreshPending(ctx, tx, documentIDs)
+	if err != nil {
+		return ApplyStats{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ApplyStats{}, fmt.Errorf("failed to commit fact refresh: %w", err)
+	}
+	return stats, nil
+}
+
+// refreshPending rebuilds the fact rows of documentIDs and marks the pending changes that
+// name them applied
+func refreshPending(ctx context.Context, tx *sql.Tx, documentIDs []int64) (ApplyStats, error) {
+	changeIDs, _, err := claimChanges(ctx, tx, `
+		SELECT change_id, document_id
+		FROM fact_change_log
+		WHERE applied_at IS NULL AND document_id = ANY($1)
+		FOR UPDATE
+	`, pq.Array(documentIDs))
+	if err != nil {
+		return ApplyStats{}, err
+	}
+	return refreshDocuments(ctx, tx, documentIDs, changeIDs)
+}
+
+// claimChanges reads (change_id, document_id) rows, returning the change IDs and the
+// distinct document IDs
+func claimChanges(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]int64, []int64, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to claim fact changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changeIDs, documentIDs []int64
+	seen := make(map[int64]bool)
+	for rows.Next() {
+		var changeID, documentID int64
+		if err := rows.Scan(&changeID, &documentID); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan fact change: %w", err)
+		}
+		changeIDs = append(changeIDs, changeID)
+		if !seen[documentID] {
+			seen[documentID] = true
+			documentIDs = append(documentIDs, documentID)
+		}
+	}
+	return changeIDs, documentIDs, rows.Err()
+}
+
+// refreshDocuments rebuilds the fact rows of documentIDs and marks changeIDs applied.
+// Documents rowsQuery no longer selects lose their fact row.
+func refreshDocuments(ctx context.Context, tx *sql.Tx, documentIDs, changeIDs []int64) (ApplyStats, error) {
+	stats := ApplyStats{Changes: len(changeIDs), Documents: len(documentIDs)}
+
+	rows, err := tx.QueryContext(ctx, insertPrefix+rowsQuery+`
+		  AND sd.document_id = ANY($1)`+upsertSuffix+`
+		RETURNING document_id`, pq.Array(documentIDs))
+	if err != nil {
+		return stats, fmt.Errorf("failed to rebuild fact rows: %w", err)
+	}
+	kept := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return stats, fmt.Errorf("failed to scan rebuilt fact row: %w", err)
+		}
+		kept = append(kept, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("failed to rebuild fact rows: %w", err)
+	}
+	stats.Updated = int64(len(kept))
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM fact_documents_lean
+		WHERE document_id = ANY($1) AND NOT document_id = ANY($2)
+	`, pq.Array(documentIDs), pq.Array(kept))
+	if err != nil {
+		return stats, fmt.Errorf("failed to remove stale fact rows: %w", err)
+	}
+	stats.Removed, _ = result.RowsAffected()
+
+	if len(changeIDs) > 0 {
+		_, err = tx.ExecContext(ctx, "UPDATE fact_change_log SET applied_at = now() WHERE change_id = ANY($1)", pq.Array(changeIDs))
+		if err != nil {
+			return stats, fmt.Errorf("failed to mark fact changes applied: %w", err)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,94 @@
+package facts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// LayerMatch is a matching layer's match of some documents to one address. MatchedBy
+// should start with "system" so the address_match trigger drops rejected pairs.
+type LayerMatch struct {
+	AddressID  int
+	MethodID   int
+	Confidence float64
+	MatchedBy  string
+}
+
+// Document selectors for SaveMatch, each taking its value as $1
+const (
+	ByDocument        = "SELECT $1::INTEGER"
+	ByOriginalAddress = "SELECT document_id FROM fact_documents_lean WHERE original_address_id = $1"
+	ByRawAddress      = "SELECT f.document_id FROM fact_documents_lean f JOIN dim_original_address oa ON oa.original_address_id = f.original_address_id WHERE oa.raw_address = $1"
+	ByPlanningAppBase = "SELECT document_id FROM src_document WHERE planning_app_base = $1 AND removed_at IS NULL"
+)
+
+// saveMatchQuery records a layer match in address_match for the unmatched documents of
+// a subquery on $1. The rejection trigger drops the rows of rejected pairs, so RETURNING
+// names only the documents actually matched.
+const saveMatchQuery = `
+	INSERT INTO address_match (
+		document_id, address_id, location_id, match_method_id,
+		confidence_score, match_status, matched_by, matched_at
+	)
+	SELECT f.document_id, da.address_id, da.location_id, $3, $4, 'auto', $5, now()
+	FROM fact_documents_lean f
+	JOIN dim_address da ON da.address_id = $2
+	WHERE f.document_id IN (%s)
+	  AND f.matched_address_id IS NULL
+	ON CONFLICT (document_id) DO UPDATE SET
+		address_id = EXCLUDED.address_id,
+		location_id = EXCLUDED.location_id,
+		match_method_id = EXCLUDED.match_method_id,
+		confidence_score = EXCLUDED.confidence_score,
+		match_status = EXCLUDED.match_status,
+		matched_by = EXCLUDED.matched_by,
+		matched_at = now()
+	RETURNING document_id`
+
+// SaveMatch records a layer's match of the unmatched documents selected by documents, a
+// query of document_id taking arg as $1, and rebuilds their fact rows from rowsQuery.
+// Layers write address_match rather than fact_documents_lean so their matches survive
+// Apply, Refresh and a full rebuild. It returns the documents matched.
+func SaveMatch(ctx context.Context, db *sql.DB, m LayerMatch, documents string, arg interface{}) (int64, error) {
+	if !strings.HasPrefix(m.MatchedBy, "system") {
+		return 0, fmt.Errorf("layer match by %q must be a system_ source", m.MatchedBy)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(saveMatchQuery, documents),
+		arg, m.AddressID, m.MethodID, m.Confidence, m.MatchedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save layer match: %w", err)
+	}
+	var documentIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan matched document: %w", err)
+		}
+		documentIDs = append(documentIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to save layer match: %w", err)
+	}
+	if len(documentIDs) == 0 {
+		return 0, nil
+	}
+
+	if _, err := refreshPending(ctx, tx, documentIDs); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit layer match: %w", err)
+	}
+	return int64(len(documentIDs)), nil
+}
@@ -0,0 +1,165 @@
+package facts
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB models address_match, fact_documents_lean and fact_change_log closely enough to
+// run SaveMatch and Refresh: the fact row rebuild takes each document's match from
+// address_match, as rowsQuery does.
+type fakeDB struct {
+	mu       sync.Mutex
+	original map[int64]int64 // document -> original_address_id
+	matches  map[int64]int64 // address_match: document -> address
+	facts    map[int64]int64 // fact_documents_lean: document -> matched address, 0 unmatched
+	changes  []int64         // fact_change_log documents, in change_id order
+	applied  map[int64]bool
+}
+
+func (f *fakeDB) Open(string) (driver.Conn, error) { return fakeConn{f}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows.(*fakeRows).values)), nil
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	f := c.db
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, "INSERT INTO address_match"):
+		original, address := args[0].Value.(int64), args[1].Value.(int64)
+		var matched [][]driver.Value
+		for doc, o := range f.original {
+			if o == original && f.facts[doc] == 0 {
+				f.matches[doc] = address
+				f.changes = append(f.changes, doc)
+				matched = append(matched, []driver.Value{doc})
+			}
+		}
+		return &fakeRows{columns: []string{"document_id"}, values: matched}, nil
+
+	case strings.Contains(query, "FROM fact_change_log") && strings.Contains(query, "FOR UPDATE"):
+		docs := idSet(args[0].Value)
+		var pending [][]driver.Value
+		for i, doc := range f.changes {
+			if !f.applied[int64(i+1)] && docs[doc] {
+				pending = append(pending, []driver.Value{int64(i + 1), doc})
+			}
+		}
+		return &fakeRows{columns: []string{"change_id", "document_id"}, values: pending}, nil
+
+	case strings.HasPrefix(query, insertPrefix+rowsQuery):
+		var kept [][]driver.Value
+		for doc := range idSet(args[0].Value) {
+			if _, ok := f.original[doc]; ok {
+				f.facts[doc] = f.matches[doc]
+				kept = append(kept, []driver.Value{doc})
+			}
+		}
+		return &fakeRows{columns: []string{"document_id"}, values: kept}, nil
+
+	case strings.Contains(query, "DELETE FROM fact_documents_lean"):
+		return &fakeRows{}, nil
+
+	case strings.Contains(query, "UPDATE fact_change_log"):
+		for id := range idSet(args[0].Value) {
+			f.applied[id] = true
+		}
+		return &fakeRows{}, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+// idSet parses a pq.Array literal such as {1,2}
+func idSet(v driver.Value) map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, s := range strings.Split(strings.Trim(v.(string), "{}"), ",") {
+		if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
+
+func TestLayerMatchSurvivesRefresh(t *testing.T) {
+	fake := &fakeDB{
+		original: map[int64]int64{1: 100, 2: 100, 3: 101},
+		matches:  map[int64]int64{},
+		facts:    map[int64]int64{1: 0, 2: 0, 3: 0},
+		applied:  map[int64]bool{},
+	}
+	sql.Register("facts-layer-test", fake)
+	db, err := sql.Open("facts-layer-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	matched, err := SaveMatch(ctx, db, LayerMatch{AddressID: 500, MethodID: 5, Confidence: 0.9, MatchedBy: "system_layer2"},
+		ByOriginalAddress, int64(100))
+	if err != nil {
+		t.Fatalf("SaveMatch: %v", err)
+	}
+	if matched != 2 || fake.facts[1] != 500 || fake.facts[2] != 500 || fake.facts[3] != 0 {
+		t.Fatalf("after SaveMatch: matched %d, facts %v", matched, fake.facts)
+	}
+	if len(fake.applied) != 2 {
+		t.Errorf("SaveMatch left changes %v pending", fake.changes)
+	}
+
+	// A later refresh of the same documents, as after a reviewer decision, keeps the match
+	if _, err := Refresh(ctx, db, 1, 2, 3); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if fake.facts[1] != 500 || fake.facts[2] != 500 || fake.facts[3] != 0 {
+		t.Errorf("after Refresh: facts %v, want documents 1 and 2 matched to 500", fake.facts)
+	}
+
+	if _, err := SaveMatch(ctx, db, LayerMatch{AddressID: 500, MatchedBy: "layer2"}, ByDocument, int64(3)); err == nil {
+		t.Error("SaveMatch accepted a non-system source, which the rejection trigger would not check")
+	}
+}
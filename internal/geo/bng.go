@@ -0,0 +1,369 @@
+// Package geo converts between OSGB36 British National Grid (EPSG:27700) and
+// ETRS89/WGS84 latitude/longitude without a database.
+//
+// The default Helmert transformation is accurate to a few metres, which is
+// enough for map display and viewport filtering. When the OSTN15 grid file is
+// available locally the OSTN15 mode is accurate to around 0.1m. ETRS89 and
+// WGS84 are treated as identical, as the OS guide does for sub-metre work.
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/ehdc-llpg/internal/config"
+)
+
+// ErrNotFinite is returned for a NaN or infinite easting or northing
+var ErrNotFinite = errors.New("easting and northing must be finite")
+
+// fromGridIterations caps the latitude iteration in fromGrid, which takes a handful of
+// steps for any coordinate on the grid
+const fromGridIterations = 100
+
+// Transformation modes
+const (
+	ModeHelmert = "helmert"
+	ModeOSTN15  = "ostn15"
+)
+
+// BNGPoint is an OSGB36 National Grid coordinate in metres
+type BNGPoint struct {
+	Easting  float64 `json:"easting"`
+	Northing float64 `json:"northing"`
+}
+
+// LatLng is an ETRS89/WGS84 coordinate in decimal degrees
+type LatLng struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// ellipsoid axes in metres
+type ellipsoid struct {
+	a, b float64
+}
+
+var (
+	airy1830 = ellipsoid{a: 6377563.396, b: 6356256.909}
+	grs80    = ellipsoid{a: 6378137.000, b: 6356752.3141}
+)
+
+// National Grid transverse Mercator projection
+const (
+	gridF0 = 0.9996012717
+	gridE0 = 400000.0
+	gridN0 = -100000.0
+)
+
+var (
+	gridLat0 = 49 * math.Pi / 180
+	gridLon0 = -2 * math.Pi / 180
+)
+
+// helmert holds 7-parameter transformation values (metres, ppm, arc-seconds)
+type helmert struct {
+	tx, ty, tz float64
+	s          float64
+	rx, ry, rz float64
+}
+
+// WGS84 -> OSGB36 parameters from the OS guide; the reverse uses the negated values
+var wgs84ToOSGB36 = helmert{
+	tx: -446.448, ty: 125.157, tz: -542.060,
+	s:  20.4894,
+	rx: -0.1502, ry: -0.2470, rz: -0.8421,
+}
+
+func (h helmert) inverse() helmert {
+	return helmert{tx: -h.tx, ty: -h.ty, tz: -h.tz, s: -h.s, rx: -h.rx, ry: -h.ry, rz: -h.rz}
+}
+
+// Transformer converts coordinates using Helmert or, when loaded, the OSTN15 grid
+type Transformer struct {
+	grid *ostnGrid
+}
+
+// NewHelmertTransformer returns a transformer using the Helmert approximation
+func NewHelmertTransformer() *Transformer {
+	return &Transformer{}
+}
+
+// NewOSTN15Transformer loads the OSTN15 grid file (OSTN15_OSGM15_DataFile.txt).
+// Points outside the grid's coverage fall back to Helmert.
+func NewOSTN15Transformer(gridPath string) (*Transformer, error) {
+	grid, err := loadOSTNGrid(gridPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Transformer{grid: grid}, nil
+}
+
+// NewTransformer uses the OSTN15 grid named by OSTN15_GRID_FILE when it exists locally,
+// otherwise Helmert
+func NewTransformer() *Transformer {
+	if path := config.Current().Paths.OSTN15GridFile; path != "" {
+		if _, err := os.Stat(path); err == nil {
+			if t, err := NewOSTN15Transformer(path); err == nil {
+				return t
+			}
+		}
+	}
+	return NewHelmertTransformer()
+}
+
+// Mode reports which transformation is in use
+func (t *Transformer) Mode() string {
+	if t.grid != nil {
+		return ModeOSTN15
+	}
+	return ModeHelmert
+}
+
+// ToWGS84 converts a BNG easting/northing to latitude/longitude. It fails for a
+// coordinate that is not finite or too far off the grid to project.
+func (t *Transformer) ToWGS84(easting, northing float64) (lat, lng float64, err error) {
+	if !finite(easting) || !finite(northing) {
+		return 0, 0, ErrNotFinite
+	}
+	if t.grid != nil {
+		if e, n, ok := t.grid.toETRS89Grid(easting, northing); ok {
+			phi, lambda, err := fromGrid(e, n, grs80)
+			if err != nil {
+				return 0, 0, err
+			}
+			return degrees(phi), degrees(lambda), nil
+		}
+	}
+
+	phi, lambda, err := fromGrid(easting, northing, airy1830)
+	if err != nil {
+		return 0, 0, err
+	}
+	phi, lambda = transformDatum(phi, lambda, airy1830, grs80, wgs84ToOSGB36.inverse())
+	return degrees(phi), degrees(lambda), nil
+}
+
+// ToBNG converts latitude/longitude to a BNG easting/northing
+func (t *Transformer) ToBNG(lat, lng float64) (easting, northing float64) {
+	phi, lambda := radians(lat), radians(lng)
+
+	if t.grid != nil {
+		e, n := toGrid(phi, lambda, grs80)
+		if se, sn, ok := t.grid.shiftAt(e, n); ok {
+			return e + se, n + sn
+		}
+	}
+
+	phi, lambda = transformDatum(phi, lambda, grs80, airy1830, wgs84ToOSGB36)
+	return toGrid(phi, lambda, airy1830)
+}
+
+// ToWGS84Batch converts a slice of BNG points, failing on the first that cannot be converted
+func (t *Transformer) ToWGS84Batch(points []BNGPoint) ([]LatLng, error) {
+	out := make([]LatLng, len(points))
+	for i, p := range points {
+		var err error
+		if out[i].Lat, out[i].Lng, err = t.ToWGS84(p.Easting, p.Northing); err != nil {
+			return nil, fmt.Errorf("point %d (%v, %v): %w", i, p.Easting, p.Northing, err)
+		}
+	}
+	return out, nil
+}
+
+// ToBNGBatch converts a slice of latitude/longitude points
+func (t *Transformer) ToBNGBatch(points []LatLng) []BNGPoint {
+	out := make([]BNGPoint, len(points))
+	for i, p := range points {
+		out[i].Easting, out[i].Northing = t.ToBNG(p.Lat, p.Lng)
+	}
+	return out
+}
+
+// Point is a GeoJSON Point geometry; its coordinates are longitude then latitude
+type Point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// ToPoint converts a BNG easting/northing to a GeoJSON Point
+func (t *Transformer) ToPoint(easting, northing float64) (Point, error) {
+	lat, lng, err := t.ToWGS84(easting, northing)
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{Type: "Point", Coordinates: [2]float64{lng, lat}}, nil
+}
+
+var defaultTransformer = NewHelmertTransformer()
+
+// ToWGS84 converts BNG to latitude/longitude with the Helmert transformation
+func ToWGS84(easting, northing float64) (lat, lng float64, err error) {
+	return defaultTransformer.ToWGS84(easting, northing)
+}
+
+// ToBNG converts latitude/longitude to BNG with the Helmert transformation
+func ToBNG(lat, lng float64) (easting, northing float64) {
+	return defaultTransformer.ToBNG(lat, lng)
+}
+
+// Distance returns the planar distance in metres between two BNG points
+func Distance(e1, n1, e2, n2 float64) float64 {
+	return math.Hypot(e1-e2, n1-n2)
+}
+
+// toGrid projects geodetic coordinates (radians) onto the National Grid
+func toGrid(phi, lambda float64, ell ellipsoid) (easting, northing float64) {
+	a, b := ell.a, ell.b
+	e2 := 1 - (b*b)/(a*a)
+	n := (a - b) / (a + b)
+
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	tanPhi := math.Tan(phi)
+
+	nu := a * gridF0 / math.Sqrt(1-e2*sinPhi*sinPhi)
+	rho := a * gridF0 * (1 - e2) / math.Pow(1-e2*sinPhi*sinPhi, 1.5)
+	eta2 := nu/rho - 1
+
+	m := meridionalArc(phi, b, n)
+
+	cos3 := cosPhi * cosPhi * cosPhi
+	cos5 := cos3 * cosPhi * cosPhi
+	tan2 := tanPhi * tanPhi
+	tan4 := tan2 * tan2
+
+	i := m + gridN0
+	ii := nu / 2 * sinPhi * cosPhi
+	iii := nu / 24 * sinPhi * cos3 * (5 - tan2 + 9*eta2)
+	iiia := nu / 720 * sinPhi * cos5 * (61 - 58*tan2 + tan4)
+	iv := nu * cosPhi
+	v := nu / 6 * cos3 * (nu/rho - tan2)
+	vi := nu / 120 * cos5 * (5 - 18*tan2 + tan4 + 14*eta2 - 58*tan2*eta2)
+
+	dl := lambda - gridLon0
+	dl2 := dl * dl
+
+	northing = i + ii*dl2 + iii*dl2*dl2 + iiia*dl2*dl2*dl2
+	easting = gridE0 + iv*dl + v*dl2*dl + vi*dl2*dl2*dl
+	return easting, northing
+}
+
+// fromGrid converts a National Grid coordinate to geodetic coordinates (radians). The
+// latitude is found by iteration, which fails for input that is not finite or does not
+// converge within fromGridIterations steps.
+func fromGrid(easting, northing float64, ell ellipsoid) (phi, lambda float64, err error) {
+	if !finite(easting) || !finite(northing) {
+		return 0, 0, ErrNotFinite
+	}
+
+	a, b := ell.a, ell.b
+	e2 := 1 - (b*b)/(a*a)
+	n := (a - b) / (a + b)
+
+	phi = gridLat0
+	m := 0.0
+	converged := false
+	for i := 0; i < fromGridIterations; i++ {
+		phi = (northing-gridN0-m)/(a*gridF0) + phi
+		m = meridionalArc(phi, b, n)
+		if math.Abs(northing-gridN0-m) < 0.00001 {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return 0, 0, fmt.Errorf("latitude for (%v, %v) did not converge after %d iterations", easting, northing, fromGridIterations)
+	}
+
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	tanPhi := math.Tan(phi)
+
+	nu := a * gridF0 / math.Sqrt(1-e2*sinPhi*sinPhi)
+	rho := a * gridF0 * (1 - e2) / math.Pow(1-e2*sinPhi*sinPhi, 1.5)
+	eta2 := nu/rho - 1
+
+	tan2 := tanPhi * tanPhi
+	tan4 := tan2 * tan2
+	tan6 := tan4 * tan2
+	secPhi := 1 / cosPhi
+	nu3 := nu * nu * nu
+	nu5 := nu3 * nu * nu
+	nu7 := nu5 * nu * nu
+
+	vii := tanPhi / (2 * rho * nu)
+	viii := tanPhi / (24 * rho * nu3) * (5 + 3*tan2 + eta2 - 9*tan2*eta2)
+	ix := tanPhi / (720 * rho * nu5) * (61 + 90*tan2 + 45*tan4)
+	x := secPhi / nu
+	xi := secPhi / (6 * nu3) * (nu/rho + 2*tan2)
+	xii := secPhi / (120 * nu5) * (5 + 28*tan2 + 24*tan4)
+	xiia := secPhi / (5040 * nu7) * (61 + 662*tan2 + 1320*tan4 + 720*tan6)
+
+	de := easting - gridE0
+	de2 := de * de
+
+	phi = phi - vii*de2 + viii*de2*de2 - ix*de2*de2*de2
+	lambda = gridLon0 + x*de - xi*de2*de + xii*de2*de2*de - xiia*de2*de2*de2*de
+	return phi, lambda, nil
+}
+
+// meridionalArc is the developed meridional arc M for latitude phi
+func meridionalArc(phi, b, n float64) float64 {
+	n2, n3 := n*n, n*n*n
+	dPhi, sPhi := phi-gridLat0, phi+gridLat0
+
+	ma := (1 + n + 5.0/4*n2 + 5.0/4*n3) * dPhi
+	mb := (3*n + 3*n2 + 21.0/8*n3) * math.Sin(dPhi) * math.Cos(sPhi)
+	mc := (15.0/8*n2 + 15.0/8*n3) * math.Sin(2*dPhi) * math.Cos(2*sPhi)
+	md := 35.0 / 24 * n3 * math.Sin(3*dPhi) * math.Cos(3*sPhi)
+
+	return b * gridF0 * (ma - mb + mc - md)
+}
+
+// transformDatum moves geodetic coordinates between ellipsoids via a Helmert transformation
+func transformDatum(phi, lambda float64, from, to ellipsoid, h helmert) (float64, float64) {
+	x, y, z := toCartesian(phi, lambda, from)
+
+	s := h.s * 1e-6
+	rx := radians(h.rx / 3600)
+	ry := radians(h.ry / 3600)
+	rz := radians(h.rz / 3600)
+
+	x2 := h.tx + (1+s)*x - rz*y + ry*z
+	y2 := h.ty + rz*x + (1+s)*y - rx*z
+	z2 := h.tz - ry*x + rx*y + (1+s)*z
+
+	return fromCartesian(x2, y2, z2, to)
+}
+
+func toCartesian(phi, lambda float64, ell ellipsoid) (x, y, z float64) {
+	e2 := 1 - (ell.b*ell.b)/(ell.a*ell.a)
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	nu := ell.a / math.Sqrt(1-e2*sinPhi*sinPhi)
+
+	return nu * cosPhi * math.Cos(lambda), nu * cosPhi * math.Sin(lambda), (1 - e2) * nu * sinPhi
+}
+
+func fromCartesian(x, y, z float64, ell ellipsoid) (phi, lambda float64) {
+	e2 := 1 - (ell.b*ell.b)/(ell.a*ell.a)
+	p := math.Hypot(x, y)
+
+	phi = math.Atan2(z, p*(1-e2))
+	for i := 0; i < 10; i++ {
+		sinPhi := math.Sin(phi)
+		nu := ell.a / math.Sqrt(1-e2*sinPhi*sinPhi)
+		next := math.Atan2(z+e2*nu*sinPhi, p)
+		if math.Abs(next-phi) < 1e-12 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+
+	return phi, math.Atan2(y, x)
+}
+
+func finite(v float64) bool       { return !math.IsNaN(v) && !math.IsInf(v, 0) }
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
@@ -0,0 +1,172 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Worked example from the OS "Guide to coordinate systems in Great Britain"
+var (
+	caisterLat = 52 + 39.0/60 + 28.8282/3600 // ETRS89
+	caisterLng = 1 + 42.0/60 + 57.8663/3600
+	caisterE   = 651409.903 // OSGB36
+	caisterN   = 313177.270
+)
+
+func TestProjectionMatchesOSGuide(t *testing.T) {
+	// OSGB36 latitude/longitude of the same point
+	phi := radians(52 + 39.0/60 + 27.2531/3600)
+	lambda := radians(1 + 43.0/60 + 4.5177/3600)
+
+	e, n := toGrid(phi, lambda, airy1830)
+	if math.Abs(e-caisterE) > 0.001 || math.Abs(n-caisterN) > 0.001 {
+		t.Errorf("toGrid = (%.3f, %.3f), want (%.3f, %.3f)", e, n, caisterE, caisterN)
+	}
+
+	backPhi, backLambda, err := fromGrid(e, n, airy1830)
+	if err != nil {
+		t.Fatalf("fromGrid: %v", err)
+	}
+	if math.Abs(degrees(backPhi-phi)) > 1e-8 || math.Abs(degrees(backLambda-lambda)) > 1e-8 {
+		t.Errorf("fromGrid did not invert toGrid")
+	}
+}
+
+func TestHelmertAccuracy(t *testing.T) {
+	e, n := ToBNG(caisterLat, caisterLng)
+	if d := Distance(e, n, caisterE, caisterN); d > 5 {
+		t.Errorf("Helmert ToBNG is %.2fm from the OS result (%.3f, %.3f)", d, e, n)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	points := []BNGPoint{
+		{Easting: 474793, Northing: 122942}, // Petersfield
+		{Easting: 471700, Northing: 139300}, // Alton
+		{Easting: 470854.738, Northing: 116133.415},
+	}
+
+	latlngs, err := NewHelmertTransformer().ToWGS84Batch(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := NewHelmertTransformer().ToBNGBatch(latlngs)
+	for i, p := range points {
+		if d := Distance(p.Easting, p.Northing, back[i].Easting, back[i].Northing); d > 0.01 {
+			t.Errorf("round trip of %+v drifted %.4fm", p, d)
+		}
+	}
+}
+
+func TestOSTN15Grid(t *testing.T) {
+	// A small grid around Petersfield with a constant shift is enough to check the
+	// interpolation and the iterative inverse
+	path := filepath.Join(t.TempDir(), "ostn15.txt")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "Point_ID,ETRS89_Easting,ETRS89_Northing,ETRS89_OSGB36_EShift,ETRS89_OSGB36_NShift,ETRS89_ODN_HeightShift,Height_Datum_Flag")
+	id := 1
+	for e := 470000; e <= 480000; e += 1000 {
+		for n := 118000; n <= 128000; n += 1000 {
+			fmt.Fprintf(f, "%d,%d,%d,95.000,-80.000,46.000,1\n", id, e, n)
+			id++
+		}
+	}
+	f.Close()
+
+	tr, err := NewOSTN15Transformer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Mode() != ModeOSTN15 {
+		t.Fatalf("Mode() = %s, want %s", tr.Mode(), ModeOSTN15)
+	}
+
+	lat, lng, err := tr.ToWGS84(474793, 122942)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, n := tr.ToBNG(lat, lng)
+	if d := Distance(e, n, 474793, 122942); d > 0.001 {
+		t.Errorf("OSTN15 round trip drifted %.4fm", d)
+	}
+
+	// Outside the loaded nodes the transformer falls back to Helmert
+	lat, lng, err = tr.ToWGS84(caisterE, caisterN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hLat, hLng, err := ToWGS84(caisterE, caisterN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lat != hLat || lng != hLng {
+		t.Errorf("expected Helmert fallback outside grid coverage")
+	}
+}
+
+func TestToWGS84RejectsUnprojectable(t *testing.T) {
+	tr := NewHelmertTransformer()
+	for _, p := range []BNGPoint{
+		{Easting: math.NaN(), Northing: 122942},
+		{Easting: 474793, Northing: math.Inf(1)},
+		{Easting: math.Inf(-1), Northing: math.NaN()},
+	} {
+		if _, _, err := tr.ToWGS84(p.Easting, p.Northing); !errors.Is(err, ErrNotFinite) {
+			t.Errorf("ToWGS84(%v, %v) error = %v, want ErrNotFinite", p.Easting, p.Northing, err)
+		}
+	}
+
+	if _, _, err := tr.ToWGS84(474793, -1e12); err == nil {
+		t.Errorf("ToWGS84 with a northing far off the grid should fail to converge")
+	}
+	if _, err := tr.ToWGS84Batch([]BNGPoint{{Easting: 474793, Northing: 122942}, {Easting: math.NaN()}}); err == nil {
+		t.Errorf("ToWGS84Batch should fail on a non-finite point")
+	}
+}
+
+func TestCheckCoordinate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name         string
+		e, n         *float64
+		wantStatus   string
+		wantE, wantN float64
+	}{
+		{"valid", f(474793), f(122942), CoordValid, 474793, 122942},
+		{"swapped", f(122942), f(474793), CoordSwapped, 474793, 122942},
+		{"dropped leading digits", f(74793), f(22942), CoordTruncated, 474793, 122942},
+		{"dropped easting digit", f(74793), f(122942), CoordTruncated, 474793, 122942},
+		{"six-figure grid reference", f(747), f(229), CoordTruncated, 474700, 122900},
+		{"lat/lng pasted", f(51.001017), f(-0.935480), CoordLatLng, 474793, 122942},
+		{"lng/lat pasted", f(-0.935480), f(51.001017), CoordLatLng, 474793, 122942},
+		{"zeros", f(0), f(0), CoordPlaceholder, 0, 0},
+		{"out of district", f(530000), f(180000), CoordOutOfDistrict, 0, 0},
+		{"missing", nil, f(122942), CoordMissing, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := CheckCoordinate(tt.e, tt.n)
+			if check.Status != tt.wantStatus {
+				t.Fatalf("Status = %s, want %s (%s)", check.Status, tt.wantStatus, check.Reason)
+			}
+			if tt.wantE == 0 {
+				if check.Easting != nil {
+					t.Errorf("expected no repaired value, got (%v, %v)", *check.Easting, *check.Northing)
+				}
+				return
+			}
+			if check.Easting == nil || Distance(*check.Easting, *check.Northing, tt.wantE, tt.wantN) > 2 {
+				t.Errorf("repaired = %v, want (%v, %v)", check, tt.wantE, tt.wantN)
+			}
+		})
+	}
+}
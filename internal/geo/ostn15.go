@@ -0,0 +1,140 @@
+package geo
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// OSTN15 grid dimensions: 1km nodes from (0,0) to (700000,1250000)
+const (
+	ostnColumns = 701
+	ostnRows    = 1251
+	ostnSpacing = 1000.0
+)
+
+// ostnGrid holds the ETRS89 -> OSGB36 easting/northing shifts at each grid node.
+// Nodes outside the coverage (or missing from the file) hold NaN.
+type ostnGrid struct {
+	eastShift  []float64
+	northShift []float64
+}
+
+// loadOSTNGrid reads the OSTN15_OSGM15_DataFile.txt CSV published by Ordnance Survey:
+// Point_ID,ETRS89_Easting,ETRS89_Northing,ETRS89_OSGB36_EShift,ETRS89_OSGB36_NShift,...
+func loadOSTNGrid(path string) (*ostnGrid, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OSTN15 grid file: %w", err)
+	}
+	defer file.Close()
+
+	grid := &ostnGrid{
+		eastShift:  make([]float64, ostnColumns*ostnRows),
+		northShift: make([]float64, ostnColumns*ostnRows),
+	}
+	for i := range grid.eastShift {
+		grid.eastShift[i] = math.NaN()
+		grid.northShift[i] = math.NaN()
+	}
+
+	scanner := bufio.NewScanner(file)
+	lineNumber, nodes := 0, 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ",")
+		if len(fields) < 5 {
+			continue
+		}
+		// Skip the header row
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+
+		values := make([]float64, 4)
+		for i := range values {
+			if values[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil {
+				return nil, fmt.Errorf("invalid OSTN15 value on line %d: %w", lineNumber, err)
+			}
+		}
+
+		col := int(math.Round(values[0] / ostnSpacing))
+		row := int(math.Round(values[1] / ostnSpacing))
+		if col < 0 || col >= ostnColumns || row < 0 || row >= ostnRows {
+			return nil, fmt.Errorf("OSTN15 node out of range on line %d", lineNumber)
+		}
+
+		// Height datum flag 0 marks nodes outside the transformation's coverage
+		if len(fields) >= 7 && strings.TrimSpace(fields[6]) == "0" {
+			continue
+		}
+
+		idx := row*ostnColumns + col
+		grid.eastShift[idx] = values[2]
+		grid.northShift[idx] = values[3]
+		nodes++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read OSTN15 grid file: %w", err)
+	}
+	if nodes == 0 {
+		return nil, fmt.Errorf("OSTN15 grid file %s contains no nodes", path)
+	}
+
+	return grid, nil
+}
+
+// shiftAt bilinearly interpolates the shifts at an ETRS89 grid position
+func (g *ostnGrid) shiftAt(easting, northing float64) (se, sn float64, ok bool) {
+	col := int(math.Floor(easting / ostnSpacing))
+	row := int(math.Floor(northing / ostnSpacing))
+	if col < 0 || col+1 >= ostnColumns || row < 0 || row+1 >= ostnRows {
+		return 0, 0, false
+	}
+
+	dx := (easting - float64(col)*ostnSpacing) / ostnSpacing
+	dy := (northing - float64(row)*ostnSpacing) / ostnSpacing
+
+	corners := [4]int{
+		row*ostnColumns + col,
+		row*ostnColumns + col + 1,
+		(row+1)*ostnColumns + col + 1,
+		(row+1)*ostnColumns + col,
+	}
+	weights := [4]float64{(1 - dx) * (1 - dy), dx * (1 - dy), dx * dy, (1 - dx) * dy}
+
+	for i, idx := range corners {
+		if math.IsNaN(g.eastShift[idx]) {
+			return 0, 0, false
+		}
+		se += weights[i] * g.eastShift[idx]
+		sn += weights[i] * g.northShift[idx]
+	}
+	return se, sn, true
+}
+
+// toETRS89Grid inverts the shift for an OSGB36 coordinate by iteration, returning the
+// ETRS89 position on the (GRS80) National Grid projection
+func (g *ostnGrid) toETRS89Grid(easting, northing float64) (float64, float64, bool) {
+	se, sn, ok := g.shiftAt(easting, northing)
+	if !ok {
+		return 0, 0, false
+	}
+	e, n := easting-se, northing-sn
+
+	for i := 0; i < 20; i++ {
+		se, sn, ok = g.shiftAt(e, n)
+		if !ok {
+			return 0, 0, false
+		}
+		nextE, nextN := easting-se, northing-sn
+		if math.Abs(nextE-e) < 0.0001 && math.Abs(nextN-n) < 0.0001 {
+			return nextE, nextN, true
+		}
+		e, n = nextE, nextN
+	}
+	return e, n, true
+}
@@ -0,0 +1,149 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// Coordinate check statuses
+const (
+	CoordValid         = "valid"
+	CoordSwapped       = "swapped"         // easting and northing transposed
+	CoordTruncated     = "truncated"       // dropped leading digit or six-figure grid reference
+	CoordLatLng        = "latlng"          // latitude/longitude pasted into the grid columns
+	CoordOutOfDistrict = "out_of_district" // plausible BNG but outside East Hampshire
+	CoordPlaceholder   = "placeholder"     // zeros, repeated or otherwise meaningless values
+	CoordMissing       = "missing"
+)
+
+// District extent with a margin, in BNG metres. EHDC runs from Rowlands Castle
+// (~473000,110000) in the south to Bentley (~478000,145000) in the north.
+const (
+	DistrictMinEasting  = 458000.0
+	DistrictMaxEasting  = 494000.0
+	DistrictMinNorthing = 106000.0
+	DistrictMaxNorthing = 150000.0
+)
+
+// Repair confidences
+const (
+	swapConfidence      = 0.95
+	latLngConfidence    = 0.90 // Helmert transformation, a few metres
+	leadingDigitConf    = 0.80
+	sixFigureConfidence = 0.60 // 100m precision
+)
+
+// CoordinateCheck is the outcome of validating one source coordinate
+type CoordinateCheck struct {
+	Status     string
+	Easting    *float64 // repaired value, nil when unusable
+	Northing   *float64
+	Confidence float64
+	Reason     string
+}
+
+// InDistrict reports whether a BNG coordinate falls inside the district extent
+func InDistrict(easting, northing float64) bool {
+	return easting >= DistrictMinEasting && easting <= DistrictMaxEasting &&
+		northing >= DistrictMinNorthing && northing <= DistrictMaxNorthing
+}
+
+// CheckCoordinate classifies a source easting/northing and proposes a repair.
+// Only valid and repaired coordinates carry a value; everything else is unusable for spatial matching.
+func CheckCoordinate(easting, northing *float64) CoordinateCheck {
+	if easting == nil || northing == nil {
+		return CoordinateCheck{Status: CoordMissing, Reason: "easting or northing missing"}
+	}
+	e, n := *easting, *northing
+
+	// Before the placeholder test, which would reject negative longitudes
+	if check, ok := checkLatLng(e, n); ok {
+		return check
+	}
+
+	if isPlaceholder(e, n) {
+		return CoordinateCheck{Status: CoordPlaceholder, Reason: fmt.Sprintf("placeholder value (%g, %g)", e, n)}
+	}
+
+	if InDistrict(e, n) {
+		return repaired(CoordValid, e, n, 1.0, "")
+	}
+
+	if InDistrict(n, e) {
+		return repaired(CoordSwapped, n, e, swapConfidence, "easting and northing transposed")
+	}
+
+	// Six-figure grid reference within SU (e.g. 747, 229 for SU 747 229)
+	if e > 0 && e < 1000 && n > 0 && n < 1000 {
+		re, rn := 400000+e*100, 100000+n*100
+		if InDistrict(re, rn) {
+			return repaired(CoordTruncated, re, rn, sixFigureConfidence, "six-figure grid reference expanded to 100m precision")
+		}
+	}
+
+	// Dropped leading digit on either axis, possibly also transposed
+	if re, rn, ok := restoreLeadingDigits(e, n); ok {
+		return repaired(CoordTruncated, re, rn, leadingDigitConf, "restored dropped leading digit")
+	}
+	if re, rn, ok := restoreLeadingDigits(n, e); ok {
+		return repaired(CoordTruncated, re, rn, leadingDigitConf*swapConfidence, "restored dropped leading digit and transposed")
+	}
+
+	if e > 0 && e < 700000 && n > 0 && n < 1300000 {
+		return CoordinateCheck{Status: CoordOutOfDistrict, Reason: fmt.Sprintf("(%.0f, %.0f) is outside the district", e, n)}
+	}
+
+	return CoordinateCheck{Status: CoordPlaceholder, Reason: fmt.Sprintf("(%g, %g) is not a British National Grid coordinate", e, n)}
+}
+
+// isPlaceholder spots zeros, sentinels and repeated digits used when no coordinate was known
+func isPlaceholder(e, n float64) bool {
+	if math.IsNaN(e) || math.IsNaN(n) || e <= 0 || n <= 0 {
+		return true
+	}
+	if e == n && !InDistrict(e, n) {
+		return true
+	}
+	for _, v := range []float64{e, n} {
+		if v == 1 || v == 9999 || v == 99999 || v == 999999 || v == 123456 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLatLng detects decimal degrees in the grid columns, in either order
+func checkLatLng(e, n float64) (CoordinateCheck, bool) {
+	pairs := [][2]float64{{e, n}, {n, e}}
+	for _, p := range pairs {
+		lat, lng := p[0], p[1]
+		if lat < 49 || lat > 61 || lng < -8 || lng > 2 {
+			continue
+		}
+		be, bn := ToBNG(lat, lng)
+		if InDistrict(be, bn) {
+			return repaired(CoordLatLng, math.Round(be), math.Round(bn), latLngConfidence,
+				fmt.Sprintf("latitude/longitude (%g, %g) converted to BNG", lat, lng)), true
+		}
+	}
+	return CoordinateCheck{}, false
+}
+
+// restoreLeadingDigits puts back the 100km digit (4xxxxx easting, 1xxxxx northing)
+// when a five-figure value lost it
+func restoreLeadingDigits(e, n float64) (float64, float64, bool) {
+	changed := false
+	if e >= 10000 && e < 100000 {
+		e += 400000
+		changed = true
+	}
+	if n >= 10000 && n < 100000 {
+		n += 100000
+		changed = true
+	}
+	return e, n, changed && InDistrict(e, n)
+}
+
+func repaired(status string, e, n, confidence float64, reason string) CoordinateCheck {
+	return CoordinateCheck{Status: status, Easting: &e, Northing: &n, Confidence: confidence, Reason: reason}
+}
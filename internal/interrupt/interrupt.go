@@ -0,0 +1,51 @@
+// Package interrupt turns SIGINT and SIGTERM into context cancellation so long runs can
+// finish or roll back their current unit of work and record what they completed.
+package interrupt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Context returns a context cancelled by the first SIGINT or SIGTERM. A second signal
+// exits immediately. Call stop to release the signal handler.
+func Context(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Fprintf(os.Stderr, "\n%s received: finishing the current unit of work (send again to abort)\n", sig)
+			cancel()
+		case <-done:
+			return
+		}
+
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, "Aborted")
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// Detached returns a context for recording the outcome of a run after ctx was cancelled,
+// such as marking it interrupted and writing partial statistics
+func Detached() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 30*time.Second)
+}
@@ -3,8 +3,6 @@ package llpg
 import (
 	"database/sql"
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -27,6 +25,9 @@ type ExpandedAddress struct {
 	AddressCanonical string
 	ExpansionType    string
 	UnitNumber       string
+	ParentUPRN       string // LLPG UPRN of the range address this row was expanded from
+	ParentRange      string // range text in the parent address, e.g. "FLATS 1-12"
+	RangeKind        string // numeric, suffix, alpha or list
 }
 
 // InitializeExpandedTable creates the expanded address table if it doesn't exist
@@ -46,6 +47,28 @@ func (re *RangeExpander) InitializeExpandedTable() error {
 	CREATE INDEX IF NOT EXISTS idx_address_expanded_uprn ON dim_address_expanded(uprn);
 	CREATE INDEX IF NOT EXISTS idx_address_expanded_canonical ON dim_address_expanded(address_canonical);
 	CREATE INDEX IF NOT EXISTS idx_address_expanded_unit ON dim_address_expanded(unit_number);
+	
+	ALTER TABLE dim_address_expanded
+		ADD COLUMN IF NOT EXISTS parent_uprn TEXT,
+		ADD COLUMN IF NOT EXISTS parent_range TEXT,
+		ADD COLUMN IF NOT EXISTS range_kind TEXT;
+	
+	CREATE TABLE IF NOT EXISTS dim_address_range (
+		range_id SERIAL PRIMARY KEY,
+		address_id INTEGER REFERENCES dim_address(address_id),
+		uprn TEXT,
+		range_text TEXT,
+		unit_prefix TEXT,
+		range_start TEXT,
+		range_end TEXT,
+		parity TEXT,
+		range_kind TEXT,
+		remainder TEXT,
+		created_at TIMESTAMP DEFAULT NOW()
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_address_range_remainder_trgm ON dim_address_range USING gin (remainder gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS idx_address_range_uprn ON dim_address_range(uprn);
 	`
 	
 	_, err := re.db.Exec(query)
@@ -70,20 +93,18 @@ func (re *RangeExpander) ExpandAllRanges() (int, error) {
 
 // clearExpansions removes all previous expansions
 func (re *RangeExpander) clearExpansions() error {
-	_, err := re.db.Exec("TRUNCATE dim_address_expanded")
+	_, err := re.db.Exec("TRUNCATE dim_address_expanded, dim_address_range")
 	return err
 }
 
-// expandPropertyRanges expands all types of property number ranges with proper validation
+// expandPropertyRanges expands street number, unit and flat ranges into individual addresses
 func (re *RangeExpander) expandPropertyRanges() (int, error) {
-	// Pattern matches property numbers with optional letters and spaces: 9-11, 9A-9C, 9 - 11
-	propertyRangePattern := regexp.MustCompile(`\b(\d+[A-Z]?)\s*-\s*(\d+[A-Z]?)\b`)
-	
-	// Query addresses with property ranges 
+	// Pre-filter candidates in the database; ParseRanges does the real validation
 	query := `
-	SELECT address_id, uprn, full_address, address_canonical
+	SELECT address_id, uprn, full_address, COALESCE(address_canonical, '')
 	FROM dim_address 
-	WHERE full_address ~ '\m\d+[A-Z]?\s*-\s*\d+[A-Z]?\M'
+	WHERE full_address ~ '\d+[A-Z]?\s*-\s*\d+[A-Z]?'
+	   OR full_address ~* '\m(FLATS?|UNITS?|APARTMENTS?|PLOTS?|SUITES?)\s+[0-9A-Z]+\s*(-|,|TO\M|&|AND\M)'
 	`
 	
 	rows, err := re.db.Query(query)
@@ -91,42 +112,69 @@ func (re *RangeExpander) expandPropertyRanges() (int, error) {
 		return 0, err
 	}
 	defer rows.Close()
+
+	type rangeRow struct {
+		addressID   int
+		uprn        string
+		fullAddress string
+		canonical   string
+	}
+
+	// Read all rows first so inserts don't run while the cursor is open
+	var candidates []rangeRow
+	for rows.Next() {
+		var r rangeRow
+		if err := rows.Scan(&r.addressID, &r.uprn, &r.fullAddress, &r.canonical); err != nil {
+			return 0, fmt.Errorf("failed to read range candidate: %v", err)
+		}
+		candidates = append(candidates, r)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
 	
 	expandedCount := 0
 	
-	for rows.Next() {
-		var addressID int
-		var uprn, fullAddress, canonical string
-		
-		if err := rows.Scan(&addressID, &uprn, &fullAddress, &canonical); err != nil {
-			continue
-		}
-		
-		// Find all property ranges in the address
-		matches := propertyRangePattern.FindAllStringSubmatch(fullAddress, -1)
+	for _, row := range candidates {
+		ranges := ParseRanges(row.fullAddress)
+		canonicalRanges := ParseRanges(row.canonical)
 		
-		for _, match := range matches {
-			startProp := strings.TrimSpace(match[1])
-			endProp := strings.TrimSpace(match[2])
+		for i, r := range ranges {
+			// Every range is recorded for reverse lookups, even those too large to expand
+			if err := re.insertRange(row.addressID, row.uprn, row.fullAddress, r); err != nil {
+				return expandedCount, fmt.Errorf("failed to record range %q for UPRN %s: %v", r.Text, row.uprn, err)
+			}
 			
-			// Validate this is a property number range
-			if !re.isValidPropertyRange(startProp, endProp, fullAddress) {
+			if !r.Expandable() {
 				continue
 			}
 			
-			// Generate individual addresses for property range
-			expanded := re.generatePropertyRange(startProp, endProp)
-			for _, propNum := range expanded {
-				// Replace the range with the individual property number
-				newAddress := strings.Replace(fullAddress, match[0], propNum, 1)
-				newCanonical := strings.Replace(canonical, match[0], propNum, 1)
+			for _, member := range r.Members() {
+				memberText := r.MemberText(member)
+				newAddress := replaceFold(row.fullAddress, r.Text, memberText)
 				
-				// Handle concatenated canonical addresses ("10-11" -> "1011")
-				concatenated := regexp.MustCompile(`\s`).ReplaceAllString(match[1], "") + regexp.MustCompile(`\s`).ReplaceAllString(match[2], "")
-				newCanonical = strings.Replace(newCanonical, concatenated, propNum, 1)
+				// Canonical forms may have dropped the hyphen ("10-11" -> "1011")
+				var newCanonical string
+				if len(canonicalRanges) == len(ranges) {
+					newCanonical = strings.Replace(row.canonical, canonicalRanges[i].Text, memberText, 1)
+				} else {
+					concatenated := strings.ReplaceAll(r.Start, " ", "") + strings.ReplaceAll(r.End, " ", "")
+					newCanonical = strings.Replace(row.canonical, concatenated, memberText, 1)
+				}
 				
-				if err := re.insertExpanded(addressID, uprn, newAddress, newCanonical, "range_expansion", propNum); err != nil {
-					continue
+				expansion := ExpandedAddress{
+					OriginalAddressID: row.addressID,
+					UPRN:              row.uprn,
+					FullAddress:       newAddress,
+					AddressCanonical:  newCanonical,
+					ExpansionType:     "range_expansion",
+					UnitNumber:        member,
+					ParentUPRN:        row.uprn,
+					ParentRange:       r.Text,
+					RangeKind:         r.Kind,
+				}
+				if err := re.insertExpanded(expansion); err != nil {
+					return expandedCount, fmt.Errorf("failed to insert %q expanded from UPRN %s: %v", memberText, row.uprn, err)
 				}
 				expandedCount++
 			}
@@ -136,90 +184,95 @@ func (re *RangeExpander) expandPropertyRanges() (int, error) {
 	return expandedCount, nil
 }
 
-// isValidPropertyRange validates if a range represents property numbers using your rules
-func (re *RangeExpander) isValidPropertyRange(start, end, address string) bool {
-	// Extract numeric parts for validation
-	startNum := regexp.MustCompile(`^(\d+)`).FindString(start)
-	endNum := regexp.MustCompile(`^(\d+)`).FindString(end)
-	
-	if startNum == "" || endNum == "" {
-		return false
-	}
-	
-	startInt, err1 := strconv.Atoi(startNum)
-	endInt, err2 := strconv.Atoi(endNum)
-	
-	if err1 != nil || err2 != nil {
-		return false
-	}
-	
-	// Extract letter suffixes for additional validation
-	startSuffix := strings.TrimPrefix(start, startNum)
-	endSuffix := strings.TrimPrefix(end, endNum)
-	
-	// Property number validation rules:
-	// 1. For pure numeric ranges: start must be less than end
-	// 2. For letter ranges (9A-9C): same number allowed if different letters
-	// 3. Range should not be too large (max 50 units for aggressive expansion)
-	// 4. Numbers should be reasonable property numbers (1-9999)
-	
-	// Check if this is a letter range (same number, different letters)
-	isLetterRange := startInt == endInt && len(startSuffix) == 1 && len(endSuffix) == 1 && startSuffix != endSuffix
-	
-	if !isLetterRange && startInt >= endInt {
-		return false // Pure numeric ranges need start < end
+// replaceFold replaces the first case-insensitive occurrence of old in s
+func replaceFold(s, old, replacement string) string {
+	idx := strings.Index(strings.ToUpper(s), strings.ToUpper(old))
+	if idx < 0 {
+		return s
 	}
+	return s[:idx] + replacement + s[idx+len(old):]
+}
+
+// insertExpanded inserts an expanded address into the database
+func (re *RangeExpander) insertExpanded(e ExpandedAddress) error {
+	query := `
+	INSERT INTO dim_address_expanded (
+		original_address_id, uprn, full_address, address_canonical, 
+		expansion_type, unit_number, parent_uprn, parent_range, range_kind, created_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
 	
-	// More aggressive: allow up to 50 units (e.g., 47-93)
-	if (endInt-startInt) > 50 || startInt < 1 || endInt > 9999 {
-		return false
-	}
+	_, err := re.db.Exec(query, e.OriginalAddressID, e.UPRN, e.FullAddress, e.AddressCanonical,
+		e.ExpansionType, e.UnitNumber, e.ParentUPRN, e.ParentRange, e.RangeKind, time.Now())
+	return err
+}
+
+// insertRange records a parsed LLPG range for reverse lookups
+func (re *RangeExpander) insertRange(addressID int, uprn, fullAddress string, r AddressRange) error {
+	query := `
+	INSERT INTO dim_address_range (
+		address_id, uprn, range_text, unit_prefix, range_start, range_end,
+		parity, range_kind, remainder
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
 	
-	// More aggressive: Always expand ranges in addresses (remove context restrictions)
-	// This will over-expand but that's what we want for comprehensive matching
-	return true
+	_, err := re.db.Exec(query, addressID, uprn, r.Text, r.Prefix, r.Start, r.End,
+		r.Parity, r.Kind, RangeRemainder(fullAddress, r.Text))
+	return err
+}
+
+// RangeMatch is an LLPG range address that contains a single-number source address
+type RangeMatch struct {
+	AddressID   int
+	UPRN        string
+	FullAddress string
+	RangeText   string
+	Similarity  float64
 }
 
-// generatePropertyRange generates individual property numbers from a range  
-func (re *RangeExpander) generatePropertyRange(start, end string) []string {
-	var result []string
+// FindContainingRanges finds the LLPG range addresses that a single-number source address falls inside,
+// e.g. "UNIT 4 MILL LANE INDUSTRIAL ESTATE" inside "UNITS 1-8 MILL LANE INDUSTRIAL ESTATE"
+func (re *RangeExpander) FindContainingRanges(address string, minSimilarity float64) ([]RangeMatch, error) {
+	prefix, value, remainder, ok := ParseSingleUnit(address)
+	if !ok || remainder == "" {
+		return nil, nil
+	}
 	
-	// Handle pure numeric ranges (9-11) and letter ranges (9A-9C)
-	startNum := regexp.MustCompile(`^(\d+)`).FindString(start)
-	endNum := regexp.MustCompile(`^(\d+)`).FindString(end)
-	startSuffix := strings.TrimPrefix(start, startNum)
-	endSuffix := strings.TrimPrefix(end, endNum)
+	query := `
+	SELECT r.address_id, r.uprn, a.full_address, r.range_text, r.unit_prefix,
+	       r.range_start, r.range_end, r.parity, r.range_kind,
+	       similarity(r.remainder, $1) AS sim
+	FROM dim_address_range r
+	JOIN dim_address a ON a.address_id = r.address_id
+	WHERE similarity(r.remainder, $1) >= $2
+	ORDER BY sim DESC
+	LIMIT 50
+	`
 	
-	startInt, _ := strconv.Atoi(startNum)
-	endInt, _ := strconv.Atoi(endNum)
+	rows, err := re.db.Query(query, remainder, minSimilarity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query address ranges: %v", err)
+	}
+	defer rows.Close()
 	
-	// If both have same number with letter suffix (9A-9C), generate letter range
-	if len(startSuffix) == 1 && len(endSuffix) == 1 && startNum == endNum && startSuffix[0] <= endSuffix[0] {
-		// Letter range: 9A-9C -> 9A, 9B, 9C
-		for c := startSuffix[0]; c <= endSuffix[0]; c++ {
-			result = append(result, startNum+string(c))
+	var matches []RangeMatch
+	for rows.Next() {
+		var m RangeMatch
+		var r AddressRange
+		if err := rows.Scan(&m.AddressID, &m.UPRN, &m.FullAddress, &m.RangeText, &r.Prefix,
+			&r.Start, &r.End, &r.Parity, &r.Kind, &m.Similarity); err != nil {
+			continue
 		}
-	} else {
-		// Numeric range: 9-11 -> 9, 10, 11
-		for i := startInt; i <= endInt; i++ {
-			result = append(result, strconv.Itoa(i)+startSuffix)
+		r.Text = m.RangeText // list members are read back from the text
+		
+		// Street numbers must not match unit ranges and vice versa
+		if r.Prefix != prefix || !r.Contains(value) {
+			continue
 		}
+		matches = append(matches, m)
 	}
 	
-	return result
-}
-
-// insertExpanded inserts an expanded address into the database
-func (re *RangeExpander) insertExpanded(originalID int, uprn, fullAddress, canonical, expansionType, unitNumber string) error {
-	query := `
-	INSERT INTO dim_address_expanded (
-		original_address_id, uprn, full_address, address_canonical, 
-		expansion_type, unit_number, created_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
-	
-	_, err := re.db.Exec(query, originalID, uprn, fullAddress, canonical, expansionType, unitNumber, time.Now())
-	return err
+	return matches, rows.Err()
 }
 
 // GetExpandedAddressStats returns statistics about the expansion
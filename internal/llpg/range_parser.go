@@ -0,0 +1,320 @@
+package llpg
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Range kinds recorded against each expansion
+const (
+	RangeKindNumeric = "numeric" // 9-11, 2-10 (EVEN)
+	RangeKindSuffix  = "suffix"  // 9A-9C
+	RangeKindAlpha   = "alpha"   // UNITS A-F
+	RangeKindList    = "list"    // UNITS 3 & 5, FLATS 1, 2 AND 4: only the named units
+)
+
+// Parity restrictions for numeric ranges
+const (
+	ParityAll  = ""
+	ParityOdd  = "odd"
+	ParityEven = "even"
+)
+
+// Limits on what counts as a plausible property range
+const (
+	maxPropertyNumber = 9999
+	maxRangeMembers   = 50 // ranges with more members are recorded for reverse lookup but not expanded
+)
+
+var (
+	// Sub-building descriptor ranges, usually in the SAON part of the address: FLATS 1-12, UNITS A-F, UNIT 3 TO 4
+	unitRangePattern = regexp.MustCompile(`\b(FLATS?|UNITS?|APARTMENTS?|PLOTS?|SUITES?)\s+(\d+[A-Z]?|[A-Z])\s*(?:-|\bTO\b)\s*(\d+[A-Z]?|[A-Z])\b(\s*\(?\s*(?:ODD|EVEN)\s*\)?)?`)
+
+	// Sub-building descriptor lists, which name each unit rather than a span: UNITS 3 & 5, FLATS 1, 2 AND 4
+	unitListPattern      = regexp.MustCompile(`\b(FLATS?|UNITS?|APARTMENTS?|PLOTS?|SUITES?)\s+((?:\d+[A-Z]?|[A-Z])(?:\s*,\s*(?:\d+[A-Z]?|[A-Z]))*\s*(?:&|\bAND\b)\s*(?:\d+[A-Z]?|[A-Z]))\b`)
+	listSeparatorPattern = regexp.MustCompile(`\s*(?:,|&|\bAND\b)\s*`)
+
+	// Street number ranges with optional parity: 9-11, 9A-9C, 2-10 (EVEN), 1-9 ODD
+	numberRangePattern = regexp.MustCompile(`\b(\d+[A-Z]?)\s*-\s*(\d+[A-Z]?)\b(\s*\(?\s*(?:ODD|EVEN)\s*\)?)?`)
+
+	// Leading single unit or number in a source address: FLAT 7, UNIT C, 12A
+	singleUnitPattern = regexp.MustCompile(`^\s*(?:(FLAT|UNIT|APARTMENT|PLOT|SUITE)\s+)?(\d+[A-Z]?|[A-Z])\b[\s,]*`)
+
+	leadingDigitsPattern = regexp.MustCompile(`^\d+`)
+	nonWordPattern       = regexp.MustCompile(`[^A-Z0-9\s]`)
+	whitespacePattern    = regexp.MustCompile(`\s+`)
+)
+
+// AddressRange is a single range found in an LLPG address
+type AddressRange struct {
+	Text   string // range as written in the address, e.g. "FLATS 1-12"
+	Prefix string // singular sub-building descriptor (FLAT, UNIT, ...) or empty for street numbers
+	Start  string
+	End    string
+	Parity string
+	Kind   string
+}
+
+// ParseRanges finds all valid property ranges in an address, unit ranges first
+func ParseRanges(address string) []AddressRange {
+	upper := strings.ToUpper(address)
+	var ranges []AddressRange
+	var claimed [][]int
+
+	for _, loc := range unitRangePattern.FindAllStringSubmatchIndex(upper, -1) {
+		r := AddressRange{
+			Text:   strings.TrimSpace(upper[loc[0]:loc[1]]),
+			Prefix: singularPrefix(upper[loc[2]:loc[3]]),
+			Start:  upper[loc[4]:loc[5]],
+			End:    upper[loc[6]:loc[7]],
+		}
+		if loc[8] >= 0 {
+			r.Parity = parseParity(upper[loc[8]:loc[9]])
+		}
+		if r.classify() {
+			ranges = append(ranges, r)
+			claimed = append(claimed, loc[:2])
+		}
+	}
+
+	for _, loc := range unitListPattern.FindAllStringSubmatchIndex(upper, -1) {
+		if overlaps(loc[:2], claimed) {
+			continue
+		}
+		members := listMembers(upper[loc[4]:loc[5]])
+		r := AddressRange{
+			Text:   strings.TrimSpace(upper[loc[0]:loc[1]]),
+			Prefix: singularPrefix(upper[loc[2]:loc[3]]),
+			Start:  members[0],
+			End:    members[len(members)-1],
+			Kind:   RangeKindList,
+		}
+		if r.classify() {
+			ranges = append(ranges, r)
+			claimed = append(claimed, loc[:2])
+		}
+	}
+
+	for _, loc := range numberRangePattern.FindAllStringSubmatchIndex(upper, -1) {
+		if overlaps(loc[:2], claimed) {
+			continue
+		}
+		r := AddressRange{
+			Text:  strings.TrimSpace(upper[loc[0]:loc[1]]),
+			Start: upper[loc[2]:loc[3]],
+			End:   upper[loc[4]:loc[5]],
+		}
+		if loc[6] >= 0 {
+			r.Parity = parseParity(upper[loc[6]:loc[7]])
+		}
+		if r.classify() {
+			ranges = append(ranges, r)
+		}
+	}
+
+	return ranges
+}
+
+// classify sets the range kind and reports whether the range is a plausible property range
+func (r *AddressRange) classify() bool {
+	// Unit lists: every named unit must be plausible on its own
+	if r.Kind == RangeKindList {
+		r.Parity = ParityAll
+		members := r.Members()
+		if len(members) < 2 {
+			return false
+		}
+		for _, m := range members {
+			if isLetter(m) {
+				continue
+			}
+			n, err := strconv.Atoi(leadingDigitsPattern.FindString(m))
+			if err != nil || n < 1 || n > maxPropertyNumber {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Alphabetic unit ranges: UNITS A-F
+	if isLetter(r.Start) && isLetter(r.End) {
+		r.Kind = RangeKindAlpha
+		r.Parity = ParityAll
+		return r.Start[0] < r.End[0]
+	}
+
+	startNum := leadingDigitsPattern.FindString(r.Start)
+	endNum := leadingDigitsPattern.FindString(r.End)
+	if startNum == "" || endNum == "" {
+		return false
+	}
+
+	startInt, err1 := strconv.Atoi(startNum)
+	endInt, err2 := strconv.Atoi(endNum)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	startSuffix := strings.TrimPrefix(r.Start, startNum)
+	endSuffix := strings.TrimPrefix(r.End, endNum)
+
+	// Letter suffix ranges on the same number: 9A-9C
+	if startInt == endInt && len(startSuffix) == 1 && len(endSuffix) == 1 {
+		r.Kind = RangeKindSuffix
+		r.Parity = ParityAll
+		return startSuffix[0] < endSuffix[0]
+	}
+
+	r.Kind = RangeKindNumeric
+	return startInt >= 1 && startInt < endInt && endInt <= maxPropertyNumber
+}
+
+// Members returns every individual unit or number covered by the range
+func (r AddressRange) Members() []string {
+	var result []string
+
+	switch r.Kind {
+	case RangeKindList:
+		loc := unitListPattern.FindStringSubmatchIndex(strings.ToUpper(r.Text))
+		if loc == nil {
+			return []string{r.Start, r.End}
+		}
+		result = listMembers(strings.ToUpper(r.Text)[loc[4]:loc[5]])
+	case RangeKindAlpha:
+		for c := r.Start[0]; c <= r.End[0]; c++ {
+			result = append(result, string(c))
+		}
+	case RangeKindSuffix:
+		num := leadingDigitsPattern.FindString(r.Start)
+		for c := r.Start[len(num)]; c <= r.End[len(num)]; c++ {
+			result = append(result, num+string(c))
+		}
+	default:
+		startNum := leadingDigitsPattern.FindString(r.Start)
+		startSuffix := strings.TrimPrefix(r.Start, startNum)
+		startInt, _ := strconv.Atoi(startNum)
+		endInt, _ := strconv.Atoi(leadingDigitsPattern.FindString(r.End))
+		for i := startInt; i <= endInt; i++ {
+			if matchesParity(i, r.Parity) {
+				result = append(result, strconv.Itoa(i)+startSuffix)
+			}
+		}
+	}
+
+	return result
+}
+
+// Expandable reports whether the range is small enough to expand into individual rows
+func (r AddressRange) Expandable() bool {
+	return len(r.Members()) <= maxRangeMembers
+}
+
+// Contains reports whether a single unit or number falls inside the range
+func (r AddressRange) Contains(value string) bool {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return false
+	}
+
+	switch r.Kind {
+	case RangeKindAlpha:
+		return isLetter(value) && value[0] >= r.Start[0] && value[0] <= r.End[0]
+	case RangeKindSuffix, RangeKindList:
+		for _, m := range r.Members() {
+			if m == value {
+				return true
+			}
+		}
+		return false
+	default:
+		num := leadingDigitsPattern.FindString(value)
+		if num == "" {
+			return false
+		}
+		n, _ := strconv.Atoi(num)
+		startInt, _ := strconv.Atoi(leadingDigitsPattern.FindString(r.Start))
+		endInt, _ := strconv.Atoi(leadingDigitsPattern.FindString(r.End))
+		return n >= startInt && n <= endInt && matchesParity(n, r.Parity)
+	}
+}
+
+// MemberText returns the replacement text for a single member, e.g. "FLAT 3" for "FLATS 1-12"
+func (r AddressRange) MemberText(member string) string {
+	if r.Prefix == "" {
+		return member
+	}
+	return r.Prefix + " " + member
+}
+
+// ParseSingleUnit splits a source address into its leading unit or number and the remainder
+func ParseSingleUnit(address string) (prefix, value, remainder string, ok bool) {
+	upper := strings.ToUpper(address)
+	loc := singleUnitPattern.FindStringSubmatchIndex(upper)
+	if loc == nil {
+		return "", "", "", false
+	}
+	if loc[2] >= 0 {
+		prefix = upper[loc[2]:loc[3]]
+	}
+	value = upper[loc[4]:loc[5]]
+
+	// A bare letter is only a unit when it has a descriptor in front of it
+	if prefix == "" && isLetter(value) {
+		return "", "", "", false
+	}
+
+	return prefix, value, RangeRemainder(upper[loc[1]:], ""), true
+}
+
+// RangeRemainder strips a range from an address and normalises what is left, for reverse lookups
+func RangeRemainder(address, rangeText string) string {
+	upper := strings.ToUpper(address)
+	if rangeText != "" {
+		upper = strings.Replace(upper, rangeText, " ", 1)
+	}
+	upper = nonWordPattern.ReplaceAllString(upper, " ")
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(upper, " "))
+}
+
+// listMembers splits the units of a list such as "1, 2 AND 4"
+func listMembers(list string) []string {
+	return listSeparatorPattern.Split(strings.TrimSpace(list), -1)
+}
+
+func singularPrefix(prefix string) string {
+	return strings.TrimSuffix(prefix, "S")
+}
+
+func parseParity(text string) string {
+	switch {
+	case strings.Contains(text, "ODD"):
+		return ParityOdd
+	case strings.Contains(text, "EVEN"):
+		return ParityEven
+	}
+	return ParityAll
+}
+
+func matchesParity(n int, parity string) bool {
+	switch parity {
+	case ParityOdd:
+		return n%2 == 1
+	case ParityEven:
+		return n%2 == 0
+	}
+	return true
+}
+
+func isLetter(s string) bool {
+	return len(s) == 1 && s[0] >= 'A' && s[0] <= 'Z'
+}
+
+func overlaps(span []int, claimed [][]int) bool {
+	for _, c := range claimed {
+		if span[0] < c[1] && c[0] < span[1] {
+			return true
+		}
+	}
+	return false
+}
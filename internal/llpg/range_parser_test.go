@@ -0,0 +1,120 @@
+package llpg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		address     string
+		wantText    string
+		wantKind    string
+		wantMembers []string
+	}{
+		{
+			name:        "street number range",
+			address:     "9-11 HIGH STREET, ALTON",
+			wantText:    "9-11",
+			wantKind:    RangeKindNumeric,
+			wantMembers: []string{"9", "10", "11"},
+		},
+		{
+			name:        "letter suffix range",
+			address:     "9A-9C HIGH STREET, ALTON",
+			wantText:    "9A-9C",
+			wantKind:    RangeKindSuffix,
+			wantMembers: []string{"9A", "9B", "9C"},
+		},
+		{
+			name:        "flats in SAON",
+			address:     "FLATS 1-4, KINGS COURT, PETERSFIELD",
+			wantText:    "FLATS 1-4",
+			wantKind:    RangeKindNumeric,
+			wantMembers: []string{"1", "2", "3", "4"},
+		},
+		{
+			name:        "lettered units",
+			address:     "Units A-D Mill Lane Industrial Estate",
+			wantText:    "UNITS A-D",
+			wantKind:    RangeKindAlpha,
+			wantMembers: []string{"A", "B", "C", "D"},
+		},
+		{
+			name:        "even numbers in brackets",
+			address:     "2-10 (EVEN) STATION ROAD",
+			wantText:    "2-10 (EVEN)",
+			wantKind:    RangeKindNumeric,
+			wantMembers: []string{"2", "4", "6", "8", "10"},
+		},
+		{
+			name:        "odd numbers",
+			address:     "1-9 ODD CHURCH STREET",
+			wantText:    "1-9 ODD",
+			wantKind:    RangeKindNumeric,
+			wantMembers: []string{"1", "3", "5", "7", "9"},
+		},
+		{
+			name:        "two named units",
+			address:     "UNITS 3 & 5, MILL LANE INDUSTRIAL ESTATE",
+			wantText:    "UNITS 3 & 5",
+			wantKind:    RangeKindList,
+			wantMembers: []string{"3", "5"},
+		},
+		{
+			name:        "listed flats",
+			address:     "Flats 1, 2 and 4 Kings Court",
+			wantText:    "FLATS 1, 2 AND 4",
+			wantKind:    RangeKindList,
+			wantMembers: []string{"1", "2", "4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges := ParseRanges(tt.address)
+			if len(ranges) != 1 {
+				t.Fatalf("ParseRanges(%q) returned %d ranges, want 1", tt.address, len(ranges))
+			}
+			r := ranges[0]
+			if r.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", r.Text, tt.wantText)
+			}
+			if r.Kind != tt.wantKind {
+				t.Errorf("Kind = %q, want %q", r.Kind, tt.wantKind)
+			}
+			if got := r.Members(); !reflect.DeepEqual(got, tt.wantMembers) {
+				t.Errorf("Members() = %v, want %v", got, tt.wantMembers)
+			}
+		})
+	}
+}
+
+func TestAddressRangeContains(t *testing.T) {
+	even := ParseRanges("2-10 (EVEN) STATION ROAD")[0]
+	if !even.Contains("6") || even.Contains("7") || even.Contains("12") {
+		t.Errorf("even range containment wrong for %+v", even)
+	}
+
+	units := ParseRanges("UNITS A-F MILL LANE")[0]
+	if units.Prefix != "UNIT" || !units.Contains("c") || units.Contains("G") {
+		t.Errorf("unit range containment wrong for %+v", units)
+	}
+
+	listed := ParseRanges("UNITS 3 & 5 MILL LANE")[0]
+	if !listed.Contains("3") || !listed.Contains("5") || listed.Contains("4") {
+		t.Errorf("unit list containment wrong for %+v", listed)
+	}
+}
+
+func TestParseSingleUnit(t *testing.T) {
+	prefix, value, remainder, ok := ParseSingleUnit("Unit 4, Mill Lane Industrial Estate")
+	if !ok || prefix != "UNIT" || value != "4" || remainder != "MILL LANE INDUSTRIAL ESTATE" {
+		t.Errorf("ParseSingleUnit() = (%q, %q, %q, %v)", prefix, value, remainder, ok)
+	}
+
+	if _, _, _, ok := ParseSingleUnit("A HOUSE NAME"); ok {
+		t.Errorf("bare letter without descriptor should not parse as a unit")
+	}
+}
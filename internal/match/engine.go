@@ -1,11 +1,15 @@
 package match
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/ehdc-llpg/internal/debug"
 	"github.com/ehdc-llpg/internal/normalize"
+	"github.com/ehdc-llpg/internal/store"
 )
 
 // Engine orchestrates the complete address matching process following ADDRESS_MATCHING_ALGORITHM.md
@@ -52,7 +56,7 @@ func NewEngine(config EngineConfig) *Engine {
 }
 
 // SuggestUPRN performs end-to-end address matching following the sophisticated algorithm
-func (e *Engine) SuggestUPRN(localDebug bool, input Input) (Result, error) {
+func (e *Engine) SuggestUPRN(ctx context.Context, localDebug bool, input Input) (Result, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -74,13 +78,24 @@ func (e *Engine) SuggestUPRN(localDebug bool, input Input) (Result, error) {
 
 	// Step 2: Generate candidates using multi-tier approach
 	debug.DebugOutput(localDebug, "\n=== Step 2: Candidate Generation ===")
-	candidates, err := e.generators.Generate(localDebug, input, canonical, tokens)
+	candidates, err := e.generators.Generate(ctx, localDebug, input, canonical, tokens)
 	if err != nil {
 		return Result{}, err
 	}
 
 	debug.DebugOutput(localDebug, "Generated %d candidates", len(candidates))
 
+	// Rule out the UPRNs reviewers have rejected for this document or its canonical address
+	if e.db != nil && len(candidates) > 0 {
+		rejections, err := LoadRejections(ctx, e.db, input.SrcID, canonical)
+		if err != nil {
+			return Result{}, err
+		}
+		if n := rejections.Suppress(candidates); n > 0 {
+			debug.DebugOutput(localDebug, "Suppressed %d candidates rejected by reviewers", n)
+		}
+	}
+
 	// Step 3: Compute rich features for each candidate
 	debug.DebugOutput(localDebug, "\n=== Step 3: Feature Computation ===")
 	for i := range candidates {
@@ -95,12 +110,12 @@ func (e *Engine) SuggestUPRN(localDebug bool, input Input) (Result, error) {
 
 	// Step 5: Make decision based on scores and thresholds
 	debug.DebugOutput(localDebug, "\n=== Step 5: Decision Making ===")
-	decision, acceptedUPRN := e.scorer.MakeDecision(localDebug, candidates)
+	decision := e.scorer.Decide(localDebug, candidates)
 
 	processingTime := time.Since(startTime)
 	debug.DebugOutput(localDebug, "\n=== Matching Complete ===")
-	debug.DebugOutput(localDebug, "Decision: %s", decision)
-	debug.DebugOutput(localDebug, "Accepted UPRN: %s", acceptedUPRN)
+	debug.DebugOutput(localDebug, "Decision: %s (%s)", decision.Decision, decision.Reason)
+	debug.DebugOutput(localDebug, "Accepted UPRN: %s", decision.AcceptedUPRN)
 	debug.DebugOutput(localDebug, "Processing time: %v", processingTime)
 	debug.DebugOutput(localDebug, "Final candidate count: %d", len(candidates))
 
@@ -108,8 +123,11 @@ func (e *Engine) SuggestUPRN(localDebug bool, input Input) (Result, error) {
 	result := Result{
 		Query:          input,
 		Candidates:     candidates,
-		Decision:       decision,
-		AcceptedUPRN:   acceptedUPRN,
+		Decision:       decision.Decision,
+		AcceptedUPRN:   decision.AcceptedUPRN,
+		Tier:           decision.Tier,
+		Margin:         decision.Margin,
+		Reason:         decision.Reason,
 		ProcessingTime: processingTime,
 		Thresholds: map[string]float64{
 			"auto_accept_high":   e.scorer.tiers.AutoAcceptHigh,
@@ -124,7 +142,7 @@ func (e *Engine) SuggestUPRN(localDebug bool, input Input) (Result, error) {
 }
 
 // BatchProcess processes multiple addresses in batch for efficiency
-func (e *Engine) BatchProcess(localDebug bool, inputs []Input, batchSize int) ([]Result, error) {
+func (e *Engine) BatchProcess(ctx context.Context, localDebug bool, inputs []Input, batchSize int) ([]Result, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -133,7 +151,12 @@ func (e *Engine) BatchProcess(localDebug bool, inputs []Input, batchSize int) ([
 	results := make([]Result, len(inputs))
 	
 	for i, input := range inputs {
-		result, err := e.SuggestUPRN(false, input) // Disable debug for batch to reduce noise
+		if err := ctx.Err(); err != nil {
+			// Return the addresses matched so far; the caller decides whether to save them
+			return results[:i], err
+		}
+
+		result, err := e.SuggestUPRN(ctx, false, input) // Disable debug for batch to reduce noise
 		if err != nil {
 			debug.DebugOutput(localDebug, "Error processing address %d: %v", i, err)
 			// Continue processing other addresses
@@ -157,34 +180,42 @@ func (e *Engine) BatchProcess(localDebug bool, inputs []Input, batchSize int) ([
 	return results, nil
 }
 
-// SaveResults persists matching results to the database following PROJECT_SPECIFICATION.md schema
-func (e *Engine) SaveResults(localDebug bool, results []Result, runLabel string) error {
+// SaveResults persists matching results to the database following PROJECT_SPECIFICATION.md
+// schema. The results are saved under a new match run the process joins, so the audit
+// chain attributes them to it and rollback-run can revert them.
+func (e *Engine) SaveResults(ctx context.Context, localDebug bool, results []Result, runLabel string) (err error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
-	// Create match run
-	var runID int64
-	err := e.db.QueryRow(`
-		INSERT INTO match_run (run_label, notes)
-		VALUES ($1, $2)
-		RETURNING run_id
-	`, runLabel, "Go-based matching engine with ADDRESS_MATCHING_ALGORITHM.md implementation").Scan(&runID)
-	
+	runs := store.NewPostgres(e.db)
+	run, err := runs.CreateRun(ctx, runLabel, "match-engine",
+		"Go-based matching engine with ADDRESS_MATCHING_ALGORITHM.md implementation")
 	if err != nil {
 		return err
 	}
-
+	runID := run.RunID
 	debug.DebugOutput(localDebug, "Created match run %d: %s", runID, runLabel)
 
+	totals := store.RunTotals{}
+	defer func() {
+		status := store.RunCompleted
+		if err != nil {
+			status = store.RunFailed
+		}
+		if cerr := runs.CompleteRun(context.Background(), runID, status, totals); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
 	// Save results in batches
-	tx, err := e.db.Begin()
+	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	// Prepare statements
-	resultStmt, err := tx.Prepare(`
+	resultStmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO match_result (run_id, src_id, candidate_uprn, method, score, tie_rank, decided, decision)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`)
@@ -193,7 +224,7 @@ func (e *Engine) SaveResults(localDebug bool, results []Result, runLabel string)
 	}
 	defer resultStmt.Close()
 
-	acceptedStmt, err := tx.Prepare(`
+	acceptedStmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO match_accepted (src_id, uprn, method, score, run_id, accepted_by)
 		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (src_id) DO UPDATE SET
@@ -201,6 +232,7 @@ func (e *Engine) SaveResults(localDebug bool, results []Result, runLabel string)
 			method = EXCLUDED.method,
 			score = EXCLUDED.score,
 			run_id = EXCLUDED.run_id,
+			manual = false,
 			accepted_at = now()
 	`)
 	if err != nil {
@@ -208,10 +240,41 @@ func (e *Engine) SaveResults(localDebug bool, results []Result, runLabel string)
 	}
 	defer acceptedStmt.Close()
 
+	explanationStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO match_explanation (run_id, src_id, decision, accepted_uprn, tier, margin, reason, thresholds, candidates)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare explanation insert: %w", err)
+	}
+	defer explanationStmt.Close()
+
 	saved := 0
 	accepted := 0
 
 	for _, result := range results {
+		if result.Decision != "error" {
+			// Keep the explanation of every decision, including rejections with no candidates,
+			// for the same top 10 candidates as match_result
+			thresholds, err := json.Marshal(result.Thresholds)
+			if err != nil {
+				return fmt.Errorf("failed to encode thresholds: %w", err)
+			}
+			candidates := e.ExplainCandidates(result)
+			if len(candidates) > 10 {
+				candidates = candidates[:10]
+			}
+			explained, err := json.Marshal(candidates)
+			if err != nil {
+				return fmt.Errorf("failed to encode explanation for document %d: %w", result.Query.SrcID, err)
+			}
+			_, err = explanationStmt.ExecContext(ctx, runID, result.Query.SrcID, result.Decision, result.AcceptedUPRN,
+				result.Tier, result.Margin, result.Reason, thresholds, explained)
+			if err != nil {
+				return fmt.Errorf("failed to save explanation for document %d: %w", result.Query.SrcID, err)
+			}
+		}
+
 		if len(result.Candidates) == 0 {
 			continue
 		}
@@ -232,11 +295,14 @@ func (e *Engine) SaveResults(localDebug bool, results []Result, runLabel string)
 			if decision == "auto_accept" {
 				decision = "accepted"
 			}
+			if candidate.Rejection != nil {
+				decided, decision = true, "suppressed"
+			}
 
 			// Use src_id from result query
 			srcID := result.Query.SrcID
 			
-			_, err = resultStmt.Exec(runID, srcID, candidate.UPRN, methods, candidate.Score, rank+1, decided, decision)
+			_, err = resultStmt.ExecContext(ctx, runID, srcID, candidate.UPRN, methods, candidate.Score, rank+1, decided, decision)
 			if err != nil {
 				return err
 			}
@@ -256,7 +322,7 @@ func (e *Engine) SaveResults(localDebug bool, results []Result, runLabel string)
 				}
 			}
 
-			_, err = acceptedStmt.Exec(srcID, result.AcceptedUPRN, topMethod, topScore, runID, "system")
+			_, err = acceptedStmt.ExecContext(ctx, srcID, result.AcceptedUPRN, topMethod, topScore, runID, "system")
 			if err != nil {
 				return err
 			}
@@ -269,6 +335,18 @@ func (e *Engine) SaveResults(localDebug bool, results []Result, runLabel string)
 		return err
 	}
 
+	for _, result := range results {
+		switch result.Decision {
+		case "auto_accept":
+			totals.Accepted++
+		case "review":
+			totals.NeedsReview++
+		case "reject":
+			totals.Rejected++
+		}
+	}
+	totals.Processed = len(results)
+
 	debug.DebugOutput(localDebug, "Saved %d match results, %d accepted matches", saved, accepted)
 	return nil
 }
@@ -325,6 +403,10 @@ func (e *Engine) GetExplanation(result Result) map[string]interface{} {
 		"processing_time": result.ProcessingTime.String(),
 		"candidate_count": len(result.Candidates),
 		"thresholds":      result.Thresholds,
+		"tier":            result.Tier,
+		"margin":          result.Margin,
+		"reason":          result.Reason,
+		"candidates":      e.ExplainCandidates(result),
 	}
 
 	if len(result.Candidates) > 0 {
@@ -333,5 +415,26 @@ func (e *Engine) GetExplanation(result Result) map[string]interface{} {
 		explanation["top_candidate"] = e.scorer.GetExplanation(topCandidate, legacyValid)
 	}
 
+	var suppressed []map[string]interface{}
+	for _, candidate := range result.Candidates {
+		if candidate.Rejection != nil {
+			suppressed = append(suppressed, map[string]interface{}{
+				"uprn":          candidate.UPRN,
+				"address":       candidate.LocAddress,
+				"score":         candidate.Score,
+				"suppressed_by": candidate.Rejection.Explain(),
+			})
+		}
+	}
+	if len(suppressed) > 0 {
+		explanation["suppressed_candidates"] = suppressed
+	}
+
 	return explanation
-}
\ No newline at end of file
+}
+
+// ExplainCandidates explains every candidate of a result: where it came from, what it scored
+// for and how far it stood from the best other eligible candidate
+func (e *Engine) ExplainCandidates(result Result) []CandidateExplanation {
+	return e.scorer.Explain(result, e.isLegacyUPRNValid(result.Query.LegacyUPRN, result.Candidates))
+}
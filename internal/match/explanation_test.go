@@ -0,0 +1,97 @@
+package match
+
+import (
+	"math"
+	"testing"
+)
+
+func TestContributionsAddUpToScore(t *testing.T) {
+	scorer := NewScorer()
+	features := map[string]interface{}{
+		"trigram_similarity":     0.8,
+		"embedding_cosine":       0.7,
+		"locality_overlap_ratio": 1.0,
+		"has_same_house_num":     true,
+		"llpg_live":              true,
+		"spatial_boost":          0.05,
+		"descriptor_penalty":     true,
+		"phonetic_hits":          0,
+	}
+
+	var total float64
+	points := make(map[string]float64)
+	for _, c := range scorer.Contributions(features, false) {
+		total += c.Points
+		points[c.Feature] = c.Points
+	}
+	if score := scorer.ScoreCandidate(false, features, false); math.Abs(total-score) > 1e-9 {
+		t.Errorf("contributions total %.6f, ScoreCandidate %.6f", total, score)
+	}
+	if points["same_house_number"] != 0.08 || points["phonetic_miss"] != -0.03 || points["usrn_match"] != 0 {
+		t.Errorf("unexpected contributions: %v", points)
+	}
+}
+
+func TestDecideRecordsTierAndMargin(t *testing.T) {
+	scorer := NewScorer()
+
+	d := scorer.Decide(false, []Candidate{{UPRN: "100", Score: 0.95}, {UPRN: "200", Score: 0.90}})
+	if d.Decision != "auto_accept" || d.Tier != TierAutoAcceptHigh || math.Abs(d.Margin-0.05) > 1e-9 {
+		t.Errorf("Decide() = %+v, want auto_accept at %s with margin 0.05", d, TierAutoAcceptHigh)
+	}
+
+	d = scorer.Decide(false, []Candidate{{UPRN: "100", Score: 0.93}, {UPRN: "200", Score: 0.92}})
+	if d.Decision != "review" || d.Tier != TierAutoAcceptHigh || d.Reason == "" {
+		t.Errorf("Decide() with a close runner-up = %+v, want review at %s", d, TierAutoAcceptHigh)
+	}
+
+	d = scorer.Decide(false, []Candidate{{UPRN: "100", Score: 0.5}})
+	if d.Decision != "reject" || d.Tier != TierBelowMin || d.Margin != 1.0 {
+		t.Errorf("Decide() for a lone weak candidate = %+v, want reject at %s with margin 1", d, TierBelowMin)
+	}
+}
+
+func TestExplainCandidates(t *testing.T) {
+	engine := NewEngine(EngineConfig{})
+	result := Result{
+		AcceptedUPRN: "100",
+		Candidates: []Candidate{
+			{UPRN: "300", Score: 0.99, Rejection: &Rejection{RejectionID: 1}},
+			{UPRN: "100", Score: 0.95, Methods: []string{"addr_exact", "trigram"}, Filters: []string{"locality"}},
+			{UPRN: "200", Score: 0.90},
+		},
+	}
+
+	explained := engine.ExplainCandidates(result)
+	if len(explained) != 3 {
+		t.Fatalf("ExplainCandidates() returned %d candidates, want 3", len(explained))
+	}
+	// Margins are against the best other eligible candidate, so the rejected 300 does not count
+	wantMargins := []float64{0.04, 0.05, -0.05}
+	for i, e := range explained {
+		if math.Abs(e.Margin-wantMargins[i]) > 1e-9 {
+			t.Errorf("candidate %s margin = %.4f, want %.4f", e.UPRN, e.Margin, wantMargins[i])
+		}
+	}
+	if !explained[1].Selected || explained[0].Selected || explained[0].SuppressedBy == nil {
+		t.Errorf("selection or suppression not explained: %+v", explained)
+	}
+	if explained[1].Rank != 2 || len(explained[1].Methods) != 2 || len(explained[1].Contributions) == 0 {
+		t.Errorf("candidate 100 explanation incomplete: %+v", explained[1])
+	}
+}
+
+func TestDedupeKeepsEveryMethodAndFilter(t *testing.T) {
+	g := &Generators{}
+	deduped := g.dedupeByUPRN([]Candidate{
+		{UPRN: "100", Score: 0.9, Methods: []string{"addr_exact"}, Filters: []string{"locality"}},
+		{UPRN: "100", Score: 0.5, Methods: []string{"vector_ann"}},
+	})
+	if len(deduped) != 1 {
+		t.Fatalf("dedupeByUPRN() returned %d candidates, want 1", len(deduped))
+	}
+	c := deduped[0]
+	if c.Score != 0.9 || len(c.Methods) != 2 || len(c.Filters) != 1 {
+		t.Errorf("dedupeByUPRN() = %+v, want score 0.9 with both methods and the filter", c)
+	}
+}
@@ -125,10 +125,27 @@ func (fc *FeatureComputer) ComputeFeatures(localDebug bool, input Input, canonic
 		features["distance_bucket"] = "unknown"
 	}
 
+	// === Temporal Features ===
+
+	// Point-in-time check of the document date against the LLPG lifecycle
+	temporalStatus, gapDays := TemporalCompatibility(input.DocDate, candidate.StartDate, candidate.EndDate)
+	features["temporal_status"] = temporalStatus
+	features["temporal_compatible"] = IsTemporallyCompatible(temporalStatus)
+	features["temporal_gap_days"] = gapDays
+	features["logical_status"] = candidate.LogicalStatus
+
+	debug.DebugOutput(localDebug, "Temporal features - Status: %s, Gap days: %d, Logical status: %s",
+		temporalStatus, gapDays, candidate.LogicalStatus)
+
 	// === Meta Features ===
-	
-	// LLPG status (assume live for now - could be enhanced from lgcstatusc)
-	features["llpg_live"] = true // TODO: Read from dim_address.lgcstatusc when available
+
+	// LLPG status - an address that existed on the document date was live for that
+	// document even if it has since been demolished; otherwise use the current status
+	if temporalStatus == TemporalExisted {
+		features["llpg_live"] = true
+	} else {
+		features["llpg_live"] = IsLiveStatus(candidate.LogicalStatus)
+	}
 
 	// BLPU class compatibility (basic heuristic)
 	features["blpu_class_compat"] = fc.blpuClassCompatible(input.RawAddress, candidate.LocAddress)
@@ -1,11 +1,14 @@
 package match
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/geo"
 	"github.com/ehdc-llpg/internal/normalize"
 )
 
@@ -59,7 +62,7 @@ func NewGenerators(db *sql.DB, vdb VectorDB, embedder Embedder, parser Parser) *
 }
 
 // Generate produces candidate UPRNs using multi-tier approach from ADDRESS_MATCHING_ALGORITHM.md
-func (g *Generators) Generate(localDebug bool, input Input, canonical string, tokens []string) ([]Candidate, error) {
+func (g *Generators) Generate(ctx context.Context, localDebug bool, input Input, canonical string, tokens []string) ([]Candidate, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
@@ -72,7 +75,7 @@ func (g *Generators) Generate(localDebug bool, input Input, canonical string, to
 	
 	// A1: Legacy UPRN validation
 	if input.LegacyUPRN != "" {
-		if cand, found := g.lookupUPRN(localDebug, input.LegacyUPRN); found {
+		if cand, found := g.lookupUPRN(ctx, localDebug, input.LegacyUPRN); found {
 			cand.Methods = append(cand.Methods, "legacy_uprn_valid")
 			cand.Features["legacy_uprn_hit"] = true
 			candidates = append(candidates, cand)
@@ -81,7 +84,7 @@ func (g *Generators) Generate(localDebug bool, input Input, canonical string, to
 	}
 
 	// A2: Exact canonical match
-	exactCands := g.exactCanonicalMatch(localDebug, canonical)
+	exactCands := g.exactCanonicalMatch(ctx, localDebug, canonical)
 	for i := range exactCands {
 		exactCands[i].Methods = append(exactCands[i].Methods, "addr_exact")
 	}
@@ -92,7 +95,7 @@ func (g *Generators) Generate(localDebug bool, input Input, canonical string, to
 	debug.DebugOutput(localDebug, "=== Tier B: Database Fuzzy Matching ===")
 
 	// B1: Trigram similarity with filtering
-	trigramCands := g.trigramMatch(localDebug, canonical, tokens, 0.50, 50)
+	trigramCands := g.trigramMatch(ctx, localDebug, canonical, tokens, 0.50, 50)
 	for i := range trigramCands {
 		trigramCands[i].Methods = append(trigramCands[i].Methods, "trigram")
 	}
@@ -119,7 +122,7 @@ func (g *Generators) Generate(localDebug bool, input Input, canonical string, to
 	// Tier C - Vector semantic matching
 	debug.DebugOutput(localDebug, "=== Tier C: Vector Semantic Matching ===")
 	if g.VDB != nil && g.Embedder != nil {
-		vectorCands, err := g.vectorMatch(localDebug, canonical, 50)
+		vectorCands, err := g.vectorMatch(ctx, localDebug, canonical, 50)
 		if err == nil {
 			for i := range vectorCands {
 				vectorCands[i].Methods = append(vectorCands[i].Methods, "vector_ann")
@@ -146,38 +149,50 @@ func (g *Generators) Generate(localDebug bool, input Input, canonical string, to
 }
 
 // lookupUPRN validates a legacy UPRN against the LLPG
-func (g *Generators) lookupUPRN(localDebug bool, uprn string) (Candidate, bool) {
+func (g *Generators) lookupUPRN(ctx context.Context, localDebug bool, uprn string) (Candidate, bool) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	trimmedUPRN := strings.TrimSpace(uprn)
 	if trimmedUPRN == "" {
 		return Candidate{}, false
 	}
 
 	var cand Candidate
-	err := g.DB.QueryRow(`
-		SELECT a.uprn, a.full_address, COALESCE(l.easting, 0), COALESCE(l.northing, 0)
+	var logicalStatus string
+	var startDate, endDate sql.NullTime
+	err := g.DB.QueryRowContext(ctx, `
+		SELECT a.uprn, a.full_address, COALESCE(l.easting, 0), COALESCE(l.northing, 0),
+		       COALESCE(a.logical_status, ''), a.start_date, a.end_date
 		FROM dim_address a
 		LEFT JOIN dim_location l ON a.location_id = l.location_id
 		WHERE a.uprn = $1
-	`, trimmedUPRN).Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing)
+	`, trimmedUPRN).Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing,
+		&logicalStatus, &startDate, &endDate)
 
 	if err != nil {
 		debug.DebugOutput(localDebug, "UPRN lookup failed for %s: %v", trimmedUPRN, err)
 		return Candidate{}, false
 	}
 
+	cand.setLifecycle(logicalStatus, startDate, endDate)
 	cand.Score = 1.0 // Perfect score for valid legacy UPRN
 	cand.Features = make(map[string]interface{})
 	return cand, true
 }
 
 // exactCanonicalMatch finds exact canonical address matches
-func (g *Generators) exactCanonicalMatch(localDebug bool, canonical string) []Candidate {
+func (g *Generators) exactCanonicalMatch(ctx context.Context, localDebug bool, canonical string) []Candidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if canonical == "" {
 		return []Candidate{}
 	}
 
-	rows, err := g.DB.Query(`
-		SELECT a.uprn, a.full_address, COALESCE(l.easting, 0), COALESCE(l.northing, 0)
+	rows, err := g.DB.QueryContext(ctx, `
+		SELECT a.uprn, a.full_address, COALESCE(l.easting, 0), COALESCE(l.northing, 0),
+		       COALESCE(a.logical_status, ''), a.start_date, a.end_date
 		FROM dim_address a
 		LEFT JOIN dim_location l ON a.location_id = l.location_id
 		WHERE a.address_canonical = $1
@@ -192,11 +207,15 @@ func (g *Generators) exactCanonicalMatch(localDebug bool, canonical string) []Ca
 	var candidates []Candidate
 	for rows.Next() {
 		var cand Candidate
-		err := rows.Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing)
+		var logicalStatus string
+		var startDate, endDate sql.NullTime
+		err := rows.Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing,
+			&logicalStatus, &startDate, &endDate)
 		if err != nil {
 			debug.DebugOutput(localDebug, "Error scanning exact match: %v", err)
 			continue
 		}
+		cand.setLifecycle(logicalStatus, startDate, endDate)
 		cand.Score = 0.99 // Very high score for exact match
 		cand.Features = make(map[string]interface{})
 		candidates = append(candidates, cand)
@@ -206,13 +225,17 @@ func (g *Generators) exactCanonicalMatch(localDebug bool, canonical string) []Ca
 }
 
 // trigramMatch uses PostgreSQL pg_trgm for fuzzy matching
-func (g *Generators) trigramMatch(localDebug bool, canonical string, tokens []string, threshold float64, limit int) []Candidate {
+func (g *Generators) trigramMatch(ctx context.Context, localDebug bool, canonical string, tokens []string, threshold float64, limit int) []Candidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if canonical == "" {
 		return []Candidate{}
 	}
 
-	rows, err := g.DB.Query(`
+	rows, err := g.DB.QueryContext(ctx, `
 		SELECT a.uprn, a.full_address, COALESCE(l.easting, 0), COALESCE(l.northing, 0),
+		       COALESCE(a.logical_status, ''), a.start_date, a.end_date,
 		       similarity($1, a.address_canonical) AS trgm_score
 		FROM dim_address a
 		LEFT JOIN dim_location l ON a.location_id = l.location_id
@@ -232,11 +255,15 @@ func (g *Generators) trigramMatch(localDebug bool, canonical string, tokens []st
 	for rows.Next() {
 		var cand Candidate
 		var trigramScore float64
-		err := rows.Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing, &trigramScore)
+		var logicalStatus string
+		var startDate, endDate sql.NullTime
+		err := rows.Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing,
+			&logicalStatus, &startDate, &endDate, &trigramScore)
 		if err != nil {
 			debug.DebugOutput(localDebug, "Error scanning trigram match: %v", err)
 			continue
 		}
+		cand.setLifecycle(logicalStatus, startDate, endDate)
 		cand.Score = trigramScore
 		cand.Features = map[string]interface{}{
 			"trigram_similarity": trigramScore,
@@ -249,7 +276,10 @@ func (g *Generators) trigramMatch(localDebug bool, canonical string, tokens []st
 }
 
 // vectorMatch uses vector similarity search
-func (g *Generators) vectorMatch(localDebug bool, canonical string, limit int) ([]Candidate, error) {
+func (g *Generators) vectorMatch(ctx context.Context, localDebug bool, canonical string, limit int) ([]Candidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if g.VDB == nil || g.Embedder == nil {
 		return []Candidate{}, fmt.Errorf("vector DB or embedder not available")
 	}
@@ -270,17 +300,22 @@ func (g *Generators) vectorMatch(localDebug bool, canonical string, limit int) (
 	for _, vr := range vectorResults {
 		// Look up full address details from PostgreSQL
 		var cand Candidate
-		err := g.DB.QueryRow(`
-			SELECT a.uprn, a.full_address, COALESCE(l.easting, 0), COALESCE(l.northing, 0)
+		var logicalStatus string
+		var startDate, endDate sql.NullTime
+		err := g.DB.QueryRowContext(ctx, `
+			SELECT a.uprn, a.full_address, COALESCE(l.easting, 0), COALESCE(l.northing, 0),
+			       COALESCE(a.logical_status, ''), a.start_date, a.end_date
 			FROM dim_address a
 			LEFT JOIN dim_location l ON a.location_id = l.location_id
 			WHERE a.uprn = $1
-		`, vr.UPRN).Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing)
+		`, vr.UPRN).Scan(&cand.UPRN, &cand.LocAddress, &cand.Easting, &cand.Northing,
+			&logicalStatus, &startDate, &endDate)
 
 		if err != nil {
 			debug.DebugOutput(localDebug, "Failed to lookup vector result UPRN %s: %v", vr.UPRN, err)
 			continue
 		}
+		cand.setLifecycle(logicalStatus, startDate, endDate)
 
 		cand.Score = vr.Score
 		cand.Features = map[string]interface{}{
@@ -301,6 +336,7 @@ func (g *Generators) applyPhoneticFilter(localDebug bool, candidates []Candidate
 			candidates[i].Features = make(map[string]interface{})
 		}
 		candidates[i].Features["phonetic_processing"] = true
+		candidates[i].Filters = append(candidates[i].Filters, "phonetic")
 	}
 	return candidates
 }
@@ -327,6 +363,7 @@ func (g *Generators) applyLocalityFilter(localDebug bool, candidates []Candidate
 			}
 		}
 		if hasMatch || len(candLocalities) == 0 { // Keep if match or no locality data
+			cand.Filters = append(cand.Filters, "locality")
 			filtered = append(filtered, cand)
 		}
 	}
@@ -358,6 +395,7 @@ func (g *Generators) applyHouseNumberFilter(localDebug bool, candidates []Candid
 		}
 		if hasMatch {
 			candidates[i].Features["same_house_number"] = true
+			candidates[i].Filters = append(candidates[i].Filters, "house_number")
 			if candidates[i].Score < 0.95 { // Avoid inflating perfect scores
 				candidates[i].Score += 0.05 // Small boost for house number match
 			}
@@ -376,6 +414,7 @@ func (g *Generators) applySpatialFilter(localDebug bool, candidates []Candidate,
 		if distance <= radiusMeters {
 			cand.Features["distance_meters"] = distance
 			cand.Features["spatial_boost"] = calculateSpatialBoost(distance)
+			cand.Filters = append(cand.Filters, fmt.Sprintf("spatial_%.0fkm", radiusMeters/1000))
 			filtered = append(filtered, cand)
 		}
 	}
@@ -384,45 +423,54 @@ func (g *Generators) applySpatialFilter(localDebug bool, candidates []Candidate,
 	return filtered
 }
 
-// dedupeByUPRN removes duplicate UPRNs, keeping the highest scoring candidate
+// dedupeByUPRN removes duplicate UPRNs, keeping the highest scoring candidate along with
+// every method that found the UPRN and every filter it passed
 func (g *Generators) dedupeByUPRN(candidates []Candidate) []Candidate {
 	uprnMap := make(map[string]Candidate)
+	var order []string
 
 	for _, cand := range candidates {
 		existing, exists := uprnMap[cand.UPRN]
-		if !exists || cand.Score > existing.Score {
-			// Merge methods from previous candidate if applicable
-			if exists {
-				methodSet := make(map[string]bool)
-				for _, method := range existing.Methods {
-					methodSet[method] = true
-				}
-				for _, method := range cand.Methods {
-					methodSet[method] = true
-				}
-				var allMethods []string
-				for method := range methodSet {
-					allMethods = append(allMethods, method)
-				}
-				cand.Methods = allMethods
-			}
+		if !exists {
+			order = append(order, cand.UPRN)
 			uprnMap[cand.UPRN] = cand
+			continue
+		}
+		methods := mergeNames(existing.Methods, cand.Methods)
+		filters := mergeNames(existing.Filters, cand.Filters)
+		if cand.Score > existing.Score {
+			existing = cand
 		}
+		existing.Methods, existing.Filters = methods, filters
+		uprnMap[cand.UPRN] = existing
 	}
 
 	var deduped []Candidate
-	for _, cand := range uprnMap {
-		deduped = append(deduped, cand)
+	for _, uprn := range order {
+		deduped = append(deduped, uprnMap[uprn])
 	}
 
 	return deduped
 }
 
-// calculateDistance computes Euclidean distance between two points (simplified)
+// mergeNames returns the names in a followed by those only in b, without duplicates
+func mergeNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, names := range [][]string{a, b} {
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				merged = append(merged, name)
+			}
+		}
+	}
+	return merged
+}
+
+// calculateDistance computes the distance in metres between two BNG points
 func calculateDistance(e1, n1, e2, n2 float64) float64 {
-	de := e1 - e2
-	dn := n1 - n2
-	return (de*de + dn*dn) * 0.5 // Simplified distance calculation
+	return geo.Distance(e1, n1, e2, n2)
 }
 
 // calculateSpatialBoost returns boost factor based on distance
@@ -0,0 +1,103 @@
+package match
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// Rejection scopes: a rejection made on the document itself, or on another document with
+// the same canonical address
+const (
+	RejectedForDocument = "document"
+	RejectedForAddress  = "address"
+)
+
+// Rejection is a reviewer's rejection of a UPRN (match_rejection) that rules the candidate out
+type Rejection struct {
+	RejectionID int64
+	SrcID       int64  // document the rejection was made on
+	Scope       string // RejectedForDocument or RejectedForAddress, relative to the query
+	UPRN        string
+	Source      string
+	Reason      string
+	RejectedBy  string
+	RejectedAt  time.Time
+}
+
+// Explain describes the rejection for explanation output
+func (r *Rejection) Explain() map[string]interface{} {
+	return map[string]interface{}{
+		"rejection_id": r.RejectionID,
+		"scope":        r.Scope,
+		"src_id":       r.SrcID,
+		"source":       r.Source,
+		"reason":       r.Reason,
+		"rejected_by":  r.RejectedBy,
+		"rejected_at":  r.RejectedAt,
+	}
+}
+
+// Rejections are the rejections that apply to one document, by UPRN
+type Rejections map[string]*Rejection
+
+// LoadRejections reads the unlifted rejections made on the document or on any document with
+// its canonical address
+func LoadRejections(ctx context.Context, db *sql.DB, srcID int64, canonical string) (Rejections, error) {
+	return LoadRejectionsFrom(ctx, store.NewPostgres(db), srcID, canonical)
+}
+
+// LoadRejectionsFrom is LoadRejections over a match store
+func LoadRejectionsFrom(ctx context.Context, matches store.MatchStore, srcID int64, canonical string) (Rejections, error) {
+	rows, err := matches.Rejections(ctx, srcID, canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	rejections := make(Rejections)
+	for _, row := range rows {
+		rejections.Add(srcID, Rejection{
+			RejectionID: row.RejectionID,
+			SrcID:       row.SrcID,
+			UPRN:        row.UPRN,
+			Source:      row.Source,
+			Reason:      row.Reason,
+			RejectedBy:  row.RejectedBy,
+			RejectedAt:  row.RejectedAt,
+		})
+	}
+	return rejections, nil
+}
+
+// Add records r against the document srcID, setting its scope. A rejection made on the
+// document itself takes precedence over one inherited through the canonical address.
+func (rs Rejections) Add(srcID int64, r Rejection) {
+	r.Scope = RejectedForAddress
+	if srcID != 0 && r.SrcID == srcID {
+		r.Scope = RejectedForDocument
+	}
+	if existing, ok := rs[r.UPRN]; ok && existing.Scope == RejectedForDocument {
+		return
+	}
+	rs[r.UPRN] = &r
+}
+
+// Rejected reports whether a reviewer has rejected uprn for the document
+func (rs Rejections) Rejected(uprn string) bool {
+	_, ok := rs[uprn]
+	return ok
+}
+
+// Suppress marks the candidates whose UPRN has been rejected and returns how many it marked
+func (rs Rejections) Suppress(candidates []Candidate) int {
+	suppressed := 0
+	for i := range candidates {
+		if r, ok := rs[candidates[i].UPRN]; ok {
+			candidates[i].Rejection = r
+			suppressed++
+		}
+	}
+	return suppressed
+}
@@ -0,0 +1,54 @@
+package match
+
+import "testing"
+
+func TestRejectionsPreferDocumentScope(t *testing.T) {
+	rejections := make(Rejections)
+	rejections.Add(42, Rejection{RejectionID: 1, SrcID: 42, UPRN: "100"})
+	rejections.Add(42, Rejection{RejectionID: 2, SrcID: 7, UPRN: "100"})
+	rejections.Add(42, Rejection{RejectionID: 3, SrcID: 7, UPRN: "200"})
+
+	if r := rejections["100"]; r.RejectionID != 1 || r.Scope != RejectedForDocument {
+		t.Errorf("UPRN 100 rejection = %d (%s), want 1 (document)", r.RejectionID, r.Scope)
+	}
+	if r := rejections["200"]; r.RejectionID != 3 || r.Scope != RejectedForAddress {
+		t.Errorf("UPRN 200 rejection = %d (%s), want 3 (address)", r.RejectionID, r.Scope)
+	}
+
+	// A query without a document only inherits rejections through the canonical address
+	anonymous := make(Rejections)
+	anonymous.Add(0, Rejection{RejectionID: 4, SrcID: 42, UPRN: "100"})
+	if r := anonymous["100"]; r.Scope != RejectedForAddress {
+		t.Errorf("scope without a document = %s, want address", r.Scope)
+	}
+}
+
+func TestMakeDecisionSkipsRejectedCandidates(t *testing.T) {
+	scorer := NewScorer()
+	candidates := []Candidate{
+		{UPRN: "100", Score: 0.97},
+		{UPRN: "200", Score: 0.95},
+		{UPRN: "300", Score: 0.60},
+	}
+	rejections := make(Rejections)
+	rejections.Add(42, Rejection{RejectionID: 1, SrcID: 42, UPRN: "100"})
+	if n := rejections.Suppress(candidates); n != 1 {
+		t.Fatalf("Suppress() = %d, want 1", n)
+	}
+
+	// The rejected leader is no longer the runner-up either, so 200 wins by a clear margin
+	decision, uprn := scorer.MakeDecision(false, candidates)
+	if decision != "auto_accept" || uprn != "200" {
+		t.Errorf("MakeDecision() = (%q, %q), want (\"auto_accept\", \"200\")", decision, uprn)
+	}
+
+	explanation := scorer.GetExplanation(candidates[0], false)
+	if explanation["suppressed"] != true || explanation["suppressed_by"] == nil {
+		t.Errorf("explanation of rejected candidate = %v, want it suppressed", explanation)
+	}
+
+	only := []Candidate{{UPRN: "100", Score: 0.99, Rejection: rejections["100"]}}
+	if decision, uprn := scorer.MakeDecision(false, only); decision != "reject" || uprn != "" {
+		t.Errorf("MakeDecision() with only a rejected candidate = (%q, %q), want (\"reject\", \"\")", decision, uprn)
+	}
+}
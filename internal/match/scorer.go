@@ -1,6 +1,7 @@
 package match
 
 import (
+	"fmt"
 	"math"
 	"sort"
 
@@ -39,8 +40,11 @@ func (s *Scorer) ScoreCandidates(localDebug bool, candidates []Candidate, legacy
 		debug.DebugOutput(localDebug, "Candidate %s scored: %.4f", candidates[i].UPRN, candidates[i].Score)
 	}
 
-	// Sort candidates by score (highest first)
+	// Sort candidates by score (highest first), suppressed candidates last
 	sort.Slice(candidates, func(i, j int) bool {
+		if (candidates[i].Rejection == nil) != (candidates[j].Rejection == nil) {
+			return candidates[i].Rejection == nil
+		}
 		return candidates[i].Score > candidates[j].Score
 	})
 
@@ -54,98 +58,152 @@ func (s *Scorer) ScoreCandidate(localDebug bool, features map[string]interface{}
 	defer debug.DebugFooter(localDebug)
 
 	var score float64
+	for _, c := range s.Contributions(features, legacyUPRNValid) {
+		if c.Points != 0 {
+			debug.DebugOutput(localDebug, "%s: %.3f*%.2f = %+.4f", c.Feature, c.Value, c.Weight, c.Points)
+		}
+		score += c.Points
+	}
 
-	// Core similarities (weighted 0.45 + 0.45 = 0.90 total)
-	trigramSim := s.getFloatFeature(features, "trigram_similarity", 0.0)
-	embeddingCos := s.getFloatFeature(features, "embedding_cosine", 0.0)
-	
-	score += s.weights.TrigramSimilarity * trigramSim
-	score += s.weights.EmbeddingCosine * embeddingCos
-	
-	debug.DebugOutput(localDebug, "Core similarities: trgm=%.3f*%.2f + emb=%.3f*%.2f = %.4f", 
-		trigramSim, s.weights.TrigramSimilarity, embeddingCos, s.weights.EmbeddingCosine, 
-		s.weights.TrigramSimilarity*trigramSim + s.weights.EmbeddingCosine*embeddingCos)
-
-	// Token overlaps
-	localityOverlap := s.getFloatFeature(features, "locality_overlap_ratio", 0.0)
-	streetOverlap := s.getFloatFeature(features, "street_overlap_ratio", 0.0)
+	// Clamp score to [0, 1] range
+	score = math.Max(0.0, math.Min(1.0, score))
 	
-	score += s.weights.LocalityOverlap * localityOverlap
-	score += s.weights.StreetOverlap * streetOverlap
+	debug.DebugOutput(localDebug, "Final score (clamped): %.4f", score)
 	
-	debug.DebugOutput(localDebug, "Token overlaps: locality=%.3f*%.2f + street=%.3f*%.2f = %.4f", 
-		localityOverlap, s.weights.LocalityOverlap, streetOverlap, s.weights.StreetOverlap,
-		s.weights.LocalityOverlap*localityOverlap + s.weights.StreetOverlap*streetOverlap)
+	return score
+}
 
-	// Boolean features (positive boosts)
-	var boosts float64
-	
-	if s.getBoolFeature(features, "has_same_house_num") {
-		boosts += s.weights.SameHouseNumber
-		debug.DebugOutput(localDebug, "House number match: +%.3f", s.weights.SameHouseNumber)
-	}
-	
-	if s.getBoolFeature(features, "has_same_house_alpha") {
-		boosts += s.weights.SameHouseAlpha
-		debug.DebugOutput(localDebug, "House alpha match: +%.3f", s.weights.SameHouseAlpha)
-	}
-	
-	if s.getBoolFeature(features, "usrn_match") {
-		boosts += s.weights.USRNMatch
-		debug.DebugOutput(localDebug, "USRN match: +%.3f", s.weights.USRNMatch)
-	}
-	
-	if s.getBoolFeature(features, "llpg_live") {
-		boosts += s.weights.LLPGLive
-		debug.DebugOutput(localDebug, "LLPG live status: +%.3f", s.weights.LLPGLive)
+// Contributions breaks a candidate's score down by feature, before clamping. Every weighted
+// feature is listed, with zero points where it did not apply.
+func (s *Scorer) Contributions(features map[string]interface{}, legacyUPRNValid bool) []Contribution {
+	flag := func(on bool) float64 {
+		if on {
+			return 1
+		}
+		return 0
 	}
-	
-	if legacyUPRNValid {
-		boosts += s.weights.LegacyUPRNValid
-		debug.DebugOutput(localDebug, "Legacy UPRN valid: +%.3f", s.weights.LegacyUPRNValid)
+	weighted := func(feature string, value, weight float64) Contribution {
+		return Contribution{Feature: feature, Value: value, Weight: weight, Points: value * weight}
 	}
-	
-	score += boosts
 
-	// Spatial boost
-	spatialBoost := s.getFloatFeature(features, "spatial_boost", 0.0)
-	score += spatialBoost
-	if spatialBoost > 0 {
-		debug.DebugOutput(localDebug, "Spatial boost: +%.4f", spatialBoost)
+	// Core similarities (weighted 0.45 + 0.45 = 0.90 total) and token overlaps
+	contributions := []Contribution{
+		weighted("trigram_similarity", s.getFloatFeature(features, "trigram_similarity", 0.0), s.weights.TrigramSimilarity),
+		weighted("embedding_cosine", s.getFloatFeature(features, "embedding_cosine", 0.0), s.weights.EmbeddingCosine),
+		weighted("locality_overlap", s.getFloatFeature(features, "locality_overlap_ratio", 0.0), s.weights.LocalityOverlap),
+		weighted("street_overlap", s.getFloatFeature(features, "street_overlap_ratio", 0.0), s.weights.StreetOverlap),
 	}
 
-	// Penalties (negative impacts)
-	var penalties float64
-	
-	if s.getBoolFeature(features, "descriptor_penalty") {
-		penalties += s.weights.DescriptorPenalty // This is negative
-		debug.DebugOutput(localDebug, "Descriptor penalty: %.3f", s.weights.DescriptorPenalty)
-	}
-	
+	// Boolean features (positive boosts)
+	contributions = append(contributions,
+		weighted("same_house_number", flag(s.getBoolFeature(features, "has_same_house_num")), s.weights.SameHouseNumber),
+		weighted("same_house_alpha", flag(s.getBoolFeature(features, "has_same_house_alpha")), s.weights.SameHouseAlpha),
+		weighted("usrn_match", flag(s.getBoolFeature(features, "usrn_match")), s.weights.USRNMatch),
+		weighted("llpg_live", flag(s.getBoolFeature(features, "llpg_live")), s.weights.LLPGLive),
+		weighted("legacy_uprn_valid", flag(legacyUPRNValid), s.weights.LegacyUPRNValid),
+	)
+
+	// Spatial boost is already in points
+	contributions = append(contributions,
+		weighted("spatial_boost", s.getFloatFeature(features, "spatial_boost", 0.0), 1.0))
+
+	// Penalties (negative weights)
 	phoneticHits := s.getIntFeature(features, "phonetic_hits", 0)
+	phoneticMiss := Contribution{Feature: "phonetic_miss", Value: float64(phoneticHits), Weight: s.weights.PhoneticMissPenalty}
 	if phoneticHits == 0 {
-		penalties += s.weights.PhoneticMissPenalty // This is negative
-		debug.DebugOutput(localDebug, "No phonetic matches penalty: %.3f", s.weights.PhoneticMissPenalty)
+		phoneticMiss.Points = s.weights.PhoneticMissPenalty
 	}
-	
-	score += penalties
+	contributions = append(contributions,
+		weighted("descriptor_penalty", flag(s.getBoolFeature(features, "descriptor_penalty")), s.weights.DescriptorPenalty),
+		phoneticMiss,
+		weighted("temporal_mismatch", flag(s.hasTemporalMismatch(features)), s.weights.TemporalMismatch),
+	)
 
-	// Clamp score to [0, 1] range
-	score = math.Max(0.0, math.Min(1.0, score))
-	
-	debug.DebugOutput(localDebug, "Final score (clamped): %.4f", score)
-	
-	return score
+	return contributions
+}
+
+// Explain explains every candidate of a result against the scorer's weights and tiers:
+// where it came from, what it scored for and how far it stood from the best other
+// eligible candidate
+func (s *Scorer) Explain(result Result, legacyUPRNValid bool) []CandidateExplanation {
+	explanations := make([]CandidateExplanation, 0, len(result.Candidates))
+	for i, candidate := range result.Candidates {
+		// Best eligible score other than this candidate's own
+		var best float64
+		found := false
+		for j, other := range result.Candidates {
+			if j != i && other.Rejection == nil && (!found || other.Score > best) {
+				best, found = other.Score, true
+			}
+		}
+		margin := 1.0 // as in Decide, a lone candidate has the maximum margin
+		if found {
+			margin = candidate.Score - best
+		}
+
+		explanation := CandidateExplanation{
+			UPRN:          candidate.UPRN,
+			Address:       candidate.LocAddress,
+			Rank:          i + 1,
+			Score:         candidate.Score,
+			Selected:      result.AcceptedUPRN != "" && candidate.UPRN == result.AcceptedUPRN,
+			Tier:          s.Tier(candidate.Score),
+			Margin:        margin,
+			Methods:       candidate.Methods,
+			Filters:       candidate.Filters,
+			Contributions: s.Contributions(candidate.Features, legacyUPRNValid),
+			Features:      candidate.Features,
+		}
+		if candidate.Rejection != nil {
+			explanation.SuppressedBy = candidate.Rejection.Explain()
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations
+}
+
+// Tier returns the highest threshold tier the score reaches
+func (s *Scorer) Tier(score float64) string {
+	switch {
+	case score >= s.tiers.AutoAcceptHigh:
+		return TierAutoAcceptHigh
+	case score >= s.tiers.AutoAcceptMedium:
+		return TierAutoAcceptMedium
+	case score >= s.tiers.ReviewThreshold:
+		return TierReview
+	case score >= s.tiers.MinThreshold:
+		return TierMinThreshold
+	default:
+		return TierBelowMin
+	}
 }
 
 // MakeDecision determines the match decision based on scores and thresholds
 func (s *Scorer) MakeDecision(localDebug bool, candidates []Candidate) (decision string, acceptedUPRN string) {
+	d := s.Decide(localDebug, candidates)
+	return d.Decision, d.AcceptedUPRN
+}
+
+// Decide determines the match decision and records the tier, margin and reason behind it
+func (s *Scorer) Decide(localDebug bool, candidates []Candidate) DecisionDetail {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 
+	// Candidates a reviewer has rejected take no part, not even as the runner-up for the margin
+	eligible := make([]Candidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Rejection != nil {
+			debug.DebugOutput(localDebug, "Candidate %s suppressed by prior rejection %d (%s)",
+				candidate.UPRN, candidate.Rejection.RejectionID, candidate.Rejection.Scope)
+			continue
+		}
+		eligible = append(eligible, candidate)
+	}
+	candidates = eligible
+
 	if len(candidates) == 0 {
 		debug.DebugOutput(localDebug, "No candidates - reject")
-		return "reject", ""
+		return DecisionDetail{Decision: "reject", Tier: TierBelowMin, Reason: "no eligible candidates"}
 	}
 
 	topCandidate := candidates[0]
@@ -153,12 +211,6 @@ func (s *Scorer) MakeDecision(localDebug bool, candidates []Candidate) (decision
 
 	debug.DebugOutput(localDebug, "Top candidate: %s (score=%.4f)", topCandidate.UPRN, topScore)
 
-	// Check if score is below minimum threshold
-	if topScore < s.tiers.MinThreshold {
-		debug.DebugOutput(localDebug, "Score %.4f below min threshold %.4f - reject", topScore, s.tiers.MinThreshold)
-		return "reject", ""
-	}
-
 	// Calculate margin to next best candidate
 	var margin float64 = 1.0 // Default to maximum margin if only one candidate
 	if len(candidates) > 1 {
@@ -166,15 +218,30 @@ func (s *Scorer) MakeDecision(localDebug bool, candidates []Candidate) (decision
 		debug.DebugOutput(localDebug, "Margin to next candidate: %.4f (next score: %.4f)", margin, candidates[1].Score)
 	}
 
+	d := DecisionDetail{Decision: "reject", Tier: s.Tier(topScore), Margin: margin}
+
+	// Check if score is below minimum threshold
+	if topScore < s.tiers.MinThreshold {
+		debug.DebugOutput(localDebug, "Score %.4f below min threshold %.4f - reject", topScore, s.tiers.MinThreshold)
+		d.Reason = fmt.Sprintf("top score %.4f below min threshold %.4f", topScore, s.tiers.MinThreshold)
+		return d
+	}
+
+	// Never auto-accept an address that did not exist on the document date
+	temporalMismatch := s.hasTemporalMismatch(topCandidate.Features)
+
 	// Auto-accept high confidence with sufficient margin
-	if topScore >= s.tiers.AutoAcceptHigh && margin >= s.tiers.WinnerMargin {
+	if !temporalMismatch && topScore >= s.tiers.AutoAcceptHigh && margin >= s.tiers.WinnerMargin {
 		debug.DebugOutput(localDebug, "Auto-accept: high confidence %.4f >= %.4f with margin %.4f >= %.4f", 
 			topScore, s.tiers.AutoAcceptHigh, margin, s.tiers.WinnerMargin)
-		return "auto_accept", topCandidate.UPRN
+		d.Decision, d.AcceptedUPRN = "auto_accept", topCandidate.UPRN
+		d.Reason = fmt.Sprintf("high confidence %.4f >= %.4f with margin %.4f >= %.4f",
+			topScore, s.tiers.AutoAcceptHigh, margin, s.tiers.WinnerMargin)
+		return d
 	}
 
 	// Auto-accept medium confidence with additional conditions and larger margin
-	if topScore >= s.tiers.AutoAcceptMedium && margin >= s.tiers.WinnerMargin+0.02 {
+	if !temporalMismatch && topScore >= s.tiers.AutoAcceptMedium && margin >= s.tiers.WinnerMargin+0.02 {
 		// Additional conditions for medium confidence auto-accept
 		hasHouseNumber := s.getBoolFeature(topCandidate.Features, "has_same_house_num")
 		localityOverlap := s.getFloatFeature(topCandidate.Features, "locality_overlap_ratio", 0.0)
@@ -182,19 +249,32 @@ func (s *Scorer) MakeDecision(localDebug bool, candidates []Candidate) (decision
 		if hasHouseNumber && localityOverlap >= 0.5 {
 			debug.DebugOutput(localDebug, "Auto-accept: medium confidence %.4f >= %.4f with house number and locality overlap %.3f", 
 				topScore, s.tiers.AutoAcceptMedium, localityOverlap)
-			return "auto_accept", topCandidate.UPRN
+			d.Decision, d.AcceptedUPRN = "auto_accept", topCandidate.UPRN
+			d.Reason = fmt.Sprintf("medium confidence %.4f >= %.4f with house number, locality overlap %.3f and margin %.4f",
+				topScore, s.tiers.AutoAcceptMedium, localityOverlap, margin)
+			return d
 		}
 	}
 
 	// Review if score is above review threshold
 	if topScore >= s.tiers.ReviewThreshold {
 		debug.DebugOutput(localDebug, "Manual review: score %.4f >= %.4f but not auto-accept", topScore, s.tiers.ReviewThreshold)
-		return "review", ""
+		d.Decision = "review"
+		switch {
+		case temporalMismatch:
+			d.Reason = "top candidate did not exist on the document date"
+		case topScore >= s.tiers.AutoAcceptMedium:
+			d.Reason = fmt.Sprintf("score %.4f reaches %s but margin %.4f or conditions fall short", topScore, d.Tier, margin)
+		default:
+			d.Reason = fmt.Sprintf("score %.4f >= review threshold %.4f", topScore, s.tiers.ReviewThreshold)
+		}
+		return d
 	}
 
 	// Otherwise reject
 	debug.DebugOutput(localDebug, "Reject: score %.4f < review threshold %.4f", topScore, s.tiers.ReviewThreshold)
-	return "reject", ""
+	d.Reason = fmt.Sprintf("score %.4f < review threshold %.4f", topScore, s.tiers.ReviewThreshold)
+	return d
 }
 
 // GetExplanation returns a human-readable explanation of the score
@@ -247,14 +327,38 @@ func (s *Scorer) GetExplanation(candidate Candidate, legacyUPRNValid bool) map[s
 	if phoneticHits == 0 {
 		totalPenalties += s.weights.PhoneticMissPenalty
 	}
+	if s.hasTemporalMismatch(candidate.Features) {
+		totalPenalties += s.weights.TemporalMismatch
+	}
 	explanation["penalties_total"] = totalPenalties
+
+	// Point-in-time compatibility
+	explanation["temporal_status"] = candidate.Features["temporal_status"]
+	explanation["temporal_gap_days"] = candidate.Features["temporal_gap_days"]
 	
 	explanation["final_score"] = candidate.Score
 	explanation["methods"] = candidate.Methods
+	explanation["filters"] = candidate.Filters
+	explanation["contributions"] = s.Contributions(candidate.Features, legacyUPRNValid)
+	explanation["tier"] = s.Tier(candidate.Score)
+
+	// Prior rejection
+	explanation["suppressed"] = candidate.Rejection != nil
+	if candidate.Rejection != nil {
+		explanation["suppressed_by"] = candidate.Rejection.Explain()
+	}
 	
 	return explanation
 }
 
+// hasTemporalMismatch reports whether the candidate could not have existed on the document date
+func (s *Scorer) hasTemporalMismatch(features map[string]interface{}) bool {
+	if status, ok := features["temporal_status"].(string); ok {
+		return !IsTemporallyCompatible(status)
+	}
+	return false
+}
+
 // Helper functions to safely extract typed values from features map
 func (s *Scorer) getFloatFeature(features map[string]interface{}, key string, defaultVal float64) float64 {
 	if val, exists := features[key]; exists {
@@ -0,0 +1,85 @@
+package match
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Temporal status values describe whether a candidate address existed on the document date
+const (
+	TemporalExisted     = "existed"
+	TemporalNotYetBuilt = "not_yet_built"
+	TemporalEnded       = "ended_before_document"
+	TemporalUnknown     = "unknown"
+)
+
+// BS7666 LPI logical status codes held in dim_address.logical_status
+const (
+	LogicalStatusApproved    = "1"
+	LogicalStatusAlternative = "3"
+	LogicalStatusProvisional = "6"
+	LogicalStatusHistoric    = "8"
+)
+
+// TemporalGraceDays allows for documents that legitimately pre-date the LLPG start date,
+// e.g. a planning decision issued before the new dwelling was built and addressed
+const TemporalGraceDays = 730
+
+// TemporalCompatibility reports whether an address with the given lifecycle dates existed
+// on the document date, and how many days outside its lifetime the document falls
+func TemporalCompatibility(docDate, startDate, endDate *time.Time) (status string, gapDays int) {
+	if docDate == nil || (startDate == nil && endDate == nil) {
+		return TemporalUnknown, 0
+	}
+
+	if startDate != nil && docDate.Before(*startDate) {
+		gap := daysBetween(*docDate, *startDate)
+		if gap > TemporalGraceDays {
+			return TemporalNotYetBuilt, gap
+		}
+	}
+
+	if endDate != nil && docDate.After(*endDate) {
+		return TemporalEnded, daysBetween(*endDate, *docDate)
+	}
+
+	return TemporalExisted, 0
+}
+
+// TemporalPredicate is TemporalCompatibility as a SQL condition, for queries that limit their
+// candidates: it holds unless the dim_address row aliased alias could not have existed on the
+// document date in the parameter param (e.g. "$2"), which may be NULL
+func TemporalPredicate(alias, param string) string {
+	return fmt.Sprintf(`(%[2]s::date IS NULL
+		OR ((%[1]s.start_date IS NULL OR %[1]s.start_date <= %[2]s::date + %[3]d)
+		AND (%[1]s.end_date IS NULL OR %[1]s.end_date >= %[2]s::date)))`, alias, param, TemporalGraceDays)
+}
+
+// IsTemporallyCompatible returns false when the address could not have existed on the document date
+func IsTemporallyCompatible(status string) bool {
+	return status != TemporalNotYetBuilt && status != TemporalEnded
+}
+
+// IsLiveStatus reports whether an LLPG logical status represents a current address.
+// Unknown status is treated as live to match the behaviour before status was loaded.
+func IsLiveStatus(logicalStatus string) bool {
+	return logicalStatus != LogicalStatusHistoric
+}
+
+// setLifecycle copies nullable lifecycle columns onto a candidate
+func (c *Candidate) setLifecycle(logicalStatus string, startDate, endDate sql.NullTime) {
+	c.LogicalStatus = logicalStatus
+	if startDate.Valid {
+		t := startDate.Time
+		c.StartDate = &t
+	}
+	if endDate.Valid {
+		t := endDate.Time
+		c.EndDate = &t
+	}
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
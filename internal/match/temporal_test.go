@@ -0,0 +1,89 @@
+package match
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) *time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestTemporalCompatibility(t *testing.T) {
+	tests := []struct {
+		name       string
+		docDate    *time.Time
+		startDate  *time.Time
+		endDate    *time.Time
+		wantStatus string
+	}{
+		{
+			name:       "no document date",
+			docDate:    nil,
+			startDate:  date("2015-06-01"),
+			wantStatus: TemporalUnknown,
+		},
+		{
+			name:       "no lifecycle dates",
+			docDate:    date("1978-03-01"),
+			wantStatus: TemporalUnknown,
+		},
+		{
+			name:       "enforcement notice pre-dates new build",
+			docDate:    date("1978-03-01"),
+			startDate:  date("2015-06-01"),
+			wantStatus: TemporalNotYetBuilt,
+		},
+		{
+			name:       "planning decision shortly before address creation",
+			docDate:    date("2014-09-01"),
+			startDate:  date("2015-06-01"),
+			wantStatus: TemporalExisted,
+		},
+		{
+			name:       "document after demolition",
+			docDate:    date("2020-01-01"),
+			startDate:  date("1950-01-01"),
+			endDate:    date("2010-01-01"),
+			wantStatus: TemporalEnded,
+		},
+		{
+			name:       "historic address matched by old document",
+			docDate:    date("1985-01-01"),
+			startDate:  date("1950-01-01"),
+			endDate:    date("2010-01-01"),
+			wantStatus: TemporalExisted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := TemporalCompatibility(tt.docDate, tt.startDate, tt.endDate)
+			if status != tt.wantStatus {
+				t.Errorf("TemporalCompatibility() = %q, want %q", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMakeDecisionTemporalMismatch(t *testing.T) {
+	scorer := NewScorer()
+	candidates := []Candidate{
+		{
+			UPRN:  "1710012345",
+			Score: 0.98,
+			Features: map[string]interface{}{
+				"temporal_status": TemporalNotYetBuilt,
+			},
+		},
+	}
+
+	decision, uprn := scorer.MakeDecision(false, candidates)
+	if decision != "review" || uprn != "" {
+		t.Errorf("MakeDecision() = (%q, %q), want (\"review\", \"\")", decision, uprn)
+	}
+}
@@ -24,6 +24,16 @@ type Candidate struct {
 	Score       float64
 	Features    map[string]interface{} // explainability
 	Methods     []string              // which generators hit (valid_uprn, trigram, vector, etc.)
+	Filters     []string              // which generation filters it passed (locality, spatial_2km, etc.)
+
+	// LLPG lifecycle, used for point-in-time matching against Input.DocDate
+	LogicalStatus string     // BS7666 logical status (1, 3, 6, 8)
+	StartDate     *time.Time // optional
+	EndDate       *time.Time // optional
+
+	// Set when a reviewer has rejected this UPRN for the document or its canonical address;
+	// a suppressed candidate is kept for explanation but never proposed or accepted
+	Rejection *Rejection
 }
 
 // Result represents the complete matching result
@@ -32,10 +42,55 @@ type Result struct {
 	Candidates    []Candidate // sorted hi→lo
 	Decision      string      // "auto_accept" | "review" | "reject"
 	AcceptedUPRN  string
+	Tier          string  // threshold tier the top eligible candidate reached
+	Margin        float64 // top eligible score minus the runner-up's
+	Reason        string  // why the decision was made
 	Thresholds    map[string]float64
 	ProcessingTime time.Duration
 }
 
+// Contribution is one feature's share of a candidate's score: Points = Value * Weight
+type Contribution struct {
+	Feature string  `json:"feature"`
+	Value   float64 `json:"value"`
+	Weight  float64 `json:"weight"`
+	Points  float64 `json:"points"`
+}
+
+// DecisionDetail is a decision with the tier and margin that produced it
+type DecisionDetail struct {
+	Decision     string  // "auto_accept" | "review" | "reject"
+	AcceptedUPRN string
+	Tier         string
+	Margin       float64
+	Reason       string
+}
+
+// CandidateExplanation is what is persisted and served for each candidate of a decision
+type CandidateExplanation struct {
+	UPRN          string                 `json:"uprn"`
+	Address       string                 `json:"address"`
+	Rank          int                    `json:"rank"`
+	Score         float64                `json:"score"`
+	Selected      bool                   `json:"selected"`
+	Tier          string                 `json:"tier"`
+	Margin        float64                `json:"margin"` // to the best other eligible candidate
+	Methods       []string               `json:"methods"`
+	Filters       []string               `json:"filters"`
+	Contributions []Contribution         `json:"contributions"`
+	Features      map[string]interface{} `json:"features"`
+	SuppressedBy  map[string]interface{} `json:"suppressed_by,omitempty"`
+}
+
+// Threshold tiers a score can reach, highest first
+const (
+	TierAutoAcceptHigh   = "auto_accept_high"
+	TierAutoAcceptMedium = "auto_accept_medium"
+	TierReview           = "review"
+	TierMinThreshold     = "min_threshold"
+	TierBelowMin         = "below_min"
+)
+
 // MatchTiers defines the matching confidence tiers
 type MatchTiers struct {
 	AutoAcceptHigh   float64 // >= 0.92
@@ -70,6 +125,7 @@ type FeatureWeights struct {
 	SpatialBoostMax       float64 // varies with distance
 	DescriptorPenalty     float64 // -0.05
 	PhoneticMissPenalty   float64 // -0.03
+	TemporalMismatch      float64 // -0.15 address did not exist on the document date
 }
 
 // DefaultWeights returns the recommended feature weights from ADDRESS_MATCHING_ALGORITHM.md
@@ -87,5 +143,6 @@ func DefaultWeights() *FeatureWeights {
 		SpatialBoostMax:     0.10,
 		DescriptorPenalty:   -0.05,
 		PhoneticMissPenalty: -0.03,
+		TemporalMismatch:    -0.15,
 	}
 }
\ No newline at end of file
@@ -1,27 +1,35 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/store"
 )
 
-// BatchProcessor handles batch address matching operations
+// BatchProcessor handles batch address matching operations. Runs are checkpointed, so
+// an interrupted or failed run can be resumed with Resume.
 type BatchProcessor struct {
 	engine *Engine
 	db     *sql.DB
+	stores store.Stores
 }
 
-// BatchStats tracks batch processing statistics
+// BatchStats tracks batch processing statistics. For a resumed run they cover every leg.
 type BatchStats struct {
+	RunID             int64
+	Legs              int
 	TotalDocuments    int
 	ProcessedCount    int
 	AutoAcceptCount   int
 	NeedsReviewCount  int
 	NoMatchCount      int
 	ErrorCount        int
+	ScoreTotal        float64
 	AverageScore      float64
 	ProcessingTime    time.Duration
 }
@@ -31,26 +39,57 @@ func NewBatchProcessor(engine *Engine, db *sql.DB) *BatchProcessor {
 	return &BatchProcessor{
 		engine: engine,
 		db:     db,
+		stores: store.NewPostgresStores(db),
 	}
 }
 
 // ProcessAllDocuments processes all unmatched documents in the database
-func (bp *BatchProcessor) ProcessAllDocuments(localDebug bool, batchSize int) (*BatchStats, error) {
+func (bp *BatchProcessor) ProcessAllDocuments(ctx context.Context, localDebug bool, batchSize int) (*BatchStats, error) {
+	return bp.start(ctx, localDebug, RunConfig{Processor: ProcessorStandard, BatchSize: batchSize})
+}
+
+// ProcessDocumentsByType processes documents of a specific type
+func (bp *BatchProcessor) ProcessDocumentsByType(ctx context.Context, localDebug bool, docType string, batchSize int) (*BatchStats, error) {
+	return bp.start(ctx, localDebug, RunConfig{Processor: ProcessorStandard, BatchSize: batchSize, DocType: docType})
+}
+
+// Resume continues a stopped run from its checkpoint with the configuration it was
+// started with
+func (bp *BatchProcessor) Resume(ctx context.Context, localDebug bool, runID int64) (*BatchStats, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
-	
-	startTime := time.Now()
+
 	stats := &BatchStats{}
+	run, err := reopenBatchRun(ctx, bp.stores, runID, ProcessorStandard, stats)
+	if err != nil {
+		return nil, err
+	}
+	run.resumed(stats)
+
+	debug.DebugOutput(localDebug, "Resuming run %d (leg %d) after document %d", runID, stats.Legs, run.cursor)
+
+	return stats, bp.process(ctx, localDebug, run, stats)
+}
+
+// start records a new run and processes it
+func (bp *BatchProcessor) start(ctx context.Context, localDebug bool, cfg RunConfig) (*BatchStats, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
 	
-	debug.DebugOutput(localDebug, "Starting batch processing with batch size: %d", batchSize)
+	stats := &BatchStats{Legs: 1}
+	
+	debug.DebugOutput(localDebug, "Starting batch processing with batch size: %d", cfg.BatchSize)
 	
 	// Get total count of unmatched documents
-	err := bp.db.QueryRow(`
+	err := bp.db.QueryRowContext(ctx, `
 		SELECT COUNT(*)
 		FROM src_document s
+		LEFT JOIN dim_document_type dt ON dt.doc_type_id = s.doc_type_id
 		LEFT JOIN address_match m ON m.document_id = s.document_id
 		WHERE m.document_id IS NULL
-	`).Scan(&stats.TotalDocuments)
+		  AND s.removed_at IS NULL
+		  AND ($1 = '' OR dt.type_code = $1)
+	`, cfg.DocType).Scan(&stats.TotalDocuments)
 	
 	if err != nil {
 		return nil, fmt.Errorf("failed to count documents: %w", err)
@@ -62,71 +101,23 @@ func (bp *BatchProcessor) ProcessAllDocuments(localDebug bool, batchSize int) (*
 		debug.DebugOutput(localDebug, "No documents to process")
 		return stats, nil
 	}
-	
-	// Process documents in batches
-	offset := 0
-	var totalScore float64
-	
-	for offset < stats.TotalDocuments {
-		debug.DebugOutput(localDebug, "Processing batch: %d-%d of %d", 
-			offset+1, minInt(offset+batchSize, stats.TotalDocuments), stats.TotalDocuments)
-		
-		// Get batch of unmatched documents
-		inputs, err := bp.getUnmatchedDocuments(offset, batchSize)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error getting batch: %v", err)
-			stats.ErrorCount++
-			offset += batchSize
-			continue
-		}
-		
-		// Process each document in the batch
-		for _, input := range inputs {
-			result, err := bp.engine.ProcessDocument(false, input) // Disable debug for batch processing
-			if err != nil {
-				debug.DebugOutput(localDebug, "Error processing document %d: %v", input.DocumentID, err)
-				stats.ErrorCount++
-				continue
-			}
-			
-			// Save result
-			err = bp.engine.SaveMatchResult(false, result)
-			if err != nil {
-				debug.DebugOutput(localDebug, "Error saving result for document %d: %v", input.DocumentID, err)
-				stats.ErrorCount++
-				continue
-			}
-			
-			// Update statistics
-			stats.ProcessedCount++
-			if result.BestCandidate != nil {
-				totalScore += result.BestCandidate.Score
-			}
-			
-			switch result.Decision {
-			case "auto_accept":
-				stats.AutoAcceptCount++
-			case "needs_review", "low_confidence":
-				stats.NeedsReviewCount++
-			case "no_match":
-				stats.NoMatchCount++
-			}
-			
-			// Progress reporting
-			if stats.ProcessedCount%100 == 0 {
-				debug.DebugOutput(localDebug, "Processed %d/%d documents (%.1f%%)", 
-					stats.ProcessedCount, stats.TotalDocuments, 
-					float64(stats.ProcessedCount)/float64(stats.TotalDocuments)*100)
-			}
-		}
-		
-		offset += batchSize
+
+	run, err := startBatchRun(ctx, bp.stores, cfg)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Calculate final statistics
-	stats.ProcessingTime = time.Since(startTime)
-	if stats.ProcessedCount > 0 {
-		stats.AverageScore = totalScore / float64(stats.ProcessedCount)
+	debug.DebugOutput(localDebug, "Started run %d", run.runID)
+
+	return stats, bp.process(ctx, localDebug, run, stats)
+}
+
+// process runs the batches and records how the run ended
+func (bp *BatchProcessor) process(ctx context.Context, localDebug bool, run *batchRun, stats *BatchStats) error {
+	err := run.process(ctx, localDebug, bp.engine, bp.getUnmatchedDocuments, stats, stats, nil)
+	run.finish(localDebug, err, stats, stats)
+	if err != nil {
+		// Interrupted or failed: every document so far was saved whole; stats are partial
+		return err
 	}
 	
 	debug.DebugOutput(localDebug, "Batch processing complete:")
@@ -143,203 +134,52 @@ func (bp *BatchProcessor) ProcessAllDocuments(localDebug bool, batchSize int) (*
 	debug.DebugOutput(localDebug, "  Average score: %.4f", stats.AverageScore)
 	debug.DebugOutput(localDebug, "  Processing time: %v", stats.ProcessingTime)
 	
-	return stats, nil
+	return nil
 }
 
-// ProcessDocumentsByType processes documents of a specific type
-func (bp *BatchProcessor) ProcessDocumentsByType(localDebug bool, docType string, batchSize int) (*BatchStats, error) {
-	debug.DebugHeader(localDebug)
-	defer debug.DebugFooter(localDebug)
-	
-	startTime := time.Now()
-	stats := &BatchStats{}
-	
-	debug.DebugOutput(localDebug, "Processing documents of type: %s", docType)
-	
-	// Get total count of unmatched documents of this type
-	err := bp.db.QueryRow(`
-		SELECT COUNT(*)
-		FROM src_document s
-		INNER JOIN dim_document_type dt ON dt.doc_type_id = s.doc_type_id
-		LEFT JOIN address_match m ON m.document_id = s.document_id
-		WHERE dt.type_code = $1 AND m.document_id IS NULL
-	`, docType).Scan(&stats.TotalDocuments)
-	
-	if err != nil {
-		return nil, fmt.Errorf("failed to count documents: %w", err)
-	}
-	
-	debug.DebugOutput(localDebug, "Found %d unmatched %s documents", stats.TotalDocuments, docType)
-	
-	if stats.TotalDocuments == 0 {
-		return stats, nil
-	}
-	
-	// Get all documents of this type
-	inputs, err := bp.getUnmatchedDocumentsByType(docType)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get documents: %w", err)
-	}
-	
-	// Process documents
-	var totalScore float64
-	for i, input := range inputs {
-		result, err := bp.engine.ProcessDocument(false, input)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error processing document %d: %v", input.DocumentID, err)
-			stats.ErrorCount++
-			continue
-		}
-		
-		// Save result
-		err = bp.engine.SaveMatchResult(false, result)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error saving result for document %d: %v", input.DocumentID, err)
-			stats.ErrorCount++
-			continue
-		}
-		
-		// Update statistics
-		stats.ProcessedCount++
-		if result.BestCandidate != nil {
-			totalScore += result.BestCandidate.Score
-		}
-		
-		switch result.Decision {
-		case "auto_accept":
-			stats.AutoAcceptCount++
-		case "needs_review", "low_confidence":
-			stats.NeedsReviewCount++
-		case "no_match":
-			stats.NoMatchCount++
-		}
-		
-		// Progress reporting
-		if (i+1)%100 == 0 {
-			debug.DebugOutput(localDebug, "Processed %d/%d %s documents", i+1, len(inputs), docType)
-		}
-	}
-	
-	// Calculate final statistics
-	stats.ProcessingTime = time.Since(startTime)
-	if stats.ProcessedCount > 0 {
-		stats.AverageScore = totalScore / float64(stats.ProcessedCount)
-	}
-	
-	return stats, nil
-}
+// getUnmatchedDocuments retrieves the next batch of unmatched documents, optionally of one type
+func (bp *BatchProcessor) getUnmatchedDocuments(ctx context.Context, docType string, afterID int64, limit int) ([]MatchInput, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
 
-// getUnmatchedDocuments retrieves a batch of unmatched documents
-func (bp *BatchProcessor) getUnmatchedDocuments(offset, limit int) ([]MatchInput, error) {
-	rows, err := bp.db.Query(`
+	rows, err := bp.db.QueryContext(ctx, `
 		SELECT 
 			s.document_id, s.raw_address, s.address_canonical,
 			s.raw_uprn, s.raw_easting, s.raw_northing
 		FROM src_document s
+		LEFT JOIN dim_document_type dt ON dt.doc_type_id = s.doc_type_id
 		LEFT JOIN address_match m ON m.document_id = s.document_id
 		WHERE m.document_id IS NULL
+		  AND s.removed_at IS NULL
 		  AND s.raw_address IS NOT NULL
 		  AND s.raw_address != ''
+		  AND ($1 = '' OR dt.type_code = $1)
+		  AND s.document_id > $2
 		ORDER BY s.document_id
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
-	
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	
-	var inputs []MatchInput
-	for rows.Next() {
-		var input MatchInput
-		var rawUPRN, rawEasting, rawNorthing sql.NullString
-		
-		err := rows.Scan(
-			&input.DocumentID, &input.RawAddress, &input.AddressCanonical,
-			&rawUPRN, &rawEasting, &rawNorthing,
-		)
-		if err != nil {
-			continue
-		}
-		
-		if rawUPRN.Valid && rawUPRN.String != "" {
-			input.RawUPRN = &rawUPRN.String
-		}
-		if rawEasting.Valid && rawEasting.String != "" {
-			input.RawEasting = &rawEasting.String
-		}
-		if rawNorthing.Valid && rawNorthing.String != "" {
-			input.RawNorthing = &rawNorthing.String
-		}
-		
-		inputs = append(inputs, input)
-	}
-	
-	return inputs, nil
-}
-
-// getUnmatchedDocumentsByType retrieves all unmatched documents of a specific type
-func (bp *BatchProcessor) getUnmatchedDocumentsByType(docType string) ([]MatchInput, error) {
-	rows, err := bp.db.Query(`
-		SELECT 
-			s.document_id, s.raw_address, s.address_canonical,
-			s.raw_uprn, s.raw_easting, s.raw_northing
-		FROM src_document s
-		INNER JOIN dim_document_type dt ON dt.doc_type_id = s.doc_type_id
-		LEFT JOIN address_match m ON m.document_id = s.document_id
-		WHERE dt.type_code = $1 
-		  AND m.document_id IS NULL
-		  AND s.raw_address IS NOT NULL
-		  AND s.raw_address != ''
-		ORDER BY s.document_id
-	`, docType)
+		LIMIT $3
+	`, docType, afterID, limit)
 	
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	
-	var inputs []MatchInput
-	for rows.Next() {
-		var input MatchInput
-		var rawUPRN, rawEasting, rawNorthing sql.NullString
-		
-		err := rows.Scan(
-			&input.DocumentID, &input.RawAddress, &input.AddressCanonical,
-			&rawUPRN, &rawEasting, &rawNorthing,
-		)
-		if err != nil {
-			continue
-		}
-		
-		if rawUPRN.Valid && rawUPRN.String != "" {
-			input.RawUPRN = &rawUPRN.String
-		}
-		if rawEasting.Valid && rawEasting.String != "" {
-			input.RawEasting = &rawEasting.String
-		}
-		if rawNorthing.Valid && rawNorthing.String != "" {
-			input.RawNorthing = &rawNorthing.String
-		}
-		
-		inputs = append(inputs, input)
-	}
-	
-	return inputs, nil
+	return scanMatchInputs(rows)
 }
 
 // GetMatchingStatistics returns overall matching statistics
-func (bp *BatchProcessor) GetMatchingStatistics(localDebug bool) (*MatchingStatistics, error) {
+func (bp *BatchProcessor) GetMatchingStatistics(ctx context.Context, localDebug bool) (*MatchingStatistics, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	stats := &MatchingStatistics{}
 	
 	// Total documents
-	err := bp.db.QueryRow("SELECT COUNT(*) FROM src_document").Scan(&stats.TotalDocuments)
+	err := bp.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM src_document WHERE removed_at IS NULL").Scan(&stats.TotalDocuments)
 	if err != nil {
 		return nil, err
 	}
 	
 	// Matched documents
-	err = bp.db.QueryRow("SELECT COUNT(*) FROM address_match").Scan(&stats.MatchedDocuments)
+	err = bp.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM address_match").Scan(&stats.MatchedDocuments)
 	if err != nil {
 		return nil, err
 	}
@@ -348,7 +188,7 @@ func (bp *BatchProcessor) GetMatchingStatistics(localDebug bool) (*MatchingStati
 	stats.UnmatchedDocuments = stats.TotalDocuments - stats.MatchedDocuments
 	
 	// Match status breakdown
-	rows, err := bp.db.Query(`
+	rows, err := bp.db.QueryContext(ctx, `
 		SELECT match_status, COUNT(*) 
 		FROM address_match 
 		GROUP BY match_status
@@ -370,7 +210,7 @@ func (bp *BatchProcessor) GetMatchingStatistics(localDebug bool) (*MatchingStati
 	}
 	
 	// Method breakdown
-	rows, err = bp.db.Query(`
+	rows, err = bp.db.QueryContext(ctx, `
 		SELECT dm.method_name, COUNT(*), AVG(am.confidence_score)
 		FROM address_match am
 		INNER JOIN dim_match_method dm ON dm.method_id = am.match_method_id
@@ -398,7 +238,7 @@ func (bp *BatchProcessor) GetMatchingStatistics(localDebug bool) (*MatchingStati
 	}
 	
 	// Average confidence score
-	err = bp.db.QueryRow("SELECT AVG(confidence_score) FROM address_match").Scan(&stats.AverageConfidence)
+	err = bp.db.QueryRowContext(ctx, "SELECT AVG(confidence_score) FROM address_match").Scan(&stats.AverageConfidence)
 	if err != nil {
 		stats.AverageConfidence = 0.0
 	}
@@ -422,10 +262,3 @@ type MethodStats struct {
 	AvgScore float64
 }
 
-// Helper function
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
\ No newline at end of file
@@ -1,17 +1,20 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"time"
 
 	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/store"
 )
 
-// HybridBatchProcessor handles high-performance batch processing with advanced analysis
+// HybridBatchProcessor handles high-performance batch processing with advanced analysis.
+// Runs are checkpointed and resumed like BatchProcessor's.
 type HybridBatchProcessor struct {
 	engine *HybridEngine
 	db     *sql.DB
+	stores store.Stores
 }
 
 // HybridBatchStats extends BatchStats with additional hybrid metrics
@@ -25,6 +28,8 @@ type HybridBatchStats struct {
 	TokenMatchCount         int
 	SpatialMatchCount       int
 	SemanticMatchCount      int
+	HybridBoostTotal        float64
+	HybridBoostCount        int
 	HybridBoostAverage      float64
 }
 
@@ -33,27 +38,62 @@ func NewHybridBatchProcessor(engine *HybridEngine, db *sql.DB) *HybridBatchProce
 	return &HybridBatchProcessor{
 		engine: engine,
 		db:     db,
+		stores: store.NewPostgresStores(db),
 	}
 }
 
 // ProcessAllDocuments processes all unmatched documents with hybrid analysis
-func (bp *HybridBatchProcessor) ProcessAllDocuments(localDebug bool, batchSize int) (*HybridBatchStats, error) {
+func (bp *HybridBatchProcessor) ProcessAllDocuments(ctx context.Context, localDebug bool, batchSize int) (*HybridBatchStats, error) {
+	return bp.start(ctx, localDebug, RunConfig{Processor: ProcessorHybrid, BatchSize: batchSize})
+}
+
+// ProcessDocumentsByType processes documents of a specific type with hybrid analysis
+func (bp *HybridBatchProcessor) ProcessDocumentsByType(ctx context.Context, localDebug bool, docType string, batchSize int) (*HybridBatchStats, error) {
+	return bp.start(ctx, localDebug, RunConfig{Processor: ProcessorHybrid, BatchSize: batchSize, DocType: docType})
+}
+
+// Resume continues a stopped hybrid run from its checkpoint
+func (bp *HybridBatchProcessor) Resume(ctx context.Context, localDebug bool, runID int64) (*HybridBatchStats, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
-	
-	startTime := time.Now()
+
 	stats := &HybridBatchStats{}
+	run, err := reopenBatchRun(ctx, bp.stores, runID, ProcessorHybrid, stats)
+	if err != nil {
+		return nil, err
+	}
+	run.resumed(&stats.BatchStats)
+
+	debug.DebugOutput(localDebug, "Resuming HYBRID run %d (leg %d) after document %d", runID, stats.Legs, run.cursor)
+
+	// Documents matched since the view was last refreshed must drop out of it
+	if _, err := bp.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW mv_unmatched_documents"); err != nil {
+		run.finish(localDebug, err, &stats.BatchStats, stats)
+		return stats, fmt.Errorf("failed to refresh unmatched documents view: %w", err)
+	}
+
+	return stats, bp.process(ctx, localDebug, run, stats)
+}
+
+// start refreshes the unmatched view, records a new run and processes it
+func (bp *HybridBatchProcessor) start(ctx context.Context, localDebug bool, cfg RunConfig) (*HybridBatchStats, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+	
+	stats := &HybridBatchStats{BatchStats: BatchStats{Legs: 1}}
 	
-	debug.DebugOutput(localDebug, "Starting HYBRID batch processing with batch size: %d", batchSize)
+	debug.DebugOutput(localDebug, "Starting HYBRID batch processing with batch size: %d", cfg.BatchSize)
 	
 	// Refresh materialized view for latest unmatched documents
-	_, err := bp.db.Exec("REFRESH MATERIALIZED VIEW mv_unmatched_documents")
+	_, err := bp.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW mv_unmatched_documents")
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh unmatched documents view: %w", err)
 	}
 	
 	// Get total count from materialized view
-	err = bp.db.QueryRow("SELECT COUNT(*) FROM mv_unmatched_documents").Scan(&stats.TotalDocuments)
+	err = bp.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM mv_unmatched_documents WHERE $1 = '' OR type_code = $1
+	`, cfg.DocType).Scan(&stats.TotalDocuments)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count documents: %w", err)
 	}
@@ -64,80 +104,32 @@ func (bp *HybridBatchProcessor) ProcessAllDocuments(localDebug bool, batchSize i
 		debug.DebugOutput(localDebug, "No documents to process")
 		return stats, nil
 	}
-	
-	// Process documents in batches
-	offset := 0
-	var totalScore float64
-	var totalHybridBoost float64
-	hybridBoostCount := 0
-	
-	for offset < stats.TotalDocuments {
-		debug.DebugOutput(localDebug, "Processing HYBRID batch: %d-%d of %d", 
-			offset+1, minInt(offset+batchSize, stats.TotalDocuments), stats.TotalDocuments)
-		
-		// Get batch from materialized view
-		inputs, err := bp.getHybridUnmatchedDocuments(offset, batchSize)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error getting hybrid batch: %v", err)
-			stats.ErrorCount++
-			offset += batchSize
-			continue
-		}
-		
-		// Process each document with hybrid analysis
-		for _, input := range inputs {
-			result, err := bp.engine.ProcessDocument(false, input) // Disable debug for batch
-			if err != nil {
-				debug.DebugOutput(localDebug, "Error processing document %d: %v", input.DocumentID, err)
-				stats.ErrorCount++
-				continue
-			}
-			
-			// Save result using hybrid engine
-			err = bp.engine.SaveMatchResult(false, result)
-			if err != nil {
-				debug.DebugOutput(localDebug, "Error saving result for document %d: %v", input.DocumentID, err)
-				stats.ErrorCount++
-				continue
-			}
-			
-			// Update standard statistics
-			stats.ProcessedCount++
+
+	run, err := startBatchRun(ctx, bp.stores, cfg)
+	if err != nil {
+		return nil, err
+	}
+	debug.DebugOutput(localDebug, "Started run %d", run.runID)
+
+	return stats, bp.process(ctx, localDebug, run, stats)
+}
+
+// process runs the batches with hybrid analysis and records how the run ended
+func (bp *HybridBatchProcessor) process(ctx context.Context, localDebug bool, run *batchRun, stats *HybridBatchStats) error {
+	err := run.process(ctx, localDebug, bp.engine, unmatchedFromView(bp.db), &stats.BatchStats, stats,
+		func(result *MatchResult) {
 			if result.BestCandidate != nil {
-				totalScore += result.BestCandidate.Score
-				
 				// Extract hybrid-specific metrics
-				bp.updateHybridStats(result, stats, &totalHybridBoost, &hybridBoostCount)
-			}
-			
-			switch result.Decision {
-			case "auto_accept":
-				stats.AutoAcceptCount++
-			case "needs_review", "low_confidence":
-				stats.NeedsReviewCount++
-			case "no_match":
-				stats.NoMatchCount++
+				bp.updateHybridStats(result, stats)
 			}
-			
-			// Progress reporting (less frequent for performance)
-			if stats.ProcessedCount%1000 == 0 {
-				debug.DebugOutput(localDebug, "HYBRID progress: %d/%d documents (%.1f%%) - %.1f docs/sec", 
-					stats.ProcessedCount, stats.TotalDocuments, 
-					float64(stats.ProcessedCount)/float64(stats.TotalDocuments)*100,
-					float64(stats.ProcessedCount)/time.Since(startTime).Seconds())
-			}
-		}
-		
-		offset += batchSize
-	}
-	
-	// Calculate final statistics
-	stats.ProcessingTime = time.Since(startTime)
-	if stats.ProcessedCount > 0 {
-		stats.AverageScore = totalScore / float64(stats.ProcessedCount)
+		})
+	if stats.HybridBoostCount > 0 {
+		stats.HybridBoostAverage = stats.HybridBoostTotal / float64(stats.HybridBoostCount)
 	}
-	if hybridBoostCount > 0 {
-		stats.HybridBoostAverage = totalHybridBoost / float64(hybridBoostCount)
+	run.finish(localDebug, err, &stats.BatchStats, stats)
+	if err != nil {
+		// Interrupted or failed: every document so far was saved whole; stats are partial
+		return err
 	}
 	
 	debug.DebugOutput(localDebug, "HYBRID batch processing complete:")
@@ -152,106 +144,11 @@ func (bp *HybridBatchProcessor) ProcessAllDocuments(localDebug bool, batchSize i
 		stats.TokenMatchCount, stats.SpatialMatchCount, stats.SemanticMatchCount)
 	debug.DebugOutput(localDebug, "  Average hybrid boost: %.4f", stats.HybridBoostAverage)
 	
-	return stats, nil
-}
-
-// ProcessDocumentsByType processes documents of a specific type with hybrid analysis
-func (bp *HybridBatchProcessor) ProcessDocumentsByType(localDebug bool, docType string, batchSize int) (*HybridBatchStats, error) {
-	debug.DebugHeader(localDebug)
-	defer debug.DebugFooter(localDebug)
-	
-	startTime := time.Now()
-	stats := &HybridBatchStats{}
-	
-	debug.DebugOutput(localDebug, "Processing HYBRID documents of type: %s", docType)
-	
-	// Refresh materialized view first
-	_, err := bp.db.Exec("REFRESH MATERIALIZED VIEW mv_unmatched_documents")
-	if err != nil {
-		return nil, fmt.Errorf("failed to refresh unmatched documents view: %w", err)
-	}
-	
-	// Get total count of unmatched documents of this type
-	err = bp.db.QueryRow(`
-		SELECT COUNT(*) FROM mv_unmatched_documents WHERE type_code = $1
-	`, docType).Scan(&stats.TotalDocuments)
-	
-	if err != nil {
-		return nil, fmt.Errorf("failed to count documents: %w", err)
-	}
-	
-	debug.DebugOutput(localDebug, "Found %d unmatched %s documents for hybrid processing", stats.TotalDocuments, docType)
-	
-	if stats.TotalDocuments == 0 {
-		return stats, nil
-	}
-	
-	// Get all documents of this type from materialized view
-	inputs, err := bp.getHybridUnmatchedDocumentsByType(docType)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get documents: %w", err)
-	}
-	
-	// Process documents with hybrid analysis
-	var totalScore float64
-	var totalHybridBoost float64
-	hybridBoostCount := 0
-	
-	for i, input := range inputs {
-		result, err := bp.engine.ProcessDocument(false, input)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error processing document %d: %v", input.DocumentID, err)
-			stats.ErrorCount++
-			continue
-		}
-		
-		// Save result
-		err = bp.engine.SaveMatchResult(false, result)
-		if err != nil {
-			debug.DebugOutput(localDebug, "Error saving result for document %d: %v", input.DocumentID, err)
-			stats.ErrorCount++
-			continue
-		}
-		
-		// Update statistics
-		stats.ProcessedCount++
-		if result.BestCandidate != nil {
-			totalScore += result.BestCandidate.Score
-			
-			// Extract hybrid-specific metrics
-			bp.updateHybridStats(result, stats, &totalHybridBoost, &hybridBoostCount)
-		}
-		
-		switch result.Decision {
-		case "auto_accept":
-			stats.AutoAcceptCount++
-		case "needs_review", "low_confidence":
-			stats.NeedsReviewCount++
-		case "no_match":
-			stats.NoMatchCount++
-		}
-		
-		// Progress reporting
-		if (i+1)%200 == 0 {
-			debug.DebugOutput(localDebug, "HYBRID processed %d/%d %s documents (%.1f docs/sec)", 
-				i+1, len(inputs), docType, float64(i+1)/time.Since(startTime).Seconds())
-		}
-	}
-	
-	// Calculate final statistics
-	stats.ProcessingTime = time.Since(startTime)
-	if stats.ProcessedCount > 0 {
-		stats.AverageScore = totalScore / float64(stats.ProcessedCount)
-	}
-	if hybridBoostCount > 0 {
-		stats.HybridBoostAverage = totalHybridBoost / float64(hybridBoostCount)
-	}
-	
-	return stats, nil
+	return nil
 }
 
 // updateHybridStats extracts and updates hybrid-specific statistics
-func (bp *HybridBatchProcessor) updateHybridStats(result *MatchResult, stats *HybridBatchStats, totalHybridBoost *float64, hybridBoostCount *int) {
+func (bp *HybridBatchProcessor) updateHybridStats(result *MatchResult, stats *HybridBatchStats) {
 	if result.BestCandidate == nil || result.BestCandidate.Features == nil {
 		return
 	}
@@ -291,99 +188,9 @@ func (bp *HybridBatchProcessor) updateHybridStats(result *MatchResult, stats *Hy
 		if prefilterScore, ok := result.BestCandidate.Features["prefilter_score"].(float64); ok {
 			boost := hybridScore - prefilterScore
 			if boost > 0 {
-				*totalHybridBoost += boost
-				*hybridBoostCount++
+				stats.HybridBoostTotal += boost
+				stats.HybridBoostCount++
 			}
 		}
 	}
 }
-
-// getHybridUnmatchedDocuments retrieves a batch from the materialized view
-func (bp *HybridBatchProcessor) getHybridUnmatchedDocuments(offset, limit int) ([]MatchInput, error) {
-	rows, err := bp.db.Query(`
-		SELECT 
-			document_id, raw_address, address_canonical,
-			raw_uprn, raw_easting, raw_northing
-		FROM mv_unmatched_documents
-		ORDER BY document_id
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
-	
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	
-	var inputs []MatchInput
-	for rows.Next() {
-		var input MatchInput
-		var rawUPRN, rawEasting, rawNorthing sql.NullString
-		
-		err := rows.Scan(
-			&input.DocumentID, &input.RawAddress, &input.AddressCanonical,
-			&rawUPRN, &rawEasting, &rawNorthing,
-		)
-		if err != nil {
-			continue
-		}
-		
-		if rawUPRN.Valid && rawUPRN.String != "" {
-			input.RawUPRN = &rawUPRN.String
-		}
-		if rawEasting.Valid && rawEasting.String != "" {
-			input.RawEasting = &rawEasting.String
-		}
-		if rawNorthing.Valid && rawNorthing.String != "" {
-			input.RawNorthing = &rawNorthing.String
-		}
-		
-		inputs = append(inputs, input)
-	}
-	
-	return inputs, nil
-}
-
-// getHybridUnmatchedDocumentsByType retrieves all unmatched documents of a specific type
-func (bp *HybridBatchProcessor) getHybridUnmatchedDocumentsByType(docType string) ([]MatchInput, error) {
-	rows, err := bp.db.Query(`
-		SELECT 
-			document_id, raw_address, address_canonical,
-			raw_uprn, raw_easting, raw_northing
-		FROM mv_unmatched_documents
-		WHERE type_code = $1
-		ORDER BY document_id
-	`, docType)
-	
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	
-	var inputs []MatchInput
-	for rows.Next() {
-		var input MatchInput
-		var rawUPRN, rawEasting, rawNorthing sql.NullString
-		
-		err := rows.Scan(
-			&input.DocumentID, &input.RawAddress, &input.AddressCanonical,
-			&rawUPRN, &rawEasting, &rawNorthing,
-		)
-		if err != nil {
-			continue
-		}
-		
-		if rawUPRN.Valid && rawUPRN.String != "" {
-			input.RawUPRN = &rawUPRN.String
-		}
-		if rawEasting.Valid && rawEasting.String != "" {
-			input.RawEasting = &rawEasting.String
-		}
-		if rawNorthing.Valid && rawNorthing.String != "" {
-			input.RawNorthing = &rawNorthing.String
-		}
-		
-		inputs = append(inputs, input)
-	}
-	
-	return inputs, nil
-}
\ No newline at end of file
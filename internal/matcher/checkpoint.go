@@ -0,0 +1,288 @@
+package matcher
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/interrupt"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// Batch processors recorded in a run's checkpoint; resume rebuilds the one that started it
+const (
+	ProcessorStandard  = "standard"
+	ProcessorOptimized = "optimized"
+	ProcessorHybrid    = "hybrid"
+)
+
+// RunConfig is the configuration a checkpointed batch run was started with. Resuming
+// reuses it, so the second leg matches the same documents the same way.
+type RunConfig struct {
+	Processor string `json:"processor"`
+	BatchSize int    `json:"batch_size"`
+	DocType   string `json:"doc_type,omitempty"` // empty for every document type
+}
+
+// documentMatcher is the part of an engine a batch run drives
+type documentMatcher interface {
+	ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error)
+	SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error
+}
+
+// fetchFunc returns up to limit unmatched documents with IDs above afterID, in ID order
+type fetchFunc func(ctx context.Context, docType string, afterID int64, limit int) ([]MatchInput, error)
+
+// batchRun is a checkpointed run in progress: its match_run row, configuration and the
+// cursor below which every document has been attempted
+type batchRun struct {
+	stores    store.Stores
+	runID     int64
+	config    RunConfig
+	cursor    int64
+	priorTime time.Duration // processing time of earlier legs
+	started   time.Time
+}
+
+// startBatchRun records a new run and its checkpoint
+func startBatchRun(ctx context.Context, stores store.Stores, cfg RunConfig) (*batchRun, error) {
+	label := "batch-" + cfg.Processor
+	if cfg.DocType != "" {
+		label += "-" + cfg.DocType
+	}
+
+	run, err := stores.Matches.CreateRun(ctx, label, "matcher/"+cfg.Processor,
+		fmt.Sprintf("Checkpointed batch run, batch size %d", cfg.BatchSize))
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode run config: %w", err)
+	}
+	if err := stores.Checkpoints.CreateCheckpoint(ctx, run.RunID, cfg.Processor, config); err != nil {
+		return nil, err
+	}
+
+	return &batchRun{stores: stores, runID: run.RunID, config: cfg, started: time.Now()}, nil
+}
+
+// reopenBatchRun reopens a stopped run started by processor and restores the statistics
+// its earlier legs accumulated into stats
+func reopenBatchRun(ctx context.Context, stores store.Stores, runID int64, processor string, stats interface{}) (*batchRun, error) {
+	cp, err := stores.Checkpoints.Checkpoint(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if cp != nil && cp.Processor != processor {
+		return nil, fmt.Errorf("run %d was started by the %s processor, not %s", runID, cp.Processor, processor)
+	}
+
+	cp, err = stores.Checkpoints.ReopenRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &batchRun{stores: stores, runID: runID, cursor: cp.LastDocumentID, started: time.Now()}
+	if err := json.Unmarshal(cp.Config, &run.config); err != nil {
+		return nil, fmt.Errorf("failed to decode config of run %d: %w", runID, err)
+	}
+	if err := json.Unmarshal(cp.Stats, stats); err != nil {
+		return nil, fmt.Errorf("failed to decode statistics of run %d: %w", runID, err)
+	}
+	return run, nil
+}
+
+// process matches the documents after the cursor, a batch at a time, checkpointing after
+// each batch. A document is saved whole before the cursor moves past it, so an interrupted
+// run loses nothing; after a crash at most one batch is repeated, and its matched
+// documents are no longer selected. all is the processor's full statistics, which may
+// embed stats; onResult updates any processor-specific parts of it.
+func (r *batchRun) process(ctx context.Context, localDebug bool, m documentMatcher, fetch fetchFunc, stats *BatchStats, all interface{}, onResult func(*MatchResult)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		inputs, err := fetch(ctx, r.config.DocType, r.cursor, r.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get documents after %d: %w", r.cursor, err)
+		}
+		if len(inputs) == 0 {
+			return nil
+		}
+
+		debug.DebugOutput(localDebug, "Processing batch of %d documents after document %d (run %d)",
+			len(inputs), r.cursor, r.runID)
+
+		for _, input := range inputs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			result, err := m.ProcessDocument(ctx, false, input) // Disable debug for batch processing
+			if err == nil {
+				err = m.SaveMatchResult(ctx, false, result)
+			}
+			if err != nil && ctx.Err() != nil {
+				// Cancelled mid-document: leave it before the cursor for the next leg
+				return ctx.Err()
+			}
+
+			if err != nil {
+				debug.DebugOutput(localDebug, "Error matching document %d: %v", input.DocumentID, err)
+				stats.ErrorCount++
+			} else {
+				stats.ProcessedCount++
+				if result.BestCandidate != nil {
+					stats.ScoreTotal += result.BestCandidate.Score
+				}
+
+				switch result.Decision {
+				case "auto_accept":
+					stats.AutoAcceptCount++
+				case "needs_review", "low_confidence":
+					stats.NeedsReviewCount++
+				case "no_match":
+					stats.NoMatchCount++
+				}
+
+				if onResult != nil {
+					onResult(result)
+				}
+			}
+			r.cursor = input.DocumentID
+
+			// Progress reporting
+			if done := stats.ProcessedCount + stats.ErrorCount; done%500 == 0 {
+				debug.DebugOutput(localDebug, "Progress: %d/%d documents (%.1f%%)",
+					done, stats.TotalDocuments, float64(done)/float64(stats.TotalDocuments)*100)
+			}
+		}
+
+		if err := r.save(ctx, stats, all); err != nil {
+			return err
+		}
+	}
+}
+
+// save checkpoints the cursor and statistics
+func (r *batchRun) save(ctx context.Context, stats *BatchStats, all interface{}) error {
+	r.settle(stats)
+
+	data, err := json.Marshal(all)
+	if err != nil {
+		return fmt.Errorf("failed to encode run statistics: %w", err)
+	}
+	return r.stores.Checkpoints.SaveCheckpoint(ctx, r.runID, r.cursor, data)
+}
+
+// finish records the last checkpoint and how the run ended. runErr decides the status:
+// cancellation is interrupted, any other error failed. Both can be resumed.
+func (r *batchRun) finish(localDebug bool, runErr error, stats *BatchStats, all interface{}) {
+	ctx, cancel := interrupt.Detached()
+	defer cancel()
+
+	if err := r.save(ctx, stats, all); err != nil {
+		debug.DebugOutput(localDebug, "Warning: failed to checkpoint run %d: %v", r.runID, err)
+	}
+
+	status := store.RunCompleted
+	switch {
+	case errors.Is(runErr, context.Canceled):
+		status = store.RunInterrupted
+	case runErr != nil:
+		status = store.RunFailed
+	}
+
+	err := r.stores.Matches.CompleteRun(ctx, r.runID, status, store.RunTotals{
+		Processed:   stats.ProcessedCount,
+		Accepted:    stats.AutoAcceptCount,
+		NeedsReview: stats.NeedsReviewCount,
+		Rejected:    stats.NoMatchCount,
+	})
+	if err != nil {
+		debug.DebugOutput(localDebug, "Warning: failed to record run %d as %s: %v", r.runID, status, err)
+	}
+}
+
+// settle fills in the run's identity and the totals derived from the counters
+func (r *batchRun) settle(stats *BatchStats) {
+	stats.RunID = r.runID
+	stats.ProcessingTime = r.priorTime + time.Since(r.started)
+	if stats.ProcessedCount > 0 {
+		stats.AverageScore = stats.ScoreTotal / float64(stats.ProcessedCount)
+	}
+}
+
+// resumed records the processing time of earlier legs and counts this one
+func (r *batchRun) resumed(stats *BatchStats) {
+	r.priorTime = stats.ProcessingTime
+	stats.Legs++
+}
+
+// ResumeRun continues a checkpointed batch run with the processor that started it. The
+// statistics combine every leg of the run.
+func ResumeRun(ctx context.Context, localDebug bool, db *sql.DB, runID int64) (*BatchStats, error) {
+	cp, err := store.NewPostgres(db).Checkpoint(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		return nil, fmt.Errorf("run %d has no checkpoint; only runs started by a resumable processor can be resumed", runID)
+	}
+
+	switch cp.Processor {
+	case ProcessorStandard:
+		return NewBatchProcessor(NewEngine(db, nil, nil), db).Resume(ctx, localDebug, runID)
+	case ProcessorOptimized:
+		return NewOptimizedBatchProcessor(NewOptimizedEngine(db), db).Resume(ctx, localDebug, runID)
+	case ProcessorHybrid:
+		stats, err := NewHybridBatchProcessor(NewHybridEngine(db, nil, nil), db).Resume(ctx, localDebug, runID)
+		if stats == nil {
+			return nil, err
+		}
+		return &stats.BatchStats, err
+	default:
+		return nil, fmt.Errorf("run %d was started by %s, which is resumed by the command that runs it", runID, cp.Processor)
+	}
+}
+
+// scanMatchInputs reads document_id, raw_address, address_canonical, raw_uprn,
+// raw_easting and raw_northing rows
+func scanMatchInputs(rows *sql.Rows) ([]MatchInput, error) {
+	defer rows.Close()
+
+	var inputs []MatchInput
+	for rows.Next() {
+		var input MatchInput
+		var rawUPRN, rawEasting, rawNorthing sql.NullString
+
+		err := rows.Scan(
+			&input.DocumentID, &input.RawAddress, &input.AddressCanonical,
+			&rawUPRN, &rawEasting, &rawNorthing,
+		)
+		if err != nil {
+			continue
+		}
+
+		if rawUPRN.Valid && rawUPRN.String != "" {
+			input.RawUPRN = &rawUPRN.String
+		}
+		if rawEasting.Valid && rawEasting.String != "" {
+			input.RawEasting = &rawEasting.String
+		}
+		if rawNorthing.Valid && rawNorthing.String != "" {
+			input.RawNorthing = &rawNorthing.String
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	return inputs, rows.Err()
+}
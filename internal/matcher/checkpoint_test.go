@@ -0,0 +1,112 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// countingMatcher accepts every document and cancels after stopAfter of them
+type countingMatcher struct {
+	seen      []int64
+	stopAfter int
+	cancel    context.CancelFunc
+}
+
+func (m *countingMatcher) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
+	return &MatchResult{Decision: "auto_accept", BestCandidate: &MatchCandidate{Score: 0.9}}, nil
+}
+
+func (m *countingMatcher) SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error {
+	return nil
+}
+
+// fetchIDs serves documents 1..n as a fetchFunc and records what was handed out
+func fetchIDs(n int64, m *countingMatcher) fetchFunc {
+	return func(ctx context.Context, docType string, afterID int64, limit int) ([]MatchInput, error) {
+		var inputs []MatchInput
+		for id := afterID + 1; id <= n && len(inputs) < limit; id++ {
+			inputs = append(inputs, MatchInput{DocumentID: id})
+			m.seen = append(m.seen, id)
+			if m.cancel != nil && len(m.seen) == m.stopAfter {
+				m.cancel()
+			}
+		}
+		return inputs, nil
+	}
+}
+
+func TestBatchRunResumesWhereItStopped(t *testing.T) {
+	stores := store.NewMemory().Stores()
+	cfg := RunConfig{Processor: ProcessorStandard, BatchSize: 3}
+
+	// First leg: cancelled while the second batch (documents 4-6) is fetched
+	ctx, cancel := context.WithCancel(context.Background())
+	first := &countingMatcher{stopAfter: 6, cancel: cancel}
+	run, err := startBatchRun(ctx, stores, cfg)
+	if err != nil {
+		t.Fatalf("startBatchRun: %v", err)
+	}
+	stats := &BatchStats{Legs: 1, TotalDocuments: 10}
+	err = run.process(ctx, false, first, fetchIDs(10, first), stats, stats, nil)
+	run.finish(false, err, stats, stats)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("first leg error = %v, want context.Canceled", err)
+	}
+	if stats.ProcessedCount != 3 {
+		t.Fatalf("first leg processed %d, want the 3 documents before cancellation", stats.ProcessedCount)
+	}
+
+	cp, _ := stores.Checkpoints.Checkpoint(context.Background(), run.runID)
+	if cp.Status != store.RunInterrupted || cp.LastDocumentID != 3 {
+		t.Fatalf("checkpoint = %+v, want interrupted at document 3", cp)
+	}
+
+	// Second leg continues after document 3 with the saved configuration
+	second := &countingMatcher{}
+	resumed := &BatchStats{}
+	run, err = reopenBatchRun(context.Background(), stores, cp.RunID, ProcessorStandard, resumed)
+	if err != nil {
+		t.Fatalf("reopenBatchRun: %v", err)
+	}
+	run.resumed(resumed)
+	err = run.process(context.Background(), false, second, fetchIDs(10, second), resumed, resumed, nil)
+	run.finish(false, err, resumed, resumed)
+	if err != nil {
+		t.Fatalf("second leg: %v", err)
+	}
+
+	if second.seen[0] != 4 || run.config.BatchSize != 3 {
+		t.Errorf("second leg started at document %d with batch size %d, want 4 and 3", second.seen[0], run.config.BatchSize)
+	}
+	if resumed.ProcessedCount != 10 || resumed.AutoAcceptCount != 10 || resumed.Legs != 2 || resumed.RunID != cp.RunID {
+		t.Errorf("combined stats = %+v, want 10 processed over 2 legs of run %d", resumed, cp.RunID)
+	}
+
+	cp, _ = stores.Checkpoints.Checkpoint(context.Background(), run.runID)
+	if cp.Status != store.RunCompleted {
+		t.Errorf("run status = %q, want completed", cp.Status)
+	}
+	if _, err := reopenBatchRun(context.Background(), stores, cp.RunID, ProcessorStandard, &BatchStats{}); err == nil {
+		t.Error("a completed run was reopened")
+	}
+}
+
+func TestReopenBatchRunRejectsOtherProcessor(t *testing.T) {
+	stores := store.NewMemory().Stores()
+	run, err := startBatchRun(context.Background(), stores, RunConfig{Processor: ProcessorHybrid, BatchSize: 10})
+	if err != nil {
+		t.Fatalf("startBatchRun: %v", err)
+	}
+	run.finish(false, errors.New("database restarted"), &BatchStats{}, &BatchStats{})
+
+	if _, err := reopenBatchRun(context.Background(), stores, run.runID, ProcessorStandard, &BatchStats{}); err == nil {
+		t.Error("a hybrid run was reopened by the standard processor")
+	}
+	cp, _ := stores.Checkpoints.Checkpoint(context.Background(), run.runID)
+	if cp.Status != store.RunFailed || cp.Legs != 1 {
+		t.Errorf("checkpoint = %+v, want the failed run left untouched", cp)
+	}
+}
@@ -1,12 +1,15 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/match"
 	"github.com/ehdc-llpg/internal/normalize"
 )
 
@@ -86,7 +89,7 @@ func NewEngine(db *sql.DB, embedder Embedder, vectorDB VectorDB) *Engine {
 }
 
 // ProcessDocument performs address matching for a single source document
-func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResult, error) {
+func (e *Engine) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 	
@@ -95,10 +98,15 @@ func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResul
 	debug.DebugOutput(localDebug, "Processing document %d: %s", input.DocumentID, input.RawAddress)
 	
 	// Load match methods
-	methods, err := e.loadMatchMethods()
+	methods, err := e.loadMatchMethods(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load match methods: %w", err)
 	}
+
+	rejections, err := match.LoadRejections(ctx, e.db, input.DocumentID, input.AddressCanonical)
+	if err != nil {
+		return nil, err
+	}
 	
 	var allCandidates []MatchCandidate
 	
@@ -107,7 +115,7 @@ func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResul
 	
 	// A1: Legacy UPRN validation
 	if input.RawUPRN != nil && *input.RawUPRN != "" {
-		candidates, err := e.exactUPRNMatch(localDebug, *input.RawUPRN, methods["exact_uprn"])
+		candidates, err := e.exactUPRNMatch(ctx, localDebug, *input.RawUPRN, methods["exact_uprn"])
 		if err == nil {
 			allCandidates = append(allCandidates, candidates...)
 			debug.DebugOutput(localDebug, "Found %d exact UPRN matches", len(candidates))
@@ -115,12 +123,14 @@ func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResul
 	}
 	
 	// A2: Exact canonical address match
-	candidates, err := e.exactTextMatch(localDebug, input.AddressCanonical, methods["exact_text"])
+	candidates, err := e.exactTextMatch(ctx, localDebug, input.AddressCanonical, methods["exact_text"])
 	if err == nil {
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Found %d exact text matches", len(candidates))
 	}
 	
+	allCandidates = withoutRejected(localDebug, rejections, allCandidates)
+
 	// If we have high-confidence deterministic matches, use them
 	if len(allCandidates) > 0 && allCandidates[0].Score >= 0.95 {
 		result := &MatchResult{
@@ -139,21 +149,21 @@ func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResul
 	debug.DebugOutput(localDebug, "=== Tier B: Fuzzy Text Matching ===")
 	
 	// B1: High confidence fuzzy matching
-	candidates, err = e.fuzzyTextMatch(localDebug, input.AddressCanonical, 0.90, 20, methods["fuzzy_high"])
+	candidates, err = e.fuzzyTextMatch(ctx, localDebug, input.AddressCanonical, 0.90, 20, methods["fuzzy_high"])
 	if err == nil {
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Found %d high-confidence fuzzy matches", len(candidates))
 	}
 	
 	// B2: Medium confidence fuzzy matching
-	candidates, err = e.fuzzyTextMatch(localDebug, input.AddressCanonical, 0.80, 50, methods["fuzzy_medium"])
+	candidates, err = e.fuzzyTextMatch(ctx, localDebug, input.AddressCanonical, 0.80, 50, methods["fuzzy_medium"])
 	if err == nil {
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Found %d medium-confidence fuzzy matches", len(candidates))
 	}
 	
 	// B3: Low confidence fuzzy matching
-	candidates, err = e.fuzzyTextMatch(localDebug, input.AddressCanonical, 0.70, 100, methods["fuzzy_low"])
+	candidates, err = e.fuzzyTextMatch(ctx, localDebug, input.AddressCanonical, 0.70, 100, methods["fuzzy_low"])
 	if err == nil {
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Found %d low-confidence fuzzy matches", len(candidates))
@@ -162,7 +172,7 @@ func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResul
 	// Tier C: Vector Semantic Matching
 	debug.DebugOutput(localDebug, "=== Tier C: Vector Semantic Matching ===")
 	if e.embedder != nil && e.vectorDB != nil {
-		candidates, err = e.vectorSemanticMatch(localDebug, input.AddressCanonical, 50, methods["spatial"])
+		candidates, err = e.vectorSemanticMatch(ctx, localDebug, input.AddressCanonical, 50, methods["spatial"])
 		if err == nil {
 			allCandidates = append(allCandidates, candidates...)
 			debug.DebugOutput(localDebug, "Found %d vector semantic matches", len(candidates))
@@ -174,11 +184,11 @@ func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResul
 	// Tier D: Spatial Filtering
 	debug.DebugOutput(localDebug, "=== Tier D: Spatial Filtering ===")
 	if input.RawEasting != nil && input.RawNorthing != nil {
-		allCandidates = e.applySpatialFilter(localDebug, allCandidates, input.RawEasting, input.RawNorthing, 2000.0)
+		allCandidates = e.applySpatialFilter(ctx, localDebug, allCandidates, input.RawEasting, input.RawNorthing, 2000.0)
 	}
 	
 	// Deduplicate and rank candidates
-	allCandidates = e.deduplicateAndRank(allCandidates)
+	allCandidates = e.deduplicateAndRank(withoutRejected(localDebug, rejections, allCandidates))
 	
 	// Make final decision
 	decision, matchStatus := e.makeMatchingDecision(allCandidates, methods)
@@ -202,10 +212,13 @@ func (e *Engine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResul
 }
 
 // loadMatchMethods loads match methods from dim_match_method table
-func (e *Engine) loadMatchMethods() (map[string]MatchMethod, error) {
+func (e *Engine) loadMatchMethods(ctx context.Context) (map[string]MatchMethod, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	methods := make(map[string]MatchMethod)
 	
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT method_id, method_code, method_name, confidence_threshold
 		FROM dim_match_method
 		ORDER BY method_id
@@ -228,13 +241,16 @@ func (e *Engine) loadMatchMethods() (map[string]MatchMethod, error) {
 }
 
 // exactUPRNMatch performs exact UPRN matching
-func (e *Engine) exactUPRNMatch(localDebug bool, uprn string, method MatchMethod) ([]MatchCandidate, error) {
+func (e *Engine) exactUPRNMatch(ctx context.Context, localDebug bool, uprn string, method MatchMethod) ([]MatchCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	trimmedUPRN := strings.TrimSpace(uprn)
 	if trimmedUPRN == "" {
 		return []MatchCandidate{}, nil
 	}
 	
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 			l.easting, l.northing
@@ -274,12 +290,15 @@ func (e *Engine) exactUPRNMatch(localDebug bool, uprn string, method MatchMethod
 }
 
 // exactTextMatch performs exact canonical text matching
-func (e *Engine) exactTextMatch(localDebug bool, canonical string, method MatchMethod) ([]MatchCandidate, error) {
+func (e *Engine) exactTextMatch(ctx context.Context, localDebug bool, canonical string, method MatchMethod) ([]MatchCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if canonical == "" {
 		return []MatchCandidate{}, nil
 	}
 	
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 			l.easting, l.northing
@@ -319,12 +338,15 @@ func (e *Engine) exactTextMatch(localDebug bool, canonical string, method MatchM
 }
 
 // fuzzyTextMatch performs fuzzy text matching using trigrams
-func (e *Engine) fuzzyTextMatch(localDebug bool, canonical string, threshold float64, limit int, method MatchMethod) ([]MatchCandidate, error) {
+func (e *Engine) fuzzyTextMatch(ctx context.Context, localDebug bool, canonical string, threshold float64, limit int, method MatchMethod) ([]MatchCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if canonical == "" {
 		return []MatchCandidate{}, nil
 	}
 	
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 			l.easting, l.northing, similarity($1, a.address_canonical) AS fuzzy_score
@@ -369,7 +391,10 @@ func (e *Engine) fuzzyTextMatch(localDebug bool, canonical string, threshold flo
 }
 
 // vectorSemanticMatch performs semantic matching using embeddings
-func (e *Engine) vectorSemanticMatch(localDebug bool, canonical string, limit int, method MatchMethod) ([]MatchCandidate, error) {
+func (e *Engine) vectorSemanticMatch(ctx context.Context, localDebug bool, canonical string, limit int, method MatchMethod) ([]MatchCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if e.embedder == nil || e.vectorDB == nil {
 		return []MatchCandidate{}, nil
 	}
@@ -390,7 +415,7 @@ func (e *Engine) vectorSemanticMatch(localDebug bool, canonical string, limit in
 	for _, vr := range vectorResults {
 		// Look up full address details
 		var candidate MatchCandidate
-		err := e.db.QueryRow(`
+		err := e.db.QueryRowContext(ctx, `
 			SELECT 
 				a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 				l.easting, l.northing
@@ -422,7 +447,7 @@ func (e *Engine) vectorSemanticMatch(localDebug bool, canonical string, limit in
 }
 
 // applySpatialFilter filters candidates by spatial proximity
-func (e *Engine) applySpatialFilter(localDebug bool, candidates []MatchCandidate, eastingStr, northingStr *string, radiusMeters float64) []MatchCandidate {
+func (e *Engine) applySpatialFilter(ctx context.Context, localDebug bool, candidates []MatchCandidate, eastingStr, northingStr *string, radiusMeters float64) []MatchCandidate {
 	if eastingStr == nil || northingStr == nil || *eastingStr == "" || *northingStr == "" {
 		return candidates
 	}
@@ -487,6 +512,25 @@ func (e *Engine) deduplicateAndRank(candidates []MatchCandidate) []MatchCandidat
 	return deduped
 }
 
+// withoutRejected drops the candidates whose UPRN a reviewer has rejected for the document or
+// its canonical address, keeping the order of the rest. Engines drop them before choosing the
+// best candidate, so the decision they report is the one saved; the address_match trigger of
+// migration 057 is only a backstop.
+func withoutRejected(localDebug bool, rejections match.Rejections, candidates []MatchCandidate) []MatchCandidate {
+	if len(rejections) == 0 {
+		return candidates
+	}
+	kept := make([]MatchCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if r, rejected := rejections[c.UPRN]; rejected {
+			debug.DebugOutput(localDebug, "Dropped UPRN %s: rejected by %s (rejection %d)", c.UPRN, r.RejectedBy, r.RejectionID)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
 // makeMatchingDecision determines the final matching decision
 func (e *Engine) makeMatchingDecision(candidates []MatchCandidate, methods map[string]MatchMethod) (string, string) {
 	if len(candidates) == 0 {
@@ -526,13 +570,16 @@ func calculateSpatialBoost(distance float64) float64 {
 }
 
 // SaveMatchResult saves the matching result to the database
-func (e *Engine) SaveMatchResult(localDebug bool, result *MatchResult) error {
+func (e *Engine) SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if result.BestCandidate == nil {
 		debug.DebugOutput(localDebug, "No match to save for document %d", result.DocumentID)
 		return nil
 	}
 	
-	_, err := e.db.Exec(`
+	_, err := e.db.ExecContext(ctx, `
 		INSERT INTO address_match (
 			document_id, address_id, location_id, match_method_id,
 			confidence_score, match_status, matched_by, matched_at
@@ -1,13 +1,16 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"math"
 	"strings"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/match"
 	"github.com/ehdc-llpg/internal/normalize"
 )
 
@@ -65,7 +68,7 @@ func NewAccurateEngine(db *sql.DB, embedder Embedder, vectorDB VectorDB) *Accura
 }
 
 // ProcessDocument performs accuracy-optimized address matching
-func (e *AccurateEngine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResult, error) {
+func (e *AccurateEngine) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 	
@@ -78,9 +81,14 @@ func (e *AccurateEngine) ProcessDocument(localDebug bool, input MatchInput) (*Ma
 	debug.DebugOutput(localDebug, "Parsed input: house='%s', street='%s', locality='%s', town='%s', postcode='%s'",
 		inputParsed.HouseNumber, inputParsed.StreetName, inputParsed.Locality, inputParsed.Town, inputParsed.Postcode)
 	
+	rejections, err := match.LoadRejections(ctx, e.db, input.DocumentID, input.AddressCanonical)
+	if err != nil {
+		return nil, err
+	}
+
 	// Strategy 1: Exact UPRN matching (if available)
 	if input.RawUPRN != nil && *input.RawUPRN != "" {
-		candidates := e.exactUPRNMatch(localDebug, *input.RawUPRN)
+		candidates := withoutRejected(localDebug, rejections, e.exactUPRNMatch(ctx, localDebug, *input.RawUPRN))
 		if len(candidates) > 0 {
 			return e.createResult(input.DocumentID, candidates, "auto_accept", "auto", time.Since(startTime)), nil
 		}
@@ -91,39 +99,45 @@ func (e *AccurateEngine) ProcessDocument(localDebug bool, input MatchInput) (*Ma
 	
 	// Pass 1: Try postcode + house number (very reliable)
 	if inputParsed.Postcode != "" && inputParsed.HouseNumber != "" {
-		candidates := e.postcodeHouseNumberMatch(localDebug, inputParsed)
+		candidates := e.postcodeHouseNumberMatch(ctx, localDebug, inputParsed)
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Postcode+HouseNumber match found %d candidates", len(candidates))
 	}
 	
 	// Pass 2: Try street name + house number + locality
 	if inputParsed.StreetName != "" {
-		candidates := e.streetLocalityMatch(localDebug, inputParsed)
+		candidates := e.streetLocalityMatch(ctx, localDebug, inputParsed)
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Street+Locality match found %d candidates", len(candidates))
 	}
 	
 	// Pass 3: Try fuzzy matching with component weighting
-	candidates := e.componentFuzzyMatch(localDebug, inputParsed, input.AddressCanonical)
+	candidates := e.componentFuzzyMatch(ctx, localDebug, inputParsed, input.AddressCanonical)
 	allCandidates = append(allCandidates, candidates...)
 	debug.DebugOutput(localDebug, "Component fuzzy match found %d candidates", len(candidates))
 	
 	// Pass 4: Try business/landmark name matching
 	if inputParsed.BusinessName != "" || inputParsed.HouseName != "" {
-		candidates := e.landmarkMatch(localDebug, inputParsed)
+		candidates := e.landmarkMatch(ctx, localDebug, inputParsed)
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Landmark match found %d candidates", len(candidates))
 	}
 	
 	// Pass 5: Handle special cases (Land at, Rear of, etc.)
 	if inputParsed.LandReference != "" {
-		candidates := e.landReferenceMatch(localDebug, inputParsed)
+		candidates := e.landReferenceMatch(ctx, localDebug, inputParsed)
 		allCandidates = append(allCandidates, candidates...)
 		debug.DebugOutput(localDebug, "Land reference match found %d candidates", len(candidates))
 	}
 	
-	// Deduplicate and rank candidates
-	finalCandidates := e.deduplicateAndRankAccurate(allCandidates)
+	// Drop the UPRNs reviewers have rejected, then deduplicate and rank candidates
+	var allowed []AccurateCandidate
+	for _, ac := range allCandidates {
+		if !rejections.Rejected(ac.MatchCandidate.UPRN) {
+			allowed = append(allowed, ac)
+		}
+	}
+	finalCandidates := e.deduplicateAndRankAccurate(allowed)
 	
 	// Make intelligent decision
 	decision, matchStatus := e.makeAccurateDecision(localDebug, finalCandidates)
@@ -213,7 +227,10 @@ func (e *AccurateEngine) parseAddressEnhanced(localDebug bool, address string) P
 }
 
 // Postcode + House Number matching (very accurate)
-func (e *AccurateEngine) postcodeHouseNumberMatch(localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+func (e *AccurateEngine) postcodeHouseNumberMatch(ctx context.Context, localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if parsed.Postcode == "" || parsed.HouseNumber == "" {
 		return []AccurateCandidate{}
 	}
@@ -230,7 +247,7 @@ func (e *AccurateEngine) postcodeHouseNumberMatch(localDebug bool, parsed Parsed
 		LIMIT 20
 	`
 	
-	rows, err := e.db.Query(query, parsed.Postcode, parsed.HouseNumber)
+	rows, err := e.db.QueryContext(ctx, query, parsed.Postcode, parsed.HouseNumber)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Postcode+HouseNumber query error: %v", err)
 		return []AccurateCandidate{}
@@ -263,7 +280,10 @@ func (e *AccurateEngine) postcodeHouseNumberMatch(localDebug bool, parsed Parsed
 }
 
 // Street + Locality matching
-func (e *AccurateEngine) streetLocalityMatch(localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+func (e *AccurateEngine) streetLocalityMatch(ctx context.Context, localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if parsed.StreetName == "" {
 		return []AccurateCandidate{}
 	}
@@ -291,7 +311,7 @@ func (e *AccurateEngine) streetLocalityMatch(localDebug bool, parsed ParsedAddre
 	
 	query += " ORDER BY sim_score DESC LIMIT 50"
 	
-	rows, err := e.db.Query(query, searchStr)
+	rows, err := e.db.QueryContext(ctx, query, searchStr)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Street+Locality query error: %v", err)
 		return []AccurateCandidate{}
@@ -328,9 +348,12 @@ func (e *AccurateEngine) streetLocalityMatch(localDebug bool, parsed ParsedAddre
 }
 
 // Component-based fuzzy matching with intelligent weighting
-func (e *AccurateEngine) componentFuzzyMatch(localDebug bool, parsed ParsedAddress, canonical string) []AccurateCandidate {
+func (e *AccurateEngine) componentFuzzyMatch(ctx context.Context, localDebug bool, parsed ParsedAddress, canonical string) []AccurateCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	// Use the optimized function but with more candidates
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			address_id, location_id, uprn, full_address, address_canonical,
 			easting, northing, match_score, match_method
@@ -394,7 +417,10 @@ func (e *AccurateEngine) componentFuzzyMatch(localDebug bool, parsed ParsedAddre
 }
 
 // Landmark/Business name matching
-func (e *AccurateEngine) landmarkMatch(localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+func (e *AccurateEngine) landmarkMatch(ctx context.Context, localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	searchTerm := parsed.BusinessName
 	if searchTerm == "" {
 		searchTerm = parsed.HouseName
@@ -419,7 +445,7 @@ func (e *AccurateEngine) landmarkMatch(localDebug bool, parsed ParsedAddress) []
 		LIMIT 20
 	`
 	
-	rows, err := e.db.Query(query, strings.ToUpper(searchTerm))
+	rows, err := e.db.QueryContext(ctx, query, strings.ToUpper(searchTerm))
 	if err != nil {
 		return []AccurateCandidate{}
 	}
@@ -450,7 +476,10 @@ func (e *AccurateEngine) landmarkMatch(localDebug bool, parsed ParsedAddress) []
 }
 
 // Land reference matching (Land at, Rear of, etc.)
-func (e *AccurateEngine) landReferenceMatch(localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+func (e *AccurateEngine) landReferenceMatch(ctx context.Context, localDebug bool, parsed ParsedAddress) []AccurateCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	// Extract the location after the land reference
 	searchStr := strings.ToUpper(parsed.Original)
 	searchStr = strings.Replace(searchStr, parsed.LandReference, "", 1)
@@ -474,7 +503,7 @@ func (e *AccurateEngine) landReferenceMatch(localDebug bool, parsed ParsedAddres
 		LIMIT 30
 	`
 	
-	rows, err := e.db.Query(query, searchStr)
+	rows, err := e.db.QueryContext(ctx, query, searchStr)
 	if err != nil {
 		return []AccurateCandidate{}
 	}
@@ -572,8 +601,11 @@ func (e *AccurateEngine) calculateComponentScore(parsed ParsedAddress, candidate
 }
 
 // Exact UPRN matching
-func (e *AccurateEngine) exactUPRNMatch(localDebug bool, uprn string) []MatchCandidate {
-	rows, err := e.db.Query(`
+func (e *AccurateEngine) exactUPRNMatch(ctx context.Context, localDebug bool, uprn string) []MatchCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 			l.easting, l.northing
@@ -687,7 +719,10 @@ func (e *AccurateEngine) createResult(documentID int64, candidates []MatchCandid
 }
 
 // SaveMatchResult saves the matching result to the database
-func (e *AccurateEngine) SaveMatchResult(localDebug bool, result *MatchResult) error {
+func (e *AccurateEngine) SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if result.BestCandidate == nil {
 		debug.DebugOutput(localDebug, "No match to save for document %d", result.DocumentID)
 		return nil
@@ -699,7 +734,7 @@ func (e *AccurateEngine) SaveMatchResult(localDebug bool, result *MatchResult) e
 		methodID = 10 // component_fuzzy default
 	}
 	
-	_, err := e.db.Exec(`
+	_, err := e.db.ExecContext(ctx, `
 		INSERT INTO address_match (
 			document_id, address_id, location_id, match_method_id,
 			confidence_score, match_status, matched_by, matched_at
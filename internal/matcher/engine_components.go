@@ -1,6 +1,7 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -8,13 +9,16 @@ import (
 
 	"github.com/ehdc-llpg/internal/debug"
 	"github.com/ehdc-llpg/internal/normalize"
+	"github.com/ehdc-llpg/internal/store"
 )
 
 // ComponentEngine performs component-based address matching
 // This engine focuses on matching individual address components
 // and can be enhanced with real gopostal parsing later
 type ComponentEngine struct {
-	db *sql.DB
+	addresses store.AddressStore
+	documents store.DocumentStore
+	matches   store.MatchStore
 }
 
 // ComponentMatch represents a match with component-level details
@@ -43,11 +47,16 @@ type ComponentScore struct {
 
 // NewComponentEngine creates a component-based matching engine
 func NewComponentEngine(db *sql.DB) *ComponentEngine {
-	return &ComponentEngine{db: db}
+	return NewComponentEngineWithStores(store.NewPostgresStores(db))
+}
+
+// NewComponentEngineWithStores creates a component engine over the given stores
+func NewComponentEngineWithStores(stores store.Stores) *ComponentEngine {
+	return &ComponentEngine{addresses: stores.Addresses, documents: stores.Documents, matches: stores.Matches}
 }
 
 // ProcessDocument performs component-based address matching
-func (e *ComponentEngine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResult, error) {
+func (e *ComponentEngine) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 	
@@ -56,13 +65,13 @@ func (e *ComponentEngine) ProcessDocument(localDebug bool, input MatchInput) (*M
 	debug.DebugOutput(localDebug, "Processing document %d (COMPONENT): %s", input.DocumentID, input.RawAddress)
 	
 	// First, populate component data if not already done
-	err := e.ensureComponentData(localDebug, input)
+	err := e.ensureComponentData(ctx, localDebug, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to ensure component data: %w", err)
 	}
 	
 	// Get or extract components for the input
-	inputComponents, err := e.getInputComponents(localDebug, input)
+	inputComponents, err := e.getInputComponents(ctx, localDebug, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract input components: %w", err)
 	}
@@ -71,7 +80,7 @@ func (e *ComponentEngine) ProcessDocument(localDebug bool, input MatchInput) (*M
 		inputComponents["house_number"], inputComponents["road"], inputComponents["city"], inputComponents["postcode"])
 	
 	// Perform component-based matching
-	candidates, err := e.performComponentMatching(localDebug, inputComponents)
+	candidates, err := e.performComponentMatching(ctx, localDebug, inputComponents)
 	if err != nil {
 		return nil, fmt.Errorf("component matching failed: %w", err)
 	}
@@ -106,12 +115,9 @@ func (e *ComponentEngine) ProcessDocument(localDebug bool, input MatchInput) (*M
 }
 
 // ensureComponentData ensures the source document has component data
-func (e *ComponentEngine) ensureComponentData(localDebug bool, input MatchInput) error {
+func (e *ComponentEngine) ensureComponentData(ctx context.Context, localDebug bool, input MatchInput) error {
 	// Check if already processed
-	var isProcessed bool
-	err := e.db.QueryRow("SELECT COALESCE(gopostal_processed, FALSE) FROM src_document WHERE document_id = $1", 
-		input.DocumentID).Scan(&isProcessed)
-	
+	_, isProcessed, err := e.documents.DocumentComponents(ctx, input.DocumentID)
 	if err == nil && isProcessed {
 		return nil // Already processed
 	}
@@ -120,22 +126,7 @@ func (e *ComponentEngine) ensureComponentData(localDebug bool, input MatchInput)
 	components := e.parseAddressComponents(localDebug, input.RawAddress)
 	
 	// Update the source document with components
-	_, err = e.db.Exec(`
-		UPDATE src_document SET
-			gopostal_house_number = $2,
-			gopostal_road = $3,
-			gopostal_city = $4,
-			gopostal_postcode = $5,
-			gopostal_unit = $6,
-			gopostal_processed = TRUE
-		WHERE document_id = $1
-	`, input.DocumentID, 
-		nullIfEmpty(components["house_number"]),
-		nullIfEmpty(components["road"]),
-		nullIfEmpty(components["city"]),
-		nullIfEmpty(components["postcode"]),
-		nullIfEmpty(components["unit"]))
-	
+	err = e.documents.SaveDocumentComponents(ctx, input.DocumentID, toStoreComponents(components))
 	if err != nil {
 		debug.DebugOutput(localDebug, "Warning: failed to update source components: %v", err)
 	}
@@ -200,65 +191,40 @@ func (e *ComponentEngine) parseAddressComponents(localDebug bool, address string
 }
 
 // getInputComponents gets the components for the input address
-func (e *ComponentEngine) getInputComponents(localDebug bool, input MatchInput) (map[string]string, error) {
-	components := make(map[string]string)
-	
-	// Try to get from database first
-	var houseNumber, road, city, postcode, unit sql.NullString
-	err := e.db.QueryRow(`
-		SELECT gopostal_house_number, gopostal_road, gopostal_city, gopostal_postcode, gopostal_unit
-		FROM src_document 
-		WHERE document_id = $1
-	`, input.DocumentID).Scan(&houseNumber, &road, &city, &postcode, &unit)
-	
-	if err == nil {
-		// Use database components
-		if houseNumber.Valid {
-			components["house_number"] = houseNumber.String
-		}
-		if road.Valid {
-			components["road"] = road.String
-		}
-		if city.Valid {
-			components["city"] = city.String
-		}
-		if postcode.Valid {
-			components["postcode"] = postcode.String
-		}
-		if unit.Valid {
-			components["unit"] = unit.String
-		}
-	} else {
-		// Parse on the fly
-		components = e.parseAddressComponents(localDebug, input.RawAddress)
+func (e *ComponentEngine) getInputComponents(ctx context.Context, localDebug bool, input MatchInput) (map[string]string, error) {
+	// Try the stored components first
+	stored, ok, err := e.documents.DocumentComponents(ctx, input.DocumentID)
+	if err == nil && ok {
+		return fromStoreComponents(stored), nil
 	}
-	
-	return components, nil
+
+	// Parse on the fly
+	return e.parseAddressComponents(localDebug, input.RawAddress), nil
 }
 
 // performComponentMatching performs the actual component-based matching
-func (e *ComponentEngine) performComponentMatching(localDebug bool, inputComponents map[string]string) ([]ComponentMatch, error) {
+func (e *ComponentEngine) performComponentMatching(ctx context.Context, localDebug bool, inputComponents map[string]string) ([]ComponentMatch, error) {
 	var allCandidates []ComponentMatch
 	
 	// Strategy 1: Exact component matches (highest priority)
-	candidates := e.exactComponentMatch(localDebug, inputComponents)
+	candidates := e.exactComponentMatch(ctx, localDebug, inputComponents)
 	allCandidates = append(allCandidates, candidates...)
 	
 	// Strategy 2: Postcode + House Number match
 	if len(allCandidates) < 10 && inputComponents["postcode"] != "" && inputComponents["house_number"] != "" {
-		candidates = e.postcodeHouseMatch(localDebug, inputComponents)
+		candidates = e.postcodeHouseMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
 	// Strategy 3: Road + City match with fuzzy
 	if len(allCandidates) < 20 && inputComponents["road"] != "" && inputComponents["city"] != "" {
-		candidates = e.roadCityMatch(localDebug, inputComponents)
+		candidates = e.roadCityMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
 	// Strategy 4: Fuzzy road-only match
 	if len(allCandidates) < 30 && inputComponents["road"] != "" {
-		candidates = e.fuzzyRoadMatch(localDebug, inputComponents)
+		candidates = e.fuzzyRoadMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
@@ -272,82 +238,21 @@ func (e *ComponentEngine) performComponentMatching(localDebug bool, inputCompone
 }
 
 // exactComponentMatch finds exact component matches
-func (e *ComponentEngine) exactComponentMatch(localDebug bool, input map[string]string) []ComponentMatch {
-	var candidates []ComponentMatch
-	
-	// Build WHERE clause dynamically based on available components
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-	
-	if input["postcode"] != "" {
-		conditions = append(conditions, fmt.Sprintf("a.gopostal_postcode = $%d", argIndex))
-		args = append(args, input["postcode"])
-		argIndex++
-	}
-	
-	if input["house_number"] != "" {
-		conditions = append(conditions, fmt.Sprintf("a.gopostal_house_number = $%d", argIndex))
-		args = append(args, input["house_number"])
-		argIndex++
-	}
-	
-	if input["road"] != "" {
-		conditions = append(conditions, fmt.Sprintf("a.gopostal_road = $%d", argIndex))
-		args = append(args, input["road"])
-		argIndex++
-	}
-	
-	if input["city"] != "" {
-		conditions = append(conditions, fmt.Sprintf("a.gopostal_city = $%d", argIndex))
-		args = append(args, input["city"])
-		argIndex++
-	}
-	
-	if len(conditions) == 0 {
-		return candidates
-	}
-	
-	query := fmt.Sprintf(`
-		SELECT 
-			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
-			l.easting, l.northing,
-			a.gopostal_house_number, a.gopostal_road, a.gopostal_city, a.gopostal_postcode
-		FROM dim_address a
-		INNER JOIN dim_location l ON l.location_id = a.location_id
-		WHERE %s
-		LIMIT 50
-	`, strings.Join(conditions, " AND "))
-	
-	rows, err := e.db.Query(query, args...)
+func (e *ComponentEngine) exactComponentMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
+	addresses, err := e.addresses.AddressesByComponents(ctx, store.Components{
+		HouseNumber: input["house_number"],
+		Road:        input["road"],
+		City:        input["city"],
+		Postcode:    input["postcode"],
+	}, 50)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Exact component query error: %v", err)
-		return candidates
+		return nil
 	}
-	defer rows.Close()
 	
-	for rows.Next() {
-		var cm ComponentMatch
-		var dbHouseNum, dbRoad, dbCity, dbPostcode sql.NullString
-		
-		err := rows.Scan(
-			&cm.MatchCandidate.AddressID, &cm.MatchCandidate.LocationID, &cm.MatchCandidate.UPRN,
-			&cm.MatchCandidate.FullAddress, &cm.MatchCandidate.AddressCanonical,
-			&cm.MatchCandidate.Easting, &cm.MatchCandidate.Northing,
-			&dbHouseNum, &dbRoad, &dbCity, &dbPostcode,
-		)
-		if err != nil {
-			continue
-		}
-		
-		// Calculate component score
-		cm.ComponentScore = e.calculateComponentScore(input, map[string]string{
-			"house_number": dbHouseNum.String,
-			"road":         dbRoad.String,
-			"city":         dbCity.String,
-			"postcode":     dbPostcode.String,
-		})
-		
+	var candidates []ComponentMatch
+	for _, a := range addresses {
+		cm := e.componentCandidate(input, a)
 		cm.MatchCandidate.Score = cm.ComponentScore.OverallScore
 		cm.MatchCandidate.MethodCode = "exact_components"
 		cm.MatchCandidate.MethodID = 13
@@ -360,51 +265,24 @@ func (e *ComponentEngine) exactComponentMatch(localDebug bool, input map[string]
 }
 
 // postcodeHouseMatch matches on postcode + house number
-func (e *ComponentEngine) postcodeHouseMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *ComponentEngine) postcodeHouseMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
 	var candidates []ComponentMatch
 	
 	if input["postcode"] == "" || input["house_number"] == "" {
 		return candidates
 	}
 	
-	rows, err := e.db.Query(`
-		SELECT 
-			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
-			l.easting, l.northing,
-			a.gopostal_house_number, a.gopostal_road, a.gopostal_city, a.gopostal_postcode
-		FROM dim_address a
-		INNER JOIN dim_location l ON l.location_id = a.location_id
-		WHERE a.gopostal_postcode = $1 AND a.gopostal_house_number = $2
-		LIMIT 20
-	`, input["postcode"], input["house_number"])
-	
+	addresses, err := e.addresses.AddressesByComponents(ctx, store.Components{
+		Postcode:    input["postcode"],
+		HouseNumber: input["house_number"],
+	}, 20)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Postcode+house query error: %v", err)
 		return candidates
 	}
-	defer rows.Close()
 	
-	for rows.Next() {
-		var cm ComponentMatch
-		var dbHouseNum, dbRoad, dbCity, dbPostcode sql.NullString
-		
-		err := rows.Scan(
-			&cm.MatchCandidate.AddressID, &cm.MatchCandidate.LocationID, &cm.MatchCandidate.UPRN,
-			&cm.MatchCandidate.FullAddress, &cm.MatchCandidate.AddressCanonical,
-			&cm.MatchCandidate.Easting, &cm.MatchCandidate.Northing,
-			&dbHouseNum, &dbRoad, &dbCity, &dbPostcode,
-		)
-		if err != nil {
-			continue
-		}
-		
-		cm.ComponentScore = e.calculateComponentScore(input, map[string]string{
-			"house_number": dbHouseNum.String,
-			"road":         dbRoad.String,
-			"city":         dbCity.String,
-			"postcode":     dbPostcode.String,
-		})
-		
+	for _, a := range addresses {
+		cm := e.componentCandidate(input, a)
 		cm.MatchCandidate.Score = cm.ComponentScore.OverallScore
 		cm.MatchCandidate.MethodCode = "postcode_house"
 		cm.MatchCandidate.MethodID = 8
@@ -417,7 +295,7 @@ func (e *ComponentEngine) postcodeHouseMatch(localDebug bool, input map[string]s
 }
 
 // roadCityMatch matches on road + city with some fuzzy matching
-func (e *ComponentEngine) roadCityMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *ComponentEngine) roadCityMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
 	var candidates []ComponentMatch
 	
 	if input["road"] == "" || input["city"] == "" {
@@ -425,55 +303,28 @@ func (e *ComponentEngine) roadCityMatch(localDebug bool, input map[string]string
 	}
 	
 	// Try exact first, then fuzzy
-	queries := []string{
-		`WHERE a.gopostal_road = $1 AND a.gopostal_city = $2`,
-		`WHERE a.gopostal_road % $1 AND a.gopostal_city = $2 AND similarity(a.gopostal_road, $1) >= 0.7`,
-	}
-	
-	for i, whereClause := range queries {
-		query := fmt.Sprintf(`
-			SELECT 
-				a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
-				l.easting, l.northing,
-				a.gopostal_house_number, a.gopostal_road, a.gopostal_city, a.gopostal_postcode,
-				COALESCE(similarity(a.gopostal_road, $1), 1.0) as road_sim
-			FROM dim_address a
-			INNER JOIN dim_location l ON l.location_id = a.location_id
-			%s
-			ORDER BY road_sim DESC
-			LIMIT 30
-		`, whereClause)
-		
-		rows, err := e.db.Query(query, input["road"], input["city"])
+	for i := 0; i < 2; i++ {
+		var scored []store.ScoredAddress
+		var err error
+		if i == 0 {
+			var exact []store.Address
+			exact, err = e.addresses.AddressesByComponents(ctx, store.Components{Road: input["road"], City: input["city"]}, 30)
+			for _, a := range exact {
+				scored = append(scored, store.ScoredAddress{Address: a, Similarity: 1.0})
+			}
+		} else {
+			scored, err = e.addresses.AddressesByRoad(ctx, input["road"], input["city"], 0.7, 30)
+		}
 		if err != nil {
 			debug.DebugOutput(localDebug, "Road+city query %d error: %v", i, err)
 			continue
 		}
 		
-		for rows.Next() {
-			var cm ComponentMatch
-			var dbHouseNum, dbRoad, dbCity, dbPostcode sql.NullString
-			var roadSim float64
-			
-			err := rows.Scan(
-				&cm.MatchCandidate.AddressID, &cm.MatchCandidate.LocationID, &cm.MatchCandidate.UPRN,
-				&cm.MatchCandidate.FullAddress, &cm.MatchCandidate.AddressCanonical,
-				&cm.MatchCandidate.Easting, &cm.MatchCandidate.Northing,
-				&dbHouseNum, &dbRoad, &dbCity, &dbPostcode, &roadSim,
-			)
-			if err != nil {
-				continue
-			}
-			
-			cm.ComponentScore = e.calculateComponentScore(input, map[string]string{
-				"house_number": dbHouseNum.String,
-				"road":         dbRoad.String,
-				"city":         dbCity.String,
-				"postcode":     dbPostcode.String,
-			})
+		for _, a := range scored {
+			cm := e.componentCandidate(input, a.Address)
 			
 			// Adjust score based on road similarity
-			cm.ComponentScore.OverallScore *= roadSim
+			cm.ComponentScore.OverallScore *= a.Similarity
 			cm.MatchCandidate.Score = cm.ComponentScore.OverallScore
 			
 			if i == 0 {
@@ -485,7 +336,6 @@ func (e *ComponentEngine) roadCityMatch(localDebug bool, input map[string]string
 			
 			candidates = append(candidates, cm)
 		}
-		rows.Close()
 		
 		// If we got good exact matches, don't try fuzzy
 		if i == 0 && len(candidates) > 5 {
@@ -498,57 +348,24 @@ func (e *ComponentEngine) roadCityMatch(localDebug bool, input map[string]string
 }
 
 // fuzzyRoadMatch performs fuzzy matching on road names
-func (e *ComponentEngine) fuzzyRoadMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *ComponentEngine) fuzzyRoadMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
 	var candidates []ComponentMatch
 	
 	if input["road"] == "" {
 		return candidates
 	}
 	
-	rows, err := e.db.Query(`
-		SELECT 
-			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
-			l.easting, l.northing,
-			a.gopostal_house_number, a.gopostal_road, a.gopostal_city, a.gopostal_postcode,
-			similarity(a.gopostal_road, $1) as road_sim
-		FROM dim_address a
-		INNER JOIN dim_location l ON l.location_id = a.location_id
-		WHERE a.gopostal_road % $1 
-		  AND similarity(a.gopostal_road, $1) >= 0.6
-		ORDER BY road_sim DESC
-		LIMIT 50
-	`, input["road"])
-	
+	scored, err := e.addresses.AddressesByRoad(ctx, input["road"], "", 0.6, 50)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Fuzzy road query error: %v", err)
 		return candidates
 	}
-	defer rows.Close()
 	
-	for rows.Next() {
-		var cm ComponentMatch
-		var dbHouseNum, dbRoad, dbCity, dbPostcode sql.NullString
-		var roadSim float64
-		
-		err := rows.Scan(
-			&cm.MatchCandidate.AddressID, &cm.MatchCandidate.LocationID, &cm.MatchCandidate.UPRN,
-			&cm.MatchCandidate.FullAddress, &cm.MatchCandidate.AddressCanonical,
-			&cm.MatchCandidate.Easting, &cm.MatchCandidate.Northing,
-			&dbHouseNum, &dbRoad, &dbCity, &dbPostcode, &roadSim,
-		)
-		if err != nil {
-			continue
-		}
-		
-		cm.ComponentScore = e.calculateComponentScore(input, map[string]string{
-			"house_number": dbHouseNum.String,
-			"road":         dbRoad.String,
-			"city":         dbCity.String,
-			"postcode":     dbPostcode.String,
-		})
+	for _, a := range scored {
+		cm := e.componentCandidate(input, a.Address)
 		
 		// Weight by road similarity
-		cm.ComponentScore.OverallScore = roadSim * 0.8 // Reduce for fuzzy match
+		cm.ComponentScore.OverallScore = a.Similarity * 0.8 // Reduce for fuzzy match
 		cm.MatchCandidate.Score = cm.ComponentScore.OverallScore
 		cm.MatchCandidate.MethodCode = "fuzzy_road"
 		cm.MatchCandidate.MethodID = 10
@@ -560,6 +377,22 @@ func (e *ComponentEngine) fuzzyRoadMatch(localDebug bool, input map[string]strin
 	return candidates
 }
 
+// componentCandidate builds a scored candidate from an LLPG address
+func (e *ComponentEngine) componentCandidate(input map[string]string, a store.Address) ComponentMatch {
+	var cm ComponentMatch
+	cm.MatchCandidate = MatchCandidate{
+		AddressID:        a.AddressID,
+		LocationID:       a.LocationID,
+		UPRN:             a.UPRN,
+		FullAddress:      a.FullAddress,
+		AddressCanonical: a.Canonical,
+		Easting:          a.Easting,
+		Northing:         a.Northing,
+	}
+	cm.ComponentScore = e.calculateComponentScore(input, fromStoreComponents(a.Components))
+	return cm
+}
+
 // calculateComponentScore calculates how well components match
 func (e *ComponentEngine) calculateComponentScore(input, candidate map[string]string) ComponentScore {
 	score := ComponentScore{}
@@ -700,34 +533,21 @@ func (e *ComponentEngine) makeComponentDecision(localDebug bool, candidates []Co
 }
 
 // SaveMatchResult saves the matching result to the database
-func (e *ComponentEngine) SaveMatchResult(localDebug bool, result *MatchResult) error {
+func (e *ComponentEngine) SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error {
 	if result.BestCandidate == nil {
 		debug.DebugOutput(localDebug, "No match to save for document %d", result.DocumentID)
 		return nil
 	}
 	
-	_, err := e.db.Exec(`
-		INSERT INTO address_match (
-			document_id, address_id, location_id, match_method_id,
-			confidence_score, match_status, matched_by, matched_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, now())
-		ON CONFLICT (document_id) DO UPDATE SET
-			address_id = EXCLUDED.address_id,
-			location_id = EXCLUDED.location_id,
-			match_method_id = EXCLUDED.match_method_id,
-			confidence_score = EXCLUDED.confidence_score,
-			match_status = EXCLUDED.match_status,
-			matched_by = EXCLUDED.matched_by,
-			matched_at = now()
-	`,
-		result.DocumentID,
-		result.BestCandidate.AddressID,
-		result.BestCandidate.LocationID,
-		result.BestCandidate.MethodID,
-		result.BestCandidate.Score,
-		result.MatchStatus,
-		"system_component",
-	)
+	err := e.matches.SaveAddressMatch(ctx, store.AddressMatch{
+		DocumentID: result.DocumentID,
+		AddressID:  result.BestCandidate.AddressID,
+		LocationID: result.BestCandidate.LocationID,
+		MethodID:   result.BestCandidate.MethodID,
+		Score:      result.BestCandidate.Score,
+		Status:     result.MatchStatus,
+		MatchedBy:  "system_component",
+	})
 	
 	if err != nil {
 		return fmt.Errorf("failed to save component match result: %w", err)
@@ -739,10 +559,30 @@ func (e *ComponentEngine) SaveMatchResult(localDebug bool, result *MatchResult)
 	return nil
 }
 
-// Helper function
-func nullIfEmpty(s string) interface{} {
-	if s == "" {
-		return nil
+// toStoreComponents converts a parsed component map for storage
+func toStoreComponents(c map[string]string) store.Components {
+	return store.Components{
+		HouseNumber: c["house_number"],
+		Road:        c["road"],
+		City:        c["city"],
+		Postcode:    c["postcode"],
+		Unit:        c["unit"],
+	}
+}
+
+// fromStoreComponents converts stored components to the map the scorer uses, omitting empty parts
+func fromStoreComponents(c store.Components) map[string]string {
+	components := make(map[string]string)
+	for key, value := range map[string]string{
+		"house_number": c.HouseNumber,
+		"road":         c.Road,
+		"city":         c.City,
+		"postcode":     c.Postcode,
+		"unit":         c.Unit,
+	} {
+		if value != "" {
+			components[key] = value
+		}
 	}
-	return s
-}
\ No newline at end of file
+	return components
+}
@@ -1,11 +1,13 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
 	"github.com/ehdc-llpg/internal/normalize"
 )
@@ -32,7 +34,7 @@ type ValidationResult struct {
 }
 
 // ProcessDocument performs component-based address matching with proper validation
-func (e *FixedComponentEngine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResult, error) {
+func (e *FixedComponentEngine) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 	
@@ -44,7 +46,7 @@ func (e *FixedComponentEngine) ProcessDocument(localDebug bool, input MatchInput
 	if input.RawUPRN != nil && *input.RawUPRN != "" {
 		debug.DebugOutput(localDebug, "Source has UPRN: %s - attempting exact UPRN match", *input.RawUPRN)
 		
-		candidates, err := e.exactUPRNDirectMatch(localDebug, *input.RawUPRN)
+		candidates, err := e.exactUPRNDirectMatch(ctx, localDebug, *input.RawUPRN)
 		if err == nil && len(candidates) > 0 {
 			debug.DebugOutput(localDebug, "Found %d exact UPRN matches", len(candidates))
 			
@@ -73,7 +75,7 @@ func (e *FixedComponentEngine) ProcessDocument(localDebug bool, input MatchInput
 		debug.DebugOutput(localDebug, "UPRN %s not found in LLPG, creating historic record", *input.RawUPRN)
 		
 		// Create historic UPRN record
-		historicCandidate, err := e.createHistoricUPRNRecord(localDebug, *input.RawUPRN, input.RawAddress, input.DocumentID)
+		historicCandidate, err := e.createHistoricUPRNRecord(ctx, localDebug, *input.RawUPRN, input.RawAddress, input.DocumentID)
 		if err == nil && historicCandidate != nil {
 			debug.DebugOutput(localDebug, "Created historic UPRN record: address_id=%d", historicCandidate.AddressID)
 			
@@ -93,7 +95,7 @@ func (e *FixedComponentEngine) ProcessDocument(localDebug bool, input MatchInput
 	}
 	
 	// Get input components for fallback matching
-	inputComponents, err := e.getInputComponents(localDebug, input)
+	inputComponents, err := e.getInputComponents(ctx, localDebug, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract input components: %w", err)
 	}
@@ -102,7 +104,7 @@ func (e *FixedComponentEngine) ProcessDocument(localDebug bool, input MatchInput
 		inputComponents["house_number"], inputComponents["road"], inputComponents["city"], inputComponents["postcode"])
 	
 	// Perform component-based matching with validation
-	candidates, err := e.performValidatedMatching(localDebug, inputComponents)
+	candidates, err := e.performValidatedMatching(ctx, localDebug, inputComponents)
 	if err != nil {
 		return nil, fmt.Errorf("component matching failed: %w", err)
 	}
@@ -137,12 +139,15 @@ func (e *FixedComponentEngine) ProcessDocument(localDebug bool, input MatchInput
 }
 
 // getInputComponents gets the components for the input address
-func (e *FixedComponentEngine) getInputComponents(localDebug bool, input MatchInput) (map[string]string, error) {
+func (e *FixedComponentEngine) getInputComponents(ctx context.Context, localDebug bool, input MatchInput) (map[string]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	components := make(map[string]string)
 	
 	// Try to get from database first
 	var houseNumber, road, city, postcode, unit sql.NullString
-	err := e.db.QueryRow(`
+	err := e.db.QueryRowContext(ctx, `
 		SELECT gopostal_house_number, gopostal_road, gopostal_city, gopostal_postcode, gopostal_unit
 		FROM src_document 
 		WHERE document_id = $1
@@ -198,36 +203,36 @@ func (e *FixedComponentEngine) extractBusinessName(address string) string {
 }
 
 // performValidatedMatching performs component-based matching with strict validation
-func (e *FixedComponentEngine) performValidatedMatching(localDebug bool, inputComponents map[string]string) ([]ComponentMatch, error) {
+func (e *FixedComponentEngine) performValidatedMatching(ctx context.Context, localDebug bool, inputComponents map[string]string) ([]ComponentMatch, error) {
 	var allCandidates []ComponentMatch
 	
 	// Strategy 1: Exact UPRN/reference match (highest priority)
 	if inputComponents["postcode"] != "" && inputComponents["house_number"] != "" {
-		candidates := e.exactUPRNMatch(localDebug, inputComponents)
+		candidates := e.exactUPRNMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
 	// Strategy 2: Postcode + House Number match (high confidence)
 	if len(allCandidates) < 5 && inputComponents["postcode"] != "" && inputComponents["house_number"] != "" {
-		candidates := e.postcodeHouseValidatedMatch(localDebug, inputComponents)
+		candidates := e.postcodeHouseValidatedMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
 	// Strategy 3: Business name match (for organizations)
 	if len(allCandidates) < 10 && inputComponents["business_name"] != "" {
-		candidates := e.businessNameMatch(localDebug, inputComponents)
+		candidates := e.businessNameMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
 	// Strategy 4: Road + City with STRICT house number validation
 	if len(allCandidates) < 15 && inputComponents["road"] != "" && inputComponents["city"] != "" {
-		candidates := e.roadCityValidatedMatch(localDebug, inputComponents)
+		candidates := e.roadCityValidatedMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
 	// Strategy 5: Fuzzy road ONLY if no house number conflict
 	if len(allCandidates) < 20 && inputComponents["road"] != "" {
-		candidates := e.fuzzyRoadValidatedMatch(localDebug, inputComponents)
+		candidates := e.fuzzyRoadValidatedMatch(ctx, localDebug, inputComponents)
 		allCandidates = append(allCandidates, candidates...)
 	}
 	
@@ -241,7 +246,10 @@ func (e *FixedComponentEngine) performValidatedMatching(localDebug bool, inputCo
 }
 
 // exactUPRNDirectMatch finds exact UPRN matches from source document UPRN
-func (e *FixedComponentEngine) exactUPRNDirectMatch(localDebug bool, uprn string) ([]ComponentMatch, error) {
+func (e *FixedComponentEngine) exactUPRNDirectMatch(ctx context.Context, localDebug bool, uprn string) ([]ComponentMatch, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var candidates []ComponentMatch
 	
 	// Clean the UPRN - remove decimal suffixes like .00
@@ -257,7 +265,7 @@ func (e *FixedComponentEngine) exactUPRNDirectMatch(localDebug bool, uprn string
 	}
 	
 	// Query LLPG for exact UPRN match
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 			l.easting, l.northing,
@@ -309,7 +317,7 @@ func (e *FixedComponentEngine) exactUPRNDirectMatch(localDebug bool, uprn string
 }
 
 // exactUPRNMatch finds exact UPRN matches (legacy - kept for compatibility)
-func (e *FixedComponentEngine) exactUPRNMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *FixedComponentEngine) exactUPRNMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
 	var candidates []ComponentMatch
 	
 	// This would need UPRN data in source - placeholder for now
@@ -319,14 +327,17 @@ func (e *FixedComponentEngine) exactUPRNMatch(localDebug bool, input map[string]
 }
 
 // postcodeHouseValidatedMatch matches on postcode + house number with validation
-func (e *FixedComponentEngine) postcodeHouseValidatedMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *FixedComponentEngine) postcodeHouseValidatedMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var candidates []ComponentMatch
 	
 	if input["postcode"] == "" || input["house_number"] == "" {
 		return candidates
 	}
 	
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 			l.easting, l.northing,
@@ -378,7 +389,10 @@ func (e *FixedComponentEngine) postcodeHouseValidatedMatch(localDebug bool, inpu
 }
 
 // businessNameMatch matches on business/organization names
-func (e *FixedComponentEngine) businessNameMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *FixedComponentEngine) businessNameMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var candidates []ComponentMatch
 	
 	if input["business_name"] == "" {
@@ -406,7 +420,7 @@ func (e *FixedComponentEngine) businessNameMatch(localDebug bool, input map[stri
 		LIMIT 5
 	`
 	
-	rows, err := e.db.Query(query, input["business_name"])
+	rows, err := e.db.QueryContext(ctx, query, input["business_name"])
 	if err != nil {
 		debug.DebugOutput(localDebug, "Business name query error: %v", err)
 		return candidates
@@ -449,7 +463,10 @@ func (e *FixedComponentEngine) businessNameMatch(localDebug bool, input map[stri
 }
 
 // roadCityValidatedMatch matches on road + city with STRICT house number validation
-func (e *FixedComponentEngine) roadCityValidatedMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *FixedComponentEngine) roadCityValidatedMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var candidates []ComponentMatch
 	
 	if input["road"] == "" || input["city"] == "" {
@@ -482,7 +499,7 @@ func (e *FixedComponentEngine) roadCityValidatedMatch(localDebug bool, input map
 		LIMIT 10
 	`, houseNumberClause)
 	
-	rows, err := e.db.Query(query, args...)
+	rows, err := e.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Validated road+city query error: %v", err)
 		return candidates
@@ -523,7 +540,10 @@ func (e *FixedComponentEngine) roadCityValidatedMatch(localDebug bool, input map
 }
 
 // fuzzyRoadValidatedMatch performs fuzzy road matching with house number validation
-func (e *FixedComponentEngine) fuzzyRoadValidatedMatch(localDebug bool, input map[string]string) []ComponentMatch {
+func (e *FixedComponentEngine) fuzzyRoadValidatedMatch(ctx context.Context, localDebug bool, input map[string]string) []ComponentMatch {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var candidates []ComponentMatch
 	
 	if input["road"] == "" {
@@ -556,7 +576,7 @@ func (e *FixedComponentEngine) fuzzyRoadValidatedMatch(localDebug bool, input ma
 		LIMIT 20
 	`, houseNumberClause)
 	
-	rows, err := e.db.Query(query, args...)
+	rows, err := e.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Validated fuzzy road query error: %v", err)
 		return candidates
@@ -763,7 +783,10 @@ func (e *FixedComponentEngine) makeValidatedDecision(localDebug bool, candidates
 }
 
 // createHistoricUPRNRecord creates a historic address record for UPRNs not found in LLPG
-func (e *FixedComponentEngine) createHistoricUPRNRecord(localDebug bool, uprn, fullAddress string, documentID int64) (*MatchCandidate, error) {
+func (e *FixedComponentEngine) createHistoricUPRNRecord(ctx context.Context, localDebug bool, uprn, fullAddress string, documentID int64) (*MatchCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	// Normalize UPRN - remove .00 decimal suffix if present
 	cleanUPRN := strings.TrimSpace(uprn)
 	if strings.HasSuffix(cleanUPRN, ".00") {
@@ -775,7 +798,7 @@ func (e *FixedComponentEngine) createHistoricUPRNRecord(localDebug bool, uprn, f
 	
 	// Call the database function to create the historic record
 	var addressID int
-	err := e.db.QueryRow(`
+	err := e.db.QueryRowContext(ctx, `
 		SELECT create_historic_uprn_record($1, $2, $3)
 	`, cleanUPRN, fullAddress, int(documentID)).Scan(&addressID)
 	
@@ -792,7 +815,7 @@ func (e *FixedComponentEngine) createHistoricUPRNRecord(localDebug bool, uprn, f
 	var fullAddr, canonical string
 	var easting, northing float64
 	
-	err = e.db.QueryRow(`
+	err = e.db.QueryRowContext(ctx, `
 		SELECT a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
 		       l.easting, l.northing
 		FROM dim_address a
@@ -0,0 +1,38 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+func TestComponentEngineProcessDocument(t *testing.T) {
+	m, err := store.LoadFixtures("../store/testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	e := NewComponentEngineWithStores(m.Stores())
+
+	ctx := context.Background()
+	result, err := e.ProcessDocument(ctx, false, MatchInput{DocumentID: 1, RawAddress: "12 High St, Alton, GU34 1AB"})
+	if err != nil {
+		t.Fatalf("ProcessDocument: %v", err)
+	}
+	if result.BestCandidate == nil || result.BestCandidate.UPRN != "100062000001" {
+		t.Fatalf("best candidate = %+v, want UPRN 100062000001", result.BestCandidate)
+	}
+
+	// Parsed components are stored for the next pass
+	c, ok, _ := m.DocumentComponents(ctx, 1)
+	if !ok || c.HouseNumber != "12" || c.Road != "HIGH STREET" || c.Postcode != "GU341AB" {
+		t.Errorf("stored components = %+v (%v)", c, ok)
+	}
+
+	if err := e.SaveMatchResult(ctx, false, result); err != nil {
+		t.Fatalf("SaveMatchResult: %v", err)
+	}
+	if am, ok := m.AddressMatches()[1]; !ok || am.AddressID != 1 || am.MatchedBy != "system_component" {
+		t.Errorf("address match = %+v (%v)", am, ok)
+	}
+}
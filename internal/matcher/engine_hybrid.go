@@ -1,12 +1,15 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/match"
 	"github.com/ehdc-llpg/internal/normalize"
 )
 
@@ -60,7 +63,7 @@ func NewHybridEngine(db *sql.DB, embedder Embedder, vectorDB VectorDB) *HybridEn
 }
 
 // ProcessDocument performs hybrid address matching
-func (e *HybridEngine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResult, error) {
+func (e *HybridEngine) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 	
@@ -70,12 +73,18 @@ func (e *HybridEngine) ProcessDocument(localDebug bool, input MatchInput) (*Matc
 	
 	// Stage 1: Fast Database Pre-filtering
 	debug.DebugOutput(localDebug, "=== STAGE 1: Fast Database Pre-filtering ===")
-	rawCandidates, err := e.fastDatabasePrefilter(localDebug, input)
+	rawCandidates, err := e.fastDatabasePrefilter(ctx, localDebug, input)
 	if err != nil {
 		return nil, fmt.Errorf("database pre-filtering failed: %w", err)
 	}
 	
 	debug.DebugOutput(localDebug, "Pre-filter found %d raw candidates", len(rawCandidates))
+
+	rejections, err := match.LoadRejections(ctx, e.db, input.DocumentID, input.AddressCanonical)
+	if err != nil {
+		return nil, err
+	}
+	rawCandidates = withoutRejected(localDebug, rejections, rawCandidates)
 	
 	if len(rawCandidates) == 0 {
 		return &MatchResult{
@@ -126,14 +135,17 @@ func (e *HybridEngine) ProcessDocument(localDebug bool, input MatchInput) (*Matc
 }
 
 // Stage 1: Fast Database Pre-filtering
-func (e *HybridEngine) fastDatabasePrefilter(localDebug bool, input MatchInput) ([]MatchCandidate, error) {
+func (e *HybridEngine) fastDatabasePrefilter(ctx context.Context, localDebug bool, input MatchInput) ([]MatchCandidate, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var rawUPRN *string
 	if input.RawUPRN != nil && *input.RawUPRN != "" {
 		rawUPRN = input.RawUPRN
 	}
 	
 	// Use optimized database function but request more candidates for analysis
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			address_id, location_id, uprn, full_address, address_canonical,
 			easting, northing, match_score, match_method
@@ -465,13 +477,16 @@ func (e *HybridEngine) intelligentDecisionMaking(localDebug bool, candidates []H
 }
 
 // SaveMatchResult saves the matching result to the database
-func (e *HybridEngine) SaveMatchResult(localDebug bool, result *MatchResult) error {
+func (e *HybridEngine) SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if result.BestCandidate == nil {
 		debug.DebugOutput(localDebug, "No match to save for document %d", result.DocumentID)
 		return nil
 	}
 	
-	_, err := e.db.Exec(`
+	_, err := e.db.ExecContext(ctx, `
 		INSERT INTO address_match (
 			document_id, address_id, location_id, match_method_id,
 			confidence_score, match_status, matched_by, matched_at
@@ -1,11 +1,14 @@
 package matcher
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
+	database "github.com/ehdc-llpg/internal/db"
 	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/match"
 )
 
 // OptimizedEngine uses database functions for faster matching
@@ -21,7 +24,10 @@ func NewOptimizedEngine(db *sql.DB) *OptimizedEngine {
 }
 
 // ProcessDocument performs optimized address matching using database functions
-func (e *OptimizedEngine) ProcessDocument(localDebug bool, input MatchInput) (*MatchResult, error) {
+func (e *OptimizedEngine) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	debug.DebugHeader(localDebug)
 	defer debug.DebugFooter(localDebug)
 	
@@ -35,7 +41,7 @@ func (e *OptimizedEngine) ProcessDocument(localDebug bool, input MatchInput) (*M
 	}
 	
 	// Use the optimized database function for all matching tiers
-	rows, err := e.db.Query(`
+	rows, err := e.db.QueryContext(ctx, `
 		SELECT 
 			address_id, location_id, uprn, full_address, address_canonical,
 			easting, northing, match_score, match_method
@@ -89,10 +95,16 @@ func (e *OptimizedEngine) ProcessDocument(localDebug bool, input MatchInput) (*M
 	}
 	
 	debug.DebugOutput(localDebug, "Found %d candidates with optimized matching", len(allCandidates))
+
+	rejections, err := match.LoadRejections(ctx, e.db, input.DocumentID, input.AddressCanonical)
+	if err != nil {
+		return nil, err
+	}
+	allCandidates = withoutRejected(localDebug, rejections, allCandidates)
 	
 	// Apply spatial filtering if coordinates available
 	if input.RawEasting != nil && input.RawNorthing != nil {
-		allCandidates = e.applySpatialFilter(localDebug, allCandidates, input.RawEasting, input.RawNorthing, 2000.0)
+		allCandidates = e.applySpatialFilter(ctx, localDebug, allCandidates, input.RawEasting, input.RawNorthing, 2000.0)
 	}
 	
 	// Make final decision
@@ -119,14 +131,17 @@ func (e *OptimizedEngine) ProcessDocument(localDebug bool, input MatchInput) (*M
 }
 
 // applySpatialFilter filters candidates by spatial proximity (simplified version)
-func (e *OptimizedEngine) applySpatialFilter(localDebug bool, candidates []MatchCandidate, eastingStr, northingStr *string, radiusMeters float64) []MatchCandidate {
+func (e *OptimizedEngine) applySpatialFilter(ctx context.Context, localDebug bool, candidates []MatchCandidate, eastingStr, northingStr *string, radiusMeters float64) []MatchCandidate {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if eastingStr == nil || northingStr == nil || *eastingStr == "" || *northingStr == "" {
 		return candidates
 	}
 	
 	// Use database for coordinate parsing and filtering
 	var easting, northing float64
-	err := e.db.QueryRow("SELECT $1::NUMERIC, $2::NUMERIC", *eastingStr, *northingStr).Scan(&easting, &northing)
+	err := e.db.QueryRowContext(ctx, "SELECT $1::NUMERIC, $2::NUMERIC", *eastingStr, *northingStr).Scan(&easting, &northing)
 	if err != nil {
 		debug.DebugOutput(localDebug, "Failed to parse coordinates: %v", err)
 		return candidates
@@ -200,13 +215,16 @@ func (e *OptimizedEngine) makeOptimizedDecision(candidates []MatchCandidate) (st
 }
 
 // SaveMatchResult saves the matching result to the database
-func (e *OptimizedEngine) SaveMatchResult(localDebug bool, result *MatchResult) error {
+func (e *OptimizedEngine) SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	if result.BestCandidate == nil {
 		debug.DebugOutput(localDebug, "No match to save for document %d", result.DocumentID)
 		return nil
 	}
 	
-	_, err := e.db.Exec(`
+	_, err := e.db.ExecContext(ctx, `
 		INSERT INTO address_match (
 			document_id, address_id, location_id, match_method_id,
 			confidence_score, match_status, matched_by, matched_at
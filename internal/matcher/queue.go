@@ -0,0 +1,176 @@
+package matcher
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/lib/pq"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/match"
+	"github.com/ehdc-llpg/internal/queue"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// UnmatchedDocumentIDs lists the documents a batch run would match, optionally of one
+// type, as work queue items
+func UnmatchedDocumentIDs(ctx context.Context, db *sql.DB, docType string) ([]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT s.document_id
+		FROM src_document s
+		LEFT JOIN dim_document_type dt ON dt.doc_type_id = s.doc_type_id
+		LEFT JOIN address_match m ON m.document_id = s.document_id
+		WHERE m.document_id IS NULL
+		  AND s.removed_at IS NULL
+		  AND s.raw_address IS NOT NULL
+		  AND s.raw_address != ''
+		  AND ($1 = '' OR dt.type_code = $1)
+		ORDER BY s.document_id
+	`, docType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unmatched documents: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan document id: %w", err)
+		}
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	return ids, rows.Err()
+}
+
+// DocumentHandler matches work queue chunks of document IDs with the standard engine,
+// explaining each decision under the chunk's run. Documents matched since they were
+// enqueued, including by an earlier claim of the same chunk, are skipped.
+func DocumentHandler(db *sql.DB) queue.Handler {
+	engine := NewEngine(db, nil, nil)
+	explanations := store.NewPostgres(db)
+	return func(ctx context.Context, chunk *store.WorkChunk) (store.ChunkResult, error) {
+		return matchChunk(ctx, engine, explanations, func(ctx context.Context, ids []int64) ([]MatchInput, error) {
+			return unmatchedDocumentsByID(ctx, db, ids)
+		}, chunk)
+	}
+}
+
+// matchChunk matches a chunk's documents and records why each was decided as it was;
+// fetch returns those still unmatched
+func matchChunk(ctx context.Context, m documentMatcher, explanations store.MatchStore, fetch func(context.Context, []int64) ([]MatchInput, error), chunk *store.WorkChunk) (store.ChunkResult, error) {
+	var result store.ChunkResult
+
+	ids := make([]int64, 0, len(chunk.Items))
+	for _, item := range chunk.Items {
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return result, fmt.Errorf("chunk %d has invalid document id %q", chunk.ChunkID, item)
+		}
+		ids = append(ids, id)
+	}
+
+	inputs, err := fetch(ctx, ids)
+	if err != nil {
+		return result, err
+	}
+
+	for _, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		decided, err := m.ProcessDocument(ctx, false, input)
+		if err == nil {
+			err = m.SaveMatchResult(ctx, false, decided)
+		}
+		if err == nil {
+			err = explanations.SaveExplanation(ctx, explainMatch(chunk.RunID, input.DocumentID, decided))
+		}
+		if err != nil && ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		if err != nil {
+			result.Errors++
+			continue
+		}
+		result.Processed++
+		if decided.Decision == "auto_accept" {
+			result.Matched++
+		}
+	}
+	return result, nil
+}
+
+// explainMatch describes a standard engine decision as a match_explanation: its top 10
+// candidates with the generator that found each and its margin to the best other. The
+// engine has no threshold tiers, so none are recorded.
+func explainMatch(runID, documentID int64, result *MatchResult) store.Explanation {
+	e := store.Explanation{RunID: runID, SrcID: documentID, Decision: result.Decision, Reason: "no candidates found"}
+	if result.BestCandidate == nil {
+		return e
+	}
+	best := result.BestCandidate
+	if result.Decision == "auto_accept" {
+		e.AcceptedUPRN = best.UPRN
+	}
+	e.Reason = fmt.Sprintf("top candidate scored %.4f by %s", best.Score, best.MethodCode)
+
+	candidates := make([]match.CandidateExplanation, 0, len(result.AllCandidates))
+	for i, candidate := range result.AllCandidates {
+		if i >= 10 {
+			break
+		}
+		// Best score other than this candidate's own; a lone candidate has the maximum margin
+		margin := 1.0
+		found := false
+		for j, other := range result.AllCandidates {
+			if j != i && (!found || candidate.Score-other.Score < margin) {
+				margin, found = candidate.Score-other.Score, true
+			}
+		}
+		candidates = append(candidates, match.CandidateExplanation{
+			UPRN:     candidate.UPRN,
+			Address:  candidate.FullAddress,
+			Rank:     i + 1,
+			Score:    candidate.Score,
+			Selected: e.AcceptedUPRN != "" && candidate.UPRN == e.AcceptedUPRN,
+			Margin:   margin,
+			Methods:  []string{candidate.MethodCode},
+			Features: candidate.Features,
+		})
+	}
+	e.Candidates = candidates
+	if len(candidates) > 0 {
+		e.Margin = &candidates[0].Margin
+	}
+	return e
+}
+
+// unmatchedDocumentsByID reads the documents among ids without an address_match
+func unmatchedDocumentsByID(ctx context.Context, db *sql.DB, ids []int64) ([]MatchInput, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			s.document_id, s.raw_address, s.address_canonical,
+			s.raw_uprn, s.raw_easting, s.raw_northing
+		FROM src_document s
+		LEFT JOIN address_match m ON m.document_id = s.document_id
+		WHERE m.document_id IS NULL
+		  AND s.removed_at IS NULL
+		  AND s.document_id = ANY($1)
+		ORDER BY s.document_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunk documents: %w", err)
+	}
+	return scanMatchInputs(rows)
+}
@@ -0,0 +1,64 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ehdc-llpg/internal/match"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// fixedMatcher decides documents 1 and 2 as auto_accept and no_match
+type fixedMatcher struct{}
+
+func (fixedMatcher) ProcessDocument(ctx context.Context, localDebug bool, input MatchInput) (*MatchResult, error) {
+	if input.DocumentID != 1 {
+		return &MatchResult{DocumentID: input.DocumentID, Decision: "no_match"}, nil
+	}
+	candidates := []MatchCandidate{
+		{UPRN: "100062000001", Score: 0.96, MethodCode: "exact_text"},
+		{UPRN: "100062000002", Score: 0.81, MethodCode: "fuzzy_medium"},
+	}
+	return &MatchResult{DocumentID: 1, BestCandidate: &candidates[0], AllCandidates: candidates, Decision: "auto_accept"}, nil
+}
+
+func (fixedMatcher) SaveMatchResult(ctx context.Context, localDebug bool, result *MatchResult) error {
+	return nil
+}
+
+func TestMatchChunkExplainsEachDecision(t *testing.T) {
+	ctx := context.Background()
+	m := store.NewMemory()
+	fetch := func(ctx context.Context, ids []int64) ([]MatchInput, error) {
+		var inputs []MatchInput
+		for _, id := range ids {
+			inputs = append(inputs, MatchInput{DocumentID: id})
+		}
+		return inputs, nil
+	}
+
+	result, err := matchChunk(ctx, fixedMatcher{}, m, fetch, &store.WorkChunk{RunID: 9, Items: []string{"1", "2"}})
+	if err != nil {
+		t.Fatalf("matchChunk: %v", err)
+	}
+	if result.Processed != 2 || result.Matched != 1 {
+		t.Errorf("result = %+v, want 2 processed and 1 matched", result)
+	}
+
+	accepted, _ := m.LatestExplanation(ctx, 1)
+	if accepted == nil || accepted.RunID != 9 || accepted.AcceptedUPRN != "100062000001" {
+		t.Fatalf("explanation of document 1 = %+v", accepted)
+	}
+	candidates, _ := accepted.Candidates.([]match.CandidateExplanation)
+	if len(candidates) != 2 || !candidates[0].Selected || candidates[1].Selected {
+		t.Errorf("candidates = %+v, want the first selected", candidates)
+	}
+	if margin := candidates[0].Margin; margin < 0.149 || margin > 0.151 {
+		t.Errorf("margin = %.4f, want 0.15 to the runner-up", margin)
+	}
+
+	unmatched, _ := m.LatestExplanation(ctx, 2)
+	if unmatched == nil || unmatched.Decision != "no_match" || unmatched.Margin != nil {
+		t.Errorf("explanation of document 2 = %+v, want no_match without a margin", unmatched)
+	}
+}
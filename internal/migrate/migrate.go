@@ -0,0 +1,556 @@
+// Package migrate applies the versioned SQL files in migrations/ and records them in
+// schema_migrations. A migration's version is its file name without .sql, so files sharing
+// a number are distinct migrations applied in file name order.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/lib/pq"
+)
+
+// lockKey is the pg_advisory_lock key held while migrating ("EHDCMIGR")
+const lockKey int64 = 0x454844434d494752
+
+// noTransactionDirective lets a migration opt out of the wrapping transaction
+// (needed for CREATE INDEX CONCURRENTLY). Its bookkeeping is recorded after the script.
+const noTransactionDirective = "-- migrate:no-transaction"
+
+var (
+	filePattern   = regexp.MustCompile(`^([0-9]+)_[a-z0-9_]+\.sql$`)
+	beginPattern  = regexp.MustCompile(`(?im)^\s*BEGIN\s*;`)
+	commitPattern = regexp.MustCompile(`(?im)^\s*COMMIT\s*;`)
+
+	// replacesPattern marks a squashed migration, e.g. "-- migrate:replaces 001-042"
+	replacesPattern = regexp.MustCompile(`(?m)^--\s*migrate:replaces\s+([0-9]+)-([0-9]+)\s*$`)
+)
+
+// Migration is one up file and its optional down file
+type Migration struct {
+	Version  string // file name without .sql, e.g. 045_planning_ref_links
+	Number   string // numeric prefix
+	Up       string
+	Down     string // empty when the migration can't be reversed
+	Checksum string // sha256 of Up
+
+	// ReplacesFrom and ReplacesTo bound the numbers of the migrations a squashed
+	// migration replaces (migrate:replaces directive); empty otherwise
+	ReplacesFrom string
+	ReplacesTo   string
+}
+
+// HasDown reports whether the migration can be rolled back
+func (m Migration) HasDown() bool {
+	return m.Down != ""
+}
+
+// Replaces reports whether version is one of the migrations m was squashed from
+func (m Migration) Replaces(version string) bool {
+	if m.ReplacesFrom == "" {
+		return false
+	}
+	match := filePattern.FindStringSubmatch(version + ".sql")
+	if match == nil {
+		return false
+	}
+	number, _ := strconv.Atoi(match[1])
+	from, _ := strconv.Atoi(m.ReplacesFrom)
+	to, _ := strconv.Atoi(m.ReplacesTo)
+	return number >= from && number <= to
+}
+
+// Status is a migration and its state in the database
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Baseline  bool // recorded by Baseline without being run
+	Drift     bool // file changed since it was applied
+	Missing   bool // recorded in the database but no longer on disk
+
+	ReplacedBy string // for a missing migration, the squashed migration that replaces it
+}
+
+// Migrator applies migrations from a file system, normally migrations.FS
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations in source
+func NewMigrator(db *sql.DB, source fs.FS) (*Migrator, error) {
+	migrations, err := Load(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads NNN_name.sql and NNN_name.down.sql files, ordered by file name
+func Load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	downs := make(map[string]string)
+	var migrations []Migration
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+
+		data, err := fs.ReadFile(source, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		if strings.HasSuffix(name, ".down.sql") {
+			downs[strings.TrimSuffix(name, ".down.sql")] = string(data)
+			continue
+		}
+
+		m := filePattern.FindStringSubmatch(name)
+		if m == nil {
+			return nil, fmt.Errorf("migration %s does not match NNN_name.sql", name)
+		}
+		sum := sha256.Sum256(data)
+		migration := Migration{
+			Version:  strings.TrimSuffix(name, ".sql"),
+			Number:   m[1],
+			Up:       string(data),
+			Checksum: hex.EncodeToString(sum[:]),
+		}
+		if r := replacesPattern.FindStringSubmatch(migration.Up); r != nil {
+			from, _ := strconv.Atoi(r[1])
+			to, _ := strconv.Atoi(r[2])
+			if from > to {
+				return nil, fmt.Errorf("migration %s replaces an empty range %s-%s", name, r[1], r[2])
+			}
+			migration.ReplacesFrom, migration.ReplacesTo = r[1], r[2]
+		}
+		migrations = append(migrations, migration)
+	}
+
+	for i := range migrations {
+		if down, ok := downs[migrations[i].Version]; ok {
+			migrations[i].Down = down
+			delete(downs, migrations[i].Version)
+		}
+	}
+	for version := range downs {
+		return nil, fmt.Errorf("down migration %s.down.sql has no up migration", version)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrations returns every known migration in apply order
+func (mg *Migrator) Migrations() []Migration {
+	return mg.migrations
+}
+
+// SharedNumbers lists numeric prefixes used by more than one migration
+func (mg *Migrator) SharedNumbers() []string {
+	seen := make(map[string]int)
+	for _, m := range mg.migrations {
+		seen[m.Number]++
+	}
+	var shared []string
+	for number, n := range seen {
+		if n > 1 {
+			shared = append(shared, number)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+// ensureTable creates schema_migrations
+func (mg *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version       text PRIMARY KEY,
+			checksum      text NOT NULL,
+			applied_at    timestamptz NOT NULL DEFAULT now(),
+			execution_ms  bigint NOT NULL DEFAULT 0,
+			baseline      boolean NOT NULL DEFAULT false
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedRecord is a schema_migrations row
+type appliedRecord struct {
+	checksum  string
+	appliedAt time.Time
+	baseline  bool
+}
+
+func (mg *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[string]appliedRecord, error) {
+	applied := make(map[string]appliedRecord)
+
+	// A dry run or status check on a fresh database doesn't create the table
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check for schema_migrations: %w", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at, baseline FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		var r appliedRecord
+		if err := rows.Scan(&version, &r.checksum, &r.appliedAt, &r.baseline); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = r
+	}
+	return applied, rows.Err()
+}
+
+// Status reports every migration on disk and any recorded migration no longer on disk
+func (mg *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := mg.withConn(false, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mg.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		statuses = buildStatus(mg.migrations, applied)
+		return nil
+	})
+	return statuses, err
+}
+
+// buildStatus merges the migrations on disk with the applied records
+func buildStatus(migrations []Migration, applied map[string]appliedRecord) []Status {
+	var statuses []Status
+	known := make(map[string]bool)
+	for _, m := range migrations {
+		known[m.Version] = true
+		s := Status{Migration: m}
+		if r, ok := applied[m.Version]; ok {
+			s.Applied, s.AppliedAt, s.Baseline = true, r.appliedAt, r.baseline
+			s.Drift = r.checksum != m.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+	for version, r := range applied {
+		if !known[version] {
+			s := Status{
+				Migration: Migration{Version: version, Checksum: r.checksum},
+				Applied:   true, AppliedAt: r.appliedAt, Baseline: r.baseline, Missing: true,
+			}
+			for _, m := range migrations {
+				if m.Replaces(version) {
+					s.ReplacedBy = m.Version
+					break
+				}
+			}
+			statuses = append(statuses, s)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+// supersedesApplied reports whether m is a squashed migration replacing migrations that are
+// already applied, in which case Up records it as a baseline instead of running it
+func supersedesApplied(statuses []Status, m Migration) bool {
+	for _, s := range statuses {
+		if s.Applied && s.ReplacedBy == m.Version {
+			return true
+		}
+	}
+	return false
+}
+
+// planUp returns the pending migrations up to and including target ("" for all)
+func planUp(statuses []Status, target string) ([]Migration, error) {
+	if err := checkTarget(statuses, target); err != nil {
+		return nil, err
+	}
+	var plan []Migration
+	for _, s := range statuses {
+		if target != "" && s.Version > target {
+			break
+		}
+		if s.Drift {
+			return nil, fmt.Errorf("migration %s has changed since it was applied (checksum mismatch)", s.Version)
+		}
+		if !s.Applied && !s.Missing {
+			plan = append(plan, s.Migration)
+		}
+	}
+	return plan, nil
+}
+
+// planDown returns the applied migrations after target, newest first. With no target
+// only the most recent migration is rolled back.
+func planDown(statuses []Status, target string) ([]Migration, error) {
+	if err := checkTarget(statuses, target); err != nil {
+		return nil, err
+	}
+	var plan []Migration
+	for i := len(statuses) - 1; i >= 0; i-- {
+		s := statuses[i]
+		if !s.Applied {
+			continue
+		}
+		if target != "" && s.Version <= target {
+			break
+		}
+		if s.ReplacedBy != "" {
+			return nil, fmt.Errorf("migration %s was squashed into %s and can't be rolled back", s.Version, s.ReplacedBy)
+		}
+		if s.Missing {
+			return nil, fmt.Errorf("migration %s is applied but its file is missing", s.Version)
+		}
+		if !s.HasDown() {
+			return nil, fmt.Errorf("migration %s has no down migration (%s.down.sql)", s.Version, s.Version)
+		}
+		plan = append(plan, s.Migration)
+		if target == "" {
+			break
+		}
+	}
+	return plan, nil
+}
+
+func checkTarget(statuses []Status, target string) error {
+	if target == "" {
+		return nil
+	}
+	for _, s := range statuses {
+		if s.Version == target {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown migration %s", target)
+}
+
+// Up applies pending migrations up to target ("" for all). With dryRun set the plan is
+// returned without touching the database beyond reading schema_migrations.
+func (mg *Migrator) Up(localDebug bool, target string, dryRun bool) ([]Migration, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	var plan []Migration
+	err := mg.withConn(!dryRun, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mg.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		statuses := buildStatus(mg.migrations, applied)
+		plan, err = planUp(statuses, target)
+		if err != nil || dryRun {
+			return err
+		}
+
+		for _, m := range plan {
+			if supersedesApplied(statuses, m) {
+				if err := mg.recordBaseline(ctx, conn, m); err != nil {
+					return err
+				}
+				debug.DebugOutput(localDebug, "Baselined %s, its replaced migrations are applied", m.Version)
+				continue
+			}
+			debug.DebugOutput(localDebug, "Applying %s", m.Version)
+			record := fmt.Sprintf(`INSERT INTO schema_migrations (version, checksum, execution_ms) VALUES (%s, %s, 0)
+ON CONFLICT (version) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = now(), baseline = false`,
+				pq.QuoteLiteral(m.Version), pq.QuoteLiteral(m.Checksum))
+			if err := mg.run(ctx, conn, m.Version, m.Up, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// Down rolls back applied migrations after target, or the latest one when target is ""
+func (mg *Migrator) Down(localDebug bool, target string, dryRun bool) ([]Migration, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	var plan []Migration
+	err := mg.withConn(!dryRun, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mg.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		plan, err = planDown(buildStatus(mg.migrations, applied), target)
+		if err != nil || dryRun {
+			return err
+		}
+
+		for _, m := range plan {
+			debug.DebugOutput(localDebug, "Rolling back %s", m.Version)
+			record := fmt.Sprintf(`DELETE FROM schema_migrations WHERE version = %s`, pq.QuoteLiteral(m.Version))
+			if err := mg.run(ctx, conn, m.Version+".down", m.Down, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// Baseline records migrations up to target as applied without running them, for databases
+// built before schema_migrations existed
+func (mg *Migrator) Baseline(localDebug bool, target string, dryRun bool) ([]Migration, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	if target == "" {
+		return nil, fmt.Errorf("baseline needs a target migration")
+	}
+
+	var plan []Migration
+	err := mg.withConn(!dryRun, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mg.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		plan, err = planUp(buildStatus(mg.migrations, applied), target)
+		if err != nil || dryRun {
+			return err
+		}
+
+		for _, m := range plan {
+			if err := mg.recordBaseline(ctx, conn, m); err != nil {
+				return err
+			}
+			debug.DebugOutput(localDebug, "Baselined %s", m.Version)
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// recordBaseline records m as applied without running it
+func (mg *Migrator) recordBaseline(ctx context.Context, conn *sql.Conn, m Migration) error {
+	_, err := conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, checksum, baseline) VALUES ($1, $2, true)
+	`, m.Version, m.Checksum)
+	if err != nil {
+		return fmt.Errorf("failed to baseline %s: %w", m.Version, err)
+	}
+	return nil
+}
+
+// withRecord puts the bookkeeping statement record inside the transaction of a script that
+// manages its own (BEGIN; ... COMMIT;), just before its COMMIT, so the script's changes and
+// its schema_migrations row commit together
+func withRecord(name, script, record string) (string, error) {
+	if n := len(beginPattern.FindAllStringIndex(script, -1)); n > 1 {
+		return "", fmt.Errorf("migration %s has %d transactions; split it so each file has at most one", name, n)
+	}
+	commits := commitPattern.FindAllStringIndex(script, -1)
+	if len(commits) != 1 {
+		return "", fmt.Errorf("migration %s starts a transaction but has %d COMMITs, expected 1", name, len(commits))
+	}
+	at := commits[0][0]
+	return script[:at] + "\n" + record + ";\n" + script[at:], nil
+}
+
+// run executes one migration script and its bookkeeping statement in a single transaction:
+// the script's own (BEGIN; ... COMMIT;) when it has one, otherwise one opened here. Scripts
+// with the no-transaction directive run directly and are recorded after they succeed.
+func (mg *Migrator) run(ctx context.Context, conn *sql.Conn, name, script, record string) error {
+	start := time.Now()
+
+	switch {
+	case strings.Contains(script, noTransactionDirective):
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return fmt.Errorf("migration %s failed: %w", name, err)
+		}
+		if _, err := conn.ExecContext(ctx, record); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", name, err)
+		}
+	case beginPattern.MatchString(script):
+		wrapped, err := withRecord(name, script, record)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, wrapped); err != nil {
+			// Leave the pooled connection usable if the script failed inside its own transaction
+			conn.ExecContext(ctx, "ROLLBACK")
+			return fmt.Errorf("migration %s failed: %w", name, err)
+		}
+	default:
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s failed: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, record); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %s: %w", name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", name, err)
+		}
+	}
+
+	// Best effort: the row is gone after a down migration
+	conn.ExecContext(ctx, `UPDATE schema_migrations SET execution_ms = $2 WHERE version = $1`,
+		strings.TrimSuffix(name, ".down"), time.Since(start).Milliseconds())
+	return nil
+}
+
+// withConn runs fn on a dedicated connection. With lock set (any change to the schema) it
+// holds the migration advisory lock and makes sure schema_migrations exists.
+func (mg *Migrator) withConn(lock bool, fn func(context.Context, *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := mg.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if lock {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockKey).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if !locked {
+			return fmt.Errorf("another process is running migrations")
+		}
+		defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockKey)
+
+		if err := mg.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+	}
+
+	return fn(ctx, conn)
+}
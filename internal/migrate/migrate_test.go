@@ -0,0 +1,298 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ehdc-llpg/migrations"
+	"github.com/lib/pq"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"001_base.sql":                 {Data: []byte("CREATE TABLE a (id int);")},
+		"001_base.down.sql":            {Data: []byte("DROP TABLE a;")},
+		"002_add_b.sql":                {Data: []byte("BEGIN;\nCREATE TABLE b (id int);\nCOMMIT;")},
+		"003_grouping.sql":             {Data: []byte("CREATE TABLE c (id int);")},
+		"003_simple_grouping.sql":      {Data: []byte("CREATE TABLE d (id int);")},
+		"003_simple_grouping.down.sql": {Data: []byte("DROP TABLE d;")},
+		"embed.go":                     {Data: []byte("package migrations")},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ms, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var versions []string
+	for _, m := range ms {
+		versions = append(versions, m.Version)
+	}
+	want := "001_base 002_add_b 003_grouping 003_simple_grouping"
+	if got := strings.Join(versions, " "); got != want {
+		t.Errorf("versions = %q, want %q", got, want)
+	}
+
+	if !ms[0].HasDown() || ms[1].HasDown() || ms[2].HasDown() || !ms[3].HasDown() {
+		t.Errorf("down files paired incorrectly")
+	}
+	if ms[0].Checksum == "" || ms[0].Checksum == ms[2].Checksum {
+		t.Errorf("checksums not distinct: %q, %q", ms[0].Checksum, ms[2].Checksum)
+	}
+
+	mg := &Migrator{migrations: ms}
+	if got := mg.SharedNumbers(); len(got) != 1 || got[0] != "003" {
+		t.Errorf("SharedNumbers = %v, want [003]", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	orphan := testFS()
+	orphan["004_missing.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE e;")}
+	if _, err := Load(orphan); err == nil {
+		t.Errorf("expected error for down file without up file")
+	}
+
+	badName := testFS()
+	badName["Fix-Things.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	if _, err := Load(badName); err == nil {
+		t.Errorf("expected error for badly named migration")
+	}
+}
+
+func TestPlanUp(t *testing.T) {
+	ms, _ := Load(testFS())
+	applied := map[string]appliedRecord{
+		"001_base": {checksum: ms[0].Checksum},
+	}
+
+	plan, err := planUp(buildStatus(ms, applied), "")
+	if err != nil {
+		t.Fatalf("planUp: %v", err)
+	}
+	if len(plan) != 3 || plan[0].Version != "002_add_b" {
+		t.Errorf("planUp = %v, want 002..003_simple_grouping", plan)
+	}
+
+	plan, err = planUp(buildStatus(ms, applied), "003_grouping")
+	if err != nil || len(plan) != 2 {
+		t.Errorf("planUp to 003_grouping = %d migrations (%v), want 2", len(plan), err)
+	}
+
+	if _, err := planUp(buildStatus(ms, applied), "009_unknown"); err == nil {
+		t.Errorf("expected error for unknown target")
+	}
+
+	applied["001_base"] = appliedRecord{checksum: "edited"}
+	if _, err := planUp(buildStatus(ms, applied), ""); err == nil {
+		t.Errorf("expected error for checksum drift")
+	}
+}
+
+func TestPlanDown(t *testing.T) {
+	ms, _ := Load(testFS())
+	applied := make(map[string]appliedRecord)
+	for _, m := range ms {
+		applied[m.Version] = appliedRecord{checksum: m.Checksum}
+	}
+
+	plan, err := planDown(buildStatus(ms, applied), "")
+	if err != nil || len(plan) != 1 || plan[0].Version != "003_simple_grouping" {
+		t.Errorf("planDown = %v (%v), want 003_simple_grouping only", plan, err)
+	}
+
+	// 003_grouping has no down file
+	if _, err := planDown(buildStatus(ms, applied), "001_base"); err == nil {
+		t.Errorf("expected error rolling back a migration without a down file")
+	}
+
+	applied["099_gone"] = appliedRecord{checksum: "x"}
+	statuses := buildStatus(ms, applied)
+	if last := statuses[len(statuses)-1]; !last.Missing {
+		t.Errorf("099_gone should be reported missing")
+	}
+	if _, err := planDown(statuses, ""); err == nil {
+		t.Errorf("expected error rolling back a migration whose file is missing")
+	}
+}
+
+func TestReplaces(t *testing.T) {
+	source := testFS()
+	source["000_baseline.sql"] = &fstest.MapFile{Data: []byte("-- migrate:replaces 1-3\nBEGIN;\nCREATE TABLE a (id int);\nCOMMIT;")}
+	delete(source, "001_base.sql")
+	delete(source, "001_base.down.sql")
+	ms, err := Load(source)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	baseline := ms[0]
+	if baseline.ReplacesFrom != "1" || baseline.ReplacesTo != "3" {
+		t.Fatalf("replaces = %s-%s, want 1-3", baseline.ReplacesFrom, baseline.ReplacesTo)
+	}
+	for version, want := range map[string]bool{"001_base": true, "003_grouping": true, "000_baseline": false, "004_later": false} {
+		if got := baseline.Replaces(version); got != want {
+			t.Errorf("Replaces(%s) = %v, want %v", version, got, want)
+		}
+	}
+
+	// A fresh database runs the baseline
+	statuses := buildStatus(ms, map[string]appliedRecord{})
+	if supersedesApplied(statuses, baseline) {
+		t.Errorf("baseline should run on a database without the replaced migrations")
+	}
+
+	// A database that ran the old files records it without running it
+	applied := map[string]appliedRecord{"001_base": {checksum: "x"}}
+	statuses = buildStatus(ms, applied)
+	if !supersedesApplied(statuses, baseline) {
+		t.Errorf("baseline should be recorded when a replaced migration is applied")
+	}
+	for _, s := range statuses {
+		if s.Version == "001_base" && s.ReplacedBy != "000_baseline" {
+			t.Errorf("001_base ReplacedBy = %q, want 000_baseline", s.ReplacedBy)
+		}
+	}
+	if _, err := planDown(statuses, ""); err == nil || !strings.Contains(err.Error(), "squashed") {
+		t.Errorf("planDown over a replaced migration = %v, want squashed error", err)
+	}
+
+	bad := testFS()
+	bad["000_baseline.sql"] = &fstest.MapFile{Data: []byte("-- migrate:replaces 5-2\nSELECT 1;")}
+	if _, err := Load(bad); err == nil {
+		t.Errorf("expected error for an empty replaces range")
+	}
+}
+
+func TestWithRecord(t *testing.T) {
+	script := "BEGIN;\nCREATE TABLE b (id int);\nCOMMIT;\nSELECT 'done' as result;"
+	got, err := withRecord("002_add_b", script, "INSERT INTO schema_migrations (version) VALUES ('002_add_b')")
+	if err != nil {
+		t.Fatalf("withRecord: %v", err)
+	}
+	record := strings.Index(got, "INSERT INTO schema_migrations")
+	commit := strings.Index(got, "COMMIT;")
+	if record < 0 || record > commit || record < strings.Index(got, "CREATE TABLE b") {
+		t.Errorf("record not placed inside the transaction:\n%s", got)
+	}
+
+	if _, err := withRecord("x", "BEGIN;\nSELECT 1;\nCOMMIT;\nBEGIN;\nSELECT 2;\nCOMMIT;", "SELECT 3"); err == nil {
+		t.Errorf("expected error for a script with two transactions")
+	}
+	if _, err := withRecord("x", "BEGIN;\nSELECT 1;", "SELECT 3"); err == nil {
+		t.Errorf("expected error for a script without COMMIT")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load(migrations.FS): %v", err)
+	}
+	if len(ms) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+
+	mg := &Migrator{migrations: ms}
+	if shared := mg.SharedNumbers(); len(shared) != 0 {
+		t.Errorf("SharedNumbers = %v, want none", shared)
+	}
+
+	if ms[0].Version != "000_baseline" || !ms[0].Replaces("041_simple_planning_grouping") || ms[0].Replaces("043_llpg_lifecycle_dates") {
+		t.Errorf("first migration %s should be the baseline replacing 001-042", ms[0].Version)
+	}
+
+	for _, m := range ms[1:] {
+		if !m.HasDown() {
+			t.Errorf("migration %s has no down file", m.Version)
+		}
+	}
+	for _, m := range ms {
+		for name, script := range map[string]string{m.Version: m.Up, m.Version + ".down": m.Down} {
+			if script == "" || !beginPattern.MatchString(script) {
+				continue
+			}
+			if _, err := withRecord(name, script, "SELECT 1"); err != nil {
+				t.Errorf("%v", err)
+			}
+		}
+	}
+}
+
+// TestMigrationsApplyToEmptyDatabase applies every migration to an empty schema, rolls
+// back to the baseline and applies them again. It needs a PostgreSQL server with PostGIS:
+// set MIGRATE_TEST_DATABASE_URL to run it.
+func TestMigrationsApplyToEmptyDatabase(t *testing.T) {
+	dsn := os.Getenv("MIGRATE_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("MIGRATE_TEST_DATABASE_URL not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer admin.Close()
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + pq.QuoteIdentifier(schema)); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	defer admin.Exec(`DROP SCHEMA ` + pq.QuoteIdentifier(schema) + ` CASCADE`)
+
+	// Every connection of the migrator resolves unqualified names in the scratch schema
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+	}
+	db, err := sql.Open("postgres", dsn+sep+"search_path="+schema+",public")
+	if err != nil {
+		t.Fatalf("open scratch schema: %v", err)
+	}
+	defer db.Close()
+
+	mg, err := NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	all := mg.Migrations()
+
+	plan, err := mg.Up(false, "", false)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(plan) != len(all) {
+		t.Errorf("Up applied %d migrations, want %d", len(plan), len(all))
+	}
+
+	statuses, err := mg.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied || s.Baseline || s.Missing {
+			t.Errorf("%s: applied=%v baseline=%v missing=%v after Up", s.Version, s.Applied, s.Baseline, s.Missing)
+		}
+	}
+
+	plan, err = mg.Down(false, all[0].Version, false)
+	if err != nil {
+		t.Fatalf("Down to %s: %v", all[0].Version, err)
+	}
+	if len(plan) != len(all)-1 {
+		t.Errorf("Down rolled back %d migrations, want %d", len(plan), len(all)-1)
+	}
+
+	if _, err := mg.Up(false, "", false); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+}
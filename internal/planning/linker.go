@@ -0,0 +1,403 @@
+package planning
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/match"
+	"github.com/ehdc-llpg/internal/review"
+	"github.com/ehdc-llpg/internal/store"
+	"github.com/lib/pq"
+)
+
+// MethodInheritedViaPlanningRef is the match method recorded for linked documents
+const MethodInheritedViaPlanningRef = "inherited_via_planning_ref"
+
+// linkerActor is recorded as accepted_by/decided_by on the linker's writes
+const linkerActor = "planning_ref_linker"
+
+// Linking thresholds
+const (
+	baseInheritedConfidence = 0.80
+	sequenceMatchBonus      = 0.05 // a decision for the exact same sub-application
+	corroborationBonus      = 0.05 // two or more decisions agree on the UPRN
+	MinUPRNAgreement        = 0.6  // share of decisions that must agree before inheriting
+)
+
+// Source types that carry a planning reference but no address
+var referenceOnlySourceTypes = []string{"microfiche_pre_1974", "microfiche_post_1974"}
+
+// Linker locates reference-only documents through decision notices in the same planning group
+type Linker struct {
+	db      *sql.DB
+	matches store.MatchStore
+	review  *review.Service
+}
+
+// NewLinker creates a new planning reference linker; its accepts follow reviews' policy
+func NewLinker(db *sql.DB, reviews *review.Service) *Linker {
+	return &Linker{db: db, matches: store.NewPostgres(db), review: reviews}
+}
+
+// LinkResult summarises a linking run
+type LinkResult struct {
+	Candidates int // reference-only documents considered
+	Linked     int // new or changed links accepted
+	Proposed   int // new or changed links left for a reviewer
+	Unchanged  int // links as the previous run left them
+	Unlinked   int // previous links that no longer hold
+	Ambiguous  int // decisions disagree on the UPRN
+	Rejected   int // a reviewer rejected the inherited UPRN
+	NoDecision int // no matched decision notice in the planning group
+}
+
+// decisionEvidence is a matched decision notice in a planning group
+type decisionEvidence struct {
+	srcID          int64
+	base, sequence string
+	uprn           string
+}
+
+// link is a planning_ref_link row
+type link struct {
+	srcID, sourceSrcID      int64
+	base, sequence, uprn    string
+	confidence              float64
+	supporting, conflicting int
+}
+
+// InheritedConfidence scores an inherited match from how many decisions in the group agree
+func InheritedConfidence(agree, total int, sequenceMatch bool) float64 {
+	if agree == 0 || total == 0 {
+		return 0
+	}
+
+	confidence := baseInheritedConfidence
+	if sequenceMatch {
+		confidence += sequenceMatchBonus
+	}
+	if agree >= 2 {
+		confidence += corroborationBonus
+	}
+	confidence *= float64(agree) / float64(total)
+
+	return math.Round(confidence*10000) / 10000
+}
+
+// Link inherits UPRN and coordinates for microfiche records from decision notices in the
+// same migration 041 planning group. Only links that changed since the last run are written;
+// links that no longer hold are removed with the accepts they made. Accepts go through the
+// review rules: a document waiting in the review queue, or a link below the sign-off
+// confidence, gets the inherited UPRN as a candidate for review instead. Matches accepted by
+// any other method, or by a reviewer, are never overwritten.
+func (l *Linker) Link(ctx context.Context, localDebug bool) (*LinkResult, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	result := &LinkResult{}
+
+	evidence, err := l.loadDecisionEvidence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	debug.DebugOutput(localDebug, "Loaded matched decision notices for %d planning groups", len(evidence))
+
+	previous, err := l.loadLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT s.src_id, s.planning_app_base, COALESCE(s.planning_app_sequence, ''),
+		       COALESCE(s.address_canonical, '')
+		FROM src_document s
+		LEFT JOIN match_accepted ma ON ma.src_id = s.src_id
+		WHERE s.source_type::text = ANY($1)
+		  AND s.planning_app_base IS NOT NULL AND s.planning_app_base != ''
+		  AND (ma.src_id IS NULL OR (ma.method = $2 AND NOT ma.manual))
+		ORDER BY s.src_id
+	`, pq.Array(referenceOnlySourceTypes), MethodInheritedViaPlanningRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reference-only documents: %w", err)
+	}
+
+	type candidate struct {
+		srcID          int64
+		base, sequence string
+		canonical      string
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.srcID, &c.base, &c.sequence, &c.canonical); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan reference-only document: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reference-only documents: %w", err)
+	}
+
+	current := make(map[int64]bool)
+	for _, c := range candidates {
+		result.Candidates++
+
+		group := groupOf(c.base, c.sequence)
+		decisions := evidence[group.Base]
+		if len(decisions) == 0 {
+			result.NoDecision++
+			continue
+		}
+
+		uprn, agree := majorityUPRN(decisions)
+		if float64(agree)/float64(len(decisions)) < MinUPRNAgreement {
+			result.Ambiguous++
+			debug.DebugOutput(localDebug, "src_id %d: decisions for %s disagree (%d/%d)", c.srcID, group.Base, agree, len(decisions))
+			continue
+		}
+
+		rejections, err := match.LoadRejectionsFrom(ctx, l.matches, c.srcID, c.canonical)
+		if err != nil {
+			return nil, err
+		}
+		if rejections.Rejected(uprn) {
+			result.Rejected++
+			debug.DebugOutput(localDebug, "src_id %d: UPRN %s was rejected by a reviewer", c.srcID, uprn)
+			continue
+		}
+
+		// Prefer the decision for the same sub-application as the source of the link
+		sourceSrcID, sequenceMatch := int64(0), false
+		for _, e := range decisions {
+			if e.uprn != uprn {
+				continue
+			}
+			if group.SameApplication(groupOf(e.base, e.sequence)) {
+				sourceSrcID, sequenceMatch = e.srcID, true
+				break
+			}
+			if sourceSrcID == 0 {
+				sourceSrcID = e.srcID
+			}
+		}
+
+		lk := link{
+			srcID:       c.srcID,
+			sourceSrcID: sourceSrcID,
+			base:        c.base,
+			sequence:    c.sequence,
+			uprn:        uprn,
+			confidence:  InheritedConfidence(agree, len(decisions), sequenceMatch),
+			supporting:  agree,
+			conflicting: len(decisions) - agree,
+		}
+		current[lk.srcID] = true
+
+		if prev, ok := previous[lk.srcID]; ok && prev == lk {
+			result.Unchanged++
+			continue
+		}
+
+		proposed, err := l.apply(ctx, lk)
+		if err != nil {
+			return nil, err
+		}
+		if proposed {
+			result.Proposed++
+		} else {
+			result.Linked++
+		}
+	}
+
+	for srcID := range previous {
+		if current[srcID] {
+			continue
+		}
+		if err := l.unlink(ctx, srcID); err != nil {
+			return nil, err
+		}
+		result.Unlinked++
+	}
+
+	debug.DebugOutput(localDebug, "Linked %d, proposed %d, unchanged %d, unlinked %d of %d reference-only documents",
+		result.Linked, result.Proposed, result.Unchanged, result.Unlinked, result.Candidates)
+	return result, nil
+}
+
+// apply records a new or changed link and accepts it, or leaves it for a reviewer when the
+// review rules do not allow an automatic accept; proposed is true in that case
+func (l *Linker) apply(ctx context.Context, lk link) (proposed bool, err error) {
+	_, err = l.db.ExecContext(ctx, `
+		INSERT INTO planning_ref_link (
+			src_id, source_src_id, planning_app_base, planning_app_sequence, uprn,
+			easting, northing, method, confidence, supporting_docs, conflicting_docs
+		)
+		VALUES (
+			$1, $2, $3, NULLIF($4, ''), $5,
+			(SELECT easting FROM dim_address WHERE uprn = $5 LIMIT 1),
+			(SELECT northing FROM dim_address WHERE uprn = $5 LIMIT 1),
+			$6, $7, $8, $9
+		)
+		ON CONFLICT (src_id) DO UPDATE SET
+			source_src_id = EXCLUDED.source_src_id,
+			planning_app_base = EXCLUDED.planning_app_base,
+			planning_app_sequence = EXCLUDED.planning_app_sequence,
+			uprn = EXCLUDED.uprn,
+			easting = EXCLUDED.easting,
+			northing = EXCLUDED.northing,
+			method = EXCLUDED.method,
+			confidence = EXCLUDED.confidence,
+			supporting_docs = EXCLUDED.supporting_docs,
+			conflicting_docs = EXCLUDED.conflicting_docs,
+			linked_at = now()
+	`, lk.srcID, lk.sourceSrcID, lk.base, lk.sequence, lk.uprn,
+		MethodInheritedViaPlanningRef, lk.confidence, lk.supporting, lk.conflicting)
+	if err != nil {
+		return false, fmt.Errorf("failed to record planning link for src_id %d: %w", lk.srcID, err)
+	}
+
+	queued, err := l.review.Direct(ctx, lk.srcID, true, lk.confidence)
+	if err != nil && !errors.Is(err, review.ErrNeedsSignoff) {
+		return false, err
+	}
+	if queued || err != nil {
+		// The previous inherited accept, if any, no longer holds
+		if err := l.unaccept(ctx, lk.srcID); err != nil {
+			return false, err
+		}
+		now := time.Now()
+		return true, l.matches.SaveResult(ctx, &store.MatchResult{
+			SrcID:         lk.srcID,
+			CandidateUPRN: lk.uprn,
+			Method:        MethodInheritedViaPlanningRef,
+			Score:         lk.confidence,
+			Confidence:    lk.confidence,
+			TieRank:       1,
+			Decided:       true,
+			Decision:      "needs_review",
+			DecidedBy:     linkerActor,
+			DecidedAt:     &now,
+			Notes: fmt.Sprintf("Inherited from decision notice %d in planning group %s (%d of %d agree)",
+				lk.sourceSrcID, lk.base, lk.supporting, lk.supporting+lk.conflicting),
+		})
+	}
+
+	return false, l.matches.Accept(ctx, store.Acceptance{
+		SrcID:      lk.srcID,
+		UPRN:       lk.uprn,
+		Method:     MethodInheritedViaPlanningRef,
+		Score:      lk.confidence,
+		Confidence: lk.confidence,
+		AcceptedBy: linkerActor,
+	})
+}
+
+// unlink removes a link that no longer holds and the accept it made
+func (l *Linker) unlink(ctx context.Context, srcID int64) error {
+	if _, err := l.db.ExecContext(ctx, `DELETE FROM planning_ref_link WHERE src_id = $1`, srcID); err != nil {
+		return fmt.Errorf("failed to remove planning link for src_id %d: %w", srcID, err)
+	}
+	return l.unaccept(ctx, srcID)
+}
+
+// unaccept removes the linker's own accept of the document; a reviewer's accept of the
+// inherited UPRN is kept
+func (l *Linker) unaccept(ctx context.Context, srcID int64) error {
+	_, err := l.db.ExecContext(ctx, `
+		DELETE FROM match_accepted
+		WHERE src_id = $1 AND method = $2 AND NOT manual
+	`, srcID, MethodInheritedViaPlanningRef)
+	if err != nil {
+		return fmt.Errorf("failed to remove inherited match for src_id %d: %w", srcID, err)
+	}
+	return nil
+}
+
+// loadLinks returns the links recorded by the previous run, by document
+func (l *Linker) loadLinks(ctx context.Context) (map[int64]link, error) {
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT src_id, source_src_id, planning_app_base, COALESCE(planning_app_sequence, ''), uprn,
+		       confidence::float8, supporting_docs, conflicting_docs
+		FROM planning_ref_link
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query planning links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make(map[int64]link)
+	for rows.Next() {
+		var lk link
+		if err := rows.Scan(&lk.srcID, &lk.sourceSrcID, &lk.base, &lk.sequence, &lk.uprn,
+			&lk.confidence, &lk.supporting, &lk.conflicting); err != nil {
+			return nil, fmt.Errorf("failed to scan planning link: %w", err)
+		}
+		links[lk.srcID] = lk
+	}
+
+	return links, rows.Err()
+}
+
+// loadDecisionEvidence returns matched decision notices by planning group
+func (l *Linker) loadDecisionEvidence(ctx context.Context) (map[string][]decisionEvidence, error) {
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT s.src_id, s.planning_app_base, COALESCE(s.planning_app_sequence, ''), ma.uprn
+		FROM src_document s
+		JOIN match_accepted ma ON ma.src_id = s.src_id
+		WHERE s.source_type = 'decision'
+		  AND s.planning_app_base IS NOT NULL AND s.planning_app_base != ''
+		  AND ma.uprn IS NOT NULL AND ma.uprn != ''
+		  AND ma.method != $1
+	`, MethodInheritedViaPlanningRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matched decision notices: %w", err)
+	}
+	defer rows.Close()
+
+	evidence := make(map[string][]decisionEvidence)
+	for rows.Next() {
+		var e decisionEvidence
+		if err := rows.Scan(&e.srcID, &e.base, &e.sequence, &e.uprn); err != nil {
+			return nil, fmt.Errorf("failed to scan decision notice: %w", err)
+		}
+		group := groupOf(e.base, e.sequence).Base
+		evidence[group] = append(evidence[group], e)
+	}
+
+	return evidence, rows.Err()
+}
+
+// groupOf is the planning group a document's migration 041 base and sequence fall in. The
+// base is normalised so that spellings of one application agree: "PRD1234-5" (041 base
+// "PRD1234-5", no sequence) and "PRD1234/5" (base "PRD1234", sequence "5") are both
+// PRD1234/5. A base in no known format is its own group.
+func groupOf(base, sequence string) Reference {
+	ref, ok := ParseReference(base)
+	if !ok {
+		return Reference{Base: base, Sequence: sequence}
+	}
+	if sequence != "" {
+		ref.Sequence = normaliseSequence(sequence)
+	}
+	return ref
+}
+
+// majorityUPRN returns the most common UPRN in a group and how many decisions carry it
+func majorityUPRN(group []decisionEvidence) (string, int) {
+	counts := make(map[string]int)
+	best, bestCount := "", 0
+	for _, e := range group {
+		counts[e.uprn]++
+		if c := counts[e.uprn]; c > bestCount || (c == bestCount && e.uprn < best) {
+			best, bestCount = e.uprn, c
+		}
+	}
+	return best, bestCount
+}
@@ -0,0 +1,110 @@
+package planning
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reference formats EHDC has used over the decades
+const (
+	FormatDistrict = "pre_1974_district" // ALR123, AUD12A, PEU45-2, PRD1234-5 (former district councils)
+	FormatNumeric  = "ehdc_numeric"      // 20003/001, F20003/001, F.20003
+	FormatSlashed  = "legacy_slashed"    // PT/SN/12/9/109, TOPME/10/6
+	FormatUnknown  = "unknown"
+)
+
+// District prefixes used before the 1974 reorganisation
+var districtPrefixes = map[string]string{
+	"ALR": "Alton Rural District",
+	"AUD": "Alton Urban District",
+	"PEU": "Petersfield Urban District",
+	"PRD": "Petersfield Rural District",
+}
+
+var (
+	copyMarkerPattern = regexp.MustCompile(`\s*\(\d+\)\s*$`)
+	letterDotPattern  = regexp.MustCompile(`^([A-Z]{1,3})[.\s]+(\d)`)
+	districtPattern   = regexp.MustCompile(`^(ALR|AUD|PEU|PRD)\s*0*(\d+)(.*)$`)
+	numericPattern    = regexp.MustCompile(`^([A-Z]{0,2})(\d{3,})(?:/(.*))?$`)
+	slashedPattern    = regexp.MustCompile(`^[A-Z]+(/[A-Z0-9]+)*/\d+$`)
+)
+
+// Reference is a parsed planning application reference
+type Reference struct {
+	Raw        string
+	Normalised string // canonical form, e.g. PRD1234/5
+	Base       string // application the reference belongs to
+	Sequence   string // sub-application / suffix within the base
+	Format     string
+	District   string // former district council for pre-1974 references
+}
+
+// ParseReference parses and normalises a planning reference.
+// The base follows the migration 041 grouping: "20003/001" has base "20003", sequence "001".
+func ParseReference(raw string) (Reference, bool) {
+	ref := Reference{Raw: raw, Format: FormatUnknown}
+
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	s = strings.ReplaceAll(s, `\`, "/")
+	s = copyMarkerPattern.ReplaceAllString(s, "") // "(2)" copy/sheet markers aren't part of the reference
+	s = letterDotPattern.ReplaceAllString(s, "$1$2")
+	s = strings.Trim(s, " /-")
+	if s == "" {
+		return ref, false
+	}
+
+	if m := districtPattern.FindStringSubmatch(s); m != nil {
+		ref.Format = FormatDistrict
+		ref.District = districtPrefixes[m[1]]
+		ref.Base = m[1] + m[2]
+		ref.Sequence = normaliseSequence(m[3])
+		ref.Normalised = joinReference(ref.Base, ref.Sequence)
+		return ref, true
+	}
+
+	compact := strings.Join(strings.Fields(s), "")
+
+	if m := numericPattern.FindStringSubmatch(compact); m != nil {
+		ref.Format = FormatNumeric
+		ref.Base = m[1] + m[2]
+		ref.Sequence = normaliseSequence(m[3])
+		ref.Normalised = joinReference(ref.Base, ref.Sequence)
+		return ref, true
+	}
+
+	if slashedPattern.MatchString(compact) {
+		parts := strings.Split(compact, "/")
+		ref.Format = FormatSlashed
+		ref.Base = strings.Join(parts[:len(parts)-1], "/")
+		ref.Sequence = parts[len(parts)-1]
+		ref.Normalised = compact
+		return ref, true
+	}
+
+	return ref, false
+}
+
+// SameApplication reports whether two references name the same application, ignoring
+// zero padding in the sequence (001 == 1)
+func (r Reference) SameApplication(other Reference) bool {
+	if r.Base == "" || r.Base != other.Base {
+		return false
+	}
+	return strings.TrimLeft(r.Sequence, "0") == strings.TrimLeft(other.Sequence, "0")
+}
+
+// normaliseSequence tidies the text after the application number: "-4", "/4", " 4" and
+// "A" suffixes all become a bare sequence, with "&" lists and "LB" markers kept
+func normaliseSequence(s string) string {
+	s = strings.Join(strings.Fields(s), "")
+	s = strings.Trim(s, "-/.")
+	s = strings.ReplaceAll(s, "/", "-")
+	return s
+}
+
+func joinReference(base, sequence string) string {
+	if sequence == "" {
+		return base
+	}
+	return base + "/" + sequence
+}
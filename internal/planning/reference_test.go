@@ -0,0 +1,65 @@
+package planning
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		raw          string
+		wantFormat   string
+		wantBase     string
+		wantSequence string
+	}{
+		{"AUD1", FormatDistrict, "AUD1", ""},
+		{"PRD1234-5", FormatDistrict, "PRD1234", "5"},
+		{"prd 1234/5", FormatDistrict, "PRD1234", "5"},
+		{"PRD0123-4 (2)", FormatDistrict, "PRD123", "4"},
+		{"ALR56A", FormatDistrict, "ALR56", "A"},
+		{"PEU12-A-3", FormatDistrict, "PEU12", "A-3"},
+		{"AUD45-LB2", FormatDistrict, "AUD45", "LB2"},
+		{"20003/001", FormatNumeric, "20003", "001"},
+		{"F.20003/001", FormatNumeric, "F20003", "001"},
+		{"F 20003", FormatNumeric, "F20003", ""},
+		{"PT/SN/12/9/109", FormatSlashed, "PT/SN/12/9", "109"},
+		{"TOPME/10/6", FormatSlashed, "TOPME/10", "6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			ref, ok := ParseReference(tt.raw)
+			if !ok {
+				t.Fatalf("ParseReference(%q) failed", tt.raw)
+			}
+			if ref.Format != tt.wantFormat || ref.Base != tt.wantBase || ref.Sequence != tt.wantSequence {
+				t.Errorf("ParseReference(%q) = (%s, %q, %q), want (%s, %q, %q)",
+					tt.raw, ref.Format, ref.Base, ref.Sequence, tt.wantFormat, tt.wantBase, tt.wantSequence)
+			}
+		})
+	}
+
+	for _, raw := range []string{"", "N/A", "SEE FILE"} {
+		if _, ok := ParseReference(raw); ok {
+			t.Errorf("ParseReference(%q) should not parse", raw)
+		}
+	}
+}
+
+func TestSameApplication(t *testing.T) {
+	a, _ := ParseReference("20003/001")
+	b, _ := ParseReference("20003/1")
+	c, _ := ParseReference("20003/002")
+	if !a.SameApplication(b) || a.SameApplication(c) {
+		t.Errorf("SameApplication wrong for %+v / %+v / %+v", a, b, c)
+	}
+}
+
+func TestInheritedConfidence(t *testing.T) {
+	if got := InheritedConfidence(1, 1, false); got != 0.80 {
+		t.Errorf("single decision = %v, want 0.80", got)
+	}
+	if got := InheritedConfidence(3, 3, true); got != 0.90 {
+		t.Errorf("corroborated exact application = %v, want 0.90", got)
+	}
+	if got := InheritedConfidence(3, 4, false); got != 0.6375 {
+		t.Errorf("partial agreement = %v, want 0.6375", got)
+	}
+}
@@ -0,0 +1,475 @@
+// Package profile reports on the shape of a source CSV before it is loaded: fill rates,
+// value patterns, likely types, date formats, postcode and UPRN validity and address
+// suitability, so problems are found before a full load and match.
+package profile
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/etl"
+	"github.com/ehdc-llpg/internal/normalize"
+	"github.com/ehdc-llpg/internal/validation"
+	"github.com/lib/pq"
+)
+
+// Column roles drive the role-specific checks
+const (
+	RoleAddress  = "address"
+	RolePostcode = "postcode"
+	RoleUPRN     = "uprn"
+	RoleDate     = "date"
+)
+
+// Likely column types
+const (
+	TypeEmpty    = "empty"
+	TypeInteger  = "integer"
+	TypeDecimal  = "decimal"
+	TypeDate     = "date"
+	TypePostcode = "postcode"
+	TypeText     = "text"
+)
+
+const (
+	maxDistinct    = 100000 // distinct values tracked exactly per column
+	maxPatterns    = 10     // patterns reported per column
+	maxPatternLen  = 30     // longer patterns are truncated
+	typeThreshold  = 0.9    // share of values needed to call a type
+	uprnCheckBatch = 10000
+)
+
+var (
+	postcodePattern    = regexp.MustCompile(`^[A-Z]{1,2}[0-9][0-9A-Z]?\s*[0-9][ABD-HJLNP-UW-Z]{2}$`)
+	integerPattern     = regexp.MustCompile(`^-?[0-9]+$`)
+	decimalPattern     = regexp.MustCompile(`^-?[0-9]*\.[0-9]+$`)
+	slashedDatePattern = regexp.MustCompile(`^([0-9]{1,2})([/.\-])([0-9]{1,2})([/.\-])([0-9]{2}|[0-9]{4})$`)
+	isoDatePattern     = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+)
+
+// Report is the profile of one CSV file
+type Report struct {
+	File       string           `json:"file"`
+	SourceType string           `json:"source_type,omitempty"`
+	Rows       int              `json:"rows"`
+	BadRows    int              `json:"bad_rows"` // unparseable or short records
+	Columns    []*ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile describes one CSV column
+type ColumnProfile struct {
+	Name           string         `json:"name"`
+	Role           string         `json:"role,omitempty"`
+	NonEmpty       int            `json:"non_empty"`
+	FillRate       float64        `json:"fill_rate"`
+	Distinct       int            `json:"distinct"`
+	DistinctCapped bool           `json:"distinct_capped,omitempty"` // more than maxDistinct values
+	LikelyType     string         `json:"likely_type"`
+	Patterns       []PatternCount `json:"patterns"`
+	DateFormats    map[string]int `json:"date_formats,omitempty"`
+	DateOrder      string         `json:"date_order,omitempty"` // dmy, mdy or mixed
+
+	PostcodeValidRate *float64        `json:"postcode_valid_rate,omitempty"`
+	UPRN              *UPRNProfile    `json:"uprn,omitempty"`
+	Address           *AddressProfile `json:"address,omitempty"`
+
+	distinct   map[string]struct{}
+	patterns   map[string]int
+	types      map[string]int
+	dateValues []string
+	serials    int
+	artefacts  int
+	postcodeOK int
+	uprns      map[string]struct{}
+}
+
+// PatternCount is a value shape (A = letter, 9 = digit) and how often it occurs
+type PatternCount struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// UPRNProfile checks distinct UPRN values against the loaded LLPG and OS Open UPRN
+type UPRNProfile struct {
+	Distinct       int     `json:"distinct"`
+	InLLPG         int     `json:"in_llpg"`
+	InOSOnly       int     `json:"in_os_only"`
+	NotFound       int     `json:"not_found"`
+	ExcelArtefacts int     `json:"excel_artefacts"` // scientific notation, ".0", leading zeros
+	ValidRate      float64 `json:"valid_rate"`
+	Checked        bool    `json:"checked"` // false when no database was available
+}
+
+// AddressProfile summarises ValidateAddressForMatching over the column
+type AddressProfile struct {
+	Checked        int            `json:"checked"`
+	Unsuitable     int            `json:"unsuitable"`
+	UnsuitableRate float64        `json:"unsuitable_rate"`
+	Issues         map[string]int `json:"issues"`
+}
+
+// Profiler profiles CSV files; the database is only used for UPRN validity
+type Profiler struct {
+	db     *sql.DB
+	parser *validation.AddressParser
+}
+
+// NewProfiler creates a new profiler. db may be nil, in which case UPRNs are not checked.
+func NewProfiler(db *sql.DB) *Profiler {
+	return &Profiler{db: db, parser: validation.NewAddressParser()}
+}
+
+// ProfileCSV reads csvPath once and profiles every column. When sourceType names a mapping,
+// column roles come from it; otherwise they are guessed from the headers.
+func (p *Profiler) ProfileCSV(localDebug bool, csvPath, sourceType string) (*Report, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	roles, err := columnRoles(header, sourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{File: csvPath, SourceType: sourceType}
+	for i, name := range header {
+		report.Columns = append(report.Columns, &ColumnProfile{
+			Name:     strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")),
+			Role:     roles[i],
+			distinct: make(map[string]struct{}),
+			patterns: make(map[string]int),
+			types:    make(map[string]int),
+			uprns:    make(map[string]struct{}),
+		})
+		if roles[i] == RoleAddress {
+			report.Columns[i].Address = &AddressProfile{Issues: make(map[string]int)}
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		report.Rows++
+		if err != nil || len(record) < len(header) {
+			report.BadRows++
+			continue
+		}
+
+		for i, col := range report.Columns {
+			p.observe(col, strings.TrimSpace(record[i]))
+		}
+		if report.Rows%50000 == 0 {
+			debug.DebugOutput(localDebug, "Profiled %d rows", report.Rows)
+		}
+	}
+
+	for _, col := range report.Columns {
+		col.finish(report.Rows)
+		if col.Role == RoleUPRN {
+			if err := p.checkUPRNs(col); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// observe adds one value to a column's profile
+func (p *Profiler) observe(col *ColumnProfile, value string) {
+	if value == "" {
+		return
+	}
+	col.NonEmpty++
+
+	if len(col.distinct) < maxDistinct {
+		col.distinct[value] = struct{}{}
+	} else if _, ok := col.distinct[value]; !ok {
+		col.DistinctCapped = true
+	}
+	col.patterns[Pattern(value)]++
+
+	upper := strings.ToUpper(value)
+	switch {
+	case integerPattern.MatchString(value):
+		col.types[TypeInteger]++
+		if serial, _ := strconv.Atoi(value); serial > 0 && serial < 73051 {
+			col.serials++ // 1900-01-01 to 2099-12-31 as an Excel serial
+		}
+	case decimalPattern.MatchString(value):
+		col.types[TypeDecimal]++
+	case postcodePattern.MatchString(upper):
+		col.types[TypePostcode]++
+	default:
+		if format := DateFormat(value); format != "" {
+			col.types[TypeDate]++
+			if col.DateFormats == nil {
+				col.DateFormats = make(map[string]int)
+			}
+			col.DateFormats[format]++
+			col.dateValues = append(col.dateValues, value)
+		} else {
+			col.types[TypeText]++
+		}
+	}
+
+	switch col.Role {
+	case RolePostcode:
+		if postcodePattern.MatchString(upper) {
+			col.postcodeOK++
+		}
+	case RoleAddress:
+		if _, postcode, _ := normalize.CanonicalAddress(value); postcode != "" {
+			col.postcodeOK++
+		}
+		v := p.parser.ValidateAddressForMatching(value)
+		col.Address.Checked++
+		if !v.Suitable {
+			col.Address.Unsuitable++
+			for _, issue := range v.Issues {
+				col.Address.Issues[issueKey(issue)]++
+			}
+		}
+	case RoleUPRN:
+		repaired := etl.RepairExcelUPRN(value)
+		if repaired.Repair != "" {
+			col.artefacts++
+		}
+		if repaired.Value != "" && !repaired.Pending && len(col.uprns) < maxDistinct {
+			col.uprns[repaired.Value] = struct{}{}
+		}
+	}
+}
+
+// finish derives rates, the likely type and the top patterns
+func (col *ColumnProfile) finish(rows int) {
+	if rows > 0 {
+		col.FillRate = float64(col.NonEmpty) / float64(rows)
+	}
+	col.Distinct = len(col.distinct)
+
+	col.LikelyType = TypeEmpty
+	if col.NonEmpty > 0 {
+		col.LikelyType = TypeText
+		for _, t := range []string{TypeInteger, TypeDecimal, TypeDate, TypePostcode} {
+			share := float64(col.types[t]) / float64(col.NonEmpty)
+			if t == TypeDecimal {
+				share = float64(col.types[TypeInteger]+col.types[TypeDecimal]) / float64(col.NonEmpty)
+			}
+			if share >= typeThreshold {
+				col.LikelyType = t
+				break
+			}
+		}
+	}
+
+	// Integers in a date column are most likely Excel serials
+	if len(col.DateFormats) > 0 && col.serials > 0 {
+		col.DateFormats["excel_serial"] = col.serials
+		if float64(col.types[TypeDate]+col.serials)/float64(col.NonEmpty) >= typeThreshold {
+			col.LikelyType = TypeDate
+		}
+	}
+	if len(col.dateValues) > 0 {
+		col.DateOrder = etl.DetectDateOrder(col.dateValues)
+	}
+
+	if (col.Role == RolePostcode || col.Role == RoleAddress) && col.NonEmpty > 0 {
+		rate := float64(col.postcodeOK) / float64(col.NonEmpty)
+		col.PostcodeValidRate = &rate
+	}
+	if col.Address != nil && col.Address.Checked > 0 {
+		col.Address.UnsuitableRate = float64(col.Address.Unsuitable) / float64(col.Address.Checked)
+	}
+
+	for pattern, count := range col.patterns {
+		col.Patterns = append(col.Patterns, PatternCount{Pattern: pattern, Count: count})
+	}
+	sort.Slice(col.Patterns, func(i, j int) bool {
+		if col.Patterns[i].Count != col.Patterns[j].Count {
+			return col.Patterns[i].Count > col.Patterns[j].Count
+		}
+		return col.Patterns[i].Pattern < col.Patterns[j].Pattern
+	})
+	if len(col.Patterns) > maxPatterns {
+		col.Patterns = col.Patterns[:maxPatterns]
+	}
+
+	col.distinct, col.patterns, col.dateValues = nil, nil, nil
+}
+
+// checkUPRNs looks up the column's distinct UPRNs in dim_address and os_uprn_reference
+func (p *Profiler) checkUPRNs(col *ColumnProfile) error {
+	profile := &UPRNProfile{Distinct: len(col.uprns), ExcelArtefacts: col.artefacts}
+	col.UPRN = profile
+	if p.db == nil || len(col.uprns) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(col.uprns))
+	for v := range col.uprns {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	col.uprns = nil
+
+	for start := 0; start < len(values); start += uprnCheckBatch {
+		end := start + uprnCheckBatch
+		if end > len(values) {
+			end = len(values)
+		}
+
+		var inLLPG, inOS int
+		err := p.db.QueryRow(`
+			SELECT
+				COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM dim_address d WHERE d.uprn = u.uprn)),
+				COUNT(*) FILTER (WHERE NOT EXISTS (SELECT 1 FROM dim_address d WHERE d.uprn = u.uprn)
+				                   AND EXISTS (SELECT 1 FROM os_uprn_reference o WHERE o.uprn = u.uprn))
+			FROM unnest($1::text[]) AS u(uprn)
+		`, pq.Array(values[start:end])).Scan(&inLLPG, &inOS)
+		if err != nil {
+			return fmt.Errorf("failed to check UPRNs for %s: %w", col.Name, err)
+		}
+		profile.InLLPG += inLLPG
+		profile.InOSOnly += inOS
+	}
+
+	profile.Checked = true
+	profile.NotFound = profile.Distinct - profile.InLLPG - profile.InOSOnly
+	if profile.Distinct > 0 {
+		profile.ValidRate = float64(profile.InLLPG+profile.InOSOnly) / float64(profile.Distinct)
+	}
+	return nil
+}
+
+// Pattern reduces a value to its shape: letters become A, digits 9, runs of whitespace one
+// space, other characters are kept. "GU32 3AN" -> "AA99 9AA".
+func Pattern(value string) string {
+	var b strings.Builder
+	lastSpace := false
+	n := 0
+	for _, r := range value {
+		if n == maxPatternLen {
+			b.WriteString("...")
+			break
+		}
+		switch {
+		case unicode.IsSpace(r):
+			if lastSpace {
+				continue
+			}
+			b.WriteByte(' ')
+			lastSpace = true
+			n++
+			continue
+		case unicode.IsDigit(r):
+			b.WriteByte('9')
+		case unicode.IsLetter(r):
+			b.WriteByte('A')
+		default:
+			b.WriteRune(r)
+		}
+		lastSpace = false
+		n++
+	}
+	return b.String()
+}
+
+// DateFormat names the layout of a date value in mapping notation (e.g. D/M/YY), or "" when
+// the value is not a date. Day and month are only told apart when one part exceeds 12.
+func DateFormat(value string) string {
+	if isoDatePattern.MatchString(value) {
+		return "YYYY-MM-DD"
+	}
+	m := slashedDatePattern.FindStringSubmatch(value)
+	if m == nil || m[2] != m[4] {
+		return ""
+	}
+
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[3])
+	if a < 1 || b < 1 || (a > 12 && b > 12) || a > 31 || b > 31 {
+		return ""
+	}
+
+	first, second := "D", "M"
+	if b > 12 {
+		first, second = "M", "D"
+	}
+	return strings.Repeat(first, len(m[1])) + m[2] + strings.Repeat(second, len(m[3])) + m[2] +
+		strings.Repeat("Y", len(m[5]))
+}
+
+// issueKey drops the quoted detail from vague-address issues so they group together
+func issueKey(issue string) string {
+	if i := strings.Index(issue, " '"); i > 0 {
+		return issue[:i]
+	}
+	return issue
+}
+
+// columnRoles assigns roles from the source type's mapping, falling back to header names
+func columnRoles(header []string, sourceType string) ([]string, error) {
+	roles := make([]string, len(header))
+	byHeader := make(map[string]string)
+
+	if sourceType != "" {
+		mapping, err := etl.LoadSourceMapping(sourceType)
+		if err != nil {
+			return nil, err
+		}
+		staged := make(map[string]etl.ColumnMapping)
+		for _, c := range mapping.Columns {
+			staged[c.Column] = c
+		}
+		for field, role := range map[string]string{"raw_address": RoleAddress, "uprn_raw": RoleUPRN, "doc_date": RoleDate} {
+			if f, ok := mapping.Document[field]; ok && f.Column != "" {
+				byHeader[strings.ToLower(staged[f.Column].CSV)] = role
+			}
+		}
+	}
+
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if role, ok := byHeader[name]; ok {
+			roles[i] = role
+			continue
+		}
+		switch {
+		case strings.Contains(name, "uprn"):
+			roles[i] = RoleUPRN
+		case strings.Contains(name, "postcode") || strings.Contains(name, "post code"):
+			roles[i] = RolePostcode
+		case strings.Contains(name, "address") || strings.Contains(name, "adress") || name == "locaddress":
+			roles[i] = RoleAddress
+		case strings.Contains(name, "date"):
+			roles[i] = RoleDate
+		}
+	}
+	return roles, nil
+}
@@ -0,0 +1,73 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPattern(t *testing.T) {
+	tests := map[string]string{
+		"GU32 3AN":    "AA99 9AA",
+		"1710030787":  "9999999999",
+		"12  High St": "99 AAAA AA",
+		"9/9/85":      "9/9/99",
+	}
+	for in, want := range tests {
+		if got := Pattern(in); got != want {
+			t.Errorf("Pattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	tests := map[string]string{
+		"9/9/85":     "D/M/YY",
+		"25/12/1990": "DD/MM/YYYY",
+		"12/25/1990": "MM/DD/YYYY",
+		"1990-12-25": "YYYY-MM-DD",
+		"PT/SN/12":   "",
+		"32/13/1990": "",
+	}
+	for in, want := range tests {
+		if got := DateFormat(in); got != want {
+			t.Errorf("DateFormat(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProfileCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agreements.csv")
+	data := "Job Number,Address,Date,BS7666UPRN,Postcode\n" +
+		"JN1,\"12 High Street, Petersfield GU32 3AN\",9/9/85,1710030787,GU32 3AN\n" +
+		"JN2,Land at Rear of Church,25/12/1990,1.71003E+9,NOT KNOWN\n" +
+		"JN3,\"4 Mill Lane, Alton\",31413,,GU34 2QG\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := NewProfiler(nil).ProfileCSV(false, path, "agreement")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 3 || len(report.Columns) != 5 {
+		t.Fatalf("rows = %d, columns = %d", report.Rows, len(report.Columns))
+	}
+
+	address, date, uprn, postcode := report.Columns[1], report.Columns[2], report.Columns[3], report.Columns[4]
+	if address.Role != RoleAddress || address.Address.Unsuitable == 0 {
+		t.Errorf("address column = %+v", address.Address)
+	}
+	if date.LikelyType != TypeDate || date.DateFormats["excel_serial"] != 1 {
+		t.Errorf("date column type %s, formats %v", date.LikelyType, date.DateFormats)
+	}
+	if uprn.Role != RoleUPRN || uprn.UPRN.ExcelArtefacts != 1 || uprn.UPRN.Checked {
+		t.Errorf("uprn column = %+v", uprn.UPRN)
+	}
+	if uprn.FillRate < 0.66 || uprn.FillRate > 0.67 {
+		t.Errorf("uprn fill rate = %v", uprn.FillRate)
+	}
+	if postcode.PostcodeValidRate == nil || *postcode.PostcodeValidRate < 0.66 || *postcode.PostcodeValidRate > 0.67 {
+		t.Errorf("postcode valid rate = %v", postcode.PostcodeValidRate)
+	}
+}
@@ -0,0 +1,105 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+// Print writes a human-readable report
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Profile of %s\n", r.File)
+	if r.SourceType != "" {
+		fmt.Fprintf(w, "Source type: %s\n", r.SourceType)
+	}
+	fmt.Fprintf(w, "Rows: %d (%d unparseable or short)\n", r.Rows, r.BadRows)
+
+	for _, col := range r.Columns {
+		fmt.Fprintf(w, "\n%s", col.Name)
+		if col.Role != "" {
+			fmt.Fprintf(w, " [%s]", col.Role)
+		}
+		fmt.Fprintln(w)
+
+		distinct := fmt.Sprintf("%d", col.Distinct)
+		if col.DistinctCapped {
+			distinct = "more than " + distinct
+		}
+		fmt.Fprintf(w, "  Fill rate:   %5.1f%% (%d values)\n", col.FillRate*100, col.NonEmpty)
+		fmt.Fprintf(w, "  Distinct:    %s\n", distinct)
+		fmt.Fprintf(w, "  Likely type: %s\n", col.LikelyType)
+
+		if len(col.Patterns) > 0 {
+			fmt.Fprintln(w, "  Patterns:")
+			for _, p := range col.Patterns {
+				fmt.Fprintf(w, "    %-32s %d\n", p.Pattern, p.Count)
+			}
+		}
+
+		if len(col.DateFormats) > 0 {
+			fmt.Fprintf(w, "  Date formats: %s", formatCounts(col.DateFormats))
+			if col.DateOrder != "" {
+				fmt.Fprintf(w, " (order: %s)", col.DateOrder)
+			}
+			fmt.Fprintln(w)
+		}
+
+		if col.PostcodeValidRate != nil {
+			fmt.Fprintf(w, "  Valid postcode: %.1f%%\n", *col.PostcodeValidRate*100)
+		}
+
+		if u := col.UPRN; u != nil {
+			if u.Checked {
+				fmt.Fprintf(w, "  UPRNs: %d distinct, %d in LLPG, %d in OS only, %d not found (%.1f%% valid)\n",
+					u.Distinct, u.InLLPG, u.InOSOnly, u.NotFound, u.ValidRate*100)
+			} else {
+				fmt.Fprintf(w, "  UPRNs: %d distinct (not checked, no database)\n", u.Distinct)
+			}
+			if u.ExcelArtefacts > 0 {
+				fmt.Fprintf(w, "  Excel artefacts: %d values (scientific notation, .0 suffix or leading zeros)\n", u.ExcelArtefacts)
+			}
+		}
+
+		if a := col.Address; a != nil && a.Checked > 0 {
+			fmt.Fprintf(w, "  Unsuitable for matching: %.1f%% (%d of %d)\n", a.UnsuitableRate*100, a.Unsuitable, a.Checked)
+			if len(a.Issues) > 0 {
+				fmt.Fprintf(w, "  Issues: %s\n", formatCounts(a.Issues))
+			}
+		}
+	}
+}
+
+// formatCounts renders a count map, largest first
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,270 @@
+// Package queue runs matcher workers against the database work queue. A queued run is
+// split into chunks of document IDs or deduplicated addresses; any number of worker
+// processes, on any number of servers, claim chunks with FOR UPDATE SKIP LOCKED,
+// heartbeat while matching them and complete them. A chunk whose worker stops
+// heartbeating is re-queued for the others.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ehdc-llpg/internal/debug"
+	"github.com/ehdc-llpg/internal/interrupt"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// Handler matches the items of a chunk. It must be safe to repeat: a chunk re-queued after
+// its worker died is matched again, and items matched the first time must be skipped.
+type Handler func(ctx context.Context, chunk *store.WorkChunk) (store.ChunkResult, error)
+
+// Options tune how workers share a queue
+type Options struct {
+	Heartbeat   time.Duration // how often a worker heartbeats its chunk
+	StaleAfter  time.Duration // a claim without a heartbeat for this long is abandoned
+	Poll        time.Duration // how long an idle worker waits for other workers' chunks
+	MaxAttempts int           // claims before a chunk that keeps failing is marked failed
+}
+
+// DefaultOptions suit chunks that take seconds to a few minutes to match
+func DefaultOptions() Options {
+	return Options{
+		Heartbeat:   15 * time.Second,
+		StaleAfter:  2 * time.Minute,
+		Poll:        5 * time.Second,
+		MaxAttempts: 3,
+	}
+}
+
+// DefaultChunkSize is the number of items enqueued per chunk
+const DefaultChunkSize = 200
+
+// WorkerID names the nth worker of this process, unique across servers
+func WorkerID(n int) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), n)
+}
+
+// Worker claims and matches chunks of one queued run
+type Worker struct {
+	ID     string
+	stores store.Stores
+	runID  int64
+	handle Handler
+	opts   Options
+}
+
+// WorkerStats are one worker's totals
+type WorkerStats struct {
+	Chunks    int
+	Processed int
+	Matched   int
+	Errors    int
+	Released  int // chunks given back after the handler failed
+	Lost      int // chunks re-queued to another worker while this one held them
+}
+
+// NewWorker creates a worker for a queued run
+func NewWorker(stores store.Stores, runID int64, id string, handle Handler, opts Options) *Worker {
+	return &Worker{ID: id, stores: stores, runID: runID, handle: handle, opts: opts}
+}
+
+// Run claims chunks until the queue is drained. While other workers still hold chunks it
+// waits, re-queueing any they abandon. When ctx is cancelled the current chunk is released
+// for another worker and ctx's error is returned.
+func (w *Worker) Run(ctx context.Context, localDebug bool) (*WorkerStats, error) {
+	stats := &WorkerStats{}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		chunk, err := w.stores.Queue.ClaimChunk(ctx, w.runID, w.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stats, ctx.Err()
+			}
+			return stats, err
+		}
+
+		if chunk == nil {
+			drained, err := w.idle(ctx, localDebug)
+			if err != nil || drained {
+				return stats, err
+			}
+			continue
+		}
+
+		if err := w.work(ctx, localDebug, chunk, stats); err != nil {
+			return stats, err
+		}
+	}
+}
+
+// idle runs when nothing is pending: it rescues abandoned chunks and reports whether the
+// queue is drained, otherwise waiting a poll interval for other workers
+func (w *Worker) idle(ctx context.Context, localDebug bool) (bool, error) {
+	report, err := Coordinate(ctx, w.stores, w.runID, w.opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, err
+	}
+	if report.Requeued > 0 {
+		debug.DebugOutput(localDebug, "Worker %s re-queued %d abandoned chunks", w.ID, report.Requeued)
+		return false, nil
+	}
+	if report.Progress.Drained() {
+		return true, nil
+	}
+
+	debug.DebugOutput(localDebug, "Worker %s waiting: %d chunks held by other workers", w.ID, report.Progress.Claimed)
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-time.After(w.opts.Poll):
+		return false, nil
+	}
+}
+
+// work matches one claimed chunk while heartbeating it. Only cancellation and store
+// failures are returned; a failing handler releases the chunk and the worker moves on.
+func (w *Worker) work(ctx context.Context, localDebug bool, chunk *store.WorkChunk, stats *WorkerStats) error {
+	debug.DebugOutput(localDebug, "Worker %s claimed chunk %d (%d %s, attempt %d)",
+		w.ID, chunk.ChunkID, len(chunk.Items), chunk.Kind, chunk.Attempts)
+
+	chunkCtx, cancel := context.WithCancel(ctx)
+	lost := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.heartbeat(chunkCtx, localDebug, chunk, cancel, lost)
+	}()
+
+	result, err := w.handle(chunkCtx, chunk)
+	cancel()
+	wg.Wait()
+
+	select {
+	case <-lost:
+		// Another worker has the chunk; whatever this one matched is skipped there
+		debug.DebugOutput(localDebug, "Worker %s lost chunk %d to a re-queue", w.ID, chunk.ChunkID)
+		stats.Lost++
+		return nil
+	default:
+	}
+
+	if ctx.Err() != nil {
+		// Interrupted: hand the chunk straight back without counting it against its attempts
+		releaseCtx, releaseCancel := interrupt.Detached()
+		defer releaseCancel()
+		if err := w.stores.Queue.ReleaseChunk(releaseCtx, chunk.ChunkID, w.ID, "worker interrupted", 0); err != nil && !errors.Is(err, store.ErrChunkLost) {
+			debug.DebugOutput(localDebug, "Warning: failed to release chunk %d: %v", chunk.ChunkID, err)
+		}
+		return ctx.Err()
+	}
+
+	if err != nil {
+		debug.DebugOutput(localDebug, "Worker %s failed chunk %d: %v", w.ID, chunk.ChunkID, err)
+		stats.Released++
+		err = w.stores.Queue.ReleaseChunk(ctx, chunk.ChunkID, w.ID, err.Error(), w.opts.MaxAttempts)
+		if err != nil && !errors.Is(err, store.ErrChunkLost) {
+			return err
+		}
+		return nil
+	}
+
+	err = w.stores.Queue.CompleteChunk(ctx, chunk.ChunkID, w.ID, result)
+	if errors.Is(err, store.ErrChunkLost) {
+		stats.Lost++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stats.Chunks++
+	stats.Processed += result.Processed
+	stats.Matched += result.Matched
+	stats.Errors += result.Errors
+	return nil
+}
+
+// heartbeat keeps the claim alive until ctx ends. If the chunk was re-queued it closes
+// lost and cancels the handler.
+func (w *Worker) heartbeat(ctx context.Context, localDebug bool, chunk *store.WorkChunk, cancel context.CancelFunc, lost chan<- struct{}) {
+	ticker := time.NewTicker(w.opts.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := w.stores.Queue.Heartbeat(ctx, chunk.ChunkID, w.ID)
+			if errors.Is(err, store.ErrChunkLost) {
+				close(lost)
+				cancel()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				// Transient: the claim survives until StaleAfter without a heartbeat
+				debug.DebugOutput(localDebug, "Warning: heartbeat for chunk %d failed: %v", chunk.ChunkID, err)
+			}
+		}
+	}
+}
+
+// Report is the state of a queued run
+type Report struct {
+	RunID    int64
+	Requeued int // abandoned chunks re-queued by this call
+	Progress store.QueueProgress
+	Workers  []store.WorkerThroughput
+	Finished bool // the queue is drained and the run recorded as ended
+}
+
+// Coordinate re-queues abandoned chunks and reports the run's progress and per-worker
+// throughput. Once the queue is drained it records the run as completed, or failed when
+// chunks gave up after MaxAttempts.
+func Coordinate(ctx context.Context, stores store.Stores, runID int64, opts Options) (*Report, error) {
+	requeued, err := stores.Queue.RequeueAbandoned(ctx, runID, opts.StaleAfter, opts.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := stores.Queue.QueueProgress(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	workers, err := stores.Queue.WorkerThroughput(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{RunID: runID, Requeued: requeued, Progress: *progress, Workers: workers}
+	if requeued == 0 && progress.Drained() {
+		status := store.RunCompleted
+		if progress.Failed > 0 {
+			status = store.RunFailed
+		}
+		err := stores.Matches.CompleteRun(ctx, runID, status, store.RunTotals{
+			Processed: progress.Processed,
+			Accepted:  progress.Matched,
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.Finished = true
+	}
+	return report, nil
+}
@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+func testOptions() Options {
+	return Options{Heartbeat: time.Millisecond, StaleAfter: time.Minute, Poll: time.Millisecond, MaxAttempts: 2}
+}
+
+func queuedRun(t *testing.T, stores store.Stores, items []string, chunkSize int) int64 {
+	t.Helper()
+	run, err := stores.Matches.CreateRun(context.Background(), "queue-test", "test", "")
+	if err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	if _, err := stores.Queue.Enqueue(context.Background(), run.RunID, store.WorkDocuments, items, chunkSize); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	return run.RunID
+}
+
+func TestWorkersShareQueue(t *testing.T) {
+	stores := store.NewMemory().Stores()
+	items := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+	runID := queuedRun(t, stores, items, 3)
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	failed := false
+	handle := func(ctx context.Context, chunk *store.WorkChunk) (store.ChunkResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if chunk.ChunkID == 2 && !failed {
+			failed = true
+			return store.ChunkResult{}, errors.New("database restarted")
+		}
+		for _, item := range chunk.Items {
+			seen[item]++
+		}
+		return store.ChunkResult{Processed: len(chunk.Items), Matched: 1}, nil
+	}
+
+	var wg sync.WaitGroup
+	stats := make([]*WorkerStats, 3)
+	for i := range stats {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			stats[i], err = NewWorker(stores, runID, WorkerID(i), handle, testOptions()).Run(context.Background(), false)
+			if err != nil {
+				t.Errorf("worker %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, item := range items {
+		if seen[item] != 1 {
+			t.Errorf("item %s matched %d times, want once", item, seen[item])
+		}
+	}
+
+	report, err := Coordinate(context.Background(), stores, runID, testOptions())
+	if err != nil {
+		t.Fatalf("Coordinate: %v", err)
+	}
+	if !report.Finished || report.Progress.Done != 4 || report.Progress.Processed != 10 {
+		t.Errorf("report = %+v, want 4 chunks done and 10 items processed", report)
+	}
+	total := 0
+	for _, w := range report.Workers {
+		total += w.Processed
+	}
+	if total != 10 {
+		t.Errorf("worker throughput covers %d items, want 10", total)
+	}
+}
+
+func TestAbandonedChunkIsRequeued(t *testing.T) {
+	mem := store.NewMemory()
+	stores := mem.Stores()
+	runID := queuedRun(t, stores, []string{"1", "2"}, 2)
+	ctx := context.Background()
+
+	// A worker claims the chunk and dies without heartbeating
+	dead, _ := stores.Queue.ClaimChunk(ctx, runID, "server-a-1-0")
+	if dead == nil {
+		t.Fatal("no chunk to claim")
+	}
+
+	opts := testOptions()
+	opts.StaleAfter = 0
+	handle := func(ctx context.Context, chunk *store.WorkChunk) (store.ChunkResult, error) {
+		return store.ChunkResult{Processed: len(chunk.Items)}, nil
+	}
+	stats, err := NewWorker(stores, runID, "server-b-1-0", handle, opts).Run(ctx, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if stats.Chunks != 1 || stats.Processed != 2 {
+		t.Errorf("rescuing worker stats = %+v, want the abandoned chunk", stats)
+	}
+
+	if err := stores.Queue.CompleteChunk(ctx, dead.ChunkID, "server-a-1-0", store.ChunkResult{}); !errors.Is(err, store.ErrChunkLost) {
+		t.Errorf("late completion by the dead worker = %v, want ErrChunkLost", err)
+	}
+}
+
+func TestInterruptedWorkerReleasesChunk(t *testing.T) {
+	stores := store.NewMemory().Stores()
+	runID := queuedRun(t, stores, []string{"1", "2", "3"}, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := func(ctx context.Context, chunk *store.WorkChunk) (store.ChunkResult, error) {
+		cancel()
+		return store.ChunkResult{}, ctx.Err()
+	}
+	_, err := NewWorker(stores, runID, "server-a-1-0", handle, testOptions()).Run(ctx, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+
+	progress, _ := stores.Queue.QueueProgress(context.Background(), runID)
+	if progress.Pending != 1 || progress.Claimed != 0 {
+		t.Errorf("progress = %+v, want the chunk back in the queue", progress)
+	}
+
+	// The interrupted claim is not counted: the next claim is the chunk's first attempt
+	chunk, _ := stores.Queue.ClaimChunk(context.Background(), runID, "server-b-1-0")
+	if chunk == nil || chunk.Attempts != 1 {
+		t.Errorf("reclaimed chunk = %+v, want attempt 1", chunk)
+	}
+}
@@ -0,0 +1,247 @@
+// Package review is the shared manual review workflow behind the web server. Reviewers
+// claim documents from a queue filtered by source type or confidence band; a claim is a
+// lease, so no two reviewers work the same document, and it lapses if not renewed. An
+// accept below the sign-off confidence is only proposed, and takes effect once a second,
+// different reviewer confirms it.
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+var (
+	// ErrOwnProposal is returned when a reviewer tries to sign off their own accept
+	ErrOwnProposal = errors.New("an accept must be signed off by a different reviewer")
+
+	// ErrNotCandidate is returned when the accepted UPRN is not one of the item's candidates,
+	// or not the UPRN proposed for sign-off
+	ErrNotCandidate = errors.New("UPRN is not a candidate for this document")
+
+	// ErrNotQueued is returned for documents that are not in the review queue
+	ErrNotQueued = errors.New("document is not in the review queue")
+
+	// ErrSignoffRole is returned when a reviewer the policy does not allow to sign off
+	// claims, confirms or refuses an accept awaiting sign-off
+	ErrSignoffRole = errors.New("signing off an accept needs the senior_reviewer role")
+
+	// ErrNeedsSignoff is returned for an accept made outside the queue below the sign-off
+	// confidence; only the queue can propose it for a second reviewer
+	ErrNeedsSignoff = errors.New("accepts below the sign-off confidence need a second reviewer; use the review queue")
+)
+
+// Policy is how the queue hands out and settles items
+type Policy struct {
+	Lease        time.Duration // how long a claim lasts without renewal
+	SignoffBelow float64       // accepts under this confidence need a second reviewer
+
+	// CanSignOff reports whether the request's reviewer may sign off accepts; nil lets
+	// every reviewer
+	CanSignOff func(ctx context.Context) bool
+}
+
+func (p Policy) canSignOff(ctx context.Context) bool {
+	return p.CanSignOff == nil || p.CanSignOff(ctx)
+}
+
+// syncEvery is how stale the queue may get before a claim refreshes it from match_result
+const syncEvery = time.Minute
+
+// Service runs the review workflow over a review store
+type Service struct {
+	store  store.ReviewStore
+	policy Policy
+
+	mu       sync.Mutex
+	lastSync time.Time
+}
+
+// NewService creates a review service
+func NewService(s store.ReviewStore, policy Policy) *Service {
+	return &Service{store: s, policy: policy}
+}
+
+// Policy returns the service's policy
+func (s *Service) Policy() Policy {
+	return s.policy
+}
+
+// Band is a named confidence range, [Min, Max); a zero Max has no upper bound
+type Band struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// Bands are the confidence bands a queue can be filtered by
+var Bands = []Band{
+	{Name: "low", Min: 0, Max: 0.6},
+	{Name: "medium", Min: 0.6, Max: 0.8},
+	{Name: "high", Min: 0.8},
+}
+
+// ParseBand looks up a confidence band by name
+func ParseBand(name string) (Band, error) {
+	for _, b := range Bands {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return Band{}, fmt.Errorf("unknown confidence band %q (want low, medium or high)", name)
+}
+
+// Filter narrows f to the band
+func (b Band) Filter(f store.ReviewFilter) store.ReviewFilter {
+	f.MinConfidence, f.MaxConfidence = b.Min, b.Max
+	return f
+}
+
+// Sync adds newly matched documents to the queue and closes ones accepted elsewhere
+func (s *Service) Sync(ctx context.Context) (added, closed int, err error) {
+	added, closed, err = s.store.SyncReviewQueue(ctx)
+	if err != nil {
+		return added, closed, err
+	}
+	s.mu.Lock()
+	s.lastSync = time.Now()
+	s.mu.Unlock()
+	return added, closed, nil
+}
+
+// Claim leases the next item matching f to reviewer, syncing the queue first if it has
+// not been synced recently; nil when nothing is waiting
+func (s *Service) Claim(ctx context.Context, reviewer string, f store.ReviewFilter) (*store.ReviewItem, error) {
+	if f.SignOff && !s.policy.canSignOff(ctx) {
+		return nil, ErrSignoffRole
+	}
+	s.mu.Lock()
+	stale := time.Since(s.lastSync) > syncEvery
+	s.mu.Unlock()
+	if stale {
+		if _, _, err := s.Sync(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.store.ClaimReview(ctx, reviewer, f, s.policy.Lease)
+}
+
+// Item reads a queued document with its candidates
+func (s *Service) Item(ctx context.Context, srcID int64) (*store.ReviewItem, error) {
+	item, err := s.store.ReviewItem(ctx, srcID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrNotQueued
+	}
+	return item, nil
+}
+
+// Renew extends the reviewer's lease by the policy's lease time
+func (s *Service) Renew(ctx context.Context, srcID int64, reviewer string) error {
+	return s.store.RenewReview(ctx, srcID, reviewer, s.policy.Lease)
+}
+
+// Accept accepts one of the item's candidates, returning the action recorded. On an item
+// awaiting sign-off it confirms the proposed accept. Otherwise a candidate below the
+// sign-off confidence is proposed rather than accepted.
+func (s *Service) Accept(ctx context.Context, srcID int64, reviewer, uprn, notes string) (string, error) {
+	item, err := s.Item(ctx, srcID)
+	if err != nil {
+		return "", err
+	}
+
+	if item.Status == store.ReviewSignoff && item.Proposal != nil {
+		if !s.policy.canSignOff(ctx) {
+			return "", ErrSignoffRole
+		}
+		if item.Proposal.ProposedBy == reviewer {
+			return "", ErrOwnProposal
+		}
+		if item.Proposal.UPRN != uprn {
+			return "", ErrNotCandidate
+		}
+		o := store.ReviewOutcome{Action: store.ReviewConfirm, Proposal: item.Proposal, Notes: notes}
+		return o.Action, s.store.FinishReview(ctx, srcID, reviewer, o)
+	}
+
+	c, ok := item.Candidate(uprn)
+	if !ok {
+		return "", ErrNotCandidate
+	}
+	o := store.ReviewOutcome{
+		Action:   store.ReviewAccept,
+		Proposal: &store.ReviewProposal{UPRN: c.UPRN, Method: c.Method, Score: c.Score, Confidence: c.Confidence},
+		Notes:    notes,
+	}
+	if c.Confidence < s.policy.SignoffBelow {
+		o.Action = store.ReviewPropose
+	}
+	return o.Action, s.store.FinishReview(ctx, srcID, reviewer, o)
+}
+
+// Direct vets an accept or reject made on a record outside the queue, such as from the
+// map. A document with an unfinished review item is queued: the caller must settle it
+// with Accept or Reject, which need the reviewer's lease. Otherwise an accept whose stored
+// confidence is below the sign-off confidence returns ErrNeedsSignoff.
+func (s *Service) Direct(ctx context.Context, srcID int64, accept bool, confidence float64) (queued bool, err error) {
+	item, err := s.store.ReviewItem(ctx, srcID)
+	if err != nil {
+		return false, err
+	}
+	if item != nil && item.Status != store.ReviewDone {
+		return true, nil
+	}
+	if accept && confidence < s.policy.SignoffBelow {
+		return false, ErrNeedsSignoff
+	}
+	return false, nil
+}
+
+// Reject rejects every candidate, or on an item awaiting sign-off refuses the proposed
+// accept and returns the item to the queue. It returns the action recorded.
+func (s *Service) Reject(ctx context.Context, srcID int64, reviewer, notes string) (string, error) {
+	item, err := s.Item(ctx, srcID)
+	if err != nil {
+		return "", err
+	}
+	action := store.ReviewReject
+	if item.Status == store.ReviewSignoff {
+		if !s.policy.canSignOff(ctx) {
+			return "", ErrSignoffRole
+		}
+		action = store.ReviewRefuse
+	}
+	return action, s.store.FinishReview(ctx, srcID, reviewer, store.ReviewOutcome{Action: action, Notes: notes})
+}
+
+// Skip gives the item back; it is not offered to this reviewer again
+func (s *Service) Skip(ctx context.Context, srcID int64, reviewer string) error {
+	return s.store.FinishReview(ctx, srcID, reviewer, store.ReviewOutcome{Action: store.ReviewSkip})
+}
+
+// Defer gives the item back until the given time
+func (s *Service) Defer(ctx context.Context, srcID int64, reviewer string, until time.Time, notes string) error {
+	return s.store.FinishReview(ctx, srcID, reviewer,
+		store.ReviewOutcome{Action: store.ReviewDefer, DeferUntil: until, Notes: notes})
+}
+
+// Release gives the item back to the queue unchanged
+func (s *Service) Release(ctx context.Context, srcID int64, reviewer string) error {
+	return s.store.FinishReview(ctx, srcID, reviewer, store.ReviewOutcome{Action: store.ReviewRelease})
+}
+
+// Counts counts the queue's items by state
+func (s *Service) Counts(ctx context.Context) (*store.ReviewQueueCounts, error) {
+	return s.store.ReviewQueueCounts(ctx)
+}
+
+// Stats summarises each reviewer's activity since the given time
+func (s *Service) Stats(ctx context.Context, since time.Time) ([]store.ReviewerStats, error) {
+	return s.store.ReviewerStats(ctx, since)
+}
@@ -0,0 +1,277 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// testQueue queues document 1 with a strong candidate and document 2 with a weak one
+func testQueue(t *testing.T) (*store.Memory, *Service, *time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	m := store.NewMemory()
+	m.SetClock(func() time.Time { return now })
+	m.AddDocument(store.SourceDocument{SrcID: 1, SourceType: "decision", RawAddress: "1 HIGH STREET ALTON"})
+	m.AddDocument(store.SourceDocument{SrcID: 2, SourceType: "land_charge", RawAddress: "BUTTS ROAD ALTON"})
+	results := []store.MatchResult{
+		{SrcID: 1, CandidateUPRN: "100062000001", Method: "fuzzy_text", Score: 0.92, Confidence: 0.92, Decision: "needs_review"},
+		{SrcID: 2, CandidateUPRN: "100062000002", Method: "fuzzy_text", Score: 0.55, Confidence: 0.55, Decision: "needs_review"},
+		{SrcID: 2, CandidateUPRN: "100062000003", Method: "spatial", Score: 0.41, Confidence: 0.41, Decision: "needs_review"},
+	}
+	for i := range results {
+		if err := m.SaveResult(ctx, &results[i]); err != nil {
+			t.Fatalf("SaveResult: %v", err)
+		}
+	}
+
+	s := NewService(m, Policy{Lease: 10 * time.Minute, SignoffBelow: 0.8})
+	if added, _, err := s.Sync(ctx); err != nil || added != 2 {
+		t.Fatalf("Sync added %d, %v; want 2", added, err)
+	}
+	return m, s, &now
+}
+
+func claim(t *testing.T, s *Service, reviewer string, f store.ReviewFilter) int64 {
+	t.Helper()
+	item, err := s.Claim(context.Background(), reviewer, f)
+	if err != nil {
+		t.Fatalf("%s Claim: %v", reviewer, err)
+	}
+	if item == nil {
+		return 0
+	}
+	return item.SrcID
+}
+
+func TestLeaseKeepsReviewersApart(t *testing.T) {
+	ctx := context.Background()
+	_, s, now := testQueue(t)
+
+	if got := claim(t, s, "alice", store.ReviewFilter{}); got != 1 {
+		t.Fatalf("alice claimed %d, want 1", got)
+	}
+	if got := claim(t, s, "alice", store.ReviewFilter{}); got != 1 {
+		t.Errorf("alice's second claim returned %d, want her held item 1", got)
+	}
+	if got := claim(t, s, "bob", store.ReviewFilter{}); got != 2 {
+		t.Errorf("bob claimed %d, want 2", got)
+	}
+	if got := claim(t, s, "carol", store.ReviewFilter{}); got != 0 {
+		t.Errorf("carol claimed %d while both items were leased", got)
+	}
+
+	*now = now.Add(11 * time.Minute)
+	if got := claim(t, s, "carol", store.ReviewFilter{}); got != 1 {
+		t.Errorf("carol claimed %d after alice's lease expired, want 1", got)
+	}
+	if _, err := s.Accept(ctx, 1, "alice", "100062000001", ""); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("alice accepted after losing her lease: %v", err)
+	}
+
+	stats, err := s.Stats(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	for _, st := range stats {
+		if st.Reviewer == "alice" && st.Actions[store.ReviewExpire] != 1 {
+			t.Errorf("alice has %d expired leases, want 1", st.Actions[store.ReviewExpire])
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	_, s, _ := testQueue(t)
+
+	if got := claim(t, s, "alice", store.ReviewFilter{SourceType: "land_charge"}); got != 2 {
+		t.Errorf("land_charge queue gave %d, want 2", got)
+	}
+	high, _ := ParseBand("high")
+	if got := claim(t, s, "bob", high.Filter(store.ReviewFilter{})); got != 1 {
+		t.Errorf("high band gave %d, want 1", got)
+	}
+	low, _ := ParseBand("low")
+	if got := claim(t, s, "carol", low.Filter(store.ReviewFilter{})); got != 0 {
+		t.Errorf("low band gave %d while its only item was leased", got)
+	}
+	if _, err := ParseBand("very high"); err == nil {
+		t.Error("ParseBand accepted an unknown band")
+	}
+}
+
+func TestLowConfidenceAcceptNeedsSecondReviewer(t *testing.T) {
+	ctx := context.Background()
+	m, s, _ := testQueue(t)
+	low, _ := ParseBand("low")
+
+	claim(t, s, "alice", low.Filter(store.ReviewFilter{}))
+	if _, err := s.Accept(ctx, 2, "alice", "100062000009", ""); !errors.Is(err, ErrNotCandidate) {
+		t.Errorf("accepting a UPRN that is not a candidate: %v", err)
+	}
+	action, err := s.Accept(ctx, 2, "alice", "100062000002", "same building")
+	if err != nil || action != store.ReviewPropose {
+		t.Fatalf("low-confidence accept = %s, %v; want propose", action, err)
+	}
+	if a, _ := m.Accepted(ctx, 2); a != nil {
+		t.Fatal("a proposed accept was written to match_accepted")
+	}
+
+	if got := claim(t, s, "alice", store.ReviewFilter{SignOff: true}); got != 0 {
+		t.Errorf("alice was offered her own proposal %d for sign-off", got)
+	}
+	if got := claim(t, s, "bob", store.ReviewFilter{SignOff: true}); got != 2 {
+		t.Fatalf("bob's sign-off queue gave %d, want 2", got)
+	}
+	action, err = s.Accept(ctx, 2, "bob", "100062000002", "")
+	if err != nil || action != store.ReviewConfirm {
+		t.Fatalf("sign-off = %s, %v; want confirm", action, err)
+	}
+	a, _ := m.Accepted(ctx, 2)
+	if a == nil || a.UPRN != "100062000002" || !a.Manual {
+		t.Fatalf("confirmed accept = %+v, want a manual accept of UPRN 100062000002", a)
+	}
+
+	counts, _ := s.Counts(ctx)
+	if counts.Done != 1 || counts.AwaitingSignoff != 0 {
+		t.Errorf("counts after sign-off = %+v", counts)
+	}
+}
+
+func TestRefusedProposalReturnsToQueue(t *testing.T) {
+	ctx := context.Background()
+	_, s, _ := testQueue(t)
+	low, _ := ParseBand("low")
+
+	claim(t, s, "alice", low.Filter(store.ReviewFilter{}))
+	s.Accept(ctx, 2, "alice", "100062000003", "")
+	claim(t, s, "bob", store.ReviewFilter{SignOff: true})
+	if action, err := s.Reject(ctx, 2, "bob", "wrong side of the road"); err != nil || action != store.ReviewRefuse {
+		t.Fatalf("refusal = %s, %v; want refuse", action, err)
+	}
+	item, _ := s.Item(ctx, 2)
+	if item.Status != store.ReviewOpen || item.Proposal != nil {
+		t.Errorf("refused item is %s with proposal %+v, want open with none", item.Status, item.Proposal)
+	}
+}
+
+type seniorKey struct{}
+
+func TestSignoffNeedsPolicyRole(t *testing.T) {
+	_, s, _ := testQueue(t)
+	s.policy.CanSignOff = func(ctx context.Context) bool { return ctx.Value(seniorKey{}) != nil }
+	ctx := context.Background()
+	senior := context.WithValue(ctx, seniorKey{}, true)
+	low, _ := ParseBand("low")
+
+	claim(t, s, "alice", low.Filter(store.ReviewFilter{}))
+	if action, err := s.Accept(ctx, 2, "alice", "100062000002", ""); err != nil || action != store.ReviewPropose {
+		t.Fatalf("low-confidence accept = %s, %v; want propose", action, err)
+	}
+
+	if _, err := s.Claim(ctx, "bob", store.ReviewFilter{SignOff: true}); !errors.Is(err, ErrSignoffRole) {
+		t.Errorf("claiming for sign-off without the role: %v, want ErrSignoffRole", err)
+	}
+	if _, err := s.Accept(ctx, 2, "bob", "100062000002", ""); !errors.Is(err, ErrSignoffRole) {
+		t.Errorf("confirming without the role: %v, want ErrSignoffRole", err)
+	}
+	if _, err := s.Reject(ctx, 2, "bob", ""); !errors.Is(err, ErrSignoffRole) {
+		t.Errorf("refusing without the role: %v, want ErrSignoffRole", err)
+	}
+
+	if item, err := s.Claim(senior, "carol", store.ReviewFilter{SignOff: true}); err != nil || item == nil || item.SrcID != 2 {
+		t.Fatalf("senior sign-off claim = %+v, %v; want item 2", item, err)
+	}
+	if action, err := s.Accept(senior, 2, "carol", "100062000002", ""); err != nil || action != store.ReviewConfirm {
+		t.Errorf("senior sign-off = %s, %v; want confirm", action, err)
+	}
+}
+
+func TestDirectDecisionsGoThroughQueue(t *testing.T) {
+	ctx := context.Background()
+	_, s, _ := testQueue(t)
+
+	// Queued documents are settled in the queue, under its lease and sign-off rules
+	if queued, err := s.Direct(ctx, 1, true, 0.92); err != nil || !queued {
+		t.Errorf("Direct accept of queued document 1 = %v, %v; want queued", queued, err)
+	}
+	if _, err := s.Accept(ctx, 1, "alice", "100062000001", ""); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("accept of an unclaimed document: %v, want ErrLeaseLost", err)
+	}
+	if _, err := s.Reject(ctx, 2, "alice", ""); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("reject of an unclaimed document: %v, want ErrLeaseLost", err)
+	}
+
+	// Elsewhere the stored confidence decides whether one reviewer may accept
+	if _, err := s.Direct(ctx, 3, true, 0.55); !errors.Is(err, ErrNeedsSignoff) {
+		t.Errorf("Direct accept below sign-off: %v, want ErrNeedsSignoff", err)
+	}
+	if queued, err := s.Direct(ctx, 3, true, 0.9); err != nil || queued {
+		t.Errorf("Direct accept above sign-off = %v, %v", queued, err)
+	}
+	if queued, err := s.Direct(ctx, 3, false, 0); err != nil || queued {
+		t.Errorf("Direct reject = %v, %v", queued, err)
+	}
+}
+
+func TestSkipAndDefer(t *testing.T) {
+	ctx := context.Background()
+	_, s, now := testQueue(t)
+
+	claim(t, s, "alice", store.ReviewFilter{})
+	if err := s.Skip(ctx, 1, "alice"); err != nil {
+		t.Fatalf("Skip: %v", err)
+	}
+	if got := claim(t, s, "alice", store.ReviewFilter{}); got != 2 {
+		t.Errorf("after skipping 1 alice claimed %d, want 2", got)
+	}
+	if err := s.Defer(ctx, 2, "alice", now.Add(time.Hour), "ask planning"); err != nil {
+		t.Fatalf("Defer: %v", err)
+	}
+	if got := claim(t, s, "alice", store.ReviewFilter{}); got != 0 {
+		t.Errorf("alice claimed %d; 1 was skipped and 2 deferred", got)
+	}
+	if got := claim(t, s, "bob", store.ReviewFilter{}); got != 1 {
+		t.Errorf("bob claimed %d, want 1 which only alice skipped", got)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if got := claim(t, s, "alice", store.ReviewFilter{}); got != 2 {
+		t.Errorf("alice claimed %d once the deferral passed, want 2", got)
+	}
+}
+
+func TestReviewerStats(t *testing.T) {
+	ctx := context.Background()
+	_, s, now := testQueue(t)
+
+	claim(t, s, "alice", store.ReviewFilter{})
+	*now = now.Add(8 * time.Minute)
+	if err := s.Renew(ctx, 1, "alice"); err != nil {
+		t.Fatalf("Renew: %v", err)
+	}
+	*now = now.Add(7 * time.Minute)
+	if _, err := s.Accept(ctx, 1, "alice", "100062000001", ""); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	claim(t, s, "bob", store.ReviewFilter{})
+
+	stats, err := s.Stats(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Reviewer != "alice" {
+		t.Fatalf("stats = %+v, want alice first", stats)
+	}
+	alice, bob := stats[0], stats[1]
+	if alice.Decisions() != 1 || alice.Held != 15*time.Minute || alice.PerHour() != 4 {
+		t.Errorf("alice: %d decisions in %s (%.1f/h), want 1 in 15m", alice.Decisions(), alice.Held, alice.PerHour())
+	}
+	if bob.Holding != 1 || bob.Decisions() != 0 {
+		t.Errorf("bob holds %d with %d decisions, want 1 and 0", bob.Holding, bob.Decisions())
+	}
+}
@@ -0,0 +1,127 @@
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/lib/pq"
+)
+
+// Change kinds, from the first snapshot to the second
+const (
+	Added             = "added"      // document only in the second snapshot
+	Removed           = "removed"    // document only in the first
+	NewlyMatched      = "matched"    // unmatched, now matched
+	NoLongerMatched   = "unmatched"  // matched, now unmatched
+	UPRNChanged       = "uprn"       // matched to a different UPRN
+	MethodChanged     = "method"     // same UPRN by a different method
+	ConfidenceChanged = "confidence" // same UPRN and method, different confidence
+	Unchanged         = "unchanged"
+)
+
+// ChangeKinds lists the kinds that are reported, in report order
+var ChangeKinds = []string{Added, Removed, NewlyMatched, NoLongerMatched, UPRNChanged, MethodChanged, ConfidenceChanged}
+
+// Side is one document in one snapshot
+type Side struct {
+	Present    bool
+	UPRN       string // empty when unmatched
+	Method     string
+	Confidence *float64
+}
+
+// Change is a document whose match differs between the snapshots
+type Change struct {
+	DocumentID int64
+	Kind       string
+	From       Side
+	To         Side
+}
+
+// DiffReport compares two snapshots
+type DiffReport struct {
+	From    Snapshot
+	To      Snapshot
+	Counts  map[string]int // by kind, including Unchanged
+	Changes []Change       // ordered by document ID
+}
+
+// confidenceEpsilon is half the resolution of match_confidence_score, DECIMAL(5,4)
+const confidenceEpsilon = 0.00005
+
+// Classify reports how a document's match changed
+func Classify(from, to Side) string {
+	switch {
+	case !from.Present:
+		return Added
+	case !to.Present:
+		return Removed
+	case from.UPRN == "" && to.UPRN == "":
+		return Unchanged
+	case from.UPRN == "":
+		return NewlyMatched
+	case to.UPRN == "":
+		return NoLongerMatched
+	case from.UPRN != to.UPRN:
+		return UPRNChanged
+	case from.Method != to.Method:
+		return MethodChanged
+	case (from.Confidence == nil) != (to.Confidence == nil):
+		return ConfidenceChanged
+	case from.Confidence != nil && math.Abs(*from.Confidence-*to.Confidence) >= confidenceEpsilon:
+		return ConfidenceChanged
+	}
+	return Unchanged
+}
+
+// Diff compares the per-document UPRN, method and confidence of two snapshots
+func Diff(ctx context.Context, db *sql.DB, fromRef, toRef string) (*DiffReport, error) {
+	from, err := Get(ctx, db, fromRef)
+	if err != nil {
+		return nil, err
+	}
+	to, err := Get(ctx, db, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(a.document_id, b.document_id),
+			a.document_id IS NOT NULL, COALESCE(a.snapshot_uprn, ''), COALESCE(a.snapshot_method, ''), a.match_confidence_score,
+			b.document_id IS NOT NULL, COALESCE(b.snapshot_uprn, ''), COALESCE(b.snapshot_method, ''), b.match_confidence_score
+		FROM %s a
+		FULL OUTER JOIN %s b ON b.document_id = a.document_id
+		ORDER BY 1
+	`, pq.QuoteIdentifier(from.TableName), pq.QuoteIdentifier(to.TableName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s and %s: %w", from.Name, to.Name, err)
+	}
+	defer rows.Close()
+
+	report := &DiffReport{From: *from, To: *to, Counts: make(map[string]int)}
+	for rows.Next() {
+		var c Change
+		var fromConfidence, toConfidence sql.NullFloat64
+		err := rows.Scan(&c.DocumentID,
+			&c.From.Present, &c.From.UPRN, &c.From.Method, &fromConfidence,
+			&c.To.Present, &c.To.UPRN, &c.To.Method, &toConfidence)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan diff row: %w", err)
+		}
+		if fromConfidence.Valid {
+			c.From.Confidence = &fromConfidence.Float64
+		}
+		if toConfidence.Valid {
+			c.To.Confidence = &toConfidence.Float64
+		}
+
+		c.Kind = Classify(c.From, c.To)
+		report.Counts[c.Kind]++
+		if c.Kind != Unchanged {
+			report.Changes = append(report.Changes, c)
+		}
+	}
+	return report, rows.Err()
+}
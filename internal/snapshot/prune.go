@@ -0,0 +1,93 @@
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	database "github.com/ehdc-llpg/internal/db"
+)
+
+// Retention decides which snapshots are pruned. A snapshot is pruned when it is older than
+// MaxAge (any age when MaxAge is 0), is not among the KeepLast newest and is not marked
+// keep. At least one limit must be set.
+type Retention struct {
+	MaxAge   time.Duration
+	KeepLast int
+}
+
+// ParseAge parses a retention age: a Go duration or a whole number of days, e.g. 30d
+func ParseAge(s string) (time.Duration, error) {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// Expired selects the snapshots r prunes from snapshots, which are newest first
+func (r Retention) Expired(snapshots []Snapshot, now time.Time) []Snapshot {
+	var expired []Snapshot
+	for i, s := range snapshots {
+		if i < r.KeepLast || s.Keep {
+			continue
+		}
+		if r.MaxAge > 0 && now.Sub(s.CreatedAt) <= r.MaxAge {
+			continue
+		}
+		expired = append(expired, s)
+	}
+	return expired
+}
+
+// Prune drops the snapshots expired under r with their catalogue rows, returning them.
+// With dryRun nothing is dropped.
+func Prune(ctx context.Context, db *sql.DB, r Retention, dryRun bool) ([]Snapshot, error) {
+	if r.MaxAge <= 0 && r.KeepLast <= 0 {
+		return nil, fmt.Errorf("a maximum age or a number of snapshots to keep is required")
+	}
+
+	snapshots, err := List(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	expired := r.Expired(snapshots, time.Now())
+	if dryRun || len(expired) == 0 {
+		return expired, nil
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range expired {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+pq.QuoteIdentifier(s.TableName)); err != nil {
+			return nil, fmt.Errorf("failed to drop %s: %w", s.TableName, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM fact_snapshot WHERE snapshot_id = $1", s.ID); err != nil {
+			return nil, fmt.Errorf("failed to remove %s from the catalogue: %w", s.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit prune: %w", err)
+	}
+	return expired, nil
+}
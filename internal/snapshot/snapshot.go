@@ -0,0 +1,300 @@
+// Package snapshot keeps named, timestamped copies of fact_documents_lean. Each snapshot
+// is a table catalogued in fact_snapshot with the run and configuration that produced it;
+// snapshots can be compared per document, restored into the fact table and pruned by
+// retention. Copying, comparing and restoring read the whole fact table, so they are
+// bounded only by ctx, like the bulk loads; catalogue queries use DB_QUERY_TIMEOUT.
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/debug"
+)
+
+// Snapshot is a fact_snapshot catalogue row
+type Snapshot struct {
+	ID         int64      `json:"snapshot_id"`
+	Name       string     `json:"name"`
+	TableName  string     `json:"table_name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RunID      *int64     `json:"run_id,omitempty"`
+	ConfigHash string     `json:"config_hash"`
+	Notes      string     `json:"notes"`
+	Rows       int        `json:"row_count"`
+	Matched    int        `json:"matched_count"`
+	Keep       bool       `json:"keep"`
+	RestoredAt *time.Time `json:"restored_at,omitempty"`
+}
+
+// Options describe a new snapshot
+type Options struct {
+	Name       string // unique; DefaultName("snapshot", now) when empty
+	RunID      int64  // 0 when the snapshot is not tied to a run
+	ConfigHash string // config.Hash of the configuration in effect
+	Notes      string
+	Keep       bool // exempt from pruning
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]*$`)
+
+// DefaultName is prefix with a timestamp, e.g. layer_2-20261018-153012
+func DefaultName(prefix string, t time.Time) string {
+	return prefix + "-" + t.Format("20060102-150405")
+}
+
+// Create copies fact_documents_lean into a new catalogued table. The UPRN and method code
+// of each match are copied with it, so diffs stay meaningful if the LLPG changes.
+func Create(ctx context.Context, localDebug bool, db *sql.DB, opts Options) (*Snapshot, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	if opts.Name == "" {
+		opts.Name = DefaultName("snapshot", time.Now())
+	}
+	if !validName.MatchString(opts.Name) {
+		return nil, fmt.Errorf("invalid snapshot name %q: use letters, digits and _ . : -", opts.Name)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, `SELECT nextval(pg_get_serial_sequence('fact_snapshot', 'snapshot_id'))`).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to allocate snapshot id: %w", err)
+	}
+	table := fmt.Sprintf("fact_snapshot_%d", id)
+
+	var runID interface{}
+	if opts.RunID != 0 {
+		runID = opts.RunID
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO fact_snapshot (snapshot_id, name, table_name, run_id, config_hash, notes, keep)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, id, opts.Name, table, runID, opts.ConfigHash, opts.Notes, opts.Keep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to catalogue snapshot %s: %w", opts.Name, err)
+	}
+
+	debug.DebugOutput(localDebug, "Copying fact_documents_lean into %s", table)
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE %s AS
+		SELECT f.*, a.uprn AS snapshot_uprn, mm.method_code AS snapshot_method
+		FROM fact_documents_lean f
+		LEFT JOIN dim_address a ON a.address_id = f.matched_address_id
+		LEFT JOIN dim_match_method mm ON mm.method_id = f.match_method_id
+	`, pq.QuoteIdentifier(table)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy fact table into %s: %w", table, err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX ON %s (document_id)", pq.QuoteIdentifier(table))); err != nil {
+		return nil, fmt.Errorf("failed to index %s: %w", table, err)
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE fact_snapshot
+		SET row_count = s.row_count, matched_count = s.matched_count
+		FROM (
+			SELECT COUNT(*) AS row_count, COUNT(*) FILTER (WHERE matched_address_id IS NOT NULL) AS matched_count
+			FROM %s
+		) s
+		WHERE snapshot_id = $1
+	`, pq.QuoteIdentifier(table)), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count snapshot rows: %w", err)
+	}
+
+	snap, err := scanSnapshot(tx.QueryRowContext(ctx, catalogueQuery+" WHERE snapshot_id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+
+	debug.DebugOutput(localDebug, "Snapshot %s: %d rows, %d matched", snap.Name, snap.Rows, snap.Matched)
+	return snap, nil
+}
+
+// List returns every catalogued snapshot, newest first
+func List(ctx context.Context, db *sql.DB) ([]Snapshot, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, catalogueQuery+" ORDER BY created_at DESC, snapshot_id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []Snapshot
+	for rows.Next() {
+		snap, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snap)
+	}
+	return snapshots, rows.Err()
+}
+
+// Get finds a snapshot by name or ID
+func Get(ctx context.Context, db *sql.DB, ref string) (*Snapshot, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query, arg := catalogueQuery+" WHERE name = $1", interface{}(ref)
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		query, arg = catalogueQuery+" WHERE snapshot_id = $1", id
+	}
+
+	snap, err := scanSnapshot(db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("snapshot %s not found", ref)
+	}
+	return snap, err
+}
+
+const catalogueQuery = `
+	SELECT snapshot_id, name, table_name, created_at, run_id, COALESCE(config_hash, ''),
+		COALESCE(notes, ''), row_count, matched_count, keep, restored_at
+	FROM fact_snapshot`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSnapshot reads a catalogueQuery row, passing sql.ErrNoRows through
+func scanSnapshot(row scanner) (*Snapshot, error) {
+	var s Snapshot
+	var runID sql.NullInt64
+	var restoredAt sql.NullTime
+	err := row.Scan(&s.ID, &s.Name, &s.TableName, &s.CreatedAt, &runID, &s.ConfigHash,
+		&s.Notes, &s.Rows, &s.Matched, &s.Keep, &restoredAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if runID.Valid {
+		s.RunID = &runID.Int64
+	}
+	if restoredAt.Valid {
+		s.RestoredAt = &restoredAt.Time
+	}
+	return &s, nil
+}
+
+// Restore replaces the contents of fact_documents_lean with a snapshot in one transaction.
+// When backup is not nil the current contents are snapshotted first, so the restore can
+// itself be undone. It returns the backup, if any, and the rows restored.
+func Restore(ctx context.Context, localDebug bool, db *sql.DB, ref string, backup *Options) (*Snapshot, int64, error) {
+	debug.DebugHeader(localDebug)
+	defer debug.DebugFooter(localDebug)
+
+	snap, err := Get(ctx, db, ref)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var saved *Snapshot
+	if backup != nil {
+		if saved, err = Create(ctx, localDebug, db, *backup); err != nil {
+			return nil, 0, fmt.Errorf("failed to snapshot the fact table before restoring: %w", err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return saved, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE fact_documents_lean IN ACCESS EXCLUSIVE MODE"); err != nil {
+		return saved, 0, fmt.Errorf("failed to lock fact_documents_lean: %w", err)
+	}
+
+	columns, err := restorableColumns(ctx, tx, snap.TableName)
+	if err != nil {
+		return saved, 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM fact_documents_lean"); err != nil {
+		return saved, 0, fmt.Errorf("failed to clear fact_documents_lean: %w", err)
+	}
+
+	list := strings.Join(columns, ", ")
+	result, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO fact_documents_lean (%s) SELECT %s FROM %s",
+		list, list, pq.QuoteIdentifier(snap.TableName)))
+	if err != nil {
+		return saved, 0, fmt.Errorf("failed to restore %s: %w", snap.Name, err)
+	}
+	restored, _ := result.RowsAffected()
+
+	// Restored rows keep their fact_id; move the sequence past them
+	_, err = tx.ExecContext(ctx, `
+		SELECT setval(pg_get_serial_sequence('fact_documents_lean', 'fact_id'),
+			COALESCE((SELECT MAX(fact_id) FROM fact_documents_lean), 0) + 1, false)
+	`)
+	if err != nil {
+		return saved, 0, fmt.Errorf("failed to reset fact_id sequence: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE fact_snapshot SET restored_at = now() WHERE snapshot_id = $1", snap.ID); err != nil {
+		return saved, 0, fmt.Errorf("failed to record restore: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return saved, 0, fmt.Errorf("failed to commit restore: %w", err)
+	}
+
+	debug.DebugOutput(localDebug, "Restored %d rows from %s", restored, snap.Name)
+	return saved, restored, nil
+}
+
+// restorableColumns lists the fact_documents_lean columns that can be written and exist in
+// the snapshot table, quoted. Generated columns are recomputed on insert.
+func restorableColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT f.column_name
+		FROM information_schema.columns f
+		JOIN information_schema.columns s
+		  ON s.table_schema = f.table_schema AND s.table_name = $1 AND s.column_name = f.column_name
+		WHERE f.table_schema = current_schema()
+		  AND f.table_name = 'fact_documents_lean'
+		  AND f.is_generated = 'NEVER'
+		ORDER BY f.ordinal_position
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fact table columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, pq.QuoteIdentifier(column))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("snapshot table %s shares no columns with fact_documents_lean", table)
+	}
+	return columns, nil
+}
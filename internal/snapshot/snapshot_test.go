@@ -0,0 +1,83 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	conf := func(f float64) *float64 { return &f }
+	matched := Side{Present: true, UPRN: "100062000001", Method: "exact_uprn", Confidence: conf(0.95)}
+
+	tests := []struct {
+		name     string
+		from, to Side
+		want     string
+	}{
+		{"added", Side{}, matched, Added},
+		{"removed", matched, Side{}, Removed},
+		{"still unmatched", Side{Present: true}, Side{Present: true}, Unchanged},
+		{"newly matched", Side{Present: true}, matched, NewlyMatched},
+		{"no longer matched", matched, Side{Present: true}, NoLongerMatched},
+		{"uprn", matched, Side{Present: true, UPRN: "100062000002", Method: "exact_uprn", Confidence: conf(0.95)}, UPRNChanged},
+		{"method", matched, Side{Present: true, UPRN: "100062000001", Method: "fuzzy_text", Confidence: conf(0.95)}, MethodChanged},
+		{"confidence", matched, Side{Present: true, UPRN: "100062000001", Method: "exact_uprn", Confidence: conf(0.90)}, ConfidenceChanged},
+		{"confidence dropped", matched, Side{Present: true, UPRN: "100062000001", Method: "exact_uprn"}, ConfidenceChanged},
+		{"rounding", matched, Side{Present: true, UPRN: "100062000001", Method: "exact_uprn", Confidence: conf(0.95000001)}, Unchanged},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: Classify = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetentionExpired(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	snapshots := []Snapshot{ // newest first
+		{Name: "today", CreatedAt: now.Add(-time.Hour)},
+		{Name: "last-week", CreatedAt: now.Add(-7 * day)},
+		{Name: "last-month", CreatedAt: now.Add(-35 * day), Keep: true},
+		{Name: "two-months", CreatedAt: now.Add(-60 * day)},
+		{Name: "old", CreatedAt: now.Add(-90 * day)},
+	}
+
+	tests := []struct {
+		r    Retention
+		want []string
+	}{
+		{Retention{MaxAge: 30 * day}, []string{"two-months", "old"}},
+		{Retention{KeepLast: 2}, []string{"two-months", "old"}},
+		{Retention{MaxAge: 5 * day, KeepLast: 4}, []string{"old"}},
+		{Retention{MaxAge: 100 * day}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range tt.r.Expired(snapshots, now) {
+			got = append(got, s.Name)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%+v expired %v, want %v", tt.r, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%+v expired %v, want %v", tt.r, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	if d, err := ParseAge("30d"); err != nil || d != 30*24*time.Hour {
+		t.Errorf("ParseAge(30d) = %v, %v", d, err)
+	}
+	if d, err := ParseAge("36h"); err != nil || d != 36*time.Hour {
+		t.Errorf("ParseAge(36h) = %v, %v", d, err)
+	}
+	if _, err := ParseAge("a month"); err == nil {
+		t.Error("ParseAge accepted \"a month\"")
+	}
+}
@@ -0,0 +1,1841 @@
+package store
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixture files read by LoadFixtures; documents.csv is optional
+const (
+	AddressFixture  = "addresses.csv"
+	DocumentFixture = "documents.csv"
+)
+
+var postcodeFormat = regexp.MustCompile(`^[A-Z]{1,2}[0-9]{1,2}[A-Z]?\s*[0-9][A-Z]{2}$`)
+
+// Memory implements every store in memory. Trigram queries use TrigramSimilarity, which
+// follows pg_trgm, so engines rank candidates as they would against Postgres.
+type Memory struct {
+	mu          sync.Mutex
+	addresses   []Address
+	documents   []SourceDocument
+	components  map[int64]Components
+	runs        []*MatchRun
+	checkpoints map[int64]*Checkpoint
+	processed   map[int64]map[string]bool
+	chunks      []*queuedChunk
+	reviews     map[int64]*queuedReview
+	reviewLog   []reviewEvent
+	clock       func() time.Time // nil for time.Now
+	results     []MatchResult
+	accepted    map[int64]Acceptance
+	matches     map[int64]AddressMatch
+	audits      map[int64]DecisionRecord // by match_id
+	overrides   []override
+	explained   []Explanation
+	rejections  []Rejection
+	events      []recordEvent
+	notes       []RecordNote
+}
+
+// recordEvent is a v_record_audit_history row
+type recordEvent struct {
+	srcID int64
+	event RecordEvent
+}
+
+// override is a match_override row
+type override struct {
+	srcID int64
+	entry OverrideHistoryEntry
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		components:  make(map[int64]Components),
+		accepted:    make(map[int64]Acceptance),
+		matches:     make(map[int64]AddressMatch),
+		audits:      make(map[int64]DecisionRecord),
+		checkpoints: make(map[int64]*Checkpoint),
+		processed:   make(map[int64]map[string]bool),
+	}
+}
+
+// LoadFixtures creates a store from addresses.csv and documents.csv in dir
+func LoadFixtures(dir string) (*Memory, error) {
+	m := NewMemory()
+
+	err := readFixture(filepath.Join(dir, AddressFixture), func(r fixtureRow) error {
+		a := Address{
+			AddressID:   r.int("address_id"),
+			LocationID:  r.int("location_id"),
+			UPRN:        r.str("uprn"),
+			FullAddress: r.str("full_address"),
+			Canonical:   r.str("address_canonical"),
+			USRN:        r.ptr("usrn"),
+			BLPUClass:   r.ptr("blpu_class"),
+			Status:      r.ptr("status_code"),
+			Easting:     r.float("easting"),
+			Northing:    r.float("northing"),
+			StartDate:   r.date("start_date"),
+			EndDate:     r.date("end_date"),
+			Components: Components{
+				HouseNumber: r.str("house_number"),
+				Road:        r.str("road"),
+				City:        r.str("city"),
+				Postcode:    r.str("postcode"),
+				Unit:        r.str("unit"),
+			},
+		}
+		m.AddAddress(a)
+		return r.err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	docPath := filepath.Join(dir, DocumentFixture)
+	if _, err := os.Stat(docPath); err == nil {
+		err = readFixture(docPath, func(r fixtureRow) error {
+			m.AddDocument(SourceDocument{
+				SrcID:        int64(r.int("src_id")),
+				SourceType:   r.str("source_type"),
+				RawAddress:   r.str("raw_address"),
+				AddrCan:      r.ptr("addr_can"),
+				PostcodeText: r.ptr("postcode_text"),
+				UPRNRaw:      r.ptr("uprn_raw"),
+				DocDate:      r.date("doc_date"),
+				Easting:      r.float("easting"),
+				Northing:     r.float("northing"),
+			})
+			return r.err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// AddAddress adds an LLPG address
+func (m *Memory) AddAddress(a Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addresses = append(m.addresses, a)
+}
+
+// AddDocument adds a source document, keeping documents in src_id order
+func (m *Memory) AddDocument(d SourceDocument) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.documents = append(m.documents, d)
+	sort.Slice(m.documents, func(i, j int) bool { return m.documents[i].SrcID < m.documents[j].SrcID })
+}
+
+// Results returns every saved match result
+func (m *Memory) Results() []MatchResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MatchResult(nil), m.results...)
+}
+
+// AddressMatches returns the address_match rows by document
+func (m *Memory) AddressMatches() map[int64]AddressMatch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matches := make(map[int64]AddressMatch, len(m.matches))
+	for k, v := range m.matches {
+		matches[k] = v
+	}
+	return matches
+}
+
+// Memory queries return immediately, so only the calls a long run loops on (paging,
+// candidate search and writes) check ctx, matching a cancelled query in Postgres
+func (m *Memory) SimilarAddresses(ctx context.Context, canonical string, minSimilarity float64, limit int) ([]ScoredAddress, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var scored []ScoredAddress
+	for _, a := range m.addresses {
+		sim := TrigramSimilarity(canonical, a.Canonical)
+		if sim >= trgmThreshold && sim >= minSimilarity {
+			scored = append(scored, ScoredAddress{Address: a, Similarity: sim})
+		}
+	}
+	return topScored(scored, limit), nil
+}
+
+func (m *Memory) AddressesInPostcode(ctx context.Context, postcode string) ([]Address, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	compact := strings.ReplaceAll(postcode, " ", "")
+	var addresses []Address
+	for _, a := range m.addresses {
+		if strings.HasSuffix(a.FullAddress, postcode) || strings.HasSuffix(a.FullAddress, compact) {
+			addresses = append(addresses, a)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].UPRN < addresses[j].UPRN })
+	return addresses, nil
+}
+
+func (m *Memory) AddressesByComponents(ctx context.Context, q Components, limit int) ([]Address, error) {
+	if q.Postcode == "" && q.HouseNumber == "" && q.Road == "" && q.City == "" {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var addresses []Address
+	for _, a := range m.addresses {
+		c := a.Components
+		if (q.Postcode == "" || c.Postcode == q.Postcode) &&
+			(q.HouseNumber == "" || c.HouseNumber == q.HouseNumber) &&
+			(q.Road == "" || c.Road == q.Road) &&
+			(q.City == "" || c.City == q.City) {
+			addresses = append(addresses, a)
+			if len(addresses) == limit {
+				break
+			}
+		}
+	}
+	return addresses, nil
+}
+
+func (m *Memory) AddressesByRoad(ctx context.Context, road, city string, minSimilarity float64, limit int) ([]ScoredAddress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var scored []ScoredAddress
+	for _, a := range m.addresses {
+		if city != "" && a.Components.City != city {
+			continue
+		}
+		sim := TrigramSimilarity(a.Components.Road, road)
+		if sim >= trgmThreshold && sim >= minSimilarity {
+			scored = append(scored, ScoredAddress{Address: a, Similarity: sim})
+		}
+	}
+	return topScored(scored, limit), nil
+}
+
+func (m *Memory) Similarity(ctx context.Context, a, b string) (float64, error) {
+	return TrigramSimilarity(a, b), nil
+}
+
+// topScored sorts by similarity (then UPRN for a stable order) and truncates
+func topScored(scored []ScoredAddress, limit int) []ScoredAddress {
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Similarity != scored[j].Similarity {
+			return scored[i].Similarity > scored[j].Similarity
+		}
+		return scored[i].UPRN < scored[j].UPRN
+	})
+	if limit > 0 && len(scored) > limit {
+		scored = scored[:limit]
+	}
+	return scored
+}
+
+func (m *Memory) UnmatchedDocuments(ctx context.Context, afterSrcID int64, limit int, sourceType string) ([]SourceDocument, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.unmatched(afterSrcID, limit, func(d SourceDocument) bool {
+		return sourceType == "" || d.SourceType == sourceType
+	}), nil
+}
+
+func (m *Memory) UnmatchedWithPostcode(ctx context.Context, afterSrcID int64, limit int) ([]SourceDocument, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.unmatched(afterSrcID, limit, func(d SourceDocument) bool {
+		return d.PostcodeText != nil && *d.PostcodeText != "" && d.AddrCan != nil && *d.AddrCan != "N A"
+	}), nil
+}
+
+func (m *Memory) unmatched(afterSrcID int64, limit int, keep func(SourceDocument) bool) []SourceDocument {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var docs []SourceDocument
+	for _, d := range m.documents {
+		if d.SrcID <= afterSrcID || !keep(d) {
+			continue
+		}
+		if _, ok := m.accepted[d.SrcID]; ok {
+			continue
+		}
+		docs = append(docs, d)
+		if len(docs) == limit {
+			break
+		}
+	}
+	return docs
+}
+
+func (m *Memory) PostcodeQuality(ctx context.Context) (*PostcodeQuality, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := &PostcodeQuality{}
+	counts := make(map[string]int)
+	for _, d := range m.documents {
+		if d.PostcodeText == nil || *d.PostcodeText == "" {
+			q.WithoutPostcode++
+			continue
+		}
+		pc := *d.PostcodeText
+		q.WithPostcode++
+		counts[pc]++
+		if !postcodeFormat.MatchString(pc) {
+			q.Invalid++
+		}
+		if _, ok := m.accepted[d.SrcID]; !ok {
+			for _, a := range m.addresses {
+				if strings.Contains(a.FullAddress, pc) {
+					q.MatchPotential++
+					break
+				}
+			}
+		}
+	}
+
+	for pc, n := range counts {
+		q.TopPostcodes = append(q.TopPostcodes, PostcodeCount{Postcode: pc, Count: n})
+	}
+	sort.Slice(q.TopPostcodes, func(i, j int) bool {
+		if q.TopPostcodes[i].Count != q.TopPostcodes[j].Count {
+			return q.TopPostcodes[i].Count > q.TopPostcodes[j].Count
+		}
+		return q.TopPostcodes[i].Postcode < q.TopPostcodes[j].Postcode
+	})
+	if len(q.TopPostcodes) > 10 {
+		q.TopPostcodes = q.TopPostcodes[:10]
+	}
+	return q, nil
+}
+
+func (m *Memory) DocumentComponents(ctx context.Context, documentID int64) (Components, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.components[documentID]
+	return c, ok, nil
+}
+
+func (m *Memory) SaveDocumentComponents(ctx context.Context, documentID int64, c Components) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components[documentID] = c
+	return nil
+}
+
+func (m *Memory) CreateRun(ctx context.Context, label, algorithmVersion, notes string) (*MatchRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run := &MatchRun{
+		RunID:            int64(len(m.runs) + 1),
+		RunLabel:         label,
+		AlgorithmVersion: algorithmVersion,
+		Notes:            notes,
+		RunStartedAt:     time.Now(),
+		Status:           RunRunning,
+	}
+	m.runs = append(m.runs, run)
+	copied := *run
+	return &copied, nil
+}
+
+func (m *Memory) JoinRun(ctx context.Context, runID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.run(runID)
+	return err
+}
+
+func (m *Memory) CompleteRun(ctx context.Context, runID int64, status string, totals RunTotals) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run, err := m.run(runID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	run.RunCompletedAt = &now
+	run.Status = status
+	run.TotalProcessed, run.AutoAccepted, run.NeedsReview, run.Rejected = totals.Processed, totals.Accepted, totals.NeedsReview, totals.Rejected
+	return nil
+}
+
+func (m *Memory) run(runID int64) (*MatchRun, error) {
+	for _, run := range m.runs {
+		if run.RunID == runID {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("match run %d not found", runID)
+}
+
+func (m *Memory) CreateCheckpoint(ctx context.Context, runID int64, processor string, config json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.run(runID); err != nil {
+		return err
+	}
+	if _, ok := m.checkpoints[runID]; ok {
+		return fmt.Errorf("run %d already has a checkpoint", runID)
+	}
+	m.checkpoints[runID] = &Checkpoint{
+		RunID: runID, Processor: processor, Config: config, Stats: json.RawMessage("{}"),
+		Legs: 1, UpdatedAt: time.Now(),
+	}
+	return nil
+}
+
+func (m *Memory) Checkpoint(ctx context.Context, runID int64) (*Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.checkpoint(runID), nil
+}
+
+// checkpoint copies the run's checkpoint with its current status
+func (m *Memory) checkpoint(runID int64) *Checkpoint {
+	cp, ok := m.checkpoints[runID]
+	if !ok {
+		return nil
+	}
+	copied := *cp
+	if run, err := m.run(runID); err == nil {
+		copied.Status = run.Status
+	}
+	return &copied
+}
+
+func (m *Memory) SaveCheckpoint(ctx context.Context, runID, lastDocumentID int64, stats json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cp, ok := m.checkpoints[runID]
+	if !ok {
+		return fmt.Errorf("run %d has no checkpoint", runID)
+	}
+	cp.LastDocumentID, cp.Stats, cp.UpdatedAt = lastDocumentID, stats, time.Now()
+	return nil
+}
+
+func (m *Memory) ReopenRun(ctx context.Context, runID int64) (*Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := reopenable(runID, m.checkpoint(runID)); err != nil {
+		return nil, err
+	}
+	run, err := m.run(runID)
+	if err != nil {
+		return nil, err
+	}
+	run.Status, run.RunCompletedAt = RunRunning, nil
+	m.checkpoints[runID].Legs++
+	return m.checkpoint(runID), nil
+}
+
+func (m *Memory) MarkProcessed(ctx context.Context, runID int64, items []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.processed[runID] == nil {
+		m.processed[runID] = make(map[string]bool)
+	}
+	for _, item := range items {
+		m.processed[runID][item] = true
+	}
+	return nil
+}
+
+func (m *Memory) ProcessedItems(ctx context.Context, runID int64) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := make(map[string]bool, len(m.processed[runID]))
+	for item := range m.processed[runID] {
+		items[item] = true
+	}
+	return items, nil
+}
+
+// queuedChunk is a work_queue_chunk row
+type queuedChunk struct {
+	WorkChunk
+	status      string
+	claimedAt   time.Time
+	heartbeatAt time.Time
+	busy        time.Duration
+	result      ChunkResult
+	lastError   string
+}
+
+func (m *Memory) Enqueue(ctx context.Context, runID int64, kind string, items []string, chunkSize int) (int, error) {
+	if chunkSize <= 0 {
+		return 0, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chunks := 0
+	for start := 0; start < len(items); start += chunkSize {
+		end := start + chunkSize
+		if end > len(items) {
+			end = len(items)
+		}
+		m.chunks = append(m.chunks, &queuedChunk{
+			WorkChunk: WorkChunk{
+				ChunkID: int64(len(m.chunks) + 1), RunID: runID, Kind: kind,
+				Items: append([]string(nil), items[start:end]...),
+			},
+			status: ChunkPending,
+		})
+		chunks++
+	}
+	return chunks, nil
+}
+
+func (m *Memory) ClaimChunk(ctx context.Context, runID int64, workerID string) (*WorkChunk, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.chunks {
+		if c.RunID == runID && c.status == ChunkPending {
+			c.status, c.WorkerID = ChunkClaimed, workerID
+			c.Attempts++
+			c.claimedAt = m.now()
+			c.heartbeatAt = c.claimedAt
+			claimed := c.WorkChunk
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Memory) Heartbeat(ctx context.Context, chunkID int64, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.claimed(chunkID, workerID)
+	if c == nil {
+		return ErrChunkLost
+	}
+	c.heartbeatAt = m.now()
+	return nil
+}
+
+func (m *Memory) CompleteChunk(ctx context.Context, chunkID int64, workerID string, r ChunkResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.claimed(chunkID, workerID)
+	if c == nil {
+		return ErrChunkLost
+	}
+	now := m.now()
+	c.status, c.result, c.heartbeatAt = ChunkDone, r, now
+	c.busy = now.Sub(c.claimedAt)
+	return nil
+}
+
+func (m *Memory) ReleaseChunk(ctx context.Context, chunkID int64, workerID, reason string, maxAttempts int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.claimed(chunkID, workerID)
+	if c == nil {
+		return ErrChunkLost
+	}
+	if maxAttempts == 0 && c.Attempts > 0 {
+		c.Attempts--
+	}
+	release(c, reason, maxAttempts)
+	return nil
+}
+
+func (m *Memory) RequeueAbandoned(ctx context.Context, runID int64, staleAfter time.Duration, maxAttempts int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := m.now().Add(-staleAfter)
+	released := 0
+	for _, c := range m.chunks {
+		if c.RunID == runID && c.status == ChunkClaimed && c.heartbeatAt.Before(cutoff) {
+			release(c, "abandoned by "+c.WorkerID, maxAttempts)
+			released++
+		}
+	}
+	return released, nil
+}
+
+// claimed returns the chunk if workerID holds it
+func (m *Memory) claimed(chunkID int64, workerID string) *queuedChunk {
+	for _, c := range m.chunks {
+		if c.ChunkID == chunkID && c.WorkerID == workerID && c.status == ChunkClaimed {
+			return c
+		}
+	}
+	return nil
+}
+
+// release returns a claimed chunk to pending, or fails it after maxAttempts claims
+func release(c *queuedChunk, reason string, maxAttempts int) {
+	c.lastError = reason
+	if maxAttempts > 0 && c.Attempts >= maxAttempts {
+		c.status = ChunkFailed
+		return
+	}
+	c.status, c.WorkerID = ChunkPending, ""
+}
+
+func (m *Memory) QueueProgress(ctx context.Context, runID int64) (*QueueProgress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var q QueueProgress
+	for _, c := range m.chunks {
+		if c.RunID != runID {
+			continue
+		}
+		switch c.status {
+		case ChunkPending:
+			q.Pending++
+		case ChunkClaimed:
+			q.Claimed++
+		case ChunkDone:
+			q.Done++
+		case ChunkFailed:
+			q.Failed++
+		}
+		q.Items += len(c.Items)
+		q.Processed += c.result.Processed
+		q.Matched += c.result.Matched
+		q.Errors += c.result.Errors
+	}
+	return &q, nil
+}
+
+func (m *Memory) WorkerThroughput(ctx context.Context, runID int64) ([]WorkerThroughput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byWorker := make(map[string]*WorkerThroughput)
+	var workers []*WorkerThroughput
+	for _, c := range m.chunks {
+		if c.RunID != runID || c.WorkerID == "" {
+			continue
+		}
+		w, ok := byWorker[c.WorkerID]
+		if !ok {
+			w = &WorkerThroughput{WorkerID: c.WorkerID}
+			byWorker[c.WorkerID] = w
+			workers = append(workers, w)
+		}
+		switch c.status {
+		case ChunkDone:
+			w.Chunks++
+			w.Processed += c.result.Processed
+			w.Matched += c.result.Matched
+			w.Errors += c.result.Errors
+			w.Busy += c.busy
+		case ChunkClaimed:
+			w.Holding++
+		}
+		if seen := c.heartbeatAt; !seen.IsZero() && (w.LastSeen == nil || seen.After(*w.LastSeen)) {
+			w.LastSeen = &seen
+		}
+	}
+
+	sort.SliceStable(workers, func(i, j int) bool {
+		if workers[i].Processed != workers[j].Processed {
+			return workers[i].Processed > workers[j].Processed
+		}
+		return workers[i].WorkerID < workers[j].WorkerID
+	})
+	result := make([]WorkerThroughput, len(workers))
+	for i, w := range workers {
+		result[i] = *w
+	}
+	return result, nil
+}
+
+// SetClock replaces the store's clock, for tests of lease and heartbeat expiry
+func (m *Memory) SetClock(clock func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = clock
+}
+
+// now is the store's clock, replaceable in tests of heartbeat expiry
+func (m *Memory) now() time.Time {
+	if m.clock != nil {
+		return m.clock()
+	}
+	return time.Now()
+}
+
+// queuedReview is a review_item row
+type queuedReview struct {
+	ReviewItem
+	claimedAt time.Time
+	skippedBy map[string]bool
+}
+
+// reviewEvent is a review_event row
+type reviewEvent struct {
+	srcID    int64
+	reviewer string
+	action   string
+	held     time.Duration
+	at       time.Time
+}
+
+// leased reports whether the item's lease is live at now
+func (r *queuedReview) leased(now time.Time) bool {
+	return r.ClaimedBy != "" && r.LeaseExpiresAt != nil && r.LeaseExpiresAt.After(now)
+}
+
+func (m *Memory) SyncReviewQueue(ctx context.Context) (int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reviews == nil {
+		m.reviews = make(map[int64]*queuedReview)
+	}
+	added := 0
+	for _, r := range m.results {
+		if r.Decision != "needs_review" {
+			continue
+		}
+		if _, ok := m.accepted[r.SrcID]; ok {
+			continue
+		}
+		item, ok := m.reviews[r.SrcID]
+		if !ok {
+			item = &queuedReview{ReviewItem: ReviewItem{SrcID: r.SrcID, Status: ReviewOpen}, skippedBy: make(map[string]bool)}
+			for _, d := range m.documents {
+				if d.SrcID == r.SrcID {
+					item.SourceType, item.RawAddress = d.SourceType, d.RawAddress
+				}
+			}
+			m.reviews[r.SrcID] = item
+			added++
+		}
+		if r.Confidence > item.Confidence {
+			item.Confidence = r.Confidence
+		}
+	}
+
+	now, closed := m.now(), 0
+	for srcID, item := range m.reviews {
+		if _, ok := m.accepted[srcID]; ok && item.Status != ReviewDone && !item.leased(now) {
+			item.Status, item.ClaimedBy, item.LeaseExpiresAt = ReviewDone, "", nil
+			closed++
+		}
+	}
+	return added, closed, nil
+}
+
+func (m *Memory) ClaimReview(ctx context.Context, reviewer string, f ReviewFilter, lease time.Duration) (*ReviewItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var next *queuedReview
+	for _, item := range m.reviews {
+		if item.Status != ReviewDone && item.ClaimedBy == reviewer && item.leased(now) {
+			return m.reviewItem(item), nil
+		}
+		if item.leased(now) || item.skippedBy[reviewer] || !f.admits(item, reviewer, now) {
+			continue
+		}
+		if next == nil || item.SrcID < next.SrcID {
+			next = item
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	if next.ClaimedBy != "" {
+		m.reviewLog = append(m.reviewLog, reviewEvent{srcID: next.SrcID, reviewer: next.ClaimedBy,
+			action: ReviewExpire, held: next.LeaseExpiresAt.Sub(next.claimedAt), at: now})
+	}
+	expires := now.Add(lease)
+	next.ClaimedBy, next.claimedAt, next.LeaseExpiresAt = reviewer, now, &expires
+	if next.Status == ReviewDeferred {
+		next.Status = ReviewOpen
+	}
+	next.DeferredUntil = nil
+	m.reviewLog = append(m.reviewLog, reviewEvent{srcID: next.SrcID, reviewer: reviewer, action: ReviewClaim, at: now})
+	return m.reviewItem(next), nil
+}
+
+// admits reports whether an unleased item is one the filter hands to reviewer
+func (f ReviewFilter) admits(item *queuedReview, reviewer string, now time.Time) bool {
+	if f.SignOff {
+		if item.Status != ReviewSignoff || item.Proposal == nil || item.Proposal.ProposedBy == reviewer {
+			return false
+		}
+	} else if item.Status != ReviewOpen &&
+		!(item.Status == ReviewDeferred && item.DeferredUntil != nil && !item.DeferredUntil.After(now)) {
+		return false
+	}
+	if f.SourceType != "" && item.SourceType != f.SourceType {
+		return false
+	}
+	if item.Confidence < f.MinConfidence {
+		return false
+	}
+	return f.MaxConfidence <= 0 || item.Confidence < f.MaxConfidence
+}
+
+func (m *Memory) ReviewItem(ctx context.Context, srcID int64) (*ReviewItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.reviews[srcID]
+	if !ok {
+		return nil, nil
+	}
+	return m.reviewItem(item), nil
+}
+
+// reviewItem copies a queued item with its best five needs_review candidates
+func (m *Memory) reviewItem(q *queuedReview) *ReviewItem {
+	item := q.ReviewItem
+	if q.Proposal != nil {
+		proposal := *q.Proposal
+		item.Proposal = &proposal
+	}
+	item.Candidates = []ReviewCandidate{}
+	for _, r := range m.results {
+		if r.SrcID == q.SrcID && r.Decision == "needs_review" {
+			item.Candidates = append(item.Candidates, ReviewCandidate{UPRN: r.CandidateUPRN,
+				Address: m.fullAddress(r.CandidateUPRN), Method: r.Method, Score: r.Score, Confidence: r.Confidence})
+		}
+	}
+	sort.SliceStable(item.Candidates, func(i, j int) bool { return item.Candidates[i].Score > item.Candidates[j].Score })
+	if len(item.Candidates) > 5 {
+		item.Candidates = item.Candidates[:5]
+	}
+	return &item
+}
+
+func (m *Memory) RenewReview(ctx context.Context, srcID int64, reviewer string, lease time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item := m.heldReview(srcID, reviewer)
+	if item == nil {
+		return ErrLeaseLost
+	}
+	expires := m.now().Add(lease)
+	item.LeaseExpiresAt = &expires
+	return nil
+}
+
+func (m *Memory) FinishReview(ctx context.Context, srcID int64, reviewer string, o ReviewOutcome) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item := m.heldReview(srcID, reviewer)
+	if item == nil {
+		return ErrLeaseLost
+	}
+	now := m.now()
+	m.reviewLog = append(m.reviewLog, reviewEvent{srcID: srcID, reviewer: reviewer, action: o.Action,
+		held: now.Sub(item.claimedAt), at: now})
+
+	switch o.Action {
+	case ReviewAccept, ReviewConfirm:
+		m.accepted[srcID] = Acceptance{SrcID: srcID, UPRN: o.Proposal.UPRN, Method: o.Proposal.Method,
+			Score: o.Proposal.Score, Confidence: o.Proposal.Confidence, AcceptedBy: reviewer, AcceptedAt: now, Manual: true}
+		m.settleCandidates(srcID, "manual_accepted", reviewer, o.Notes, now)
+	case ReviewReject:
+		m.settleCandidates(srcID, "manual_rejected", reviewer, o.Notes, now)
+	case ReviewSkip:
+		item.skippedBy[reviewer] = true
+	case ReviewPropose:
+		proposal := *o.Proposal
+		proposal.ProposedBy, proposal.ProposedAt, proposal.Reason = reviewer, now, o.Notes
+		item.Proposal = &proposal
+	case ReviewRefuse:
+		item.Proposal = nil
+	case ReviewDefer:
+		until := o.DeferUntil
+		item.DeferredUntil = &until
+	}
+
+	item.Status = reviewStatusAfter(o.Action, item.Status)
+	item.ClaimedBy, item.LeaseExpiresAt = "", nil
+	if o.Notes != "" {
+		item.Notes = o.Notes
+	}
+	return nil
+}
+
+// heldReview returns the item if reviewer holds a live lease on it
+func (m *Memory) heldReview(srcID int64, reviewer string) *queuedReview {
+	item, ok := m.reviews[srcID]
+	if !ok || item.Status == ReviewDone || item.ClaimedBy != reviewer || !item.leased(m.now()) {
+		return nil
+	}
+	return item
+}
+
+// settleCandidates marks a document's needs_review results with the reviewer's decision
+func (m *Memory) settleCandidates(srcID int64, decision, reviewer, notes string, at time.Time) {
+	for i := range m.results {
+		r := &m.results[i]
+		if r.SrcID == srcID && r.Decision == "needs_review" {
+			r.Decided, r.Decision, r.DecidedBy, r.DecidedAt, r.Notes = true, decision, reviewer, &at, notes
+		}
+	}
+}
+
+func (m *Memory) ReviewQueueCounts(ctx context.Context) (*ReviewQueueCounts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var c ReviewQueueCounts
+	for _, item := range m.reviews {
+		switch {
+		case item.Status == ReviewDone:
+			c.Done++
+		case item.leased(now):
+			c.Claimed++
+		case item.Status == ReviewOpen:
+			c.Open++
+		case item.Status == ReviewDeferred:
+			c.Deferred++
+		case item.Status == ReviewSignoff:
+			c.AwaitingSignoff++
+		}
+	}
+	return &c, nil
+}
+
+func (m *Memory) ReviewerStats(ctx context.Context, since time.Time) ([]ReviewerStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byReviewer := make(map[string]*ReviewerStats)
+	reviewer := func(name string) *ReviewerStats {
+		s, ok := byReviewer[name]
+		if !ok {
+			s = &ReviewerStats{Reviewer: name, Actions: make(map[string]int)}
+			byReviewer[name] = s
+		}
+		return s
+	}
+
+	for _, e := range m.reviewLog {
+		if e.at.Before(since) {
+			continue
+		}
+		s := reviewer(e.reviewer)
+		s.Actions[e.action]++
+		s.Held += e.held
+		if at := e.at; s.LastActive == nil || at.After(*s.LastActive) {
+			s.LastActive = &at
+		}
+	}
+
+	now := m.now()
+	for _, item := range m.reviews {
+		if item.Status != ReviewDone && item.leased(now) {
+			reviewer(item.ClaimedBy).Holding++
+		}
+	}
+	return sortReviewerStats(byReviewer), nil
+}
+
+func (m *Memory) SaveResult(ctx context.Context, result *MatchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result.MatchID = int64(len(m.results) + 1)
+	m.results = append(m.results, *result)
+	return nil
+}
+
+func (m *Memory) Accept(ctx context.Context, a Acceptance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if a.AcceptedAt.IsZero() {
+		a.AcceptedAt = time.Now()
+	}
+	m.accepted[a.SrcID] = a
+	return nil
+}
+
+func (m *Memory) Accepted(ctx context.Context, srcID int64) (*Acceptance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.accepted[srcID]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+// AddRejection records a reviewer's rejection of r.UPRN
+func (m *Memory) AddRejection(r Rejection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r.RejectionID = int64(len(m.rejections) + 1)
+	if r.RejectedAt.IsZero() {
+		r.RejectedAt = m.now()
+	}
+	m.rejections = append(m.rejections, r)
+}
+
+func (m *Memory) Rejections(ctx context.Context, srcID int64, canonical string) ([]Rejection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Rejection
+	for _, r := range m.rejections {
+		if r.SrcID == srcID || (canonical != "" && r.AddressCanonical == canonical) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *Memory) SaveAddressMatch(ctx context.Context, am AddressMatch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matches[am.DocumentID] = am
+	return nil
+}
+
+func (m *Memory) SaveExplanation(ctx context.Context, e Explanation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.CreatedAt = m.now()
+	m.explained = append(m.explained, e)
+	return nil
+}
+
+func (m *Memory) LatestExplanation(ctx context.Context, srcID int64) (*Explanation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.explained) - 1; i >= 0; i-- {
+		if m.explained[i].SrcID == srcID {
+			e := m.explained[i]
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Memory) RecordDecision(ctx context.Context, d DecisionRecord) (int64, error) {
+	decidedAt := d.DecidedAt
+	result := &MatchResult{
+		RunID: d.RunID, SrcID: d.SrcID, CandidateUPRN: d.UPRN, Method: d.Method, Score: d.Score,
+		TieRank: 1, Features: d.Features, Decided: true, Decision: d.Decision, DecidedBy: d.DecidedBy,
+		DecidedAt: &decidedAt,
+	}
+	if err := m.SaveResult(ctx, result); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	m.audits[result.MatchID] = d
+	m.mu.Unlock()
+
+	for _, alt := range d.Alternatives {
+		m.SaveResult(ctx, &MatchResult{
+			RunID: d.RunID, SrcID: d.SrcID, CandidateUPRN: alt.UPRN, Method: alt.Method, Score: alt.Score,
+			TieRank: alt.TieRank, Decision: "not_selected", DecidedBy: d.DecidedBy, DecidedAt: &decidedAt,
+		})
+	}
+
+	if d.Decision == "accepted" {
+		m.Accept(ctx, Acceptance{SrcID: d.SrcID, UPRN: d.UPRN, Method: d.Method, Score: d.Score,
+			RunID: d.RunID, AcceptedBy: d.DecidedBy, AcceptedAt: d.DecidedAt})
+	}
+	if d.Explained != nil && d.RunID != 0 {
+		explained := *d.Explained
+		explained.RunID, explained.SrcID = d.RunID, d.SrcID
+		m.SaveExplanation(ctx, explained)
+	}
+	return result.MatchID, nil
+}
+
+func (m *Memory) RecordOverride(ctx context.Context, o OverrideRecord) error {
+	m.mu.Lock()
+	m.overrides = append(m.overrides, override{srcID: o.SrcID, entry: OverrideHistoryEntry{
+		OverrideID:  int64(len(m.overrides) + 1),
+		UPRN:        o.UPRN,
+		UPRNAddress: m.fullAddress(o.UPRN),
+		Reason:      o.Reason,
+		CreatedBy:   o.ReviewerID,
+		CreatedAt:   o.CreatedAt,
+	}})
+	m.mu.Unlock()
+
+	if o.UPRN != "" {
+		return m.Accept(ctx, Acceptance{SrcID: o.SrcID, UPRN: o.UPRN, Method: "manual_override", Score: 1.0,
+			AcceptedBy: o.ReviewerID, AcceptedAt: o.CreatedAt, Manual: true})
+	}
+	return nil
+}
+
+func (m *Memory) fullAddress(uprn string) string {
+	for _, a := range m.addresses {
+		if a.UPRN == uprn {
+			return a.FullAddress
+		}
+	}
+	return ""
+}
+
+func (m *Memory) DecisionHistory(ctx context.Context, srcID int64) ([]DecisionHistoryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var history []DecisionHistoryEntry
+	for _, r := range m.results {
+		if r.SrcID != srcID {
+			continue
+		}
+		entry := DecisionHistoryEntry{
+			MatchID: r.MatchID, RunID: r.RunID, CandidateUPRN: r.CandidateUPRN,
+			CandidateAddress: m.fullAddress(r.CandidateUPRN), Method: r.Method, Score: r.Score,
+			TieRank: r.TieRank, Decided: r.Decided, Decision: r.Decision, DecidedBy: r.DecidedBy,
+		}
+		if r.DecidedAt != nil {
+			entry.DecidedAt = *r.DecidedAt
+		}
+		if run, err := m.run(r.RunID); err == nil {
+			entry.RunLabel = run.RunLabel
+		}
+		if d, ok := m.audits[r.MatchID]; ok {
+			entry.Features, entry.Explanation, entry.ProcessingTime = d.Features, d.Explanation, d.ProcessingTime
+		}
+		history = append(history, entry)
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		if !history[i].DecidedAt.Equal(history[j].DecidedAt) {
+			return history[i].DecidedAt.After(history[j].DecidedAt)
+		}
+		return history[i].TieRank < history[j].TieRank
+	})
+	return history, nil
+}
+
+func (m *Memory) OverrideHistory(ctx context.Context, srcID int64) ([]OverrideHistoryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var history []OverrideHistoryEntry
+	for i := len(m.overrides) - 1; i >= 0; i-- {
+		if m.overrides[i].srcID == srcID {
+			history = append(history, m.overrides[i].entry)
+		}
+	}
+	return history, nil
+}
+
+func (m *Memory) RunStatistics(ctx context.Context, runID int64) (*MatchingStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run, err := m.run(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run info: %w", err)
+	}
+	stats := &MatchingStats{RunID: runID, RunLabel: run.RunLabel, RunStartedAt: run.RunStartedAt, Notes: run.Notes}
+
+	byKey := make(map[[2]string]*resultGroup)
+	var groups []resultGroup
+	for _, r := range m.results {
+		if r.RunID != runID || r.TieRank != 1 {
+			continue
+		}
+		key := [2]string{r.Decision, r.Method}
+		g, ok := byKey[key]
+		if !ok {
+			g = &resultGroup{decision: r.Decision, method: r.Method, min: r.Score, max: r.Score}
+			byKey[key] = g
+		}
+		g.avg = (g.avg*float64(g.count) + r.Score) / float64(g.count+1)
+		g.count++
+		if r.Score < g.min {
+			g.min = r.Score
+		}
+		if r.Score > g.max {
+			g.max = r.Score
+		}
+	}
+	for _, g := range byKey {
+		groups = append(groups, *g)
+	}
+
+	summariseGroups(stats, groups)
+	return stats, nil
+}
+
+// fixtureRow reads named columns of a fixture CSV record, keeping the first parse error
+type fixtureRow struct {
+	header map[string]int
+	record []string
+	err    error
+}
+
+func (r *fixtureRow) str(column string) string {
+	if i, ok := r.header[column]; ok && i < len(r.record) {
+		return strings.TrimSpace(r.record[i])
+	}
+	return ""
+}
+
+func (r *fixtureRow) ptr(column string) *string {
+	if s := r.str(column); s != "" {
+		return &s
+	}
+	return nil
+}
+
+func (r *fixtureRow) int(column string) int {
+	s := r.str(column)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s: %w", column, err)
+	}
+	return n
+}
+
+func (r *fixtureRow) float(column string) *float64 {
+	s := r.str(column)
+	if s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s: %w", column, err)
+	}
+	return &f
+}
+
+func (r *fixtureRow) date(column string) *time.Time {
+	s := r.str(column)
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s: %w", column, err)
+	}
+	return &t
+}
+
+func readFixture(path string, fn func(fixtureRow) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open fixture: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	headerRow, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read %s header: %w", path, err)
+	}
+	header := make(map[string]int)
+	for i, col := range headerRow {
+		header[strings.TrimSpace(col)] = i
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := fn(fixtureRow{header: header, record: record}); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+	}
+}
+
+// record builds a document's Record; address quality is a rough stand-in for the view's
+// grading, from whether the document has a canonical address and a postcode
+func (m *Memory) record(d SourceDocument) Record {
+	r := Record{
+		SrcID:             int(d.SrcID),
+		SourceType:        d.SourceType,
+		ExternalRef:       d.ExternalRef,
+		DocType:           d.DocType,
+		OriginalAddress:   d.RawAddress,
+		CanonicalAddress:  d.AddrCan,
+		ExtractedPostcode: d.PostcodeText,
+		SourceUPRN:        d.UPRNRaw,
+		SourceEasting:     d.Easting,
+		SourceNorthing:    d.Northing,
+		MatchStatus:       RecordUnmatched,
+		AddressQuality:    "POOR",
+	}
+	if d.Filepath != nil {
+		r.Filepath = *d.Filepath
+	}
+	if d.DocDate != nil {
+		date := d.DocDate.Format("2006-01-02")
+		r.DocDate = &date
+	}
+	switch {
+	case d.AddrCan != nil && d.PostcodeText != nil:
+		r.AddressQuality = "GOOD"
+	case d.AddrCan != nil || d.PostcodeText != nil:
+		r.AddressQuality = "FAIR"
+	}
+
+	if a, ok := m.accepted[d.SrcID]; ok {
+		r.MatchStatus = RecordMatched
+		uprn, method, score := a.UPRN, a.Method, a.Score
+		r.MatchedUPRN, r.MatchMethod, r.MatchScore = &uprn, &method, &score
+		for _, addr := range m.addresses {
+			if addr.UPRN == a.UPRN {
+				full := addr.FullAddress
+				r.LLPGAddress, r.LLPGEasting, r.LLPGNorthing, r.USRN = &full, addr.Easting, addr.Northing, addr.USRN
+				break
+			}
+		}
+		return r
+	}
+	for _, res := range m.results {
+		if res.SrcID == d.SrcID && res.Decision == "needs_review" {
+			r.MatchStatus = RecordNeedsReview
+			break
+		}
+	}
+	return r
+}
+
+// admits reports whether r passes the filter; address is what AddressSearch looks in
+func (f RecordFilter) admits(r Record, address ...string) bool {
+	if (f.SourceType != "" && r.SourceType != f.SourceType) ||
+		(f.MatchStatus != "" && r.MatchStatus != f.MatchStatus) ||
+		(f.AddressQuality != "" && r.AddressQuality != f.AddressQuality) {
+		return false
+	}
+	if f.MinScore != nil && (r.MatchScore == nil || *r.MatchScore < *f.MinScore) {
+		return false
+	}
+	if f.MaxScore != nil && (r.MatchScore == nil || *r.MatchScore > *f.MaxScore) {
+		return false
+	}
+	if f.AddressSearch == "" {
+		return true
+	}
+	for _, a := range address {
+		if containsFold(a, f.AddressSearch) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToUpper(s), strings.ToUpper(substr))
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (m *Memory) Records(ctx context.Context, f RecordFilter, limit, offset int) ([]Record, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var records []Record
+	total := 0
+	for _, d := range m.documents {
+		r := m.record(d)
+		if !f.admits(r, r.OriginalAddress, deref(r.CanonicalAddress)) {
+			continue
+		}
+		if total >= offset && len(records) < limit {
+			records = append(records, r)
+		}
+		total++
+	}
+	return records, total, nil
+}
+
+func (m *Memory) Record(ctx context.Context, srcID int64) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.documents {
+		if d.SrcID == srcID {
+			r := m.record(d)
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *Memory) SearchRecords(ctx context.Context, term string, f RecordFilter, limit int) ([]RecordSearchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f.AddressSearch = ""
+	type ranked struct {
+		rank int
+		r    RecordSearchResult
+	}
+	var found []ranked
+	for _, d := range m.documents {
+		r := m.record(d)
+		if !f.admits(r) {
+			continue
+		}
+		rank := 0
+		switch {
+		case containsFold(deref(r.ExternalRef), term):
+			rank = 1
+		case containsFold(r.OriginalAddress, term):
+			rank = 2
+		case containsFold(deref(r.CanonicalAddress), term):
+			rank = 3
+		default:
+			continue
+		}
+		address := r.OriginalAddress
+		if r.CanonicalAddress != nil {
+			address = *r.CanonicalAddress
+		}
+		found = append(found, ranked{rank, RecordSearchResult{
+			SrcID: r.SrcID, SourceType: r.SourceType, Address: address, MatchStatus: r.MatchStatus,
+			AddressQuality: r.AddressQuality, MatchScore: r.MatchScore, ExternalRef: r.ExternalRef,
+		}})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].rank < found[j].rank })
+
+	var results []RecordSearchResult
+	for _, f := range found {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, f.r)
+	}
+	return results, nil
+}
+
+func listing(a Address) AddressListing {
+	l := AddressListing{
+		UPRN: a.UPRN, Address: a.FullAddress, CanonicalAddress: a.Canonical,
+		USRN: a.USRN, BLPUClass: a.BLPUClass, Status: deref(a.Status),
+	}
+	if a.Easting != nil && a.Northing != nil {
+		l.Easting, l.Northing = *a.Easting, *a.Northing
+	}
+	return l
+}
+
+func (m *Memory) SearchAddresses(ctx context.Context, term string, limit int) ([]AddressListing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type ranked struct {
+		rank int
+		sim  float64
+		a    Address
+	}
+	var found []ranked
+	for _, a := range m.addresses {
+		sim := TrigramSimilarity(a.Canonical, term)
+		rank := 0
+		switch {
+		case containsFold(a.FullAddress, term):
+			rank = 1
+		case containsFold(a.Canonical, term):
+			rank = 2
+		case sim >= trgmThreshold:
+			rank = 3
+		default:
+			continue
+		}
+		found = append(found, ranked{rank, sim, a})
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].rank != found[j].rank {
+			return found[i].rank < found[j].rank
+		}
+		return found[i].sim > found[j].sim
+	})
+
+	var listings []AddressListing
+	for _, f := range found {
+		if len(listings) == limit {
+			break
+		}
+		listings = append(listings, listing(f.a))
+	}
+	return listings, nil
+}
+
+func (m *Memory) RecordCandidates(ctx context.Context, srcID int64, limit int) ([]AddressListing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var address string
+	for _, d := range m.documents {
+		if d.SrcID == srcID {
+			address = d.RawAddress
+			if d.AddrCan != nil {
+				address = *d.AddrCan
+			}
+		}
+	}
+	if address == "" {
+		return nil, nil
+	}
+
+	var listings []AddressListing
+	for _, a := range m.addresses {
+		if len(listings) == limit {
+			break
+		}
+		if containsFold(a.Canonical, address) {
+			listings = append(listings, listing(a))
+		}
+	}
+	return listings, nil
+}
+
+func (m *Memory) AddressExists(ctx context.Context, uprn string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.addresses {
+		if a.UPRN == uprn {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) BestResult(ctx context.Context, srcID int64, uprn string) (*MatchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	confidence := func(r MatchResult) float64 {
+		if r.Confidence != 0 {
+			return r.Confidence
+		}
+		return r.Score
+	}
+	var best *MatchResult
+	for i, r := range m.results {
+		if r.SrcID == srcID && r.CandidateUPRN == uprn && (best == nil || confidence(r) > confidence(*best)) {
+			best = &m.results[i]
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	r := *best
+	r.Confidence = confidence(r)
+	if r.Method == "" {
+		r.Method = "manual"
+	}
+	return &r, nil
+}
+
+func (m *Memory) LatestResults(ctx context.Context, srcID int64, limit int) ([]ResultSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var latest int64
+	found := false
+	for _, r := range m.results {
+		if r.SrcID == srcID && (!found || r.RunID > latest) {
+			latest, found = r.RunID, true
+		}
+	}
+
+	var ranked []MatchResult
+	for _, r := range m.results {
+		if r.SrcID == srcID && r.RunID == latest {
+			ranked = append(ranked, r)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].TieRank < ranked[j].TieRank })
+
+	var results []ResultSummary
+	for _, r := range ranked {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, ResultSummary{
+			RunID: r.RunID, UPRN: r.CandidateUPRN, Address: m.fullAddress(r.CandidateUPRN),
+			Method: r.Method, Score: r.Score, Decision: r.Decision, DecidedAt: r.DecidedAt,
+		})
+	}
+	return results, nil
+}
+
+func (m *Memory) RecordStats(ctx context.Context) (*RecordStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := &RecordStats{
+		BySourceType:     make(map[string]CategoryCount),
+		ByMatchMethod:    make(map[string]int),
+		ByAddressQuality: make(map[string]CategoryCount),
+	}
+	for _, d := range m.documents {
+		r := m.record(d)
+		matched := 0
+		switch r.MatchStatus {
+		case RecordMatched:
+			stats.Matched++
+			matched = 1
+			method := "unknown"
+			if r.MatchMethod != nil && *r.MatchMethod != "" {
+				method = *r.MatchMethod
+			}
+			stats.ByMatchMethod[method]++
+		case RecordUnmatched:
+			stats.Unmatched++
+		case RecordNeedsReview:
+			stats.NeedsReview++
+		}
+		stats.Total++
+
+		c := stats.BySourceType[r.SourceType]
+		stats.BySourceType[r.SourceType] = CategoryCount{Total: c.Total + 1, Matched: c.Matched + matched}
+		c = stats.ByAddressQuality[r.AddressQuality]
+		stats.ByAddressQuality[r.AddressQuality] = CategoryCount{Total: c.Total + 1, Matched: c.Matched + matched}
+	}
+	return stats, nil
+}
+
+// mapped returns the record's map position, its matched address's when it has one, and
+// whether it falls inside box
+func mapped(r Record, box *BNGBox) (e, n float64, ok bool) {
+	switch {
+	case r.LLPGEasting != nil && r.LLPGNorthing != nil:
+		e, n = *r.LLPGEasting, *r.LLPGNorthing
+	case r.SourceEasting != nil && r.SourceNorthing != nil:
+		e, n = *r.SourceEasting, *r.SourceNorthing
+	default:
+		return 0, 0, false
+	}
+	if box != nil && (e < box.MinE || e > box.MaxE || n < box.MinN || n > box.MaxN) {
+		return 0, 0, false
+	}
+	return e, n, true
+}
+
+func (m *Memory) ViewportCounts(ctx context.Context, box *BNGBox) ([]ViewportCount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var counts []ViewportCount
+	index := make(map[[3]string]int)
+	for _, d := range m.documents {
+		r := m.record(d)
+		if _, _, ok := mapped(r, box); !ok {
+			continue
+		}
+		key := [3]string{r.SourceType, r.MatchStatus, r.AddressQuality}
+		i, ok := index[key]
+		if !ok {
+			i = len(counts)
+			index[key] = i
+			counts = append(counts, ViewportCount{SourceType: key[0], MatchStatus: key[1], AddressQuality: key[2]})
+		}
+		counts[i].Count++
+	}
+	return counts, nil
+}
+
+// MapFeatures leaves every feature's geometry to the caller
+func (m *Memory) MapFeatures(ctx context.Context, f RecordFilter, box *BNGBox, limit int) ([]MapFeature, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var features []MapFeature
+	for _, d := range m.documents {
+		if len(features) == limit {
+			break
+		}
+		r := m.record(d)
+		address := r.OriginalAddress
+		if r.LLPGAddress != nil {
+			address = *r.LLPGAddress
+		}
+		if !f.admits(r, address) {
+			continue
+		}
+		e, n, ok := mapped(r, box)
+		if !ok {
+			continue
+		}
+		feature, err := json.Marshal(map[string]interface{}{
+			"type": "Feature",
+			"properties": map[string]interface{}{
+				"src_id":             r.SrcID,
+				"source_type":        r.SourceType,
+				"external_reference": r.ExternalRef,
+				"address":            address,
+				"uprn":               r.MatchedUPRN,
+				"match_status":       r.MatchStatus,
+				"match_score":        r.MatchScore,
+				"address_quality":    r.AddressQuality,
+				"match_method":       r.MatchMethod,
+				"doc_type":           r.DocType,
+				"doc_date":           r.DocDate,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, MapFeature{Feature: feature, Easting: &e, Northing: &n})
+	}
+	return features, nil
+}
+
+func (m *Memory) RecentAccepts(ctx context.Context, within time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	since := m.now().Add(-within)
+	count := 0
+	for _, a := range m.accepted {
+		if a.AcceptedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// logEvent appends to a record's audit history; callers hold m.mu
+func (m *Memory) logEvent(srcID int64, eventType, action, details, user, notes string) {
+	e := RecordEvent{EventType: eventType, Action: action, UserName: user, EventTimestamp: m.now()}
+	if details != "" {
+		e.Details = &details
+	}
+	if notes != "" {
+		e.Notes = &notes
+	}
+	m.events = append(m.events, recordEvent{srcID: srcID, event: e})
+}
+
+func (m *Memory) ManualAccept(ctx context.Context, d ManualDecision) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.accepted[d.SrcID] = Acceptance{SrcID: d.SrcID, UPRN: d.UPRN, Method: d.Method, Score: d.Score,
+		Confidence: d.Confidence, AcceptedBy: d.DecidedBy, AcceptedAt: m.now(), Manual: true}
+	m.logEvent(d.SrcID, "MATCH_DECISION", "ACCEPT_MATCH", d.UPRN, d.DecidedBy, d.Reason)
+	return nil
+}
+
+func (m *Memory) ManualReject(ctx context.Context, d ManualDecision) error {
+	m.mu.Lock()
+	a, ok := m.accepted[d.SrcID]
+	var canonical string
+	for _, doc := range m.documents {
+		if doc.SrcID == d.SrcID {
+			canonical = deref(doc.AddrCan)
+		}
+	}
+	delete(m.accepted, d.SrcID)
+	m.logEvent(d.SrcID, "MATCH_DECISION", "REJECT_MATCH", a.UPRN, d.DecidedBy, d.Reason)
+	m.mu.Unlock()
+
+	if ok {
+		m.AddRejection(Rejection{SrcID: d.SrcID, AddressCanonical: canonical, UPRN: a.UPRN,
+			Source: "web_reject", Reason: d.Reason, RejectedBy: d.DecidedBy})
+	}
+	return nil
+}
+
+func (m *Memory) SetCoordinates(ctx context.Context, c CoordinateChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.documents {
+		if m.documents[i].SrcID == c.SrcID {
+			e, n := c.Easting, c.Northing
+			m.documents[i].Easting, m.documents[i].Northing = &e, &n
+		}
+	}
+	m.logEvent(c.SrcID, "COORDINATE_CHANGE", c.Source,
+		fmt.Sprintf("%.2f, %.2f", c.Easting, c.Northing), c.ChangedBy, c.Reason)
+	return nil
+}
+
+func (m *Memory) RecordHistory(ctx context.Context, srcID int64, eventType string, limit int) ([]RecordEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var history []RecordEvent
+	for i := len(m.events) - 1; i >= 0 && len(history) < limit; i-- {
+		e := m.events[i]
+		if e.srcID == srcID && (eventType == "" || e.event.EventType == eventType) {
+			history = append(history, e.event)
+		}
+	}
+	return history, nil
+}
+
+func (m *Memory) AddNote(ctx context.Context, n *RecordNote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n.ID = len(m.notes) + 1
+	n.CreatedAt = m.now()
+	m.notes = append(m.notes, *n)
+	m.logEvent(n.SrcID, "NOTE", n.Type, "", n.CreatedBy, n.Text)
+	return nil
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestTrigramSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"12 HIGH STREET ALTON", "12 high street, alton", 1},
+		{"HIGH STREET", "BUTTS ROAD", 0},
+		{"word", "two words", 4.0 / 11.0}, // pg_trgm documentation example
+		{"", "ALTON", 0},
+	}
+	for _, tt := range tests {
+		if got := TrigramSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TrigramSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	m, err := LoadFixtures("testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	ctx := context.Background()
+
+	similar, err := m.SimilarAddresses(ctx, "12 HIGH STREET ALTON", 0.5, 10)
+	if err != nil || len(similar) == 0 {
+		t.Fatalf("SimilarAddresses = %v, %v", similar, err)
+	}
+	if similar[0].UPRN != "100062000001" || similar[0].Similarity != 1 {
+		t.Errorf("best match = %s (%.2f), want 100062000001 (1.00)", similar[0].UPRN, similar[0].Similarity)
+	}
+
+	inPostcode, _ := m.AddressesInPostcode(ctx, "GU32 3ED")
+	if len(inPostcode) != 2 {
+		t.Errorf("AddressesInPostcode(GU32 3ED) = %d addresses, want 2", len(inPostcode))
+	}
+
+	byComponents, _ := m.AddressesByComponents(ctx, Components{Postcode: "GU341AB", HouseNumber: "14"}, 10)
+	if len(byComponents) != 1 || byComponents[0].UPRN != "100062000002" {
+		t.Errorf("AddressesByComponents = %v, want 100062000002", byComponents)
+	}
+}
+
+func TestUnmatchedDocumentsPaging(t *testing.T) {
+	m, err := LoadFixtures("testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	ctx := context.Background()
+
+	page, _ := m.UnmatchedDocuments(ctx, 0, 2, "")
+	if len(page) != 2 || page[0].SrcID != 1 || page[1].SrcID != 2 {
+		t.Fatalf("first page = %v, want src_id 1, 2", page)
+	}
+
+	if err := m.Accept(ctx, Acceptance{SrcID: 3, UPRN: "100062000005", Method: "test"}); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	page, _ = m.UnmatchedDocuments(ctx, 2, 2, "")
+	if len(page) != 1 || page[0].SrcID != 4 {
+		t.Errorf("second page = %v, want src_id 4 only", page)
+	}
+
+	page, _ = m.UnmatchedWithPostcode(ctx, 0, 10)
+	if len(page) != 1 || page[0].SrcID != 1 {
+		t.Errorf("UnmatchedWithPostcode = %v, want src_id 1 only", page)
+	}
+}
+
+func TestMemoryHonoursCancellation(t *testing.T) {
+	m, err := LoadFixtures("testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.UnmatchedDocuments(ctx, 0, 10, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("UnmatchedDocuments after cancel: err = %v, want context.Canceled", err)
+	}
+	if err := m.SaveResult(ctx, &MatchResult{SrcID: 1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SaveResult after cancel: err = %v, want context.Canceled", err)
+	}
+	if len(m.Results()) != 0 {
+		t.Errorf("cancelled SaveResult stored %d results", len(m.Results()))
+	}
+}
+
+func TestCompleteRunRecordsStatus(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+
+	run, err := m.CreateRun(ctx, "test", "v1", "")
+	if err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	if run.Status != RunRunning {
+		t.Errorf("new run status = %q, want %q", run.Status, RunRunning)
+	}
+	if err := m.CompleteRun(ctx, run.RunID, RunInterrupted, RunTotals{Processed: 7, Accepted: 3}); err != nil {
+		t.Fatalf("CompleteRun: %v", err)
+	}
+	got := m.runs[0]
+	if got.Status != RunInterrupted || got.TotalProcessed != 7 || got.AutoAccepted != 3 || got.RunCompletedAt == nil {
+		t.Errorf("completed run = %+v, want interrupted with partial totals", got)
+	}
+}
+
+func TestReopenRunResumesFromCheckpoint(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+
+	run, _ := m.CreateRun(ctx, "batch", "v1", "")
+	if _, err := m.ReopenRun(ctx, run.RunID); err == nil {
+		t.Error("ReopenRun succeeded for a run without a checkpoint")
+	}
+	if err := m.CreateCheckpoint(ctx, run.RunID, "standard", json.RawMessage(`{"batch_size":10}`)); err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	if err := m.SaveCheckpoint(ctx, run.RunID, 42, json.RawMessage(`{"ProcessedCount":5}`)); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	m.CompleteRun(ctx, run.RunID, RunInterrupted, RunTotals{Processed: 5})
+
+	cp, err := m.ReopenRun(ctx, run.RunID)
+	if err != nil {
+		t.Fatalf("ReopenRun: %v", err)
+	}
+	if cp.LastDocumentID != 42 || cp.Legs != 2 || cp.Status != RunRunning || string(cp.Stats) != `{"ProcessedCount":5}` {
+		t.Errorf("reopened checkpoint = %+v, want cursor 42, leg 2, running, saved stats", cp)
+	}
+	if m.runs[0].RunCompletedAt != nil {
+		t.Error("reopened run still has a completion time")
+	}
+
+	m.CompleteRun(ctx, run.RunID, RunCompleted, RunTotals{Processed: 9})
+	if _, err := m.ReopenRun(ctx, run.RunID); err == nil {
+		t.Error("ReopenRun succeeded for a completed run")
+	}
+}
+
+func TestProcessedItems(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+
+	m.MarkProcessed(ctx, 1, []string{"1 HIGH STREET", "2 HIGH STREET"})
+	m.MarkProcessed(ctx, 1, []string{"2 HIGH STREET"})
+	m.MarkProcessed(ctx, 2, []string{"3 HIGH STREET"})
+
+	items, err := m.ProcessedItems(ctx, 1)
+	if err != nil {
+		t.Fatalf("ProcessedItems: %v", err)
+	}
+	if len(items) != 2 || !items["1 HIGH STREET"] || items["3 HIGH STREET"] {
+		t.Errorf("run 1 processed = %v, want its two addresses only", items)
+	}
+}
+
+func TestManualDecisionsOnRecords(t *testing.T) {
+	m, err := LoadFixtures("testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	ctx := context.Background()
+
+	err = m.ManualAccept(ctx, ManualDecision{SrcID: 1, UPRN: "100062000001", Method: "manual",
+		Score: 0.9, Confidence: 0.9, DecidedBy: "alice"})
+	if err != nil {
+		t.Fatalf("ManualAccept: %v", err)
+	}
+	matched, total, _ := m.Records(ctx, RecordFilter{MatchStatus: RecordMatched}, 10, 0)
+	if total != 1 || matched[0].SrcID != 1 || matched[0].LLPGAddress == nil {
+		t.Fatalf("matched records = %+v (%d), want record 1 with its LLPG address", matched, total)
+	}
+	if stats, _ := m.RecordStats(ctx); stats.Matched != 1 || stats.ByMatchMethod["manual"] != 1 {
+		t.Errorf("RecordStats = %+v, want 1 manual match", stats)
+	}
+
+	if err := m.ManualReject(ctx, ManualDecision{SrcID: 1, Reason: "wrong building", DecidedBy: "bob"}); err != nil {
+		t.Fatalf("ManualReject: %v", err)
+	}
+	if r, _ := m.Record(ctx, 1); r.MatchStatus != RecordUnmatched {
+		t.Errorf("record 1 is %s after the reject, want %s", r.MatchStatus, RecordUnmatched)
+	}
+	rejected, _ := m.Rejections(ctx, 0, "12 HIGH STREET ALTON")
+	if len(rejected) != 1 || rejected[0].UPRN != "100062000001" || rejected[0].Source != "web_reject" {
+		t.Errorf("Rejections = %+v, want the rejected UPRN against the canonical address", rejected)
+	}
+
+	history, _ := m.RecordHistory(ctx, 1, "", 10)
+	if len(history) != 2 || history[0].Action != "REJECT_MATCH" {
+		t.Errorf("RecordHistory = %+v, want the reject before the accept", history)
+	}
+}
@@ -0,0 +1,2519 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	database "github.com/ehdc-llpg/internal/db"
+)
+
+// Postgres implements every store against the matching schema
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres creates a Postgres-backed store
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// SimilarAddresses uses pg_trgm on dim_address.address_canonical
+func (p *Postgres) SimilarAddresses(ctx context.Context, canonical string, minSimilarity float64, limit int) ([]ScoredAddress, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT d.address_id, COALESCE(d.location_id, 0), d.uprn, d.full_address, d.address_canonical,
+		       d.usrn, d.blpu_class, d.status_code, l.easting, l.northing,
+		       similarity($1, d.address_canonical) as trgm_score
+		FROM dim_address d
+		LEFT JOIN dim_location l ON d.location_id = l.location_id
+		WHERE d.address_canonical % $1
+		  AND similarity($1, d.address_canonical) >= $2
+		ORDER BY trgm_score DESC
+		LIMIT $3
+	`, canonical, minSimilarity, limit)
+	if err != nil {
+		return nil, fmt.Errorf("trigram query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []ScoredAddress
+	for rows.Next() {
+		var a ScoredAddress
+		err := rows.Scan(&a.AddressID, &a.LocationID, &a.UPRN, &a.FullAddress, &a.Canonical,
+			&a.USRN, &a.BLPUClass, &a.Status, &a.Easting, &a.Northing, &a.Similarity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan address: %w", err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// AddressesInPostcode matches the postcode at the end of dim_address.full_address
+func (p *Postgres) AddressesInPostcode(ctx context.Context, postcode string) ([]Address, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT d.address_id, COALESCE(d.location_id, 0), d.uprn, d.full_address, d.address_canonical,
+		       d.start_date, d.end_date
+		FROM dim_address d
+		WHERE d.full_address LIKE $1
+		   OR d.full_address LIKE $2
+		ORDER BY d.uprn
+	`, "%"+postcode, "%"+strings.ReplaceAll(postcode, " ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("postcode query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []Address
+	for rows.Next() {
+		var a Address
+		if err := rows.Scan(&a.AddressID, &a.LocationID, &a.UPRN, &a.FullAddress, &a.Canonical,
+			&a.StartDate, &a.EndDate); err != nil {
+			return nil, fmt.Errorf("failed to scan address: %w", err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// componentColumns are selected by the component queries, which need a location
+const componentColumns = `
+	a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
+	l.easting, l.northing,
+	a.gopostal_house_number, a.gopostal_road, a.gopostal_city, a.gopostal_postcode`
+
+// AddressesByComponents matches the gopostal_* columns exactly
+func (p *Postgres) AddressesByComponents(ctx context.Context, q Components, limit int) ([]Address, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var conditions []string
+	var args []interface{}
+	for _, c := range []struct{ column, value string }{
+		{"gopostal_postcode", q.Postcode},
+		{"gopostal_house_number", q.HouseNumber},
+		{"gopostal_road", q.Road},
+		{"gopostal_city", q.City},
+	} {
+		if c.value != "" {
+			args = append(args, c.value)
+			conditions = append(conditions, fmt.Sprintf("a.%s = $%d", c.column, len(args)))
+		}
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+	args = append(args, limit)
+
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM dim_address a
+		INNER JOIN dim_location l ON l.location_id = a.location_id
+		WHERE %s
+		LIMIT $%d
+	`, componentColumns, strings.Join(conditions, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("component query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []Address
+	for rows.Next() {
+		var a Address
+		if err := scanComponentAddress(rows, &a); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// AddressesByRoad uses pg_trgm on dim_address.gopostal_road
+func (p *Postgres) AddressesByRoad(ctx context.Context, road, city string, minSimilarity float64, limit int) ([]ScoredAddress, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	where := `WHERE a.gopostal_road % $1 AND similarity(a.gopostal_road, $1) >= $2`
+	args := []interface{}{road, minSimilarity, limit}
+	if city != "" {
+		where += ` AND a.gopostal_city = $4`
+		args = append(args, city)
+	}
+
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s, similarity(a.gopostal_road, $1) as road_sim
+		FROM dim_address a
+		INNER JOIN dim_location l ON l.location_id = a.location_id
+		%s
+		ORDER BY road_sim DESC
+		LIMIT $3
+	`, componentColumns, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("road query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []ScoredAddress
+	for rows.Next() {
+		var a ScoredAddress
+		if err := scanComponentAddress(rows, &a.Address, &a.Similarity); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func scanComponentAddress(rows *sql.Rows, a *Address, extra ...interface{}) error {
+	var houseNumber, road, city, postcode sql.NullString
+	dest := []interface{}{&a.AddressID, &a.LocationID, &a.UPRN, &a.FullAddress, &a.Canonical,
+		&a.Easting, &a.Northing, &houseNumber, &road, &city, &postcode}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return fmt.Errorf("failed to scan address: %w", err)
+	}
+	a.Components = Components{
+		HouseNumber: houseNumber.String,
+		Road:        road.String,
+		City:        city.String,
+		Postcode:    postcode.String,
+	}
+	return nil
+}
+
+// Similarity asks pg_trgm for the similarity of two strings
+func (p *Postgres) Similarity(ctx context.Context, a, b string) (float64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var score float64
+	if err := p.db.QueryRowContext(ctx, `SELECT similarity($1, $2)`, a, b).Scan(&score); err != nil {
+		return 0, fmt.Errorf("similarity query failed: %w", err)
+	}
+	return score, nil
+}
+
+// documentColumns are the src_document columns read into SourceDocument
+const documentColumns = `
+	s.src_id, s.source_type, s.job_number, s.filepath, s.external_ref,
+	s.doc_type, s.doc_date, s.raw_address, s.addr_can, s.postcode_text,
+	s.uprn_raw, s.easting_raw, s.northing_raw, s.easting_repaired, s.northing_repaired`
+
+// UnmatchedDocuments returns current documents without a match_accepted row
+func (p *Postgres) UnmatchedDocuments(ctx context.Context, afterSrcID int64, limit int, sourceType string) ([]SourceDocument, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + documentColumns + `
+		FROM src_document s
+		LEFT JOIN match_accepted m ON m.src_id = s.src_id
+		WHERE m.src_id IS NULL AND s.removed_at IS NULL AND s.src_id > $1`
+	args := []interface{}{afterSrcID, limit}
+	if sourceType != "" {
+		query += ` AND s.source_type = $3`
+		args = append(args, sourceType)
+	}
+	query += `
+		ORDER BY s.src_id
+		LIMIT $2`
+
+	return p.queryDocuments(ctx, query, args...)
+}
+
+// UnmatchedWithPostcode returns unmatched documents with a postcode and canonical address
+func (p *Postgres) UnmatchedWithPostcode(ctx context.Context, afterSrcID int64, limit int) ([]SourceDocument, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return p.queryDocuments(ctx, `
+		SELECT `+documentColumns+`
+		FROM src_document s
+		LEFT JOIN match_accepted m ON m.src_id = s.src_id
+		WHERE m.src_id IS NULL
+		  AND s.removed_at IS NULL
+		  AND s.src_id > $1
+		  AND s.postcode_text IS NOT NULL
+		  AND s.postcode_text != ''
+		  AND s.addr_can IS NOT NULL
+		  AND s.addr_can != 'N A'
+		ORDER BY s.src_id
+		LIMIT $2
+	`, afterSrcID, limit)
+}
+
+func (p *Postgres) queryDocuments(ctx context.Context, query string, args ...interface{}) ([]SourceDocument, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []SourceDocument
+	for rows.Next() {
+		var doc SourceDocument
+		err := rows.Scan(
+			&doc.SrcID, &doc.SourceType, &doc.JobNumber, &doc.Filepath, &doc.ExternalRef,
+			&doc.DocType, &doc.DocDate, &doc.RawAddress, &doc.AddrCan, &doc.PostcodeText,
+			&doc.UPRNRaw, &doc.EastingRaw, &doc.NorthingRaw, &doc.Easting, &doc.Northing,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// PostcodeQuality counts postcode coverage and the most common postcodes
+func (p *Postgres) PostcodeQuality(ctx context.Context) (*PostcodeQuality, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	q := &PostcodeQuality{}
+	err := p.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(CASE WHEN postcode_text IS NOT NULL AND postcode_text != '' THEN 1 END) as with_postcode,
+			COUNT(CASE WHEN postcode_text IS NULL OR postcode_text = '' THEN 1 END) as without_postcode,
+			COUNT(CASE WHEN postcode_text IS NOT NULL AND postcode_text != ''
+				AND postcode_text !~ '^[A-Z]{1,2}[0-9]{1,2}[A-Z]?\s*[0-9][A-Z]{2}$' THEN 1 END) as invalid
+		FROM src_document
+	`).Scan(&q.WithPostcode, &q.WithoutPostcode, &q.Invalid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count postcodes: %w", err)
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT postcode_text, COUNT(*) as count
+		FROM src_document
+		WHERE postcode_text IS NOT NULL AND postcode_text != ''
+		GROUP BY postcode_text
+		ORDER BY count DESC
+		LIMIT 10
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top postcodes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pc PostcodeCount
+		if err := rows.Scan(&pc.Postcode, &pc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan postcode: %w", err)
+		}
+		q.TopPostcodes = append(q.TopPostcodes, pc)
+	}
+
+	err = p.db.QueryRowContext(ctx, `
+		WITH postcode_pairs AS (
+			SELECT DISTINCT s.src_id, s.postcode_text
+			FROM src_document s
+			LEFT JOIN match_accepted m ON m.src_id = s.src_id
+			WHERE m.src_id IS NULL
+			  AND s.postcode_text IS NOT NULL
+			  AND s.postcode_text != ''
+		)
+		SELECT COUNT(DISTINCT pp.src_id)
+		FROM postcode_pairs pp
+		WHERE EXISTS (
+			SELECT 1 FROM dim_address d
+			WHERE d.full_address LIKE '%' || pp.postcode_text || '%'
+		)
+	`).Scan(&q.MatchPotential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count postcode match potential: %w", err)
+	}
+
+	return q, nil
+}
+
+// DocumentComponents reads the gopostal_* columns of src_document
+func (p *Postgres) DocumentComponents(ctx context.Context, documentID int64) (Components, bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var processed bool
+	var houseNumber, road, city, postcode, unit sql.NullString
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COALESCE(gopostal_processed, FALSE),
+		       gopostal_house_number, gopostal_road, gopostal_city, gopostal_postcode, gopostal_unit
+		FROM src_document
+		WHERE document_id = $1
+	`, documentID).Scan(&processed, &houseNumber, &road, &city, &postcode, &unit)
+	if err == sql.ErrNoRows {
+		return Components{}, false, nil
+	}
+	if err != nil {
+		return Components{}, false, fmt.Errorf("failed to read document components: %w", err)
+	}
+	return Components{
+		HouseNumber: houseNumber.String,
+		Road:        road.String,
+		City:        city.String,
+		Postcode:    postcode.String,
+		Unit:        unit.String,
+	}, processed, nil
+}
+
+// SaveDocumentComponents stores parsed components and marks the document processed
+func (p *Postgres) SaveDocumentComponents(ctx context.Context, documentID int64, c Components) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		UPDATE src_document SET
+			gopostal_house_number = $2,
+			gopostal_road = $3,
+			gopostal_city = $4,
+			gopostal_postcode = $5,
+			gopostal_unit = $6,
+			gopostal_processed = TRUE
+		WHERE document_id = $1
+	`, documentID, nullIfEmpty(c.HouseNumber), nullIfEmpty(c.Road), nullIfEmpty(c.City),
+		nullIfEmpty(c.Postcode), nullIfEmpty(c.Unit))
+	if err != nil {
+		return fmt.Errorf("failed to update source components: %w", err)
+	}
+	return nil
+}
+
+// CreateRun inserts a match_run row
+func (p *Postgres) CreateRun(ctx context.Context, label, algorithmVersion, notes string) (*MatchRun, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	run := &MatchRun{
+		RunLabel:         label,
+		AlgorithmVersion: algorithmVersion,
+		Notes:            notes,
+		RunStartedAt:     time.Now(),
+		Status:           RunRunning,
+	}
+
+	// The creating process joins the run, so the audit chain attributes its writes to it
+	err := p.db.QueryRowContext(ctx, `
+		WITH run AS (
+			INSERT INTO match_run (run_label, algorithm_version, notes, run_started_at, status)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING run_id
+		), joined AS (
+			INSERT INTO match_run_client (run_id, client)
+			SELECT run_id, current_setting('application_name') FROM run
+			WHERE current_setting('application_name') <> ''
+		)
+		SELECT run_id FROM run
+	`, label, algorithmVersion, notes, run.RunStartedAt, run.Status).Scan(&run.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create match run: %w", err)
+	}
+	return run, nil
+}
+
+// CompleteRun records how a run ended and the totals it reached
+func (p *Postgres) CompleteRun(ctx context.Context, runID int64, status string, totals RunTotals) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		WITH ended AS (
+			UPDATE match_run_client SET ended_at = $1 WHERE run_id = $7 AND ended_at IS NULL
+		)
+		UPDATE match_run
+		SET run_completed_at = $1, status = $2,
+			total_processed = $3, auto_accepted = $4, needs_review = $5, rejected = $6
+		WHERE run_id = $7
+	`, time.Now(), status, totals.Processed, totals.Accepted, totals.NeedsReview, totals.Rejected, runID)
+	if err != nil {
+		return fmt.Errorf("failed to complete match run: %w", err)
+	}
+	return nil
+}
+
+// JoinRun records this process (its application_name) as working on the run, so the
+// audit chain attributes its writes to the run until the run completes
+func (p *Postgres) JoinRun(ctx context.Context, runID int64) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return joinRun(ctx, p.db, runID)
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// joinRun inserts the match_run_client row; sessions without an application_name cannot be told apart
+func joinRun(ctx context.Context, db execer, runID int64) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO match_run_client (run_id, client)
+		SELECT $1, current_setting('application_name')
+		WHERE current_setting('application_name') <> ''
+	`, runID)
+	if err != nil {
+		return fmt.Errorf("failed to join run %d: %w", runID, err)
+	}
+	return nil
+}
+
+// CreateCheckpoint inserts the run's match_run_checkpoint row
+func (p *Postgres) CreateCheckpoint(ctx context.Context, runID int64, processor string, config json.RawMessage) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO match_run_checkpoint (run_id, processor, config)
+		VALUES ($1, $2, $3)
+	`, runID, processor, string(config))
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Checkpoint reads a run's checkpoint with its status
+func (p *Postgres) Checkpoint(ctx context.Context, runID int64) (*Checkpoint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return p.scanCheckpoint(p.db.QueryRowContext(ctx, checkpointQuery, runID))
+}
+
+// SaveCheckpoint records the cursor and statistics reached so far
+func (p *Postgres) SaveCheckpoint(ctx context.Context, runID, lastDocumentID int64, stats json.RawMessage) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		UPDATE match_run_checkpoint
+		SET last_document_id = $1, stats = $2, updated_at = now()
+		WHERE run_id = $3
+	`, lastDocumentID, string(stats), runID)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ReopenRun locks the run, checks it can be resumed and starts a new leg. A run left
+// running by a crashed process can be reopened; its status was never updated.
+func (p *Postgres) ReopenRun(ctx context.Context, runID int64) (*Checkpoint, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	cp, err := p.scanCheckpoint(tx.QueryRowContext(ctx, checkpointQuery+" FOR UPDATE OF c", runID))
+	if err != nil {
+		return nil, err
+	}
+	if err := reopenable(runID, cp); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE match_run SET status = $1, run_completed_at = NULL WHERE run_id = $2
+	`, RunRunning, runID); err != nil {
+		return nil, fmt.Errorf("failed to reopen run: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE match_run_checkpoint SET legs = legs + 1, updated_at = now() WHERE run_id = $1
+	`, runID); err != nil {
+		return nil, fmt.Errorf("failed to reopen run: %w", err)
+	}
+	if err := joinRun(ctx, tx, runID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	cp.Legs++
+	cp.Status = RunRunning
+	return cp, nil
+}
+
+// MarkProcessed adds items to the run's match_run_processed set
+func (p *Postgres) MarkProcessed(ctx context.Context, runID int64, items []string) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO match_run_processed (run_id, item)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, runID, pq.Array(items))
+	if err != nil {
+		return fmt.Errorf("failed to record processed items: %w", err)
+	}
+	return nil
+}
+
+// ProcessedItems reads the run's processed set
+func (p *Postgres) ProcessedItems(ctx context.Context, runID int64) (map[string]bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, "SELECT item FROM match_run_processed WHERE run_id = $1", runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[string]bool)
+	for rows.Next() {
+		var item string
+		if err := rows.Scan(&item); err != nil {
+			return nil, fmt.Errorf("failed to scan processed item: %w", err)
+		}
+		items[item] = true
+	}
+	return items, rows.Err()
+}
+
+const checkpointQuery = `
+	SELECT c.run_id, c.processor, c.config, c.last_document_id, c.stats, c.legs,
+		COALESCE(r.status, ''), c.updated_at
+	FROM match_run_checkpoint c
+	JOIN match_run r ON r.run_id = c.run_id
+	WHERE c.run_id = $1`
+
+// scanCheckpoint reads a checkpointQuery row; nil when there is none
+func (p *Postgres) scanCheckpoint(row *sql.Row) (*Checkpoint, error) {
+	var cp Checkpoint
+	var config, stats []byte
+	err := row.Scan(&cp.RunID, &cp.Processor, &config, &cp.LastDocumentID, &stats, &cp.Legs,
+		&cp.Status, &cp.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	cp.Config, cp.Stats = json.RawMessage(config), json.RawMessage(stats)
+	return &cp, nil
+}
+
+// Enqueue inserts the run's work_queue_chunk rows in one transaction
+func (p *Postgres) Enqueue(ctx context.Context, runID int64, kind string, items []string, chunkSize int) (int, error) {
+	if chunkSize <= 0 {
+		return 0, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO work_queue_chunk (run_id, kind, items) VALUES ($1, $2, $3)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare enqueue: %w", err)
+	}
+	defer stmt.Close()
+
+	chunks := 0
+	for start := 0; start < len(items); start += chunkSize {
+		end := start + chunkSize
+		if end > len(items) {
+			end = len(items)
+		}
+		if _, err := stmt.ExecContext(ctx, runID, kind, pq.Array(items[start:end])); err != nil {
+			return 0, fmt.Errorf("failed to enqueue chunk: %w", err)
+		}
+		chunks++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return chunks, nil
+}
+
+// ClaimChunk claims the lowest pending chunk. SKIP LOCKED lets any number of workers
+// claim concurrently without waiting on, or double-claiming, each other's rows.
+func (p *Postgres) ClaimChunk(ctx context.Context, runID int64, workerID string) (*WorkChunk, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	chunk := WorkChunk{WorkerID: workerID}
+	err := p.db.QueryRowContext(ctx, `
+		UPDATE work_queue_chunk
+		SET status = $3, worker_id = $2, attempts = attempts + 1,
+			claimed_at = now(), heartbeat_at = now()
+		WHERE chunk_id = (
+			SELECT chunk_id FROM work_queue_chunk
+			WHERE run_id = $1 AND status = $4
+			ORDER BY chunk_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING chunk_id, run_id, kind, items, attempts
+	`, runID, workerID, ChunkClaimed, ChunkPending).Scan(
+		&chunk.ChunkID, &chunk.RunID, &chunk.Kind, pq.Array(&chunk.Items), &chunk.Attempts)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim chunk: %w", err)
+	}
+	return &chunk, nil
+}
+
+// Heartbeat touches the chunk's heartbeat_at while workerID still holds it
+func (p *Postgres) Heartbeat(ctx context.Context, chunkID int64, workerID string) error {
+	return p.updateClaimed(ctx, "heartbeat", `
+		UPDATE work_queue_chunk SET heartbeat_at = now()
+		WHERE chunk_id = $1 AND worker_id = $2 AND status = $3
+	`, chunkID, workerID, ChunkClaimed)
+}
+
+// CompleteChunk records the chunk's result if workerID still holds it
+func (p *Postgres) CompleteChunk(ctx context.Context, chunkID int64, workerID string, r ChunkResult) error {
+	return p.updateClaimed(ctx, "complete chunk", `
+		UPDATE work_queue_chunk
+		SET status = $4, completed_at = now(), processed = $5, matched = $6, errors = $7
+		WHERE chunk_id = $1 AND worker_id = $2 AND status = $3
+	`, chunkID, workerID, ChunkClaimed, ChunkDone, r.Processed, r.Matched, r.Errors)
+}
+
+// ReleaseChunk returns a chunk workerID holds to pending, or fails it after maxAttempts;
+// with maxAttempts 0 the claim does not count as an attempt
+func (p *Postgres) ReleaseChunk(ctx context.Context, chunkID int64, workerID, reason string, maxAttempts int) error {
+	return p.updateClaimed(ctx, "release chunk", `
+		UPDATE work_queue_chunk
+		SET status = CASE WHEN $5 > 0 AND attempts >= $5 THEN $6 ELSE $4 END,
+			worker_id = CASE WHEN $5 > 0 AND attempts >= $5 THEN worker_id END,
+			attempts = CASE WHEN $5 = 0 THEN GREATEST(attempts - 1, 0) ELSE attempts END,
+			heartbeat_at = NULL, last_error = $7
+		WHERE chunk_id = $1 AND worker_id = $2 AND status = $3
+	`, chunkID, workerID, ChunkClaimed, ChunkPending, maxAttempts, ChunkFailed, reason)
+}
+
+// updateClaimed runs an update of a chunk workerID claimed; no row means it was lost
+func (p *Postgres) updateClaimed(ctx context.Context, action, query string, args ...interface{}) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrChunkLost
+	}
+	return nil
+}
+
+// RequeueAbandoned releases the run's claimed chunks whose heartbeat is stale
+func (p *Postgres) RequeueAbandoned(ctx context.Context, runID int64, staleAfter time.Duration, maxAttempts int) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, `
+		UPDATE work_queue_chunk
+		SET status = CASE WHEN $3 > 0 AND attempts >= $3 THEN $5 ELSE $6 END,
+			last_error = 'abandoned by ' || worker_id,
+			worker_id = CASE WHEN $3 > 0 AND attempts >= $3 THEN worker_id END,
+			heartbeat_at = NULL
+		WHERE run_id = $1 AND status = $4
+		  AND heartbeat_at < now() - $2 * interval '1 millisecond'
+	`, runID, staleAfter.Milliseconds(), maxAttempts, ChunkClaimed, ChunkFailed, ChunkPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-queue abandoned chunks: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// QueueProgress counts the run's chunks by status
+func (p *Postgres) QueueProgress(ctx context.Context, runID int64) (*QueueProgress, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var q QueueProgress
+	err := p.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status = $2),
+			COUNT(*) FILTER (WHERE status = $3),
+			COUNT(*) FILTER (WHERE status = $4),
+			COUNT(*) FILTER (WHERE status = $5),
+			COALESCE(SUM(array_length(items, 1)), 0),
+			COALESCE(SUM(processed), 0),
+			COALESCE(SUM(matched), 0),
+			COALESCE(SUM(errors), 0)
+		FROM work_queue_chunk
+		WHERE run_id = $1
+	`, runID, ChunkPending, ChunkClaimed, ChunkDone, ChunkFailed).Scan(
+		&q.Pending, &q.Claimed, &q.Done, &q.Failed, &q.Items, &q.Processed, &q.Matched, &q.Errors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue progress: %w", err)
+	}
+	return &q, nil
+}
+
+// WorkerThroughput aggregates the run's chunks by worker_id
+func (p *Postgres) WorkerThroughput(ctx context.Context, runID int64) ([]WorkerThroughput, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT worker_id,
+			COUNT(*) FILTER (WHERE status = $2),
+			COALESCE(SUM(processed) FILTER (WHERE status = $2), 0),
+			COALESCE(SUM(matched) FILTER (WHERE status = $2), 0),
+			COALESCE(SUM(errors) FILTER (WHERE status = $2), 0),
+			COALESCE(EXTRACT(EPOCH FROM SUM(completed_at - claimed_at) FILTER (WHERE status = $2)), 0),
+			COUNT(*) FILTER (WHERE status = $3),
+			MAX(GREATEST(heartbeat_at, completed_at))
+		FROM work_queue_chunk
+		WHERE run_id = $1 AND worker_id IS NOT NULL
+		GROUP BY worker_id
+		ORDER BY 3 DESC, worker_id
+	`, runID, ChunkDone, ChunkClaimed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worker throughput: %w", err)
+	}
+	defer rows.Close()
+
+	var workers []WorkerThroughput
+	for rows.Next() {
+		var w WorkerThroughput
+		var busySeconds float64
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&w.WorkerID, &w.Chunks, &w.Processed, &w.Matched, &w.Errors,
+			&busySeconds, &w.Holding, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan worker throughput: %w", err)
+		}
+		w.Busy = time.Duration(busySeconds * float64(time.Second))
+		if lastSeen.Valid {
+			w.LastSeen = &lastSeen.Time
+		}
+		workers = append(workers, w)
+	}
+	return workers, rows.Err()
+}
+
+// liveLease is true while a review item's lease has not expired
+const liveLease = `COALESCE(lease_expires_at > now(), false)`
+
+// SyncReviewQueue queues documents with needs_review candidates and no accept, and closes
+// unclaimed items that were accepted some other way
+func (p *Postgres) SyncReviewQueue(ctx context.Context) (int, int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, `
+		INSERT INTO review_item (src_id, source_type, confidence)
+		SELECT mr.src_id, MAX(s.source_type), COALESCE(MAX(mr.confidence), 0)
+		FROM match_result mr
+		JOIN src_document s ON s.src_id = mr.src_id
+		WHERE mr.decision = 'needs_review'
+		  AND NOT EXISTS (SELECT 1 FROM match_accepted a WHERE a.src_id = mr.src_id)
+		GROUP BY mr.src_id
+		ON CONFLICT (src_id) DO NOTHING
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to queue documents for review: %w", err)
+	}
+	added, _ := result.RowsAffected()
+
+	result, err = p.db.ExecContext(ctx, `
+		UPDATE review_item r
+		SET status = $1, decision = 'accepted_elsewhere', decided_at = now(),
+			claimed_by = NULL, claimed_at = NULL, lease_expires_at = NULL
+		WHERE r.status <> $1 AND NOT `+liveLease+`
+		  AND EXISTS (SELECT 1 FROM match_accepted a WHERE a.src_id = r.src_id)
+	`, ReviewDone)
+	if err != nil {
+		return int(added), 0, fmt.Errorf("failed to close accepted review items: %w", err)
+	}
+	closed, _ := result.RowsAffected()
+	return int(added), int(closed), nil
+}
+
+// ClaimReview leases the lowest eligible item to reviewer. SKIP LOCKED keeps concurrent
+// claims from waiting on, or double-claiming, the same row; an expired lease is taken
+// over and logged against its previous holder.
+func (p *Postgres) ClaimReview(ctx context.Context, reviewer string, f ReviewFilter, lease time.Duration) (*ReviewItem, error) {
+	held, err := p.heldReview(ctx, reviewer)
+	if err != nil || held != 0 {
+		if err != nil {
+			return nil, err
+		}
+		return p.ReviewItem(ctx, held)
+	}
+
+	srcID, err := p.claimReview(ctx, reviewer, f, lease)
+	if err != nil || srcID == 0 {
+		return nil, err
+	}
+	return p.ReviewItem(ctx, srcID)
+}
+
+// heldReview returns the item reviewer holds a live lease on, or 0
+func (p *Postgres) heldReview(ctx context.Context, reviewer string) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var srcID int64
+	err := p.db.QueryRowContext(ctx, `
+		SELECT src_id FROM review_item
+		WHERE claimed_by = $1 AND status <> $2 AND `+liveLease+`
+		ORDER BY claimed_at
+		LIMIT 1
+	`, reviewer, ReviewDone).Scan(&srcID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read held review item: %w", err)
+	}
+	return srcID, nil
+}
+
+func (p *Postgres) claimReview(ctx context.Context, reviewer string, f ReviewFilter, lease time.Duration) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	args := []interface{}{reviewer}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := []string{"NOT " + liveLease,
+		"NOT EXISTS (SELECT 1 FROM review_skip k WHERE k.src_id = r.src_id AND k.reviewer = $1)"}
+	if f.SignOff {
+		where = append(where, "status = "+arg(ReviewSignoff), "proposed_by <> $1")
+	} else {
+		where = append(where, fmt.Sprintf("(status = %s OR (status = %s AND deferred_until <= now()))",
+			arg(ReviewOpen), arg(ReviewDeferred)))
+	}
+	if f.SourceType != "" {
+		where = append(where, "source_type = "+arg(f.SourceType))
+	}
+	if f.MinConfidence > 0 {
+		where = append(where, "confidence >= "+arg(f.MinConfidence))
+	}
+	if f.MaxConfidence > 0 {
+		where = append(where, "confidence < "+arg(f.MaxConfidence))
+	}
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var srcID int64
+	var previous sql.NullString
+	var previousHeld sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT src_id, claimed_by, (EXTRACT(EPOCH FROM lease_expires_at - claimed_at) * 1000)::bigint
+		FROM review_item r
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY src_id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`, args...).Scan(&srcID, &previous, &previousHeld)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a review item: %w", err)
+	}
+
+	if previous.Valid {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO review_event (src_id, reviewer, action, held_ms) VALUES ($1, $2, $3, $4)
+		`, srcID, previous.String, ReviewExpire, previousHeld)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record expired lease: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE review_item
+		SET claimed_by = $2, claimed_at = now(), lease_expires_at = now() + $3 * interval '1 millisecond',
+			status = CASE WHEN status = $4 THEN $5 ELSE status END,
+			deferred_until = NULL
+		WHERE src_id = $1
+	`, srcID, reviewer, lease.Milliseconds(), ReviewDeferred, ReviewOpen)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim review item %d: %w", srcID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO review_event (src_id, reviewer, action) VALUES ($1, $2, $3)
+	`, srcID, reviewer, ReviewClaim)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record claim: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit claim: %w", err)
+	}
+	return srcID, nil
+}
+
+// ReviewItem reads a review_item with its document's address and needs_review candidates
+func (p *Postgres) ReviewItem(ctx context.Context, srcID int64) (*ReviewItem, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	item := ReviewItem{Candidates: []ReviewCandidate{}}
+	var leaseExpires, deferredUntil, proposedAt sql.NullTime
+	var proposedUPRN sql.NullString
+	var proposal ReviewProposal
+	err := p.db.QueryRowContext(ctx, `
+		SELECT r.src_id, COALESCE(r.source_type, ''), COALESCE(s.raw_address, ''), r.confidence, r.status,
+			COALESCE(r.claimed_by, ''), r.lease_expires_at, r.deferred_until,
+			r.proposed_uprn, COALESCE(r.proposed_method, ''), COALESCE(r.proposed_score, 0),
+			COALESCE(r.proposed_confidence, 0), COALESCE(r.proposed_by, ''), r.proposed_at,
+			COALESCE(r.proposal_reason, ''), COALESCE(r.notes, '')
+		FROM review_item r
+		LEFT JOIN src_document s ON s.src_id = r.src_id
+		WHERE r.src_id = $1
+	`, srcID).Scan(&item.SrcID, &item.SourceType, &item.RawAddress, &item.Confidence, &item.Status,
+		&item.ClaimedBy, &leaseExpires, &deferredUntil,
+		&proposedUPRN, &proposal.Method, &proposal.Score,
+		&proposal.Confidence, &proposal.ProposedBy, &proposedAt,
+		&proposal.Reason, &item.Notes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review item %d: %w", srcID, err)
+	}
+	if leaseExpires.Valid {
+		item.LeaseExpiresAt = &leaseExpires.Time
+	}
+	if deferredUntil.Valid {
+		item.DeferredUntil = &deferredUntil.Time
+	}
+	if proposedUPRN.Valid {
+		proposal.UPRN = proposedUPRN.String
+		proposal.ProposedAt = proposedAt.Time
+		item.Proposal = &proposal
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT mr.candidate_uprn, COALESCE(d.full_address, ''), COALESCE(mr.method, ''),
+			COALESCE(mr.score, 0), COALESCE(mr.confidence, 0)
+		FROM match_result mr
+		LEFT JOIN dim_address d ON d.uprn = mr.candidate_uprn
+		WHERE mr.src_id = $1 AND mr.decision = 'needs_review'
+		ORDER BY mr.score DESC, mr.tie_rank
+		LIMIT 5
+	`, srcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review candidates: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c ReviewCandidate
+		if err := rows.Scan(&c.UPRN, &c.Address, &c.Method, &c.Score, &c.Confidence); err != nil {
+			return nil, fmt.Errorf("failed to scan review candidate: %w", err)
+		}
+		item.Candidates = append(item.Candidates, c)
+	}
+	return &item, rows.Err()
+}
+
+// RenewReview extends the lease reviewer holds on the item
+func (p *Postgres) RenewReview(ctx context.Context, srcID int64, reviewer string, lease time.Duration) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, `
+		UPDATE review_item SET lease_expires_at = now() + $3 * interval '1 millisecond'
+		WHERE src_id = $1 AND claimed_by = $2 AND status <> $4 AND `+liveLease+`
+	`, srcID, reviewer, lease.Milliseconds(), ReviewDone)
+	if err != nil {
+		return fmt.Errorf("failed to renew review lease: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// FinishReview records the outcome, its effect on match_accepted and match_result, and
+// the review_event in one transaction
+func (p *Postgres) FinishReview(ctx context.Context, srcID int64, reviewer string, o ReviewOutcome) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM review_item
+		WHERE src_id = $1 AND claimed_by = $2 AND status <> $3 AND `+liveLease+`
+		FOR UPDATE
+	`, srcID, reviewer, ReviewDone).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock review item %d: %w", srcID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO review_event (src_id, reviewer, action, held_ms)
+		SELECT src_id, $2, $3, (EXTRACT(EPOCH FROM now() - claimed_at) * 1000)::bigint
+		FROM review_item WHERE src_id = $1
+	`, srcID, reviewer, o.Action)
+	if err != nil {
+		return fmt.Errorf("failed to record review event: %w", err)
+	}
+
+	switch o.Action {
+	case ReviewAccept, ReviewConfirm:
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO match_accepted (src_id, uprn, method, score, confidence, accepted_by, manual, accepted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, true, now())
+			ON CONFLICT (src_id) DO UPDATE SET
+				uprn = EXCLUDED.uprn,
+				method = EXCLUDED.method,
+				score = EXCLUDED.score,
+				confidence = EXCLUDED.confidence,
+				accepted_by = EXCLUDED.accepted_by,
+				manual = true,
+				accepted_at = now()
+		`, srcID, o.Proposal.UPRN, o.Proposal.Method, o.Proposal.Score, o.Proposal.Confidence, reviewer)
+		if err != nil {
+			return fmt.Errorf("failed to accept match: %w", err)
+		}
+		err = settleCandidates(ctx, tx, srcID, "manual_accepted", reviewer, o.Notes)
+	case ReviewReject:
+		err = rejectCandidates(ctx, tx, srcID, "review_queue", reviewer, o.Notes)
+		if err == nil {
+			err = settleCandidates(ctx, tx, srcID, "manual_rejected", reviewer, o.Notes)
+		}
+	case ReviewSkip:
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO review_skip (src_id, reviewer) VALUES ($1, $2) ON CONFLICT DO NOTHING
+		`, srcID, reviewer)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record %s: %w", o.Action, err)
+	}
+
+	sets := []string{"claimed_by = NULL", "claimed_at = NULL", "lease_expires_at = NULL"}
+	args := []interface{}{srcID}
+	set := func(column string, v interface{}) {
+		args = append(args, v)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	set("status", reviewStatusAfter(o.Action, status))
+	if o.Notes != "" {
+		set("notes", o.Notes)
+	}
+	switch o.Action {
+	case ReviewPropose:
+		set("proposed_uprn", o.Proposal.UPRN)
+		set("proposed_method", o.Proposal.Method)
+		set("proposed_score", o.Proposal.Score)
+		set("proposed_confidence", o.Proposal.Confidence)
+		set("proposed_by", reviewer)
+		set("proposal_reason", o.Notes)
+		sets = append(sets, "proposed_at = now()")
+	case ReviewRefuse:
+		sets = append(sets, "proposed_uprn = NULL", "proposed_method = NULL", "proposed_score = NULL",
+			"proposed_confidence = NULL", "proposed_by = NULL", "proposed_at = NULL", "proposal_reason = NULL")
+	case ReviewDefer:
+		set("deferred_until", o.DeferUntil)
+	case ReviewAccept, ReviewConfirm, ReviewReject:
+		set("decision", o.Action)
+		set("decided_by", reviewer)
+		sets = append(sets, "decided_at = now()")
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE review_item SET "+strings.Join(sets, ", ")+" WHERE src_id = $1", args...)
+	if err != nil {
+		return fmt.Errorf("failed to update review item %d: %w", srcID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit review: %w", err)
+	}
+	return nil
+}
+
+// settleCandidates marks a document's needs_review candidates with the reviewer's decision
+func settleCandidates(ctx context.Context, tx *sql.Tx, srcID int64, decision, reviewer, notes string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE match_result
+		SET decided = true, decision = $2, decided_by = $3, reviewed_at = now(), notes = $4
+		WHERE src_id = $1 AND decision = 'needs_review'
+	`, srcID, decision, reviewer, notes)
+	return err
+}
+
+// rejectCandidates records the rejection of each of a document's needs_review candidates,
+// so later runs neither propose nor accept them again
+func rejectCandidates(ctx context.Context, tx *sql.Tx, srcID int64, source, reviewer, notes string) error {
+	_, err := tx.ExecContext(ctx, `
+		SELECT record_match_rejection($1, c.candidate_uprn, $2, $4, $3)
+		FROM (
+			SELECT DISTINCT candidate_uprn FROM match_result
+			WHERE src_id = $1 AND decision = 'needs_review' AND candidate_uprn IS NOT NULL
+		) c
+	`, srcID, source, reviewer, notes)
+	return err
+}
+
+// ReviewQueueCounts counts review items by status, with live leases counted as claimed
+func (p *Postgres) ReviewQueueCounts(ctx context.Context) (*ReviewQueueCounts, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var c ReviewQueueCounts
+	err := p.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status = $1 AND NOT `+liveLease+`),
+			COUNT(*) FILTER (WHERE status <> $4 AND `+liveLease+`),
+			COUNT(*) FILTER (WHERE status = $2 AND NOT `+liveLease+`),
+			COUNT(*) FILTER (WHERE status = $3 AND NOT `+liveLease+`),
+			COUNT(*) FILTER (WHERE status = $4)
+		FROM review_item
+	`, ReviewOpen, ReviewDeferred, ReviewSignoff, ReviewDone).Scan(
+		&c.Open, &c.Claimed, &c.Deferred, &c.AwaitingSignoff, &c.Done)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count review items: %w", err)
+	}
+	return &c, nil
+}
+
+// ReviewerStats aggregates review_event by reviewer and action
+func (p *Postgres) ReviewerStats(ctx context.Context, since time.Time) ([]ReviewerStats, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	byReviewer := make(map[string]*ReviewerStats)
+	reviewer := func(name string) *ReviewerStats {
+		s, ok := byReviewer[name]
+		if !ok {
+			s = &ReviewerStats{Reviewer: name, Actions: make(map[string]int)}
+			byReviewer[name] = s
+		}
+		return s
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT reviewer, action, COUNT(*), COALESCE(SUM(held_ms), 0), MAX(created_at)
+		FROM review_event
+		WHERE created_at >= $1
+		GROUP BY reviewer, action
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reviewer stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, action string
+		var count int
+		var heldMS int64
+		var last time.Time
+		if err := rows.Scan(&name, &action, &count, &heldMS, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan reviewer stats: %w", err)
+		}
+		s := reviewer(name)
+		s.Actions[action] = count
+		s.Held += time.Duration(heldMS) * time.Millisecond
+		if s.LastActive == nil || last.After(*s.LastActive) {
+			s.LastActive = &last
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reviewer stats: %w", err)
+	}
+
+	rows, err = p.db.QueryContext(ctx, `
+		SELECT claimed_by, COUNT(*) FROM review_item
+		WHERE status <> $1 AND `+liveLease+`
+		GROUP BY claimed_by
+	`, ReviewDone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read held review items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var holding int
+		if err := rows.Scan(&name, &holding); err != nil {
+			return nil, fmt.Errorf("failed to scan held review items: %w", err)
+		}
+		reviewer(name).Holding = holding
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read held review items: %w", err)
+	}
+	return sortReviewerStats(byReviewer), nil
+}
+
+// SaveResult inserts a match_result row
+func (p *Postgres) SaveResult(ctx context.Context, result *MatchResult) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	featuresJSON, err := json.Marshal(result.Features)
+	if err != nil {
+		return fmt.Errorf("failed to marshal features: %w", err)
+	}
+
+	err = p.db.QueryRowContext(ctx, `
+		INSERT INTO match_result (
+			run_id, src_id, candidate_uprn, method, score, confidence,
+			tie_rank, features, decided, decision, decided_by, decided_at, notes
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING match_id
+	`, result.RunID, result.SrcID, result.CandidateUPRN, result.Method,
+		result.Score, result.Confidence, result.TieRank, featuresJSON,
+		result.Decided, result.Decision, result.DecidedBy, result.DecidedAt, result.Notes).Scan(&result.MatchID)
+	if err != nil {
+		return fmt.Errorf("failed to save match result: %w", err)
+	}
+	return nil
+}
+
+// Accept upserts the document's match_accepted row
+func (p *Postgres) Accept(ctx context.Context, a Acceptance) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO match_accepted (src_id, uprn, method, score, confidence, run_id, accepted_by, manual)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (src_id) DO UPDATE SET
+			uprn = EXCLUDED.uprn,
+			method = EXCLUDED.method,
+			score = EXCLUDED.score,
+			confidence = EXCLUDED.confidence,
+			run_id = EXCLUDED.run_id,
+			accepted_by = EXCLUDED.accepted_by,
+			manual = EXCLUDED.manual,
+			accepted_at = now()
+	`, a.SrcID, a.UPRN, a.Method, a.Score, a.Confidence, a.RunID, a.AcceptedBy, a.Manual)
+	if err != nil {
+		return fmt.Errorf("failed to accept match for src_id %d: %w", a.SrcID, err)
+	}
+	return nil
+}
+
+// Accepted reads the document's match_accepted row
+func (p *Postgres) Accepted(ctx context.Context, srcID int64) (*Acceptance, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	a := &Acceptance{SrcID: srcID}
+	var confidence sql.NullFloat64
+	var runID sql.NullInt64
+	err := p.db.QueryRowContext(ctx, `
+		SELECT uprn, method, score, confidence, run_id, accepted_by, accepted_at, manual
+		FROM match_accepted
+		WHERE src_id = $1
+	`, srcID).Scan(&a.UPRN, &a.Method, &a.Score, &confidence, &runID, &a.AcceptedBy, &a.AcceptedAt, &a.Manual)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accepted match for src_id %d: %w", srcID, err)
+	}
+	a.Confidence, a.RunID = confidence.Float64, runID.Int64
+	return a, nil
+}
+
+// Rejections reads the unlifted rejections that apply to the document
+func (p *Postgres) Rejections(ctx context.Context, srcID int64, canonical string) ([]Rejection, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT rejection_id, src_id, uprn, COALESCE(address_canonical, ''), source,
+			COALESCE(reason, ''), rejected_by, rejected_at
+		FROM match_rejection
+		WHERE lifted_at IS NULL
+		  AND (src_id = $1 OR ($2 <> '' AND address_canonical = $2))
+		ORDER BY rejected_at
+	`, srcID, canonical)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rejections for document %d: %w", srcID, err)
+	}
+	defer rows.Close()
+
+	var rejections []Rejection
+	for rows.Next() {
+		var r Rejection
+		if err := rows.Scan(&r.RejectionID, &r.SrcID, &r.UPRN, &r.AddressCanonical, &r.Source,
+			&r.Reason, &r.RejectedBy, &r.RejectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rejection: %w", err)
+		}
+		rejections = append(rejections, r)
+	}
+	return rejections, rows.Err()
+}
+
+// SaveAddressMatch upserts the document's address_match row
+func (p *Postgres) SaveAddressMatch(ctx context.Context, m AddressMatch) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO address_match (
+			document_id, address_id, location_id, match_method_id,
+			confidence_score, match_status, matched_by, matched_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+		ON CONFLICT (document_id) DO UPDATE SET
+			address_id = EXCLUDED.address_id,
+			location_id = EXCLUDED.location_id,
+			match_method_id = EXCLUDED.match_method_id,
+			confidence_score = EXCLUDED.confidence_score,
+			match_status = EXCLUDED.match_status,
+			matched_by = EXCLUDED.matched_by,
+			matched_at = now()
+	`, m.DocumentID, m.AddressID, m.LocationID, m.MethodID, m.Score, m.Status, m.MatchedBy)
+	if err != nil {
+		return fmt.Errorf("failed to save address match for document %d: %w", m.DocumentID, err)
+	}
+	return nil
+}
+
+// SaveExplanation inserts the document's match_explanation row for the run
+func (p *Postgres) SaveExplanation(ctx context.Context, e Explanation) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return saveExplanation(ctx, p.db, e)
+}
+
+// saveExplanation inserts a match_explanation row; nil thresholds and candidates are
+// stored as an empty object and list
+func saveExplanation(ctx context.Context, db execer, e Explanation) error {
+	thresholds := []byte("{}")
+	if e.Thresholds != nil {
+		encoded, err := json.Marshal(e.Thresholds)
+		if err != nil {
+			return fmt.Errorf("failed to encode thresholds: %w", err)
+		}
+		thresholds = encoded
+	}
+	candidates := []byte("[]")
+	if e.Candidates != nil {
+		encoded, err := json.Marshal(e.Candidates)
+		if err != nil {
+			return fmt.Errorf("failed to encode explanation for document %d: %w", e.SrcID, err)
+		}
+		candidates = encoded
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO match_explanation (run_id, src_id, decision, accepted_uprn, tier, margin, reason, thresholds, candidates)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, NULLIF($7, ''), $8, $9)
+	`, e.RunID, e.SrcID, e.Decision, e.AcceptedUPRN, e.Tier, e.Margin, e.Reason, thresholds, candidates)
+	if err != nil {
+		return fmt.Errorf("failed to save explanation for document %d: %w", e.SrcID, err)
+	}
+	return nil
+}
+
+// LatestExplanation reads the document's newest match_explanation outside rolled-back runs
+func (p *Postgres) LatestExplanation(ctx context.Context, srcID int64) (*Explanation, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	e := Explanation{SrcID: srcID}
+	var acceptedUPRN, tier, reason sql.NullString
+	var margin sql.NullFloat64
+	var thresholds, candidates []byte
+	err := p.db.QueryRowContext(ctx, `
+		SELECT run_id, decision, accepted_uprn, tier, margin, reason, thresholds, candidates, created_at
+		FROM match_explanation
+		WHERE src_id = $1
+		  AND run_id NOT IN (SELECT run_id FROM match_run_rollback WHERE completed_at IS NOT NULL)
+		ORDER BY created_at DESC, explanation_id DESC
+		LIMIT 1
+	`, srcID).Scan(&e.RunID, &e.Decision, &acceptedUPRN, &tier, &margin, &reason,
+		&thresholds, &candidates, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read explanation for document %d: %w", srcID, err)
+	}
+
+	e.AcceptedUPRN, e.Tier, e.Reason = acceptedUPRN.String, tier.String, reason.String
+	if margin.Valid {
+		e.Margin = &margin.Float64
+	}
+	e.Thresholds, e.Candidates = json.RawMessage(thresholds), json.RawMessage(candidates)
+	return &e, nil
+}
+
+// RecordDecision writes match_result, match_accepted, match_audit and, for a decision
+// with a run, match_explanation in one transaction
+func (p *Postgres) RecordDecision(ctx context.Context, d DecisionRecord) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var matchID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO match_result (
+			run_id, src_id, candidate_uprn, method, score, tie_rank,
+			decided, decision, decided_by, decided_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING match_id
+	`, d.RunID, d.SrcID, d.UPRN, d.Method, d.Score, 1,
+		true, d.Decision, d.DecidedBy, d.DecidedAt).Scan(&matchID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert match result: %w", err)
+	}
+
+	for _, alt := range d.Alternatives {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO match_result (
+				run_id, src_id, candidate_uprn, method, score, tie_rank,
+				decided, decision, decided_by, decided_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, d.RunID, d.SrcID, alt.UPRN, alt.Method, alt.Score, alt.TieRank,
+			false, "not_selected", d.DecidedBy, d.DecidedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record candidate %s: %w", alt.UPRN, err)
+		}
+	}
+
+	if d.Decision == "accepted" {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO match_accepted (src_id, uprn, method, score, run_id, accepted_by, accepted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (src_id) DO UPDATE SET
+				uprn = EXCLUDED.uprn,
+				method = EXCLUDED.method,
+				score = EXCLUDED.score,
+				run_id = EXCLUDED.run_id,
+				accepted_by = EXCLUDED.accepted_by,
+				manual = false,
+				accepted_at = EXCLUDED.accepted_at
+		`, d.SrcID, d.UPRN, d.Method, d.Score, d.RunID, d.DecidedBy, d.DecidedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert match accepted: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS match_audit (
+			audit_id        bigserial PRIMARY KEY,
+			match_id        bigint REFERENCES match_result(match_id),
+			src_id          bigint NOT NULL,
+			decision_type   text NOT NULL,
+			features_json   jsonb,
+			candidates_json jsonb,
+			explanation_json jsonb,
+			processing_time_ms bigint,
+			created_at      timestamptz DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create audit table: %w", err)
+	}
+
+	featuresJSON, _ := json.Marshal(d.Features)
+	candidatesJSON, _ := json.Marshal(d.Candidates)
+	explanationJSON, _ := json.Marshal(d.Explanation)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO match_audit (
+			match_id, src_id, decision_type, features_json, candidates_json,
+			explanation_json, processing_time_ms
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, matchID, d.SrcID, d.Decision, featuresJSON, candidatesJSON,
+		explanationJSON, d.ProcessingTime.Milliseconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert detailed audit: %w", err)
+	}
+
+	if d.Explained != nil && d.RunID != 0 {
+		explained := *d.Explained
+		explained.RunID, explained.SrcID = d.RunID, d.SrcID
+		if err := saveExplanation(ctx, tx, explained); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return matchID, nil
+}
+
+// RecordOverride inserts a match_override row and, with a UPRN, replaces the accept
+func (p *Postgres) RecordOverride(ctx context.Context, o OverrideRecord) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO match_override (src_id, uprn, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, o.SrcID, o.UPRN, o.Reason, o.ReviewerID, o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record manual override: %w", err)
+	}
+
+	if o.UPRN != "" {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO match_accepted (src_id, uprn, method, score, run_id, accepted_by, manual, accepted_at)
+			VALUES ($1, $2, 'manual_override', 1.0, 0, $3, true, $4)
+			ON CONFLICT (src_id) DO UPDATE SET
+				uprn = EXCLUDED.uprn,
+				method = EXCLUDED.method,
+				accepted_by = EXCLUDED.accepted_by,
+				manual = true,
+				accepted_at = EXCLUDED.accepted_at
+		`, o.SrcID, o.UPRN, o.ReviewerID, o.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update match_accepted: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DecisionHistory reads a document's match_result rows with their audit detail, newest first
+func (p *Postgres) DecisionHistory(ctx context.Context, srcID int64) ([]DecisionHistoryEntry, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			mr.match_id,
+			mr.run_id,
+			mr.candidate_uprn,
+			mr.method,
+			mr.score,
+			mr.tie_rank,
+			mr.decided,
+			mr.decision,
+			mr.decided_by,
+			mr.decided_at,
+			ma.features_json,
+			ma.explanation_json,
+			ma.processing_time_ms,
+			COALESCE(r.run_label, ''),
+			COALESCE(da.locaddress, '') as candidate_address
+		FROM match_result mr
+		LEFT JOIN match_audit ma ON ma.match_id = mr.match_id
+		LEFT JOIN match_run r ON r.run_id = mr.run_id
+		LEFT JOIN dim_address da ON da.uprn = mr.candidate_uprn
+		WHERE mr.src_id = $1
+		ORDER BY mr.decided_at DESC, mr.tie_rank ASC
+	`, srcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query decision history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []DecisionHistoryEntry
+	for rows.Next() {
+		var entry DecisionHistoryEntry
+		var featuresJSON, explanationJSON sql.NullString
+		var processingTimeMS sql.NullInt64
+
+		err := rows.Scan(
+			&entry.MatchID, &entry.RunID, &entry.CandidateUPRN, &entry.Method, &entry.Score,
+			&entry.TieRank, &entry.Decided, &entry.Decision, &entry.DecidedBy, &entry.DecidedAt,
+			&featuresJSON, &explanationJSON, &processingTimeMS, &entry.RunLabel, &entry.CandidateAddress,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan decision history: %w", err)
+		}
+
+		if featuresJSON.Valid {
+			json.Unmarshal([]byte(featuresJSON.String), &entry.Features)
+		}
+		if explanationJSON.Valid {
+			json.Unmarshal([]byte(explanationJSON.String), &entry.Explanation)
+		}
+		if processingTimeMS.Valid {
+			entry.ProcessingTime = time.Duration(processingTimeMS.Int64) * time.Millisecond
+		}
+
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// OverrideHistory reads a document's match_override rows, newest first
+func (p *Postgres) OverrideHistory(ctx context.Context, srcID int64) ([]OverrideHistoryEntry, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			mo.override_id,
+			COALESCE(mo.uprn, ''),
+			mo.reason,
+			mo.created_by,
+			mo.created_at,
+			COALESCE(da.locaddress, '') as uprn_address
+		FROM match_override mo
+		LEFT JOIN dim_address da ON da.uprn = mo.uprn
+		WHERE mo.src_id = $1
+		ORDER BY mo.created_at DESC
+	`, srcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query override history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []OverrideHistoryEntry
+	for rows.Next() {
+		var entry OverrideHistoryEntry
+		err := rows.Scan(&entry.OverrideID, &entry.UPRN, &entry.Reason, &entry.CreatedBy,
+			&entry.CreatedAt, &entry.UPRNAddress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan override history: %w", err)
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// RunStatistics summarises a run's rank-1 results by decision and method
+func (p *Postgres) RunStatistics(ctx context.Context, runID int64) (*MatchingStats, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	stats := &MatchingStats{RunID: runID}
+
+	var notes sql.NullString
+	err := p.db.QueryRowContext(ctx, `
+		SELECT run_label, run_started_at, notes
+		FROM match_run
+		WHERE run_id = $1
+	`, runID).Scan(&stats.RunLabel, &stats.RunStartedAt, &notes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run info: %w", err)
+	}
+	stats.Notes = notes.String
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			decision,
+			method,
+			COUNT(*) as count,
+			AVG(score) as avg_score,
+			MIN(score) as min_score,
+			MAX(score) as max_score
+		FROM match_result
+		WHERE run_id = $1 AND tie_rank = 1
+		GROUP BY decision, method
+		ORDER BY decision, method
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query decision stats: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []resultGroup
+	for rows.Next() {
+		var g resultGroup
+		if err := rows.Scan(&g.decision, &g.method, &g.count, &g.avg, &g.min, &g.max); err != nil {
+			return nil, fmt.Errorf("failed to scan decision stats: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	summariseGroups(stats, groups)
+	return stats, nil
+}
+
+// resultGroup is one (decision, method) group of rank-1 results
+type resultGroup struct {
+	decision, method string
+	count            int64
+	avg, min, max    float64
+}
+
+// summariseGroups fills the decision and method breakdowns from grouped results
+func summariseGroups(stats *MatchingStats, groups []resultGroup) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].decision != groups[j].decision {
+			return groups[i].decision < groups[j].decision
+		}
+		return groups[i].method < groups[j].method
+	})
+
+	stats.DecisionBreakdown = make(map[string]DecisionStats)
+	stats.MethodBreakdown = make(map[string]MethodStats)
+	for _, g := range groups {
+		ds := stats.DecisionBreakdown[g.decision]
+		ds.Decision = g.decision
+		ds.Count += g.count
+		stats.DecisionBreakdown[g.decision] = ds
+
+		ms, exists := stats.MethodBreakdown[g.method]
+		if exists {
+			ms.AvgScore = (ms.AvgScore*float64(ms.Count) + g.avg*float64(g.count)) / float64(ms.Count+g.count)
+			ms.Count += g.count
+			if g.min < ms.MinScore {
+				ms.MinScore = g.min
+			}
+			if g.max > ms.MaxScore {
+				ms.MaxScore = g.max
+			}
+		} else {
+			ms = MethodStats{Method: g.method, Count: g.count, AvgScore: g.avg, MinScore: g.min, MaxScore: g.max}
+		}
+		stats.MethodBreakdown[g.method] = ms
+
+		stats.TotalProcessed += g.count
+	}
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// LiveRecords keeps v_enhanced_source_documents and v_map_all_records to documents still in
+// their source file; a later import batch sets removed_at on the others
+const LiveRecords = "src_id NOT IN (SELECT src_id FROM src_document WHERE removed_at IS NOT NULL AND src_id IS NOT NULL)"
+
+// recordColumns are the v_enhanced_source_documents columns read into a Record
+const recordColumns = `
+	src_id, source_type, filepath, external_ref, doc_type, doc_date,
+	original_address, canonical_address, extracted_postcode,
+	source_uprn, source_easting, source_northing,
+	address_quality, match_status, match_method, match_score,
+	coordinate_distance, address_similarity,
+	matched_uprn, llpg_address, llpg_easting, llpg_northing, usrn,
+	import_date`
+
+// mapFeature is the jsonb_build_object expression for a v_map_all_records feature
+const mapFeature = `jsonb_build_object(
+	'type', 'Feature',
+	'properties', jsonb_build_object(
+		'src_id', src_id,
+		'source_type', source_type,
+		'external_reference', external_reference,
+		'address', address,
+		'uprn', uprn,
+		'match_status', match_status,
+		'match_score', match_score,
+		'address_quality', address_quality,
+		'match_method', match_method,
+		'doc_type', doc_type,
+		'doc_date', doc_date
+	)
+)`
+
+func scanRecord(row interface{ Scan(...interface{}) error }) (Record, error) {
+	var r Record
+	var docDate sql.NullString
+	err := row.Scan(
+		&r.SrcID, &r.SourceType, &r.Filepath, &r.ExternalRef,
+		&r.DocType, &docDate, &r.OriginalAddress, &r.CanonicalAddress,
+		&r.ExtractedPostcode, &r.SourceUPRN, &r.SourceEasting, &r.SourceNorthing,
+		&r.AddressQuality, &r.MatchStatus, &r.MatchMethod, &r.MatchScore,
+		&r.CoordinateDistance, &r.AddressSimilarity,
+		&r.MatchedUPRN, &r.LLPGAddress, &r.LLPGEasting, &r.LLPGNorthing,
+		&r.USRN, &r.ImportDate,
+	)
+	if docDate.Valid {
+		r.DocDate = &docDate.String
+	}
+	return r, err
+}
+
+// recordConditions appends f's filters to a record query's WHERE clause; address is the
+// column expression AddressSearch looks in
+func recordConditions(f RecordFilter, address string, args []interface{}) (string, []interface{}) {
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var where string
+	if f.SourceType != "" {
+		where += " AND source_type = " + arg(f.SourceType)
+	}
+	if f.MatchStatus != "" {
+		where += " AND match_status = " + arg(f.MatchStatus)
+	}
+	if f.AddressQuality != "" {
+		where += " AND address_quality = " + arg(f.AddressQuality)
+	}
+	if f.MinScore != nil {
+		where += " AND match_score >= " + arg(*f.MinScore)
+	}
+	if f.MaxScore != nil {
+		where += " AND match_score <= " + arg(*f.MaxScore)
+	}
+	if f.AddressSearch != "" {
+		pattern := arg("%" + f.AddressSearch + "%")
+		where += " AND (" + strings.ReplaceAll(address, "?", pattern) + ")"
+	}
+	return where, args
+}
+
+// Records reads v_enhanced_source_documents
+func (p *Postgres) Records(ctx context.Context, f RecordFilter, limit, offset int) ([]Record, int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	where, args := recordConditions(f, "original_address ILIKE ? OR canonical_address ILIKE ?", nil)
+
+	var total int
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM v_enhanced_source_documents
+		WHERE `+LiveRecords+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	args = append(args, limit, offset)
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+recordColumns+`
+		FROM v_enhanced_source_documents
+		WHERE `+LiveRecords+where+fmt.Sprintf(`
+		ORDER BY src_id
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan record: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, total, rows.Err()
+}
+
+func (p *Postgres) Record(ctx context.Context, srcID int64) (*Record, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	r, err := scanRecord(p.db.QueryRowContext(ctx, `
+		SELECT `+recordColumns+`
+		FROM v_enhanced_source_documents
+		WHERE src_id = $1
+	`, srcID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record %d: %w", srcID, err)
+	}
+	return &r, nil
+}
+
+func (p *Postgres) SearchRecords(ctx context.Context, term string, f RecordFilter, limit int) ([]RecordSearchResult, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	f.AddressSearch = ""
+	where, args := recordConditions(f, "", []interface{}{"%" + term + "%"})
+	args = append(args, limit)
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			src_id, source_type,
+			COALESCE(canonical_address, original_address) as address,
+			match_status, address_quality, match_score, external_ref
+		FROM v_enhanced_source_documents
+		WHERE (
+			original_address ILIKE $1 OR
+			canonical_address ILIKE $1 OR
+			external_ref ILIKE $1
+		)
+		AND `+LiveRecords+where+fmt.Sprintf(`
+		ORDER BY
+			CASE
+				WHEN external_ref ILIKE $1 THEN 1
+				WHEN original_address ILIKE $1 THEN 2
+				WHEN canonical_address ILIKE $1 THEN 3
+				ELSE 4
+			END,
+			src_id
+		LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("record search failed: %w", err)
+	}
+	defer rows.Close()
+
+	var results []RecordSearchResult
+	for rows.Next() {
+		var r RecordSearchResult
+		if err := rows.Scan(&r.SrcID, &r.SourceType, &r.Address, &r.MatchStatus,
+			&r.AddressQuality, &r.MatchScore, &r.ExternalRef); err != nil {
+			return nil, fmt.Errorf("failed to scan record: %w", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// addressListingColumns are the dim_address columns read into an AddressListing
+const addressListingColumns = `
+	uprn, locaddress, addr_can, easting, northing,
+	usrn, blpu_class, postal_flag, status`
+
+func (p *Postgres) queryAddressListings(ctx context.Context, query string, args ...interface{}) ([]AddressListing, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("address query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var listings []AddressListing
+	for rows.Next() {
+		var a AddressListing
+		if err := rows.Scan(&a.UPRN, &a.Address, &a.CanonicalAddress, &a.Easting, &a.Northing,
+			&a.USRN, &a.BLPUClass, &a.PostalFlag, &a.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan address: %w", err)
+		}
+		listings = append(listings, a)
+	}
+	return listings, rows.Err()
+}
+
+// SearchAddresses ranks addresses containing term before those only trigram-similar to it
+func (p *Postgres) SearchAddresses(ctx context.Context, term string, limit int) ([]AddressListing, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return p.queryAddressListings(ctx, `
+		SELECT `+addressListingColumns+`
+		FROM dim_address
+		WHERE
+			locaddress ILIKE $1 OR
+			addr_can ILIKE $1 OR
+			addr_can % $2
+		ORDER BY
+			CASE
+				WHEN locaddress ILIKE $1 THEN 1
+				WHEN addr_can ILIKE $1 THEN 2
+				ELSE 3
+			END,
+			similarity(addr_can, $2) DESC
+		LIMIT $3
+	`, "%"+term+"%", term, limit)
+}
+
+func (p *Postgres) RecordCandidates(ctx context.Context, srcID int64, limit int) ([]AddressListing, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return p.queryAddressListings(ctx, `
+		SELECT `+addressListingColumns+`
+		FROM dim_address
+		WHERE addr_can ILIKE (
+			SELECT '%' || COALESCE(canonical_address, original_address) || '%'
+			FROM v_enhanced_source_documents
+			WHERE src_id = $1
+		)
+		LIMIT $2
+	`, srcID, limit)
+}
+
+func (p *Postgres) AddressExists(ctx context.Context, uprn string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := p.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM dim_address WHERE uprn = $1)`, uprn).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up UPRN %s: %w", uprn, err)
+	}
+	return exists, nil
+}
+
+// BestResult orders by confidence, falling back to score for results recorded without one
+func (p *Postgres) BestResult(ctx context.Context, srcID int64, uprn string) (*MatchResult, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	r := MatchResult{SrcID: srcID, CandidateUPRN: uprn}
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COALESCE(method, 'manual'), COALESCE(score, 0), COALESCE(confidence, score, 0)
+		FROM match_result
+		WHERE src_id = $1 AND candidate_uprn = $2
+		ORDER BY COALESCE(confidence, score) DESC NULLS LAST
+		LIMIT 1
+	`, srcID, uprn).Scan(&r.Method, &r.Score, &r.Confidence)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read candidate %s for document %d: %w", uprn, srcID, err)
+	}
+	return &r, nil
+}
+
+func (p *Postgres) LatestResults(ctx context.Context, srcID int64, limit int) ([]ResultSummary, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT mr.run_id, mr.candidate_uprn, COALESCE(d.locaddress, ''), COALESCE(mr.method, ''),
+			COALESCE(mr.score, 0), COALESCE(mr.decision, ''), mr.decided_at
+		FROM match_result mr
+		LEFT JOIN dim_address d ON d.uprn = mr.candidate_uprn
+		WHERE mr.src_id = $1
+		  AND mr.run_id = (SELECT max(run_id) FROM match_result WHERE src_id = $1)
+		ORDER BY mr.tie_rank
+		LIMIT $2
+	`, srcID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query results for document %d: %w", srcID, err)
+	}
+	defer rows.Close()
+
+	var results []ResultSummary
+	for rows.Next() {
+		var r ResultSummary
+		if err := rows.Scan(&r.RunID, &r.UPRN, &r.Address, &r.Method, &r.Score, &r.Decision, &r.DecidedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan result: %w", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (p *Postgres) RecordStats(ctx context.Context) (*RecordStats, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	stats := &RecordStats{
+		BySourceType:     make(map[string]CategoryCount),
+		ByMatchMethod:    make(map[string]int),
+		ByAddressQuality: make(map[string]CategoryCount),
+	}
+	err := p.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) as total,
+			COUNT(CASE WHEN match_status = 'MATCHED' THEN 1 END) as matched,
+			COUNT(CASE WHEN match_status = 'UNMATCHED' THEN 1 END) as unmatched,
+			COUNT(CASE WHEN match_status = 'NEEDS_REVIEW' THEN 1 END) as needs_review
+		FROM v_enhanced_source_documents
+		WHERE `+LiveRecords).Scan(&stats.Total, &stats.Matched, &stats.Unmatched, &stats.NeedsReview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	for column, counts := range map[string]map[string]CategoryCount{
+		"source_type":     stats.BySourceType,
+		"address_quality": stats.ByAddressQuality,
+	} {
+		rows, err := p.db.QueryContext(ctx, `
+			SELECT
+				`+column+`,
+				COUNT(*) as total,
+				COUNT(CASE WHEN match_status = 'MATCHED' THEN 1 END) as matched
+			FROM v_enhanced_source_documents
+			WHERE `+LiveRecords+`
+			GROUP BY `+column)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count records by %s: %w", column, err)
+		}
+		for rows.Next() {
+			var category string
+			var c CategoryCount
+			if err := rows.Scan(&category, &c.Total, &c.Matched); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s counts: %w", column, err)
+			}
+			counts[category] = c
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			COALESCE(match_method, 'unknown') as method,
+			COUNT(*) as count
+		FROM v_enhanced_source_documents
+		WHERE match_status = 'MATCHED' AND `+LiveRecords+`
+		GROUP BY match_method
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count records by method: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var method string
+		var count int
+		if err := rows.Scan(&method, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan method counts: %w", err)
+		}
+		stats.ByMatchMethod[method] = count
+	}
+	return stats, rows.Err()
+}
+
+func (p *Postgres) ViewportCounts(ctx context.Context, box *BNGBox) ([]ViewportCount, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var within string
+	var args []interface{}
+	if box != nil {
+		within = " AND easting BETWEEN $1 AND $2 AND northing BETWEEN $3 AND $4"
+		args = append(args, box.MinE, box.MaxE, box.MinN, box.MaxN)
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			source_type,
+			match_status,
+			address_quality,
+			COUNT(*) as count
+		FROM v_map_all_records
+		WHERE easting IS NOT NULL AND northing IS NOT NULL AND `+LiveRecords+within+`
+		GROUP BY source_type, match_status, address_quality
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count mapped records: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []ViewportCount
+	for rows.Next() {
+		var c ViewportCount
+		if err := rows.Scan(&c.SourceType, &c.MatchStatus, &c.AddressQuality, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan mapped record counts: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// MapFeatures selects from v_map_all_records inside a box, and otherwise uses
+// get_record_geojson, whose features carry their geometry
+func (p *Postgres) MapFeatures(ctx context.Context, f RecordFilter, box *BNGBox, limit int) ([]MapFeature, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var query string
+	var args []interface{}
+	if box != nil {
+		var where string
+		where, args = recordConditions(f, "address ILIKE ?", []interface{}{box.MinE, box.MaxE, box.MinN, box.MaxN})
+		args = append(args, limit)
+		query = `
+			SELECT ` + mapFeature + ` as geojson_feature, easting, northing
+			FROM v_map_all_records
+			WHERE easting IS NOT NULL
+			  AND northing IS NOT NULL
+			  AND ` + LiveRecords + `
+			  AND easting BETWEEN $1 AND $2
+			  AND northing BETWEEN $3 AND $4` + where + fmt.Sprintf(`
+			ORDER BY src_id
+			LIMIT $%d`, len(args))
+	} else {
+		// The function's features carry src_id as a property, as the box query's do
+		query = `
+			SELECT geojson_feature, NULL::float8, NULL::float8
+			FROM get_record_geojson($1, $2, $3, $4, $5, $6, $7)
+			WHERE (geojson_feature -> 'properties' ->> 'src_id')::BIGINT NOT IN (
+				SELECT src_id FROM src_document WHERE removed_at IS NOT NULL AND src_id IS NOT NULL)`
+		args = []interface{}{
+			nullIfEmpty(f.SourceType),
+			nullIfEmpty(f.MatchStatus),
+			nullIfEmpty(f.AddressQuality),
+			f.MinScore,
+			f.MaxScore,
+			nullIfEmpty(f.AddressSearch),
+			limit,
+		}
+	}
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("map feature query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var features []MapFeature
+	for rows.Next() {
+		var mf MapFeature
+		var feature []byte
+		if err := rows.Scan(&feature, &mf.Easting, &mf.Northing); err != nil {
+			return nil, fmt.Errorf("failed to scan map feature: %w", err)
+		}
+		mf.Feature = json.RawMessage(feature)
+		features = append(features, mf)
+	}
+	return features, rows.Err()
+}
+
+func (p *Postgres) RecentAccepts(ctx context.Context, within time.Duration) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM match_accepted
+		WHERE accepted_at > NOW() - make_interval(secs => $1)
+	`, within.Seconds()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recent accepts: %w", err)
+	}
+	return count, nil
+}
+
+// currentMatch reads a record's status and matched UPRN for the audit row of a decision
+func currentMatch(ctx context.Context, tx *sql.Tx, srcID int64) (status, uprn sql.NullString, err error) {
+	err = tx.QueryRowContext(ctx, `
+		SELECT match_status, matched_uprn
+		FROM v_enhanced_source_documents
+		WHERE src_id = $1
+	`, srcID).Scan(&status, &uprn)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return status, uprn, err
+}
+
+// ManualAccept writes audit_match_decisions and a manual match_accepted row
+func (p *Postgres) ManualAccept(ctx context.Context, d ManualDecision) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	oldStatus, oldUPRN, err := currentMatch(ctx, tx, d.SrcID)
+	if err != nil {
+		return fmt.Errorf("failed to read record %d: %w", d.SrcID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_match_decisions (
+			src_id, old_match_status, new_match_status, old_uprn, new_uprn,
+			decision_type, decision_reason, match_method, match_score, confidence,
+			decided_by, client_info
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`, d.SrcID, oldStatus, RecordMatched, oldUPRN, d.UPRN, "ACCEPT_MATCH",
+		d.Reason, d.Method, d.Score, d.Confidence, d.DecidedBy, d.ClientInfo)
+	if err != nil {
+		return fmt.Errorf("failed to audit accept: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO match_accepted (src_id, uprn, method, score, confidence, accepted_by, manual, accepted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, true, NOW())
+		ON CONFLICT (src_id) DO UPDATE SET
+			uprn = EXCLUDED.uprn,
+			method = EXCLUDED.method,
+			score = EXCLUDED.score,
+			confidence = EXCLUDED.confidence,
+			accepted_by = EXCLUDED.accepted_by,
+			manual = true,
+			accepted_at = NOW()
+	`, d.SrcID, d.UPRN, d.Method, d.Score, d.Confidence, d.DecidedBy)
+	if err != nil {
+		return fmt.Errorf("failed to accept: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ManualReject writes audit_match_decisions, records the rejection and deletes the
+// match_accepted row
+func (p *Postgres) ManualReject(ctx context.Context, d ManualDecision) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	oldStatus, oldUPRN, err := currentMatch(ctx, tx, d.SrcID)
+	if err != nil {
+		return fmt.Errorf("failed to read record %d: %w", d.SrcID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_match_decisions (
+			src_id, old_match_status, new_match_status, old_uprn, new_uprn,
+			decision_type, decision_reason, decided_by, client_info
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, d.SrcID, oldStatus, RecordUnmatched, oldUPRN, nil, "REJECT_MATCH",
+		d.Reason, d.DecidedBy, d.ClientInfo)
+	if err != nil {
+		return fmt.Errorf("failed to audit reject: %w", err)
+	}
+
+	// Keep the rejected UPRN out of later runs for this record and its canonical address
+	_, err = tx.ExecContext(ctx, "SELECT record_match_rejection($1, $2, 'web_reject', $3, $4)",
+		d.SrcID, oldUPRN, d.Reason, d.DecidedBy)
+	if err != nil {
+		return fmt.Errorf("failed to record rejection: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM match_accepted WHERE src_id = $1", d.SrcID); err != nil {
+		return fmt.Errorf("failed to remove accept: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SetCoordinates writes audit_coordinate_changes and the new source_documents coordinates
+func (p *Postgres) SetCoordinates(ctx context.Context, c CoordinateChange) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := database.BeginTx(ctx, p.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldE, oldN sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `
+		SELECT source_easting, source_northing
+		FROM source_documents
+		WHERE src_id = $1
+	`, c.SrcID).Scan(&oldE, &oldN)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read coordinates of record %d: %w", c.SrcID, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_coordinate_changes (
+			src_id, old_easting, old_northing, new_easting, new_northing,
+			coordinate_source, change_reason, changed_by, client_info
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, c.SrcID, oldE, oldN, c.Easting, c.Northing, c.Source, c.Reason, c.ChangedBy, c.ClientInfo)
+	if err != nil {
+		return fmt.Errorf("failed to audit coordinate change: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE source_documents
+		SET source_easting = $1, source_northing = $2
+		WHERE src_id = $3
+	`, c.Easting, c.Northing, c.SrcID)
+	if err != nil {
+		return fmt.Errorf("failed to set coordinates: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (p *Postgres) RecordHistory(ctx context.Context, srcID int64, eventType string, limit int) ([]RecordEvent, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	args := []interface{}{srcID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `
+		SELECT event_type, action, details, user_name, event_timestamp, notes
+		FROM v_record_audit_history
+		WHERE src_id = $1`
+	if eventType != "" {
+		query += " AND event_type = " + arg(eventType)
+	}
+	query += " ORDER BY event_timestamp DESC LIMIT " + arg(limit)
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history of record %d: %w", srcID, err)
+	}
+	defer rows.Close()
+
+	var history []RecordEvent
+	for rows.Next() {
+		var e RecordEvent
+		if err := rows.Scan(&e.EventType, &e.Action, &e.Details, &e.UserName, &e.EventTimestamp, &e.Notes); err != nil {
+			return nil, fmt.Errorf("failed to scan history: %w", err)
+		}
+		history = append(history, e)
+	}
+	return history, rows.Err()
+}
+
+func (p *Postgres) AddNote(ctx context.Context, n *RecordNote) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO record_notes (src_id, note_type, note_text, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, n.SrcID, n.Type, n.Text, n.CreatedBy).Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add note to record %d: %w", n.SrcID, err)
+	}
+	return nil
+}
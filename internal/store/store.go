@@ -0,0 +1,949 @@
+// Package store defines the repositories the matching engines read and write through:
+// LLPG addresses, source documents, match results and accepts, and the audit trail.
+// Postgres is the production implementation; Memory is loaded from fixture CSVs so
+// engines can be tested without a database.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Address is an LLPG address (dim_address with its dim_location coordinates)
+type Address struct {
+	AddressID   int
+	LocationID  int
+	UPRN        string
+	FullAddress string
+	Canonical   string
+	USRN        *string
+	BLPUClass   *string
+	Status      *string
+	Easting     *float64
+	Northing    *float64
+	Components  Components
+
+	// LLPG lifecycle, for point-in-time matching against SourceDocument.DocDate
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// ScoredAddress is an address with its trigram similarity to the query
+type ScoredAddress struct {
+	Address
+	Similarity float64
+}
+
+// Components are parsed address parts (the gopostal_* columns)
+type Components struct {
+	HouseNumber string
+	Road        string
+	City        string
+	Postcode    string
+	Unit        string
+}
+
+// SourceDocument represents a source document for matching
+type SourceDocument struct {
+	SrcID        int64      `json:"src_id"`
+	SourceType   string     `json:"source_type"`
+	JobNumber    *string    `json:"job_number,omitempty"`
+	Filepath     *string    `json:"filepath,omitempty"`
+	ExternalRef  *string    `json:"external_ref,omitempty"`
+	DocType      *string    `json:"doc_type,omitempty"`
+	DocDate      *time.Time `json:"doc_date,omitempty"`
+	RawAddress   string     `json:"raw_address"`
+	AddrCan      *string    `json:"addr_can,omitempty"`
+	PostcodeText *string    `json:"postcode_text,omitempty"`
+	UPRNRaw      *string    `json:"uprn_raw,omitempty"`
+	EastingRaw   *float64   `json:"easting_raw,omitempty"`
+	NorthingRaw  *float64   `json:"northing_raw,omitempty"`
+
+	// Validated/repaired coordinates (src_document.easting_repaired); spatial matching uses only these
+	Easting  *float64 `json:"easting,omitempty"`
+	Northing *float64 `json:"northing,omitempty"`
+}
+
+// PostcodeQuality summarises postcode coverage of the source documents
+type PostcodeQuality struct {
+	WithPostcode    int
+	WithoutPostcode int
+	Invalid         int
+	TopPostcodes    []PostcodeCount
+	MatchPotential  int // unmatched documents whose postcode appears in the LLPG
+}
+
+// PostcodeCount is a postcode and how many documents carry it
+type PostcodeCount struct {
+	Postcode string
+	Count    int
+}
+
+// Run statuses (match_run.status)
+const (
+	RunRunning     = "running"
+	RunCompleted   = "completed"
+	RunInterrupted = "interrupted"
+	RunFailed      = "failed"
+)
+
+// RunTotals are the counts recorded when a run ends, partial for an interrupted run
+type RunTotals struct {
+	Processed   int
+	Accepted    int
+	NeedsReview int
+	Rejected    int
+}
+
+// MatchRun represents a matching run record
+type MatchRun struct {
+	RunID            int64      `json:"run_id"`
+	RunStartedAt     time.Time  `json:"run_started_at"`
+	RunCompletedAt   *time.Time `json:"run_completed_at,omitempty"`
+	RunLabel         string     `json:"run_label"`
+	AlgorithmVersion string     `json:"algorithm_version"`
+	Notes            string     `json:"notes"`
+	TotalProcessed   int        `json:"total_processed"`
+	AutoAccepted     int        `json:"auto_accepted"`
+	NeedsReview      int        `json:"needs_review"`
+	Rejected         int        `json:"rejected"`
+	Status           string     `json:"status"`
+}
+
+// Checkpoint is the persisted progress of a resumable run. Cursor-ordered processors
+// advance LastDocumentID; parallel ones record finished items with MarkProcessed.
+type Checkpoint struct {
+	RunID          int64
+	Processor      string
+	Config         json.RawMessage // the configuration the run was started with
+	LastDocumentID int64
+	Stats          json.RawMessage // statistics accumulated over every leg
+	Legs           int
+	Status         string // the run's match_run.status
+	UpdatedAt      time.Time
+}
+
+// reopenable reports why a run cannot be resumed, if it cannot
+func reopenable(runID int64, cp *Checkpoint) error {
+	if cp == nil {
+		return fmt.Errorf("run %d has no checkpoint; only runs started by a resumable processor can be resumed", runID)
+	}
+	if cp.Status == RunCompleted {
+		return fmt.Errorf("run %d already completed", runID)
+	}
+	return nil
+}
+
+// Work queue item kinds (work_queue_chunk.kind)
+const (
+	WorkDocuments = "documents" // document IDs
+	WorkAddresses = "addresses" // deduplicated raw addresses
+)
+
+// Work queue chunk statuses (work_queue_chunk.status)
+const (
+	ChunkPending = "pending"
+	ChunkClaimed = "claimed"
+	ChunkDone    = "done"
+	ChunkFailed  = "failed"
+)
+
+// ErrChunkLost is returned to a worker whose chunk was re-queued after its heartbeat went
+// stale; another worker may already hold it
+var ErrChunkLost = errors.New("chunk was re-queued by another worker")
+
+// WorkChunk is a claimed chunk of a queued run
+type WorkChunk struct {
+	ChunkID  int64
+	RunID    int64
+	Kind     string
+	Items    []string
+	WorkerID string
+	Attempts int // including this claim
+}
+
+// ChunkResult is what a worker records when it completes a chunk
+type ChunkResult struct {
+	Processed int
+	Matched   int
+	Errors    int
+}
+
+// QueueProgress counts a queued run's chunks by status and the items done so far
+type QueueProgress struct {
+	Pending   int
+	Claimed   int
+	Done      int
+	Failed    int
+	Items     int // items in every chunk
+	Processed int
+	Matched   int
+	Errors    int
+}
+
+// Drained reports whether no chunk is waiting or being worked on
+func (q QueueProgress) Drained() bool {
+	return q.Pending == 0 && q.Claimed == 0
+}
+
+// WorkerThroughput is one worker's completed chunks in a queued run. Busy is the time
+// between claiming and completing them.
+type WorkerThroughput struct {
+	WorkerID  string
+	Chunks    int
+	Processed int
+	Matched   int
+	Errors    int
+	Busy      time.Duration
+	Holding   int        // chunks the worker has claimed and not yet completed
+	LastSeen  *time.Time // latest heartbeat or completion
+}
+
+// ItemsPerSecond is the worker's throughput while busy
+func (w WorkerThroughput) ItemsPerSecond() float64 {
+	if w.Busy <= 0 {
+		return 0
+	}
+	return float64(w.Processed) / w.Busy.Seconds()
+}
+
+// Review item statuses (review_item.status)
+const (
+	ReviewOpen     = "open"
+	ReviewDeferred = "deferred"
+	ReviewSignoff  = "awaiting_signoff"
+	ReviewDone     = "done"
+)
+
+// Review actions (review_event.action). Accept, Confirm and Reject close an item; Propose
+// puts a low-confidence accept up for a second reviewer, who Confirms or Refuses it.
+const (
+	ReviewClaim   = "claim"
+	ReviewAccept  = "accept"
+	ReviewPropose = "propose"
+	ReviewConfirm = "confirm"
+	ReviewRefuse  = "refuse"
+	ReviewReject  = "reject"
+	ReviewSkip    = "skip"
+	ReviewDefer   = "defer"
+	ReviewRelease = "release"
+	ReviewExpire  = "expire"
+)
+
+// ErrLeaseLost is returned to a reviewer whose lease on an item has expired or was never
+// held; another reviewer may have claimed it since
+var ErrLeaseLost = errors.New("review lease is not held")
+
+// ReviewFilter selects which items ClaimReview may hand out
+type ReviewFilter struct {
+	SourceType    string  // empty for every source type
+	MinConfidence float64 // best candidate confidence, inclusive
+	MaxConfidence float64 // exclusive; 0 for no upper bound
+	SignOff       bool    // claim accepts awaiting sign-off instead of open items
+}
+
+// ReviewItem is a document in the review queue with its needs_review candidates
+type ReviewItem struct {
+	SrcID          int64             `json:"src_id"`
+	SourceType     string            `json:"source_type"`
+	RawAddress     string            `json:"raw_address"`
+	Confidence     float64           `json:"confidence"`
+	Status         string            `json:"status"`
+	ClaimedBy      string            `json:"claimed_by,omitempty"`
+	LeaseExpiresAt *time.Time        `json:"lease_expires_at,omitempty"`
+	DeferredUntil  *time.Time        `json:"deferred_until,omitempty"`
+	Proposal       *ReviewProposal   `json:"proposal,omitempty"`
+	Notes          string            `json:"notes,omitempty"`
+	Candidates     []ReviewCandidate `json:"candidates"`
+}
+
+// Candidate returns the item's candidate for uprn, if it has one
+func (i *ReviewItem) Candidate(uprn string) (ReviewCandidate, bool) {
+	for _, c := range i.Candidates {
+		if c.UPRN == uprn {
+			return c, true
+		}
+	}
+	return ReviewCandidate{}, false
+}
+
+// ReviewProposal is an accept awaiting a second reviewer's sign-off
+type ReviewProposal struct {
+	UPRN       string    `json:"uprn"`
+	Method     string    `json:"method"`
+	Score      float64   `json:"score"`
+	Confidence float64   `json:"confidence"`
+	ProposedBy string    `json:"proposed_by"`
+	ProposedAt time.Time `json:"proposed_at"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// ReviewCandidate is a needs_review match_result row
+type ReviewCandidate struct {
+	UPRN       string  `json:"uprn"`
+	Address    string  `json:"address"`
+	Method     string  `json:"method"`
+	Score      float64 `json:"score"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ReviewOutcome is what a reviewer does with a claimed item. Accept, Propose and Confirm
+// carry the UPRN in Proposal; Defer sets DeferUntil.
+type ReviewOutcome struct {
+	Action     string
+	Proposal   *ReviewProposal
+	DeferUntil time.Time
+	Notes      string
+}
+
+// ReviewQueueCounts counts review items by state. Claimed items are counted once, under
+// Claimed, whatever their status.
+type ReviewQueueCounts struct {
+	Open            int `json:"open"`
+	Claimed         int `json:"claimed"`
+	Deferred        int `json:"deferred"`
+	AwaitingSignoff int `json:"awaiting_signoff"`
+	Done            int `json:"done"`
+}
+
+// ReviewerStats is one reviewer's activity. Held is the time between claiming items and
+// acting on them.
+type ReviewerStats struct {
+	Reviewer   string         `json:"reviewer"`
+	Actions    map[string]int `json:"actions"`
+	Held       time.Duration  `json:"-"`
+	Holding    int            `json:"holding"` // items the reviewer holds a live lease on
+	LastActive *time.Time     `json:"last_active,omitempty"`
+}
+
+// Decisions counts the actions that settled or advanced an item
+func (s ReviewerStats) Decisions() int {
+	return s.Actions[ReviewAccept] + s.Actions[ReviewPropose] + s.Actions[ReviewConfirm] +
+		s.Actions[ReviewRefuse] + s.Actions[ReviewReject]
+}
+
+// PerHour is the reviewer's decisions per hour of holding items
+func (s ReviewerStats) PerHour() float64 {
+	if s.Held <= 0 {
+		return 0
+	}
+	return float64(s.Decisions()) / s.Held.Hours()
+}
+
+// sortReviewerStats orders reviewers by decisions, most first
+func sortReviewerStats(byReviewer map[string]*ReviewerStats) []ReviewerStats {
+	stats := make([]ReviewerStats, 0, len(byReviewer))
+	for _, s := range byReviewer {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Decisions() != stats[j].Decisions() {
+			return stats[i].Decisions() > stats[j].Decisions()
+		}
+		return stats[i].Reviewer < stats[j].Reviewer
+	})
+	return stats
+}
+
+// validate checks the outcome carries what its action needs
+func (o ReviewOutcome) validate() error {
+	switch o.Action {
+	case ReviewAccept, ReviewPropose, ReviewConfirm:
+		if o.Proposal == nil || o.Proposal.UPRN == "" {
+			return fmt.Errorf("%s needs a UPRN", o.Action)
+		}
+	case ReviewDefer:
+		if o.DeferUntil.IsZero() {
+			return fmt.Errorf("defer needs a time to defer until")
+		}
+	case ReviewRefuse, ReviewReject, ReviewSkip, ReviewRelease:
+	default:
+		return fmt.Errorf("unknown review action %q", o.Action)
+	}
+	return nil
+}
+
+// reviewStatusAfter is an item's status once action is recorded against it
+func reviewStatusAfter(action, current string) string {
+	switch action {
+	case ReviewAccept, ReviewConfirm, ReviewReject:
+		return ReviewDone
+	case ReviewPropose:
+		return ReviewSignoff
+	case ReviewRefuse:
+		return ReviewOpen
+	case ReviewDefer:
+		return ReviewDeferred
+	}
+	return current
+}
+
+// MatchResult represents a candidate match result
+type MatchResult struct {
+	MatchID       int64                  `json:"match_id"`
+	RunID         int64                  `json:"run_id"`
+	SrcID         int64                  `json:"src_id"`
+	CandidateUPRN string                 `json:"candidate_uprn"`
+	Method        string                 `json:"method"`
+	Score         float64                `json:"score"`
+	Confidence    float64                `json:"confidence"`
+	TieRank       int                    `json:"tie_rank"`
+	Features      map[string]interface{} `json:"features"`
+	Decided       bool                   `json:"decided"`
+	Decision      string                 `json:"decision"`
+	DecidedBy     string                 `json:"decided_by"`
+	DecidedAt     *time.Time             `json:"decided_at,omitempty"`
+	Notes         string                 `json:"notes"`
+}
+
+// Acceptance is a match_accepted row: the UPRN a document is matched to
+type Acceptance struct {
+	SrcID      int64
+	UPRN       string
+	Method     string
+	Score      float64
+	Confidence float64
+	RunID      int64
+	AcceptedBy string
+	AcceptedAt time.Time
+	Manual     bool // a reviewer's accept, which lifts any rejection of the pair
+}
+
+// Rejection is an unlifted match_rejection row: a reviewer ruled the UPRN out for the
+// document it was made on and for every document with the same canonical address
+type Rejection struct {
+	RejectionID      int64
+	SrcID            int64
+	UPRN             string
+	AddressCanonical string
+	Source           string
+	Reason           string
+	RejectedBy       string
+	RejectedAt       time.Time
+}
+
+// AddressMatch is an address_match row written by the component engine
+type AddressMatch struct {
+	DocumentID int64
+	AddressID  int
+	LocationID int
+	MethodID   int
+	Score      float64
+	Status     string
+	MatchedBy  string
+}
+
+// Explanation is a match_explanation row: why a document was decided as it was in a run.
+// Thresholds and Candidates are stored as JSON; Candidates holds the top candidates in the
+// shape of match.CandidateExplanation.
+type Explanation struct {
+	RunID        int64
+	SrcID        int64
+	Decision     string
+	AcceptedUPRN string   // empty unless a candidate was accepted
+	Tier         string   // empty when the engine has no threshold tiers
+	Margin       *float64 // nil when there were no candidates
+	Reason       string
+	Thresholds   interface{}
+	Candidates   interface{}
+	CreatedAt    time.Time
+}
+
+// DecisionRecord is one audited matching decision. Alternatives are the other candidates
+// that were considered; Candidates is stored as JSON for explanation.
+type DecisionRecord struct {
+	SrcID          int64
+	UPRN           string
+	Decision       string
+	Method         string
+	Score          float64
+	Features       map[string]interface{}
+	DecidedBy      string
+	DecidedAt      time.Time
+	RunID          int64
+	Alternatives   []Alternative
+	Candidates     interface{}
+	Explanation    map[string]interface{}
+	ProcessingTime time.Duration
+
+	// Explained is written to match_explanation with the decision when the decision
+	// has a run
+	Explained *Explanation
+}
+
+// Alternative is a candidate that was not selected
+type Alternative struct {
+	UPRN    string
+	Method  string
+	Score   float64
+	TieRank int
+}
+
+// OverrideRecord is a reviewer's manual decision
+type OverrideRecord struct {
+	SrcID      int64
+	UPRN       string // empty when the reviewer only recorded a reason
+	Reason     string
+	ReviewerID string
+	CreatedAt  time.Time
+}
+
+// DecisionHistoryEntry is a match_result row with its audit detail
+type DecisionHistoryEntry struct {
+	MatchID          int64                  `json:"match_id"`
+	RunID            int64                  `json:"run_id"`
+	RunLabel         string                 `json:"run_label"`
+	CandidateUPRN    string                 `json:"candidate_uprn"`
+	CandidateAddress string                 `json:"candidate_address"`
+	Method           string                 `json:"method"`
+	Score            float64                `json:"score"`
+	TieRank          int                    `json:"tie_rank"`
+	Decided          bool                   `json:"decided"`
+	Decision         string                 `json:"decision"`
+	DecidedBy        string                 `json:"decided_by"`
+	DecidedAt        time.Time              `json:"decided_at"`
+	Features         map[string]interface{} `json:"features"`
+	Explanation      map[string]interface{} `json:"explanation"`
+	ProcessingTime   time.Duration          `json:"processing_time"`
+}
+
+// OverrideHistoryEntry is a match_override row
+type OverrideHistoryEntry struct {
+	OverrideID  int64     `json:"override_id"`
+	UPRN        string    `json:"uprn"`
+	UPRNAddress string    `json:"uprn_address"`
+	Reason      string    `json:"reason"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MatchingStats summarises the rank-1 results of a run
+type MatchingStats struct {
+	RunID             int64                    `json:"run_id"`
+	RunLabel          string                   `json:"run_label"`
+	RunStartedAt      time.Time                `json:"run_started_at"`
+	Notes             string                   `json:"notes"`
+	TotalProcessed    int64                    `json:"total_processed"`
+	DecisionBreakdown map[string]DecisionStats `json:"decision_breakdown"`
+	MethodBreakdown   map[string]MethodStats   `json:"method_breakdown"`
+}
+
+type DecisionStats struct {
+	Decision string `json:"decision"`
+	Count    int64  `json:"count"`
+}
+
+type MethodStats struct {
+	Method   string  `json:"method"`
+	Count    int64   `json:"count"`
+	AvgScore float64 `json:"avg_score"`
+	MinScore float64 `json:"min_score"`
+	MaxScore float64 `json:"max_score"`
+}
+
+// Record match statuses (v_enhanced_source_documents.match_status)
+const (
+	RecordMatched     = "MATCHED"
+	RecordUnmatched   = "UNMATCHED"
+	RecordNeedsReview = "NEEDS_REVIEW"
+)
+
+// Record is a source document as the web UI shows it, with its accepted match
+// (v_enhanced_source_documents)
+type Record struct {
+	SrcID              int       `json:"src_id"`
+	SourceType         string    `json:"source_type"`
+	Filepath           string    `json:"filepath"`
+	ExternalRef        *string   `json:"external_ref"`
+	DocType            *string   `json:"doc_type"`
+	DocDate            *string   `json:"doc_date"`
+	OriginalAddress    string    `json:"original_address"`
+	CanonicalAddress   *string   `json:"canonical_address"`
+	ExtractedPostcode  *string   `json:"extracted_postcode"`
+	SourceUPRN         *string   `json:"source_uprn"`
+	SourceEasting      *float64  `json:"source_easting"`
+	SourceNorthing     *float64  `json:"source_northing"`
+	AddressQuality     string    `json:"address_quality"`
+	MatchStatus        string    `json:"match_status"`
+	MatchMethod        *string   `json:"match_method"`
+	MatchScore         *float64  `json:"match_score"`
+	CoordinateDistance *float64  `json:"coordinate_distance"`
+	AddressSimilarity  *float64  `json:"address_similarity"`
+	MatchedUPRN        *string   `json:"matched_uprn"`
+	LLPGAddress        *string   `json:"llpg_address"`
+	LLPGEasting        *float64  `json:"llpg_easting"`
+	LLPGNorthing       *float64  `json:"llpg_northing"`
+	USRN               *string   `json:"usrn"`
+	ImportDate         time.Time `json:"import_date"`
+}
+
+// RecordFilter narrows record listings, searches and map features; empty fields match
+// every record
+type RecordFilter struct {
+	SourceType     string
+	MatchStatus    string
+	AddressQuality string
+	AddressSearch  string   // part of the address, in any case
+	MinScore       *float64 // map features only
+	MaxScore       *float64
+}
+
+// RecordSearchResult is a record found by SearchRecords
+type RecordSearchResult struct {
+	SrcID          int      `json:"src_id"`
+	SourceType     string   `json:"source_type"`
+	Address        string   `json:"address"`
+	MatchStatus    string   `json:"match_status"`
+	AddressQuality string   `json:"address_quality"`
+	MatchScore     *float64 `json:"match_score"`
+	ExternalRef    *string  `json:"external_ref"`
+}
+
+// AddressListing is an LLPG address as the web UI lists it
+type AddressListing struct {
+	UPRN             string  `json:"uprn"`
+	Address          string  `json:"address"`
+	CanonicalAddress string  `json:"canonical_address"`
+	Easting          float64 `json:"easting"`
+	Northing         float64 `json:"northing"`
+	USRN             *string `json:"usrn"`
+	BLPUClass        *string `json:"blpu_class"`
+	PostalFlag       *string `json:"postal_flag"`
+	Status           string  `json:"status"`
+}
+
+// CategoryCount is how many records fall in a category and how many of them are matched
+type CategoryCount struct {
+	Total   int
+	Matched int
+}
+
+// RecordStats summarises the live records by match status, source type, method and quality
+type RecordStats struct {
+	Total            int
+	Matched          int
+	Unmatched        int
+	NeedsReview      int
+	BySourceType     map[string]CategoryCount
+	ByMatchMethod    map[string]int // matched records only
+	ByAddressQuality map[string]CategoryCount
+}
+
+// BNGBox is an easting and northing envelope
+type BNGBox struct {
+	MinE, MinN, MaxE, MaxN float64
+}
+
+// ViewportCount is the number of mapped records with one source type, status and quality
+type ViewportCount struct {
+	SourceType     string
+	MatchStatus    string
+	AddressQuality string
+	Count          int
+}
+
+// MapFeature is a record's GeoJSON feature. Features found in a box have no geometry; the
+// caller places them at Easting and Northing.
+type MapFeature struct {
+	Feature  json.RawMessage
+	Easting  *float64
+	Northing *float64
+}
+
+// ResultSummary is one of a document's candidates from its latest run
+type ResultSummary struct {
+	RunID     int64
+	UPRN      string
+	Address   string
+	Method    string
+	Score     float64
+	Decision  string
+	DecidedAt *time.Time
+}
+
+// ManualDecision is an accept or reject entered on a record outside the review queue
+type ManualDecision struct {
+	SrcID      int64
+	UPRN       string // the accepted UPRN; unused for a reject
+	Method     string
+	Score      float64
+	Confidence float64
+	Reason     string
+	DecidedBy  string
+	ClientInfo string // JSON describing the caller, for the audit row
+}
+
+// CoordinateChange sets a record's source coordinates by hand
+type CoordinateChange struct {
+	SrcID      int64
+	Easting    float64
+	Northing   float64
+	Source     string
+	Reason     string
+	ChangedBy  string
+	ClientInfo string
+}
+
+// RecordEvent is an entry in a record's audit history (v_record_audit_history)
+type RecordEvent struct {
+	EventType      string    `json:"event_type"`
+	Action         string    `json:"action"`
+	Details        *string   `json:"details"`
+	UserName       string    `json:"user_name"`
+	EventTimestamp time.Time `json:"event_timestamp"`
+	Notes          *string   `json:"notes"`
+}
+
+// RecordNote is a note entered on a record
+type RecordNote struct {
+	ID        int
+	SrcID     int64
+	Type      string
+	Text      string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// AddressStore reads the LLPG. Every store method takes a context that cancels its queries;
+// the Postgres implementation also bounds each call by DB_QUERY_TIMEOUT.
+type AddressStore interface {
+	// SimilarAddresses returns addresses whose canonical form has trigram similarity of at
+	// least minSimilarity to canonical, best first
+	SimilarAddresses(ctx context.Context, canonical string, minSimilarity float64, limit int) ([]ScoredAddress, error)
+
+	// AddressesInPostcode returns addresses whose full address ends with the postcode
+	AddressesInPostcode(ctx context.Context, postcode string) ([]Address, error)
+
+	// AddressesByComponents returns addresses equal to every non-empty component in q
+	AddressesByComponents(ctx context.Context, q Components, limit int) ([]Address, error)
+
+	// AddressesByRoad returns addresses whose road is similar to road (and in city when
+	// given), with the road similarity, best first
+	AddressesByRoad(ctx context.Context, road, city string, minSimilarity float64, limit int) ([]ScoredAddress, error)
+
+	// Similarity is the trigram similarity of two strings
+	Similarity(ctx context.Context, a, b string) (float64, error)
+}
+
+// DocumentStore reads source documents
+type DocumentStore interface {
+	// UnmatchedDocuments pages through documents without an accepted match, by src_id
+	UnmatchedDocuments(ctx context.Context, afterSrcID int64, limit int, sourceType string) ([]SourceDocument, error)
+
+	// UnmatchedWithPostcode pages through unmatched documents that have a postcode and address
+	UnmatchedWithPostcode(ctx context.Context, afterSrcID int64, limit int) ([]SourceDocument, error)
+
+	PostcodeQuality(ctx context.Context) (*PostcodeQuality, error)
+
+	// DocumentComponents returns a document's stored components; ok is false until they
+	// have been saved
+	DocumentComponents(ctx context.Context, documentID int64) (c Components, ok bool, err error)
+	SaveDocumentComponents(ctx context.Context, documentID int64, c Components) error
+}
+
+// MatchStore records runs, candidate results and accepted matches
+type MatchStore interface {
+	CreateRun(ctx context.Context, label, algorithmVersion, notes string) (*MatchRun, error)
+
+	// CompleteRun records the run's final status (RunCompleted, RunInterrupted or RunFailed)
+	// and the totals it reached
+	CompleteRun(ctx context.Context, runID int64, status string, totals RunTotals) error
+
+	// JoinRun records this process as working on an existing run, e.g. a queue worker, so
+	// its writes are attributed to the run; creating or reopening a run joins it
+	JoinRun(ctx context.Context, runID int64) error
+
+	SaveResult(ctx context.Context, result *MatchResult) error
+	Accept(ctx context.Context, a Acceptance) error
+	Accepted(ctx context.Context, srcID int64) (*Acceptance, error) // nil when the document is unmatched
+	SaveAddressMatch(ctx context.Context, m AddressMatch) error
+
+	// Rejections returns the unlifted rejections made on the document or on any document
+	// with its canonical address (when not empty), oldest first
+	Rejections(ctx context.Context, srcID int64, canonical string) ([]Rejection, error)
+
+	// SaveExplanation records why a document was decided as it was in a run;
+	// LatestExplanation returns the newest one outside rolled-back runs, nil when none
+	SaveExplanation(ctx context.Context, e Explanation) error
+	LatestExplanation(ctx context.Context, srcID int64) (*Explanation, error)
+}
+
+// AuditStore keeps the decision and override trail
+type AuditStore interface {
+	// RecordDecision stores the decision, its alternatives, the accept (for "accepted")
+	// and the detailed audit atomically, returning the decision's match_id
+	RecordDecision(ctx context.Context, d DecisionRecord) (int64, error)
+	RecordOverride(ctx context.Context, o OverrideRecord) error
+	DecisionHistory(ctx context.Context, srcID int64) ([]DecisionHistoryEntry, error)
+	OverrideHistory(ctx context.Context, srcID int64) ([]OverrideHistoryEntry, error)
+	RunStatistics(ctx context.Context, runID int64) (*MatchingStats, error)
+}
+
+// CheckpointStore persists run progress so a stopped run can continue where it left off
+type CheckpointStore interface {
+	// CreateCheckpoint records the processor and configuration a run was started with
+	CreateCheckpoint(ctx context.Context, runID int64, processor string, config json.RawMessage) error
+
+	// Checkpoint returns a run's checkpoint; nil when the run has none
+	Checkpoint(ctx context.Context, runID int64) (*Checkpoint, error)
+
+	// SaveCheckpoint advances the cursor and replaces the accumulated statistics
+	SaveCheckpoint(ctx context.Context, runID, lastDocumentID int64, stats json.RawMessage) error
+
+	// ReopenRun marks a stopped run running again and counts a new leg. Completed runs
+	// and runs without a checkpoint cannot be reopened.
+	ReopenRun(ctx context.Context, runID int64) (*Checkpoint, error)
+
+	// MarkProcessed adds items to a run's processed set; ProcessedItems returns the set
+	MarkProcessed(ctx context.Context, runID int64, items []string) error
+	ProcessedItems(ctx context.Context, runID int64) (map[string]bool, error)
+}
+
+// QueueStore is the work queue shared by matcher worker processes. A chunk is claimed by
+// one worker at a time; a worker that stops heartbeating loses it to RequeueAbandoned.
+type QueueStore interface {
+	// Enqueue splits items into chunks of chunkSize, returning the number of chunks
+	Enqueue(ctx context.Context, runID int64, kind string, items []string, chunkSize int) (int, error)
+
+	// ClaimChunk claims the run's lowest pending chunk for workerID, skipping chunks other
+	// workers are claiming; nil when none is pending
+	ClaimChunk(ctx context.Context, runID int64, workerID string) (*WorkChunk, error)
+
+	// Heartbeat records that workerID is still working on the chunk. It returns
+	// ErrChunkLost once the chunk has been re-queued.
+	Heartbeat(ctx context.Context, chunkID int64, workerID string) error
+
+	// CompleteChunk marks the chunk done with its result, or returns ErrChunkLost
+	CompleteChunk(ctx context.Context, chunkID int64, workerID string, r ChunkResult) error
+
+	// ReleaseChunk gives up a claimed chunk: back to pending, or failed once it has been
+	// claimed maxAttempts times. 0 is for interrupted workers: the chunk goes back to pending
+	// and its claim is taken off its attempts.
+	ReleaseChunk(ctx context.Context, chunkID int64, workerID, reason string, maxAttempts int) error
+
+	// RequeueAbandoned releases claimed chunks whose last heartbeat is older than
+	// staleAfter, returning how many were released
+	RequeueAbandoned(ctx context.Context, runID int64, staleAfter time.Duration, maxAttempts int) (int, error)
+
+	QueueProgress(ctx context.Context, runID int64) (*QueueProgress, error)
+
+	// WorkerThroughput summarises each worker's chunks, busiest first
+	WorkerThroughput(ctx context.Context, runID int64) ([]WorkerThroughput, error)
+}
+
+// ReviewStore is the shared manual review queue. An item is leased to one reviewer at a
+// time; a lease that is not renewed expires and the item can be claimed again.
+type ReviewStore interface {
+	// SyncReviewQueue adds documents with needs_review candidates and no accept, and closes
+	// unclaimed items whose document has since been accepted elsewhere
+	SyncReviewQueue(ctx context.Context) (added, closed int, err error)
+
+	// ClaimReview leases the next item matching f to reviewer, or returns the item the
+	// reviewer already holds; nil when none is available
+	ClaimReview(ctx context.Context, reviewer string, f ReviewFilter, lease time.Duration) (*ReviewItem, error)
+
+	// ReviewItem reads an item with its candidates; nil when the document is not queued
+	ReviewItem(ctx context.Context, srcID int64) (*ReviewItem, error)
+
+	// RenewReview extends the reviewer's lease, or returns ErrLeaseLost
+	RenewReview(ctx context.Context, srcID int64, reviewer string, lease time.Duration) error
+
+	// FinishReview records the outcome and ends the reviewer's lease. Accept and Confirm
+	// write match_accepted and Reject settles the candidates, in the same transaction.
+	// It returns ErrLeaseLost unless the reviewer holds a live lease.
+	FinishReview(ctx context.Context, srcID int64, reviewer string, o ReviewOutcome) error
+
+	ReviewQueueCounts(ctx context.Context) (*ReviewQueueCounts, error)
+
+	// ReviewerStats summarises each reviewer's actions since the given time, most
+	// decisions first
+	ReviewerStats(ctx context.Context, since time.Time) ([]ReviewerStats, error)
+}
+
+// RecordStore serves the web UI: records with their matches, statistics, map features and
+// searches, and the accepts, rejects, coordinates and notes entered on records by hand.
+// Listings, counts and searches leave out documents removed from their source file.
+type RecordStore interface {
+	// Records returns a page of the records matching f in src_id order, and how many match
+	Records(ctx context.Context, f RecordFilter, limit, offset int) ([]Record, int, error)
+
+	// Record reads one record, removed or not; nil when there is none
+	Record(ctx context.Context, srcID int64) (*Record, error)
+
+	// SearchRecords finds records whose address or external reference contains term,
+	// reference matches first
+	SearchRecords(ctx context.Context, term string, f RecordFilter, limit int) ([]RecordSearchResult, error)
+
+	// SearchAddresses finds LLPG addresses containing term or similar to it
+	SearchAddresses(ctx context.Context, term string, limit int) ([]AddressListing, error)
+
+	// RecordCandidates returns LLPG addresses containing the record's address
+	RecordCandidates(ctx context.Context, srcID int64, limit int) ([]AddressListing, error)
+
+	// AddressExists reports whether the UPRN is in the LLPG
+	AddressExists(ctx context.Context, uprn string) (bool, error)
+
+	// BestResult returns the matcher's most confident result for the pair; nil when no
+	// run proposed the UPRN for the document
+	BestResult(ctx context.Context, srcID int64, uprn string) (*MatchResult, error)
+
+	// LatestResults returns the document's candidates from its latest run by tie rank
+	LatestResults(ctx context.Context, srcID int64, limit int) ([]ResultSummary, error)
+
+	RecordStats(ctx context.Context) (*RecordStats, error)
+
+	// ViewportCounts counts mapped records inside box, or every mapped record when box is nil
+	ViewportCounts(ctx context.Context, box *BNGBox) ([]ViewportCount, error)
+
+	// MapFeatures returns features for the mapped records matching f in src_id order,
+	// inside box when it is not nil
+	MapFeatures(ctx context.Context, f RecordFilter, box *BNGBox, limit int) ([]MapFeature, error)
+
+	// RecentAccepts counts the accepts made within the last period
+	RecentAccepts(ctx context.Context, within time.Duration) (int, error)
+
+	// ManualAccept audits the decision and accepts the UPRN as a manual accept, atomically
+	ManualAccept(ctx context.Context, d ManualDecision) error
+
+	// ManualReject audits the decision, rejects the accepted UPRN for the record and its
+	// canonical address, and removes the accept, atomically
+	ManualReject(ctx context.Context, d ManualDecision) error
+
+	// SetCoordinates audits and applies the change atomically
+	SetCoordinates(ctx context.Context, c CoordinateChange) error
+
+	// RecordHistory returns the record's audit events newest first, only those of
+	// eventType when it is not empty
+	RecordHistory(ctx context.Context, srcID int64, eventType string, limit int) ([]RecordEvent, error)
+
+	// AddNote stores the note, setting its ID and CreatedAt
+	AddNote(ctx context.Context, n *RecordNote) error
+}
+
+// Stores bundles the repositories engines and web handlers are built from
+type Stores struct {
+	Addresses   AddressStore
+	Documents   DocumentStore
+	Matches     MatchStore
+	Audit       AuditStore
+	Checkpoints CheckpointStore
+	Queue       QueueStore
+	Review      ReviewStore
+	Records     RecordStore
+}
+
+// NewPostgresStores returns Stores backed by db
+func NewPostgresStores(db *sql.DB) Stores {
+	p := NewPostgres(db)
+	return Stores{Addresses: p, Documents: p, Matches: p, Audit: p, Checkpoints: p, Queue: p, Review: p, Records: p}
+}
+
+// Stores returns Stores backed by the in-memory data
+func (m *Memory) Stores() Stores {
+	return Stores{Addresses: m, Documents: m, Matches: m, Audit: m, Checkpoints: m, Queue: m, Review: m, Records: m}
+}
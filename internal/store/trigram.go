@@ -0,0 +1,41 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+)
+
+// trgmThreshold is pg_trgm's default similarity_threshold, applied by the % operator
+const trgmThreshold = 0.3
+
+// TrigramSimilarity computes pg_trgm's similarity(): each alphanumeric word is lower-cased
+// and padded with two leading spaces and one trailing space, and the score is shared
+// trigrams over all distinct trigrams.
+func TrigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		padded := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
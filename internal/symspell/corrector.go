@@ -29,14 +29,14 @@ func GetCorrector() *Corrector {
 
 // IsEnabled returns true if SymSpell correction is enabled and initialized.
 func IsEnabled() bool {
-	config := LoadConfigFromEnv()
+	config := LoadConfig()
 	return config.Enabled && globalCorrector != nil
 }
 
 // InitGlobalCorrector initializes the global corrector from database.
 // Should be called once at application startup if SymSpell is enabled.
 func InitGlobalCorrector(db *sql.DB) error {
-	config := LoadConfigFromEnv()
+	config := LoadConfig()
 	if !config.Enabled {
 		return nil
 	}
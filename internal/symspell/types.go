@@ -5,10 +5,7 @@
 // performance, making it suitable for high-volume address correction.
 package symspell
 
-import (
-	"os"
-	"strconv"
-)
+import "github.com/ehdc-llpg/internal/config"
 
 // Config holds SymSpell configuration parameters.
 // Default values are from Appendix I of the thesis.
@@ -45,31 +42,15 @@ func DefaultConfig() *Config {
 	}
 }
 
-// LoadConfigFromEnv loads configuration from environment variables.
-func LoadConfigFromEnv() *Config {
-	cfg := DefaultConfig()
-
-	if v := os.Getenv("SYMSPELL_ENABLED"); v != "" {
-		cfg.Enabled = v == "true" || v == "1"
-	}
-
-	if v := os.Getenv("SYMSPELL_MAX_EDIT_DISTANCE"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 3 {
-			cfg.MaxEditDistance = n
-		}
-	}
+// LoadConfig reads the SYMSPELL_* settings from the shared configuration.
+func LoadConfig() *Config {
+	settings := config.Current().SymSpell
 
-	if v := os.Getenv("SYMSPELL_PREFIX_LENGTH"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.PrefixLength = n
-		}
-	}
-
-	if v := os.Getenv("SYMSPELL_MIN_TERM_LENGTH"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.MinTermLength = n
-		}
-	}
+	cfg := DefaultConfig()
+	cfg.Enabled = settings.Enabled
+	cfg.MaxEditDistance = settings.MaxEditDistance
+	cfg.PrefixLength = settings.PrefixLength
+	cfg.MinTermLength = settings.MinTermLength
 
 	return cfg
 }
@@ -1,16 +1,19 @@
 package web
 
 import (
-	"encoding/json"
-	"os"
+	"time"
+
+	"github.com/ehdc-llpg/internal/config"
 )
 
 // Config represents the web server configuration
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Auth     AuthConfig     `json:"auth"`
-	Features FeatureConfig  `json:"features"`
+	Server   ServerConfig    `json:"server"`
+	Database config.Database `json:"-"`
+	Auth     AuthConfig      `json:"auth"`
+	Features FeatureConfig   `json:"features"`
+	Export   ExportConfig    `json:"export"`
+	Review   ReviewConfig    `json:"review"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -19,16 +22,11 @@ type ServerConfig struct {
 	Host string `json:"host"`
 }
 
-// DatabaseConfig contains database connection settings
-type DatabaseConfig struct {
-	URL            string `json:"url"`
-	MaxConnections int    `json:"max_connections"`
-}
-
 // AuthConfig contains authentication settings
 type AuthConfig struct {
-	Enabled    bool   `json:"enabled"`
-	SessionKey string `json:"session_key"`
+	Enabled    bool          `json:"enabled"`
+	SessionKey string        `json:"-"`
+	SessionTTL time.Duration `json:"session_ttl"`
 }
 
 // FeatureConfig contains feature toggles
@@ -37,39 +35,44 @@ type FeatureConfig struct {
 	ManualOverrideEnabled bool `json:"manual_override_enabled"`
 }
 
-// LoadConfig loads configuration from a JSON file
-func LoadConfig(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, err
-	}
+// ExportConfig contains export job settings
+type ExportConfig struct {
+	Dir     string        `json:"dir"`
+	TTL     time.Duration `json:"ttl"`
+	Workers int           `json:"workers"`
+}
 
-	return &config, nil
+// ReviewConfig contains review queue settings
+type ReviewConfig struct {
+	Lease        time.Duration `json:"lease"`
+	SignoffBelow float64       `json:"signoff_below"`
 }
 
-// DefaultConfig returns a default configuration
-func DefaultConfig() *Config {
+// NewConfig builds the web server configuration from the shared configuration
+func NewConfig(cfg *config.Config) *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: 8080,
-			Host: "0.0.0.0",
-		},
-		Database: DatabaseConfig{
-			URL:            "", // Must be set via environment variables
-			MaxConnections: 25,
+			Port: cfg.Web.Port,
+			Host: cfg.Web.Host,
 		},
+		Database: cfg.Database,
 		Auth: AuthConfig{
-			Enabled:    false,
-			SessionKey: "development-session-key",
+			Enabled:    cfg.Web.Auth,
+			SessionKey: cfg.Web.SessionKey,
+			SessionTTL: cfg.Web.SessionTTL,
 		},
 		Features: FeatureConfig{
-			ExportEnabled:         true,
-			ManualOverrideEnabled: true,
+			ExportEnabled:         cfg.Web.ExportEnabled,
+			ManualOverrideEnabled: cfg.Web.ManualOverrideEnabled,
+		},
+		Export: ExportConfig{
+			Dir:     cfg.Web.ExportDir,
+			TTL:     cfg.Web.ExportTTL,
+			Workers: cfg.Web.ExportWorkers,
+		},
+		Review: ReviewConfig{
+			Lease:        cfg.Web.ReviewLease,
+			SignoffBelow: cfg.Web.SignoffBelow,
 		},
 	}
-}
\ No newline at end of file
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
+
+	"github.com/ehdc-llpg/internal/geo"
+	"github.com/ehdc-llpg/internal/store"
 )
 
 // Config represents the web server configuration (simplified)
@@ -13,11 +16,22 @@ type Config struct {
 		ExportEnabled         bool `json:"export_enabled"`
 		ManualOverrideEnabled bool `json:"manual_override_enabled"`
 	} `json:"features"`
+
+	// Geo converts between BNG and WGS84 for maps, viewports and exports; nil means Helmert
+	Geo *geo.Transformer `json:"-"`
+}
+
+// transformer returns the configured coordinate transformer
+func (c *Config) transformer() *geo.Transformer {
+	if c == nil || c.Geo == nil {
+		return geo.NewHelmertTransformer()
+	}
+	return c.Geo
 }
 
 // APIHandler handles general API endpoints
 type APIHandler struct {
-	DB     *sql.DB
+	Stores store.Stores
 	Config *Config
 }
 
@@ -49,134 +63,50 @@ type ViewportStatsResponse struct {
 
 // GetStats returns overall system statistics
 func (h *APIHandler) GetStats(w http.ResponseWriter, r *http.Request) {
-	// Query overall statistics from database views
-	var stats StatsResponse
-	
-	// Get total counts by match status
-	query := `
-		SELECT 
-			COUNT(*) as total,
-			COUNT(CASE WHEN match_status = 'MATCHED' THEN 1 END) as matched,
-			COUNT(CASE WHEN match_status = 'UNMATCHED' THEN 1 END) as unmatched,
-			COUNT(CASE WHEN match_status = 'NEEDS_REVIEW' THEN 1 END) as needs_review
-		FROM v_enhanced_source_documents
-	`
-	
-	err := h.DB.QueryRow(query).Scan(
-		&stats.TotalRecords,
-		&stats.MatchedRecords,
-		&stats.UnmatchedRecords,
-		&stats.NeedsReview,
-	)
+	counts, err := h.Stores.Records.RecordStats(r.Context())
 	if err != nil {
+		log.Printf("Failed to read record statistics: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	// Calculate match rate
-	if stats.TotalRecords > 0 {
-		stats.MatchRate = float64(stats.MatchedRecords) / float64(stats.TotalRecords) * 100
-	}
-
-	// Get statistics by source type
-	stats.BySourceType = make(map[string]Stats)
-	sourceQuery := `
-		SELECT 
-			source_type,
-			COUNT(*) as total,
-			COUNT(CASE WHEN match_status = 'MATCHED' THEN 1 END) as matched
-		FROM v_enhanced_source_documents
-		GROUP BY source_type
-	`
-	
-	rows, err := h.DB.Query(sourceQuery)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
+	stats := StatsResponse{
+		TotalRecords:     counts.Total,
+		MatchedRecords:   counts.Matched,
+		UnmatchedRecords: counts.Unmatched,
+		NeedsReview:      counts.NeedsReview,
+		MatchRate:        matchRate(store.CategoryCount{Total: counts.Total, Matched: counts.Matched}),
+		BySourceType:     make(map[string]Stats),
+		ByMatchMethod:    make(map[string]Stats),
+		ByAddressQuality: make(map[string]Stats),
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var sourceType string
-		var total, matched int
-		if err := rows.Scan(&sourceType, &total, &matched); err != nil {
-			continue
-		}
-		
-		matchRate := float64(0)
-		if total > 0 {
-			matchRate = float64(matched) / float64(total) * 100
-		}
-		
-		stats.BySourceType[sourceType] = Stats{
-			Count:     total,
-			MatchRate: matchRate,
-		}
+	for sourceType, c := range counts.BySourceType {
+		stats.BySourceType[sourceType] = Stats{Count: c.Total, MatchRate: matchRate(c)}
 	}
-
-	// Get statistics by match method (for matched records only)
-	stats.ByMatchMethod = make(map[string]Stats)
-	methodQuery := `
-		SELECT 
-			COALESCE(match_method, 'unknown') as method,
-			COUNT(*) as count
-		FROM v_enhanced_source_documents
-		WHERE match_status = 'MATCHED'
-		GROUP BY match_method
-	`
-	
-	rows, err = h.DB.Query(methodQuery)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var method string
-			var count int
-			if err := rows.Scan(&method, &count); err != nil {
-				continue
-			}
-			stats.ByMatchMethod[method] = Stats{Count: count, MatchRate: 100}
-		}
+	// Statistics by match method cover matched records only
+	for method, count := range counts.ByMatchMethod {
+		stats.ByMatchMethod[method] = Stats{Count: count, MatchRate: 100}
 	}
-
-	// Get statistics by address quality
-	stats.ByAddressQuality = make(map[string]Stats)
-	qualityQuery := `
-		SELECT 
-			address_quality,
-			COUNT(*) as total,
-			COUNT(CASE WHEN match_status = 'MATCHED' THEN 1 END) as matched
-		FROM v_enhanced_source_documents
-		GROUP BY address_quality
-	`
-	
-	rows, err = h.DB.Query(qualityQuery)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var quality string
-			var total, matched int
-			if err := rows.Scan(&quality, &total, &matched); err != nil {
-				continue
-			}
-			
-			matchRate := float64(0)
-			if total > 0 {
-				matchRate = float64(matched) / float64(total) * 100
-			}
-			
-			stats.ByAddressQuality[quality] = Stats{
-				Count:     total,
-				MatchRate: matchRate,
-			}
-		}
+	for quality, c := range counts.ByAddressQuality {
+		stats.ByAddressQuality[quality] = Stats{Count: c.Total, MatchRate: matchRate(c)}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
 
+// matchRate is the percentage of the category's records that are matched
+func matchRate(c store.CategoryCount) float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Matched) / float64(c.Total) * 100
+}
+
 // GetViewportStats returns statistics for records within a map viewport
 func (h *APIHandler) GetViewportStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// Parse viewport bounds from query parameters
 	minLat := parseFloat(r.URL.Query().Get("min_lat"), -90)
 	maxLat := parseFloat(r.URL.Query().Get("max_lat"), 90)
@@ -188,56 +118,28 @@ func (h *APIHandler) GetViewportStats(w http.ResponseWriter, r *http.Request) {
 	stats.ByMatchStatus = make(map[string]int)
 	stats.ByQuality = make(map[string]int)
 
-	// Query records within viewport bounds
+	// Count records within viewport bounds
 	// Convert WGS84 bounds to BNG coordinates for spatial query
-	query := `
-		SELECT 
-			source_type,
-			match_status,
-			address_quality,
-			COUNT(*) as count
-		FROM v_map_all_records
-		WHERE easting IS NOT NULL AND northing IS NOT NULL
-			AND ST_Within(
-				ST_SetSRID(ST_MakePoint(easting, northing), 27700),
-				ST_Transform(ST_MakeEnvelope($1, $2, $3, $4, 4326), 27700)
-			)
-		GROUP BY source_type, match_status, address_quality
-	`
+	viewport := ViewportBounds{MinLat: minLat, MaxLat: maxLat, MinLng: minLng, MaxLng: maxLng}
+	var box store.BNGBox
+	box.MinE, box.MinN, box.MaxE, box.MaxN = viewport.BNGBounds(h.Config.transformer())
 
-	rows, err := h.DB.Query(query, minLng, minLat, maxLng, maxLat)
+	counts, err := h.Stores.Records.ViewportCounts(ctx, &box)
 	if err != nil {
-		// Fallback to simpler query if spatial query fails
-		simpleQuery := `
-			SELECT 
-				source_type,
-				match_status,
-				address_quality,
-				COUNT(*) as count
-			FROM v_map_all_records
-			WHERE easting IS NOT NULL AND northing IS NOT NULL
-			GROUP BY source_type, match_status, address_quality
-		`
-		rows, err = h.DB.Query(simpleQuery)
+		// Fall back to every mapped record if the spatial query fails
+		counts, err = h.Stores.Records.ViewportCounts(ctx, nil)
 	}
-	
 	if err != nil {
+		log.Printf("Failed to count records in viewport: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var sourceType, matchStatus, quality string
-		var count int
-		if err := rows.Scan(&sourceType, &matchStatus, &quality, &count); err != nil {
-			continue
-		}
 
-		stats.TotalRecords += count
-		stats.BySourceType[sourceType] += count
-		stats.ByMatchStatus[matchStatus] += count
-		stats.ByQuality[quality] += count
+	for _, c := range counts {
+		stats.TotalRecords += c.Count
+		stats.BySourceType[c.SourceType] += c.Count
+		stats.ByMatchStatus[c.MatchStatus] += c.Count
+		stats.ByQuality[c.AddressQuality] += c.Count
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ehdc-llpg/internal/auth"
+)
+
+// AuthHandler signs users in and out of the web interface
+type AuthHandler struct {
+	DB         *sql.DB
+	Config     *Config
+	Store      *auth.Store
+	SessionTTL time.Duration
+}
+
+// loginRequest is the body of POST /api/auth/login
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Login checks a username and password and sets the session cookie
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Username == "" || req.Password == "" {
+		http.Error(w, "Username and password required", http.StatusBadRequest)
+		return
+	}
+
+	token, id, expires, err := h.Store.Login(r.Context(), req.Username, req.Password, r.RemoteAddr, h.SessionTTL)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		log.Printf("Failed sign-in for %q from %s", req.Username, r.RemoteAddr)
+		http.Error(w, "Wrong username or password", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Sign-in error: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":       id,
+		"expires_at": expires,
+	})
+}
+
+// Logout ends the caller's session and clears the cookie
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(auth.SessionCookie); err == nil && cookie.Value != "" {
+		if err := h.Store.Logout(r.Context(), cookie.Value); err != nil {
+			log.Printf("Sign-out error: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me returns the caller's identity
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.FromContext(r.Context()))
+}
+
+// userResponse is a user as listed by GET /api/admin/users
+type userResponse struct {
+	Username    string     `json:"username"`
+	DisplayName string     `json:"display_name"`
+	Role        auth.Role  `json:"role"`
+	HasPassword bool       `json:"has_password"`
+	APIKeys     int        `json:"api_keys"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	DisabledAt  *time.Time `json:"disabled_at"`
+}
+
+// Users lists the web interface's users; they are managed with matcher auth
+func (h *AuthHandler) Users(w http.ResponseWriter, r *http.Request) {
+	users, err := h.Store.Users(r.Context())
+	if err != nil {
+		log.Printf("Listing users failed: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	resp := make([]userResponse, 0, len(users))
+	for _, u := range users {
+		resp = append(resp, userResponse{
+			Username: u.Username, DisplayName: u.DisplayName, Role: u.Role, HasPassword: u.HasPassword,
+			APIKeys: u.Keys, CreatedBy: u.CreatedBy, CreatedAt: u.CreatedAt,
+			LastLoginAt: u.LastLoginAt, DisabledAt: u.DisabledAt,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// actor names the caller in decided_by and similar columns
+func actor(r *http.Request) string {
+	if id := auth.FromContext(r.Context()); id != nil {
+		return id.Username
+	}
+	return auth.AnonymousUser
+}
@@ -3,23 +3,40 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+
+	"github.com/ehdc-llpg/internal/auth"
+	"github.com/ehdc-llpg/internal/export"
+	"github.com/ehdc-llpg/internal/geo"
+	"github.com/ehdc-llpg/internal/store"
 )
 
-// ExportHandler handles data export endpoints
+// ExportHandler handles data export endpoints; exports run as background jobs
 type ExportHandler struct {
 	DB     *sql.DB
 	Config *Config
+	Jobs   *export.Manager
 }
 
 // ExportRequest represents an export request
 type ExportRequest struct {
-	Format      string                 `json:"format"`       // csv, geojson
-	SourceTypes []string               `json:"source_types"` // filter by source types
-	MatchStatus []string               `json:"match_status"` // filter by match status
-	AddressQuery string                `json:"address_query"` // address search filter
-	Viewport    *ViewportBounds        `json:"viewport"`     // spatial bounds
-	Options     map[string]interface{} `json:"options"`      // format-specific options
+	Format         string                 `json:"format"`          // csv, geojson
+	SourceTypes    []string               `json:"source_types"`    // filter by source types
+	MatchStatus    []string               `json:"match_status"`    // filter by match status
+	AddressQuality []string               `json:"address_quality"` // filter by address quality
+	MinScore       *float64               `json:"min_score"`       // match score range
+	MaxScore       *float64               `json:"max_score"`
+	AddressQuery   string                 `json:"address_query"` // address search filter
+	Viewport       *ViewportBounds        `json:"viewport"`      // spatial bounds
+	Options        map[string]interface{} `json:"options"`       // format-specific options
 }
 
 // ViewportBounds represents map viewport bounds
@@ -30,16 +47,42 @@ type ViewportBounds struct {
 	MaxLng float64 `json:"max_lng"`
 }
 
-// ExportResponse represents an export response
-type ExportResponse struct {
-	Success     bool   `json:"success"`
-	Message     string `json:"message"`
-	DownloadURL string `json:"download_url,omitempty"`
-	RecordCount int    `json:"record_count"`
-	Format      string `json:"format"`
+// BNGBounds converts the viewport to a BNG envelope covering all four corners
+func (v *ViewportBounds) BNGBounds(t *geo.Transformer) (minE, minN, maxE, maxN float64) {
+	corners := t.ToBNGBatch([]geo.LatLng{
+		{Lat: v.MinLat, Lng: v.MinLng},
+		{Lat: v.MinLat, Lng: v.MaxLng},
+		{Lat: v.MaxLat, Lng: v.MinLng},
+		{Lat: v.MaxLat, Lng: v.MaxLng},
+	})
+
+	minE, minN = corners[0].Easting, corners[0].Northing
+	maxE, maxN = minE, minN
+	for _, c := range corners[1:] {
+		minE, maxE = math.Min(minE, c.Easting), math.Max(maxE, c.Easting)
+		minN, maxN = math.Min(minN, c.Northing), math.Max(maxN, c.Northing)
+	}
+	return minE, minN, maxE, maxN
+}
+
+// ExportStatus is an export job as reported to its requester
+type ExportStatus struct {
+	export.Job
+	Progress    float64 `json:"progress"`
+	StatusURL   string  `json:"status_url"`
+	DownloadURL string  `json:"download_url,omitempty"`
 }
 
-// ExportData handles data export requests
+func exportStatus(job export.Job) ExportStatus {
+	status := ExportStatus{Job: job, Progress: job.Progress(), StatusURL: "/api/exports/" + job.ID}
+	if job.Status == export.StatusDone {
+		status.DownloadURL = status.StatusURL + "/download"
+	}
+	return status
+}
+
+// ExportData queues an export of the records matching the request. It responds 202 with
+// the job; poll its status_url until it is done, then fetch its download_url.
 func (h *ExportHandler) ExportData(w http.ResponseWriter, r *http.Request) {
 	if !h.Config.Features.ExportEnabled {
 		http.Error(w, "Export feature disabled", http.StatusForbidden)
@@ -55,67 +98,119 @@ func (h *ExportHandler) ExportData(w http.ResponseWriter, r *http.Request) {
 
 	// Validate format
 	if exportReq.Format == "" {
-		exportReq.Format = "csv" // Default to CSV
+		exportReq.Format = export.FormatCSV // Default to CSV
 	}
-	if exportReq.Format != "csv" && exportReq.Format != "geojson" {
+	if exportReq.Format != export.FormatCSV && exportReq.Format != export.FormatGeoJSON {
 		http.Error(w, "Unsupported export format. Use 'csv' or 'geojson'", http.StatusBadRequest)
 		return
 	}
 
 	// Build export query based on format
-	var query string
-	var args []interface{}
-	
-	if exportReq.Format == "geojson" {
-		// GeoJSON export - only records with coordinates
-		query, args = h.buildGeoJSONExportQuery(&exportReq)
+	var q export.Query
+	if exportReq.Format == export.FormatGeoJSON {
+		// GeoJSON export - only records with coordinates, located by the transformer
+		q.SQL, q.Args = h.buildGeoJSONExportQuery(&exportReq)
+		transformer := h.Config.transformer()
+		q.Locate = func(easting, northing float64) interface{} {
+			point, err := transformer.ToPoint(easting, northing)
+			if err != nil {
+				return nil // a null geometry, as for a feature with no location
+			}
+			return point
+		}
 	} else {
 		// CSV export - all matching records
-		query, args = h.buildCSVExportQuery(&exportReq)
+		q.SQL, q.Args = h.buildCSVExportQuery(&exportReq)
 	}
 
-	// Execute query to count records first
-	countQuery := h.convertToCountQuery(query)
-	var recordCount int
-	err := h.DB.QueryRow(countQuery, args...).Scan(&recordCount)
+	job, err := h.Jobs.Submit(exportReq.Format, q, actor(r))
+	if errors.Is(err, export.ErrQueueFull) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		log.Printf("Export not queued: %v", err)
+		http.Error(w, "Export failed", http.StatusInternalServerError)
 		return
 	}
 
-	// Check if record count is reasonable for immediate export
-	if recordCount > 50000 {
-		// For large exports, we would typically queue this as a background job
-		// For now, return an error suggesting the user narrow their filters
-		response := ExportResponse{
-			Success:     false,
-			Message:     "Too many records for immediate export. Please narrow your filters.",
-			RecordCount: recordCount,
-			Format:      exportReq.Format,
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+	status := exportStatus(job)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", status.StatusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// ListExports returns the caller's exports, newest first; admins see everyone's
+func (h *ExportHandler) ListExports(w http.ResponseWriter, r *http.Request) {
+	requestedBy := actor(r)
+	if auth.FromContext(r.Context()).Can(auth.RoleAdmin) {
+		requestedBy = ""
+	}
+	statuses := make([]ExportStatus, 0)
+	for _, job := range h.Jobs.List(requestedBy) {
+		statuses = append(statuses, exportStatus(job))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// GetExport returns an export's status and progress
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exportStatus(job))
+}
+
+// DownloadExport sends a finished export's file
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.job(w, r); !ok {
+		return
+	}
+	f, job, err := h.Jobs.Open(mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, export.ErrNotFound):
+		http.Error(w, "Export has expired", http.StatusGone)
+		return
+	case errors.Is(err, export.ErrNotReady):
+		http.Error(w, "Export is "+job.Status, http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Export download failed: %v", err)
+		http.Error(w, "Export file unavailable", http.StatusInternalServerError)
 		return
 	}
+	defer f.Close()
 
-	// For demonstration purposes, return a success response with metadata
-	// In a full implementation, this would generate the actual file and return a download URL
-	response := ExportResponse{
-		Success:     true,
-		Message:     "Export completed successfully",
-		DownloadURL: "/api/download/" + generateExportID(),
-		RecordCount: recordCount,
-		Format:      exportReq.Format,
+	contentType := "text/csv; charset=utf-8"
+	if job.Format == export.FormatGeoJSON {
+		contentType = "application/geo+json"
 	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+job.Filename()+`"`)
+	http.ServeContent(w, r, job.Filename(), *job.CompletedAt, f)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// job looks up the export named in the URL; only its requester and admins may see it
+func (h *ExportHandler) job(w http.ResponseWriter, r *http.Request) (export.Job, bool) {
+	job, err := h.Jobs.Get(mux.Vars(r)["id"])
+	if err == nil && job.RequestedBy != actor(r) && !auth.FromContext(r.Context()).Can(auth.RoleAdmin) {
+		err = export.ErrNotFound
+	}
+	if err != nil {
+		http.Error(w, "Export not found", http.StatusNotFound)
+		return job, false
+	}
+	return job, true
 }
 
 // buildCSVExportQuery builds a query for CSV export
 func (h *ExportHandler) buildCSVExportQuery(req *ExportRequest) (string, []interface{}) {
 	query := `
-		SELECT 
+		SELECT
 			src_id, source_type, filepath, external_ref, doc_type, doc_date,
 			original_address, canonical_address, extracted_postcode,
 			source_uprn, source_easting, source_northing,
@@ -123,38 +218,57 @@ func (h *ExportHandler) buildCSVExportQuery(req *ExportRequest) (string, []inter
 			coordinate_distance, address_similarity,
 			matched_uprn, llpg_address, llpg_easting, llpg_northing, usrn
 		FROM v_enhanced_source_documents
-		WHERE 1=1
+		WHERE ` + store.LiveRecords + `
 	`
-	
+
 	var args []interface{}
 	argIndex := 1
 
 	// Add filters
 	if len(req.SourceTypes) > 0 {
-		query += " AND source_type = ANY($" + string(rune(argIndex)) + ")"
-		args = append(args, req.SourceTypes)
+		query += " AND source_type = ANY($" + strconv.Itoa(argIndex) + ")"
+		args = append(args, pq.Array(req.SourceTypes))
 		argIndex++
 	}
-	
+
 	if len(req.MatchStatus) > 0 {
-		query += " AND match_status = ANY($" + string(rune(argIndex)) + ")"
-		args = append(args, req.MatchStatus)
+		query += " AND match_status = ANY($" + strconv.Itoa(argIndex) + ")"
+		args = append(args, pq.Array(req.MatchStatus))
+		argIndex++
+	}
+
+	if len(req.AddressQuality) > 0 {
+		query += " AND address_quality = ANY($" + strconv.Itoa(argIndex) + ")"
+		args = append(args, pq.Array(req.AddressQuality))
+		argIndex++
+	}
+
+	if req.MinScore != nil {
+		query += " AND match_score >= $" + strconv.Itoa(argIndex)
+		args = append(args, *req.MinScore)
+		argIndex++
+	}
+
+	if req.MaxScore != nil {
+		query += " AND match_score <= $" + strconv.Itoa(argIndex)
+		args = append(args, *req.MaxScore)
 		argIndex++
 	}
-	
+
 	if req.AddressQuery != "" {
-		query += " AND (original_address ILIKE $" + string(rune(argIndex)) + " OR canonical_address ILIKE $" + string(rune(argIndex)) + ")"
+		query += " AND (original_address ILIKE $" + strconv.Itoa(argIndex) + " OR canonical_address ILIKE $" + strconv.Itoa(argIndex) + ")"
 		args = append(args, "%"+req.AddressQuery+"%")
 		argIndex++
 	}
 
 	// Add viewport filter if specified
 	if req.Viewport != nil {
-		query += ` AND ST_Within(
-			ST_SetSRID(ST_MakePoint(COALESCE(llpg_easting, source_easting), COALESCE(llpg_northing, source_northing)), 27700),
-			ST_Transform(ST_MakeEnvelope($` + string(rune(argIndex)) + `, $` + string(rune(argIndex+1)) + `, $` + string(rune(argIndex+2)) + `, $` + string(rune(argIndex+3)) + `, 4326), 27700)
-		)`
-		args = append(args, req.Viewport.MinLng, req.Viewport.MinLat, req.Viewport.MaxLng, req.Viewport.MaxLat)
+		// Filter on BNG directly rather than transforming every row with PostGIS
+		minE, minN, maxE, maxN := req.Viewport.BNGBounds(h.Config.transformer())
+		query += fmt.Sprintf(` AND COALESCE(llpg_easting, source_easting) BETWEEN $%d AND $%d
+			AND COALESCE(llpg_northing, source_northing) BETWEEN $%d AND $%d`,
+			argIndex, argIndex+1, argIndex+2, argIndex+3)
+		args = append(args, minE, maxE, minN, maxN)
 		argIndex += 4
 	}
 
@@ -162,46 +276,72 @@ func (h *ExportHandler) buildCSVExportQuery(req *ExportRequest) (string, []inter
 	return query, args
 }
 
-// buildGeoJSONExportQuery builds a query for GeoJSON export
+// buildGeoJSONExportQuery builds a query for GeoJSON export: one Feature per record with
+// coordinates, with the same properties as the map's features, then its easting and northing
+// for the export to locate it
 func (h *ExportHandler) buildGeoJSONExportQuery(req *ExportRequest) (string, []interface{}) {
-	// Use the existing get_record_geojson function for consistency
-	query := "SELECT geojson_feature FROM get_record_geojson($1, $2, $3, $4, $5, $6, $7)"
-	
-	var sourceType, matchStatus, addressQuality, addressSearch interface{}
-	var minScore, maxScore interface{}
-	
-	if len(req.SourceTypes) == 1 {
-		sourceType = req.SourceTypes[0]
-	}
-	if len(req.MatchStatus) == 1 {
-		matchStatus = req.MatchStatus[0]
+	query := `
+		SELECT jsonb_build_object(
+			'type', 'Feature',
+			'properties', jsonb_build_object(
+				'src_id', src_id,
+				'source_type', source_type,
+				'external_reference', external_reference,
+				'address', address,
+				'uprn', uprn,
+				'match_status', match_status,
+				'match_score', match_score,
+				'address_quality', address_quality,
+				'match_method', match_method,
+				'doc_type', doc_type,
+				'doc_date', doc_date
+			)
+		) AS geojson_feature, easting, northing
+		FROM v_map_all_records
+		WHERE easting IS NOT NULL AND northing IS NOT NULL AND ` + store.LiveRecords + `
+	`
+
+	var args []interface{}
+	argIndex := 1
+
+	if len(req.SourceTypes) > 0 {
+		query += " AND source_type = ANY($" + strconv.Itoa(argIndex) + ")"
+		args = append(args, pq.Array(req.SourceTypes))
+		argIndex++
+	}
+	if len(req.MatchStatus) > 0 {
+		query += " AND match_status = ANY($" + strconv.Itoa(argIndex) + ")"
+		args = append(args, pq.Array(req.MatchStatus))
+		argIndex++
+	}
+	if len(req.AddressQuality) > 0 {
+		query += " AND address_quality = ANY($" + strconv.Itoa(argIndex) + ")"
+		args = append(args, pq.Array(req.AddressQuality))
+		argIndex++
+	}
+	if req.MinScore != nil {
+		query += " AND match_score >= $" + strconv.Itoa(argIndex)
+		args = append(args, *req.MinScore)
+		argIndex++
+	}
+	if req.MaxScore != nil {
+		query += " AND match_score <= $" + strconv.Itoa(argIndex)
+		args = append(args, *req.MaxScore)
+		argIndex++
 	}
 	if req.AddressQuery != "" {
-		addressSearch = req.AddressQuery
+		query += " AND address ILIKE $" + strconv.Itoa(argIndex)
+		args = append(args, "%"+req.AddressQuery+"%")
+		argIndex++
 	}
-	
-	args := []interface{}{
-		sourceType,
-		matchStatus,
-		addressQuality,
-		minScore,
-		maxScore,
-		addressSearch,
-		50000, // Default limit
+	if req.Viewport != nil {
+		minE, minN, maxE, maxN := req.Viewport.BNGBounds(h.Config.transformer())
+		query += fmt.Sprintf(" AND easting BETWEEN $%d AND $%d AND northing BETWEEN $%d AND $%d",
+			argIndex, argIndex+1, argIndex+2, argIndex+3)
+		args = append(args, minE, maxE, minN, maxN)
+		argIndex += 4
 	}
 
+	query += " ORDER BY src_id"
 	return query, args
 }
-
-// convertToCountQuery converts a SELECT query to a COUNT query
-func (h *ExportHandler) convertToCountQuery(query string) string {
-	// Simple implementation - wrap the query in a COUNT
-	// This is a basic approach; in practice, you'd want more sophisticated query rewriting
-	return "SELECT COUNT(*) FROM (" + query + ") AS count_subquery"
-}
-
-// generateExportID generates a unique export ID for download tracking
-func generateExportID() string {
-	// Simple implementation - in practice, use UUID or similar
-	return "export_123456789"
-}
\ No newline at end of file
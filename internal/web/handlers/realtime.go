@@ -1,17 +1,19 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/ehdc-llpg/internal/store"
 )
 
 // RealtimeHandler handles real-time updates via Server-Sent Events
 type RealtimeHandler struct {
-	DB     *sql.DB
+	Stores store.Stores
 	Config *Config
 }
 
@@ -99,7 +101,7 @@ func (h *RealtimeHandler) SSEUpdates(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Send initial stats
-	if stats := h.getLatestStats(); stats != nil {
+	if stats := h.getLatestStats(ctx); stats != nil {
 		h.sendSSEEvent(w, flusher, "stats_update", stats)
 	}
 
@@ -111,10 +113,10 @@ func (h *RealtimeHandler) SSEUpdates(w http.ResponseWriter, r *http.Request) {
 			return
 		case <-ticker.C:
 			// Periodic update check
-			if h.hasDataChanges() {
+			if h.hasDataChanges(ctx) {
 				// Send data update notification
 				update := &DataUpdate{
-					RecordsChanged: h.getChangedRecordsCount(),
+					RecordsChanged: h.getChangedRecordsCount(ctx),
 					ChangeType:     "matches_updated",
 					Viewport:       viewport,
 				}
@@ -122,7 +124,7 @@ func (h *RealtimeHandler) SSEUpdates(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Send updated stats
-			if stats := h.getLatestStats(); stats != nil {
+			if stats := h.getLatestStats(ctx); stats != nil {
 				h.sendSSEEvent(w, flusher, "stats_update", stats)
 			}
 
@@ -153,44 +155,24 @@ func (h *RealtimeHandler) sendSSEEvent(w http.ResponseWriter, flusher http.Flush
 }
 
 // getLatestStats gets the latest system statistics
-func (h *RealtimeHandler) getLatestStats() *StatsUpdate {
-	query := `
-		SELECT 
-			COUNT(*) as total,
-			COUNT(CASE WHEN match_status = 'MATCHED' THEN 1 END) as matched
-		FROM v_enhanced_source_documents
-	`
-
-	var total, matched int
-	err := h.DB.QueryRow(query).Scan(&total, &matched)
+func (h *RealtimeHandler) getLatestStats(ctx context.Context) *StatsUpdate {
+	stats, err := h.Stores.Records.RecordStats(ctx)
 	if err != nil {
 		return nil
 	}
 
-	matchRate := float64(0)
-	if total > 0 {
-		matchRate = float64(matched) / float64(total) * 100
-	}
-
 	return &StatsUpdate{
-		TotalRecords:   total,
-		MatchedRecords: matched,
-		MatchRate:      matchRate,
+		TotalRecords:   stats.Total,
+		MatchedRecords: stats.Matched,
+		MatchRate:      matchRate(store.CategoryCount{Total: stats.Total, Matched: stats.Matched}),
 	}
 }
 
 // hasDataChanges checks if there have been recent data changes
-func (h *RealtimeHandler) hasDataChanges() bool {
+func (h *RealtimeHandler) hasDataChanges(ctx context.Context) bool {
 	// Simple implementation - check for recent matches
 	// In production, this could use a change log table or timestamps
-	query := `
-		SELECT COUNT(*)
-		FROM match_accepted
-		WHERE accepted_at > NOW() - INTERVAL '1 minute'
-	`
-
-	var recentChanges int
-	err := h.DB.QueryRow(query).Scan(&recentChanges)
+	recentChanges, err := h.Stores.Records.RecentAccepts(ctx, time.Minute)
 	if err != nil {
 		return false
 	}
@@ -199,15 +181,8 @@ func (h *RealtimeHandler) hasDataChanges() bool {
 }
 
 // getChangedRecordsCount gets the count of recently changed records
-func (h *RealtimeHandler) getChangedRecordsCount() int {
-	query := `
-		SELECT COUNT(*)
-		FROM match_accepted
-		WHERE accepted_at > NOW() - INTERVAL '5 minutes'
-	`
-
-	var count int
-	err := h.DB.QueryRow(query).Scan(&count)
+func (h *RealtimeHandler) getChangedRecordsCount(ctx context.Context) int {
+	count, err := h.Stores.Records.RecentAccepts(ctx, 5*time.Minute)
 	if err != nil {
 		return 0
 	}
@@ -1,48 +1,33 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
+
+	"github.com/ehdc-llpg/internal/auth"
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/facts"
+	"github.com/ehdc-llpg/internal/match"
+	"github.com/ehdc-llpg/internal/store"
 )
 
 // RecordsHandler handles record-related endpoints
 type RecordsHandler struct {
-	DB     *sql.DB
+	Stores store.Stores
+	DB     *sql.DB // for facts.Refresh
 	Config *Config
+	Review *ReviewHandler // settles accepts and rejects of records in the review queue
 }
 
 // Record represents a source document record
-type Record struct {
-	SrcID               int       `json:"src_id"`
-	SourceType          string    `json:"source_type"`
-	Filepath            string    `json:"filepath"`
-	ExternalRef         *string   `json:"external_ref"`
-	DocType             *string   `json:"doc_type"`
-	DocDate             *string   `json:"doc_date"`
-	OriginalAddress     string    `json:"original_address"`
-	CanonicalAddress    *string   `json:"canonical_address"`
-	ExtractedPostcode   *string   `json:"extracted_postcode"`
-	SourceUPRN          *string   `json:"source_uprn"`
-	SourceEasting       *float64  `json:"source_easting"`
-	SourceNorthing      *float64  `json:"source_northing"`
-	AddressQuality      string    `json:"address_quality"`
-	MatchStatus         string    `json:"match_status"`
-	MatchMethod         *string   `json:"match_method"`
-	MatchScore          *float64  `json:"match_score"`
-	CoordinateDistance  *float64  `json:"coordinate_distance"`
-	AddressSimilarity   *float64  `json:"address_similarity"`
-	MatchedUPRN         *string   `json:"matched_uprn"`
-	LLPGAddress         *string   `json:"llpg_address"`
-	LLPGEasting         *float64  `json:"llpg_easting"`
-	LLPGNorthing        *float64  `json:"llpg_northing"`
-	USRN                *string   `json:"usrn"`
-	ImportDate          time.Time `json:"import_date"`
-}
+type Record = store.Record
 
 // MatchCandidate represents a potential match for a record
 type MatchCandidate struct {
@@ -80,109 +65,20 @@ func (h *RecordsHandler) ListRecords(w http.ResponseWriter, r *http.Request) {
 	}
 	offset := (page - 1) * perPage
 
-	// Parse filter parameters
-	sourceType := query.Get("source_type")
-	matchStatus := query.Get("match_status")
-	addressQuality := query.Get("address_quality")
-	addressSearch := query.Get("address_search")
-	
-	// Build dynamic query
-	baseQuery := `
-		SELECT 
-			src_id, source_type, filepath, external_ref, doc_type, doc_date,
-			original_address, canonical_address, extracted_postcode,
-			source_uprn, source_easting, source_northing,
-			address_quality, match_status, match_method, match_score,
-			coordinate_distance, address_similarity,
-			matched_uprn, llpg_address, llpg_easting, llpg_northing, usrn,
-			import_date
-		FROM v_enhanced_source_documents
-		WHERE 1=1
-	`
-	
-	countQuery := `
-		SELECT COUNT(*) 
-		FROM v_enhanced_source_documents
-		WHERE 1=1
-	`
-	
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	// Add filter conditions
-	if sourceType != "" {
-		conditions = append(conditions, " AND source_type = $"+strconv.Itoa(argIndex))
-		args = append(args, sourceType)
-		argIndex++
-	}
-	if matchStatus != "" {
-		conditions = append(conditions, " AND match_status = $"+strconv.Itoa(argIndex))
-		args = append(args, matchStatus)
-		argIndex++
-	}
-	if addressQuality != "" {
-		conditions = append(conditions, " AND address_quality = $"+strconv.Itoa(argIndex))
-		args = append(args, addressQuality)
-		argIndex++
-	}
-	if addressSearch != "" {
-		conditions = append(conditions, " AND (original_address ILIKE $"+strconv.Itoa(argIndex)+" OR canonical_address ILIKE $"+strconv.Itoa(argIndex)+")")
-		args = append(args, "%"+addressSearch+"%")
-		argIndex++
+	filter := store.RecordFilter{
+		SourceType:     query.Get("source_type"),
+		MatchStatus:    query.Get("match_status"),
+		AddressQuality: query.Get("address_quality"),
+		AddressSearch:  query.Get("address_search"),
 	}
 
-	// Apply conditions to both queries
-	for _, condition := range conditions {
-		baseQuery += condition
-		countQuery += condition
-	}
-
-	// Get total count
-	var total int
-	err := h.DB.QueryRow(countQuery, args...).Scan(&total)
+	records, total, err := h.Stores.Records.Records(r.Context(), filter, perPage, offset)
 	if err != nil {
+		log.Printf("Failed to list records: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	// Add ordering and pagination to main query
-	baseQuery += " ORDER BY src_id LIMIT $" + strconv.Itoa(argIndex) + " OFFSET $" + strconv.Itoa(argIndex+1)
-	args = append(args, perPage, offset)
-
-	// Execute main query
-	rows, err := h.DB.Query(baseQuery, args...)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var records []Record
-	for rows.Next() {
-		var record Record
-		var docDate sql.NullString
-		
-		err := rows.Scan(
-			&record.SrcID, &record.SourceType, &record.Filepath, &record.ExternalRef,
-			&record.DocType, &docDate, &record.OriginalAddress, &record.CanonicalAddress,
-			&record.ExtractedPostcode, &record.SourceUPRN, &record.SourceEasting, &record.SourceNorthing,
-			&record.AddressQuality, &record.MatchStatus, &record.MatchMethod, &record.MatchScore,
-			&record.CoordinateDistance, &record.AddressSimilarity,
-			&record.MatchedUPRN, &record.LLPGAddress, &record.LLPGEasting, &record.LLPGNorthing,
-			&record.USRN, &record.ImportDate,
-		)
-		if err != nil {
-			continue
-		}
-
-		if docDate.Valid {
-			record.DocDate = &docDate.String
-		}
-
-		records = append(records, record)
-	}
-
 	response := RecordsListResponse{
 		Records: records,
 		Total:   total,
@@ -203,43 +99,15 @@ func (h *RecordsHandler) GetRecord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `
-		SELECT 
-			src_id, source_type, filepath, external_ref, doc_type, doc_date,
-			original_address, canonical_address, extracted_postcode,
-			source_uprn, source_easting, source_northing,
-			address_quality, match_status, match_method, match_score,
-			coordinate_distance, address_similarity,
-			matched_uprn, llpg_address, llpg_easting, llpg_northing, usrn,
-			import_date
-		FROM v_enhanced_source_documents
-		WHERE src_id = $1
-	`
-
-	var record Record
-	var docDate sql.NullString
-	
-	err = h.DB.QueryRow(query, srcID).Scan(
-		&record.SrcID, &record.SourceType, &record.Filepath, &record.ExternalRef,
-		&record.DocType, &docDate, &record.OriginalAddress, &record.CanonicalAddress,
-		&record.ExtractedPostcode, &record.SourceUPRN, &record.SourceEasting, &record.SourceNorthing,
-		&record.AddressQuality, &record.MatchStatus, &record.MatchMethod, &record.MatchScore,
-		&record.CoordinateDistance, &record.AddressSimilarity,
-		&record.MatchedUPRN, &record.LLPGAddress, &record.LLPGEasting, &record.LLPGNorthing,
-		&record.USRN, &record.ImportDate,
-	)
-	
-	if err == sql.ErrNoRows {
-		http.Error(w, "Record not found", http.StatusNotFound)
-		return
-	}
+	record, err := h.Stores.Records.Record(r.Context(), int64(srcID))
 	if err != nil {
+		log.Printf("Failed to read record %d: %v", srcID, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-
-	if docDate.Valid {
-		record.DocDate = &docDate.String
+	if record == nil {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -258,53 +126,212 @@ func (h *RecordsHandler) GetCandidates(w http.ResponseWriter, r *http.Request) {
 	// For now, return a simple implementation
 	// In a full implementation, this would run the matching algorithms
 	// to find potential candidates from the LLPG
-	
-	query := `
-		SELECT 
-			uprn, locaddress, addr_can, easting, northing, usrn, 
-			blpu_class, postal_flag, status
-		FROM dim_address
-		WHERE addr_can ILIKE (
-			SELECT '%' || COALESCE(canonical_address, original_address) || '%'
-			FROM v_enhanced_source_documents 
-			WHERE src_id = $1
-		)
-		LIMIT 10
-	`
-
-	rows, err := h.DB.Query(query, srcID)
+	listings, err := h.Stores.Records.RecordCandidates(r.Context(), int64(srcID), 10)
 	if err != nil {
+		log.Printf("Failed to find candidates for record %d: %v", srcID, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
 	var candidates []MatchCandidate
-	for rows.Next() {
-		var candidate MatchCandidate
-		err := rows.Scan(
-			&candidate.UPRN, &candidate.Address, &candidate.CanonicalAddress,
-			&candidate.Easting, &candidate.Northing, &candidate.USRN,
-			&candidate.BLPUClass, &candidate.PostalFlag, &candidate.Status,
-		)
+	for _, l := range listings {
+		candidates = append(candidates, MatchCandidate{
+			UPRN: l.UPRN, Address: l.Address, CanonicalAddress: l.CanonicalAddress,
+			Easting: l.Easting, Northing: l.Northing, USRN: l.USRN,
+			BLPUClass: l.BLPUClass, PostalFlag: l.PostalFlag, Status: l.Status,
+
+			// Mock score and method for demonstration
+			Score:    0.85,
+			Method:   "fuzzy_similarity",
+			Features: []string{"address_similarity", "postcode_match"},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// RecordExplanation explains the latest match decision for a record
+type RecordExplanation struct {
+	SrcID        int                          `json:"src_id"`
+	Source       string                       `json:"source"` // match_explanation, or match_result when none was persisted
+	RunID        *int64                       `json:"run_id"`
+	Decision     string                       `json:"decision"`
+	AcceptedUPRN *string                      `json:"accepted_uprn"`
+	Tier         *string                      `json:"tier"`
+	Margin       *float64                     `json:"margin"`
+	Reason       *string                      `json:"reason"`
+	Thresholds   json.RawMessage              `json:"thresholds"`
+	Candidates   []match.CandidateExplanation `json:"candidates"`
+	CreatedAt    *time.Time                   `json:"created_at"`
+}
+
+// GetExplanation returns why a record was matched as it was: per-feature contributions,
+// generators, filters, threshold tier and margin for each candidate. Runs that were rolled
+// back are passed over; decisions made before explanations were persisted are rebuilt from
+// match_result without the contributions.
+func (h *RecordsHandler) GetExplanation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	srcID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	stored, err := h.Stores.Matches.LatestExplanation(ctx, int64(srcID))
+	if err != nil {
+		log.Printf("Failed to read explanation for record %d: %v", srcID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var explanation RecordExplanation
+	if stored == nil {
+		explanation, err = h.explainFromResults(ctx, srcID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "No match decision recorded for this record", http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			continue
+			log.Printf("Failed to rebuild explanation for record %d: %v", srcID, err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
 		}
+	} else {
+		explanation, err = storedExplanation(stored)
+		if err != nil {
+			log.Printf("Failed to decode explanation for record %d: %v", srcID, err)
+			http.Error(w, "Invalid stored explanation", http.StatusInternalServerError)
+			return
+		}
+	}
 
-		// Mock score and method for demonstration
-		candidate.Score = 0.85
-		candidate.Method = "fuzzy_similarity"
-		candidate.Features = []string{"address_similarity", "postcode_match"}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(explanation)
+}
 
-		candidates = append(candidates, candidate)
+// storedExplanation converts a persisted explanation. Its candidates and thresholds are
+// JSON read from match_explanation, or the engine's values in the memory store.
+func storedExplanation(e *store.Explanation) (RecordExplanation, error) {
+	runID, createdAt := e.RunID, e.CreatedAt
+	explanation := RecordExplanation{
+		SrcID:     int(e.SrcID),
+		Source:    "match_explanation",
+		RunID:     &runID,
+		Decision:  e.Decision,
+		Margin:    e.Margin,
+		CreatedAt: &createdAt,
+	}
+	if e.AcceptedUPRN != "" {
+		explanation.AcceptedUPRN = &e.AcceptedUPRN
+	}
+	if e.Tier != "" {
+		explanation.Tier = &e.Tier
+	}
+	if e.Reason != "" {
+		explanation.Reason = &e.Reason
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(candidates)
+	thresholds, err := rawJSON(e.Thresholds)
+	if err != nil {
+		return explanation, err
+	}
+	explanation.Thresholds = thresholds
+
+	candidates, err := rawJSON(e.Candidates)
+	if err != nil {
+		return explanation, err
+	}
+	if err := json.Unmarshal(candidates, &explanation.Candidates); err != nil {
+		return explanation, err
+	}
+	return explanation, nil
+}
+
+func rawJSON(v interface{}) (json.RawMessage, error) {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return json.Marshal(v)
+}
+
+// explainFromResults rebuilds what it can of an explanation from the record's latest
+// match_result rows: scores, the generator that found each candidate, tiers and margins
+// against the default thresholds
+func (h *RecordsHandler) explainFromResults(ctx context.Context, srcID int) (RecordExplanation, error) {
+	explanation := RecordExplanation{SrcID: srcID, Source: "match_result"}
+
+	results, err := h.Stores.Records.LatestResults(ctx, int64(srcID), 10)
+	if err != nil {
+		return explanation, err
+	}
+
+	scorer := match.NewScorer()
+	var decisions []string
+	for _, res := range results {
+		runID := res.RunID
+		explanation.RunID = &runID
+		if res.DecidedAt != nil {
+			explanation.CreatedAt = res.DecidedAt
+		}
+		explanation.Candidates = append(explanation.Candidates, match.CandidateExplanation{
+			UPRN:     res.UPRN,
+			Address:  res.Address,
+			Score:    res.Score,
+			Rank:     len(explanation.Candidates) + 1,
+			Tier:     scorer.Tier(res.Score),
+			Methods:  []string{res.Method},
+			Selected: res.Decision == "accepted",
+		})
+		decisions = append(decisions, res.Decision)
+	}
+	if len(explanation.Candidates) == 0 {
+		return explanation, sql.ErrNoRows
+	}
+
+	// Margins against the best other candidate that was not suppressed
+	for i := range explanation.Candidates {
+		margin, found := 1.0, false
+		for j, other := range explanation.Candidates {
+			if j == i || decisions[j] == "suppressed" {
+				continue
+			}
+			if m := explanation.Candidates[i].Score - other.Score; !found || m < margin {
+				margin, found = m, true
+			}
+		}
+		explanation.Candidates[i].Margin = margin
+	}
+
+	top := explanation.Candidates[0]
+	explanation.Decision = decisions[0]
+	explanation.Tier = &top.Tier
+	explanation.Margin = &top.Margin
+	if top.Selected {
+		explanation.AcceptedUPRN = &top.UPRN
+	}
+	tiers := match.DefaultTiers()
+	explanation.Thresholds, _ = json.Marshal(map[string]float64{
+		"auto_accept_high":   tiers.AutoAcceptHigh,
+		"auto_accept_medium": tiers.AutoAcceptMedium,
+		"review":             tiers.ReviewThreshold,
+		"min_threshold":      tiers.MinThreshold,
+		"winner_margin":      tiers.WinnerMargin,
+	})
+	return explanation, nil
 }
 
-// AcceptMatch accepts a match candidate for a record
+// AcceptMatch accepts a candidate for a record. A record in the review queue is accepted
+// through the queue, so the caller must hold its lease and a low-confidence accept is
+// proposed for sign-off. Otherwise the stored candidate's method, score and confidence
+// are recorded, and one below the sign-off confidence is refused; the body's method and
+// score are ignored.
 func (h *RecordsHandler) AcceptMatch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := database.WithTimeout(r.Context())
+	defer cancel()
+
 	if !h.Config.Features.ManualOverrideEnabled {
 		http.Error(w, "Feature disabled", http.StatusForbidden)
 		return
@@ -319,10 +346,8 @@ func (h *RecordsHandler) AcceptMatch(w http.ResponseWriter, r *http.Request) {
 
 	// Parse JSON body for match acceptance
 	var acceptRequest struct {
-		UPRN   string  `json:"uprn"`
-		Method string  `json:"method"`
-		Score  float64 `json:"score"`
-		Reason string  `json:"reason"`
+		UPRN   string `json:"uprn"`
+		Reason string `json:"reason"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&acceptRequest); err != nil {
@@ -331,76 +356,56 @@ func (h *RecordsHandler) AcceptMatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate UPRN exists in LLPG
-	var exists bool
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM dim_address WHERE uprn = $1)`
-	err = h.DB.QueryRow(checkQuery, acceptRequest.UPRN).Scan(&exists)
+	exists, err := h.Stores.Records.AddressExists(ctx, acceptRequest.UPRN)
 	if err != nil || !exists {
 		http.Error(w, "Invalid UPRN", http.StatusBadRequest)
 		return
 	}
 
-	// Get current record state for audit
-	var currentRecord Record
-	getCurrentQuery := `
-		SELECT match_status, matched_uprn
-		FROM v_enhanced_source_documents
-		WHERE src_id = $1
-	`
-	h.DB.QueryRow(getCurrentQuery, srcID).Scan(&currentRecord.MatchStatus, &currentRecord.MatchedUPRN)
-
-	// Begin transaction for atomic operations
-	tx, err := h.DB.Begin()
+	// The matcher's own assessment of the candidate; a UPRN it never proposed has none
+	method, score, confidence := "manual", 0.0, 0.0
+	best, err := h.Stores.Records.BestResult(ctx, int64(srcID), acceptRequest.UPRN)
 	if err != nil {
+		log.Printf("Error reading candidate %s for record %d: %v", acceptRequest.UPRN, srcID, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer tx.Rollback()
-
-	// Insert audit record
-	auditQuery := `
-		INSERT INTO audit_match_decisions (
-			src_id, old_match_status, new_match_status, old_uprn, new_uprn,
-			decision_type, decision_reason, match_method, match_score, confidence,
-			decided_by, client_info
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	`
-
-	clientInfo := h.getClientInfo(r)
-	_, err = tx.Exec(auditQuery, srcID, currentRecord.MatchStatus, "MATCHED",
-		currentRecord.MatchedUPRN, &acceptRequest.UPRN, "ACCEPT_MATCH", 
-		acceptRequest.Reason, acceptRequest.Method, acceptRequest.Score, 
-		acceptRequest.Score, "web_user", clientInfo)
+	if best != nil {
+		method, score, confidence = best.Method, best.Score, best.Confidence
+	}
 
+	queued, err := h.Review.Service.Direct(ctx, int64(srcID), true, confidence)
 	if err != nil {
-		http.Error(w, "Audit logging failed", http.StatusInternalServerError)
+		h.Review.fail(w, err)
 		return
 	}
-
-	// Insert/update match_accepted table (handled by trigger)
-	matchQuery := `
-		INSERT INTO match_accepted (src_id, uprn, method, score, confidence, accepted_by, accepted_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW())
-		ON CONFLICT (src_id) DO UPDATE SET
-			uprn = EXCLUDED.uprn,
-			method = EXCLUDED.method,
-			score = EXCLUDED.score,
-			confidence = EXCLUDED.confidence,
-			accepted_by = EXCLUDED.accepted_by,
-			accepted_at = NOW()
-	`
-
-	_, err = tx.Exec(matchQuery, srcID, acceptRequest.UPRN, acceptRequest.Method, 
-		acceptRequest.Score, acceptRequest.Score, "web_user")
-
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	if queued {
+		h.Review.settle(w, r, int64(srcID), func(reviewer string) (string, error) {
+			action, err := h.Review.Service.Accept(ctx, int64(srcID), reviewer, acceptRequest.UPRN, acceptRequest.Reason)
+			if err == nil && (action == store.ReviewAccept || action == store.ReviewConfirm) {
+				h.refreshFacts(ctx, srcID)
+			}
+			return action, err
+		})
 		return
 	}
 
-	if err = tx.Commit(); err != nil {
-		http.Error(w, "Transaction commit failed", http.StatusInternalServerError)
+	err = h.Stores.Records.ManualAccept(ctx, store.ManualDecision{
+		SrcID:      int64(srcID),
+		UPRN:       acceptRequest.UPRN,
+		Method:     method,
+		Score:      score,
+		Confidence: confidence,
+		Reason:     acceptRequest.Reason,
+		DecidedBy:  actor(r),
+		ClientInfo: h.getClientInfo(r),
+	})
+	if err != nil {
+		log.Printf("Failed to accept %s for record %d: %v", acceptRequest.UPRN, srcID, err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	h.refreshFacts(ctx, srcID)
 
 	// Return success response
 	response := map[string]interface{}{
@@ -416,6 +421,9 @@ func (h *RecordsHandler) AcceptMatch(w http.ResponseWriter, r *http.Request) {
 
 // SetCoordinates sets manual coordinates for a record
 func (h *RecordsHandler) SetCoordinates(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := database.WithTimeout(r.Context())
+	defer cancel()
+
 	if !h.Config.Features.ManualOverrideEnabled {
 		http.Error(w, "Feature disabled", http.StatusForbidden)
 		return
@@ -441,66 +449,21 @@ func (h *RecordsHandler) SetCoordinates(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get current coordinates for audit
-	var currentEasting, currentNorthing sql.NullFloat64
-	getCurrentQuery := `
-		SELECT source_easting, source_northing
-		FROM source_documents
-		WHERE src_id = $1
-	`
-	h.DB.QueryRow(getCurrentQuery, srcID).Scan(&currentEasting, &currentNorthing)
-
-	// Begin transaction
-	tx, err := h.DB.Begin()
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback()
-
-	// Insert audit record for coordinate change
-	auditQuery := `
-		INSERT INTO audit_coordinate_changes (
-			src_id, old_easting, old_northing, new_easting, new_northing,
-			coordinate_source, change_reason, changed_by, client_info
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`
-
-	clientInfo := h.getClientInfo(r)
-	var oldE, oldN interface{}
-	if currentEasting.Valid {
-		oldE = currentEasting.Float64
-	}
-	if currentNorthing.Valid {
-		oldN = currentNorthing.Float64
-	}
-
-	_, err = tx.Exec(auditQuery, srcID, oldE, oldN, coordRequest.Easting, 
-		coordRequest.Northing, coordRequest.Source, coordRequest.Reason, 
-		"web_user", clientInfo)
-
-	if err != nil {
-		http.Error(w, "Audit logging failed", http.StatusInternalServerError)
-		return
-	}
-
-	// Update source coordinates
-	updateQuery := `
-		UPDATE source_documents 
-		SET source_easting = $1, source_northing = $2
-		WHERE src_id = $3
-	`
-	_, err = tx.Exec(updateQuery, coordRequest.Easting, coordRequest.Northing, srcID)
+	err = h.Stores.Records.SetCoordinates(ctx, store.CoordinateChange{
+		SrcID:      int64(srcID),
+		Easting:    coordRequest.Easting,
+		Northing:   coordRequest.Northing,
+		Source:     coordRequest.Source,
+		Reason:     coordRequest.Reason,
+		ChangedBy:  actor(r),
+		ClientInfo: h.getClientInfo(r),
+	})
 	if err != nil {
+		log.Printf("Failed to set coordinates of record %d: %v", srcID, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	if err = tx.Commit(); err != nil {
-		http.Error(w, "Transaction commit failed", http.StatusInternalServerError)
-		return
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":    "coordinates_set",
@@ -511,8 +474,12 @@ func (h *RecordsHandler) SetCoordinates(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// RejectMatch rejects all candidates for a record
+// RejectMatch rejects all candidates for a record. A record in the review queue is
+// rejected through the queue, so the caller must hold its lease.
 func (h *RecordsHandler) RejectMatch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := database.WithTimeout(r.Context())
+	defer cancel()
+
 	if !h.Config.Features.ManualOverrideEnabled {
 		http.Error(w, "Feature disabled", http.StatusForbidden)
 		return
@@ -531,52 +498,30 @@ func (h *RecordsHandler) RejectMatch(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewDecoder(r.Body).Decode(&rejectRequest)
 
-	// Get current record state for audit
-	var currentRecord Record
-	getCurrentQuery := `
-		SELECT match_status, matched_uprn
-		FROM v_enhanced_source_documents
-		WHERE src_id = $1
-	`
-	h.DB.QueryRow(getCurrentQuery, srcID).Scan(&currentRecord.MatchStatus, &currentRecord.MatchedUPRN)
-
-	// Begin transaction
-	tx, err := h.DB.Begin()
+	queued, err := h.Review.Service.Direct(ctx, int64(srcID), false, 0)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		h.Review.fail(w, err)
 		return
 	}
-	defer tx.Rollback()
-
-	// Insert audit record
-	auditQuery := `
-		INSERT INTO audit_match_decisions (
-			src_id, old_match_status, new_match_status, old_uprn, new_uprn,
-			decision_type, decision_reason, decided_by, client_info
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`
-
-	clientInfo := h.getClientInfo(r)
-	_, err = tx.Exec(auditQuery, srcID, currentRecord.MatchStatus, "UNMATCHED",
-		currentRecord.MatchedUPRN, nil, "REJECT_MATCH", 
-		rejectRequest.Reason, "web_user", clientInfo)
-
-	if err != nil {
-		http.Error(w, "Audit logging failed", http.StatusInternalServerError)
+	if queued {
+		h.Review.settle(w, r, int64(srcID), func(reviewer string) (string, error) {
+			return h.Review.Service.Reject(ctx, int64(srcID), reviewer, rejectRequest.Reason)
+		})
 		return
 	}
 
-	// Remove from match_accepted table
-	_, err = tx.Exec("DELETE FROM match_accepted WHERE src_id = $1", srcID)
+	err = h.Stores.Records.ManualReject(ctx, store.ManualDecision{
+		SrcID:      int64(srcID),
+		Reason:     rejectRequest.Reason,
+		DecidedBy:  actor(r),
+		ClientInfo: h.getClientInfo(r),
+	})
 	if err != nil {
+		log.Printf("Failed to reject the match of record %d: %v", srcID, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-
-	if err = tx.Commit(); err != nil {
-		http.Error(w, "Transaction commit failed", http.StatusInternalServerError)
-		return
-	}
+	h.refreshFacts(ctx, srcID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -599,51 +544,12 @@ func (h *RecordsHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	eventType := query.Get("type") // Optional filter by event type
 	limit := parseIntParam(query.Get("limit"), 50)
 
-	baseQuery := `
-		SELECT event_type, action, details, user_name, event_timestamp, notes
-		FROM v_record_audit_history
-		WHERE src_id = $1
-	`
-	
-	var queryArgs []interface{}
-	queryArgs = append(queryArgs, srcID)
-	argIndex := 2
-
-	if eventType != "" {
-		baseQuery += " AND event_type = $" + strconv.Itoa(argIndex)
-		queryArgs = append(queryArgs, eventType)
-		argIndex++
-	}
-
-	baseQuery += " ORDER BY event_timestamp DESC LIMIT $" + strconv.Itoa(argIndex)
-	queryArgs = append(queryArgs, limit)
-
-	rows, err := h.DB.Query(baseQuery, queryArgs...)
+	history, err := h.Stores.Records.RecordHistory(r.Context(), int64(srcID), eventType, limit)
 	if err != nil {
+		log.Printf("Failed to read history of record %d: %v", srcID, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	type HistoryEntry struct {
-		EventType      string    `json:"event_type"`
-		Action         string    `json:"action"`
-		Details        *string   `json:"details"`
-		UserName       string    `json:"user_name"`
-		EventTimestamp time.Time `json:"event_timestamp"`
-		Notes          *string   `json:"notes"`
-	}
-
-	var history []HistoryEntry
-	for rows.Next() {
-		var entry HistoryEntry
-		err := rows.Scan(&entry.EventType, &entry.Action, &entry.Details, 
-			&entry.UserName, &entry.EventTimestamp, &entry.Notes)
-		if err != nil {
-			continue
-		}
-		history = append(history, entry)
-	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(history)
@@ -677,34 +583,33 @@ func (h *RecordsHandler) AddNote(w http.ResponseWriter, r *http.Request) {
 		noteRequest.Type = "GENERAL"
 	}
 
-	query := `
-		INSERT INTO record_notes (src_id, note_type, note_text, created_by)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
-	`
-
-	var noteID int
-	var createdAt time.Time
-	err = h.DB.QueryRow(query, srcID, noteRequest.Type, noteRequest.Text, "web_user").
-		Scan(&noteID, &createdAt)
-
-	if err != nil {
+	note := store.RecordNote{SrcID: int64(srcID), Type: noteRequest.Type, Text: noteRequest.Text, CreatedBy: actor(r)}
+	if err := h.Stores.Records.AddNote(r.Context(), &note); err != nil {
+		log.Printf("Failed to add a note to record %d: %v", srcID, err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
 	response := map[string]interface{}{
-		"id":         noteID,
+		"id":         note.ID,
 		"src_id":     srcID,
 		"text":       noteRequest.Text,
 		"type":       noteRequest.Type,
-		"created_at": createdAt,
+		"created_at": note.CreatedAt,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// refreshFacts rebuilds the record's fact row so a decision shows in the CSV exports at
+// once. A failure only delays it: fact-apply picks the change up from the change log.
+func (h *RecordsHandler) refreshFacts(ctx context.Context, srcID int) {
+	if _, err := facts.Refresh(ctx, h.DB, int64(srcID)); err != nil {
+		log.Printf("Fact row for record %d left to fact-apply: %v", srcID, err)
+	}
+}
+
 // Helper method to extract client information
 func (h *RecordsHandler) getClientInfo(r *http.Request) string {
 	clientInfo := map[string]string{
@@ -713,6 +618,12 @@ func (h *RecordsHandler) getClientInfo(r *http.Request) string {
 		"x_forwarded":   r.Header.Get("X-Forwarded-For"),
 		"accept_lang":   r.Header.Get("Accept-Language"),
 	}
+	if id := auth.FromContext(r.Context()); id != nil {
+		clientInfo["auth_method"] = id.Method
+		if id.KeyID != 0 {
+			clientInfo["api_key_id"] = strconv.FormatInt(id.KeyID, 10)
+		}
+	}
 	
 	jsonBytes, _ := json.Marshal(clientInfo)
 	return string(jsonBytes)
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ehdc-llpg/internal/auth"
+	"github.com/ehdc-llpg/internal/facts"
+	"github.com/ehdc-llpg/internal/review"
+	"github.com/ehdc-llpg/internal/snapshot"
+	"github.com/ehdc-llpg/internal/store"
+)
+
+// ReviewHandler serves the shared review queue
+type ReviewHandler struct {
+	DB      *sql.DB
+	Config  *Config
+	Service *review.Service
+}
+
+// reviewRequest is the body of review actions; every field is optional
+type reviewRequest struct {
+	UPRN     string `json:"uprn"`
+	Notes    string `json:"notes"`
+	Until    string `json:"until"`     // RFC 3339, for defer
+	DeferFor string `json:"defer_for"` // e.g. 2h or 3d, for defer
+}
+
+// GetQueue returns the queue's counts, confidence bands and policy
+func (h *ReviewHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.Service.Counts(r.Context())
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	policy := h.Service.Policy()
+	bands := make([]map[string]interface{}, 0, len(review.Bands))
+	for _, b := range review.Bands {
+		bands = append(bands, map[string]interface{}{"name": b.Name, "min": b.Min, "max": b.Max})
+	}
+	h.respond(w, map[string]interface{}{
+		"counts":        counts,
+		"bands":         bands,
+		"lease_seconds": policy.Lease.Seconds(),
+		"signoff_below": policy.SignoffBelow,
+	})
+}
+
+// Claim leases the next item to the reviewer. Query parameters source_type and band
+// (low, medium, high) filter the queue; signoff=true claims accepts awaiting sign-off.
+// It responds 204 when nothing is waiting.
+func (h *ReviewHandler) Claim(w http.ResponseWriter, r *http.Request) {
+	reviewer, ok := h.reviewer(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	f := store.ReviewFilter{SourceType: q.Get("source_type"), SignOff: q.Get("signoff") == "true"}
+	if name := q.Get("band"); name != "" {
+		band, err := review.ParseBand(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f = band.Filter(f)
+	}
+
+	item, err := h.Service.Claim(r.Context(), reviewer, f)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	if item == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.respond(w, item)
+}
+
+// GetItem returns a queued document with its candidates
+func (h *ReviewHandler) GetItem(w http.ResponseWriter, r *http.Request) {
+	srcID, ok := h.srcID(w, r)
+	if !ok {
+		return
+	}
+	item, err := h.Service.Item(r.Context(), srcID)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	h.respond(w, item)
+}
+
+// Renew extends the reviewer's lease
+func (h *ReviewHandler) Renew(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, func(ctx context.Context, srcID int64, reviewer string, req reviewRequest) (string, error) {
+		return "renewed", h.Service.Renew(ctx, srcID, reviewer)
+	})
+}
+
+// Accept accepts a candidate; below the sign-off confidence it is proposed instead, and
+// on an item awaiting sign-off it confirms the proposal
+func (h *ReviewHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, func(ctx context.Context, srcID int64, reviewer string, req reviewRequest) (string, error) {
+		action, err := h.Service.Accept(ctx, srcID, reviewer, req.UPRN, req.Notes)
+		if err == nil && (action == store.ReviewAccept || action == store.ReviewConfirm) {
+			h.refreshFacts(ctx, srcID)
+		}
+		return action, err
+	})
+}
+
+// Reject rejects every candidate, or refuses a proposal awaiting sign-off
+func (h *ReviewHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, func(ctx context.Context, srcID int64, reviewer string, req reviewRequest) (string, error) {
+		return h.Service.Reject(ctx, srcID, reviewer, req.Notes)
+	})
+}
+
+// Skip gives the item back and stops it being offered to this reviewer
+func (h *ReviewHandler) Skip(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, func(ctx context.Context, srcID int64, reviewer string, req reviewRequest) (string, error) {
+		return store.ReviewSkip, h.Service.Skip(ctx, srcID, reviewer)
+	})
+}
+
+// Defer gives the item back until the body's until time, or for defer_for (default 1d)
+func (h *ReviewHandler) Defer(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, func(ctx context.Context, srcID int64, reviewer string, req reviewRequest) (string, error) {
+		until, err := deferUntil(req, time.Now())
+		if err != nil {
+			return "", err
+		}
+		return store.ReviewDefer, h.Service.Defer(ctx, srcID, reviewer, until, req.Notes)
+	})
+}
+
+// Release gives the item back unchanged
+func (h *ReviewHandler) Release(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, func(ctx context.Context, srcID int64, reviewer string, req reviewRequest) (string, error) {
+		return store.ReviewRelease, h.Service.Release(ctx, srcID, reviewer)
+	})
+}
+
+// GetStats returns per-reviewer throughput over the last since (default 7d)
+func (h *ReviewHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	window := 7 * 24 * time.Hour
+	if s := r.URL.Query().Get("since"); s != "" {
+		d, err := snapshot.ParseAge(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	stats, err := h.Service.Stats(r.Context(), time.Now().Add(-window))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	reviewers := make([]map[string]interface{}, 0, len(stats))
+	for _, s := range stats {
+		reviewers = append(reviewers, map[string]interface{}{
+			"reviewer":     s.Reviewer,
+			"actions":      s.Actions,
+			"decisions":    s.Decisions(),
+			"held_minutes": s.Held.Minutes(),
+			"per_hour":     s.PerHour(),
+			"holding":      s.Holding,
+			"last_active":  s.LastActive,
+		})
+	}
+	h.respond(w, map[string]interface{}{"since": time.Now().Add(-window), "reviewers": reviewers})
+}
+
+// act runs a review action on the item for the reviewer and reports what was recorded
+func (h *ReviewHandler) act(w http.ResponseWriter, r *http.Request,
+	fn func(ctx context.Context, srcID int64, reviewer string, req reviewRequest) (string, error)) {
+	srcID, ok := h.srcID(w, r)
+	if !ok {
+		return
+	}
+
+	var req reviewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.settle(w, r, srcID, func(reviewer string) (string, error) {
+		return fn(r.Context(), srcID, reviewer, req)
+	})
+}
+
+// settle runs a review action for the request's reviewer and reports what was recorded
+func (h *ReviewHandler) settle(w http.ResponseWriter, r *http.Request, srcID int64, fn func(reviewer string) (string, error)) {
+	reviewer, ok := h.reviewer(w, r)
+	if !ok {
+		return
+	}
+	action, err := fn(reviewer)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	h.respond(w, map[string]interface{}{
+		"status":    action,
+		"src_id":    srcID,
+		"reviewer":  reviewer,
+		"timestamp": time.Now(),
+	})
+}
+
+// deferUntil reads the defer time from the request
+func deferUntil(req reviewRequest, now time.Time) (time.Time, error) {
+	if req.Until != "" {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			return time.Time{}, errBadRequest{"until must be an RFC 3339 time"}
+		}
+		return until, nil
+	}
+	wait := 24 * time.Hour
+	if req.DeferFor != "" {
+		d, err := snapshot.ParseAge(req.DeferFor)
+		if err != nil {
+			return time.Time{}, errBadRequest{err.Error()}
+		}
+		wait = d
+	}
+	return now.Add(wait), nil
+}
+
+// errBadRequest is a request problem reported as 400
+type errBadRequest struct{ msg string }
+
+func (e errBadRequest) Error() string { return e.msg }
+
+// reviewer identifies the reviewer: the signed-in user, or with authentication disabled
+// the X-Reviewer header or reviewer parameter
+func (h *ReviewHandler) reviewer(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if id := auth.FromContext(r.Context()); id != nil && id.Method != auth.MethodAnonymous {
+		return id.Username, true
+	}
+	reviewer := r.Header.Get("X-Reviewer")
+	if reviewer == "" {
+		reviewer = r.URL.Query().Get("reviewer")
+	}
+	if reviewer == "" {
+		http.Error(w, "Reviewer required (X-Reviewer header)", http.StatusBadRequest)
+		return "", false
+	}
+	return reviewer, true
+}
+
+func (h *ReviewHandler) srcID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	srcID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return srcID, true
+}
+
+// fail maps review errors to HTTP statuses
+func (h *ReviewHandler) fail(w http.ResponseWriter, err error) {
+	var bad errBadRequest
+	switch {
+	case errors.As(err, &bad), errors.Is(err, review.ErrNotCandidate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, review.ErrOwnProposal), errors.Is(err, review.ErrSignoffRole),
+		errors.Is(err, review.ErrNeedsSignoff):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, review.ErrNotQueued):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, store.ErrLeaseLost):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Review queue error: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+	}
+}
+
+func (h *ReviewHandler) respond(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// refreshFacts rebuilds the document's fact row after an accept; see RecordsHandler
+func (h *ReviewHandler) refreshFacts(ctx context.Context, srcID int64) {
+	if _, err := facts.Refresh(ctx, h.DB, srcID); err != nil {
+		log.Printf("Fact row for record %d left to fact-apply: %v", srcID, err)
+	}
+}
@@ -1,28 +1,93 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
+
+	"github.com/ehdc-llpg/internal/auth"
+	database "github.com/ehdc-llpg/internal/db"
 )
 
-// Authentication middleware (basic implementation for development)
-func Authentication(sessionKey string) func(http.Handler) http.Handler {
+// Authenticator checks the credentials a request presents; *auth.Store implements it
+type Authenticator interface {
+	AuthenticateKey(ctx context.Context, key string) (*auth.Identity, error)
+	AuthenticateSession(ctx context.Context, token string) (*auth.Identity, error)
+}
+
+// Authentication identifies the caller from an X-API-Key (or Authorization: Bearer) header
+// or the session cookie, and rejects the request with 401 when there is neither or the
+// credential is not valid. The caller is recorded in the request context for RequireRole
+// and handlers, and named as the audit actor of the request's transactions.
+func Authentication(a Authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// For development, allow all requests through
-			// In production, implement proper session/token validation here
-			
-			// Check for API key in header (simple implementation)
-			apiKey := r.Header.Get("X-API-Key")
-			if apiKey == "" {
-				// Allow requests without API key for development
-				// In production, return 401 Unauthorized
+			var id *auth.Identity
+			var err error
+			if key := apiKey(r); key != "" {
+				id, err = a.AuthenticateKey(r.Context(), key)
+			} else if cookie, cerr := r.Cookie(auth.SessionCookie); cerr == nil && cookie.Value != "" {
+				id, err = a.AuthenticateSession(r.Context(), cookie.Value)
+			} else {
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				http.Error(w, "Invalid or expired credentials", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				log.Printf("Authentication failed: %v", err)
+				http.Error(w, "Authentication unavailable", http.StatusInternalServerError)
+				return
 			}
 
-			// Set user context for request tracking
-			// ctx := context.WithValue(r.Context(), "user", "development")
-			// r = r.WithContext(ctx)
+			next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), id)))
+		})
+	}
+}
+
+// Anonymous lets every request through as auth.Anonymous, for development with
+// WEB_AUTH=false
+func Anonymous() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), auth.Anonymous())))
+		})
+	}
+}
 
-			next.ServeHTTP(w, r)
+// RequireRole wraps a handler so only callers holding at least the role reach it
+func RequireRole(role auth.Role) func(http.HandlerFunc) http.Handler {
+	return func(next http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := auth.FromContext(r.Context())
+			if id == nil {
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if !id.Can(role) {
+				http.Error(w, "This needs the "+string(role)+" role", http.StatusForbidden)
+				return
+			}
+			next(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func withIdentity(ctx context.Context, id *auth.Identity) context.Context {
+	return database.WithActor(auth.WithIdentity(ctx, id), id.Username)
+}
+
+// apiKey returns the key from X-API-Key or an Authorization: Bearer header
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Timeout limits each request to d, answering 503 if the handler has not finished, except
+// on the named routes, which stream for as long as the client reads: export downloads and
+// server-sent events. It takes the place of the server's WriteTimeout, which would cut
+// those off.
+func Timeout(d time.Duration, streaming ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(streaming))
+	for _, name := range streaming {
+		exempt[name] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		limited := http.TimeoutHandler(next, d, "Request timed out")
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil && exempt[route.GetName()] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
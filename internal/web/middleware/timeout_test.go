@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTimeoutSparesStreamingRoutes(t *testing.T) {
+	const limit = 50 * time.Millisecond
+
+	// A download slower than the limit, written in chunks as ServeContent does
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			if _, err := io.WriteString(w, "chunk\n"); err != nil {
+				return
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			time.Sleep(limit / 2)
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/download", slow).Name("download")
+	router.HandleFunc("/stats", slow)
+	router.Use(Timeout(limit, "download"))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("download cut off: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || strings.Count(string(body), "chunk") != 5 {
+		t.Errorf("download: %d with %q, want all 5 chunks", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(server.URL + "/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("slow non-streaming route: %d, want 503", resp.StatusCode)
+	}
+}
@@ -11,8 +11,13 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
 
+	"github.com/ehdc-llpg/internal/auth"
+	database "github.com/ehdc-llpg/internal/db"
+	"github.com/ehdc-llpg/internal/export"
+	"github.com/ehdc-llpg/internal/geo"
+	"github.com/ehdc-llpg/internal/review"
+	"github.com/ehdc-llpg/internal/store"
 	"github.com/ehdc-llpg/internal/web/handlers"
 	"github.com/ehdc-llpg/internal/web/middleware"
 )
@@ -23,42 +28,58 @@ type Server struct {
 	db         *sql.DB
 	httpServer *http.Server
 	router     *mux.Router
+	exports    *export.Manager
 }
 
+// requestTimeout bounds every route except the streaming ones (see middleware.Timeout)
+const requestTimeout = 15 * time.Second
+
+// Routes that stream for as long as the client reads, so have no request timeout
+const (
+	routeExportDownload = "export-download"
+	routeUpdatesStream  = "updates-stream"
+)
+
 // NewServer creates a new web server instance
 func NewServer(config *Config) (*Server, error) {
-	// Initialize database connection
-	db, err := sql.Open("postgres", config.Database.URL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+	if config.Auth.Enabled && len(config.Auth.SessionKey) < 32 {
+		return nil, fmt.Errorf("SESSION_KEY must be at least 32 characters when WEB_AUTH is on")
 	}
 
-	// Configure database connection pool
-	db.SetMaxOpenConns(config.Database.MaxConnections)
-	db.SetMaxIdleConns(config.Database.MaxConnections / 2)
-	db.SetConnMaxLifetime(time.Hour)
-
-	// Test database connection
-	if err := db.Ping(); err != nil {
+	// Initialize database connection
+	db, err := database.Open(config.Database)
+	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	db.SetConnMaxLifetime(time.Hour)
 
-	// Create server instance
+	// Create server instance; exports run in the background and expire after Export.TTL
 	server := &Server{
 		config: config,
 		db:     db,
+		exports: export.NewManager(export.DBSource{DB: db}, export.Options{
+			Dir:     config.Export.Dir,
+			TTL:     config.Export.TTL,
+			Workers: config.Export.Workers,
+		}),
+	}
+	if config.Features.ExportEnabled {
+		if err := server.exports.Start(); err != nil {
+			db.Close()
+			return nil, err
+		}
 	}
 
 	// Setup routes
 	server.setupRoutes()
 
-	// Create HTTP server
+	// Create HTTP server; there is no WriteTimeout, which would cut off export downloads, so
+	// the router limits each request instead
 	server.httpServer = &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
-		Handler:      server.router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:        fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
+		Handler:     server.router,
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 60 * time.Second,
 	}
 
 	return server, nil
@@ -72,52 +93,100 @@ func (s *Server) setupRoutes() {
 	handlerConfig := &handlers.Config{}
 	handlerConfig.Features.ExportEnabled = s.config.Features.ExportEnabled
 	handlerConfig.Features.ManualOverrideEnabled = s.config.Features.ManualOverrideEnabled
+	handlerConfig.Geo = geo.NewTransformer()
+	fmt.Printf("Coordinate transformation: %s\n", handlerConfig.Geo.Mode())
 
 	// Create handlers with database access
-	apiHandler := &handlers.APIHandler{DB: s.db, Config: handlerConfig}
-	recordsHandler := &handlers.RecordsHandler{DB: s.db, Config: handlerConfig}
-	mapsHandler := &handlers.MapsHandler{DB: s.db, Config: handlerConfig}
-	searchHandler := &handlers.SearchHandler{DB: s.db, Config: handlerConfig}
-	exportHandler := &handlers.ExportHandler{DB: s.db, Config: handlerConfig}
-	realtimeHandler := &handlers.RealtimeHandler{DB: s.db, Config: handlerConfig}
-
-	// API routes
+	stores := store.NewPostgresStores(s.db)
+	apiHandler := &handlers.APIHandler{Stores: stores, Config: handlerConfig}
+	mapsHandler := &handlers.MapsHandler{Stores: stores, Config: handlerConfig}
+	searchHandler := &handlers.SearchHandler{Stores: stores, Config: handlerConfig}
+	exportHandler := &handlers.ExportHandler{DB: s.db, Config: handlerConfig, Jobs: s.exports}
+	realtimeHandler := &handlers.RealtimeHandler{Stores: stores, Config: handlerConfig}
+	reviewHandler := &handlers.ReviewHandler{DB: s.db, Config: handlerConfig,
+		Service: review.NewService(store.NewPostgres(s.db), review.Policy{
+			Lease:        s.config.Review.Lease,
+			SignoffBelow: s.config.Review.SignoffBelow,
+			CanSignOff: func(ctx context.Context) bool {
+				return auth.FromContext(ctx).Can(auth.RoleSeniorReviewer)
+			},
+		})}
+	recordsHandler := &handlers.RecordsHandler{Stores: stores, DB: s.db, Config: handlerConfig, Review: reviewHandler}
+
+	// Sign-in is outside the /api subrouter, so it needs no credentials
+	authStore := auth.NewStore(s.db, s.config.Auth.SessionKey)
+	authHandler := &handlers.AuthHandler{DB: s.db, Config: handlerConfig, Store: authStore,
+		SessionTTL: s.config.Auth.SessionTTL}
+	s.router.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST")
+
+	// API routes; each needs at least the role it is wrapped in
 	api := s.router.PathPrefix("/api").Subrouter()
+	viewer := middleware.RequireRole(auth.RoleViewer)
+	reviewer := middleware.RequireRole(auth.RoleReviewer)
+	senior := middleware.RequireRole(auth.RoleSeniorReviewer)
+	admin := middleware.RequireRole(auth.RoleAdmin)
+
+	// Caller and users
+	api.Handle("/auth/me", viewer(authHandler.Me)).Methods("GET")
+	api.Handle("/auth/logout", viewer(authHandler.Logout)).Methods("POST")
+	api.Handle("/admin/users", admin(authHandler.Users)).Methods("GET")
 
 	// Core data endpoints
-	api.HandleFunc("/records", recordsHandler.ListRecords).Methods("GET")
-	api.HandleFunc("/records/geojson", mapsHandler.GetGeoJSON).Methods("GET")
-	api.HandleFunc("/records/{id:[0-9]+}", recordsHandler.GetRecord).Methods("GET")
-	api.HandleFunc("/records/{id:[0-9]+}/candidates", recordsHandler.GetCandidates).Methods("GET")
+	api.Handle("/records", viewer(recordsHandler.ListRecords)).Methods("GET")
+	api.Handle("/records/geojson", viewer(mapsHandler.GetGeoJSON)).Methods("GET")
+	api.Handle("/records/{id:[0-9]+}", viewer(recordsHandler.GetRecord)).Methods("GET")
+	api.Handle("/records/{id:[0-9]+}/candidates", viewer(recordsHandler.GetCandidates)).Methods("GET")
+	api.Handle("/records/{id:[0-9]+}/explanation", viewer(recordsHandler.GetExplanation)).Methods("GET")
+
+	// Modification endpoints (if features enabled); accepts and rejects of queued records go
+	// through the review queue. Moving a record's coordinates bypasses the match, so it is
+	// for senior reviewers
+	if s.config.Features.ManualOverrideEnabled {
+		api.Handle("/records/{id:[0-9]+}/accept", reviewer(recordsHandler.AcceptMatch)).Methods("POST")
+		api.Handle("/records/{id:[0-9]+}/coordinates", senior(recordsHandler.SetCoordinates)).Methods("PUT")
+		api.Handle("/records/{id:[0-9]+}/reject", reviewer(recordsHandler.RejectMatch)).Methods("POST")
+	}
 
-	// Modification endpoints (if features enabled)
+	// Review queue: items are leased to one reviewer at a time; signing off low-confidence
+	// accepts needs senior_reviewer, which the review policy checks
+	api.Handle("/review/queue", viewer(reviewHandler.GetQueue)).Methods("GET")
+	api.Handle("/review/stats", viewer(reviewHandler.GetStats)).Methods("GET")
+	api.Handle("/review/{id:[0-9]+}", viewer(reviewHandler.GetItem)).Methods("GET")
 	if s.config.Features.ManualOverrideEnabled {
-		api.HandleFunc("/records/{id:[0-9]+}/accept", recordsHandler.AcceptMatch).Methods("POST")
-		api.HandleFunc("/records/{id:[0-9]+}/coordinates", recordsHandler.SetCoordinates).Methods("PUT")
-		api.HandleFunc("/records/{id:[0-9]+}/reject", recordsHandler.RejectMatch).Methods("POST")
+		api.Handle("/review/claim", reviewer(reviewHandler.Claim)).Methods("POST")
+		api.Handle("/review/{id:[0-9]+}/renew", reviewer(reviewHandler.Renew)).Methods("POST")
+		api.Handle("/review/{id:[0-9]+}/accept", reviewer(reviewHandler.Accept)).Methods("POST")
+		api.Handle("/review/{id:[0-9]+}/reject", reviewer(reviewHandler.Reject)).Methods("POST")
+		api.Handle("/review/{id:[0-9]+}/skip", reviewer(reviewHandler.Skip)).Methods("POST")
+		api.Handle("/review/{id:[0-9]+}/defer", reviewer(reviewHandler.Defer)).Methods("POST")
+		api.Handle("/review/{id:[0-9]+}/release", reviewer(reviewHandler.Release)).Methods("POST")
 	}
 
 	// Audit and history endpoints
-	api.HandleFunc("/records/{id:[0-9]+}/history", recordsHandler.GetHistory).Methods("GET")
-	api.HandleFunc("/records/{id:[0-9]+}/notes", recordsHandler.AddNote).Methods("POST")
+	api.Handle("/records/{id:[0-9]+}/history", viewer(recordsHandler.GetHistory)).Methods("GET")
+	api.Handle("/records/{id:[0-9]+}/notes", reviewer(recordsHandler.AddNote)).Methods("POST")
 
 	// Search endpoints
-	api.HandleFunc("/search/llpg", searchHandler.SearchLLPG).Methods("GET")
-	api.HandleFunc("/search/records", searchHandler.SearchRecords).Methods("GET")
+	api.Handle("/search/llpg", viewer(searchHandler.SearchLLPG)).Methods("GET")
+	api.Handle("/search/records", viewer(searchHandler.SearchRecords)).Methods("GET")
 
-	// Export endpoint (if enabled)
+	// Export endpoints (if enabled): POST /export queues a job, polled until it can be downloaded
 	if s.config.Features.ExportEnabled {
-		api.HandleFunc("/export", exportHandler.ExportData).Methods("POST")
+		api.Handle("/export", viewer(exportHandler.ExportData)).Methods("POST")
+		api.Handle("/exports", viewer(exportHandler.ListExports)).Methods("GET")
+		api.Handle("/exports/{id:[0-9a-f]+}", viewer(exportHandler.GetExport)).Methods("GET")
+		api.Handle("/exports/{id:[0-9a-f]+}/download", viewer(exportHandler.DownloadExport)).Methods("GET").
+			Name(routeExportDownload)
 	}
 
 	// Statistics endpoints
-	api.HandleFunc("/stats", apiHandler.GetStats).Methods("GET")
-	api.HandleFunc("/stats/viewport", apiHandler.GetViewportStats).Methods("GET")
+	api.Handle("/stats", viewer(apiHandler.GetStats)).Methods("GET")
+	api.Handle("/stats/viewport", viewer(apiHandler.GetViewportStats)).Methods("GET")
 
 	// Real-time update endpoints
-	api.HandleFunc("/updates/stream", realtimeHandler.SSEUpdates).Methods("GET")
-	api.HandleFunc("/updates/status", realtimeHandler.MatchingStatus).Methods("GET")
-	api.HandleFunc("/updates/refresh", realtimeHandler.TriggerRefresh).Methods("POST")
+	api.Handle("/updates/stream", viewer(realtimeHandler.SSEUpdates)).Methods("GET").Name(routeUpdatesStream)
+	api.Handle("/updates/status", viewer(realtimeHandler.MatchingStatus)).Methods("GET")
+	api.Handle("/updates/refresh", admin(realtimeHandler.TriggerRefresh)).Methods("POST")
 
 	// Static file serving
 	staticDir := "internal/web/static"
@@ -128,10 +197,13 @@ func (s *Server) setupRoutes() {
 	// Apply middleware
 	s.router.Use(middleware.CORS())
 	s.router.Use(middleware.RequestLogging())
-	
+	s.router.Use(middleware.Timeout(requestTimeout, routeExportDownload, routeUpdatesStream))
+
+	// Identify the caller of every API route; without authentication everyone is web_user
 	if s.config.Auth.Enabled {
-		// Apply authentication middleware to API routes only
-		api.Use(middleware.Authentication(s.config.Auth.SessionKey))
+		api.Use(middleware.Authentication(authStore))
+	} else {
+		api.Use(middleware.Anonymous())
 	}
 }
 
@@ -162,6 +234,11 @@ func (s *Server) Start() error {
 		fmt.Printf("Server shutdown error: %v\n", err)
 	}
 
+	// Stop running exports; their jobs are reported as failed
+	if s.config.Features.ExportEnabled {
+		s.exports.Stop()
+	}
+
 	// Close database connection
 	if err := s.db.Close(); err != nil {
 		fmt.Printf("Database close error: %v\n", err)
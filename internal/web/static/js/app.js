@@ -415,16 +415,21 @@ class EHDCMappingApp {
         }
     }
 
-    // Export functionality
+    // Export functionality: the server queues the export and writes the file in the
+    // background, so poll its status until it can be downloaded
     async exportCurrentView(format = 'csv') {
+        const button = document.querySelector('.export-button');
+        const label = button ? button.textContent : '';
+        const setLabel = text => { if (button) button.textContent = text; };
+        if (button) button.disabled = true;
+
         try {
-            const filters = this.filters.getAPIFilters();
             const exportData = {
                 format: format,
-                filters: filters
+                ...this.filters.getExportFilters()
             };
 
-            // Add viewport bounds if needed
+            // Limit the export to the map viewport
             const bounds = this.map.map.getBounds();
             exportData.viewport = {
                 min_lat: bounds.getSouth(),
@@ -440,28 +445,36 @@ class EHDCMappingApp {
                 },
                 body: JSON.stringify(exportData)
             });
-
             if (!response.ok) {
-                throw new Error(`Export failed: ${response.statusText}`);
+                throw new Error((await response.text()).trim() || response.statusText);
             }
 
-            const result = await response.json();
-            
-            if (result.success && result.download_url) {
-                // Create download link
-                const link = document.createElement('a');
-                link.href = result.download_url;
-                link.download = `ehdc-export-${new Date().toISOString().split('T')[0]}.${format}`;
-                link.click();
-                
-                this.showNotification(`Export completed: ${result.record_count} records`, 'success');
-            } else {
-                this.showError(result.message || 'Export failed');
+            let job = await response.json();
+            setLabel('Export queued...');
+            while (job.status === 'queued' || job.status === 'running') {
+                await new Promise(resolve => setTimeout(resolve, 2000));
+                const status = await fetch(job.status_url);
+                if (!status.ok) {
+                    throw new Error((await status.text()).trim() || status.statusText);
+                }
+                job = await status.json();
+                if (job.status === 'running') {
+                    setLabel(`Exporting... ${Math.round(job.progress * 100)}%`);
+                }
             }
-            
+
+            if (job.status !== 'done') {
+                throw new Error(job.error || `export ${job.status}`);
+            }
+            window.location.href = job.download_url;
+            this.showNotification(`Export completed: ${job.rows_written} records`, 'success');
+
         } catch (error) {
             console.error('Export error:', error);
-            this.showError('Export failed. Please try again.');
+            this.showError(`Export failed: ${error.message}`);
+        } finally {
+            setLabel(label);
+            if (button) button.disabled = false;
         }
     }
 
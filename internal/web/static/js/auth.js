@@ -0,0 +1,74 @@
+// Authentication
+// Sends the browser to the sign-in page when the API answers 401, shows who is signed in
+// in the header, and exposes the caller as Auth.ready (a promise of /api/auth/me)
+
+const Auth = {
+    user: null,
+
+    loginPage() {
+        const next = encodeURIComponent(location.pathname + location.search);
+        location.href = `/login.html?next=${next}`;
+    },
+
+    wrapFetch() {
+        const fetchAPI = window.fetch.bind(window);
+        window.fetch = async (input, init) => {
+            const response = await fetchAPI(input, init);
+            const url = typeof input === 'string' ? input : input.url;
+            if (response.status === 401 && !url.includes('/api/auth/')) {
+                this.loginPage();
+            }
+            return response;
+        };
+    },
+
+    async load() {
+        const response = await fetch('/api/auth/me');
+        if (response.status === 401) {
+            this.loginPage();
+            return null;
+        }
+        if (!response.ok) {
+            throw new Error(`Failed to load user: ${response.status}`);
+        }
+        this.user = await response.json();
+        this.showUser();
+        return this.user;
+    },
+
+    can(role) {
+        const roles = ['viewer', 'reviewer', 'senior_reviewer', 'admin'];
+        return !!this.user && roles.indexOf(this.user.role) >= roles.indexOf(role);
+    },
+
+    showUser() {
+        const header = document.querySelector('header');
+        if (!header || this.user.method === 'anonymous') {
+            return;
+        }
+        const box = document.createElement('div');
+        box.className = 'header-user';
+        const name = document.createElement('span');
+        name.textContent = `${this.user.display_name || this.user.username} (${this.user.role.replace('_', ' ')})`;
+        const signOut = document.createElement('a');
+        signOut.href = '#';
+        signOut.textContent = 'Sign out';
+        signOut.addEventListener('click', async (event) => {
+            event.preventDefault();
+            await fetch('/api/auth/logout', { method: 'POST' });
+            location.href = '/login.html';
+        });
+        box.append(name, signOut);
+        header.appendChild(box);
+    }
+};
+
+Auth.wrapFetch();
+Auth.ready = new Promise(resolve => {
+    document.addEventListener('DOMContentLoaded', () => {
+        Auth.load().then(resolve, error => {
+            console.error(error);
+            resolve(null);
+        });
+    });
+});
@@ -501,6 +501,30 @@ class FilterPanel {
         return filters;
     }
 
+    // Filters for an export; unlike the map API, exports take every selected value
+    getExportFilters() {
+        const filters = {};
+
+        if (this.filters.addressSearch.trim()) {
+            filters.address_query = this.filters.addressSearch.trim();
+        }
+        if (this.filters.sourceTypes.length < 4) {
+            filters.source_types = [...this.filters.sourceTypes];
+        }
+        if (this.filters.matchStatus.length < 3) {
+            filters.match_status = [...this.filters.matchStatus];
+        }
+        if (this.filters.addressQuality.length < 3) {
+            filters.address_quality = [...this.filters.addressQuality];
+        }
+        if (this.filters.matchScoreRange[0] > 0 || this.filters.matchScoreRange[1] < 1) {
+            filters.min_score = this.filters.matchScoreRange[0];
+            filters.max_score = this.filters.matchScoreRange[1];
+        }
+
+        return filters;
+    }
+
     updateFilterSummary() {
         const summary = document.getElementById('filter-summary');
         const activeFilters = [];
@@ -8,6 +8,7 @@ class RecordDrawer {
         this.currentRecord = null;
         this.matchCandidates = [];
         this.selectedCandidate = null;
+        this.explanation = null;
         this.isLoading = false;
         
         this.init();
@@ -48,6 +49,7 @@ class RecordDrawer {
                             <div class="drawer-tabs">
                                 <button class="tab-button active" data-tab="details">Details</button>
                                 <button class="tab-button" data-tab="candidates">Matches</button>
+                                <button class="tab-button" data-tab="explanation">Why</button>
                                 <button class="tab-button" data-tab="history">History</button>
                                 <button class="tab-button" data-tab="coordinates">Location</button>
                             </div>
@@ -171,6 +173,18 @@ class RecordDrawer {
                                 </div>
                             </div>
 
+                            <!-- Explanation Tab -->
+                            <div class="tab-content" id="tab-explanation">
+                                <div class="explanation-header">
+                                    <h3>Why This Decision</h3>
+                                </div>
+                                <div class="explanation-body" id="explanation-body">
+                                    <div class="no-explanation">
+                                        <p>Loading explanation...</p>
+                                    </div>
+                                </div>
+                            </div>
+
                             <!-- History Tab -->
                             <div class="tab-content" id="tab-history">
                                 <div class="history-header">
@@ -369,6 +383,7 @@ class RecordDrawer {
             // Load record details
             const record = await this.loadRecord(recordId);
             this.currentRecord = record;
+            this.explanation = null;
 
             // Load match candidates if record is unmatched or needs review
             if (record.match_status !== 'MATCHED') {
@@ -412,6 +427,7 @@ class RecordDrawer {
         this.currentRecord = null;
         this.matchCandidates = [];
         this.selectedCandidate = null;
+        this.explanation = null;
 
         // Reset to first tab
         this.switchTab('details');
@@ -464,6 +480,9 @@ class RecordDrawer {
         if (tabName === 'candidates' && this.matchCandidates.length === 0) {
             this.loadMatchCandidates(this.currentRecord.src_id);
         }
+        if (tabName === 'explanation' && this.currentRecord && !this.explanation) {
+            this.loadExplanation(this.currentRecord.src_id);
+        }
     }
 
     // Data loading methods
@@ -484,6 +503,25 @@ class RecordDrawer {
         return await response.json();
     }
 
+    async loadExplanation(recordId) {
+        const body = document.getElementById('explanation-body');
+        try {
+            const response = await fetch(`/api/records/${recordId}/explanation`);
+            if (response.status === 404) {
+                body.innerHTML = '<div class="no-explanation"><p>No match decision has been recorded for this record.</p></div>';
+                return;
+            }
+            if (!response.ok) {
+                throw new Error(response.statusText);
+            }
+            this.explanation = await response.json();
+            this.populateExplanation(this.explanation);
+        } catch (error) {
+            console.error('Error loading explanation:', error);
+            body.innerHTML = '<div class="no-explanation"><p>Failed to load the explanation.</p></div>';
+        }
+    }
+
     async loadDecisionHistory(recordId) {
         try {
             const response = await fetch(`/api/records/${recordId}/history?limit=100`);
@@ -627,6 +665,72 @@ class RecordDrawer {
         });
     }
 
+    populateExplanation(explanation) {
+        const body = document.getElementById('explanation-body');
+        const fmt = (value, digits = 3) => value == null ? '-' : Number(value).toFixed(digits);
+        const signed = value => (value > 0 ? '+' : '') + Number(value).toFixed(3);
+        const tags = (names, cls) => (names || [])
+            .map(name => `<span class="${cls}">${this.escape(name)}</span>`).join('') || '<span class="explanation-none">none</span>';
+
+        const summary = `
+            <div class="explanation-summary">
+                <div class="field-grid">
+                    <div class="field-item"><label>Decision</label><span class="field-value">${this.escape(explanation.decision)}</span></div>
+                    <div class="field-item"><label>Tier</label><span class="field-value">${this.escape(explanation.tier || '-')}</span></div>
+                    <div class="field-item"><label>Margin to runner-up</label><span class="field-value">${fmt(explanation.margin, 4)}</span></div>
+                    <div class="field-item"><label>Run</label><span class="field-value">${explanation.run_id || '-'}</span></div>
+                </div>
+                ${explanation.reason ? `<p class="explanation-reason">${this.escape(explanation.reason)}</p>` : ''}
+                ${explanation.source !== 'match_explanation'
+                    ? '<p class="explanation-note">Rebuilt from match results: feature contributions were not recorded for this run.</p>' : ''}
+            </div>
+        `;
+
+        const candidates = (explanation.candidates || []).map(candidate => {
+            const contributions = (candidate.contributions || []).filter(c => c.points !== 0);
+            const rows = contributions.map(c => `
+                <tr class="${c.points < 0 ? 'contribution-negative' : 'contribution-positive'}">
+                    <td>${this.escape(c.feature)}</td>
+                    <td>${fmt(c.value)}</td>
+                    <td>${fmt(c.weight, 2)}</td>
+                    <td>${signed(c.points)}</td>
+                </tr>
+            `).join('');
+
+            return `
+                <div class="explanation-candidate ${candidate.selected ? 'selected' : ''} ${candidate.suppressed_by ? 'suppressed' : ''}">
+                    <div class="candidate-header">
+                        <div class="candidate-score">
+                            <span class="score-value">#${candidate.rank} &middot; ${(candidate.score * 100).toFixed(1)}%</span>
+                            <span class="score-method">${this.escape(candidate.tier)} &middot; margin ${signed(candidate.margin)}</span>
+                        </div>
+                        ${candidate.selected ? '<span class="explanation-badge">Selected</span>' : ''}
+                        ${candidate.suppressed_by ? `<span class="explanation-badge suppressed">Rejected by ${this.escape(candidate.suppressed_by.rejected_by)}</span>` : ''}
+                    </div>
+                    <div class="candidate-address">
+                        <strong>${this.escape(candidate.address || '')}</strong>
+                        <small>UPRN: ${this.escape(candidate.uprn)}</small>
+                    </div>
+                    <div class="explanation-row"><label>Found by</label><div class="candidate-features">${tags(candidate.methods, 'feature-tag')}</div></div>
+                    <div class="explanation-row"><label>Filters passed</label><div class="candidate-features">${tags(candidate.filters, 'filter-tag')}</div></div>
+                    ${rows ? `
+                        <table class="contribution-table">
+                            <thead><tr><th>Feature</th><th>Value</th><th>Weight</th><th>Points</th></tr></thead>
+                            <tbody>${rows}</tbody>
+                        </table>` : ''}
+                </div>
+            `;
+        }).join('');
+
+        body.innerHTML = summary + (candidates || '<div class="no-explanation"><p>No candidates were found.</p></div>');
+    }
+
+    escape(value) {
+        const div = document.createElement('div');
+        div.textContent = value == null ? '' : String(value);
+        return div.innerHTML;
+    }
+
     selectCandidate(candidate, index) {
         this.selectedCandidate = candidate;
 
@@ -841,19 +945,25 @@ class RecordDrawer {
                 headers: {
                     'Content-Type': 'application/json'
                 },
+                // The server records the stored candidate's method and score
                 body: JSON.stringify({
-                    uprn: this.selectedCandidate.uprn,
-                    method: this.selectedCandidate.method,
-                    score: this.selectedCandidate.score
+                    uprn: this.selectedCandidate.uprn
                 })
             });
 
             if (response.ok) {
-                this.app.showNotification('Match accepted successfully', 'success');
+                const result = await response.json();
+                const message = result.status === 'propose'
+                    ? 'Match proposed for sign-off'
+                    : 'Match accepted successfully';
+                this.app.showNotification(message, 'success');
                 // Refresh record data
                 this.open(this.currentRecord.src_id);
                 // Trigger map refresh
                 this.app.map.loadData(this.app.filters.getAPIFilters());
+            } else {
+                // Queued records need the review lease; weak matches need a second reviewer
+                this.app.showError(await response.text());
             }
         } catch (error) {
             console.error('Error accepting match:', error);
@@ -875,6 +985,8 @@ class RecordDrawer {
                 this.app.showNotification('Matches rejected successfully', 'success');
                 this.open(this.currentRecord.src_id);
                 this.app.map.loadData(this.app.filters.getAPIFilters());
+            } else {
+                this.app.showError(await response.text());
             }
         } catch (error) {
             console.error('Error rejecting matches:', error);
@@ -0,0 +1,216 @@
+// Review Queue Page
+// Claims records from the shared review queue, renews the lease while a record is open,
+// and records accept, reject, skip, defer and release decisions
+
+class ReviewQueue {
+    constructor() {
+        this.item = null;
+        this.leaseSeconds = 900;
+        this.renewTimer = null;
+
+        this.reviewerInput = document.getElementById('reviewer');
+        this.reviewerInput.value = localStorage.getItem('reviewer') || '';
+        this.reviewerInput.addEventListener('change', () => {
+            localStorage.setItem('reviewer', this.reviewerInput.value.trim());
+        });
+        // Signed-in reviewers act as themselves; the server ignores X-Reviewer for them
+        Auth.ready.then(user => {
+            if (user && user.method !== 'anonymous') {
+                this.reviewerInput.value = user.username;
+                this.reviewerInput.disabled = true;
+            }
+        });
+
+        document.getElementById('claim').addEventListener('click', () => this.claim());
+
+        this.loadQueue();
+        this.loadStats();
+        setInterval(() => this.loadQueue(), 30000);
+    }
+
+    get reviewer() {
+        return this.reviewerInput.value.trim();
+    }
+
+    async request(method, url, body) {
+        const options = {
+            method,
+            headers: { 'X-Reviewer': this.reviewer }
+        };
+        if (body) {
+            options.headers['Content-Type'] = 'application/json';
+            options.body = JSON.stringify(body);
+        }
+        const response = await fetch(url, options);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || response.statusText);
+        }
+        return response.status === 204 ? null : response.json();
+    }
+
+    async loadQueue() {
+        try {
+            const queue = await this.request('GET', '/api/review/queue');
+            this.leaseSeconds = queue.lease_seconds;
+            const c = queue.counts;
+            document.getElementById('queue-counts').innerHTML = `
+                <span>Open</span><strong>${c.open}</strong>
+                <span>Being reviewed</span><strong>${c.claimed}</strong>
+                <span>Deferred</span><strong>${c.deferred}</strong>
+                <span>Awaiting sign-off</span><strong>${c.awaiting_signoff}</strong>
+                <span>Done</span><strong>${c.done}</strong>
+                <span>Sign-off below</span><strong>${queue.signoff_below}</strong>`;
+        } catch (error) {
+            console.error('Failed to load review queue:', error);
+        }
+    }
+
+    async loadStats() {
+        try {
+            const stats = await this.request('GET', '/api/review/stats?since=7d');
+            const rows = stats.reviewers.map(r => `
+                <tr>
+                    <td>${this.escape(r.reviewer)}</td>
+                    <td>${r.decisions}</td>
+                    <td>${r.per_hour.toFixed(1)}</td>
+                    <td>${r.holding}</td>
+                </tr>`).join('');
+            document.getElementById('reviewer-stats').innerHTML =
+                '<tr><th>Reviewer</th><th>Decisions</th><th>Per hour</th><th>Holding</th></tr>' + rows;
+        } catch (error) {
+            console.error('Failed to load reviewer stats:', error);
+        }
+    }
+
+    async claim() {
+        if (!this.reviewer) {
+            this.message('Enter your name before claiming a record', true);
+            return;
+        }
+
+        const params = new URLSearchParams();
+        const sourceType = document.getElementById('source-type').value;
+        const band = document.getElementById('band').value;
+        if (sourceType) params.set('source_type', sourceType);
+        if (band) params.set('band', band);
+        if (document.getElementById('signoff').checked) params.set('signoff', 'true');
+
+        try {
+            const item = await this.request('POST', '/api/review/claim?' + params.toString());
+            if (!item) {
+                this.show(null);
+                this.message('Nothing waiting in this queue');
+                return;
+            }
+            this.show(item);
+            this.message('');
+        } catch (error) {
+            this.message(error.message, true);
+        }
+        this.loadQueue();
+    }
+
+    show(item) {
+        this.item = item;
+        clearInterval(this.renewTimer);
+        const container = document.getElementById('review-item');
+        if (!item) {
+            container.innerHTML = '';
+            return;
+        }
+
+        // Renew at half the lease so a slow decision keeps the record
+        this.renewTimer = setInterval(() => this.renew(), this.leaseSeconds * 500);
+
+        const proposal = item.proposal ? `
+            <div class="review-proposal">
+                <strong>Awaiting sign-off:</strong> ${this.escape(item.proposal.proposed_by)} proposed
+                UPRN ${this.escape(item.proposal.uprn)} (${item.proposal.confidence.toFixed(2)})
+                ${item.proposal.reason ? '<br>' + this.escape(item.proposal.reason) : ''}
+            </div>` : '';
+
+        const candidates = item.candidates.map(c => `
+            <div class="review-candidate">
+                <div>
+                    <div><strong>${this.escape(c.uprn)}</strong> ${this.escape(c.address)}</div>
+                    <div>${this.escape(c.method)} &middot; score ${c.score.toFixed(3)} &middot; confidence ${c.confidence.toFixed(3)}</div>
+                </div>
+                <button class="review-button" data-uprn="${this.escape(c.uprn)}">Accept</button>
+            </div>`).join('');
+
+        const signoff = item.proposal
+            ? `<button class="review-button" data-uprn="${this.escape(item.proposal.uprn)}">Sign off</button>
+               <button class="review-button danger" data-action="reject">Refuse</button>`
+            : '<button class="review-button danger" data-action="reject">Reject all</button>';
+
+        container.innerHTML = `
+            <h2>Record ${item.src_id} &middot; ${this.escape(item.source_type)}</h2>
+            <div>${this.escape(item.raw_address)}</div>
+            ${proposal}
+            ${item.proposal ? '' : candidates}
+            <div class="review-actions">
+                <textarea id="review-notes" placeholder="Notes (optional)"></textarea>
+                ${signoff}
+                <button class="review-button secondary" data-action="defer">Defer 1 day</button>
+                <button class="review-button secondary" data-action="skip">Skip</button>
+                <button class="review-button secondary" data-action="release">Release</button>
+            </div>`;
+
+        container.querySelectorAll('button[data-uprn]').forEach(button => {
+            button.addEventListener('click', () => this.act('accept', { uprn: button.dataset.uprn }));
+        });
+        container.querySelectorAll('button[data-action]').forEach(button => {
+            button.addEventListener('click', () => this.act(button.dataset.action, {}));
+        });
+    }
+
+    async renew() {
+        if (!this.item) return;
+        try {
+            await this.request('POST', `/api/review/${this.item.src_id}/renew`);
+        } catch (error) {
+            this.show(null);
+            this.message('Your claim on this record lapsed: ' + error.message, true);
+        }
+    }
+
+    async act(action, body) {
+        if (!this.item) return;
+        body.notes = document.getElementById('review-notes').value.trim();
+        try {
+            const result = await this.request('POST', `/api/review/${this.item.src_id}/${action}`, body);
+            const done = {
+                accept: 'Accepted',
+                propose: 'Proposed for sign-off by a second reviewer',
+                confirm: 'Signed off',
+                refuse: 'Proposal refused; the record is back in the queue',
+                reject: 'Rejected all candidates',
+                skip: 'Skipped',
+                defer: 'Deferred',
+                release: 'Released'
+            };
+            this.show(null);
+            this.message(`Record ${result.src_id}: ${done[result.status] || result.status}`);
+        } catch (error) {
+            this.message(error.message, true);
+        }
+        this.loadQueue();
+        this.loadStats();
+    }
+
+    message(text, isError = false) {
+        const element = document.getElementById('review-message');
+        element.textContent = text;
+        element.classList.toggle('error', isError);
+    }
+
+    escape(value) {
+        const div = document.createElement('div');
+        div.textContent = value == null ? '' : String(value);
+        return div.innerHTML;
+    }
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+    window.reviewQueue = new ReviewQueue();
+});
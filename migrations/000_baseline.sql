@@ -0,0 +1,2363 @@
+-- Migration 000: Baseline Schema
+-- Purpose: Create the schema that migrations 001-042 left behind, in one idempotent script,
+--          so a fresh database can be built with migrate-up. The old scripts could not be
+--          replayed: several re-created tables without IF NOT EXISTS, two created
+--          fact_documents_lean with different columns and most populated data as they went.
+--          Seed rows for the lookup dimensions are kept; data loading is left to the ETL.
+--          Also folds in the DDL that lived outside migrations/: sql/expand_llpg_ranges.sql
+--          and the helper functions of the group consensus scripts in scripts/.
+-- Date: 2026-10-18
+--
+-- A database that already has any of 001-042 recorded gets this version recorded as a
+-- baseline instead of running it.
+-- migrate:replaces 001-042
+
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS postgis;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- ============================================================================
+-- STAGING TABLES
+-- ============================================================================
+
+-- ogc_fid,locaddress,easting,northing,lgcstatusc,bs7666uprn,bs7666usrn,landparcel,blpuclass,postal
+CREATE TABLE IF NOT EXISTS stg_ehdc_llpg (
+    ogc_fid text,
+    locaddress text,
+    easting text,
+    northing text,
+    lgcstatusc text,
+    bs7666uprn text,
+    bs7666usrn text,
+    landparcel text,
+    blpuclass text,
+    postal text,
+    loaded_at timestamptz DEFAULT now()
+);
+
+-- UPRN,X_COORDINATE,Y_COORDINATE,LATITUDE,LONGITUDE
+CREATE TABLE IF NOT EXISTS stg_os_uprn (
+    uprn text,
+    x_coordinate text,
+    y_coordinate text,
+    latitude text,
+    longitude text,
+    loaded_at timestamptz DEFAULT now()
+);
+
+-- Job Number,Filepath,Planning Application Number,Adress,Decision Date,Decision Type,Document Type,BS7666UPRN,Easting,Northing
+CREATE TABLE IF NOT EXISTS stg_decision_notices (
+    job_number text,
+    filepath text,
+    planning_application_number text,
+    adress text,  -- Note: typo preserved from source
+    decision_date text,
+    decision_type text,
+    document_type text,
+    bs7666uprn text,
+    easting text,
+    northing text,
+    loaded_at timestamptz DEFAULT now()
+);
+
+-- Job Number,Filepath,Card Code,Address,BS7666UPRN,Easting,Northing
+CREATE TABLE IF NOT EXISTS stg_land_charges (
+    job_number text,
+    filepath text,
+    card_code text,
+    address text,
+    bs7666uprn text,
+    easting text,
+    northing text,
+    loaded_at timestamptz DEFAULT now()
+);
+
+-- Job Number,Filepath,Planning Enforcement Reference Number,Address,Date,Document Type,BS7666UPRN,Easting,Northing
+CREATE TABLE IF NOT EXISTS stg_enforcement_notices (
+    job_number text,
+    filepath text,
+    planning_enforcement_reference_number text,
+    address text,
+    date text,
+    document_type text,
+    bs7666uprn text,
+    easting text,
+    northing text,
+    loaded_at timestamptz DEFAULT now()
+);
+
+-- Job Number,Filepath,Address,Date,BS7666UPRN,Easting,Northing
+CREATE TABLE IF NOT EXISTS stg_agreements (
+    job_number text,
+    filepath text,
+    address text,
+    date text,
+    bs7666uprn text,
+    easting text,
+    northing text,
+    loaded_at timestamptz DEFAULT now()
+);
+
+-- Job Number, Filepath, Address, BS7666UPRN, Easting, Northing
+CREATE TABLE IF NOT EXISTS stg_street_name_numbering (
+    job_number TEXT,
+    filepath TEXT,
+    address TEXT,
+    bs7666uprn TEXT,
+    easting TEXT,
+    northing TEXT
+);
+
+-- Job Number, Filepath, Planning Application Reference Number, Fiche Number
+CREATE TABLE IF NOT EXISTS stg_microfiche_post_1974 (
+    job_number TEXT,
+    filepath TEXT,
+    planning_application_reference TEXT,
+    fiche_number TEXT
+);
+
+CREATE TABLE IF NOT EXISTS stg_microfiche_pre_1974 (
+    job_number TEXT,
+    filepath TEXT,
+    planning_application_reference TEXT,
+    fiche_number TEXT
+);
+
+-- Job Number, Filepath, Enlargement Map Number
+CREATE TABLE IF NOT EXISTS stg_enlargement_maps (
+    job_number TEXT,
+    filepath TEXT,
+    enlargement_map_number TEXT
+);
+
+-- Job Number, Filepath, Address, BS7666UPRN, Easting, Northing
+CREATE TABLE IF NOT EXISTS stg_enl_folders (
+    job_number TEXT,
+    filepath TEXT,
+    address TEXT,
+    bs7666uprn TEXT,
+    easting TEXT,
+    northing TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_stg_street_name_numbering_job ON stg_street_name_numbering(job_number);
+CREATE INDEX IF NOT EXISTS idx_stg_street_name_numbering_uprn ON stg_street_name_numbering(bs7666uprn);
+CREATE INDEX IF NOT EXISTS idx_stg_microfiche_post_1974_job ON stg_microfiche_post_1974(job_number);
+CREATE INDEX IF NOT EXISTS idx_stg_microfiche_post_1974_ref ON stg_microfiche_post_1974(planning_application_reference);
+CREATE INDEX IF NOT EXISTS idx_stg_microfiche_pre_1974_job ON stg_microfiche_pre_1974(job_number);
+CREATE INDEX IF NOT EXISTS idx_stg_microfiche_pre_1974_ref ON stg_microfiche_pre_1974(planning_application_reference);
+CREATE INDEX IF NOT EXISTS idx_stg_enlargement_maps_job ON stg_enlargement_maps(job_number);
+CREATE INDEX IF NOT EXISTS idx_stg_enlargement_maps_num ON stg_enlargement_maps(enlargement_map_number);
+CREATE INDEX IF NOT EXISTS idx_stg_enl_folders_job ON stg_enl_folders(job_number);
+CREATE INDEX IF NOT EXISTS idx_stg_enl_folders_uprn ON stg_enl_folders(bs7666uprn);
+
+COMMENT ON TABLE stg_street_name_numbering IS 'Staging table for street name and numbering records';
+COMMENT ON TABLE stg_microfiche_post_1974 IS 'Staging table for microfiche records post-1974';
+COMMENT ON TABLE stg_microfiche_pre_1974 IS 'Staging table for microfiche records pre-1974';
+COMMENT ON TABLE stg_enlargement_maps IS 'Staging table for enlargement map records';
+COMMENT ON TABLE stg_enl_folders IS 'Staging table for ENL folder records';
+
+-- ============================================================================
+-- NORMALISED SCHEMA
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS dim_location (
+    location_id SERIAL PRIMARY KEY,
+    uprn TEXT UNIQUE,                    -- UPRN when available
+    easting NUMERIC,                     -- British National Grid X
+    northing NUMERIC,                    -- British National Grid Y
+    latitude NUMERIC,                    -- WGS84 latitude
+    longitude NUMERIC,                   -- WGS84 longitude
+    geom_27700 GEOMETRY(POINT, 27700),   -- BNG point geometry
+    geom_4326 GEOMETRY(POINT, 4326),     -- WGS84 point geometry
+    source_dataset TEXT,                 -- 'os_uprn', 'ehdc_llpg', 'derived'
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS dim_address (
+    address_id SERIAL PRIMARY KEY,
+    location_id INTEGER REFERENCES dim_location(location_id),
+    uprn TEXT,                          -- unique when present (idx_dim_address_uprn_unique)
+    full_address TEXT NOT NULL,
+    address_canonical TEXT,             -- Normalized for matching
+    usrn TEXT,                          -- Unique Street Reference Number
+    blpu_class TEXT,                    -- Basic Land and Property Unit class
+    postal_flag BOOLEAN,                -- TRUE/FALSE postal address
+    status_code TEXT,                   -- LGC status code
+    created_at TIMESTAMPTZ DEFAULT now(),
+
+    -- gopostal components
+    gopostal_processed BOOLEAN DEFAULT FALSE,
+    gopostal_house TEXT,
+    gopostal_house_number TEXT,
+    gopostal_road TEXT,
+    gopostal_suburb TEXT,
+    gopostal_city TEXT,
+    gopostal_state_district TEXT,
+    gopostal_state TEXT,
+    gopostal_postcode TEXT,
+    gopostal_country TEXT,
+    gopostal_unit TEXT,
+    gopostal_level TEXT,
+    gopostal_staircase TEXT,
+    gopostal_entrance TEXT,
+    gopostal_po_box TEXT,
+
+    -- Historic UPRNs created from source documents
+    is_historic BOOLEAN DEFAULT FALSE,
+    created_from_source BOOLEAN DEFAULT FALSE,
+    source_document_id INTEGER,
+    historic_created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS dim_document_type (
+    doc_type_id SERIAL PRIMARY KEY,
+    type_code TEXT UNIQUE,              -- 'decision', 'land_charge', etc.
+    type_name TEXT,
+    description TEXT
+);
+
+CREATE TABLE IF NOT EXISTS dim_match_method (
+    method_id SERIAL PRIMARY KEY,
+    method_code TEXT UNIQUE,            -- 'exact_uprn', 'fuzzy_text', 'spatial'
+    method_name TEXT,
+    description TEXT,
+    confidence_threshold NUMERIC(5,4)  -- Minimum confidence for auto-accept
+);
+
+CREATE TABLE IF NOT EXISTS src_document (
+    document_id SERIAL PRIMARY KEY,
+    src_id BIGINT GENERATED ALWAYS AS (document_id) STORED UNIQUE,
+    source_type TEXT,                   -- mapping source, set by the ETL import
+    doc_type_id INTEGER REFERENCES dim_document_type(doc_type_id),
+    job_number TEXT,
+    filepath TEXT,
+    external_reference TEXT,            -- Planning app number, card code, etc.
+    document_date DATE,
+    raw_address TEXT NOT NULL,
+    address_canonical TEXT,             -- Normalized for matching
+    raw_uprn TEXT,                      -- UPRN as provided in source
+    raw_easting TEXT,                   -- Coordinates as provided
+    raw_northing TEXT,
+    created_at TIMESTAMPTZ DEFAULT now(),
+
+    -- gopostal components
+    gopostal_processed BOOLEAN DEFAULT FALSE,
+    gopostal_house TEXT,
+    gopostal_house_number TEXT,
+    gopostal_road TEXT,
+    gopostal_suburb TEXT,
+    gopostal_city TEXT,
+    gopostal_state_district TEXT,
+    gopostal_state TEXT,
+    gopostal_postcode TEXT,
+    gopostal_country TEXT,
+    gopostal_unit TEXT,
+    gopostal_level TEXT,
+    gopostal_staircase TEXT,
+    gopostal_entrance TEXT,
+    gopostal_po_box TEXT,
+
+    -- Planning application grouping (20003/001 -> base 20003, sequence 001)
+    planning_app_base TEXT,
+    planning_app_sequence TEXT,
+    planning_app_group_id INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS address_match (
+    match_id SERIAL PRIMARY KEY,
+    document_id INTEGER REFERENCES src_document(document_id),
+    address_id INTEGER REFERENCES dim_address(address_id),
+    location_id INTEGER REFERENCES dim_location(location_id),
+    match_method_id INTEGER REFERENCES dim_match_method(method_id),
+    confidence_score NUMERIC(5,4),     -- 0.0000 to 1.0000
+    match_status TEXT,                  -- 'auto', 'manual', 'rejected'
+    matched_by TEXT,                    -- System or user
+    matched_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS address_normalization_rules (
+    rule_id SERIAL PRIMARY KEY,
+    pattern TEXT,
+    replacement TEXT,
+    rule_type TEXT,                     -- 'abbreviation', 'cleanup', etc.
+    priority INTEGER DEFAULT 0
+);
+
+INSERT INTO dim_document_type (type_code, type_name, description) VALUES
+('decision', 'Decision Notice', 'Planning application decision notices'),
+('land_charge', 'Land Charge', 'Land charges cards'),
+('enforcement', 'Enforcement Notice', 'Planning enforcement notices'),
+('agreement', 'Agreement', 'Planning agreements and obligations')
+ON CONFLICT (type_code) DO NOTHING;
+
+INSERT INTO dim_match_method (method_code, method_name, description, confidence_threshold) VALUES
+('exact_uprn', 'Exact UPRN Match', 'Direct UPRN match against LLPG', 1.0000),
+('exact_text', 'Exact Text Match', 'Exact canonical address match', 0.9500),
+('fuzzy_high', 'High Confidence Fuzzy', 'High confidence fuzzy text matching', 0.9000),
+('fuzzy_medium', 'Medium Confidence Fuzzy', 'Medium confidence fuzzy text matching', 0.8000),
+('fuzzy_low', 'Low Confidence Fuzzy', 'Low confidence fuzzy text matching', 0.7000),
+('spatial', 'Spatial Match', 'Coordinate-based spatial matching', 0.8500),
+('manual', 'Manual Match', 'Manually verified match', 1.0000)
+ON CONFLICT (method_code) DO NOTHING;
+
+-- Methods of the validated component matcher use fixed ids
+INSERT INTO dim_match_method (method_id, method_code, method_name, confidence_threshold) VALUES
+(20, 'postcode_house_validated', 'Postcode + House Number (Validated)', 0.95),
+(21, 'business_name_match', 'Business Name Matching', 0.80),
+(22, 'road_city_validated', 'Road + City (Validated)', 0.85),
+(23, 'fuzzy_road_validated', 'Fuzzy Road (Validated)', 0.70),
+(24, 'exact_components_validated', 'Exact Components (Validated)', 0.98)
+ON CONFLICT DO NOTHING;
+
+SELECT setval('dim_match_method_method_id_seq', GREATEST(25, (SELECT MAX(method_id) + 1 FROM dim_match_method)), false);
+
+INSERT INTO address_normalization_rules (pattern, replacement, rule_type, priority)
+SELECT v.pattern, v.replacement, v.rule_type, v.priority
+FROM (VALUES
+    ('\\bRD\\b', 'ROAD', 'abbreviation', 100),
+    ('\\bST\\b', 'STREET', 'abbreviation', 100),
+    ('\\bAVE\\b', 'AVENUE', 'abbreviation', 100),
+    ('\\bGDNS\\b', 'GARDENS', 'abbreviation', 100),
+    ('\\bCT\\b', 'COURT', 'abbreviation', 100),
+    ('\\bDR\\b', 'DRIVE', 'abbreviation', 100),
+    ('\\bLN\\b', 'LANE', 'abbreviation', 100),
+    ('\\bPL\\b', 'PLACE', 'abbreviation', 100),
+    ('\\bSQ\\b', 'SQUARE', 'abbreviation', 100),
+    ('\\bCRES\\b', 'CRESCENT', 'abbreviation', 100),
+    ('\\bTER\\b', 'TERRACE', 'abbreviation', 100),
+    ('\\bCL\\b', 'CLOSE', 'abbreviation', 100),
+    ('\\bPK\\b', 'PARK', 'abbreviation', 90),
+    ('\\bGRN\\b', 'GREEN', 'abbreviation', 90),
+    ('\\bWY\\b', 'WAY', 'abbreviation', 90)
+) AS v(pattern, replacement, rule_type, priority)
+WHERE NOT EXISTS (SELECT 1 FROM address_normalization_rules r WHERE r.pattern = v.pattern);
+
+CREATE INDEX IF NOT EXISTS idx_dim_location_uprn ON dim_location(uprn);
+CREATE INDEX IF NOT EXISTS idx_dim_location_coords ON dim_location(easting, northing);
+CREATE INDEX IF NOT EXISTS idx_dim_location_geom_27700 ON dim_location USING GIST(geom_27700);
+CREATE INDEX IF NOT EXISTS idx_dim_location_geom_4326 ON dim_location USING GIST(geom_4326);
+CREATE INDEX IF NOT EXISTS idx_dim_location_source ON dim_location(source_dataset);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dim_address_uprn_unique ON dim_address(uprn) WHERE uprn IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_dim_address_uprn ON dim_address(uprn);
+CREATE INDEX IF NOT EXISTS idx_dim_address_location_id ON dim_address(location_id);
+CREATE INDEX IF NOT EXISTS idx_dim_address_canonical_trgm ON dim_address USING GIN(address_canonical gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_dim_address_full_text ON dim_address USING GIN(to_tsvector('english', full_address));
+CREATE INDEX IF NOT EXISTS idx_dim_address_historic ON dim_address(is_historic);
+CREATE INDEX IF NOT EXISTS idx_dim_address_gopostal_house_number ON dim_address(gopostal_house_number);
+CREATE INDEX IF NOT EXISTS idx_dim_address_gopostal_road ON dim_address(gopostal_road);
+CREATE INDEX IF NOT EXISTS idx_dim_address_gopostal_city ON dim_address(gopostal_city);
+CREATE INDEX IF NOT EXISTS idx_dim_address_gopostal_postcode ON dim_address(gopostal_postcode);
+CREATE INDEX IF NOT EXISTS idx_dim_address_gopostal_processed ON dim_address(gopostal_processed);
+CREATE INDEX IF NOT EXISTS idx_dim_address_gopostal_road_trgm ON dim_address USING GIN(gopostal_road gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_dim_address_gopostal_house_trgm ON dim_address USING GIN(gopostal_house gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_src_document_doc_type ON src_document(doc_type_id);
+CREATE INDEX IF NOT EXISTS idx_src_document_canonical_trgm ON src_document USING GIN(address_canonical gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_src_document_raw_uprn ON src_document(raw_uprn);
+CREATE INDEX IF NOT EXISTS idx_src_document_date ON src_document(document_date);
+CREATE INDEX IF NOT EXISTS idx_src_document_gopostal_house_number ON src_document(gopostal_house_number);
+CREATE INDEX IF NOT EXISTS idx_src_document_gopostal_road ON src_document(gopostal_road);
+CREATE INDEX IF NOT EXISTS idx_src_document_gopostal_city ON src_document(gopostal_city);
+CREATE INDEX IF NOT EXISTS idx_src_document_gopostal_postcode ON src_document(gopostal_postcode);
+CREATE INDEX IF NOT EXISTS idx_src_document_gopostal_processed ON src_document(gopostal_processed);
+CREATE INDEX IF NOT EXISTS idx_src_document_planning_base ON src_document(planning_app_base);
+CREATE INDEX IF NOT EXISTS idx_src_document_planning_group ON src_document(planning_app_group_id);
+CREATE INDEX IF NOT EXISTS idx_src_document_planning_base_seq ON src_document(planning_app_base, planning_app_sequence);
+
+CREATE INDEX IF NOT EXISTS idx_address_match_document ON address_match(document_id);
+CREATE INDEX IF NOT EXISTS idx_address_match_address ON address_match(address_id);
+CREATE INDEX IF NOT EXISTS idx_address_match_location ON address_match(location_id);
+CREATE INDEX IF NOT EXISTS idx_address_match_method ON address_match(match_method_id);
+CREATE INDEX IF NOT EXISTS idx_address_match_status ON address_match(match_status);
+CREATE INDEX IF NOT EXISTS idx_address_match_confidence ON address_match(confidence_score);
+
+COMMENT ON COLUMN src_document.src_id IS 'Document id as referenced by the matching tables (always document_id)';
+
+-- ============================================================================
+-- BATCH MATCHING
+-- ============================================================================
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_unmatched_documents AS
+SELECT
+    s.document_id,
+    s.raw_address,
+    s.address_canonical,
+    s.raw_uprn,
+    s.raw_easting,
+    s.raw_northing,
+    dt.type_code
+FROM src_document s
+INNER JOIN dim_document_type dt ON dt.doc_type_id = s.doc_type_id
+LEFT JOIN address_match m ON m.document_id = s.document_id
+WHERE m.document_id IS NULL
+  AND s.raw_address IS NOT NULL
+  AND s.raw_address != ''
+  AND s.address_canonical IS NOT NULL
+  AND s.address_canonical != ''
+ORDER BY s.document_id;
+
+CREATE INDEX IF NOT EXISTS idx_mv_unmatched_documents_id ON mv_unmatched_documents(document_id);
+CREATE INDEX IF NOT EXISTS idx_mv_unmatched_documents_type ON mv_unmatched_documents(type_code);
+CREATE INDEX IF NOT EXISTS idx_mv_unmatched_documents_canonical ON mv_unmatched_documents USING GIN(address_canonical gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS match_statistics (
+    stat_date DATE DEFAULT CURRENT_DATE,
+    total_documents INTEGER,
+    matched_documents INTEGER,
+    unmatched_documents INTEGER,
+    match_rate NUMERIC(5,2),
+    avg_confidence NUMERIC(5,4),
+    last_updated TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS gopostal_processing_stats (
+    id SERIAL PRIMARY KEY,
+    table_name TEXT,
+    total_records INTEGER,
+    processed_records INTEGER,
+    processing_date TIMESTAMPTZ DEFAULT now(),
+    processing_time INTERVAL,
+    notes TEXT
+);
+
+-- Batch matching helpers
+CREATE OR REPLACE FUNCTION fast_address_match(
+    input_canonical TEXT,
+    input_uprn TEXT DEFAULT NULL,
+    limit_results INTEGER DEFAULT 50
+) RETURNS TABLE (
+    address_id INTEGER,
+    location_id INTEGER,
+    uprn TEXT,
+    full_address TEXT,
+    address_canonical TEXT,
+    easting NUMERIC,
+    northing NUMERIC,
+    match_score NUMERIC,
+    match_method TEXT
+) AS $$
+BEGIN
+    -- First try exact UPRN match if provided
+    IF input_uprn IS NOT NULL AND input_uprn != '' THEN
+        RETURN QUERY
+        SELECT
+            a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
+            l.easting, l.northing, 1.0::NUMERIC as match_score, 'exact_uprn' as match_method
+        FROM dim_address a
+        INNER JOIN dim_location l ON l.location_id = a.location_id
+        WHERE a.uprn = input_uprn;
+
+        -- If we found exact UPRN match, return immediately
+        IF FOUND THEN
+            RETURN;
+        END IF;
+    END IF;
+
+    -- Try exact text match
+    RETURN QUERY
+    SELECT
+        a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
+        l.easting, l.northing, 0.99::NUMERIC as match_score, 'exact_text' as match_method
+    FROM dim_address a
+    INNER JOIN dim_location l ON l.location_id = a.location_id
+    WHERE a.address_canonical = input_canonical
+    LIMIT limit_results;
+
+    -- If we have exact matches, return
+    IF FOUND THEN
+        RETURN;
+    END IF;
+
+    -- Single combined fuzzy query with multiple thresholds
+    RETURN QUERY
+    SELECT
+        a.address_id, a.location_id, a.uprn, a.full_address, a.address_canonical,
+        l.easting, l.northing,
+        similarity(input_canonical, a.address_canonical) as match_score,
+        CASE
+            WHEN similarity(input_canonical, a.address_canonical) >= 0.90 THEN 'fuzzy_high'
+            WHEN similarity(input_canonical, a.address_canonical) >= 0.80 THEN 'fuzzy_medium'
+            ELSE 'fuzzy_low'
+        END as match_method
+    FROM dim_address a
+    INNER JOIN dim_location l ON l.location_id = a.location_id
+    WHERE a.address_canonical % input_canonical
+      AND similarity(input_canonical, a.address_canonical) >= 0.70
+    ORDER BY similarity(input_canonical, a.address_canonical) DESC
+    LIMIT limit_results;
+
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION match_gopostal_components(
+    src_house_number TEXT,
+    src_road TEXT,
+    src_city TEXT,
+    src_postcode TEXT,
+    src_house TEXT,
+    src_unit TEXT,
+    limit_results INTEGER DEFAULT 50
+) RETURNS TABLE (
+    address_id INTEGER,
+    location_id INTEGER,
+    uprn TEXT,
+    full_address TEXT,
+    match_score REAL,
+    match_reason TEXT
+) AS $$
+DECLARE
+    has_postcode BOOLEAN := src_postcode IS NOT NULL AND src_postcode != '';
+    has_house_number BOOLEAN := src_house_number IS NOT NULL AND src_house_number != '';
+    has_road BOOLEAN := src_road IS NOT NULL AND src_road != '';
+    has_city BOOLEAN := src_city IS NOT NULL AND src_city != '';
+BEGIN
+    -- Strategy 1: Exact component match (highest confidence)
+    IF has_postcode AND has_house_number THEN
+        RETURN QUERY
+        SELECT
+            a.address_id, a.location_id, a.uprn, a.full_address,
+            1.0::REAL as match_score,
+            'Exact: postcode + house number' as match_reason
+        FROM dim_address a
+        WHERE a.gopostal_postcode = src_postcode
+          AND a.gopostal_house_number = src_house_number
+        LIMIT limit_results;
+
+        IF FOUND THEN RETURN; END IF;
+    END IF;
+
+    -- Strategy 2: Road + House Number + City (very high confidence)
+    IF has_road AND has_house_number AND has_city THEN
+        RETURN QUERY
+        SELECT
+            a.address_id, a.location_id, a.uprn, a.full_address,
+            0.95::REAL as match_score,
+            'Road + house number + city' as match_reason
+        FROM dim_address a
+        WHERE a.gopostal_road = src_road
+          AND a.gopostal_house_number = src_house_number
+          AND a.gopostal_city = src_city
+        LIMIT limit_results;
+
+        IF FOUND THEN RETURN; END IF;
+    END IF;
+
+    -- Strategy 3: Fuzzy road match with city (high confidence)
+    IF has_road AND has_city THEN
+        RETURN QUERY
+        SELECT
+            a.address_id, a.location_id, a.uprn, a.full_address,
+            similarity(src_road, a.gopostal_road) * 0.9 as match_score,
+            'Fuzzy road + city' as match_reason
+        FROM dim_address a
+        WHERE a.gopostal_city = src_city
+          AND a.gopostal_road % src_road
+          AND similarity(src_road, a.gopostal_road) >= 0.7
+        ORDER BY similarity(src_road, a.gopostal_road) DESC
+        LIMIT limit_results;
+
+        IF FOUND THEN RETURN; END IF;
+    END IF;
+
+    -- Strategy 4: House/Business name match (medium confidence)
+    IF src_house IS NOT NULL AND src_house != '' THEN
+        RETURN QUERY
+        SELECT
+            a.address_id, a.location_id, a.uprn, a.full_address,
+            similarity(src_house, a.gopostal_house) * 0.85 as match_score,
+            'House/business name' as match_reason
+        FROM dim_address a
+        WHERE a.gopostal_house % src_house
+          AND similarity(src_house, a.gopostal_house) >= 0.6
+        ORDER BY similarity(src_house, a.gopostal_house) DESC
+        LIMIT limit_results;
+    END IF;
+
+    -- Strategy 5: Postcode-only match (low confidence, many results)
+    IF has_postcode THEN
+        RETURN QUERY
+        SELECT
+            a.address_id, a.location_id, a.uprn, a.full_address,
+            0.5::REAL as match_score,
+            'Postcode only' as match_reason
+        FROM dim_address a
+        WHERE a.gopostal_postcode = src_postcode
+        LIMIT limit_results;
+    END IF;
+
+END;
+$$ LANGUAGE plpgsql;
+
+-- Function to simulate gopostal parsing (placeholder until real gopostal is used)
+-- This will be replaced by actual gopostal processing
+CREATE OR REPLACE FUNCTION simulate_gopostal_parse(input_address TEXT)
+RETURNS TABLE (
+    house TEXT,
+    house_number TEXT,
+    road TEXT,
+    city TEXT,
+    postcode TEXT
+) AS $$
+BEGIN
+    -- This is a simplified parser - will be replaced by gopostal
+    -- Extract postcode
+    postcode := substring(input_address from '[A-Z]{1,2}[0-9]{1,2}[A-Z]?\s*[0-9][A-Z]{2}');
+
+    -- Extract house number
+    house_number := substring(input_address from '^\d+[A-Za-z]?');
+
+    -- Extract common city names (Hampshire specific)
+    IF input_address ~* 'ALTON' THEN city := 'ALTON';
+    ELSIF input_address ~* 'PETERSFIELD' THEN city := 'PETERSFIELD';
+    ELSIF input_address ~* 'BORDON' THEN city := 'BORDON';
+    ELSIF input_address ~* 'LIPHOOK' THEN city := 'LIPHOOK';
+    ELSIF input_address ~* 'LISS' THEN city := 'LISS';
+    END IF;
+
+    -- Extract road (simplified)
+    road := regexp_replace(input_address, '^\d+[A-Za-z]?\s+', ''); -- Remove house number
+    road := regexp_replace(road, '\s+(ALTON|PETERSFIELD|BORDON|LIPHOOK|LISS).*$', '', 'i'); -- Remove city onwards
+    road := trim(road);
+
+    RETURN QUERY SELECT simulate_gopostal_parse.house,
+                        simulate_gopostal_parse.house_number,
+                        simulate_gopostal_parse.road,
+                        simulate_gopostal_parse.city,
+                        simulate_gopostal_parse.postcode;
+END;
+$$ LANGUAGE plpgsql;
+
+-- ============================================================================
+-- FACT TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS match_method (
+    method_id SERIAL PRIMARY KEY,
+    method_name VARCHAR(100) NOT NULL,
+    method_code VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO match_method (method_name, method_code, description) VALUES
+('Exact Components', 'exact_components', 'Perfect match on multiple address components'),
+('Postcode + House Number', 'postcode_house', 'Match on postcode and house number'),
+('Road + City Exact', 'road_city_exact', 'Exact match on road and city'),
+('Road + City Fuzzy', 'road_city_fuzzy', 'Fuzzy match on road and city'),
+('Fuzzy Road', 'fuzzy_road', 'Fuzzy matching on road name only'),
+('No Match', 'no_match', 'No suitable match found'),
+('Manual Review', 'manual_review', 'Requires manual verification')
+ON CONFLICT (method_code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS fact_documents (
+    -- Primary Keys
+    document_id             BIGINT PRIMARY KEY,
+    fact_id                 BIGSERIAL UNIQUE NOT NULL,
+
+    -- Source Document Information
+    original_filename       VARCHAR(255),
+    import_batch_id         INTEGER,
+    import_timestamp        TIMESTAMP WITH TIME ZONE,
+    document_type           VARCHAR(50),
+    document_status         VARCHAR(20) DEFAULT 'active',
+
+    -- Original Address Data
+    raw_address             TEXT,
+    parsed_address_line_1   VARCHAR(255),
+    parsed_address_line_2   VARCHAR(255),
+    parsed_town             VARCHAR(100),
+    parsed_county           VARCHAR(100),
+    parsed_postcode         VARCHAR(10),
+    parsed_country          VARCHAR(50),
+
+    -- Standardized Address Components (from gopostal)
+    std_house_number        VARCHAR(20),
+    std_house_name          VARCHAR(100),
+    std_road                VARCHAR(200),
+    std_suburb              VARCHAR(100),
+    std_city                VARCHAR(100),
+    std_state_district      VARCHAR(100),
+    std_state               VARCHAR(100),
+    std_postcode            VARCHAR(10),
+    std_country             VARCHAR(50),
+    std_unit                VARCHAR(50),
+
+    -- Address Matching Results
+    match_status            VARCHAR(20), -- 'matched', 'no_match', 'needs_review'
+    match_method            VARCHAR(50), -- 'exact_components', 'postcode_house', etc.
+    match_confidence        DECIMAL(5,4), -- 0.0000 to 1.0000
+    match_decision          VARCHAR(20), -- 'auto_accept', 'needs_review', 'low_confidence'
+    matched_uprn            VARCHAR(20), -- The golden UPRN if matched
+    matched_address_id      INTEGER, -- Reference to dim_address
+    matched_location_id     INTEGER, -- Reference to dim_location
+
+    -- Matched Address Information (denormalized for performance)
+    matched_full_address    TEXT,
+    matched_address_canonical TEXT,
+    matched_easting         DECIMAL(10,2),
+    matched_northing        DECIMAL(10,2),
+    matched_latitude        DECIMAL(10,8),
+    matched_longitude       DECIMAL(11,8),
+
+    -- Business Data Fields
+    property_type           VARCHAR(100),
+    property_description    TEXT,
+    planning_reference      VARCHAR(50),
+    application_date        DATE,
+    decision_date           DATE,
+    application_status      VARCHAR(50),
+    development_type        VARCHAR(100),
+
+    -- Additional Source Fields (flexible JSON for varying schemas)
+    additional_data         JSONB,
+
+    -- Data Quality Flags
+    address_quality_score   DECIMAL(3,2), -- 0.00 to 1.00
+    data_completeness_score DECIMAL(3,2), -- 0.00 to 1.00
+    validation_flags        TEXT[], -- Array of validation warnings/notes
+
+    -- Audit Information
+    created_at              TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at              TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    processed_by            VARCHAR(50) DEFAULT 'system',
+    processing_version      VARCHAR(20) DEFAULT '1.0',
+
+    -- Foreign Key Constraints
+    CONSTRAINT fk_matched_address FOREIGN KEY (matched_address_id)
+        REFERENCES dim_address(address_id),
+    CONSTRAINT fk_matched_location FOREIGN KEY (matched_location_id)
+        REFERENCES dim_location(location_id),
+
+    -- Check Constraints
+    CONSTRAINT chk_match_confidence CHECK (match_confidence >= 0.0 AND match_confidence <= 1.0),
+    CONSTRAINT chk_address_quality CHECK (address_quality_score >= 0.0 AND address_quality_score <= 1.0),
+    CONSTRAINT chk_data_completeness CHECK (data_completeness_score >= 0.0 AND data_completeness_score <= 1.0),
+    CONSTRAINT chk_match_status CHECK (match_status IN ('matched', 'no_match', 'needs_review', 'pending')),
+    CONSTRAINT chk_match_decision CHECK (match_decision IN ('auto_accept', 'needs_review', 'low_confidence', 'no_match'))
+);
+
+-- Create performance indexes
+CREATE INDEX IF NOT EXISTS idx_fact_documents_uprn ON fact_documents(matched_uprn) WHERE matched_uprn IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_match_status ON fact_documents(match_status);
+CREATE INDEX IF NOT EXISTS idx_fact_documents_postcode ON fact_documents(std_postcode) WHERE std_postcode IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_location ON fact_documents(matched_location_id) WHERE matched_location_id IS NOT NULL;
+
+-- Spatial index for geographic queries
+CREATE INDEX IF NOT EXISTS idx_fact_documents_spatial ON fact_documents USING GIST(
+    ST_Point(matched_longitude, matched_latitude)
+) WHERE matched_longitude IS NOT NULL AND matched_latitude IS NOT NULL;
+
+-- Business query patterns
+CREATE INDEX IF NOT EXISTS idx_fact_documents_planning_ref ON fact_documents(planning_reference) WHERE planning_reference IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_app_date ON fact_documents(application_date) WHERE application_date IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_property_type ON fact_documents(property_type) WHERE property_type IS NOT NULL;
+
+-- Data quality indexes
+CREATE INDEX IF NOT EXISTS idx_fact_documents_quality ON fact_documents(address_quality_score DESC, data_completeness_score DESC);
+CREATE INDEX IF NOT EXISTS idx_fact_documents_confidence ON fact_documents(match_confidence DESC) WHERE match_confidence IS NOT NULL;
+
+-- Composite indexes for common queries
+CREATE INDEX IF NOT EXISTS idx_fact_documents_status_confidence ON fact_documents(match_status, match_confidence DESC);
+CREATE INDEX IF NOT EXISTS idx_fact_documents_type_status ON fact_documents(document_type, match_status);
+
+-- Create trigger for updating updated_at timestamp
+CREATE OR REPLACE FUNCTION update_fact_documents_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tr_fact_documents_updated_at ON fact_documents;
+CREATE TRIGGER tr_fact_documents_updated_at
+    BEFORE UPDATE ON fact_documents
+    FOR EACH ROW
+    EXECUTE FUNCTION update_fact_documents_updated_at();
+
+-- Add comments for documentation
+COMMENT ON TABLE fact_documents IS 'Unified fact table containing all source documents with address matching results and UPRN associations';
+COMMENT ON COLUMN fact_documents.document_id IS 'Primary key linking to original src_document';
+COMMENT ON COLUMN fact_documents.matched_uprn IS 'UPRN from dim_address if successfully matched';
+COMMENT ON COLUMN fact_documents.match_confidence IS 'Confidence score from address matching (0.0-1.0)';
+COMMENT ON COLUMN fact_documents.address_quality_score IS 'Overall address data quality assessment';
+COMMENT ON COLUMN fact_documents.validation_flags IS 'Array of data quality issues or validation warnings';
+
+-- ============================================================================
+-- OPERATIONAL VIEWS ON fact_documents
+-- ============================================================================
+
+-- View 1: High-quality matched records (ready for production use)
+CREATE OR REPLACE VIEW vw_high_quality_matches AS
+SELECT
+    document_id,
+    matched_uprn,
+    raw_address,
+    matched_full_address,
+    match_confidence,
+    match_method,
+    planning_reference,
+    application_date,
+    property_type,
+    development_type,
+    matched_easting,
+    matched_northing,
+    matched_latitude,
+    matched_longitude,
+    address_quality_score,
+    data_completeness_score
+FROM fact_documents
+WHERE match_status = 'matched'
+  AND match_decision = 'auto_accept'
+  AND match_confidence >= 0.85
+  AND matched_uprn IS NOT NULL;
+
+COMMENT ON VIEW vw_high_quality_matches IS 'High-confidence address matches ready for automated processing';
+
+-- View 2: Records needing manual review
+CREATE OR REPLACE VIEW vw_needs_review AS
+SELECT
+    document_id,
+    raw_address,
+    matched_full_address,
+    match_confidence,
+    match_method,
+    match_decision,
+    validation_flags,
+    planning_reference,
+    property_type,
+    application_date,
+    address_quality_score,
+    data_completeness_score,
+    std_postcode,
+    std_road,
+    std_city
+FROM fact_documents
+WHERE match_decision = 'needs_review'
+   OR (match_status = 'matched' AND match_confidence BETWEEN 0.70 AND 0.94)
+ORDER BY match_confidence DESC, data_completeness_score DESC;
+
+COMMENT ON VIEW vw_needs_review IS 'Medium-confidence matches requiring manual verification';
+
+-- View 3: Unmatched addresses for investigation
+CREATE OR REPLACE VIEW vw_unmatched_addresses AS
+SELECT
+    document_id,
+    raw_address,
+    std_postcode,
+    std_road,
+    std_city,
+    std_house_number,
+    validation_flags,
+    planning_reference,
+    property_type,
+    application_date,
+    data_completeness_score,
+    address_quality_score,
+    additional_data
+FROM fact_documents
+WHERE match_status = 'no_match'
+   OR match_decision = 'no_match'
+ORDER BY data_completeness_score DESC, address_quality_score DESC;
+
+COMMENT ON VIEW vw_unmatched_addresses IS 'Addresses that could not be matched - candidates for manual investigation';
+
+-- View 4: Geographic summary by area
+CREATE OR REPLACE VIEW vw_geographic_summary AS
+SELECT
+    COALESCE(std_city, 'Unknown Area') as area,
+    COUNT(*) as total_documents,
+    COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) as matched_documents,
+    COUNT(CASE WHEN match_decision = 'auto_accept' THEN 1 END) as auto_accepted,
+    COUNT(CASE WHEN match_decision = 'needs_review' THEN 1 END) as needs_review,
+    COUNT(CASE WHEN match_status = 'no_match' THEN 1 END) as no_match,
+    ROUND(100.0 * COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) / COUNT(*), 2) as match_rate_pct,
+    ROUND(AVG(match_confidence), 3) as avg_confidence,
+    ROUND(AVG(address_quality_score), 3) as avg_address_quality,
+    ROUND(AVG(data_completeness_score), 3) as avg_data_completeness
+FROM fact_documents
+WHERE std_city IS NOT NULL
+GROUP BY std_city
+HAVING COUNT(*) >= 3
+ORDER BY match_rate_pct DESC, total_documents DESC;
+
+COMMENT ON VIEW vw_geographic_summary IS 'Address matching performance summary by geographic area';
+
+-- View 5: Data quality dashboard
+CREATE OR REPLACE VIEW vw_data_quality_dashboard AS
+SELECT
+    'Overall Statistics' as metric_category,
+    COUNT(*) as total_records,
+    COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) as with_uprn,
+    COUNT(CASE WHEN match_status = 'matched' THEN 1 END) as matched,
+    COUNT(CASE WHEN match_decision = 'auto_accept' THEN 1 END) as auto_accepted,
+    COUNT(CASE WHEN match_decision = 'needs_review' THEN 1 END) as needs_review,
+    COUNT(CASE WHEN match_status = 'no_match' THEN 1 END) as no_match,
+    ROUND(100.0 * COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) / COUNT(*), 2) as uprn_coverage_pct,
+    ROUND(AVG(address_quality_score), 3) as avg_address_quality,
+    ROUND(AVG(data_completeness_score), 3) as avg_data_completeness,
+    ROUND(AVG(match_confidence), 3) as avg_match_confidence
+FROM fact_documents
+
+UNION ALL
+
+SELECT
+    'High Quality (>= 0.85)' as metric_category,
+    COUNT(*) as total_records,
+    COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) as with_uprn,
+    COUNT(CASE WHEN match_status = 'matched' THEN 1 END) as matched,
+    COUNT(CASE WHEN match_decision = 'auto_accept' THEN 1 END) as auto_accepted,
+    COUNT(CASE WHEN match_decision = 'needs_review' THEN 1 END) as needs_review,
+    COUNT(CASE WHEN match_status = 'no_match' THEN 1 END) as no_match,
+    ROUND(100.0 * COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) / COUNT(*), 2) as uprn_coverage_pct,
+    ROUND(AVG(address_quality_score), 3) as avg_address_quality,
+    ROUND(AVG(data_completeness_score), 3) as avg_data_completeness,
+    ROUND(AVG(match_confidence), 3) as avg_match_confidence
+FROM fact_documents
+WHERE match_confidence >= 0.85
+
+UNION ALL
+
+SELECT
+    'Medium Quality (0.70-0.84)' as metric_category,
+    COUNT(*) as total_records,
+    COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) as with_uprn,
+    COUNT(CASE WHEN match_status = 'matched' THEN 1 END) as matched,
+    COUNT(CASE WHEN match_decision = 'auto_accept' THEN 1 END) as auto_accepted,
+    COUNT(CASE WHEN match_decision = 'needs_review' THEN 1 END) as needs_review,
+    COUNT(CASE WHEN match_status = 'no_match' THEN 1 END) as no_match,
+    ROUND(100.0 * COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) / COUNT(*), 2) as uprn_coverage_pct,
+    ROUND(AVG(address_quality_score), 3) as avg_address_quality,
+    ROUND(AVG(data_completeness_score), 3) as avg_data_completeness,
+    ROUND(AVG(match_confidence), 3) as avg_match_confidence
+FROM fact_documents
+WHERE match_confidence BETWEEN 0.70 AND 0.84;
+
+COMMENT ON VIEW vw_data_quality_dashboard IS 'Comprehensive data quality metrics for monitoring and reporting';
+
+-- View 6: Business intelligence summary
+CREATE OR REPLACE VIEW vw_business_intelligence AS
+SELECT
+    property_type,
+    COUNT(*) as total_applications,
+    COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) as with_address_match,
+    COUNT(CASE WHEN application_date >= CURRENT_DATE - INTERVAL '1 year' THEN 1 END) as recent_applications,
+    COUNT(CASE WHEN decision_date IS NOT NULL THEN 1 END) as with_decisions,
+    ROUND(100.0 * COUNT(CASE WHEN matched_uprn IS NOT NULL THEN 1 END) / COUNT(*), 2) as address_match_rate,
+    ROUND(AVG(match_confidence), 3) as avg_match_confidence,
+    MIN(application_date) as earliest_application,
+    MAX(application_date) as latest_application
+FROM fact_documents
+WHERE property_type IS NOT NULL
+GROUP BY property_type
+HAVING COUNT(*) >= 5
+ORDER BY total_applications DESC;
+
+COMMENT ON VIEW vw_business_intelligence IS 'Business metrics by property type for strategic analysis';
+
+-- View 7: Spatial analysis view (for GIS applications)
+CREATE OR REPLACE VIEW vw_spatial_analysis AS
+SELECT
+    document_id,
+    matched_uprn,
+    planning_reference,
+    property_type,
+    application_date,
+    match_confidence,
+    matched_easting,
+    matched_northing,
+    matched_latitude,
+    matched_longitude,
+    ST_Point(matched_longitude, matched_latitude) as geom_wgs84,
+    ST_SetSRID(ST_Point(matched_easting, matched_northing), 27700) as geom_bng
+FROM fact_documents
+WHERE matched_latitude IS NOT NULL
+  AND matched_longitude IS NOT NULL
+  AND matched_easting IS NOT NULL
+  AND matched_northing IS NOT NULL
+  AND matched_uprn IS NOT NULL;
+
+COMMENT ON VIEW vw_spatial_analysis IS 'Spatially-enabled view for GIS analysis and mapping applications';
+
+-- View 8: Audit and processing summary
+CREATE OR REPLACE VIEW vw_processing_audit AS
+SELECT
+    processing_version,
+    processed_by,
+    DATE(created_at) as processing_date,
+    COUNT(*) as records_processed,
+    COUNT(CASE WHEN match_status = 'matched' THEN 1 END) as matched_count,
+    COUNT(CASE WHEN validation_flags IS NOT NULL AND array_length(validation_flags, 1) > 0 THEN 1 END) as with_validation_issues,
+    ROUND(AVG(address_quality_score), 3) as avg_address_quality,
+    ROUND(AVG(data_completeness_score), 3) as avg_data_completeness,
+    MIN(created_at) as first_processed,
+    MAX(created_at) as last_processed
+FROM fact_documents
+GROUP BY processing_version, processed_by, DATE(created_at)
+ORDER BY processing_date DESC, processed_by;
+
+COMMENT ON VIEW vw_processing_audit IS 'Audit trail showing processing batches and quality metrics';
+
+-- View 9: Validation issues summary
+CREATE OR REPLACE VIEW vw_validation_issues AS
+SELECT
+    unnest(validation_flags) as validation_issue,
+    COUNT(*) as occurrence_count,
+    ROUND(100.0 * COUNT(*) / (SELECT COUNT(*) FROM fact_documents WHERE validation_flags IS NOT NULL), 2) as percentage_of_flagged,
+    COUNT(CASE WHEN match_status = 'matched' THEN 1 END) as matched_despite_issue,
+    COUNT(CASE WHEN match_status = 'no_match' THEN 1 END) as no_match_with_issue
+FROM fact_documents
+WHERE validation_flags IS NOT NULL
+  AND array_length(validation_flags, 1) > 0
+GROUP BY unnest(validation_flags)
+ORDER BY occurrence_count DESC;
+
+COMMENT ON VIEW vw_validation_issues IS 'Summary of data validation issues and their impact on matching';
+
+-- View 10: Match method performance
+CREATE OR REPLACE VIEW vw_match_method_performance AS
+SELECT
+    match_method,
+    COUNT(*) as total_matches,
+    ROUND(AVG(match_confidence), 4) as avg_confidence,
+    ROUND(MIN(match_confidence), 4) as min_confidence,
+    ROUND(MAX(match_confidence), 4) as max_confidence,
+    COUNT(CASE WHEN match_decision = 'auto_accept' THEN 1 END) as auto_accepted,
+    COUNT(CASE WHEN match_decision = 'needs_review' THEN 1 END) as needs_review,
+    ROUND(100.0 * COUNT(CASE WHEN match_decision = 'auto_accept' THEN 1 END) / COUNT(*), 2) as auto_accept_rate
+FROM fact_documents
+WHERE match_status = 'matched'
+  AND match_method IS NOT NULL
+GROUP BY match_method
+ORDER BY total_matches DESC;
+
+COMMENT ON VIEW vw_match_method_performance IS 'Performance analysis of different address matching methods';
+
+-- ============================================================================
+-- STAR SCHEMA DIMENSIONS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS dim_document_status (
+    document_status_id      SERIAL PRIMARY KEY,
+    status_code            VARCHAR(20) NOT NULL UNIQUE,
+    status_name            VARCHAR(50) NOT NULL,
+    status_category        VARCHAR(30),
+    is_active_status       BOOLEAN DEFAULT TRUE,
+    is_final_status        BOOLEAN DEFAULT FALSE,
+    sort_order             INTEGER,
+    created_at             TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO dim_document_status (status_code, status_name, status_category, is_active_status, is_final_status, sort_order) VALUES
+('ACTIVE', 'Active', 'Current', TRUE, FALSE, 1),
+('PENDING', 'Pending Review', 'Processing', TRUE, FALSE, 2),
+('UNDER_REVIEW', 'Under Review', 'Processing', TRUE, FALSE, 3),
+('APPROVED', 'Approved', 'Completed', FALSE, TRUE, 4),
+('REJECTED', 'Rejected', 'Completed', FALSE, TRUE, 5),
+('WITHDRAWN', 'Withdrawn', 'Completed', FALSE, TRUE, 6),
+('ARCHIVED', 'Archived', 'Historical', FALSE, TRUE, 7),
+('UNKNOWN', 'Unknown Status', 'Other', TRUE, FALSE, 99)
+ON CONFLICT (status_code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS dim_original_address (
+    original_address_id     SERIAL PRIMARY KEY,
+    raw_address            TEXT NOT NULL,
+    address_hash           VARCHAR(64) NOT NULL UNIQUE,
+
+    -- Parsed components (not used in this case)
+    address_line_1         VARCHAR(255),
+    address_line_2         VARCHAR(255),
+    town                   VARCHAR(100),
+    county                 VARCHAR(100),
+    postcode               VARCHAR(20),
+    country                VARCHAR(50),
+
+    -- Standardized components (from gopostal) - increased sizes based on actual data
+    std_house_number       VARCHAR(50),  -- Was 20, now 50
+    std_house_name         VARCHAR(150), -- Was 100, now 150
+    std_road               VARCHAR(300), -- Was 200, now 300
+    std_suburb             VARCHAR(150), -- Was 100, now 150
+    std_city               VARCHAR(150), -- Was 100, now 150
+    std_state_district     VARCHAR(150), -- Was 100, now 150
+    std_state              VARCHAR(100),
+    std_postcode           VARCHAR(20),  -- Was 10, now 20
+    std_country            VARCHAR(50),
+    std_unit               VARCHAR(100), -- Was 50, now 100
+
+    -- Quality metrics
+    address_quality_score  DECIMAL(3,2),
+    component_completeness DECIMAL(3,2),
+    gopostal_processed    BOOLEAN DEFAULT FALSE,
+
+    -- Usage tracking
+    usage_count           INTEGER DEFAULT 0,
+    first_seen            TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used             TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    -- Audit
+    created_at            TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at            TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_dim_original_address_hash ON dim_original_address(address_hash);
+CREATE INDEX IF NOT EXISTS idx_dim_original_address_postcode ON dim_original_address(std_postcode) WHERE std_postcode IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_dim_original_address_road_city ON dim_original_address(std_road, std_city) WHERE std_road IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_dim_original_address_usage ON dim_original_address(usage_count DESC);
+
+CREATE TABLE IF NOT EXISTS dim_match_decision (
+    match_decision_id      SERIAL PRIMARY KEY,
+    decision_code         VARCHAR(20) NOT NULL UNIQUE,
+    decision_name         VARCHAR(50) NOT NULL,
+    auto_process          BOOLEAN DEFAULT FALSE,
+    requires_review       BOOLEAN DEFAULT FALSE,
+    confidence_min        DECIMAL(3,2),
+    confidence_max        DECIMAL(3,2),
+    description          TEXT,
+    created_at           TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO dim_match_decision (decision_code, decision_name, auto_process, requires_review, confidence_min, confidence_max, description) VALUES
+('AUTO_ACCEPT', 'Auto Accept', TRUE, FALSE, 0.85, 1.00, 'High confidence match - auto process'),
+('NEEDS_REVIEW', 'Needs Review', FALSE, TRUE, 0.50, 0.84, 'Medium confidence - requires manual review'),
+('LOW_CONFIDENCE', 'Low Confidence', FALSE, TRUE, 0.20, 0.49, 'Low confidence match - manual verification needed'),
+('NO_MATCH', 'No Match', FALSE, FALSE, 0.00, 0.19, 'No suitable match found'),
+('MANUAL_OVERRIDE', 'Manual Override', TRUE, FALSE, 0.00, 1.00, 'Manually verified and overridden')
+ON CONFLICT (decision_code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS dim_property_type (
+    property_type_id      SERIAL PRIMARY KEY,
+    property_code        VARCHAR(20) NOT NULL UNIQUE,
+    property_name        VARCHAR(100) NOT NULL,
+    property_category    VARCHAR(50),
+    use_class           VARCHAR(10),
+    description         TEXT,
+    is_residential      BOOLEAN DEFAULT FALSE,
+    is_commercial       BOOLEAN DEFAULT FALSE,
+    sort_order          INTEGER,
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO dim_property_type (property_code, property_name, property_category, use_class, is_residential, is_commercial, sort_order) VALUES
+('HOUSE', 'House', 'Residential', 'C3', TRUE, FALSE, 1),
+('FLAT', 'Flat/Apartment', 'Residential', 'C3', TRUE, FALSE, 2),
+('BUNGALOW', 'Bungalow', 'Residential', 'C3', TRUE, FALSE, 3),
+('MAISONETTE', 'Maisonette', 'Residential', 'C3', TRUE, FALSE, 4),
+('OFFICE', 'Office Building', 'Commercial', 'B1', FALSE, TRUE, 10),
+('RETAIL', 'Retail Unit', 'Commercial', 'A1', FALSE, TRUE, 11),
+('RESTAURANT', 'Restaurant/Cafe', 'Commercial', 'A3', FALSE, TRUE, 12),
+('INDUSTRIAL', 'Industrial Unit', 'Industrial', 'B2', FALSE, TRUE, 20),
+('WAREHOUSE', 'Warehouse', 'Industrial', 'B8', FALSE, TRUE, 21),
+('MIXED_USE', 'Mixed Use', 'Mixed', 'MIXED', FALSE, FALSE, 30),
+('OTHER', 'Other/Unspecified', 'Other', 'OTHER', FALSE, FALSE, 99),
+('UNKNOWN', 'Unknown Type', 'Unknown', NULL, FALSE, FALSE, 100)
+ON CONFLICT (property_code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS dim_application_status (
+    application_status_id  SERIAL PRIMARY KEY,
+    status_code           VARCHAR(20) NOT NULL UNIQUE,
+    status_name           VARCHAR(50) NOT NULL,
+    status_category       VARCHAR(30),
+    is_final_status       BOOLEAN DEFAULT FALSE,
+    days_typical_duration INTEGER,
+    sort_order            INTEGER,
+    created_at            TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO dim_application_status (status_code, status_name, status_category, is_final_status, days_typical_duration, sort_order) VALUES
+('SUBMITTED', 'Submitted', 'Initial', FALSE, 0, 1),
+('VALIDATED', 'Validated', 'Processing', FALSE, 7, 2),
+('CONSULTEE', 'Out for Consultation', 'Processing', FALSE, 21, 3),
+('ASSESSMENT', 'Under Assessment', 'Processing', FALSE, 42, 4),
+('COMMITTEE', 'Committee Decision', 'Decision', FALSE, 14, 5),
+('APPROVED', 'Approved', 'Final', TRUE, NULL, 10),
+('REFUSED', 'Refused', 'Final', TRUE, NULL, 11),
+('WITHDRAWN', 'Withdrawn', 'Final', TRUE, NULL, 12),
+('INVALID', 'Invalid', 'Final', TRUE, NULL, 13),
+('UNKNOWN', 'Unknown Status', 'Other', FALSE, NULL, 99)
+ON CONFLICT (status_code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS dim_development_type (
+    development_type_id   SERIAL PRIMARY KEY,
+    development_code     VARCHAR(20) NOT NULL UNIQUE,
+    development_name     VARCHAR(100) NOT NULL,
+    development_category VARCHAR(50),
+    impact_level        VARCHAR(20),
+    requires_eia        BOOLEAN DEFAULT FALSE,
+    fee_category        VARCHAR(20),
+    description         TEXT,
+    sort_order          INTEGER,
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO dim_development_type (development_code, development_name, development_category, impact_level, requires_eia, sort_order) VALUES
+('HOUSEHOLDER', 'Householder Extension', 'Residential', 'Minor', FALSE, 1),
+('NEW_DWELLING', 'New Dwelling', 'Residential', 'Major', FALSE, 2),
+('SUBDIVISION', 'Plot Subdivision', 'Residential', 'Minor', FALSE, 3),
+('CHANGE_USE', 'Change of Use', 'Commercial', 'Major', FALSE, 10),
+('NEW_COMMERCIAL', 'New Commercial Building', 'Commercial', 'Major', FALSE, 11),
+('INDUSTRIAL', 'Industrial Development', 'Industrial', 'Major', TRUE, 20),
+('INFRASTRUCTURE', 'Infrastructure', 'Infrastructure', 'Significant', TRUE, 30),
+('DEMOLITION', 'Demolition', 'Other', 'Minor', FALSE, 40),
+('LISTED_BUILDING', 'Listed Building Works', 'Heritage', 'Major', FALSE, 50),
+('OTHER', 'Other Development', 'Other', 'Minor', FALSE, 90),
+('UNKNOWN', 'Unknown Type', 'Unknown', 'Minor', FALSE, 99)
+ON CONFLICT (development_code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS dim_date (
+    date_id              INTEGER PRIMARY KEY,
+    date_actual          DATE NOT NULL UNIQUE,
+    day_name            VARCHAR(10),
+    day_of_week         INTEGER,
+    day_of_month        INTEGER,
+    day_of_year         INTEGER,
+    week_of_year        INTEGER,
+    month_name          VARCHAR(10),
+    month_number        INTEGER,
+    quarter             INTEGER,
+    year                INTEGER,
+    is_weekend          BOOLEAN,
+    is_holiday          BOOLEAN DEFAULT FALSE,
+    financial_year      INTEGER,
+    financial_quarter   INTEGER
+);
+
+-- Populate dim_date with a range of dates (2020-2030)
+INSERT INTO dim_date (
+    date_id, date_actual, day_name, day_of_week, day_of_month, day_of_year,
+    week_of_year, month_name, month_number, quarter, year, is_weekend,
+    financial_year, financial_quarter
+)
+SELECT
+    TO_CHAR(d, 'YYYYMMDD')::INTEGER as date_id,
+    d as date_actual,
+    TO_CHAR(d, 'Day') as day_name,
+    EXTRACT(DOW FROM d) as day_of_week,
+    EXTRACT(DAY FROM d) as day_of_month,
+    EXTRACT(DOY FROM d) as day_of_year,
+    EXTRACT(WEEK FROM d) as week_of_year,
+    TO_CHAR(d, 'Month') as month_name,
+    EXTRACT(MONTH FROM d) as month_number,
+    EXTRACT(QUARTER FROM d) as quarter,
+    EXTRACT(YEAR FROM d) as year,
+    EXTRACT(DOW FROM d) IN (0,6) as is_weekend,
+    CASE
+        WHEN EXTRACT(MONTH FROM d) >= 4 THEN EXTRACT(YEAR FROM d)
+        ELSE EXTRACT(YEAR FROM d) - 1
+    END as financial_year,
+    CASE
+        WHEN EXTRACT(MONTH FROM d) BETWEEN 4 AND 6 THEN 1
+        WHEN EXTRACT(MONTH FROM d) BETWEEN 7 AND 9 THEN 2
+        WHEN EXTRACT(MONTH FROM d) BETWEEN 10 AND 12 THEN 3
+        ELSE 4
+    END as financial_quarter
+FROM generate_series('2020-01-01'::DATE, '2030-12-31'::DATE, '1 day') d
+ON CONFLICT (date_id) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_dim_document_status_code ON dim_document_status(status_code);
+CREATE INDEX IF NOT EXISTS idx_dim_match_decision_code ON dim_match_decision(decision_code);
+CREATE INDEX IF NOT EXISTS idx_dim_property_type_code ON dim_property_type(property_code);
+CREATE INDEX IF NOT EXISTS idx_dim_application_status_code ON dim_application_status(status_code);
+CREATE INDEX IF NOT EXISTS idx_dim_development_type_code ON dim_development_type(development_code);
+
+COMMENT ON TABLE dim_original_address IS 'Dimension table for original addresses from source documents - deduplicated';
+COMMENT ON TABLE dim_match_decision IS 'Dimension table for match confidence decisions';
+COMMENT ON TABLE dim_date IS 'Date dimension with financial year calculations for UK public sector';
+
+CREATE OR REPLACE FUNCTION create_address_hash(address_text TEXT)
+RETURNS VARCHAR(64) AS $$
+BEGIN
+    RETURN MD5(LOWER(TRIM(COALESCE(address_text, ''))));
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION date_to_date_id(input_date DATE)
+RETURNS INTEGER AS $$
+BEGIN
+    IF input_date IS NULL THEN
+        RETURN NULL;
+    END IF;
+    RETURN TO_CHAR(input_date, 'YYYYMMDD')::INTEGER;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- ============================================================================
+-- LEAN FACT TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS fact_documents_lean (
+    -- Fact table surrogate key
+    fact_id                    BIGSERIAL PRIMARY KEY,
+
+    -- Business key
+    document_id               BIGINT NOT NULL UNIQUE,
+
+    -- Dimension foreign keys (using existing column names)
+    doc_type_id               INTEGER REFERENCES dim_document_type(doc_type_id),
+    document_status_id        INTEGER REFERENCES dim_document_status(document_status_id),
+    original_address_id       INTEGER REFERENCES dim_original_address(original_address_id),
+    matched_address_id        INTEGER REFERENCES dim_address(address_id), -- Existing LLPG
+    matched_location_id       INTEGER REFERENCES dim_location(location_id), -- Existing
+    match_method_id           INTEGER REFERENCES dim_match_method(method_id),
+    match_decision_id         INTEGER REFERENCES dim_match_decision(match_decision_id),
+    property_type_id          INTEGER REFERENCES dim_property_type(property_type_id),
+    application_status_id     INTEGER REFERENCES dim_application_status(application_status_id),
+    development_type_id       INTEGER REFERENCES dim_development_type(development_type_id),
+
+    -- Date dimensions (using date_id from dim_date)
+    application_date_id       INTEGER REFERENCES dim_date(date_id),
+    decision_date_id          INTEGER REFERENCES dim_date(date_id),
+    import_date_id            INTEGER REFERENCES dim_date(date_id),
+
+    -- Measures (facts/metrics) - the actual numerical data
+    match_confidence_score    DECIMAL(5,4), -- 0.0000 to 1.0000
+    address_quality_score     DECIMAL(3,2), -- 0.00 to 1.00
+    data_completeness_score   DECIMAL(3,2), -- 0.00 to 1.00
+    processing_time_ms        INTEGER,      -- Time taken to process this record
+
+    -- Business measures
+    application_fee           DECIMAL(10,2),
+    estimated_value          DECIMAL(12,2),
+    floor_area_sqm           DECIMAL(10,2),
+
+    -- Technical identifiers (keep minimal business keys)
+    import_batch_id          INTEGER,
+    planning_reference       VARCHAR(50), -- Keep as it's a true business identifier
+
+    -- Boolean measures (computed flags)
+    is_matched               BOOLEAN GENERATED ALWAYS AS (matched_address_id IS NOT NULL) STORED,
+    is_auto_processed        BOOLEAN DEFAULT FALSE,
+    has_validation_issues    BOOLEAN DEFAULT FALSE,
+    is_high_confidence       BOOLEAN GENERATED ALWAYS AS (match_confidence_score >= 0.85) STORED,
+
+    -- Minimal flexible data (use very sparingly)
+    additional_measures      JSONB,
+
+    -- Audit measures
+    created_at              TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at              TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    processing_version      VARCHAR(20) DEFAULT '1.0'
+);
+
+-- Create indexes on the fact table for performance
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_document_id ON fact_documents_lean(document_id);
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_original_address ON fact_documents_lean(original_address_id) WHERE original_address_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_matched_address ON fact_documents_lean(matched_address_id) WHERE matched_address_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_application_date ON fact_documents_lean(application_date_id) WHERE application_date_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_match_confidence ON fact_documents_lean(match_confidence_score) WHERE match_confidence_score IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_import_batch ON fact_documents_lean(import_batch_id) WHERE import_batch_id IS NOT NULL;
+
+-- Composite indexes for common query patterns
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_type_status ON fact_documents_lean(doc_type_id, document_status_id) WHERE doc_type_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_match_decision ON fact_documents_lean(match_method_id, match_decision_id) WHERE match_method_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_property_development ON fact_documents_lean(property_type_id, development_type_id) WHERE property_type_id IS NOT NULL;
+
+-- Index on boolean flags for filtering
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_matched ON fact_documents_lean(is_matched);
+CREATE INDEX IF NOT EXISTS idx_fact_documents_lean_high_confidence ON fact_documents_lean(is_high_confidence);
+
+-- Update trigger to maintain updated_at timestamp
+CREATE OR REPLACE FUNCTION update_fact_documents_lean_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tr_fact_documents_lean_updated_at ON fact_documents_lean;
+CREATE TRIGGER tr_fact_documents_lean_updated_at
+    BEFORE UPDATE ON fact_documents_lean
+    FOR EACH ROW
+    EXECUTE FUNCTION update_fact_documents_lean_updated_at();
+
+-- Add table comments
+COMMENT ON TABLE fact_documents_lean IS 'Lean fact table for documents with foreign key references to dimension tables';
+COMMENT ON COLUMN fact_documents_lean.fact_id IS 'Surrogate key for the fact table';
+COMMENT ON COLUMN fact_documents_lean.document_id IS 'Business key from source system';
+COMMENT ON COLUMN fact_documents_lean.match_confidence_score IS 'Address matching confidence (0.0000-1.0000)';
+COMMENT ON COLUMN fact_documents_lean.address_quality_score IS 'Overall address data quality (0.00-1.00)';
+COMMENT ON COLUMN fact_documents_lean.data_completeness_score IS 'Completeness of record data (0.00-1.00)';
+COMMENT ON COLUMN fact_documents_lean.is_matched IS 'Computed: TRUE if matched_address_id is not null';
+COMMENT ON COLUMN fact_documents_lean.is_high_confidence IS 'Computed: TRUE if match_confidence_score >= 0.85';
+
+-- ============================================================================
+-- HISTORIC UPRNS
+-- ============================================================================
+
+-- Create a function to create historic UPRN records
+CREATE OR REPLACE FUNCTION create_historic_uprn_record(
+    p_uprn TEXT,
+    p_full_address TEXT,
+    p_document_id INTEGER
+) RETURNS INTEGER AS $$
+DECLARE
+    v_address_id INTEGER;
+    v_location_id INTEGER;
+BEGIN
+    -- Check if UPRN already exists
+    SELECT address_id INTO v_address_id
+    FROM dim_address
+    WHERE uprn = p_uprn;
+
+    IF v_address_id IS NOT NULL THEN
+        -- UPRN already exists, return existing ID
+        RETURN v_address_id;
+    END IF;
+
+    -- Create a default location for historic records (0,0 coordinates)
+    INSERT INTO dim_location (easting, northing, latitude, longitude)
+    VALUES (0, 0, 0, 0)
+    RETURNING location_id INTO v_location_id;
+
+    -- Create the historic address record
+    INSERT INTO dim_address (
+        location_id,
+        uprn,
+        full_address,
+        address_canonical,
+        is_historic,
+        created_from_source,
+        source_document_id,
+        historic_created_at,
+        created_at
+    ) VALUES (
+        v_location_id,
+        p_uprn,
+        p_full_address,
+        LOWER(REGEXP_REPLACE(p_full_address, '[^a-zA-Z0-9 ]', '', 'g')), -- Simple canonicalization
+        TRUE,
+        TRUE,
+        p_document_id,
+        NOW(),
+        NOW()
+    )
+    RETURNING address_id INTO v_address_id;
+
+    RETURN v_address_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create view for historic UPRNs that need to be created
+CREATE OR REPLACE VIEW vw_missing_uprns AS
+SELECT DISTINCT
+    sd.raw_uprn,
+    sd.document_id,
+    sd.raw_address,
+    NOT EXISTS(SELECT 1 FROM dim_address WHERE uprn = sd.raw_uprn) as needs_creation
+FROM src_document sd
+WHERE sd.raw_uprn IS NOT NULL
+  AND sd.raw_uprn != ''
+  AND NOT EXISTS(SELECT 1 FROM dim_address WHERE uprn = sd.raw_uprn)
+ORDER BY sd.document_id;
+
+-- Add comment explaining historic records
+COMMENT ON COLUMN dim_address.is_historic IS
+'TRUE if this address was created from a source document UPRN that did not exist in the original LLPG data';
+
+COMMENT ON COLUMN dim_address.created_from_source IS
+'TRUE if this record was created from a source document rather than imported from LLPG';
+
+COMMENT ON COLUMN dim_address.source_document_id IS
+'The document_id from src_document that caused this historic record to be created';
+
+-- ============================================================================
+-- LEAN VIEWS AND CSV EXPORTS
+-- ============================================================================
+
+-- High Quality Matches View (Updated)
+CREATE OR REPLACE VIEW vw_high_quality_matches_lean AS
+SELECT
+    f.document_id,
+    dt.type_name as document_type,
+    oa.raw_address as original_address,
+    da.full_address as matched_address,
+    da.uprn,
+    f.match_confidence_score,
+    mm.method_name as match_method,
+    md.decision_name as match_decision,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.is_auto_processed,
+    f.created_at as matched_at
+FROM fact_documents_lean f
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+INNER JOIN dim_original_address oa ON f.original_address_id = oa.original_address_id
+INNER JOIN dim_address da ON f.matched_address_id = da.address_id
+INNER JOIN dim_location dl ON f.matched_location_id = dl.location_id
+LEFT JOIN dim_match_method mm ON f.match_method_id = mm.method_id
+LEFT JOIN dim_match_decision md ON f.match_decision_id = md.match_decision_id
+WHERE f.match_confidence_score >= 0.85
+   OR f.is_high_confidence = true;
+
+-- Needs Review View (Updated)
+CREATE OR REPLACE VIEW vw_needs_review_lean AS
+SELECT
+    f.document_id,
+    dt.type_name as document_type,
+    oa.raw_address as original_address,
+    da.full_address as suggested_match,
+    da.uprn as suggested_uprn,
+    f.match_confidence_score,
+    mm.method_name as match_method,
+    md.decision_name as current_decision,
+    CASE
+        WHEN f.match_confidence_score < 0.5 THEN 'Low confidence'
+        WHEN f.match_confidence_score < 0.7 THEN 'Medium confidence'
+        WHEN md.decision_code = 'needs_review' THEN 'Flagged for review'
+        ELSE 'Other'
+    END as review_reason,
+    f.created_at as processed_at
+FROM fact_documents_lean f
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+INNER JOIN dim_original_address oa ON f.original_address_id = oa.original_address_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_match_method mm ON f.match_method_id = mm.method_id
+LEFT JOIN dim_match_decision md ON f.match_decision_id = md.match_decision_id
+WHERE (f.match_confidence_score BETWEEN 0.3 AND 0.85 AND f.matched_address_id IS NOT NULL)
+   OR md.decision_code = 'needs_review'
+   OR f.has_validation_issues = true;
+
+-- Match Method Performance View (Updated)
+CREATE OR REPLACE VIEW vw_match_method_performance_lean AS
+SELECT
+    mm.method_name,
+    mm.method_code,
+    COUNT(*) as total_matches,
+    ROUND(AVG(f.match_confidence_score), 3) as avg_confidence,
+    ROUND(MIN(f.match_confidence_score), 3) as min_confidence,
+    ROUND(MAX(f.match_confidence_score), 3) as max_confidence,
+    COUNT(*) FILTER (WHERE f.match_confidence_score >= 0.85) as high_confidence_count,
+    COUNT(*) FILTER (WHERE f.match_confidence_score < 0.5) as low_confidence_count,
+    COUNT(*) FILTER (WHERE f.is_auto_processed = true) as auto_processed_count,
+    ROUND(100.0 * COUNT(*) FILTER (WHERE f.is_auto_processed = true) / COUNT(*), 1) as auto_process_rate
+FROM fact_documents_lean f
+INNER JOIN dim_match_method mm ON f.match_method_id = mm.method_id
+WHERE f.matched_address_id IS NOT NULL
+GROUP BY mm.method_name, mm.method_code
+ORDER BY total_matches DESC;
+
+-- Data Quality Dashboard View (Updated)
+CREATE OR REPLACE VIEW vw_data_quality_dashboard_lean AS
+SELECT
+    dt.type_name as document_type,
+    COUNT(*) as total_documents,
+    COUNT(f.matched_address_id) as matched_documents,
+    ROUND(100.0 * COUNT(f.matched_address_id) / NULLIF(COUNT(*), 0), 1) as match_rate,
+    ROUND(AVG(f.match_confidence_score), 3) as avg_confidence,
+    COUNT(*) FILTER (WHERE f.match_confidence_score >= 0.85) as high_confidence_matches,
+    COUNT(*) FILTER (WHERE f.match_confidence_score < 0.5 AND f.matched_address_id IS NOT NULL) as low_confidence_matches,
+    COUNT(*) FILTER (WHERE f.has_validation_issues = true) as validation_issues,
+    COUNT(*) FILTER (WHERE f.is_auto_processed = true) as auto_processed,
+    COUNT(*) FILTER (WHERE f.matched_address_id IS NULL) as unmatched
+FROM fact_documents_lean f
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+GROUP BY dt.type_name
+ORDER BY total_documents DESC;
+
+-- Complete Documents View (Updated)
+CREATE OR REPLACE VIEW vw_documents_complete AS
+SELECT
+    f.document_id,
+    f.fact_id,
+    dt.type_name as document_type,
+    ds.status_name as document_status,
+    oa.raw_address as original_address,
+    da.full_address as matched_address,
+    da.uprn,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.match_confidence_score,
+    mm.method_name as match_method,
+    md.decision_name as match_decision,
+    f.is_matched,
+    f.is_auto_processed,
+    f.is_high_confidence,
+    f.has_validation_issues,
+    f.created_at,
+    f.updated_at
+FROM fact_documents_lean f
+LEFT JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+LEFT JOIN dim_document_status ds ON f.document_status_id = ds.document_status_id
+LEFT JOIN dim_original_address oa ON f.original_address_id = oa.original_address_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_location dl ON f.matched_location_id = dl.location_id
+LEFT JOIN dim_match_method mm ON f.match_method_id = mm.method_id
+LEFT JOIN dim_match_decision md ON f.match_decision_id = md.match_decision_id;
+
+-- ============================================================================
+-- NEW: CSV RECONSTRUCTION VIEWS
+-- ============================================================================
+
+-- CSV Export: Decision Notices
+CREATE OR REPLACE VIEW vw_csv_export_decision_notices AS
+SELECT
+    s.document_id,
+    s.external_reference as planning_app_no,
+    s.document_date,
+    s.raw_address as address,
+    s.raw_uprn as uprn,
+    da.full_address as matched_llpg_address,
+    da.uprn as matched_llpg_uprn,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.match_confidence_score,
+    CASE
+        WHEN f.matched_address_id IS NOT NULL THEN 'Matched'
+        ELSE 'Unmatched'
+    END as match_status
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_location dl ON f.matched_location_id = dl.location_id
+WHERE dt.type_name = 'Decision Notice'
+ORDER BY s.document_date DESC, s.external_reference;
+
+-- CSV Export: Land Charges
+CREATE OR REPLACE VIEW vw_csv_export_land_charges AS
+SELECT
+    s.document_id,
+    s.external_reference as planning_app_no,
+    s.document_date,
+    s.raw_address as address,
+    s.raw_uprn as uprn,
+    da.full_address as matched_llpg_address,
+    da.uprn as matched_llpg_uprn,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.match_confidence_score,
+    CASE
+        WHEN f.matched_address_id IS NOT NULL THEN 'Matched'
+        ELSE 'Unmatched'
+    END as match_status
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_location dl ON f.matched_location_id = dl.location_id
+WHERE dt.type_name = 'Land Charge'
+ORDER BY s.document_date DESC, s.external_reference;
+
+-- CSV Export: Agreements
+CREATE OR REPLACE VIEW vw_csv_export_agreements AS
+SELECT
+    s.document_id,
+    s.external_reference as planning_app_no,
+    s.document_date,
+    s.raw_address as address,
+    s.raw_uprn as uprn,
+    da.full_address as matched_llpg_address,
+    da.uprn as matched_llpg_uprn,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.match_confidence_score,
+    CASE
+        WHEN f.matched_address_id IS NOT NULL THEN 'Matched'
+        ELSE 'Unmatched'
+    END as match_status
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_location dl ON f.matched_location_id = dl.location_id
+WHERE dt.type_name = 'Agreement'
+ORDER BY s.document_date DESC, s.external_reference;
+
+-- CSV Export: Enforcement Notices
+CREATE OR REPLACE VIEW vw_csv_export_enforcement_notices AS
+SELECT
+    s.document_id,
+    s.external_reference as planning_app_no,
+    s.document_date,
+    s.raw_address as address,
+    s.raw_uprn as uprn,
+    da.full_address as matched_llpg_address,
+    da.uprn as matched_llpg_uprn,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.match_confidence_score,
+    CASE
+        WHEN f.matched_address_id IS NOT NULL THEN 'Matched'
+        ELSE 'Unmatched'
+    END as match_status
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_location dl ON f.matched_location_id = dl.location_id
+WHERE dt.type_name = 'Enforcement Notice'
+ORDER BY s.document_date DESC, s.external_reference;
+
+-- CSV Export: Street Name and Numbering
+CREATE OR REPLACE VIEW vw_csv_export_street_naming AS
+SELECT
+    s.document_id,
+    s.external_reference as planning_app_no,
+    s.document_date,
+    s.raw_address as address,
+    s.raw_uprn as uprn,
+    da.full_address as matched_llpg_address,
+    da.uprn as matched_llpg_uprn,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.match_confidence_score,
+    CASE
+        WHEN f.matched_address_id IS NOT NULL THEN 'Matched'
+        ELSE 'Unmatched'
+    END as match_status
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_location dl ON f.matched_location_id = dl.location_id
+WHERE dt.type_name = 'Street Name and Numbering'
+ORDER BY s.document_date DESC, s.external_reference;
+
+-- CSV Export: Combined All Documents
+CREATE OR REPLACE VIEW vw_csv_export_all_documents AS
+SELECT
+    s.document_id,
+    dt.type_name as document_type,
+    s.external_reference as planning_app_no,
+    s.document_date,
+    s.raw_address as original_address,
+    s.raw_uprn as original_uprn,
+    da.full_address as matched_llpg_address,
+    da.uprn as matched_llpg_uprn,
+    dl.easting,
+    dl.northing,
+    dl.latitude,
+    dl.longitude,
+    f.match_confidence_score,
+    mm.method_name as match_method,
+    CASE
+        WHEN f.matched_address_id IS NOT NULL THEN 'Matched'
+        ELSE 'Unmatched'
+    END as match_status,
+    CASE
+        WHEN f.match_confidence_score >= 0.85 THEN 'High'
+        WHEN f.match_confidence_score >= 0.5 THEN 'Medium'
+        WHEN f.match_confidence_score > 0 THEN 'Low'
+        ELSE NULL
+    END as confidence_level
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+LEFT JOIN dim_address da ON f.matched_address_id = da.address_id
+LEFT JOIN dim_location dl ON f.matched_location_id = dl.location_id
+LEFT JOIN dim_match_method mm ON f.match_method_id = mm.method_id
+ORDER BY dt.type_name, s.document_date DESC, s.external_reference;
+
+-- CSV Export: Unmatched Documents Only
+CREATE OR REPLACE VIEW vw_csv_export_unmatched AS
+SELECT
+    s.document_id,
+    dt.type_name as document_type,
+    s.external_reference as planning_app_no,
+    s.document_date,
+    s.raw_address as address,
+    s.raw_uprn as uprn,
+    s.gopostal_house_number,
+    s.gopostal_road,
+    s.gopostal_city,
+    s.gopostal_postcode,
+    'Requires manual matching' as action_required
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+WHERE f.matched_address_id IS NULL
+  AND s.raw_address IS NOT NULL
+  AND s.raw_address <> 'N/A'
+  AND LENGTH(s.raw_address) > 5
+ORDER BY dt.type_name, s.document_date DESC;
+
+-- CSV Export: High Confidence Matches for Validation
+CREATE OR REPLACE VIEW vw_csv_export_high_confidence AS
+SELECT
+    s.document_id,
+    dt.type_name as document_type,
+    s.external_reference as planning_app_no,
+    s.raw_address as original_address,
+    da.full_address as matched_address,
+    da.uprn as matched_uprn,
+    f.match_confidence_score,
+    mm.method_name as match_method,
+    dl.easting,
+    dl.northing
+FROM src_document s
+INNER JOIN fact_documents_lean f ON s.document_id = f.document_id
+INNER JOIN dim_document_type dt ON f.doc_type_id = dt.doc_type_id
+INNER JOIN dim_address da ON f.matched_address_id = da.address_id
+INNER JOIN dim_location dl ON f.matched_location_id = dl.location_id
+LEFT JOIN dim_match_method mm ON f.match_method_id = mm.method_id
+WHERE f.match_confidence_score >= 0.85
+  OR f.is_high_confidence = true
+ORDER BY f.match_confidence_score DESC;
+
+-- Create indexes for better view performance
+CREATE INDEX IF NOT EXISTS idx_fact_doc_type_match ON fact_documents_lean(doc_type_id, matched_address_id);
+CREATE INDEX IF NOT EXISTS idx_src_doc_external_ref ON src_document(external_reference);
+CREATE INDEX IF NOT EXISTS idx_src_doc_date ON src_document(document_date);
+
+-- Add comments to document the views
+COMMENT ON VIEW vw_csv_export_decision_notices IS 'Reconstructs Decision Notices CSV format with match results';
+COMMENT ON VIEW vw_csv_export_land_charges IS 'Reconstructs Land Charges CSV format with match results';
+COMMENT ON VIEW vw_csv_export_agreements IS 'Reconstructs Agreements CSV format with match results';
+COMMENT ON VIEW vw_csv_export_enforcement_notices IS 'Reconstructs Enforcement Notices CSV format with match results';
+COMMENT ON VIEW vw_csv_export_street_naming IS 'Reconstructs Street Name and Numbering CSV format with match results';
+COMMENT ON VIEW vw_csv_export_all_documents IS 'Combined export of all document types with match results';
+COMMENT ON VIEW vw_csv_export_unmatched IS 'Export of unmatched documents requiring manual intervention';
+COMMENT ON VIEW vw_csv_export_high_confidence IS 'High confidence matches for quality validation';
+
+-- ============================================================================
+-- PLANNING APPLICATION GROUPING
+-- ============================================================================
+
+-- Function to split planning application numbers
+CREATE OR REPLACE FUNCTION split_planning_app_number(app_no TEXT)
+RETURNS TABLE(base_app TEXT, sequence TEXT) AS $$
+BEGIN
+    -- Handle null/empty cases
+    IF app_no IS NULL OR LENGTH(TRIM(app_no)) = 0 THEN
+        RETURN QUERY SELECT app_no, NULL::TEXT;
+        RETURN;
+    END IF;
+
+    -- Check if it contains a slash (like 20003/001)
+    IF position('/' IN app_no) > 0 THEN
+        RETURN QUERY SELECT
+            TRIM(split_part(app_no, '/', 1)) as base_app,
+            TRIM(split_part(app_no, '/', 2)) as sequence;
+    ELSE
+        -- No slash, it's a base application
+        RETURN QUERY SELECT TRIM(app_no), NULL::TEXT;
+    END IF;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Create view for planning application groups with address analysis
+CREATE OR REPLACE VIEW vw_planning_app_groups AS
+SELECT
+    planning_app_base,
+    planning_app_group_id,
+    COUNT(*) as total_documents,
+    COUNT(DISTINCT raw_address) as unique_addresses,
+    COUNT(DISTINCT CASE WHEN raw_address <> 'N/A' AND LENGTH(raw_address) > 5 THEN raw_address END) as valid_addresses,
+
+    -- Address matching statistics
+    COUNT(am.document_id) as matched_documents,
+    COUNT(DISTINCT am.address_id) as unique_matched_addresses,
+    COUNT(DISTINCT s.raw_uprn) FILTER (WHERE s.raw_uprn IS NOT NULL AND s.raw_uprn <> '') as source_uprns,
+
+    -- Most common address in the group
+    (SELECT raw_address
+     FROM src_document s2
+     WHERE s2.planning_app_base = s.planning_app_base
+       AND s2.raw_address <> 'N/A'
+       AND LENGTH(s2.raw_address) > 5
+     GROUP BY raw_address
+     ORDER BY COUNT(*) DESC
+     LIMIT 1) as most_common_address,
+
+    -- Check if group has source UPRN
+    (SELECT s3.raw_uprn
+     FROM src_document s3
+     WHERE s3.planning_app_base = s.planning_app_base
+       AND s3.raw_uprn IS NOT NULL
+       AND s3.raw_uprn <> ''
+     LIMIT 1) as group_source_uprn,
+
+    -- Check if group has successful match
+    (SELECT da.uprn
+     FROM src_document s4
+     JOIN address_match am2 ON s4.document_id = am2.document_id
+     JOIN dim_address da ON am2.address_id = da.address_id
+     WHERE s4.planning_app_base = s.planning_app_base
+       AND am2.confidence_score >= 0.8
+     ORDER BY am2.confidence_score DESC
+     LIMIT 1) as best_matched_uprn
+
+FROM src_document s
+LEFT JOIN address_match am ON s.document_id = am.document_id
+WHERE planning_app_base IS NOT NULL
+GROUP BY planning_app_base, planning_app_group_id
+ORDER BY planning_app_base;
+
+-- Create view for problematic planning groups (same group, different matches)
+CREATE OR REPLACE VIEW vw_planning_groups_inconsistent_matches AS
+SELECT
+    pag.planning_app_base,
+    pag.total_documents,
+    pag.unique_addresses,
+    pag.matched_documents,
+    pag.unique_matched_addresses,
+    pag.most_common_address,
+    pag.group_source_uprn,
+    pag.best_matched_uprn,
+
+    -- List all different matched UPRNs in this group
+    array_agg(DISTINCT da.uprn ORDER BY da.uprn) FILTER (WHERE da.uprn IS NOT NULL) as all_matched_uprns,
+
+    -- Check if there are inconsistencies
+    CASE
+        WHEN pag.unique_matched_addresses > 1 THEN 'Multiple different matches'
+        WHEN pag.matched_documents > 0 AND pag.matched_documents < pag.total_documents THEN 'Partial matches'
+        WHEN pag.unique_addresses > 1 AND pag.matched_documents = 0 THEN 'No matches despite address variations'
+        ELSE 'Consistent'
+    END as issue_type
+
+FROM vw_planning_app_groups pag
+LEFT JOIN src_document s ON s.planning_app_base = pag.planning_app_base
+LEFT JOIN address_match am ON s.document_id = am.document_id
+LEFT JOIN dim_address da ON am.address_id = da.address_id
+WHERE pag.planning_app_base IS NOT NULL
+GROUP BY pag.planning_app_base, pag.total_documents, pag.unique_addresses, pag.matched_documents,
+         pag.unique_matched_addresses, pag.most_common_address, pag.group_source_uprn, pag.best_matched_uprn
+HAVING COUNT(DISTINCT da.uprn) > 1  -- Only show groups with inconsistent matches
+   OR (COUNT(DISTINCT s.raw_address) > 1 AND COUNT(am.document_id) = 0)  -- Multiple addresses but no matches
+   OR (COUNT(am.document_id) > 0 AND COUNT(am.document_id) < COUNT(DISTINCT s.document_id))  -- Partial matches
+ORDER BY pag.total_documents DESC;
+
+-- Create function to find the best match within a planning group
+CREATE OR REPLACE FUNCTION get_best_group_match(group_base TEXT)
+RETURNS TABLE(
+    best_uprn TEXT,
+    best_address_id INTEGER,
+    confidence_score NUMERIC,
+    match_method TEXT,
+    total_votes INTEGER
+) AS $$
+BEGIN
+    RETURN QUERY
+    WITH group_matches AS (
+        -- Get all matches for documents in this planning group
+        SELECT
+            da.uprn,
+            am.address_id,
+            da.full_address,
+            am.confidence_score,
+            mm.method_name,
+            COUNT(*) as vote_count,
+            AVG(am.confidence_score) as avg_confidence,
+            MAX(am.confidence_score) as max_confidence
+        FROM src_document s
+        JOIN address_match am ON s.document_id = am.document_id
+        JOIN dim_address da ON am.address_id = da.address_id
+        LEFT JOIN dim_match_method mm ON am.match_method_id = mm.method_id
+        WHERE s.planning_app_base = group_base
+        GROUP BY da.uprn, am.address_id, da.full_address, am.confidence_score, mm.method_name
+    ),
+    source_uprn_match AS (
+        -- Check if any document in the group has a source UPRN that exists in LLPG
+        SELECT
+            s.raw_uprn as uprn,
+            da.address_id,
+            1.0 as confidence_score,
+            'source_uprn' as method_name,
+            COUNT(*) as vote_count
+        FROM src_document s
+        JOIN dim_address da ON s.raw_uprn = da.uprn
+        WHERE s.planning_app_base = group_base
+          AND s.raw_uprn IS NOT NULL
+          AND s.raw_uprn <> ''
+        GROUP BY s.raw_uprn, da.address_id
+    ),
+    all_candidates AS (
+        SELECT uprn, address_id, avg_confidence as confidence_score, method_name, vote_count
+        FROM group_matches
+        UNION ALL
+        SELECT uprn, address_id, confidence_score, method_name, vote_count
+        FROM source_uprn_match
+    ),
+    ranked_candidates AS (
+        SELECT
+            uprn,
+            address_id,
+            confidence_score,
+            method_name,
+            vote_count,
+            -- Priority: 1) Source UPRN, 2) Most votes, 3) Highest confidence
+            ROW_NUMBER() OVER (
+                ORDER BY
+                    CASE WHEN method_name = 'source_uprn' THEN 1 ELSE 2 END,
+                    vote_count DESC,
+                    confidence_score DESC
+            ) as rank
+        FROM all_candidates
+    )
+    SELECT
+        rc.uprn,
+        rc.address_id,
+        rc.confidence_score,
+        rc.method_name,
+        rc.vote_count
+    FROM ranked_candidates rc
+    WHERE rank = 1;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create view to show planning groups with inconsistent matches
+CREATE OR REPLACE VIEW vw_inconsistent_planning_groups AS
+WITH group_stats AS (
+    SELECT
+        s.planning_app_base,
+        s.planning_app_group_id,
+        COUNT(*) as total_documents,
+        COUNT(DISTINCT s.raw_address) FILTER (WHERE s.raw_address <> 'N/A' AND LENGTH(s.raw_address) > 5) as unique_addresses,
+        COUNT(am.document_id) as matched_documents,
+        COUNT(DISTINCT am.address_id) as unique_matched_addresses,
+        COUNT(DISTINCT da.uprn) as unique_matched_uprns,
+
+        -- Check for source UPRNs in the group
+        COUNT(DISTINCT s.raw_uprn) FILTER (WHERE s.raw_uprn IS NOT NULL AND s.raw_uprn <> '') as source_uprns,
+
+        -- Get the most common address (by count)
+        (SELECT s2.raw_address
+         FROM src_document s2
+         WHERE s2.planning_app_base = s.planning_app_base
+           AND s2.raw_address <> 'N/A'
+           AND LENGTH(s2.raw_address) > 5
+         GROUP BY s2.raw_address
+         ORDER BY COUNT(*) DESC, LENGTH(s2.raw_address) DESC
+         LIMIT 1) as representative_address
+
+    FROM src_document s
+    LEFT JOIN address_match am ON s.document_id = am.document_id
+    LEFT JOIN dim_address da ON am.address_id = da.address_id
+    WHERE s.planning_app_base IS NOT NULL
+    GROUP BY s.planning_app_base, s.planning_app_group_id
+),
+best_matches AS (
+    SELECT
+        gs.planning_app_base,
+        bgm.best_uprn,
+        bgm.best_address_id,
+        bgm.confidence_score as best_confidence,
+        bgm.match_method as best_method,
+        bgm.total_votes
+    FROM group_stats gs
+    CROSS JOIN LATERAL get_best_group_match(gs.planning_app_base) bgm
+)
+SELECT
+    gs.planning_app_base,
+    gs.total_documents,
+    gs.unique_addresses,
+    gs.matched_documents,
+    gs.unique_matched_addresses,
+    gs.unique_matched_uprns,
+    gs.source_uprns,
+    gs.representative_address,
+
+    bm.best_uprn as recommended_uprn,
+    bm.best_address_id as recommended_address_id,
+    bm.best_confidence as recommended_confidence,
+    bm.best_method as recommended_method,
+    bm.total_votes as recommendation_strength,
+
+    -- Flag different types of issues
+    CASE
+        WHEN gs.unique_matched_uprns > 1 THEN 'Multiple different UPRNs matched'
+        WHEN gs.matched_documents > 0 AND gs.matched_documents < gs.total_documents THEN 'Partial matches only'
+        WHEN gs.unique_addresses > 1 AND gs.matched_documents = 0 THEN 'Multiple addresses, no matches'
+        WHEN gs.matched_documents = 0 THEN 'No matches'
+        ELSE 'Consistent'
+    END as issue_type
+
+FROM group_stats gs
+LEFT JOIN best_matches bm ON gs.planning_app_base = bm.planning_app_base
+WHERE gs.unique_matched_uprns > 1  -- Focus on groups with inconsistent matches
+   OR (gs.matched_documents > 0 AND gs.matched_documents < gs.total_documents)
+   OR (gs.unique_addresses > 1 AND gs.matched_documents = 0)
+ORDER BY gs.total_documents DESC, gs.unique_matched_uprns DESC;
+
+-- Create corrected matches table
+CREATE TABLE IF NOT EXISTS address_match_corrected (
+    document_id INTEGER PRIMARY KEY REFERENCES src_document(document_id),
+    original_address_id INTEGER REFERENCES dim_address(address_id),
+    original_confidence_score NUMERIC(5,4),
+    original_method_id INTEGER REFERENCES dim_match_method(method_id),
+
+    corrected_address_id INTEGER REFERENCES dim_address(address_id),
+    corrected_location_id INTEGER REFERENCES dim_location(location_id),
+    corrected_confidence_score NUMERIC(5,4),
+    corrected_method_id INTEGER REFERENCES dim_match_method(method_id),
+
+    correction_reason TEXT,
+    planning_app_base TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT fk_corrected_address_location
+        FOREIGN KEY (corrected_address_id) REFERENCES dim_address(address_id),
+    CONSTRAINT fk_corrected_location
+        FOREIGN KEY (corrected_location_id) REFERENCES dim_location(location_id)
+);
+
+-- Create index for performance
+CREATE INDEX IF NOT EXISTS idx_address_match_corrected_planning ON address_match_corrected(planning_app_base);
+CREATE INDEX IF NOT EXISTS idx_address_match_corrected_document ON address_match_corrected(document_id);
+
+-- ============================================================================
+-- RANGE EXPANSION (was sql/expand_llpg_ranges.sql; the Go expander adds the range columns)
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS dim_address_expanded (
+    expanded_id SERIAL PRIMARY KEY,
+    original_address_id INTEGER REFERENCES dim_address(address_id),
+    uprn TEXT,
+    full_address TEXT,
+    address_canonical TEXT,
+    expansion_type TEXT, -- 'range_expansion', 'original'
+    unit_number TEXT,    -- The specific unit number extracted
+    created_at TIMESTAMP DEFAULT NOW(),
+    parent_uprn TEXT,
+    parent_range TEXT,
+    range_kind TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_address_expanded_uprn ON dim_address_expanded(uprn);
+CREATE INDEX IF NOT EXISTS idx_address_expanded_canonical ON dim_address_expanded(address_canonical);
+CREATE INDEX IF NOT EXISTS idx_address_expanded_unit ON dim_address_expanded(unit_number);
+
+CREATE TABLE IF NOT EXISTS dim_address_range (
+    range_id SERIAL PRIMARY KEY,
+    address_id INTEGER REFERENCES dim_address(address_id),
+    uprn TEXT,
+    range_text TEXT,
+    unit_prefix TEXT,
+    range_start TEXT,
+    range_end TEXT,
+    parity TEXT,
+    range_kind TEXT,
+    remainder TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_address_range_remainder_trgm ON dim_address_range USING gin (remainder gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_address_range_uprn ON dim_address_range(uprn);
+
+-- Function to expand numeric ranges
+CREATE OR REPLACE FUNCTION expand_address_ranges() RETURNS INTEGER AS $$
+DECLARE
+    rec RECORD;
+    range_match TEXT[];
+    start_num INTEGER;
+    end_num INTEGER;
+    i INTEGER;
+    new_address TEXT;
+    new_canonical TEXT;
+    expanded_count INTEGER := 0;
+BEGIN
+    -- Clear previous expansions
+    DELETE FROM dim_address_expanded WHERE expansion_type = 'range_expansion';
+
+    -- First, copy all original addresses
+    INSERT INTO dim_address_expanded (original_address_id, uprn, full_address, address_canonical, expansion_type, unit_number)
+    SELECT address_id, uprn, full_address, address_canonical, 'original', NULL
+    FROM dim_address;
+
+    -- Process addresses with numeric ranges
+    FOR rec IN
+        SELECT address_id, uprn, full_address, address_canonical
+        FROM dim_address
+        WHERE full_address ~ '\m\d+-\d+\M'  -- Matches patterns like "10-11", "3-4"
+    LOOP
+        -- Extract the range pattern (e.g., "10-11")
+        range_match := regexp_match(rec.full_address, '(\d+)-(\d+)');
+
+        IF range_match IS NOT NULL THEN
+            start_num := range_match[1]::INTEGER;
+            end_num := range_match[2]::INTEGER;
+
+            -- Generate individual addresses for each number in the range
+            FOR i IN start_num..end_num LOOP
+                -- Replace the range with the individual number
+                new_address := regexp_replace(rec.full_address, '\m' || start_num || '-' || end_num || '\M', i::TEXT);
+                new_canonical := regexp_replace(rec.address_canonical, '\m' || start_num || '-' || end_num || '\M', i::TEXT);
+
+                -- Insert the expanded address
+                INSERT INTO dim_address_expanded (
+                    original_address_id, uprn, full_address, address_canonical,
+                    expansion_type, unit_number
+                ) VALUES (
+                    rec.address_id, rec.uprn, new_address, new_canonical,
+                    'range_expansion', i::TEXT
+                );
+
+                expanded_count := expanded_count + 1;
+            END LOOP;
+        END IF;
+    END LOOP;
+
+    -- Handle Unit ranges specifically (e.g., "Unit, 10-11")
+    FOR rec IN
+        SELECT address_id, uprn, full_address, address_canonical
+        FROM dim_address
+        WHERE full_address ~ 'Unit[,\s]+\d+-\d+'
+    LOOP
+        -- Extract the unit range
+        range_match := regexp_match(rec.full_address, 'Unit[,\s]+(\d+)-(\d+)');
+
+        IF range_match IS NOT NULL THEN
+            start_num := range_match[1]::INTEGER;
+            end_num := range_match[2]::INTEGER;
+
+            FOR i IN start_num..end_num LOOP
+                -- Replace "Unit, 10-11" with "Unit, 10" etc.
+                new_address := regexp_replace(rec.full_address, 'Unit[,\s]+\d+-\d+', 'Unit, ' || i);
+                new_canonical := regexp_replace(rec.address_canonical, 'UNIT\s*\d+\s*\d+', 'UNIT ' || i);
+
+                INSERT INTO dim_address_expanded (
+                    original_address_id, uprn, full_address, address_canonical,
+                    expansion_type, unit_number
+                ) VALUES (
+                    rec.address_id, rec.uprn, new_address, new_canonical,
+                    'range_expansion', i::TEXT
+                );
+
+                expanded_count := expanded_count + 1;
+            END LOOP;
+        END IF;
+    END LOOP;
+
+    -- Handle alpha ranges (e.g., "9A-9C")
+    FOR rec IN
+        SELECT address_id, uprn, full_address, address_canonical
+        FROM dim_address
+        WHERE full_address ~ '\m\d+[A-Z]-\d+[A-Z]\M'
+    LOOP
+        -- Extract the alpha range pattern
+        range_match := regexp_match(rec.full_address, '(\d+)([A-Z])-(\d+)([A-Z])');
+
+        IF range_match IS NOT NULL AND range_match[1] = range_match[3] THEN
+            -- Same number, different letters (e.g., 9A-9C)
+            FOR i IN ASCII(range_match[2])..ASCII(range_match[4]) LOOP
+                new_address := regexp_replace(
+                    rec.full_address,
+                    '\m' || range_match[1] || range_match[2] || '-' || range_match[3] || range_match[4] || '\M',
+                    range_match[1] || CHR(i)
+                );
+                new_canonical := regexp_replace(
+                    rec.address_canonical,
+                    '\m' || range_match[1] || range_match[2] || '-' || range_match[3] || range_match[4] || '\M',
+                    range_match[1] || CHR(i)
+                );
+
+                INSERT INTO dim_address_expanded (
+                    original_address_id, uprn, full_address, address_canonical,
+                    expansion_type, unit_number
+                ) VALUES (
+                    rec.address_id, rec.uprn, new_address, new_canonical,
+                    'range_expansion', range_match[1] || CHR(i)
+                );
+
+                expanded_count := expanded_count + 1;
+            END LOOP;
+        END IF;
+    END LOOP;
+
+    RETURN expanded_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Effective match of a document: the group correction if there is one, else the original
+-- (was scripts/rebuild_fact_table_with_corrections.sql)
+CREATE OR REPLACE FUNCTION get_effective_match(doc_id INTEGER)
+RETURNS TABLE(
+    address_id INTEGER,
+    location_id INTEGER,
+    confidence_score NUMERIC,
+    match_method_id INTEGER
+) AS $$
+BEGIN
+    RETURN QUERY
+    -- First try corrected match
+    SELECT
+        amc.corrected_address_id,
+        amc.corrected_location_id,
+        amc.corrected_confidence_score,
+        amc.corrected_method_id
+    FROM address_match_corrected amc
+    WHERE amc.document_id = doc_id
+
+    UNION ALL
+
+    -- If no correction exists, use original match
+    SELECT
+        am.address_id,
+        am.location_id,
+        am.confidence_score,
+        am.match_method_id
+    FROM address_match am
+    WHERE am.document_id = doc_id
+      AND NOT EXISTS (
+          SELECT 1 FROM address_match_corrected amc2
+          WHERE amc2.document_id = doc_id
+      )
+
+    LIMIT 1;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Most-voted matched address in a planning group, used by the group consensus scripts
+CREATE OR REPLACE FUNCTION get_group_best_match_simple(group_base TEXT)
+RETURNS TABLE(uprn TEXT, address_id INTEGER, votes INTEGER) AS $$
+    SELECT da.uprn, am.address_id, COUNT(*)::INTEGER
+    FROM src_document s
+    JOIN address_match am ON am.document_id = s.document_id
+    JOIN dim_address da ON da.address_id = am.address_id
+    WHERE s.planning_app_base = group_base
+    GROUP BY da.uprn, am.address_id
+    ORDER BY COUNT(*) DESC, MAX(am.confidence_score) DESC
+$$ LANGUAGE sql STABLE;
+
+-- Used by group consensus (was scripts/safe_group_consensus.sql)
+CREATE OR REPLACE FUNCTION is_real_address(address_text TEXT)
+RETURNS BOOLEAN AS $$
+BEGIN
+    -- Check if this looks like a real address vs planning reference
+    IF address_text IS NULL OR LENGTH(TRIM(address_text)) < 10 THEN
+        RETURN FALSE;
+    END IF;
+
+    -- Planning reference patterns (F12345, AU123, etc.)
+    IF address_text ~ '^[A-Z]{1,3}[0-9]+/?[0-9]*$' THEN
+        RETURN FALSE;
+    END IF;
+
+    -- N/A and similar non-addresses
+    IF UPPER(address_text) IN ('N/A', 'NOT APPLICABLE', 'NONE', 'NULL', 'TBC') THEN
+        RETURN FALSE;
+    END IF;
+
+    -- Must contain typical address indicators
+    IF address_text ~* '(street|road|avenue|lane|way|close|drive|court|place|crescent|gardens|park|hill|view|house|cottage|farm|manor|hall)'
+       OR address_text ~ ',' THEN  -- Has commas (typical of addresses)
+        RETURN TRUE;
+    END IF;
+
+    RETURN FALSE;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+SELECT 'Created baseline schema' as result;
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Migration 043 (down): LLPG Lifecycle Dates
+-- Purpose: Remove the LLPG lifecycle columns from dim_address and the staging tables.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_dim_address_logical_status;
+DROP INDEX IF EXISTS idx_dim_address_lifecycle;
+ALTER TABLE dim_address DROP COLUMN IF EXISTS end_date;
+ALTER TABLE dim_address DROP COLUMN IF EXISTS start_date;
+ALTER TABLE dim_address DROP COLUMN IF EXISTS logical_status;
+
+ALTER TABLE IF EXISTS stg_llpg
+DROP COLUMN IF EXISTS enddate,
+DROP COLUMN IF EXISTS startdate,
+DROP COLUMN IF EXISTS logicalstatus;
+
+ALTER TABLE stg_ehdc_llpg
+DROP COLUMN IF EXISTS enddate,
+DROP COLUMN IF EXISTS startdate,
+DROP COLUMN IF EXISTS logicalstatus;
+
+COMMIT;
+
+SELECT 'Removed LLPG lifecycle dates' as result;
@@ -0,0 +1,77 @@
+-- Migration 043: LLPG Lifecycle Dates
+-- Purpose: Load LLPG start/end dates and logical status so candidates can be
+--          scored by whether the address existed on the source document date
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- 1. Staging columns for the BS7666 lifecycle fields (optional in the CSV extract)
+ALTER TABLE stg_ehdc_llpg
+ADD COLUMN IF NOT EXISTS logicalstatus TEXT,
+ADD COLUMN IF NOT EXISTS startdate TEXT,
+ADD COLUMN IF NOT EXISTS enddate TEXT;
+
+-- Legacy ETL staging table used by internal/etl
+ALTER TABLE IF EXISTS stg_llpg
+ADD COLUMN IF NOT EXISTS logicalstatus TEXT,
+ADD COLUMN IF NOT EXISTS startdate TEXT,
+ADD COLUMN IF NOT EXISTS enddate TEXT;
+
+-- 2. Lifecycle columns on the address dimension
+ALTER TABLE dim_address
+ADD COLUMN IF NOT EXISTS logical_status TEXT,
+ADD COLUMN IF NOT EXISTS start_date DATE,
+ADD COLUMN IF NOT EXISTS end_date DATE;
+
+COMMENT ON COLUMN dim_address.logical_status IS
+'BS7666 LPI logical status: 1 = approved, 3 = alternative, 6 = provisional, 8 = historic';
+
+COMMENT ON COLUMN dim_address.start_date IS
+'Date the address came into existence in the LLPG (BLPU/LPI start date)';
+
+COMMENT ON COLUMN dim_address.end_date IS
+'Date the address ceased to exist (demolished, merged, renumbered); NULL while live';
+
+-- 3. Populate from staging where the extract carries the fields
+UPDATE dim_address d
+SET
+    logical_status = COALESCE(NULLIF(TRIM(s.logicalstatus), ''), NULLIF(TRIM(s.lgcstatusc), '')),
+    start_date = CASE
+        WHEN s.startdate ~ '^\d{4}-\d{2}-\d{2}' THEN to_date(LEFT(s.startdate, 10), 'YYYY-MM-DD')
+        WHEN s.startdate ~ '^\d{1,2}/\d{1,2}/\d{4}$' THEN to_date(s.startdate, 'DD/MM/YYYY')
+        ELSE NULL
+    END,
+    end_date = CASE
+        WHEN s.enddate ~ '^\d{4}-\d{2}-\d{2}' THEN to_date(LEFT(s.enddate, 10), 'YYYY-MM-DD')
+        WHEN s.enddate ~ '^\d{1,2}/\d{1,2}/\d{4}$' THEN to_date(s.enddate, 'DD/MM/YYYY')
+        ELSE NULL
+    END
+FROM stg_ehdc_llpg s
+WHERE d.uprn = s.bs7666uprn;
+
+-- 4. Fall back to the LGC status code for rows without an explicit logical status
+UPDATE dim_address
+SET logical_status = status_code
+WHERE logical_status IS NULL
+  AND status_code IS NOT NULL;
+
+-- Historic records created from source documents are historic by definition
+UPDATE dim_address
+SET logical_status = '8'
+WHERE logical_status IS NULL
+  AND is_historic = TRUE;
+
+CREATE INDEX IF NOT EXISTS idx_dim_address_lifecycle
+ON dim_address(start_date, end_date);
+
+CREATE INDEX IF NOT EXISTS idx_dim_address_logical_status
+ON dim_address(logical_status);
+
+SELECT
+    COUNT(*) AS total_addresses,
+    COUNT(start_date) AS with_start_date,
+    COUNT(end_date) AS with_end_date,
+    COUNT(*) FILTER (WHERE logical_status = '8') AS historic
+FROM dim_address;
+
+COMMIT;
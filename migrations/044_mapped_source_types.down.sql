@@ -0,0 +1,12 @@
+-- Migration 044 (down): Mapped Source Types
+-- Purpose: Remove the land charges staging table used by the ETL pipeline. PostgreSQL can't
+--          drop enum values, so the source_type values added to an existing enum stay.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TABLE IF EXISTS stg_land_charges_cards;
+
+COMMIT;
+
+SELECT 'Removed mapped source staging table' as result;
@@ -0,0 +1,33 @@
+-- Migration 044: Mapped Source Types
+-- Purpose: Register the source types loaded through mapping files
+--          (street naming & numbering, microfiche, enlargement maps, ENL folders)
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- The source_type enum only exists in the original src_document schema
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_type WHERE typname = 'source_type') THEN
+        ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'street_name_numbering';
+        ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'microfiche_post_1974';
+        ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'microfiche_pre_1974';
+        ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'enlargement_map';
+        ALTER TYPE source_type ADD VALUE IF NOT EXISTS 'enl_folder';
+    END IF;
+END $$;
+
+-- Land charges are staged under the name used by the ETL pipeline
+CREATE TABLE IF NOT EXISTS stg_land_charges_cards (
+    job_number TEXT,
+    filepath TEXT,
+    card_code TEXT,
+    address TEXT,
+    bs7666uprn TEXT,
+    easting TEXT,
+    northing TEXT
+);
+
+SELECT 'Registered mapped source types' as result;
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- Migration 045 (down): Planning Reference Links
+-- Purpose: Remove planning reference links and the parsed planning reference columns.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TABLE IF EXISTS planning_ref_link;
+
+DROP INDEX IF EXISTS idx_src_document_planning_base;
+ALTER TABLE src_document DROP COLUMN IF EXISTS planning_app_sequence;
+ALTER TABLE src_document DROP COLUMN IF EXISTS planning_app_base;
+
+COMMIT;
+
+SELECT 'Removed planning reference links' as result;
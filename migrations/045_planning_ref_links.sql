@@ -0,0 +1,37 @@
+-- Migration 045: Planning Reference Links
+-- Purpose: Locate microfiche records (planning reference only, no address) by inheriting
+--          the matched UPRN and coordinates of decision notices sharing a planning_app_base
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- Same grouping columns as migration 041; the linker fills them from normalised references
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS planning_app_base TEXT;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS planning_app_sequence TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_src_document_planning_base ON src_document(planning_app_base);
+
+CREATE TABLE IF NOT EXISTS planning_ref_link (
+    src_id BIGINT PRIMARY KEY,              -- reference-only document (microfiche)
+    source_src_id BIGINT NOT NULL,          -- decision notice the match was inherited from
+    planning_app_base TEXT NOT NULL,
+    planning_app_sequence TEXT,
+    uprn TEXT NOT NULL,
+    easting NUMERIC,
+    northing NUMERIC,
+    method TEXT NOT NULL DEFAULT 'inherited_via_planning_ref',
+    confidence NUMERIC(5,4) NOT NULL,
+    supporting_docs INTEGER NOT NULL,       -- decisions in the group agreeing on the UPRN
+    conflicting_docs INTEGER NOT NULL DEFAULT 0,
+    linked_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_planning_ref_link_base ON planning_ref_link(planning_app_base);
+CREATE INDEX IF NOT EXISTS idx_planning_ref_link_uprn ON planning_ref_link(uprn);
+
+COMMENT ON TABLE planning_ref_link IS 'Microfiche records located via decision notices with the same planning application base';
+COMMENT ON COLUMN planning_ref_link.confidence IS 'Inherited confidence: 0.80 base, +0.05 same sub-application, +0.05 corroborated, scaled by decision agreement';
+
+SELECT 'Created planning reference links' as result;
+
+COMMIT;
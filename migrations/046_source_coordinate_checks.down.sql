@@ -0,0 +1,17 @@
+-- Migration 046 (down): Source Coordinate Checks
+-- Purpose: Remove the coordinate classification and repair columns from src_document.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_src_document_coord_status;
+ALTER TABLE src_document DROP COLUMN IF EXISTS coord_checked_at;
+ALTER TABLE src_document DROP COLUMN IF EXISTS coord_repair_reason;
+ALTER TABLE src_document DROP COLUMN IF EXISTS coord_repair_confidence;
+ALTER TABLE src_document DROP COLUMN IF EXISTS northing_repaired;
+ALTER TABLE src_document DROP COLUMN IF EXISTS easting_repaired;
+ALTER TABLE src_document DROP COLUMN IF EXISTS coord_status;
+
+COMMIT;
+
+SELECT 'Removed source coordinate checks' as result;
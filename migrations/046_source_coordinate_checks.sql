@@ -0,0 +1,24 @@
+-- Migration 046: Source Coordinate Checks
+-- Purpose: Keep original source easting/northing alongside a validated/repaired copy.
+--          Spatial matching reads only the repaired columns.
+-- Date: 2026-10-18
+
+BEGIN;
+
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS coord_status TEXT;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS easting_repaired NUMERIC;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS northing_repaired NUMERIC;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS coord_repair_confidence NUMERIC(5,4);
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS coord_repair_reason TEXT;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS coord_checked_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_src_document_coord_status ON src_document(coord_status);
+
+COMMENT ON COLUMN src_document.coord_status IS 'valid, swapped, truncated, latlng, out_of_district, placeholder or missing';
+COMMENT ON COLUMN src_document.easting_repaired IS 'Validated/repaired easting; NULL when the source coordinate is unusable';
+COMMENT ON COLUMN src_document.northing_repaired IS 'Validated/repaired northing; NULL when the source coordinate is unusable';
+COMMENT ON COLUMN src_document.coord_repair_confidence IS '1.0 for valid coordinates, lower for repairs';
+
+SELECT 'Added source coordinate check columns' as result;
+
+COMMIT;
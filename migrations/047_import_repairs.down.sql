@@ -0,0 +1,26 @@
+-- Migration 047 (down): Import Repairs
+-- Purpose: Remove the import repair log and the import_flags columns.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TABLE IF EXISTS import_repair;
+
+ALTER TABLE src_document DROP COLUMN IF EXISTS import_flags;
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['stg_decision_notices', 'stg_land_charges_cards', 'stg_enforcement_notices',
+                             'stg_agreements', 'stg_street_name_numbering', 'stg_enl_folders']
+    LOOP
+        IF to_regclass(t) IS NOT NULL THEN
+            EXECUTE format('ALTER TABLE %I DROP COLUMN IF EXISTS import_flags', t);
+        END IF;
+    END LOOP;
+END $$;
+
+COMMIT;
+
+SELECT 'Removed import repairs' as result;
@@ -0,0 +1,45 @@
+-- Migration 047: Import Repairs
+-- Purpose: Flag values repaired from Excel artefacts (scientific-notation UPRNs, lost leading
+--          zeros, serial and month-first dates) and log database-resolved UPRN repairs.
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- Staging tables whose mappings request repairs carry the flags through to src_document
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['stg_decision_notices', 'stg_land_charges_cards', 'stg_enforcement_notices',
+                             'stg_agreements', 'stg_street_name_numbering', 'stg_enl_folders']
+    LOOP
+        IF to_regclass(t) IS NOT NULL THEN
+            EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS import_flags TEXT', t);
+        END IF;
+    END LOOP;
+END $$;
+
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS import_flags TEXT;
+
+CREATE TABLE IF NOT EXISTS import_repair (
+    repair_id       BIGSERIAL PRIMARY KEY,
+    src_id          BIGINT NOT NULL REFERENCES src_document(src_id) ON DELETE CASCADE,
+    column_name     TEXT NOT NULL,
+    original_value  TEXT NOT NULL,
+    repaired_value  TEXT,
+    repair_type     TEXT NOT NULL,
+    status          TEXT NOT NULL CHECK (status IN ('repaired', 'rejected')),
+    candidates      INTEGER NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_repair_src ON import_repair(src_id);
+CREATE INDEX IF NOT EXISTS idx_import_repair_status ON import_repair(repair_type, status);
+
+COMMENT ON COLUMN src_document.import_flags IS 'Semicolon-separated column:repair flags set during import, e.g. bs7666uprn:leading_zeros';
+COMMENT ON TABLE import_repair IS 'Scientific-notation UPRNs resolved (or rejected) against OS Open UPRN after loading';
+COMMENT ON COLUMN import_repair.candidates IS 'OS Open UPRN values inside the district consistent with the truncated value';
+
+SELECT 'Added import repair flags and log' as result;
+
+COMMIT;
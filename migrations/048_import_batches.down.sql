@@ -0,0 +1,34 @@
+-- Migration 048 (down): Import Batches
+-- Purpose: Remove import batch lineage. Soft-removed documents become visible again.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['stg_decision_notices', 'stg_land_charges_cards', 'stg_enforcement_notices',
+                             'stg_agreements', 'stg_street_name_numbering', 'stg_enl_folders',
+                             'stg_enlargement_maps', 'stg_microfiche_post_1974', 'stg_microfiche_pre_1974']
+    LOOP
+        IF to_regclass(t) IS NOT NULL THEN
+            EXECUTE format('ALTER TABLE %I DROP COLUMN IF EXISTS source_row', t);
+        END IF;
+    END LOOP;
+END $$;
+
+DROP INDEX IF EXISTS idx_src_document_import_batch;
+DROP INDEX IF EXISTS idx_src_document_natural_key;
+ALTER TABLE src_document DROP COLUMN IF EXISTS removed_batch_id;
+ALTER TABLE src_document DROP COLUMN IF EXISTS removed_at;
+ALTER TABLE src_document DROP COLUMN IF EXISTS row_hash;
+ALTER TABLE src_document DROP COLUMN IF EXISTS natural_key;
+ALTER TABLE src_document DROP COLUMN IF EXISTS source_row;
+ALTER TABLE src_document DROP COLUMN IF EXISTS import_batch_id;
+
+DROP TABLE IF EXISTS import_batch;
+
+COMMIT;
+
+SELECT 'Removed import batches' as result;
@@ -0,0 +1,63 @@
+-- Migration 048: Import Batches
+-- Purpose: Record each source file load (path, SHA-256, row count, loader version) and keep
+--          every document's batch and CSV row, so corrected files can be re-imported by
+--          natural key without duplicating documents or losing match decisions.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS import_batch (
+    batch_id        BIGSERIAL PRIMARY KEY,
+    source_type     TEXT NOT NULL,
+    file_path       TEXT NOT NULL,
+    file_sha256     TEXT NOT NULL,
+    file_size       BIGINT NOT NULL,
+    loader_version  TEXT NOT NULL,
+    status          TEXT NOT NULL CHECK (status IN ('running', 'completed', 'failed')),
+    row_count       BIGINT NOT NULL DEFAULT 0,
+    rows_rejected   BIGINT NOT NULL DEFAULT 0,
+    rows_added      BIGINT NOT NULL DEFAULT 0,
+    rows_changed    BIGINT NOT NULL DEFAULT 0,
+    rows_removed    BIGINT NOT NULL DEFAULT 0,
+    rows_unchanged  BIGINT NOT NULL DEFAULT 0,
+    started_at      TIMESTAMPTZ DEFAULT now(),
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_batch_source ON import_batch(source_type, batch_id DESC);
+
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS import_batch_id BIGINT REFERENCES import_batch(batch_id);
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS source_row INTEGER;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS natural_key TEXT;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS row_hash TEXT;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS removed_at TIMESTAMPTZ;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS removed_batch_id BIGINT REFERENCES import_batch(batch_id);
+
+CREATE INDEX IF NOT EXISTS idx_src_document_natural_key ON src_document(source_type, natural_key);
+CREATE INDEX IF NOT EXISTS idx_src_document_import_batch ON src_document(import_batch_id);
+
+-- Staging tables record the CSV row each value came from
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['stg_decision_notices', 'stg_land_charges_cards', 'stg_enforcement_notices',
+                             'stg_agreements', 'stg_street_name_numbering', 'stg_enl_folders',
+                             'stg_enlargement_maps', 'stg_microfiche_post_1974', 'stg_microfiche_pre_1974']
+    LOOP
+        IF to_regclass(t) IS NOT NULL THEN
+            EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS source_row INTEGER', t);
+        END IF;
+    END LOOP;
+END $$;
+
+COMMENT ON TABLE import_batch IS 'One row per source file load; an unchanged file (same SHA-256 and loader version) is not reloaded';
+COMMENT ON COLUMN src_document.import_batch_id IS 'Latest batch that contained this document';
+COMMENT ON COLUMN src_document.source_row IS 'CSV row number (header excluded) in that batch''s file';
+COMMENT ON COLUMN src_document.natural_key IS 'Mapping natural key (default job_number|filepath), #n suffix for repeats';
+COMMENT ON COLUMN src_document.row_hash IS 'md5 of the document fields, used to detect changed rows';
+COMMENT ON COLUMN src_document.removed_at IS 'Set when a later batch no longer contains the document; match decisions are kept';
+
+SELECT 'Added import batches and document lineage' as result;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration 049 (down): Match Run Status
+-- Purpose: Remove run status. The total columns are left as the engines use them.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_match_run_status;
+ALTER TABLE match_run DROP COLUMN IF EXISTS status;
+
+COMMIT;
+
+SELECT 'Removed match run status' as result;
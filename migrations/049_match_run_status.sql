@@ -0,0 +1,35 @@
+-- Migration 049: Match Run Status
+-- Purpose: Record whether a matching run completed, failed or was interrupted (SIGINT/SIGTERM),
+--          with the partial totals an interrupted run had reached.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS match_run (
+    run_id          BIGSERIAL PRIMARY KEY,
+    run_started_at  TIMESTAMPTZ DEFAULT now(),
+    run_label       TEXT,
+    notes           TEXT
+);
+
+-- Columns the engines already write; older databases may lack them
+ALTER TABLE match_run ADD COLUMN IF NOT EXISTS algorithm_version TEXT;
+ALTER TABLE match_run ADD COLUMN IF NOT EXISTS run_completed_at TIMESTAMPTZ;
+ALTER TABLE match_run ADD COLUMN IF NOT EXISTS total_processed INTEGER DEFAULT 0;
+ALTER TABLE match_run ADD COLUMN IF NOT EXISTS auto_accepted INTEGER DEFAULT 0;
+ALTER TABLE match_run ADD COLUMN IF NOT EXISTS needs_review INTEGER DEFAULT 0;
+ALTER TABLE match_run ADD COLUMN IF NOT EXISTS rejected INTEGER DEFAULT 0;
+
+-- Existing runs predate status tracking and are recorded as completed
+ALTER TABLE match_run ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'completed'
+    CHECK (status IN ('running', 'completed', 'interrupted', 'failed'));
+ALTER TABLE match_run ALTER COLUMN status SET DEFAULT 'running';
+
+CREATE INDEX IF NOT EXISTS idx_match_run_status ON match_run(status) WHERE status <> 'completed';
+
+COMMENT ON COLUMN match_run.status IS 'running until the run finishes; interrupted runs keep the totals reached when they stopped';
+COMMENT ON COLUMN match_run.run_completed_at IS 'When the run stopped, whatever its status';
+
+SELECT 'Added match run status' as result;
+
+COMMIT;
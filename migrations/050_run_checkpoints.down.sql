@@ -0,0 +1,12 @@
+-- Migration 050 (down): Run Checkpoints
+-- Purpose: Remove run checkpoints. Runs already recorded in match_run are kept.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TABLE IF EXISTS match_run_processed;
+DROP TABLE IF EXISTS match_run_checkpoint;
+
+COMMIT;
+
+SELECT 'Removed match run checkpoints' as result;
@@ -0,0 +1,34 @@
+-- Migration 050: Run Checkpoints
+-- Purpose: Persist the progress and configuration of long matching runs so an interrupted
+--          or failed run can be resumed where it stopped instead of restarting.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS match_run_checkpoint (
+    run_id            BIGINT PRIMARY KEY REFERENCES match_run(run_id) ON DELETE CASCADE,
+    processor         TEXT NOT NULL,
+    config            JSONB NOT NULL DEFAULT '{}',
+    last_document_id  BIGINT NOT NULL DEFAULT 0,
+    stats             JSONB NOT NULL DEFAULT '{}',
+    legs              INTEGER NOT NULL DEFAULT 1,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Items finished by runs whose workers complete them out of order
+CREATE TABLE IF NOT EXISTS match_run_processed (
+    run_id  BIGINT NOT NULL REFERENCES match_run(run_id) ON DELETE CASCADE,
+    item    TEXT NOT NULL,
+    PRIMARY KEY (run_id, item)
+);
+
+COMMENT ON TABLE match_run_checkpoint IS 'Resumable progress of a matching run; resume continues from it with the same config';
+COMMENT ON COLUMN match_run_checkpoint.processor IS 'Batch processor that started the run: standard, optimized, hybrid or layer3-enhanced';
+COMMENT ON COLUMN match_run_checkpoint.last_document_id IS 'Cursor of a document_id-ordered run: every document up to it has been attempted';
+COMMENT ON COLUMN match_run_checkpoint.stats IS 'Statistics accumulated over every leg of the run';
+COMMENT ON COLUMN match_run_checkpoint.legs IS 'Number of times the run has been started, including resumes';
+COMMENT ON TABLE match_run_processed IS 'Processed-item set for parallel runs, e.g. raw addresses in layer3-enhanced';
+
+SELECT 'Added match run checkpoints' as result;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Migration 051 (down): Work Queue
+-- Purpose: Remove the work queue. Runs and results written by queue workers are kept.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TABLE IF EXISTS work_queue_chunk;
+
+COMMIT;
+
+SELECT 'Removed work queue' as result;
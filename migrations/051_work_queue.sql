@@ -0,0 +1,43 @@
+-- Migration 051: Work Queue
+-- Purpose: Database-backed queue of document IDs or deduplicated addresses that matcher
+--          worker processes on any number of servers claim in chunks with SKIP LOCKED.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS work_queue_chunk (
+    chunk_id      BIGSERIAL PRIMARY KEY,
+    run_id        BIGINT NOT NULL REFERENCES match_run(run_id) ON DELETE CASCADE,
+    kind          TEXT NOT NULL CHECK (kind IN ('documents', 'addresses')),
+    items         TEXT[] NOT NULL,
+    status        TEXT NOT NULL DEFAULT 'pending'
+                  CHECK (status IN ('pending', 'claimed', 'done', 'failed')),
+    worker_id     TEXT,
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    claimed_at    TIMESTAMPTZ,
+    heartbeat_at  TIMESTAMPTZ,
+    completed_at  TIMESTAMPTZ,
+    processed     INTEGER NOT NULL DEFAULT 0,
+    matched       INTEGER NOT NULL DEFAULT 0,
+    errors        INTEGER NOT NULL DEFAULT 0,
+    last_error    TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Claiming takes the lowest pending chunk of a run
+CREATE INDEX IF NOT EXISTS idx_work_queue_chunk_pending
+    ON work_queue_chunk (run_id, chunk_id) WHERE status = 'pending';
+
+-- Abandoned chunks are found by their last heartbeat
+CREATE INDEX IF NOT EXISTS idx_work_queue_chunk_claimed
+    ON work_queue_chunk (run_id, heartbeat_at) WHERE status = 'claimed';
+
+COMMENT ON TABLE work_queue_chunk IS 'Chunks of a queued run; workers claim them with FOR UPDATE SKIP LOCKED';
+COMMENT ON COLUMN work_queue_chunk.items IS 'Document IDs (kind documents) or raw addresses (kind addresses)';
+COMMENT ON COLUMN work_queue_chunk.worker_id IS 'Worker holding or last completing the chunk, host-pid-n';
+COMMENT ON COLUMN work_queue_chunk.attempts IS 'Times the chunk has been claimed; re-queued chunks fail after the worker limit';
+COMMENT ON COLUMN work_queue_chunk.heartbeat_at IS 'Last heartbeat of the claiming worker; stale claims are re-queued';
+
+SELECT 'Added work queue' as result;
+
+COMMIT;
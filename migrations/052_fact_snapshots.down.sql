@@ -0,0 +1,20 @@
+-- Migration 052 (down): Fact Snapshots
+-- Purpose: Remove the snapshot catalogue and every catalogued snapshot table.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOR t IN SELECT table_name FROM fact_snapshot LOOP
+        EXECUTE format('DROP TABLE IF EXISTS %I', t);
+    END LOOP;
+END $$;
+
+DROP TABLE IF EXISTS fact_snapshot;
+
+COMMIT;
+
+SELECT 'Removed fact snapshot catalogue' as result;
@@ -0,0 +1,33 @@
+-- Migration 052: Fact Snapshots
+-- Purpose: Catalogue named, timestamped snapshots of fact_documents_lean so matching output
+--          can be listed, compared between runs, restored and pruned by retention.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS fact_snapshot (
+    snapshot_id    BIGSERIAL PRIMARY KEY,
+    name           TEXT NOT NULL UNIQUE,
+    table_name     TEXT NOT NULL UNIQUE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    run_id         BIGINT REFERENCES match_run(run_id) ON DELETE SET NULL,
+    config_hash    TEXT,
+    notes          TEXT,
+    row_count      INTEGER NOT NULL DEFAULT 0,
+    matched_count  INTEGER NOT NULL DEFAULT 0,
+    keep           BOOLEAN NOT NULL DEFAULT FALSE,
+    restored_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_fact_snapshot_created_at ON fact_snapshot (created_at);
+
+COMMENT ON TABLE fact_snapshot IS 'Catalogue of fact_documents_lean snapshots; each row owns the table named in table_name';
+COMMENT ON COLUMN fact_snapshot.table_name IS 'fact_snapshot_<id>: a copy of fact_documents_lean with snapshot_uprn and snapshot_method';
+COMMENT ON COLUMN fact_snapshot.run_id IS 'Matching run whose output the snapshot records, when known';
+COMMENT ON COLUMN fact_snapshot.config_hash IS 'Fingerprint of the matching configuration in effect (config.Hash)';
+COMMENT ON COLUMN fact_snapshot.keep IS 'Exempt from retention pruning';
+COMMENT ON COLUMN fact_snapshot.restored_at IS 'Last time the snapshot was restored into fact_documents_lean';
+
+SELECT 'Added fact snapshot catalogue' as result;
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Migration 053 (down): Fact Change Log
+-- Purpose: Remove the change-log triggers, the change log and the consistency check history.
+--          match_accepted is left in place: it holds reviewer decisions.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_fact_change_match_accepted ON match_accepted;
+DROP TRIGGER IF EXISTS trg_fact_change_address_match ON address_match;
+DROP TRIGGER IF EXISTS trg_fact_change_address_match_corrected ON address_match_corrected;
+DROP TRIGGER IF EXISTS trg_fact_change_src_document ON src_document;
+DROP FUNCTION IF EXISTS log_fact_change();
+
+DROP TABLE IF EXISTS fact_consistency_check;
+DROP TABLE IF EXISTS fact_change_log;
+
+COMMIT;
+
+SELECT 'Removed fact change log' as result;
@@ -0,0 +1,98 @@
+-- Migration 053: Fact Change Log
+-- Purpose: Log the documents whose match_accepted, address_match, address_match_corrected or
+--          src_document rows change, so fact_documents_lean can be maintained row by row
+--          between full rebuilds, and record periodic consistency checks against a rebuild.
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- Reviewer decisions; created by the original schema, which older databases may lack
+CREATE TABLE IF NOT EXISTS match_accepted (
+    src_id      BIGINT PRIMARY KEY,
+    uprn        TEXT NOT NULL,
+    method      TEXT,
+    score       NUMERIC(6,4),
+    confidence  NUMERIC(6,4),
+    run_id      BIGINT,
+    accepted_by TEXT,
+    accepted_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS fact_change_log (
+    change_id    BIGSERIAL PRIMARY KEY,
+    document_id  BIGINT NOT NULL,
+    source       TEXT NOT NULL,
+    changed_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    applied_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_fact_change_log_pending ON fact_change_log (change_id) WHERE applied_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_fact_change_log_document ON fact_change_log (document_id);
+
+-- Logs the document named by the column in TG_ARGV[0] of the old and new rows
+CREATE OR REPLACE FUNCTION log_fact_change() RETURNS trigger AS $$
+DECLARE
+    old_id BIGINT;
+    new_id BIGINT;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        old_id := (to_jsonb(OLD) ->> TG_ARGV[0])::BIGINT;
+        INSERT INTO fact_change_log (document_id, source) VALUES (old_id, TG_TABLE_NAME);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        new_id := (to_jsonb(NEW) ->> TG_ARGV[0])::BIGINT;
+        IF new_id IS DISTINCT FROM old_id THEN
+            INSERT INTO fact_change_log (document_id, source) VALUES (new_id, TG_TABLE_NAME);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_fact_change_match_accepted ON match_accepted;
+CREATE TRIGGER trg_fact_change_match_accepted
+    AFTER INSERT OR UPDATE OR DELETE ON match_accepted
+    FOR EACH ROW EXECUTE FUNCTION log_fact_change('src_id');
+
+DROP TRIGGER IF EXISTS trg_fact_change_address_match ON address_match;
+CREATE TRIGGER trg_fact_change_address_match
+    AFTER INSERT OR UPDATE OR DELETE ON address_match
+    FOR EACH ROW EXECUTE FUNCTION log_fact_change('document_id');
+
+DROP TRIGGER IF EXISTS trg_fact_change_address_match_corrected ON address_match_corrected;
+CREATE TRIGGER trg_fact_change_address_match_corrected
+    AFTER INSERT OR UPDATE OR DELETE ON address_match_corrected
+    FOR EACH ROW EXECUTE FUNCTION log_fact_change('document_id');
+
+-- Only the src_document columns the fact row is built from; normalisation passes update others
+DROP TRIGGER IF EXISTS trg_fact_change_src_document ON src_document;
+CREATE TRIGGER trg_fact_change_src_document
+    AFTER INSERT OR DELETE OR UPDATE OF raw_address, doc_type_id, document_date, external_reference,
+        job_number, filepath, raw_uprn, gopostal_processed, gopostal_postcode ON src_document
+    FOR EACH ROW EXECUTE FUNCTION log_fact_change('document_id');
+
+CREATE TABLE IF NOT EXISTS fact_consistency_check (
+    check_id      BIGSERIAL PRIMARY KEY,
+    checked_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expected_rows INTEGER NOT NULL,
+    actual_rows   INTEGER NOT NULL,
+    missing       INTEGER NOT NULL DEFAULT 0,
+    extra         INTEGER NOT NULL DEFAULT 0,
+    drifted       INTEGER NOT NULL DEFAULT 0,
+    repaired      INTEGER NOT NULL DEFAULT 0,
+    pending       INTEGER NOT NULL DEFAULT 0,
+    duration_ms   INTEGER NOT NULL DEFAULT 0,
+    sample        BIGINT[]
+);
+
+COMMENT ON TABLE fact_change_log IS 'Documents whose fact_documents_lean row is stale; the applier rebuilds just those rows';
+COMMENT ON COLUMN fact_change_log.source IS 'Table whose change logged the document';
+COMMENT ON COLUMN fact_change_log.applied_at IS 'When the fact row was rebuilt, by the applier or a full rebuild';
+COMMENT ON TABLE fact_consistency_check IS 'Comparisons of fact_documents_lean with the rows a full rebuild would write';
+COMMENT ON COLUMN fact_consistency_check.drifted IS 'Documents present in both whose match or attributes differ';
+COMMENT ON COLUMN fact_consistency_check.pending IS 'Unapplied changes when the check ran; their documents may legitimately differ';
+COMMENT ON COLUMN fact_consistency_check.sample IS 'First differing document IDs';
+
+SELECT 'Added fact change log and consistency checks' as result;
+
+COMMIT;
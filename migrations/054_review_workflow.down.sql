@@ -0,0 +1,13 @@
+-- Migration 054 (down): Review Workflow
+-- Purpose: Remove the review queue, skips and event log.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TABLE IF EXISTS review_event;
+DROP TABLE IF EXISTS review_skip;
+DROP TABLE IF EXISTS review_item;
+
+COMMIT;
+
+SELECT 'Removed review workflow' as result;
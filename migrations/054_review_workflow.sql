@@ -0,0 +1,64 @@
+-- Migration 054: Review Workflow
+-- Purpose: Shared review queue for the web interface: items leased to one reviewer at a time,
+--          skip and defer, a second reviewer's sign-off for low-confidence accepts, and an
+--          event log for per-reviewer throughput.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS review_item (
+    src_id               BIGINT PRIMARY KEY,
+    source_type          TEXT,
+    confidence           NUMERIC(5,4) NOT NULL DEFAULT 0,
+    status               TEXT NOT NULL DEFAULT 'open'
+                         CHECK (status IN ('open', 'deferred', 'awaiting_signoff', 'done')),
+    claimed_by           TEXT,
+    claimed_at           TIMESTAMPTZ,
+    lease_expires_at     TIMESTAMPTZ,
+    deferred_until       TIMESTAMPTZ,
+    proposed_uprn        TEXT,
+    proposed_method      TEXT,
+    proposed_score       NUMERIC,
+    proposed_confidence  NUMERIC(5,4),
+    proposed_by          TEXT,
+    proposed_at          TIMESTAMPTZ,
+    proposal_reason      TEXT,
+    decision             TEXT,
+    decided_by           TEXT,
+    decided_at           TIMESTAMPTZ,
+    notes                TEXT,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_item_queue ON review_item (status, confidence) WHERE status <> 'done';
+CREATE INDEX IF NOT EXISTS idx_review_item_source_type ON review_item (source_type) WHERE status <> 'done';
+
+-- A skipped item is not offered to the same reviewer again
+CREATE TABLE IF NOT EXISTS review_skip (
+    src_id      BIGINT NOT NULL REFERENCES review_item(src_id) ON DELETE CASCADE,
+    reviewer    TEXT NOT NULL,
+    skipped_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (src_id, reviewer)
+);
+
+CREATE TABLE IF NOT EXISTS review_event (
+    event_id     BIGSERIAL PRIMARY KEY,
+    src_id       BIGINT NOT NULL,
+    reviewer     TEXT NOT NULL,
+    action       TEXT NOT NULL
+                 CHECK (action IN ('claim', 'accept', 'propose', 'confirm', 'refuse', 'reject', 'skip', 'defer', 'release', 'expire')),
+    held_ms      BIGINT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_event_reviewer ON review_event (reviewer, created_at);
+
+COMMENT ON TABLE review_item IS 'Documents awaiting manual review; a lease (claimed_by until lease_expires_at) keeps two reviewers off the same record';
+COMMENT ON COLUMN review_item.confidence IS 'Best needs_review candidate confidence, for confidence-band queues';
+COMMENT ON COLUMN review_item.status IS 'open, deferred until deferred_until, awaiting_signoff of the proposed_* accept, or done';
+COMMENT ON COLUMN review_item.proposed_by IS 'Reviewer whose low-confidence accept awaits a second, different reviewer';
+COMMENT ON COLUMN review_event.held_ms IS 'Time from claim to this action, for throughput';
+
+SELECT 'Added review workflow' as result;
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- Migration 055 (down): Audit Chain
+-- Purpose: Remove the audit chain triggers, functions, chain and checkpoints. match_override and
+--          audit_match_decisions are left in place: they hold reviewer decisions.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_audit_chain_match_accepted ON match_accepted;
+DROP TRIGGER IF EXISTS trg_audit_chain_match_override ON match_override;
+DROP TRIGGER IF EXISTS trg_audit_chain_audit_match_decisions ON audit_match_decisions;
+DROP TRIGGER IF EXISTS trg_audit_chain_address_match ON address_match;
+DROP TRIGGER IF EXISTS trg_audit_chain_address_match_corrected ON address_match_corrected;
+
+DO $$
+BEGIN
+    IF to_regclass('match_result') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS trg_audit_chain_match_result ON match_result;
+    END IF;
+    IF to_regclass('match_audit') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS trg_audit_chain_match_audit ON match_audit;
+    END IF;
+END $$;
+
+DROP TABLE IF EXISTS audit_checkpoint;
+DROP TABLE IF EXISTS audit_chain;
+
+DROP FUNCTION IF EXISTS audit_chain_attach(TEXT, TEXT);
+DROP FUNCTION IF EXISTS audit_chain_append();
+DROP FUNCTION IF EXISTS audit_chain_refuse_change();
+DROP FUNCTION IF EXISTS audit_chain_record(TEXT, TEXT, TEXT, JSONB);
+DROP FUNCTION IF EXISTS audit_chain_hash(BIGINT, TEXT, TEXT, TEXT, JSONB, TEXT, TIMESTAMPTZ, TEXT);
+DROP FUNCTION IF EXISTS audit_chain_hash(BIGINT, TEXT, TEXT, TEXT, JSONB, TEXT, TIMESTAMPTZ, TEXT, BIGINT);
+
+COMMIT;
+
+SELECT 'Removed audit hash chain' as result;
@@ -0,0 +1,181 @@
+-- Migration 055: Audit Chain
+-- Purpose: Make the match audit trail tamper-evident. Every insert, update and delete on the
+--          tables that record match decisions appends an entry to audit_chain whose hash covers
+--          the entry's content and the previous entry's hash, so a removed or edited entry breaks
+--          the chain. Rows that already exist are chained as baseline entries. Signed daily
+--          checkpoints of the chain head are recorded in audit_checkpoint.
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- Manual overrides and web decisions; created by the original schema, which older databases may lack
+CREATE TABLE IF NOT EXISTS match_override (
+    override_id BIGSERIAL PRIMARY KEY,
+    src_id      BIGINT,
+    uprn        TEXT,
+    reason      TEXT,
+    created_by  TEXT,
+    created_at  TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS audit_match_decisions (
+    decision_id      BIGSERIAL PRIMARY KEY,
+    src_id           BIGINT NOT NULL,
+    old_match_status TEXT,
+    new_match_status TEXT,
+    old_uprn         TEXT,
+    new_uprn         TEXT,
+    decision_type    TEXT NOT NULL,
+    decision_reason  TEXT,
+    match_method     TEXT,
+    match_score      NUMERIC(6,4),
+    confidence       NUMERIC(6,4),
+    decided_by       TEXT,
+    client_info      TEXT,
+    decided_at       TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS audit_chain (
+    seq         BIGINT PRIMARY KEY,
+    table_name  TEXT NOT NULL,
+    operation   TEXT NOT NULL CHECK (operation IN ('INSERT', 'UPDATE', 'DELETE', 'BASELINE')),
+    row_key     TEXT,
+    row_data    JSONB,
+    actor       TEXT NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    prev_hash   TEXT NOT NULL,
+    hash        TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_chain_row ON audit_chain (table_name, row_key, seq);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoint (
+    checkpoint_id   BIGSERIAL PRIMARY KEY,
+    checkpoint_date DATE NOT NULL,
+    seq             BIGINT NOT NULL,
+    hash            TEXT NOT NULL,
+    public_key      TEXT NOT NULL,
+    signature       TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_checkpoint_date ON audit_checkpoint (checkpoint_date);
+
+-- The entry hash: SHA-256 of the fields joined by newlines, with the time in Unix microseconds.
+-- internal/audit.ChainEntry.ComputeHash must produce the same value.
+CREATE OR REPLACE FUNCTION audit_chain_hash(
+    p_seq BIGINT, p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB,
+    p_actor TEXT, p_recorded_at TIMESTAMPTZ, p_prev_hash TEXT
+) RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(concat_ws(E'\n',
+        p_seq::TEXT, p_table, p_operation, COALESCE(p_row_key, ''), COALESCE(p_row_data::TEXT, ''),
+        p_actor, (extract(epoch FROM p_recorded_at) * 1000000)::BIGINT::TEXT, p_prev_hash
+    ), 'UTF8')), 'hex');
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Appends one entry; the advisory lock serialises writers so seq has no gaps
+CREATE OR REPLACE FUNCTION audit_chain_record(
+    p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB
+) RETURNS VOID AS $$
+DECLARE
+    last_seq  BIGINT;
+    last_hash TEXT;
+    e         audit_chain%ROWTYPE;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_chain'));
+    SELECT seq, hash INTO last_seq, last_hash FROM audit_chain ORDER BY seq DESC LIMIT 1;
+
+    e.seq := COALESCE(last_seq, 0) + 1;
+    e.table_name := p_table;
+    e.operation := p_operation;
+    e.row_key := p_row_key;
+    e.row_data := p_row_data;
+    e.actor := session_user;
+    e.recorded_at := clock_timestamp();
+    e.prev_hash := COALESCE(last_hash, repeat('0', 64));
+    e.hash := audit_chain_hash(e.seq, e.table_name, e.operation, e.row_key, e.row_data,
+        e.actor, e.recorded_at, e.prev_hash);
+
+    INSERT INTO audit_chain VALUES (e.*);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Chains the row change; the row key is the column named by TG_ARGV[0]. Rows are serialised
+-- in UTC so timestamps read the same whatever the writer's session time zone.
+CREATE OR REPLACE FUNCTION audit_chain_append() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM audit_chain_record(TG_TABLE_NAME, TG_OP, to_jsonb(OLD) ->> TG_ARGV[0], NULL);
+    ELSE
+        IF TG_OP = 'UPDATE' AND (to_jsonb(OLD) ->> TG_ARGV[0]) IS DISTINCT FROM (to_jsonb(NEW) ->> TG_ARGV[0]) THEN
+            PERFORM audit_chain_record(TG_TABLE_NAME, 'DELETE', to_jsonb(OLD) ->> TG_ARGV[0], NULL);
+        END IF;
+        PERFORM audit_chain_record(TG_TABLE_NAME, TG_OP, to_jsonb(NEW) ->> TG_ARGV[0], to_jsonb(NEW));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SET timezone = 'UTC';
+
+-- The chain is append-only
+CREATE OR REPLACE FUNCTION audit_chain_refuse_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_chain is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_chain_append_only ON audit_chain;
+CREATE TRIGGER trg_audit_chain_append_only
+    BEFORE UPDATE OR DELETE ON audit_chain
+    FOR EACH ROW EXECUTE FUNCTION audit_chain_refuse_change();
+
+DROP TRIGGER IF EXISTS trg_audit_chain_no_truncate ON audit_chain;
+CREATE TRIGGER trg_audit_chain_no_truncate
+    BEFORE TRUNCATE ON audit_chain
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_chain_refuse_change();
+
+-- Puts a table under the chain: adds its trigger and chains its rows that have no entry yet.
+-- Safe to re-run, and used for tables created after this migration (match_result, match_audit).
+CREATE OR REPLACE FUNCTION audit_chain_attach(p_table TEXT, p_key TEXT) RETURNS INTEGER AS $$
+DECLARE
+    r       RECORD;
+    chained INTEGER := 0;
+BEGIN
+    IF to_regclass(p_table) IS NULL THEN
+        RETURN 0;
+    END IF;
+
+    EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', 'trg_audit_chain_' || p_table, p_table);
+    EXECUTE format('CREATE TRIGGER %I AFTER INSERT OR UPDATE OR DELETE ON %I '
+                   'FOR EACH ROW EXECUTE FUNCTION audit_chain_append(%L)',
+                   'trg_audit_chain_' || p_table, p_table, p_key);
+
+    FOR r IN EXECUTE format(
+        'SELECT to_jsonb(t) AS data FROM %I t
+         WHERE NOT EXISTS (SELECT 1 FROM audit_chain c WHERE c.table_name = %L AND c.row_key = to_jsonb(t) ->> %L)
+         ORDER BY t.%I', p_table, p_table, p_key, p_key)
+    LOOP
+        PERFORM audit_chain_record(p_table, 'BASELINE', r.data ->> p_key, r.data);
+        chained := chained + 1;
+    END LOOP;
+    RETURN chained;
+END;
+$$ LANGUAGE plpgsql SET timezone = 'UTC';
+
+SELECT audit_chain_attach('match_accepted', 'src_id');
+SELECT audit_chain_attach('match_override', 'override_id');
+SELECT audit_chain_attach('audit_match_decisions', 'decision_id');
+SELECT audit_chain_attach('match_result', 'match_id');
+SELECT audit_chain_attach('match_audit', 'audit_id');
+
+COMMENT ON TABLE audit_chain IS 'Hash chain of every change to the match decision tables; verify with matcher-v2 -cmd=verify-audit';
+COMMENT ON COLUMN audit_chain.operation IS 'INSERT, UPDATE or DELETE, or BASELINE for rows that existed when the table was attached';
+COMMENT ON COLUMN audit_chain.row_data IS 'The row after the change; NULL for deletes';
+COMMENT ON COLUMN audit_chain.actor IS 'Database session user that made the change';
+COMMENT ON COLUMN audit_chain.prev_hash IS 'Hash of the previous entry; 64 zeros for the first';
+COMMENT ON COLUMN audit_chain.hash IS 'SHA-256 of this entry''s fields and prev_hash, see audit_chain_hash';
+COMMENT ON TABLE audit_checkpoint IS 'Signed chain heads exported by matcher-v2 -cmd=audit-checkpoint';
+COMMENT ON COLUMN audit_checkpoint.public_key IS 'Base64 Ed25519 public key the checkpoint was signed with';
+
+SELECT 'Added audit hash chain and checkpoints' as result;
+
+COMMIT;
@@ -0,0 +1,40 @@
+-- Migration 056 (down): Run Rollback
+-- Purpose: Stop attributing audit chain entries to runs and remove the rollback records.
+--          The run_id column stays on audit_chain, which is append-only, and entries keep
+--          their hashes; address_match and address_match_corrected stay chained.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE OR REPLACE FUNCTION audit_chain_record(
+    p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB
+) RETURNS VOID AS $$
+DECLARE
+    last_seq  BIGINT;
+    last_hash TEXT;
+    e         audit_chain%ROWTYPE;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_chain'));
+    SELECT seq, hash INTO last_seq, last_hash FROM audit_chain ORDER BY seq DESC LIMIT 1;
+
+    e.seq := COALESCE(last_seq, 0) + 1;
+    e.table_name := p_table;
+    e.operation := p_operation;
+    e.row_key := p_row_key;
+    e.row_data := p_row_data;
+    e.actor := session_user;
+    e.recorded_at := clock_timestamp();
+    e.prev_hash := COALESCE(last_hash, repeat('0', 64));
+    e.hash := audit_chain_hash(e.seq, e.table_name, e.operation, e.row_key, e.row_data,
+        e.actor, e.recorded_at, e.prev_hash, NULL);
+
+    INSERT INTO audit_chain VALUES (e.*);
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS match_run_rollback;
+DROP TABLE IF EXISTS match_run_client;
+
+COMMIT;
+
+SELECT 'Removed run attribution and rollback records' as result;
@@ -0,0 +1,96 @@
+-- Migration 056: Run Rollback
+-- Purpose: Attribute audit chain entries to the match run whose process made them, chain
+--          address_match and address_match_corrected too, and record rollbacks, so
+--          rollback-run can restore every row a run changed to its state before the run.
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- The processes working on each run, by application_name (internal/db sets a unique one
+-- per process). A run's creator joins it; so does every process that resumes it or works
+-- its queue. A process's writes belong to the run it joined last, until the run completes.
+CREATE TABLE IF NOT EXISTS match_run_client (
+    run_id    BIGINT NOT NULL REFERENCES match_run(run_id) ON DELETE CASCADE,
+    client    TEXT NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ended_at  TIMESTAMPTZ,
+    PRIMARY KEY (run_id, client, joined_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_run_client_active ON match_run_client (client, joined_at) WHERE ended_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS match_run_rollback (
+    rollback_run_id BIGINT PRIMARY KEY REFERENCES match_run(run_id),
+    run_id          BIGINT NOT NULL REFERENCES match_run(run_id),
+    requested_by    TEXT NOT NULL,
+    reason          TEXT,
+    restored        INTEGER NOT NULL DEFAULT 0,
+    removed         INTEGER NOT NULL DEFAULT 0,
+    kept            INTEGER NOT NULL DEFAULT 0,
+    started_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_run_rollback_run ON match_run_rollback (run_id);
+
+ALTER TABLE audit_chain ADD COLUMN IF NOT EXISTS run_id BIGINT;
+CREATE INDEX IF NOT EXISTS idx_audit_chain_run ON audit_chain (run_id, table_name, row_key) WHERE run_id IS NOT NULL;
+
+-- The run is appended to the hashed content when set, so entries without one hash as before
+DROP FUNCTION IF EXISTS audit_chain_hash(BIGINT, TEXT, TEXT, TEXT, JSONB, TEXT, TIMESTAMPTZ, TEXT);
+CREATE OR REPLACE FUNCTION audit_chain_hash(
+    p_seq BIGINT, p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB,
+    p_actor TEXT, p_recorded_at TIMESTAMPTZ, p_prev_hash TEXT, p_run_id BIGINT
+) RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(concat_ws(E'\n',
+        p_seq::TEXT, p_table, p_operation, COALESCE(p_row_key, ''), COALESCE(p_row_data::TEXT, ''),
+        p_actor, (extract(epoch FROM p_recorded_at) * 1000000)::BIGINT::TEXT, p_prev_hash, p_run_id::TEXT
+    ), 'UTF8')), 'hex');
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION audit_chain_record(
+    p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB
+) RETURNS VOID AS $$
+DECLARE
+    last_seq  BIGINT;
+    last_hash TEXT;
+    e         audit_chain%ROWTYPE;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_chain'));
+    SELECT seq, hash INTO last_seq, last_hash FROM audit_chain ORDER BY seq DESC LIMIT 1;
+
+    e.seq := COALESCE(last_seq, 0) + 1;
+    e.table_name := p_table;
+    e.operation := p_operation;
+    e.row_key := p_row_key;
+    e.row_data := p_row_data;
+    e.actor := session_user;
+    e.recorded_at := clock_timestamp();
+    e.prev_hash := COALESCE(last_hash, repeat('0', 64));
+    IF p_operation <> 'BASELINE' THEN
+        SELECT run_id INTO e.run_id
+        FROM match_run_client
+        WHERE client = current_setting('application_name') AND client <> '' AND ended_at IS NULL
+        ORDER BY joined_at DESC
+        LIMIT 1;
+    END IF;
+    e.hash := audit_chain_hash(e.seq, e.table_name, e.operation, e.row_key, e.row_data,
+        e.actor, e.recorded_at, e.prev_hash, e.run_id);
+
+    INSERT INTO audit_chain VALUES (e.*);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Baselining address_match takes a few minutes on a full database
+SELECT audit_chain_attach('address_match', 'match_id');
+SELECT audit_chain_attach('address_match_corrected', 'document_id');
+
+COMMENT ON TABLE match_run_client IS 'Processes (application_name) working on a run; the audit chain attributes their writes to it';
+COMMENT ON COLUMN match_run_client.ended_at IS 'When the run completed; NULL while the process may still be writing for it';
+COMMENT ON TABLE match_run_rollback IS 'Rollbacks of match runs; each rollback is itself a run, so it can be rolled back in turn';
+COMMENT ON COLUMN match_run_rollback.kept IS 'Rows left as they are because a reviewer or a later run changed them after the run';
+COMMENT ON COLUMN audit_chain.run_id IS 'Match run whose process made the change, from match_run_client; NULL for reviewers and baselines';
+
+SELECT 'Added run attribution and rollback' as result;
+
+COMMIT;
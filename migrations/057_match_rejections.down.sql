@@ -0,0 +1,26 @@
+-- Migration 057 (down): Match Rejections
+-- Purpose: Stop honouring reviewer rejections and remove them.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_address_match_rejections ON address_match;
+DROP TRIGGER IF EXISTS trg_match_accepted_rejections ON match_accepted;
+
+DO $$
+BEGIN
+    IF to_regclass('match_result') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS trg_match_result_rejections ON match_result;
+    END IF;
+END $$;
+
+DROP FUNCTION IF EXISTS address_match_honour_rejections();
+DROP FUNCTION IF EXISTS match_accepted_honour_rejections();
+DROP FUNCTION IF EXISTS match_result_honour_rejections();
+DROP FUNCTION IF EXISTS match_rejection_for(BIGINT, TEXT);
+DROP FUNCTION IF EXISTS record_match_rejection(BIGINT, TEXT, TEXT, TEXT, TEXT);
+DROP TABLE IF EXISTS match_rejection;
+
+COMMIT;
+
+SELECT 'Removed match rejections' as result;
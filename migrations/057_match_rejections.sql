@@ -0,0 +1,142 @@
+-- Migration 057: Match Rejections
+-- Purpose: Keep reviewers' rejections as negative evidence. A rejected (document, UPRN) pair,
+--          and the (canonical address, UPRN) pair behind it, is never proposed or accepted
+--          automatically again until a reviewer accepts that UPRN for the document.
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- One row per rejection; it applies to its document and to every document whose
+-- src_document.address_canonical is the same
+CREATE TABLE IF NOT EXISTS match_rejection (
+    rejection_id      BIGSERIAL PRIMARY KEY,
+    src_id            BIGINT NOT NULL,
+    address_canonical TEXT,
+    uprn              TEXT NOT NULL,
+    source            TEXT NOT NULL,
+    reason            TEXT,
+    rejected_by       TEXT NOT NULL,
+    rejected_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    lifted_by         TEXT,
+    lifted_at         TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_match_rejection_document ON match_rejection (src_id, uprn) WHERE lifted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_match_rejection_address ON match_rejection (address_canonical, uprn)
+    WHERE lifted_at IS NULL AND address_canonical IS NOT NULL;
+
+-- Records a rejection, taking the canonical address from the document; a NULL UPRN or an
+-- already rejected pair is ignored
+CREATE OR REPLACE FUNCTION record_match_rejection(
+    p_src_id BIGINT, p_uprn TEXT, p_source TEXT, p_reason TEXT, p_rejected_by TEXT
+) RETURNS VOID AS $$
+BEGIN
+    IF p_uprn IS NULL OR p_uprn = '' THEN
+        RETURN;
+    END IF;
+    INSERT INTO match_rejection (src_id, address_canonical, uprn, source, reason, rejected_by)
+    SELECT p_src_id, NULLIF((SELECT address_canonical FROM src_document WHERE document_id = p_src_id), ''),
+        p_uprn, p_source, NULLIF(p_reason, ''), COALESCE(NULLIF(p_rejected_by, ''), session_user)
+    ON CONFLICT (src_id, uprn) WHERE lifted_at IS NULL DO NOTHING;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The rejection that rules out matching the document to the UPRN, preferring one made on
+-- the document itself; NULL when there is none
+CREATE OR REPLACE FUNCTION match_rejection_for(p_src_id BIGINT, p_uprn TEXT) RETURNS BIGINT AS $$
+    SELECT r.rejection_id
+    FROM match_rejection r
+    LEFT JOIN src_document s ON s.document_id = p_src_id
+    WHERE r.uprn = p_uprn AND r.lifted_at IS NULL
+      AND (r.src_id = p_src_id OR (r.address_canonical <> '' AND r.address_canonical = s.address_canonical))
+    ORDER BY (r.src_id = p_src_id) DESC, r.rejected_at DESC
+    LIMIT 1;
+$$ LANGUAGE sql STABLE;
+
+-- Engines that write match_result directly still cannot put a rejected pair up for review
+-- or accept it: the row is kept, settled as suppressed, for the explanation
+CREATE OR REPLACE FUNCTION match_result_honour_rejections() RETURNS trigger AS $$
+DECLARE
+    rejection BIGINT;
+BEGIN
+    IF NEW.decision IN ('needs_review', 'auto_accepted', 'accepted') AND NEW.candidate_uprn IS NOT NULL
+       AND COALESCE(NEW.decided_by, 'system') LIKE 'system%' THEN
+        rejection := match_rejection_for(NEW.src_id, NEW.candidate_uprn);
+        IF rejection IS NOT NULL THEN
+            NEW.decision := 'suppressed';
+            NEW.decided := true;
+            NEW.notes := concat_ws(' - ', 'Suppressed by prior rejection ' || rejection, NULLIF(NEW.notes, ''));
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- match_result is created on first use; run this block again once it exists
+DO $$
+BEGIN
+    IF to_regclass('match_result') IS NOT NULL THEN
+        DROP TRIGGER IF EXISTS trg_match_result_rejections ON match_result;
+        CREATE TRIGGER trg_match_result_rejections
+            BEFORE INSERT ON match_result
+            FOR EACH ROW EXECUTE FUNCTION match_result_honour_rejections();
+    END IF;
+END $$;
+
+-- An automatic accept of a rejected pair is dropped; a reviewer's accept lifts the
+-- document's rejection of that UPRN
+CREATE OR REPLACE FUNCTION match_accepted_honour_rejections() RETURNS trigger AS $$
+DECLARE
+    rejection BIGINT;
+BEGIN
+    IF COALESCE(NEW.accepted_by, 'system') = 'system' THEN
+        rejection := match_rejection_for(NEW.src_id, NEW.uprn);
+        IF rejection IS NOT NULL THEN
+            RAISE NOTICE 'accept of UPRN % for document % suppressed by prior rejection %', NEW.uprn, NEW.src_id, rejection;
+            RETURN NULL;
+        END IF;
+    ELSE
+        UPDATE match_rejection SET lifted_by = NEW.accepted_by, lifted_at = now()
+        WHERE src_id = NEW.src_id AND uprn = NEW.uprn AND lifted_at IS NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_match_accepted_rejections ON match_accepted;
+CREATE TRIGGER trg_match_accepted_rejections
+    BEFORE INSERT OR UPDATE OF src_id, uprn, accepted_by ON match_accepted
+    FOR EACH ROW EXECUTE FUNCTION match_accepted_honour_rejections();
+
+-- The component engines write address_match as system_*
+CREATE OR REPLACE FUNCTION address_match_honour_rejections() RETURNS trigger AS $$
+DECLARE
+    rejection BIGINT;
+BEGIN
+    IF COALESCE(NEW.matched_by, 'system') LIKE 'system%' THEN
+        rejection := match_rejection_for(NEW.document_id,
+            (SELECT uprn FROM dim_address WHERE address_id = NEW.address_id));
+        IF rejection IS NOT NULL THEN
+            RAISE NOTICE 'match of document % suppressed by prior rejection %', NEW.document_id, rejection;
+            RETURN NULL;
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_address_match_rejections ON address_match;
+CREATE TRIGGER trg_address_match_rejections
+    BEFORE INSERT OR UPDATE OF document_id, address_id, matched_by ON address_match
+    FOR EACH ROW EXECUTE FUNCTION address_match_honour_rejections();
+
+SELECT audit_chain_attach('match_rejection', 'rejection_id');
+
+COMMENT ON TABLE match_rejection IS 'Reviewer rejections of a UPRN for a document; matching never proposes or accepts the pair, or the canonical address with the UPRN, again';
+COMMENT ON COLUMN match_rejection.address_canonical IS 'The document''s src_document.address_canonical when rejected; other documents with it are covered too';
+COMMENT ON COLUMN match_rejection.source IS 'Where the rejection was made: web_reject, review_queue or cli_review';
+COMMENT ON COLUMN match_rejection.lifted_at IS 'When a reviewer accepted the UPRN for the document after all; lifted rejections no longer apply';
+
+SELECT 'Added match rejections' as result;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Migration 058 (down): Match Explanations
+-- Purpose: Remove persisted match explanations.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TABLE IF EXISTS match_explanation;
+
+COMMIT;
+
+SELECT 'Removed match explanations' as result;
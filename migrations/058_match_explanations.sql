@@ -0,0 +1,33 @@
+-- Migration 058: Match Explanations
+-- Purpose: Persist why each document was matched the way it was: per-feature score
+--          contributions, generators, filters, threshold tier and margin, per candidate.
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- One row per document per match run, written by match.Engine.SaveResults
+CREATE TABLE IF NOT EXISTS match_explanation (
+    explanation_id BIGSERIAL PRIMARY KEY,
+    run_id         BIGINT NOT NULL REFERENCES match_run(run_id) ON DELETE CASCADE,
+    src_id         BIGINT NOT NULL,
+    decision       TEXT NOT NULL,
+    accepted_uprn  TEXT,
+    tier           TEXT,
+    margin         NUMERIC(6,4),
+    reason         TEXT,
+    thresholds     JSONB NOT NULL DEFAULT '{}'::jsonb,
+    candidates     JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_explanation_src ON match_explanation (src_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_match_explanation_run ON match_explanation (run_id);
+
+COMMENT ON TABLE match_explanation IS 'Explanation of each match decision, served by GET /api/records/{id}/explanation';
+COMMENT ON COLUMN match_explanation.tier IS 'Threshold tier the top eligible score reached: auto_accept_high, auto_accept_medium, review, min_threshold or below_min';
+COMMENT ON COLUMN match_explanation.margin IS 'Top eligible score minus the runner-up''s; 1 when there is no runner-up';
+COMMENT ON COLUMN match_explanation.candidates IS 'Top 10 candidates: uprn, address, rank, score, selected, tier, margin, methods, filters, contributions, features and suppressed_by';
+
+SELECT 'Added match explanations' as result;
+
+COMMIT;
@@ -0,0 +1,49 @@
+-- Migration 059 (down): Web Authentication
+-- Purpose: Remove web users, API keys and sessions, and stop naming the web user in audit
+--          chain entries. Existing entries keep their actors and hashes.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE OR REPLACE FUNCTION audit_chain_record(
+    p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB
+) RETURNS VOID AS $$
+DECLARE
+    last_seq  BIGINT;
+    last_hash TEXT;
+    e         audit_chain%ROWTYPE;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_chain'));
+    SELECT seq, hash INTO last_seq, last_hash FROM audit_chain ORDER BY seq DESC LIMIT 1;
+
+    e.seq := COALESCE(last_seq, 0) + 1;
+    e.table_name := p_table;
+    e.operation := p_operation;
+    e.row_key := p_row_key;
+    e.row_data := p_row_data;
+    e.actor := session_user;
+    e.recorded_at := clock_timestamp();
+    e.prev_hash := COALESCE(last_hash, repeat('0', 64));
+    IF p_operation <> 'BASELINE' THEN
+        SELECT run_id INTO e.run_id
+        FROM match_run_client
+        WHERE client = current_setting('application_name') AND client <> '' AND ended_at IS NULL
+        ORDER BY joined_at DESC
+        LIMIT 1;
+    END IF;
+    e.hash := audit_chain_hash(e.seq, e.table_name, e.operation, e.row_key, e.row_data,
+        e.actor, e.recorded_at, e.prev_hash, e.run_id);
+
+    INSERT INTO audit_chain VALUES (e.*);
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS app_session;
+DROP TABLE IF EXISTS app_api_key;
+DROP TABLE IF EXISTS app_user;
+
+COMMENT ON COLUMN audit_chain.actor IS 'Database session user that made the change';
+
+COMMIT;
+
+SELECT 'Removed web users, API keys and sessions' as result;
@@ -0,0 +1,92 @@
+-- Migration 059: Web Authentication
+-- Purpose: Users, API keys and sessions for the web interface, with hashed secrets and a
+--          role per user, and record the signed-in user on every audit chain entry.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS app_user (
+    user_id       BIGSERIAL PRIMARY KEY,
+    username      TEXT NOT NULL UNIQUE CHECK (username ~ '^[a-z0-9][a-z0-9._@-]*$'),
+    display_name  TEXT,
+    role          TEXT NOT NULL CHECK (role IN ('viewer', 'reviewer', 'senior_reviewer', 'admin')),
+    password_hash TEXT,
+    created_by    TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_login_at TIMESTAMPTZ,
+    disabled_at   TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS app_api_key (
+    key_id       BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES app_user(user_id) ON DELETE CASCADE,
+    prefix       TEXT NOT NULL UNIQUE,
+    secret_hash  TEXT NOT NULL,
+    label        TEXT,
+    created_by   TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_api_key_user ON app_api_key (user_id);
+
+CREATE TABLE IF NOT EXISTS app_session (
+    token_hash   TEXT PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES app_user(user_id) ON DELETE CASCADE,
+    client       TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_session_user ON app_session (user_id);
+
+-- The web server connects as one database user, so it names the signed-in user in the
+-- transaction setting ehdc.actor (internal/db BeginTx); the actor is then "alice via postgres".
+-- app_user is deliberately not chained: the chain would keep every password hash forever.
+CREATE OR REPLACE FUNCTION audit_chain_record(
+    p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB
+) RETURNS VOID AS $$
+DECLARE
+    last_seq  BIGINT;
+    last_hash TEXT;
+    e         audit_chain%ROWTYPE;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_chain'));
+    SELECT seq, hash INTO last_seq, last_hash FROM audit_chain ORDER BY seq DESC LIMIT 1;
+
+    e.seq := COALESCE(last_seq, 0) + 1;
+    e.table_name := p_table;
+    e.operation := p_operation;
+    e.row_key := p_row_key;
+    e.row_data := p_row_data;
+    e.actor := COALESCE(NULLIF(current_setting('ehdc.actor', true), '') || ' via ' || session_user, session_user);
+    e.recorded_at := clock_timestamp();
+    e.prev_hash := COALESCE(last_hash, repeat('0', 64));
+    IF p_operation <> 'BASELINE' THEN
+        SELECT run_id INTO e.run_id
+        FROM match_run_client
+        WHERE client = current_setting('application_name') AND client <> '' AND ended_at IS NULL
+        ORDER BY joined_at DESC
+        LIMIT 1;
+    END IF;
+    e.hash := audit_chain_hash(e.seq, e.table_name, e.operation, e.row_key, e.row_data,
+        e.actor, e.recorded_at, e.prev_hash, e.run_id);
+
+    INSERT INTO audit_chain VALUES (e.*);
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON TABLE app_user IS 'Web interface users; created with matcher auth user add';
+COMMENT ON COLUMN app_user.password_hash IS 'PBKDF2-SHA256 hash; NULL for users who only use API keys';
+COMMENT ON TABLE app_api_key IS 'API keys (X-API-Key); only a SHA-256 hash of each key is kept';
+COMMENT ON COLUMN app_api_key.prefix IS 'The ehdc_<prefix>_ part of the key, used to find it';
+COMMENT ON TABLE app_session IS 'Browser sessions; the token is kept as an HMAC keyed by SESSION_KEY';
+COMMENT ON COLUMN audit_chain.actor IS 'Database session user that made the change, prefixed by the web user ("alice via postgres") for web requests';
+
+SELECT 'Added web users, API keys and sessions' as result;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration 060 (down): One Address Match Per Document
+-- Purpose: Allow several address_match rows per document again.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_address_match_document ON address_match (document_id);
+DROP INDEX IF EXISTS idx_address_match_document_unique;
+
+COMMIT;
+
+SELECT 'Allowed several address_match rows per document' as result;
@@ -0,0 +1,22 @@
+-- Migration 060: One Address Match Per Document
+-- Purpose: Matching layers record their matches in address_match, upserting on document_id,
+--          so the fact table can always be rebuilt from it. Keep each document's newest
+--          row and make document_id unique.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DELETE FROM address_match am
+USING address_match newer
+WHERE newer.document_id = am.document_id
+  AND newer.match_id > am.match_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_address_match_document_unique ON address_match (document_id);
+DROP INDEX IF EXISTS idx_address_match_document;
+
+COMMENT ON TABLE address_match IS 'The matcher''s match of each document, one row per document; matching layers write here and facts rebuilds fact_documents_lean from it';
+COMMENT ON COLUMN address_match.matched_by IS 'system_<layer> for automatic matches, which honour reviewer rejections; otherwise the user';
+
+SELECT 'Made address_match one row per document' as result;
+
+COMMIT;
@@ -0,0 +1,34 @@
+-- Migration 061 (down): Manual Accepts
+-- Purpose: Treat every accept not by system as a reviewer's again.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE OR REPLACE FUNCTION match_accepted_honour_rejections() RETURNS trigger AS $$
+DECLARE
+    rejection BIGINT;
+BEGIN
+    IF COALESCE(NEW.accepted_by, 'system') = 'system' THEN
+        rejection := match_rejection_for(NEW.src_id, NEW.uprn);
+        IF rejection IS NOT NULL THEN
+            RAISE NOTICE 'accept of UPRN % for document % suppressed by prior rejection %', NEW.uprn, NEW.src_id, rejection;
+            RETURN NULL;
+        END IF;
+    ELSE
+        UPDATE match_rejection SET lifted_by = NEW.accepted_by, lifted_at = now()
+        WHERE src_id = NEW.src_id AND uprn = NEW.uprn AND lifted_at IS NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_match_accepted_rejections ON match_accepted;
+CREATE TRIGGER trg_match_accepted_rejections
+    BEFORE INSERT OR UPDATE OF src_id, uprn, accepted_by ON match_accepted
+    FOR EACH ROW EXECUTE FUNCTION match_accepted_honour_rejections();
+
+ALTER TABLE match_accepted DROP COLUMN IF EXISTS manual;
+
+COMMIT;
+
+SELECT 'Removed manual accepts' as result;
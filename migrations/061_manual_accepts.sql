@@ -0,0 +1,42 @@
+-- Migration 061: Manual Accepts
+-- Purpose: Mark reviewers' accepts explicitly. Only a manual accept lifts a rejection; every
+--          other accept of a rejected pair is dropped, whoever the accepted_by names.
+-- Date: 2026-10-18
+
+BEGIN;
+
+ALTER TABLE match_accepted ADD COLUMN IF NOT EXISTS manual BOOLEAN NOT NULL DEFAULT false;
+
+-- Accepts made by the review paths before the flag existed
+UPDATE match_accepted SET manual = true
+WHERE method IN ('manual_review', 'manual_override')
+   OR src_id IN (SELECT src_id FROM review_event WHERE action IN ('accept', 'confirm'));
+
+CREATE OR REPLACE FUNCTION match_accepted_honour_rejections() RETURNS trigger AS $$
+DECLARE
+    rejection BIGINT;
+BEGIN
+    IF NOT NEW.manual THEN
+        rejection := match_rejection_for(NEW.src_id, NEW.uprn);
+        IF rejection IS NOT NULL THEN
+            RAISE NOTICE 'accept of UPRN % for document % suppressed by prior rejection %', NEW.uprn, NEW.src_id, rejection;
+            RETURN NULL;
+        END IF;
+    ELSE
+        UPDATE match_rejection SET lifted_by = NEW.accepted_by, lifted_at = now()
+        WHERE src_id = NEW.src_id AND uprn = NEW.uprn AND lifted_at IS NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_match_accepted_rejections ON match_accepted;
+CREATE TRIGGER trg_match_accepted_rejections
+    BEFORE INSERT OR UPDATE OF src_id, uprn, accepted_by, manual ON match_accepted
+    FOR EACH ROW EXECUTE FUNCTION match_accepted_honour_rejections();
+
+COMMENT ON COLUMN match_accepted.manual IS 'True when a reviewer accepted the match (web, review queue, CLI review or override); only these lift a rejection';
+
+SELECT 'Added manual accepts' as result;
+
+COMMIT;
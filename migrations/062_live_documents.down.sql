@@ -0,0 +1,18 @@
+-- Migration 062 (down): Live Documents
+-- Purpose: Drop the live documents view and index, and stop logging fact changes on removal.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_fact_change_src_document ON src_document;
+CREATE TRIGGER trg_fact_change_src_document
+    AFTER INSERT OR DELETE OR UPDATE OF raw_address, doc_type_id, document_date, external_reference,
+        job_number, filepath, raw_uprn, gopostal_processed, gopostal_postcode ON src_document
+    FOR EACH ROW EXECUTE FUNCTION log_fact_change('document_id');
+
+DROP INDEX IF EXISTS idx_src_document_removed;
+DROP VIEW IF EXISTS src_document_live;
+
+COMMIT;
+
+SELECT 'Removed live documents view' as result;
@@ -0,0 +1,32 @@
+-- Migration 062: Live Documents
+-- Purpose: Keep documents a later import batch removed out of matching, the fact table and the
+--          web views: a src_document_live view for readers, an index for excluding removed
+--          documents, and a fact change logged when removed_at changes.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE OR REPLACE VIEW src_document_live AS
+SELECT * FROM src_document WHERE removed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_src_document_removed ON src_document (src_id) WHERE removed_at IS NOT NULL;
+
+-- Removing or restoring a document drops or rebuilds its fact row
+DROP TRIGGER IF EXISTS trg_fact_change_src_document ON src_document;
+CREATE TRIGGER trg_fact_change_src_document
+    AFTER INSERT OR DELETE OR UPDATE OF raw_address, doc_type_id, document_date, external_reference,
+        job_number, filepath, raw_uprn, gopostal_processed, gopostal_postcode, removed_at ON src_document
+    FOR EACH ROW EXECUTE FUNCTION log_fact_change('document_id');
+
+-- Documents already removed still have fact rows; the applier deletes them
+INSERT INTO fact_change_log (document_id, source)
+SELECT f.document_id, 'src_document'
+FROM fact_documents_lean f
+JOIN src_document s ON s.document_id = f.document_id
+WHERE s.removed_at IS NOT NULL;
+
+COMMENT ON VIEW src_document_live IS 'Documents still in their source file; removed ones keep their match decisions but are not matched, exported or shown';
+
+SELECT 'Added live documents view and removal fact changes' as result;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Migration 063 (down): LLPG Import Flags
+-- Purpose: Remove the LLPG staging import flags.
+-- Date: 2026-10-18
+
+BEGIN;
+
+ALTER TABLE IF EXISTS stg_llpg DROP COLUMN IF EXISTS import_flags;
+
+COMMIT;
+
+SELECT 'Removed LLPG staging import flags' as result;
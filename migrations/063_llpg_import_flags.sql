@@ -0,0 +1,19 @@
+-- Migration 063: LLPG Import Flags
+-- Purpose: Record Excel repairs to LLPG eastings and northings in staging, as source documents
+--          record theirs.
+-- Date: 2026-10-18
+
+BEGIN;
+
+ALTER TABLE IF EXISTS stg_llpg ADD COLUMN IF NOT EXISTS import_flags TEXT;
+
+DO $$
+BEGIN
+    IF to_regclass('stg_llpg') IS NOT NULL THEN
+        COMMENT ON COLUMN stg_llpg.import_flags IS 'Semicolon-separated column:repair flags, e.g. easting:thousands_separator';
+    END IF;
+END $$;
+
+SELECT 'Added LLPG staging import flags' as result;
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Migration 064 (down): Planning Reference Columns
+-- Purpose: Remove the planning reference linker's columns.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_src_document_planning_ref_base;
+ALTER TABLE src_document DROP COLUMN IF EXISTS planning_ref_sequence;
+ALTER TABLE src_document DROP COLUMN IF EXISTS planning_ref_base;
+
+COMMIT;
+
+SELECT 'Removed planning reference columns' as result;
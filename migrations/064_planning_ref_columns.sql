@@ -0,0 +1,19 @@
+-- Migration 064: Planning Reference Columns
+-- Purpose: Give the planning reference linker its own normalised base and sequence columns,
+--          so link-planning-refs no longer overwrites the migration 041 grouping in
+--          planning_app_base and planning_app_sequence that group consensus relies on.
+-- Date: 2026-10-18
+
+BEGIN;
+
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS planning_ref_base TEXT;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS planning_ref_sequence TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_src_document_planning_ref_base ON src_document(planning_ref_base);
+
+COMMENT ON COLUMN src_document.planning_ref_base IS 'Application base of the normalised external_ref, e.g. PRD1234; written by link-planning-refs';
+COMMENT ON COLUMN src_document.planning_ref_sequence IS 'Sub-application within planning_ref_base, e.g. 5 for PRD1234/5; written by link-planning-refs';
+
+SELECT 'Added planning reference columns' as result;
+
+COMMIT;
@@ -0,0 +1,105 @@
+-- Migration 065 (down): Audit Chain Batches
+-- Purpose: Chain rows one at a time again, with a row-level trigger per chained table.
+--          Pending entries are chained first; existing entries are kept.
+-- Date: 2026-10-18
+
+BEGIN;
+
+SELECT audit_chain_flush(NULL);
+
+CREATE OR REPLACE FUNCTION audit_chain_record(
+    p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB
+) RETURNS VOID AS $$
+DECLARE
+    last_seq  BIGINT;
+    last_hash TEXT;
+    e         audit_chain%ROWTYPE;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_chain'));
+    SELECT seq, hash INTO last_seq, last_hash FROM audit_chain ORDER BY seq DESC LIMIT 1;
+
+    e.seq := COALESCE(last_seq, 0) + 1;
+    e.table_name := p_table;
+    e.operation := p_operation;
+    e.row_key := p_row_key;
+    e.row_data := p_row_data;
+    e.actor := COALESCE(NULLIF(current_setting('ehdc.actor', true), '') || ' via ' || session_user, session_user);
+    e.recorded_at := clock_timestamp();
+    e.prev_hash := COALESCE(last_hash, repeat('0', 64));
+    IF p_operation <> 'BASELINE' THEN
+        SELECT run_id INTO e.run_id
+        FROM match_run_client
+        WHERE client = current_setting('application_name') AND client <> '' AND ended_at IS NULL
+        ORDER BY joined_at DESC
+        LIMIT 1;
+    END IF;
+    e.hash := audit_chain_hash(e.seq, e.table_name, e.operation, e.row_key, e.row_data,
+        e.actor, e.recorded_at, e.prev_hash, e.run_id);
+
+    INSERT INTO audit_chain VALUES (e.*);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION audit_chain_append() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM audit_chain_record(TG_TABLE_NAME, TG_OP, to_jsonb(OLD) ->> TG_ARGV[0], NULL);
+    ELSE
+        IF TG_OP = 'UPDATE' AND (to_jsonb(OLD) ->> TG_ARGV[0]) IS DISTINCT FROM (to_jsonb(NEW) ->> TG_ARGV[0]) THEN
+            PERFORM audit_chain_record(TG_TABLE_NAME, 'DELETE', to_jsonb(OLD) ->> TG_ARGV[0], NULL);
+        END IF;
+        PERFORM audit_chain_record(TG_TABLE_NAME, TG_OP, to_jsonb(NEW) ->> TG_ARGV[0], to_jsonb(NEW));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SET timezone = 'UTC';
+
+CREATE OR REPLACE FUNCTION audit_chain_attach(p_table TEXT, p_key TEXT) RETURNS INTEGER AS $$
+DECLARE
+    r       RECORD;
+    op      TEXT;
+    chained INTEGER := 0;
+BEGIN
+    IF to_regclass(p_table) IS NULL THEN
+        RETURN 0;
+    END IF;
+
+    FOREACH op IN ARRAY ARRAY['insert', 'update', 'delete'] LOOP
+        EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', 'trg_audit_chain_' || p_table || '_' || op, p_table);
+    END LOOP;
+    EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', 'trg_audit_chain_' || p_table, p_table);
+    EXECUTE format('CREATE TRIGGER %I AFTER INSERT OR UPDATE OR DELETE ON %I '
+                   'FOR EACH ROW EXECUTE FUNCTION audit_chain_append(%L)',
+                   'trg_audit_chain_' || p_table, p_table, p_key);
+
+    FOR r IN EXECUTE format(
+        'SELECT to_jsonb(t) AS data FROM %I t
+         WHERE NOT EXISTS (SELECT 1 FROM audit_chain c WHERE c.table_name = %L AND c.row_key = to_jsonb(t) ->> %L)
+         ORDER BY t.%I', p_table, p_table, p_key, p_key)
+    LOOP
+        PERFORM audit_chain_record(p_table, 'BASELINE', r.data ->> p_key, r.data);
+        chained := chained + 1;
+    END LOOP;
+    RETURN chained;
+END;
+$$ LANGUAGE plpgsql SET timezone = 'UTC';
+
+SELECT audit_chain_attach('match_result', 'match_id');
+SELECT audit_chain_attach('match_accepted', 'src_id');
+SELECT audit_chain_attach('match_override', 'override_id');
+SELECT audit_chain_attach('audit_match_decisions', 'decision_id');
+SELECT audit_chain_attach('match_audit', 'audit_id');
+SELECT audit_chain_attach('address_match', 'match_id');
+SELECT audit_chain_attach('address_match_corrected', 'document_id');
+SELECT audit_chain_attach('match_rejection', 'rejection_id');
+
+DROP FUNCTION IF EXISTS audit_chain_append_rows();
+DROP FUNCTION IF EXISTS audit_chain_flush(INTEGER);
+DROP FUNCTION IF EXISTS audit_chain_run_id();
+DROP FUNCTION IF EXISTS audit_chain_actor();
+DROP TABLE IF EXISTS audit_chain_pending;
+DROP FUNCTION IF EXISTS audit_chain_pending_refuse_update();
+
+COMMIT;
+
+SELECT 'Audit chain entries are appended a row at a time again' as result;
@@ -0,0 +1,189 @@
+-- Migration 065: Audit Chain Batches
+-- Purpose: Stop chained writes from queueing behind each other. audit_chain_record took the
+--          chain's advisory lock for every row and held it until the writing transaction
+--          committed, so every transaction that touched a chained table waited for the one
+--          before it. Chained tables now have statement-level triggers that copy the changed
+--          rows from their transition tables into audit_chain_pending, which takes no lock.
+--          audit_chain_flush() is the single chainer: it moves pending entries into
+--          audit_chain in order, hashing each onto the last, and only chainers wait for its
+--          lock. verify-audit, audit-checkpoint and run rollback flush first, and
+--          -cmd=audit-chain flushes on its own (run it from cron).
+-- Date: 2026-10-18
+
+BEGIN;
+
+-- Changes written but not yet chained. A writer stamps its actor, time and run here, as
+-- neither can be known by the chainer's session. Removing an entry before it is chained
+-- leaves its row differing from the chain, which verify-audit reports.
+CREATE TABLE IF NOT EXISTS audit_chain_pending (
+    pending_id  BIGSERIAL PRIMARY KEY,
+    table_name  TEXT NOT NULL,
+    operation   TEXT NOT NULL CHECK (operation IN ('INSERT', 'UPDATE', 'DELETE', 'BASELINE')),
+    row_key     TEXT,
+    row_data    JSONB,
+    actor       TEXT NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    run_id      BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_chain_pending_row ON audit_chain_pending (table_name, row_key);
+
+CREATE OR REPLACE FUNCTION audit_chain_pending_refuse_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_chain_pending entries cannot be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_chain_pending_no_update ON audit_chain_pending;
+CREATE TRIGGER trg_audit_chain_pending_no_update
+    BEFORE UPDATE ON audit_chain_pending
+    FOR EACH ROW EXECUTE FUNCTION audit_chain_pending_refuse_update();
+
+-- The writer as the chain names it: "alice via postgres" for web requests (see migration 059)
+CREATE OR REPLACE FUNCTION audit_chain_actor() RETURNS TEXT AS $$
+    SELECT COALESCE(NULLIF(current_setting('ehdc.actor', true), '') || ' via ' || session_user, session_user);
+$$ LANGUAGE sql STABLE;
+
+-- The match run the writing process has joined, if any (see migration 056)
+CREATE OR REPLACE FUNCTION audit_chain_run_id() RETURNS BIGINT AS $$
+    SELECT run_id
+    FROM match_run_client
+    WHERE client = current_setting('application_name') AND client <> '' AND ended_at IS NULL
+    ORDER BY joined_at DESC
+    LIMIT 1;
+$$ LANGUAGE sql STABLE;
+
+-- Chains pending entries in the order they were written, at most p_limit of them (all when
+-- NULL). Each entry's hash covers the one before it, so entries are hashed one at a time and
+-- inserted as they go; chainers wait for each other, writers never do. Returns the number of
+-- entries chained.
+CREATE OR REPLACE FUNCTION audit_chain_flush(p_limit INTEGER DEFAULT 10000) RETURNS INTEGER AS $$
+DECLARE
+    p       RECORD;
+    e       audit_chain%ROWTYPE;
+    chained INTEGER := 0;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_chain'));
+    SELECT seq, hash INTO e.seq, e.hash FROM audit_chain ORDER BY seq DESC LIMIT 1;
+    e.seq := COALESCE(e.seq, 0);
+    e.hash := COALESCE(e.hash, repeat('0', 64));
+
+    FOR p IN
+        WITH taken AS (
+            DELETE FROM audit_chain_pending
+            WHERE pending_id IN (SELECT pending_id FROM audit_chain_pending ORDER BY pending_id LIMIT p_limit)
+            RETURNING *
+        )
+        SELECT * FROM taken ORDER BY pending_id
+    LOOP
+        e.seq := e.seq + 1;
+        e.prev_hash := e.hash;
+        e.table_name := p.table_name;
+        e.operation := p.operation;
+        e.row_key := p.row_key;
+        e.row_data := p.row_data;
+        e.actor := p.actor;
+        e.recorded_at := p.recorded_at;
+        e.run_id := p.run_id;
+        e.hash := audit_chain_hash(e.seq, e.table_name, e.operation, e.row_key, e.row_data,
+            e.actor, e.recorded_at, e.prev_hash, e.run_id);
+        INSERT INTO audit_chain VALUES (e.*);
+        chained := chained + 1;
+    END LOOP;
+    RETURN chained;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Appends one entry
+CREATE OR REPLACE FUNCTION audit_chain_record(
+    p_table TEXT, p_operation TEXT, p_row_key TEXT, p_row_data JSONB
+) RETURNS VOID AS $$
+    INSERT INTO audit_chain_pending (table_name, operation, row_key, row_data, actor, recorded_at, run_id)
+    VALUES (p_table, p_operation, p_row_key, p_row_data, audit_chain_actor(), clock_timestamp(),
+        CASE WHEN p_operation <> 'BASELINE' THEN audit_chain_run_id() END);
+$$ LANGUAGE sql;
+
+-- Records a statement's row changes from its transition tables; the row key is the column named
+-- by TG_ARGV[0]. An update that changes a row's key records a DELETE of the old key, as before,
+-- and all of a statement's deletes come before its updates.
+CREATE OR REPLACE FUNCTION audit_chain_append_rows() RETURNS trigger AS $$
+DECLARE
+    v_actor  TEXT := audit_chain_actor();
+    v_run_id BIGINT := audit_chain_run_id();
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO audit_chain_pending (table_name, operation, row_key, row_data, actor, recorded_at, run_id)
+        SELECT TG_TABLE_NAME, 'DELETE', to_jsonb(o) ->> TG_ARGV[0], NULL, v_actor, clock_timestamp(), v_run_id
+        FROM old_rows o;
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        INSERT INTO audit_chain_pending (table_name, operation, row_key, row_data, actor, recorded_at, run_id)
+        SELECT TG_TABLE_NAME, 'DELETE', moved.k, NULL, v_actor, clock_timestamp(), v_run_id
+        FROM (
+            SELECT to_jsonb(o) ->> TG_ARGV[0] AS k FROM old_rows o
+            EXCEPT
+            SELECT to_jsonb(n) ->> TG_ARGV[0] FROM new_rows n
+        ) AS moved;
+    END IF;
+
+    INSERT INTO audit_chain_pending (table_name, operation, row_key, row_data, actor, recorded_at, run_id)
+    SELECT TG_TABLE_NAME, TG_OP, to_jsonb(n) ->> TG_ARGV[0], to_jsonb(n), v_actor, clock_timestamp(), v_run_id
+    FROM new_rows n;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SET timezone = 'UTC';
+
+-- Puts a table under the chain: replaces its triggers and records a BASELINE entry for each row
+-- with no entry yet, in one statement. Transition tables need a trigger per operation.
+CREATE OR REPLACE FUNCTION audit_chain_attach(p_table TEXT, p_key TEXT) RETURNS INTEGER AS $$
+DECLARE
+    op      TEXT;
+    chained INTEGER;
+BEGIN
+    IF to_regclass(p_table) IS NULL THEN
+        RETURN 0;
+    END IF;
+
+    EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', 'trg_audit_chain_' || p_table, p_table);
+    FOREACH op IN ARRAY ARRAY['insert', 'update', 'delete'] LOOP
+        EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', 'trg_audit_chain_' || p_table || '_' || op, p_table);
+        EXECUTE format('CREATE TRIGGER %I AFTER %s ON %I REFERENCING %s '
+                       'FOR EACH STATEMENT EXECUTE FUNCTION audit_chain_append_rows(%L)',
+                       'trg_audit_chain_' || p_table || '_' || op, upper(op), p_table,
+                       CASE op WHEN 'insert' THEN 'NEW TABLE AS new_rows'
+                               WHEN 'update' THEN 'OLD TABLE AS old_rows NEW TABLE AS new_rows'
+                               ELSE 'OLD TABLE AS old_rows' END,
+                       p_key);
+    END LOOP;
+
+    EXECUTE format(
+        'INSERT INTO audit_chain_pending (table_name, operation, row_key, row_data, actor, recorded_at)
+         SELECT %L, ''BASELINE'', to_jsonb(t) ->> %L, to_jsonb(t), audit_chain_actor(), clock_timestamp()
+         FROM %I t
+         WHERE NOT EXISTS (SELECT 1 FROM audit_chain c WHERE c.table_name = %L AND c.row_key = to_jsonb(t) ->> %L)
+           AND NOT EXISTS (SELECT 1 FROM audit_chain_pending p WHERE p.table_name = %L AND p.row_key = to_jsonb(t) ->> %L)
+         ORDER BY t.%I', p_table, p_key, p_table, p_table, p_key, p_table, p_key, p_key);
+    GET DIAGNOSTICS chained = ROW_COUNT;
+    RETURN chained;
+END;
+$$ LANGUAGE plpgsql SET timezone = 'UTC';
+
+-- Swap every chained table to the statement triggers; their rows are already chained
+SELECT audit_chain_attach('match_result', 'match_id');
+SELECT audit_chain_attach('match_accepted', 'src_id');
+SELECT audit_chain_attach('match_override', 'override_id');
+SELECT audit_chain_attach('audit_match_decisions', 'decision_id');
+SELECT audit_chain_attach('match_audit', 'audit_id');
+SELECT audit_chain_attach('address_match', 'match_id');
+SELECT audit_chain_attach('address_match_corrected', 'document_id');
+SELECT audit_chain_attach('match_rejection', 'rejection_id');
+
+DROP FUNCTION IF EXISTS audit_chain_append();
+
+COMMENT ON TABLE audit_chain_pending IS 'Chained-table changes waiting for audit_chain_flush() to hash them into audit_chain';
+
+SELECT 'Audit chain entries are recorded a statement at a time and chained by audit_chain_flush()' as result;
+
+COMMIT;
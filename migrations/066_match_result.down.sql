@@ -0,0 +1,14 @@
+-- Migration 066 (down): Match Result
+-- Purpose: Remove the indexes this migration added. match_result is left in place, chained
+--          and checked against rejections: it holds every run's candidates and decisions.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_match_result_review;
+DROP INDEX IF EXISTS idx_match_result_run;
+DROP INDEX IF EXISTS idx_match_result_src_run;
+
+COMMIT;
+
+SELECT 'Removed match_result indexes' as result;
@@ -0,0 +1,49 @@
+-- Migration 066: Match Result
+-- Purpose: Create match_result, the candidates and decisions of every match run. The engines,
+--          the review queue and the explanations all read and write it, but no migration
+--          created it, so on a new database migrations 055, 057 and 065 skipped it and its
+--          rows were neither chained nor checked against reviewer rejections. Puts it under
+--          the audit chain and attaches the rejection trigger.
+-- Date: 2026-10-18
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS match_result (
+    match_id        BIGSERIAL PRIMARY KEY,
+    run_id          BIGINT,
+    src_id          BIGINT NOT NULL,
+    candidate_uprn  TEXT,
+    method          TEXT NOT NULL,
+    score           NUMERIC,
+    confidence      NUMERIC,
+    tie_rank        INTEGER,
+    features        JSONB,
+    decided         BOOLEAN DEFAULT false,
+    decision        TEXT,
+    decided_by      TEXT,
+    decided_at      TIMESTAMPTZ,
+    notes           TEXT
+);
+
+-- Columns the engines write that databases created from the original schema lack
+ALTER TABLE match_result ADD COLUMN IF NOT EXISTS confidence NUMERIC;
+ALTER TABLE match_result ADD COLUMN IF NOT EXISTS features JSONB;
+ALTER TABLE match_result ADD COLUMN IF NOT EXISTS notes TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_match_result_src_run ON match_result (src_id, run_id, tie_rank);
+CREATE INDEX IF NOT EXISTS idx_match_result_run ON match_result (run_id);
+CREATE INDEX IF NOT EXISTS idx_match_result_review ON match_result (src_id) WHERE decision = 'needs_review';
+
+DROP TRIGGER IF EXISTS trg_match_result_rejections ON match_result;
+CREATE TRIGGER trg_match_result_rejections
+    BEFORE INSERT ON match_result
+    FOR EACH ROW EXECUTE FUNCTION match_result_honour_rejections();
+
+SELECT audit_chain_attach('match_result', 'match_id');
+
+COMMENT ON TABLE match_result IS 'Candidates each match run considered for a document, best first (tie_rank 1), with the decision taken';
+COMMENT ON COLUMN match_result.decision IS 'For example auto_accepted, needs_review, accepted or rejected; suppressed when a reviewer had rejected the pair (see match_rejection)';
+
+SELECT 'Created match_result under the audit chain and reviewer rejections' as result;
+
+COMMIT;
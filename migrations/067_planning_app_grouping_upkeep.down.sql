@@ -0,0 +1,17 @@
+-- Migration 067 (down): Planning App Grouping Upkeep
+-- Purpose: Stop filling the migration 041 grouping and restore migration 064's empty
+--          planning_ref columns. Grouping already filled is kept.
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_src_document_planning_app_group ON src_document;
+DROP FUNCTION IF EXISTS src_document_planning_app_group();
+
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS planning_ref_base TEXT;
+ALTER TABLE src_document ADD COLUMN IF NOT EXISTS planning_ref_sequence TEXT;
+CREATE INDEX IF NOT EXISTS idx_src_document_planning_ref_base ON src_document(planning_ref_base);
+
+COMMIT;
+
+SELECT 'Removed planning app grouping upkeep' as result;
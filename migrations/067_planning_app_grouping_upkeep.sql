@@ -0,0 +1,75 @@
+-- Migration 067: Planning App Grouping Upkeep
+-- Purpose: Key the planning reference linker on the migration 041 grouping. Drops the
+--          planning_ref_base and planning_ref_sequence columns migration 064 gave the linker,
+--          and keeps planning_app_base, planning_app_sequence and planning_app_group_id filled
+--          for documents loaded or re-referenced since 041, using 041's
+--          split_planning_app_number().
+-- Date: 2026-10-18
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_src_document_planning_ref_base;
+ALTER TABLE src_document DROP COLUMN IF EXISTS planning_ref_sequence;
+ALTER TABLE src_document DROP COLUMN IF EXISTS planning_ref_base;
+
+-- A document joins the group of its base, or starts a new one
+CREATE OR REPLACE FUNCTION src_document_planning_app_group() RETURNS trigger AS $$
+BEGIN
+    SELECT base_app, sequence INTO NEW.planning_app_base, NEW.planning_app_sequence
+    FROM split_planning_app_number(NULLIF(TRIM(NEW.external_reference), ''));
+
+    IF NEW.planning_app_base IS NULL THEN
+        NEW.planning_app_group_id := NULL;
+    ELSE
+        SELECT planning_app_group_id INTO NEW.planning_app_group_id
+        FROM src_document
+        WHERE planning_app_base = NEW.planning_app_base AND planning_app_group_id IS NOT NULL
+        LIMIT 1;
+        IF NEW.planning_app_group_id IS NULL THEN
+            SELECT COALESCE(MAX(planning_app_group_id), 0) + 1 INTO NEW.planning_app_group_id
+            FROM src_document;
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_src_document_planning_app_group ON src_document;
+CREATE TRIGGER trg_src_document_planning_app_group
+    BEFORE INSERT OR UPDATE OF external_reference ON src_document
+    FOR EACH ROW EXECUTE FUNCTION src_document_planning_app_group();
+
+-- Documents loaded since migration 041
+UPDATE src_document s
+SET (planning_app_base, planning_app_sequence) = (
+    SELECT base_app, sequence FROM split_planning_app_number(s.external_reference)
+)
+WHERE s.planning_app_base IS NULL
+  AND s.external_reference IS NOT NULL AND TRIM(s.external_reference) <> '';
+
+WITH next_id AS (
+    SELECT COALESCE(MAX(planning_app_group_id), 0) AS last_id FROM src_document
+),
+groups AS (
+    SELECT g.planning_app_base,
+           COALESCE(g.existing_id, n.last_id + ROW_NUMBER() OVER (PARTITION BY g.existing_id IS NULL ORDER BY g.planning_app_base)) AS group_id
+    FROM (
+        SELECT planning_app_base, MAX(planning_app_group_id) AS existing_id
+        FROM src_document
+        WHERE planning_app_base IS NOT NULL
+        GROUP BY planning_app_base
+        HAVING COUNT(*) FILTER (WHERE planning_app_group_id IS NULL) > 0
+    ) g
+    CROSS JOIN next_id n
+)
+UPDATE src_document s
+SET planning_app_group_id = g.group_id
+FROM groups g
+WHERE s.planning_app_base = g.planning_app_base
+  AND s.planning_app_group_id IS NULL;
+
+COMMENT ON TRIGGER trg_src_document_planning_app_group ON src_document IS 'Keeps the migration 041 planning grouping current as external_reference changes';
+
+SELECT 'Planning app grouping kept current; planning_ref columns removed' as result;
+
+COMMIT;
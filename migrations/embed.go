@@ -0,0 +1,10 @@
+// Package migrations embeds the schema migration files so binaries can apply them
+// without the source tree. See internal/migrate for the runner.
+package migrations
+
+import "embed"
+
+// FS holds every NNN_name.sql (up) and NNN_name.down.sql (down) file
+//
+//go:embed *.sql
+var FS embed.FS
@@ -3,12 +3,17 @@
 # Export CSV files from PostgreSQL views
 # Usage: ./export_csv.sh [output_directory]
 
-# Configuration
-DB_HOST="localhost"
-DB_PORT="15435"
-DB_NAME="ehdc_llpg"
-DB_USER="postgres"
-DB_PASSWORD="kljh234hjkl2h"
+# Load environment variables if .env exists
+if [ -f .env ]; then
+    export $(grep -v '^#' .env | xargs)
+fi
+
+# Configuration, from the environment or .env
+DB_HOST="${DB_HOST:-localhost}"
+DB_PORT="${DB_PORT:-5432}"
+DB_NAME="${DB_NAME:?DB_NAME is not set}"
+DB_USER="${DB_USER:?DB_USER is not set}"
+DB_PASSWORD="${DB_PASSWORD:?DB_PASSWORD is not set}"
 
 # Output directory (default to current directory)
 OUTPUT_DIR="${1:-.}"
@@ -6,43 +6,7 @@ BEGIN;
 -- Truncate and rebuild the fact table to include corrections
 TRUNCATE TABLE fact_documents_lean;
 
--- Helper function to get the effective match (corrected or original)
-CREATE OR REPLACE FUNCTION get_effective_match(doc_id INTEGER)
-RETURNS TABLE(
-    address_id INTEGER,
-    location_id INTEGER,
-    confidence_score NUMERIC,
-    match_method_id INTEGER
-) AS $$
-BEGIN
-    RETURN QUERY
-    -- First try corrected match
-    SELECT 
-        amc.corrected_address_id,
-        amc.corrected_location_id,
-        amc.corrected_confidence_score,
-        amc.corrected_method_id
-    FROM address_match_corrected amc
-    WHERE amc.document_id = doc_id
-    
-    UNION ALL
-    
-    -- If no correction exists, use original match
-    SELECT 
-        am.address_id,
-        am.location_id,
-        am.confidence_score,
-        am.match_method_id
-    FROM address_match am
-    WHERE am.document_id = doc_id
-      AND NOT EXISTS (
-          SELECT 1 FROM address_match_corrected amc2 
-          WHERE amc2.document_id = doc_id
-      )
-    
-    LIMIT 1;
-END;
-$$ LANGUAGE plpgsql;
+-- get_effective_match() (corrected or original match) is created by migration 000
 
 -- Repopulate fact table with corrections applied
 INSERT INTO fact_documents_lean (
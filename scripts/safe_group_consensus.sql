@@ -1,33 +1,7 @@
 -- Safe Group Consensus Correction Script
 -- Only applies group consensus when addresses are clearly similar and safe
 
-CREATE OR REPLACE FUNCTION is_real_address(address_text TEXT) 
-RETURNS BOOLEAN AS $$
-BEGIN
-    -- Check if this looks like a real address vs planning reference
-    IF address_text IS NULL OR LENGTH(TRIM(address_text)) < 10 THEN
-        RETURN FALSE;
-    END IF;
-    
-    -- Planning reference patterns (F12345, AU123, etc.)
-    IF address_text ~ '^[A-Z]{1,3}[0-9]+/?[0-9]*$' THEN
-        RETURN FALSE;
-    END IF;
-    
-    -- N/A and similar non-addresses
-    IF UPPER(address_text) IN ('N/A', 'NOT APPLICABLE', 'NONE', 'NULL', 'TBC') THEN
-        RETURN FALSE;
-    END IF;
-    
-    -- Must contain typical address indicators
-    IF address_text ~* '(street|road|avenue|lane|way|close|drive|court|place|crescent|gardens|park|hill|view|house|cottage|farm|manor|hall)' 
-       OR address_text ~ ',' THEN  -- Has commas (typical of addresses)
-        RETURN TRUE;
-    END IF;
-    
-    RETURN FALSE;
-END;
-$$ LANGUAGE plpgsql IMMUTABLE;
+-- is_real_address() and get_group_best_match_simple() are created by migration 000
 
 -- Safe group consensus correction with strict rules
 WITH safe_group_candidates AS (
@@ -121,9 +121,11 @@ echo "🎉 Setup complete!"
 echo ""
 echo "📋 Next steps:"
 echo "  1. Start the system:     ./start_complete_system.sh"
-echo "  2. Load LLPG data:       ./bin/matcher-v2 -cmd=load-llpg -llpg=llpg_docs/ehdc_llpg_20250710.csv"
-echo "  3. Load source docs:     ./bin/matcher-v2 -cmd=load-sources"
-echo "  4. Run matching:         ./bin/matcher-v2 -cmd=comprehensive-match"
+echo "  2. Create the schema:    ./bin/matcher-v2 -cmd=migrate-up"
+echo "     (existing database:   ./bin/matcher-v2 -cmd=migrate-baseline -target=<last applied migration>)"
+echo "  3. Load LLPG data:       ./bin/matcher-v2 -cmd=load-llpg -llpg=llpg_docs/ehdc_llpg_20250710.csv"
+echo "  4. Load source docs:     ./bin/matcher-v2 -cmd=load-sources"
+echo "  5. Run matching:         ./bin/matcher-v2 -cmd=comprehensive-match"
 echo ""
 echo "📚 Documentation:"
 echo "  • README.md - Project overview and commands"